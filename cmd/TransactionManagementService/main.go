@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"net/http"
 	"os"
 
 	"github.com/PereRohit/util/config"
@@ -8,7 +10,12 @@ import (
 	"github.com/PereRohit/util/server"
 
 	svcCfg "github.com/vatsal278/TransactionManagementService/internal/config"
+	"github.com/vatsal278/TransactionManagementService/internal/logic"
+	"github.com/vatsal278/TransactionManagementService/internal/outbox"
+	"github.com/vatsal278/TransactionManagementService/internal/repo/datasource"
 	"github.com/vatsal278/TransactionManagementService/internal/router"
+	"github.com/vatsal278/TransactionManagementService/internal/scheduler"
+	"github.com/vatsal278/TransactionManagementService/internal/webhook"
 )
 
 func main() {
@@ -23,6 +30,48 @@ func main() {
 	// Initialize the service configuration based on the loaded configuration
 	svcInitCfg := svcCfg.InitSvcConfig(cfg)
 
+	// Create the datasource used outside of request handling
+	ds := datasource.NewSql(svcInitCfg.DbSvc, cfg.DataBase.TableName)
+
+	// Seed the exchange rate table from the configured file, if any
+	if cfg.FxRatesFile != "" {
+		rates, err := svcCfg.LoadFxRates(cfg.FxRatesFile)
+		if err != nil {
+			log.Error(err)
+			os.Exit(1)
+		}
+		for _, rate := range rates {
+			err = ds.UpsertFxRate(rate)
+			if err != nil {
+				log.Error(err)
+				os.Exit(1)
+			}
+		}
+	}
+
+	// Start delivering outbox messages to the account service in the background
+	dispatcher := outbox.NewDispatcher(ds, svcInitCfg.ExternalService.AccSvcUrl, svcInitCfg.Cfg.Outbox)
+	go dispatcher.Run(context.Background())
+
+	// Start delivering queued events to the registered webhooks in the background
+	webhooks := webhook.NewDispatcher(ds, svcInitCfg.Cfg.Webhooks)
+	go webhooks.Run(context.Background())
+
+	// Start creating the transactions of due schedules in the background
+	schedules := scheduler.NewScheduler(logic.NewTransactionManagementServiceLogic(ds, svcInitCfg.ExternalService), svcInitCfg.Cfg.Scheduler)
+	go schedules.Run(context.Background())
+
+	// Serve the internal routes, such as metrics, on the admin address only
+	if cfg.AdminAddr != "" {
+		admin := router.RegisterAdmin(svcInitCfg)
+		go func() {
+			err := http.ListenAndServe(cfg.AdminAddr, admin)
+			if err != nil {
+				log.Error(err)
+			}
+		}()
+	}
+
 	// Register the routes and handlers for the service
 	r := router.Register(svcInitCfg)
 
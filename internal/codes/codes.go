@@ -58,60 +58,192 @@ const (
 	ErrPdf
 	ErrAssertResp
 	ErrAssertPdf
+	ErrInvalidFilter
+	ErrInvalidCursor
+	ErrIdempotencyKey
+	ErrIdempotencyMismatch
+	ErrIdempotencyInProgress
+	ErrInvalidTransfer
+	ErrUnknownRecipient
+	ErrUnbalancedTransfer
+	ErrInvalidStatusTransition
+	ErrStatusChanged
+	ErrUseReverse
+	ErrNotCompensable
+	ErrAlreadyReversed
+	ErrRefundExceeds
+	ErrInvalidAmount
+	ErrInvalidCurrency
+	ErrCurrencyPrecision
+	ErrNoFxRate
+	ErrNoAccount
+	ErrGetBalance
+	ErrUserDailyAmountLimit
+	ErrUserDailyCountLimit
+	ErrUserWeeklyAmountLimit
+	ErrUserWeeklyCountLimit
+	ErrUserMonthlyAmountLimit
+	ErrUserMonthlyCountLimit
+	ErrAccountDailyAmountLimit
+	ErrAccountDailyCountLimit
+	ErrAccountWeeklyAmountLimit
+	ErrAccountWeeklyCountLimit
+	ErrAccountMonthlyAmountLimit
+	ErrAccountMonthlyCountLimit
+	ErrRiskRejected
+	ErrRiskReview
+	ErrReviewRequired
+	ErrInvalidSchedule
+	ErrSchedule
+	ErrGetSchedule
+	ErrNoSchedule
+	ErrScheduleClosed
+	ErrScheduleBusy
+	ErrGetStatement
+	ErrNoStatementTemplate
+	ErrExport
+	ErrNoQfxBankId
+	ErrImport
+	ErrInvalidImport
+	ErrImportTooLarge
+	ErrNothingImported
+	ErrBatch
+	ErrBatchTooLarge
+	ErrBatchRolledBack
+	ErrBatchPartial
+	ErrEmptyBatch
+	ErrWebhook
+	ErrInvalidWebhook
+	ErrGetWebhook
+	ErrNoWebhook
+	ErrNoWebhookDelivery
+	ErrWebhookDisabled
+	ErrIdempotencyStore
+	ErrAccount
+	ErrAccountOwner
+	ErrRequestTooLarge
+	ErrNotReviewer
+	ErrRecipientBalance
 )
 
 var errCodes = map[errCode]string{
-	ErrUnauthorized:        "UnAuthorized",
-	ErrTokenExpired:        "Token is expired",
-	ErrMatchingToken:       "Compared literals are not same",
-	ErrAssertClaims:        "unable to assert claims",
-	ErrAssertUserid:        "unable to assert userid",
-	ErrUnauthorizedAgent:   "UnAuthorized user agent",
-	ErrUnauthorizedUrl:     "UnAuthorized url",
-	ErrKeyNotFound:         "unable to find this Uuid",
-	ErrEncodingFile:        "unable to json encode the data",
-	ErrConvertingToPdf:     "unable to convert to pdf format",
-	ErrIdNeeded:            "id needed",
-	ErrDecodingData:        "unable to decode the data",
-	ErrCreatingAccount:     "Problem creating account",
-	ErrEmailExists:         "Email is already in use",
-	ErrCreatingSalt:        "Unable to generate salt",
-	ErrHashPassword:        "Unable to generate hashed password",
-	Success:                "SUCCESS",
-	AccActivationInProcess: "Account activation in progress",
-	ErrFetchingUser:        "Problem fetching your account",
-	AccNotFound:            "User account was not found",
-	PassDontMatch:          "Password doesnt match",
-	IncorrectPassword:      "Incorrect Password",
-	ErrGenerateJwt:         "Unable to generate jwt token",
-	ErrLogging:             "Problem logging into your account",
-	ErrReadingReqBody:      "Unable to read request body",
-	ErrUnmarshall:          "Unable to unmarshal request body",
-	ErrParseRegDate:        "Unable to parse registration date",
-	ErrValidate:            "Validation of fields failed",
-	InvalidCredentials:     "Invalid user credentials",
-	ErrDuration:            "Error parsing time duration",
-	AccActivationErr:       "Err activating account",
-	ErrPassRegex:           "failed to match password",
-	ErrPassLowerCase:       "password must contain 1 lower case character",
-	ErrPassUpperCase:       "password must contain 1 upper case character",
-	ErrPassNumeric:         "password must contain 1 numeric character",
-	ErrPassSpecial:         "password must contain 1 special character",
-	ErrExtractMsg:          "unable to extract msg",
-	ErrAccExists:           "account already exists",
-	ErrUpdatingTransaction: "error updating transaction details",
-	ErrUpdatingServices:    "error updating services",
-	ErrRedis:               "error saving cache in redis",
-	ErrNewTransaction:      "error updating new transaction",
-	ErrUuid:                "error creating new uuid",
-	ErrGetTransaction:      "error fetching transactions",
-	ErrNoTransaction:       "no transactions were found",
-	ErrDefaultPage:         "changing page to default value=1 previously it was",
-	ErrDefaultLimit:        "changing limit to default value=5 previously it was",
-	ErrFetchinDataUserSvc:  "err fetching data from user mgmt svc",
-	ErrPdf:                 "err generating pdf",
-	ErrAssertResp:          "error assert response data",
-	ErrAssertPdf:           "error assert pdf []byte",
+	ErrUnauthorized:              "UnAuthorized",
+	ErrTokenExpired:              "Token is expired",
+	ErrMatchingToken:             "Compared literals are not same",
+	ErrAssertClaims:              "unable to assert claims",
+	ErrAssertUserid:              "unable to assert userid",
+	ErrUnauthorizedAgent:         "UnAuthorized user agent",
+	ErrUnauthorizedUrl:           "UnAuthorized url",
+	ErrKeyNotFound:               "unable to find this Uuid",
+	ErrEncodingFile:              "unable to json encode the data",
+	ErrConvertingToPdf:           "unable to convert to pdf format",
+	ErrIdNeeded:                  "id needed",
+	ErrDecodingData:              "unable to decode the data",
+	ErrCreatingAccount:           "Problem creating account",
+	ErrEmailExists:               "Email is already in use",
+	ErrCreatingSalt:              "Unable to generate salt",
+	ErrHashPassword:              "Unable to generate hashed password",
+	Success:                      "SUCCESS",
+	AccActivationInProcess:       "Account activation in progress",
+	ErrFetchingUser:              "Problem fetching your account",
+	AccNotFound:                  "User account was not found",
+	PassDontMatch:                "Password doesnt match",
+	IncorrectPassword:            "Incorrect Password",
+	ErrGenerateJwt:               "Unable to generate jwt token",
+	ErrLogging:                   "Problem logging into your account",
+	ErrReadingReqBody:            "Unable to read request body",
+	ErrUnmarshall:                "Unable to unmarshal request body",
+	ErrParseRegDate:              "Unable to parse registration date",
+	ErrValidate:                  "Validation of fields failed",
+	InvalidCredentials:           "Invalid user credentials",
+	ErrDuration:                  "Error parsing time duration",
+	AccActivationErr:             "Err activating account",
+	ErrPassRegex:                 "failed to match password",
+	ErrPassLowerCase:             "password must contain 1 lower case character",
+	ErrPassUpperCase:             "password must contain 1 upper case character",
+	ErrPassNumeric:               "password must contain 1 numeric character",
+	ErrPassSpecial:               "password must contain 1 special character",
+	ErrExtractMsg:                "unable to extract msg",
+	ErrAccExists:                 "account already exists",
+	ErrUpdatingTransaction:       "error updating transaction details",
+	ErrUpdatingServices:          "error updating services",
+	ErrRedis:                     "error saving cache in redis",
+	ErrNewTransaction:            "error updating new transaction",
+	ErrUuid:                      "error creating new uuid",
+	ErrGetTransaction:            "error fetching transactions",
+	ErrNoTransaction:             "no transactions were found",
+	ErrDefaultPage:               "changing page to default value=1 previously it was",
+	ErrDefaultLimit:              "changing limit to default value=5 previously it was",
+	ErrFetchinDataUserSvc:        "err fetching data from user mgmt svc",
+	ErrPdf:                       "err generating pdf",
+	ErrAssertResp:                "error assert response data",
+	ErrAssertPdf:                 "error assert pdf []byte",
+	ErrInvalidFilter:             "invalid filter parameter",
+	ErrInvalidCursor:             "invalid pagination cursor",
+	ErrIdempotencyKey:            "Idempotency-Key must be between 1 and 255 characters",
+	ErrIdempotencyMismatch:       "Idempotency-Key was already used with a different request",
+	ErrIdempotencyInProgress:     "a request with this Idempotency-Key is still being processed",
+	ErrInvalidTransfer:           "a transfer must be a debit to a different account",
+	ErrUnknownRecipient:          "recipient account not found",
+	ErrUnbalancedTransfer:        "transfer legs do not balance",
+	ErrInvalidStatusTransition:   "transaction cannot move to the requested status",
+	ErrStatusChanged:             "transaction status was changed by another request",
+	ErrUseReverse:                "transactions are reversed through the reverse endpoint",
+	ErrNotCompensable:            "only approved transactions can be reversed or refunded, and reversals and refunds cannot themselves be",
+	ErrAlreadyReversed:           "transaction was already reversed",
+	ErrRefundExceeds:             "refund exceeds the amount left on the transaction",
+	ErrInvalidAmount:             "amount must be greater than zero",
+	ErrInvalidCurrency:           "currency must be a supported ISO 4217 code",
+	ErrCurrencyPrecision:         "amount has more decimal places than the currency allows",
+	ErrNoFxRate:                  "no exchange rate is available between the currencies",
+	ErrNoAccount:                 "no account with specified account_number was found",
+	ErrGetBalance:                "error fetching balance",
+	ErrUserDailyAmountLimit:      "debit exceeds your daily spending limit",
+	ErrUserDailyCountLimit:       "debit exceeds your daily limit on the number of debits",
+	ErrUserWeeklyAmountLimit:     "debit exceeds your weekly spending limit",
+	ErrUserWeeklyCountLimit:      "debit exceeds your weekly limit on the number of debits",
+	ErrUserMonthlyAmountLimit:    "debit exceeds your monthly spending limit",
+	ErrUserMonthlyCountLimit:     "debit exceeds your monthly limit on the number of debits",
+	ErrAccountDailyAmountLimit:   "debit exceeds the account's daily spending limit",
+	ErrAccountDailyCountLimit:    "debit exceeds the account's daily limit on the number of debits",
+	ErrAccountWeeklyAmountLimit:  "debit exceeds the account's weekly spending limit",
+	ErrAccountWeeklyCountLimit:   "debit exceeds the account's weekly limit on the number of debits",
+	ErrAccountMonthlyAmountLimit: "debit exceeds the account's monthly spending limit",
+	ErrAccountMonthlyCountLimit:  "debit exceeds the account's monthly limit on the number of debits",
+	ErrRiskRejected:              "transaction was rejected by the risk rules",
+	ErrRiskReview:                "transaction is held for review",
+	ErrReviewRequired:            "transactions are approved or rejected by a reviewer other than the sender",
+	ErrInvalidSchedule:           "invalid schedule",
+	ErrSchedule:                  "error saving schedule",
+	ErrGetSchedule:               "error fetching schedules",
+	ErrNoSchedule:                "no schedule with specified schedule_id was found",
+	ErrScheduleClosed:            "completed and cancelled schedules cannot be changed",
+	ErrScheduleBusy:              "schedule is being run, try again",
+	ErrGetStatement:              "error fetching statement",
+	ErrNoStatementTemplate:       "statements are not configured",
+	ErrExport:                    "error exporting transactions",
+	ErrNoQfxBankId:               "qfx exports are not configured",
+	ErrImport:                    "error importing transactions",
+	ErrInvalidImport:             "invalid import file",
+	ErrImportTooLarge:            "import file is too large",
+	ErrNothingImported:           "no row of the import file is valid",
+	ErrBatch:                     "error creating batch",
+	ErrBatchTooLarge:             "batch holds too many transactions",
+	ErrBatchRolledBack:           "batch was rolled back, no transaction was created",
+	ErrBatchPartial:              "some transactions of the batch were not created",
+	ErrEmptyBatch:                "batch holds no transaction",
+	ErrWebhook:                   "error saving webhook",
+	ErrInvalidWebhook:            "invalid webhook",
+	ErrGetWebhook:                "error fetching webhooks",
+	ErrNoWebhook:                 "no webhook with specified webhook_id was found",
+	ErrNoWebhookDelivery:         "no delivery with specified delivery_id was found",
+	ErrWebhookDisabled:           "webhook was disabled",
+	ErrIdempotencyStore:          "error storing idempotency key",
+	ErrAccount:                   "error registering account",
+	ErrAccountOwner:              "account is registered to another user",
+	ErrRequestTooLarge:           "request body is too large",
+	ErrNotReviewer:               "only reviewers see the review queue",
+	ErrRecipientBalance:          "recipient of the transfer does not have enough balance to give it back",
 }
 
 func GetErr(code errCode) string {
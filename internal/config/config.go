@@ -2,6 +2,8 @@ package config
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/PereRohit/util/config"
 	_ "github.com/go-sql-driver/mysql"
@@ -17,15 +19,29 @@ import (
 type Config struct {
 	ServiceRouteVersion string              `json:"service_route_version"`
 	ServerConfig        config.ServerConfig `json:"server_config"`
+	AdminAddr           string              `json:"admin_addr"`
 	DataBase            DbCfg               `json:"db_svc"`
 	SecretKey           string              `json:"secret_key"`
+	CursorSecret        string              `json:"cursor_secret"`
 	Cookie              CookieStruct        `json:"cookie"`
 	Cache               CacheCfg            `json:"cache"`
+	Idempotency         IdempotencyCfg      `json:"idempotency"`
 	AccSvcUrl           string              `json:"acc_svc_url"`
 	PdfServiceUrl       string              `json:"pdf_svc_url"`
 	UserSvcUrl          string              `json:"user_svc_url"`
 	HtmlTemplateFile    string              `json:"html_template_file_path"`
 	TemplateUuid        string              `json:"html_template_file_uuid"`
+	StatementTemplate   string              `json:"statement_template_file_path"`
+	StatementUuid       string              `json:"statement_template_file_uuid"`
+	Outbox              OutboxCfg           `json:"outbox"`
+	FxRatesFile         string              `json:"fx_rates_file"`
+	Limits              LimitsCfg           `json:"limits"`
+	RiskRulesFile       string              `json:"risk_rules_file"`
+	Scheduler           SchedulerCfg        `json:"scheduler"`
+	Ofx                 OfxCfg              `json:"ofx"`
+	Import              ImportCfg           `json:"import"`
+	Batch               BatchCfg            `json:"batch"`
+	Webhooks            WebhookCfg          `json:"webhooks"`
 }
 
 // SvcConfig struct contains the configuration for this service and other required services
@@ -77,6 +93,247 @@ type CacheCfg struct {
 	Time     time.Duration
 }
 
+// OutboxCfg struct defines the configuration for the outbox dispatcher
+type OutboxCfg struct {
+	PollIntervalStr string        `json:"poll_interval"`
+	PollInterval    time.Duration `json:"-"`
+	BaseBackoffStr  string        `json:"base_backoff"`
+	BaseBackoff     time.Duration `json:"-"`
+	MaxBackoffStr   string        `json:"max_backoff"`
+	MaxBackoff      time.Duration `json:"-"`
+	MaxAttempts     int           `json:"max_attempts"`
+	BatchSize       int           `json:"batch_size"`
+}
+
+// SchedulerCfg struct defines how often due schedules are run, and how an occurrence failing on a server error is
+// retried before it is skipped
+type SchedulerCfg struct {
+	PollIntervalStr string        `json:"poll_interval"`
+	PollInterval    time.Duration `json:"-"`
+	BaseBackoffStr  string        `json:"base_backoff"`
+	BaseBackoff     time.Duration `json:"-"`
+	MaxBackoffStr   string        `json:"max_backoff"`
+	MaxBackoff      time.Duration `json:"-"`
+	MaxAttempts     int           `json:"max_attempts"`
+	BatchSize       int           `json:"batch_size"`
+}
+
+// OfxCfg struct defines how the bank and its accounts are identified in OFX and QFX exports. QFX exports are only
+// served once IntuBid, the id Intuit assigned to the bank, is set.
+type OfxCfg struct {
+	Org     string `json:"org"`
+	Fid     string `json:"fid"`
+	BankId  string `json:"bank_id"`
+	IntuBid string `json:"intu_bid"`
+}
+
+// ImportCfg struct defines how large an import file may be and how many of its rows are inserted per database
+// transaction
+type ImportCfg struct {
+	MaxBytes  int64 `json:"max_bytes"`
+	BatchSize int   `json:"batch_size"`
+}
+
+// WebhookCfg struct defines how often due webhook deliveries are sent, how long an endpoint is given to answer and
+// how failed deliveries are retried before they are given up
+type WebhookCfg struct {
+	PollIntervalStr string        `json:"poll_interval"`
+	PollInterval    time.Duration `json:"-"`
+	TimeoutStr      string        `json:"timeout"`
+	Timeout         time.Duration `json:"-"`
+	BaseBackoffStr  string        `json:"base_backoff"`
+	BaseBackoff     time.Duration `json:"-"`
+	MaxBackoffStr   string        `json:"max_backoff"`
+	MaxBackoff      time.Duration `json:"-"`
+	MaxAttempts     int           `json:"max_attempts"`
+	BatchSize       int           `json:"batch_size"`
+}
+
+// BatchCfg struct defines how many transactions a batch may hold
+type BatchCfg struct {
+	MaxSize int `json:"max_size"`
+}
+
+// LimitRule struct defines a cap on the debits made within a calendar window, a zero cap is not enforced
+type LimitRule struct {
+	Window    string      `json:"window"`     // daily, weekly or monthly
+	MaxAmount model.Money `json:"max_amount"` // Largest total amount of debits in Currency
+	Currency  string      `json:"currency"`   // Currency of max_amount, the default currency when empty
+	MaxCount  int         `json:"max_count"`  // Largest number of debits in any currency
+}
+
+// AmountCurrency returns the currency the amount cap of the rule is in. Only debits in that currency count towards
+// it, amounts in different currencies are never compared.
+func (r LimitRule) AmountCurrency() string {
+	if r.Currency == "" {
+		return model.DefaultCurrency
+	}
+	return r.Currency
+}
+
+// LimitRules struct defines the spending limits applied across all accounts of a user and to each account separately
+type LimitRules struct {
+	User    []LimitRule `json:"user"`
+	Account []LimitRule `json:"account"`
+}
+
+// LimitsCfg struct defines the default spending limits along with the limits overriding them for some users.
+// An override replaces the default rules of each scope it lists, an empty list lifting all limits of that scope.
+type LimitsCfg struct {
+	LimitRules
+	Overrides map[string]LimitRules `json:"overrides"` // Keyed by user id
+}
+
+// For returns the spending limits applied to the user
+func (c LimitsCfg) For(userId string) LimitRules {
+	rules := c.LimitRules
+	override, ok := c.Overrides[userId]
+	if !ok {
+		return rules
+	}
+	if override.User != nil {
+		rules.User = override.User
+	}
+	if override.Account != nil {
+		rules.Account = override.Account
+	}
+	return rules
+}
+
+// validateLimitRules checks the rules of one scope name supported windows and currencies and set at least one cap.
+func validateLimitRules(scope string, rules []LimitRule) error {
+	for i, rule := range rules {
+		switch rule.Window {
+		case model.WindowDaily, model.WindowWeekly, model.WindowMonthly:
+		default:
+			return fmt.Errorf("%s limit %d: window %q must be daily, weekly or monthly", scope, i, rule.Window)
+		}
+		if _, ok := model.CurrencyExponent(rule.AmountCurrency()); !ok {
+			return fmt.Errorf("%s limit %d: unsupported currency %s", scope, i, rule.Currency)
+		}
+		if rule.MaxAmount < 0 || rule.MaxCount < 0 || (rule.MaxAmount == 0 && rule.MaxCount == 0) {
+			return fmt.Errorf("%s limit %d: max_amount or max_count must be positive", scope, i)
+		}
+	}
+	return nil
+}
+
+// ValidateCursorSecret requires a key of its own for signing pagination cursors. Cursors are signed over data clients
+// choose, so the key signing auth tokens must never be used for them.
+func ValidateCursorSecret(cfg Config) error {
+	if cfg.CursorSecret == "" {
+		return errors.New("cursor_secret is required")
+	}
+	if cfg.CursorSecret == cfg.SecretKey {
+		return errors.New("cursor_secret must differ from secret_key")
+	}
+	return nil
+}
+
+// ValidateLimits checks the default spending limits and every override.
+func ValidateLimits(cfg LimitsCfg) error {
+	all := map[string]LimitRules{"default": cfg.LimitRules}
+	for userId, rules := range cfg.Overrides {
+		all["user "+userId] = rules
+	}
+	for name, rules := range all {
+		err := validateLimitRules(name+" user", rules.User)
+		if err != nil {
+			return err
+		}
+		err = validateLimitRules(name+" account", rules.Account)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RiskRule struct defines a declarative risk rule adding its score to a new transaction it matches. Fields not used
+// by the type of the rule are ignored.
+type RiskRule struct {
+	Name      string        `json:"name"`
+	Type      string        `json:"type"`
+	Score     int           `json:"score"`
+	MinAmount model.Money   `json:"min_amount"` // amount: smallest amount matched
+	Currency  string        `json:"currency"`   // amount: currency matched, any when empty
+	FromHour  int           `json:"from_hour"`  // unusual_hour: first hour matched, in UTC
+	ToHour    int           `json:"to_hour"`    // unusual_hour: hour the range ends before, may wrap past midnight
+	WindowStr string        `json:"window"`     // rapid_repeat: how far back repeats are looked for
+	Window    time.Duration `json:"-"`
+	MaxCount  int           `json:"max_count"` // rapid_repeat: repeats allowed within the window
+}
+
+// RiskCfg struct defines the risk rules along with the total scores at which a new transaction is held for review or
+// rejected, a zero score disabling that decision. Reviewers are the users deciding on transactions held for review.
+type RiskCfg struct {
+	ReviewScore int        `json:"review_score"`
+	RejectScore int        `json:"reject_score"`
+	Reviewers   []string   `json:"reviewers"`
+	Rules       []RiskRule `json:"rules"`
+}
+
+// IsReviewer reports whether the user decides on transactions held for review
+func (c RiskCfg) IsReviewer(userId string) bool {
+	for _, reviewer := range c.Reviewers {
+		if reviewer == userId {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadRiskRules reads the risk rules from a JSON file, rejecting unknown rule types and incomplete rules.
+func LoadRiskRules(path string) (RiskCfg, error) {
+	var cfg RiskCfg
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	err = json.Unmarshal(file, &cfg)
+	if err != nil {
+		return cfg, err
+	}
+	if cfg.ReviewScore < 0 || cfg.RejectScore < 0 {
+		return cfg, fmt.Errorf("review_score and reject_score must not be negative")
+	}
+	for i := range cfg.Rules {
+		rule := &cfg.Rules[i]
+		if rule.Name == "" || rule.Score <= 0 {
+			return cfg, fmt.Errorf("rule %d: name and a positive score are required", i)
+		}
+		switch rule.Type {
+		case model.RiskAmount:
+			if rule.MinAmount <= 0 {
+				return cfg, fmt.Errorf("rule %q: min_amount must be positive", rule.Name)
+			}
+			if _, ok := model.CurrencyExponent(rule.Currency); rule.Currency != "" && !ok {
+				return cfg, fmt.Errorf("rule %q: unsupported currency %s", rule.Name, rule.Currency)
+			}
+		case model.RiskNewCounterparty:
+		case model.RiskUnusualHour:
+			if rule.FromHour < 0 || rule.FromHour > 23 || rule.ToHour < 0 || rule.ToHour > 23 || rule.FromHour == rule.ToHour {
+				return cfg, fmt.Errorf("rule %q: from_hour and to_hour must be different hours between 0 and 23", rule.Name)
+			}
+		case model.RiskRapidRepeat:
+			rule.Window, err = time.ParseDuration(rule.WindowStr)
+			if err != nil || rule.Window <= 0 || rule.MaxCount < 0 {
+				return cfg, fmt.Errorf("rule %q: window must be a positive duration and max_count not negative", rule.Name)
+			}
+		default:
+			return cfg, fmt.Errorf("rule %q: unsupported type %q", rule.Name, rule.Type)
+		}
+	}
+	return cfg, nil
+}
+
+// IdempotencyCfg struct defines how long idempotency keys are remembered and how large a body sent with one may be
+type IdempotencyCfg struct {
+	Duration string `json:"duration"`
+	MaxBytes int64  `json:"max_bytes"`
+	Time     time.Duration
+}
+
 // CacherSvc struct defines the cacher service
 type CacherSvc struct {
 	Cacher redis.Cacher
@@ -84,15 +341,24 @@ type CacherSvc struct {
 
 // PdfSvc struct defines the pdf service
 type PdfSvc struct {
-	PdfService sdk.HtmlToPdfSvcI
-	UuId       string
+	PdfService    sdk.HtmlToPdfSvcI
+	UuId          string
+	StatementUuId string // Template of account statements, statements are disabled when empty
 }
 
 // ExternalSvc struct defines the external services
 type ExternalSvc struct {
-	AccSvcUrl string
-	PdfSvc    PdfSvc
-	UserSvc   string
+	AccSvcUrl    string
+	PdfSvc       PdfSvc
+	UserSvc      string
+	CursorSecret string       // Key signing pagination cursors
+	Cacher       redis.Cacher // Cache of responses, invalidated after writes to the ledger
+	Limits       LimitsCfg    // Spending limits applied to new debits
+	Risk         RiskCfg      // Risk rules scoring new transactions
+	Scheduler    SchedulerCfg // Batching and retries of scheduled transactions
+	Ofx          OfxCfg       // Identity of the bank in OFX and QFX exports
+	Import       ImportCfg    // Limits of bulk imports
+	Batch        BatchCfg     // Limits of batches of new transactions
 }
 
 // Connect initializes and returns a database connection object.
@@ -136,11 +402,224 @@ func Connect(cfg DbCfg, tableName string) *sql.DB {
 	if err != nil {
 		panic(err.Error())
 	}
+	err = migrate(db, cfg.DbName, tableName, model.SchemaMigrations)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	// Create the outbox table kept alongside the transactions table.
+	x = fmt.Sprintf("create table if not exists %s_outbox", tableName)
+	_, err = db.Exec(x + model.OutboxSchema)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	// Create the status history table kept alongside the transactions table.
+	x = fmt.Sprintf("create table if not exists %s_status_history", tableName)
+	_, err = db.Exec(x + model.StatusHistorySchema)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	// Create the spending lock table kept alongside the transactions table.
+	x = fmt.Sprintf("create table if not exists %s_spending_locks", tableName)
+	_, err = db.Exec(x + model.SpendingLockSchema)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	// Create the risk hit table kept alongside the transactions table.
+	x = fmt.Sprintf("create table if not exists %s_risk_hits", tableName)
+	_, err = db.Exec(x + model.RiskHitSchema)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	// Create the schedule and schedule execution tables kept alongside the transactions table.
+	x = fmt.Sprintf("create table if not exists %s_schedules", tableName)
+	_, err = db.Exec(x + model.ScheduleSchema)
+	if err != nil {
+		panic(err.Error())
+	}
+	x = fmt.Sprintf("create table if not exists %s_schedule_executions", tableName)
+	_, err = db.Exec(x + model.ScheduleExecutionSchema)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	// Create the exchange rate table kept alongside the transactions table.
+	x = fmt.Sprintf("create table if not exists %s_fx_rates", tableName)
+	_, err = db.Exec(x + model.FxRateSchema)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	// Create the account table kept alongside the transactions table.
+	x = fmt.Sprintf("create table if not exists %s_accounts", tableName)
+	_, err = db.Exec(x + model.AccountSchema)
+	if err != nil {
+		panic(err.Error())
+	}
+	err = backfillAccounts(db, tableName)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	// Create the idempotency key table kept alongside the transactions table.
+	x = fmt.Sprintf("create table if not exists %s_idempotency_keys", tableName)
+	_, err = db.Exec(x + model.IdempotencySchema)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	// Create the webhook and webhook delivery tables kept alongside the transactions table.
+	x = fmt.Sprintf("create table if not exists %s_webhooks", tableName)
+	_, err = db.Exec(x + model.WebhookSchema)
+	if err != nil {
+		panic(err.Error())
+	}
+	x = fmt.Sprintf("create table if not exists %s_webhook_deliveries", tableName)
+	_, err = db.Exec(x + model.WebhookDeliverySchema)
+	if err != nil {
+		panic(err.Error())
+	}
 
 	// Return the database connection object.
 	return db
 }
 
+// backfillAccounts registers every account found in the transactions table that is not registered yet, to the user
+// and in the currency of its earliest transaction, so accounts used before accounts were registered keep their owner.
+// Registered accounts are left alone and running it again changes nothing.
+func backfillAccounts(db *sql.DB, tableName string) error {
+	query := fmt.Sprintf(`INSERT IGNORE INTO %[1]s_accounts (account_number, user_id, currency)
+		SELECT t.account_number, t.user_id, t.currency FROM %[1]s t
+		WHERE t.transaction_id = (
+			SELECT f.transaction_id FROM %[1]s f WHERE f.account_number = t.account_number
+			ORDER BY f.created_at, f.transaction_id LIMIT 1
+		)`, tableName)
+	_, err := db.Exec(query)
+	if err != nil {
+		return fmt.Errorf("backfilling %s_accounts: %w", tableName, err)
+	}
+	return nil
+}
+
+// migrate adds to a table the columns and indexes of the migrations it does not have yet, so tables created by an
+// earlier version of the service keep working. Running it again changes nothing.
+func migrate(db *sql.DB, dbName string, tableName string, migrations []model.Migration) error {
+	for _, migration := range migrations {
+		exists, err := migrationApplied(db, dbName, tableName, migration)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD %s", tableName, migration.Definition))
+		if err != nil {
+			// Another instance starting at the same time may have added it first
+			exists, checkErr := migrationApplied(db, dbName, tableName, migration)
+			if checkErr != nil || !exists {
+				return fmt.Errorf("migrating %s: %w", tableName, err)
+			}
+		}
+	}
+	return nil
+}
+
+// migrationApplied reports whether the column or index of a migration is already part of the table
+func migrationApplied(db *sql.DB, dbName string, tableName string, migration model.Migration) (bool, error) {
+	query := "SELECT COUNT(*) FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND COLUMN_NAME = ?"
+	name := migration.Column
+	if migration.Index != "" {
+		query = "SELECT COUNT(*) FROM information_schema.STATISTICS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND INDEX_NAME = ?"
+		name = migration.Index
+	}
+	var count int
+	err := db.QueryRow(query, dbName, tableName, name).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// durationOrDefault parses a configured duration, falling back to def when it is not set.
+func durationOrDefault(value string, def time.Duration) time.Duration {
+	if value == "" {
+		return def
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		panic(err.Error())
+	}
+	return duration
+}
+
+// InitOutboxCfg fills in the parsed durations of the outbox configuration, applying defaults to unset values.
+func InitOutboxCfg(cfg *OutboxCfg) {
+	cfg.PollInterval = durationOrDefault(cfg.PollIntervalStr, time.Second)
+	cfg.BaseBackoff = durationOrDefault(cfg.BaseBackoffStr, time.Second)
+	cfg.MaxBackoff = durationOrDefault(cfg.MaxBackoffStr, 5*time.Minute)
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 10
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 50
+	}
+}
+
+// InitSchedulerCfg fills in the parsed durations of the scheduler configuration, applying defaults to unset values.
+func InitSchedulerCfg(cfg *SchedulerCfg) {
+	cfg.PollInterval = durationOrDefault(cfg.PollIntervalStr, 10*time.Second)
+	cfg.BaseBackoff = durationOrDefault(cfg.BaseBackoffStr, time.Minute)
+	cfg.MaxBackoff = durationOrDefault(cfg.MaxBackoffStr, time.Hour)
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 50
+	}
+}
+
+// InitWebhookCfg fills in the parsed durations of the webhook configuration, applying defaults to unset values.
+func InitWebhookCfg(cfg *WebhookCfg) {
+	cfg.PollInterval = durationOrDefault(cfg.PollIntervalStr, time.Second)
+	cfg.Timeout = durationOrDefault(cfg.TimeoutStr, 5*time.Second)
+	cfg.BaseBackoff = durationOrDefault(cfg.BaseBackoffStr, 10*time.Second)
+	cfg.MaxBackoff = durationOrDefault(cfg.MaxBackoffStr, time.Hour)
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 8
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 50
+	}
+}
+
+// LoadFxRates reads the exchange rates listed in a JSON file, rejecting unsupported currencies and missing rates.
+func LoadFxRates(path string) ([]model.FxRate, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rates []model.FxRate
+	err = json.Unmarshal(file, &rates)
+	if err != nil {
+		return nil, err
+	}
+	for i, rate := range rates {
+		_, baseOk := model.CurrencyExponent(rate.BaseCurrency)
+		_, quoteOk := model.CurrencyExponent(rate.QuoteCurrency)
+		if !baseOk || !quoteOk || rate.BaseCurrency == rate.QuoteCurrency {
+			return nil, fmt.Errorf("rate %d: unsupported currency pair %s/%s", i, rate.BaseCurrency, rate.QuoteCurrency)
+		}
+		if rate.Rate <= 0 {
+			return nil, fmt.Errorf("rate %d: rate must be positive", i)
+		}
+	}
+	return rates, nil
+}
+
 // InitSvcConfig initializes and returns a SvcConfig struct containing
 // various service configurations and dependencies.
 // It takes in a Config struct containing configuration data.
@@ -155,6 +634,27 @@ func InitSvcConfig(cfg Config) *SvcConfig {
 		panic(err.Error())
 	}
 	cfg.Cache.Time = duration
+	cfg.Idempotency.Time = durationOrDefault(cfg.Idempotency.Duration, 24*time.Hour)
+	if cfg.Idempotency.MaxBytes <= 0 {
+		cfg.Idempotency.MaxBytes = 1 << 20
+	}
+	InitOutboxCfg(&cfg.Outbox)
+	InitSchedulerCfg(&cfg.Scheduler)
+	InitOfxCfg(&cfg.Ofx)
+	InitImportCfg(&cfg.Import)
+	InitBatchCfg(&cfg.Batch)
+	InitWebhookCfg(&cfg.Webhooks)
+	err = ValidateLimits(cfg.Limits)
+	if err != nil {
+		panic(err.Error())
+	}
+	var risk RiskCfg
+	if cfg.RiskRulesFile != "" {
+		risk, err = LoadRiskRules(cfg.RiskRulesFile)
+		if err != nil {
+			panic(err.Error())
+		}
+	}
 	pdfSvcI := sdk.NewHtmlToPdfSvc(cfg.PdfServiceUrl)
 	if cfg.TemplateUuid == "" {
 		file, err := os.ReadFile(cfg.HtmlTemplateFile)
@@ -167,10 +667,34 @@ func InitSvcConfig(cfg Config) *SvcConfig {
 		}
 		cfg.TemplateUuid = uuid
 	}
+	// The statement template is optional, statements are only served once it is registered
+	if cfg.StatementUuid == "" && cfg.StatementTemplate != "" {
+		file, err := os.ReadFile(cfg.StatementTemplate)
+		if err != nil {
+			panic(err.Error())
+		}
+		uuid, err := pdfSvcI.Register(file)
+		if err != nil {
+			panic(err.Error())
+		}
+		cfg.StatementUuid = uuid
+	}
+	err = ValidateCursorSecret(cfg)
+	if err != nil {
+		panic(err.Error())
+	}
 	utilSvc := ExternalSvc{
-		AccSvcUrl: cfg.AccSvcUrl,
-		UserSvc:   cfg.UserSvcUrl,
-		PdfSvc:    PdfSvc{PdfService: pdfSvcI, UuId: cfg.TemplateUuid},
+		AccSvcUrl:    cfg.AccSvcUrl,
+		UserSvc:      cfg.UserSvcUrl,
+		PdfSvc:       PdfSvc{PdfService: pdfSvcI, UuId: cfg.TemplateUuid, StatementUuId: cfg.StatementUuid},
+		CursorSecret: cfg.CursorSecret,
+		Cacher:       cacher,
+		Limits:       cfg.Limits,
+		Risk:         risk,
+		Scheduler:    cfg.Scheduler,
+		Ofx:          cfg.Ofx,
+		Import:       cfg.Import,
+		Batch:        cfg.Batch,
 	}
 
 	// Return the SvcConfig object containing the initialized services and configurations.
@@ -184,3 +708,30 @@ func InitSvcConfig(cfg Config) *SvcConfig {
 		ExternalService:     utilSvc,
 	}
 }
+
+// InitOfxCfg applies defaults to the unset parts of the bank's identity in OFX exports.
+func InitOfxCfg(cfg *OfxCfg) {
+	if cfg.Org == "" {
+		cfg.Org = "MicroBank"
+	}
+	if cfg.BankId == "" {
+		cfg.BankId = "000000000"
+	}
+}
+
+// InitImportCfg applies defaults to the unset limits of bulk imports.
+func InitImportCfg(cfg *ImportCfg) {
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = 10 << 20
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+}
+
+// InitBatchCfg applies defaults to the unset limits of batches of new transactions.
+func InitBatchCfg(cfg *BatchCfg) {
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = 100
+	}
+}
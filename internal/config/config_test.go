@@ -1,16 +1,21 @@
 package config
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/PereRohit/util/config"
 	"github.com/PereRohit/util/response"
 	"github.com/PereRohit/util/testutil"
 	"github.com/golang/mock/gomock"
 	"github.com/gorilla/mux"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
 	jwtSvc "github.com/vatsal278/TransactionManagementService/internal/repo/authentication"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"regexp"
 	"testing"
 	"time"
@@ -467,3 +472,593 @@ func TestInitSvcConfig(t *testing.T) {
 
 	}
 }
+
+func TestInitOutboxCfg(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       OutboxCfg
+		want      OutboxCfg
+		wantPanic bool
+	}{
+		{
+			name: "Success::defaults",
+			want: OutboxCfg{PollInterval: time.Second, BaseBackoff: time.Second, MaxBackoff: 5 * time.Minute, MaxAttempts: 10, BatchSize: 50},
+		},
+		{
+			name: "Success::configured",
+			cfg:  OutboxCfg{PollIntervalStr: "5s", BaseBackoffStr: "2s", MaxBackoffStr: "1h", MaxAttempts: 3, BatchSize: 10},
+			want: OutboxCfg{PollIntervalStr: "5s", PollInterval: 5 * time.Second, BaseBackoffStr: "2s", BaseBackoff: 2 * time.Second, MaxBackoffStr: "1h", MaxBackoff: time.Hour, MaxAttempts: 3, BatchSize: 10},
+		},
+		{
+			name:      "Failure::invalid duration",
+			cfg:       OutboxCfg{MaxBackoffStr: "abc"},
+			wantPanic: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				a := recover()
+				if (a != nil) != tt.wantPanic {
+					t.Errorf("Want: %v, Got: %v", tt.wantPanic, a)
+				}
+			}()
+			InitOutboxCfg(&tt.cfg)
+			diff := testutil.Diff(tt.cfg, tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestLoadFxRates(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		want    []model.FxRate
+		wantErr bool
+	}{
+		{
+			name:    "Success::rates",
+			content: `[{"base_currency":"USD","quote_currency":"EUR","rate":0.925,"effective_at":"2023-01-01T00:00:00Z"}]`,
+			want:    []model.FxRate{{BaseCurrency: "USD", QuoteCurrency: "EUR", Rate: 92500000, EffectiveAt: time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)}},
+		},
+		{
+			name:    "Failure::unsupported currency",
+			content: `[{"base_currency":"USD","quote_currency":"KWD","rate":0.3,"effective_at":"2023-01-01T00:00:00Z"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "Failure::same currency",
+			content: `[{"base_currency":"USD","quote_currency":"USD","rate":1,"effective_at":"2023-01-01T00:00:00Z"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "Failure::missing rate",
+			content: `[{"base_currency":"USD","quote_currency":"EUR","effective_at":"2023-01-01T00:00:00Z"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "Failure::too precise rate",
+			content: `[{"base_currency":"USD","quote_currency":"EUR","rate":0.123456789,"effective_at":"2023-01-01T00:00:00Z"}]`,
+			wantErr: true,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, fmt.Sprintf("rates%d.json", i))
+			err := os.WriteFile(path, []byte(tt.content), 0600)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadFxRates(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			diff := testutil.Diff(got, tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+	_, err := LoadFxRates(filepath.Join(dir, "missing.json"))
+	if err == nil {
+		t.Errorf("Want: %v, Got: %v", "error", err)
+	}
+}
+
+func TestBackfillAccounts(t *testing.T) {
+	backfill := regexp.QuoteMeta(`INSERT IGNORE INTO transactions_accounts (account_number, user_id, currency)
+		SELECT t.account_number, t.user_id, t.currency FROM transactions t
+		WHERE t.transaction_id = (
+			SELECT f.transaction_id FROM transactions f WHERE f.account_number = t.account_number
+			ORDER BY f.created_at, f.transaction_id LIMIT 1
+		)`)
+	tests := []struct {
+		name    string
+		setup   func(mock sqlmock.Sqlmock)
+		wantErr bool
+	}{
+		{
+			name: "Success::accounts backfilled",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(backfill).WillReturnResult(sqlmock.NewResult(0, 2))
+			},
+		},
+		{
+			name: "Failure::insert",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(backfill).WillReturnError(errors.New("error"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			tt.setup(mock)
+			err = backfillAccounts(db, "transactions")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			err = mock.ExpectationsWereMet()
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	columnQuery := regexp.QuoteMeta("SELECT COUNT(*) FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND COLUMN_NAME = ?")
+	indexQuery := regexp.QuoteMeta("SELECT COUNT(*) FROM information_schema.STATISTICS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND INDEX_NAME = ?")
+	migrations := []model.Migration{
+		{Column: "batch_id", Definition: "batch_id VARCHAR(255) NOT NULL DEFAULT ''"},
+		{Index: "idx_batch", Definition: "INDEX idx_batch (batch_id)"},
+	}
+	count := func(n int) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"count"}).AddRow(n)
+	}
+	tests := []struct {
+		name    string
+		setup   func(mock sqlmock.Sqlmock)
+		wantErr bool
+	}{
+		{
+			name: "Success::missing column and index added",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(columnQuery).WithArgs("db", "transactions", "batch_id").WillReturnRows(count(0))
+				mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE transactions ADD batch_id VARCHAR(255) NOT NULL DEFAULT ''")).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(indexQuery).WithArgs("db", "transactions", "idx_batch").WillReturnRows(count(0))
+				mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE transactions ADD INDEX idx_batch (batch_id)")).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+		},
+		{
+			name: "Success::up to date table left alone",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(columnQuery).WithArgs("db", "transactions", "batch_id").WillReturnRows(count(1))
+				mock.ExpectQuery(indexQuery).WithArgs("db", "transactions", "idx_batch").WillReturnRows(count(1))
+			},
+		},
+		{
+			name: "Success::column added concurrently",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(columnQuery).WithArgs("db", "transactions", "batch_id").WillReturnRows(count(0))
+				mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE transactions ADD batch_id")).WillReturnError(errors.New("duplicate column name"))
+				mock.ExpectQuery(columnQuery).WithArgs("db", "transactions", "batch_id").WillReturnRows(count(1))
+				mock.ExpectQuery(indexQuery).WithArgs("db", "transactions", "idx_batch").WillReturnRows(count(1))
+			},
+		},
+		{
+			name: "Failure::alter table",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(columnQuery).WithArgs("db", "transactions", "batch_id").WillReturnRows(count(0))
+				mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE transactions ADD batch_id")).WillReturnError(errors.New("error"))
+				mock.ExpectQuery(columnQuery).WithArgs("db", "transactions", "batch_id").WillReturnRows(count(0))
+			},
+			wantErr: true,
+		},
+		{
+			name: "Failure::information schema",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(columnQuery).WithArgs("db", "transactions", "batch_id").WillReturnError(errors.New("error"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			tt.setup(mock)
+			err = migrate(db, "db", "transactions", migrations)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			err = mock.ExpectationsWereMet()
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestLimitsCfg_For(t *testing.T) {
+	daily := []LimitRule{{Window: model.WindowDaily, MaxAmount: 100000}}
+	monthly := []LimitRule{{Window: model.WindowMonthly, MaxCount: 50}}
+	cfg := LimitsCfg{
+		LimitRules: LimitRules{User: daily, Account: monthly},
+		Overrides: map[string]LimitRules{
+			"vip":    {User: []LimitRule{{Window: model.WindowDaily, MaxAmount: 1000000}}},
+			"exempt": {User: []LimitRule{}, Account: []LimitRule{}},
+		},
+	}
+	tests := []struct {
+		name   string
+		userId string
+		want   LimitRules
+	}{
+		{name: "defaults", userId: "123", want: LimitRules{User: daily, Account: monthly}},
+		{name: "override of one scope", userId: "vip", want: LimitRules{User: []LimitRule{{Window: model.WindowDaily, MaxAmount: 1000000}}, Account: monthly}},
+		{name: "limits lifted", userId: "exempt", want: LimitRules{User: []LimitRule{}, Account: []LimitRule{}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := testutil.Diff(cfg.For(tt.userId), tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestValidateCursorSecret(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "own key", cfg: Config{SecretKey: "secret", CursorSecret: "cursor-secret"}},
+		{name: "missing", cfg: Config{SecretKey: "secret"}, wantErr: true},
+		{name: "same as the token key", cfg: Config{SecretKey: "secret", CursorSecret: "secret"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCursorSecret(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     LimitsCfg
+		wantErr bool
+	}{
+		{
+			name: "Success::rules",
+			cfg: LimitsCfg{
+				LimitRules: LimitRules{User: []LimitRule{{Window: model.WindowDaily, MaxAmount: 100000, MaxCount: 10}}, Account: []LimitRule{{Window: model.WindowWeekly, MaxCount: 5}}},
+				Overrides:  map[string]LimitRules{"123": {User: []LimitRule{{Window: model.WindowMonthly, MaxAmount: 1, Currency: "EUR"}}}},
+			},
+		},
+		{
+			name: "Success::no limits",
+		},
+		{
+			name:    "Failure::unknown window",
+			cfg:     LimitsCfg{LimitRules: LimitRules{User: []LimitRule{{Window: "yearly", MaxAmount: 100000}}}},
+			wantErr: true,
+		},
+		{
+			name:    "Failure::unsupported currency",
+			cfg:     LimitsCfg{LimitRules: LimitRules{User: []LimitRule{{Window: model.WindowDaily, MaxAmount: 100000, Currency: "XYZ"}}}},
+			wantErr: true,
+		},
+		{
+			name:    "Failure::no cap",
+			cfg:     LimitsCfg{LimitRules: LimitRules{Account: []LimitRule{{Window: model.WindowDaily}}}},
+			wantErr: true,
+		},
+		{
+			name:    "Failure::negative cap",
+			cfg:     LimitsCfg{LimitRules: LimitRules{User: []LimitRule{{Window: model.WindowDaily, MaxCount: -1}}}},
+			wantErr: true,
+		},
+		{
+			name:    "Failure::invalid override",
+			cfg:     LimitsCfg{Overrides: map[string]LimitRules{"123": {Account: []LimitRule{{Window: "hourly", MaxCount: 1}}}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateLimits(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLimitsCfg_JSON(t *testing.T) {
+	var got Config
+	err := json.Unmarshal([]byte(`{"limits":{"user":[{"window":"daily","max_amount":1000.50}],"overrides":{"vip":{"user":[]}}}}`), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := LimitsCfg{
+		LimitRules: LimitRules{User: []LimitRule{{Window: model.WindowDaily, MaxAmount: 100050}}},
+		Overrides:  map[string]LimitRules{"vip": {User: []LimitRule{}}},
+	}
+	diff := testutil.Diff(got.Limits, want)
+	if diff != "" {
+		t.Error(testutil.Callers(), diff)
+	}
+}
+
+func TestLoadRiskRules(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		want    RiskCfg
+		wantErr bool
+	}{
+		{
+			name: "Success::rules",
+			content: `{"review_score":50,"reject_score":100,"reviewers":["risk-1"],"rules":[
+				{"name":"large amount","type":"amount","score":60,"min_amount":1000,"currency":"USD"},
+				{"name":"new payee","type":"new_counterparty","score":30},
+				{"name":"night","type":"unusual_hour","score":20,"from_hour":23,"to_hour":5},
+				{"name":"repeats","type":"rapid_repeat","score":50,"window":"10m","max_count":2}]}`,
+			want: RiskCfg{
+				ReviewScore: 50,
+				RejectScore: 100,
+				Reviewers:   []string{"risk-1"},
+				Rules: []RiskRule{
+					{Name: "large amount", Type: model.RiskAmount, Score: 60, MinAmount: 100000, Currency: "USD"},
+					{Name: "new payee", Type: model.RiskNewCounterparty, Score: 30},
+					{Name: "night", Type: model.RiskUnusualHour, Score: 20, FromHour: 23, ToHour: 5},
+					{Name: "repeats", Type: model.RiskRapidRepeat, Score: 50, WindowStr: "10m", Window: 10 * time.Minute, MaxCount: 2},
+				},
+			},
+		},
+		{
+			name:    "Failure::unknown type",
+			content: `{"rules":[{"name":"velocity","type":"velocity","score":10}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "Failure::missing score",
+			content: `{"rules":[{"name":"new payee","type":"new_counterparty"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "Failure::amount without threshold",
+			content: `{"rules":[{"name":"large amount","type":"amount","score":10}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "Failure::amount in unsupported currency",
+			content: `{"rules":[{"name":"large amount","type":"amount","score":10,"min_amount":1,"currency":"XYZ"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "Failure::empty hour range",
+			content: `{"rules":[{"name":"night","type":"unusual_hour","score":10,"from_hour":3,"to_hour":3}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "Failure::invalid window",
+			content: `{"rules":[{"name":"repeats","type":"rapid_repeat","score":10,"window":"soon"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "Failure::negative threshold",
+			content: `{"review_score":-1}`,
+			wantErr: true,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, fmt.Sprintf("risk%d.json", i))
+			err := os.WriteFile(path, []byte(tt.content), 0600)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadRiskRules(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			diff := testutil.Diff(got, tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestRiskCfg_IsReviewer(t *testing.T) {
+	cfg := RiskCfg{Reviewers: []string{"risk-1", "risk-2"}}
+	if !cfg.IsReviewer("risk-2") {
+		t.Errorf("Want: %v, Got: %v", true, false)
+	}
+	if cfg.IsReviewer("123") {
+		t.Errorf("Want: %v, Got: %v", false, true)
+	}
+}
+
+func TestInitSchedulerCfg(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       SchedulerCfg
+		want      SchedulerCfg
+		wantPanic bool
+	}{
+		{
+			name: "Success::defaults",
+			want: SchedulerCfg{PollInterval: 10 * time.Second, BaseBackoff: time.Minute, MaxBackoff: time.Hour, MaxAttempts: 5, BatchSize: 50},
+		},
+		{
+			name: "Success::configured",
+			cfg:  SchedulerCfg{PollIntervalStr: "1m", BaseBackoffStr: "30s", MaxBackoffStr: "2h", MaxAttempts: 3, BatchSize: 10},
+			want: SchedulerCfg{PollIntervalStr: "1m", PollInterval: time.Minute, BaseBackoffStr: "30s", BaseBackoff: 30 * time.Second, MaxBackoffStr: "2h", MaxBackoff: 2 * time.Hour, MaxAttempts: 3, BatchSize: 10},
+		},
+		{
+			name:      "Failure::invalid duration",
+			cfg:       SchedulerCfg{PollIntervalStr: "abc"},
+			wantPanic: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				a := recover()
+				if (a != nil) != tt.wantPanic {
+					t.Errorf("Want: %v, Got: %v", tt.wantPanic, a)
+				}
+			}()
+			InitSchedulerCfg(&tt.cfg)
+			diff := testutil.Diff(tt.cfg, tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestInitWebhookCfg(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       WebhookCfg
+		want      WebhookCfg
+		wantPanic bool
+	}{
+		{
+			name: "Success::defaults",
+			want: WebhookCfg{PollInterval: time.Second, Timeout: 5 * time.Second, BaseBackoff: 10 * time.Second, MaxBackoff: time.Hour, MaxAttempts: 8, BatchSize: 50},
+		},
+		{
+			name: "Success::configured",
+			cfg:  WebhookCfg{PollIntervalStr: "5s", TimeoutStr: "2s", BaseBackoffStr: "1m", MaxBackoffStr: "6h", MaxAttempts: 3, BatchSize: 10},
+			want: WebhookCfg{PollIntervalStr: "5s", PollInterval: 5 * time.Second, TimeoutStr: "2s", Timeout: 2 * time.Second, BaseBackoffStr: "1m", BaseBackoff: time.Minute, MaxBackoffStr: "6h", MaxBackoff: 6 * time.Hour, MaxAttempts: 3, BatchSize: 10},
+		},
+		{
+			name:      "Failure::invalid duration",
+			cfg:       WebhookCfg{TimeoutStr: "abc"},
+			wantPanic: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				a := recover()
+				if (a != nil) != tt.wantPanic {
+					t.Errorf("Want: %v, Got: %v", tt.wantPanic, a)
+				}
+			}()
+			InitWebhookCfg(&tt.cfg)
+			diff := testutil.Diff(tt.cfg, tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestInitOfxCfg(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  OfxCfg
+		want OfxCfg
+	}{
+		{
+			name: "Success::defaults",
+			want: OfxCfg{Org: "MicroBank", BankId: "000000000"},
+		},
+		{
+			name: "Success::configured",
+			cfg:  OfxCfg{Org: "Bank", Fid: "1", BankId: "123456789", IntuBid: "3000"},
+			want: OfxCfg{Org: "Bank", Fid: "1", BankId: "123456789", IntuBid: "3000"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			InitOfxCfg(&tt.cfg)
+			diff := testutil.Diff(tt.cfg, tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestInitImportCfg(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  ImportCfg
+		want ImportCfg
+	}{
+		{
+			name: "Success::defaults",
+			want: ImportCfg{MaxBytes: 10 << 20, BatchSize: 500},
+		},
+		{
+			name: "Success::configured",
+			cfg:  ImportCfg{MaxBytes: 1024, BatchSize: 10},
+			want: ImportCfg{MaxBytes: 1024, BatchSize: 10},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			InitImportCfg(&tt.cfg)
+			diff := testutil.Diff(tt.cfg, tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestInitBatchCfg(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  BatchCfg
+		want BatchCfg
+	}{
+		{
+			name: "Success::defaults",
+			want: BatchCfg{MaxSize: 100},
+		},
+		{
+			name: "Success::configured",
+			cfg:  BatchCfg{MaxSize: 10},
+			want: BatchCfg{MaxSize: 10},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			InitBatchCfg(&tt.cfg)
+			diff := testutil.Diff(tt.cfg, tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
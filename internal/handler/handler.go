@@ -1,8 +1,10 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"github.com/PereRohit/util/log"
+	respModel "github.com/PereRohit/util/model"
 	"github.com/PereRohit/util/request"
 	"github.com/PereRohit/util/response"
 	"github.com/gorilla/mux"
@@ -10,8 +12,13 @@ import (
 	"github.com/vatsal278/TransactionManagementService/internal/config"
 	"github.com/vatsal278/TransactionManagementService/internal/model"
 	"github.com/vatsal278/TransactionManagementService/pkg/session"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/vatsal278/TransactionManagementService/internal/logic"
 	"github.com/vatsal278/TransactionManagementService/internal/repo/datasource"
@@ -19,6 +26,12 @@ import (
 
 const TransactionManagementServiceName = "transactionManagementService"
 
+// defaultListLimit and maxListLimit are the page size of a listing when none is given and the largest one served
+const (
+	defaultListLimit = 5
+	maxListLimit     = 100
+)
+
 //go:generate mockgen --build_flags=--mod=mod --destination=./../../pkg/mock/mock_handler.go --package=mock github.com/vatsal278/TransactionManagementService/internal/handler TransactionManagementServiceHandler
 
 // TransactionManagementServiceHandler defines the interface for the Transaction Management Service.
@@ -26,8 +39,30 @@ const TransactionManagementServiceName = "transactionManagementService"
 type TransactionManagementServiceHandler interface {
 	HealthChecker
 	GetTransactions(w http.ResponseWriter, r *http.Request)
+	GetTransaction(w http.ResponseWriter, r *http.Request)
 	NewTransaction(w http.ResponseWriter, r *http.Request)
 	DownloadTransaction(w http.ResponseWriter, r *http.Request)
+	UpdateTransactionStatus(w http.ResponseWriter, r *http.Request)
+	GetReviewQueue(w http.ResponseWriter, r *http.Request)
+	ReverseTransaction(w http.ResponseWriter, r *http.Request)
+	RefundTransaction(w http.ResponseWriter, r *http.Request)
+	GetBalance(w http.ResponseWriter, r *http.Request)
+	CreateSchedule(w http.ResponseWriter, r *http.Request)
+	GetSchedules(w http.ResponseWriter, r *http.Request)
+	GetSchedule(w http.ResponseWriter, r *http.Request)
+	UpdateSchedule(w http.ResponseWriter, r *http.Request)
+	CancelSchedule(w http.ResponseWriter, r *http.Request)
+	GetStatement(w http.ResponseWriter, r *http.Request)
+	ExportTransactions(w http.ResponseWriter, r *http.Request)
+	ExportAccount(w http.ResponseWriter, r *http.Request)
+	ImportTransactions(w http.ResponseWriter, r *http.Request)
+	NewBatch(w http.ResponseWriter, r *http.Request)
+	CreateWebhook(w http.ResponseWriter, r *http.Request)
+	GetWebhooks(w http.ResponseWriter, r *http.Request)
+	DeleteWebhook(w http.ResponseWriter, r *http.Request)
+	GetWebhookDeliveries(w http.ResponseWriter, r *http.Request)
+	RedeliverWebhook(w http.ResponseWriter, r *http.Request)
+	RegisterAccount(w http.ResponseWriter, r *http.Request)
 }
 
 // transactionManagementService implements TransactionManagementServiceHandler.
@@ -62,8 +97,158 @@ func (svc transactionManagementService) HealthCheck() (svcName string, msg strin
 	return
 }
 
+// transactionTypes and transactionStatuses list the values accepted by the type and status filters,
+// sortColumns the columns a listing may be sorted by.
+var (
+	transactionTypes    = map[string]bool{"credit": true, "debit": true}
+	transactionStatuses = map[string]bool{"pending": true, "approved": true, "rejected": true, "failed": true, "reversed": true, "review": true}
+	sortColumns         = map[string]bool{"created_at": true, "amount": true, "updated_at": true}
+)
+
+// parseTime parses a filter timestamp given either in RFC 3339 or as a YYYY-MM-DD date.
+// A date used as an upper bound covers the whole day.
+func parseTime(value string, endOfDay bool) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return t, nil
+	}
+	t, err = time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
+
+// positiveIntParam parses an optional positive integer query parameter, returning 0 when it is absent.
+func positiveIntParam(queryParams url.Values, param string) (int, error) {
+	v := queryParams.Get(param)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%s must be a positive integer", param)
+	}
+	return n, nil
+}
+
+// amountParam parses an optional non negative amount query parameter, returning nil when it is absent.
+func amountParam(queryParams url.Values, param string) (*model.Money, error) {
+	v := queryParams.Get(param)
+	if v == "" {
+		return nil, nil
+	}
+	amount, err := model.ParseMoney(v)
+	if err != nil || amount < 0 {
+		return nil, fmt.Errorf("%s must be a non negative amount with at most 2 decimal places", param)
+	}
+	return &amount, nil
+}
+
+// parseSort parses the sort query parameters. Each value is a comma separated list of column[:asc|desc] keys,
+// applied in the order given.
+func parseSort(values []string) ([]model.OrderBy, error) {
+	var orderBy []model.OrderBy
+	seen := map[string]bool{}
+	for _, value := range values {
+		for _, key := range strings.Split(value, ",") {
+			column, direction, _ := strings.Cut(strings.TrimSpace(key), ":")
+			if !sortColumns[column] {
+				return nil, fmt.Errorf("cannot sort by %q", column)
+			}
+			if seen[column] {
+				return nil, fmt.Errorf("sort key %q given more than once", column)
+			}
+			seen[column] = true
+			switch strings.ToLower(direction) {
+			case "", "asc":
+				orderBy = append(orderBy, model.OrderBy{Column: column})
+			case "desc":
+				orderBy = append(orderBy, model.OrderBy{Column: column, Desc: true})
+			default:
+				return nil, fmt.Errorf("sort direction %q must be asc or desc", direction)
+			}
+		}
+	}
+	return orderBy, nil
+}
+
+// parseListTransactions reads and validates the filter query parameters of the list endpoint.
+func parseListTransactions(queryParams url.Values) (model.ListTransactions, error) {
+	var list model.ListTransactions
+	if v := queryParams.Get("from"); v != "" {
+		from, err := parseTime(v, false)
+		if err != nil {
+			return list, errors.New("from must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+		}
+		list.From = &from
+	}
+	if v := queryParams.Get("to"); v != "" {
+		to, err := parseTime(v, true)
+		if err != nil {
+			return list, errors.New("to must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+		}
+		list.To = &to
+	}
+	if list.From != nil && list.To != nil && list.From.After(*list.To) {
+		return list, errors.New("from must not be after to")
+	}
+	list.Type = queryParams.Get("type")
+	if list.Type != "" && !transactionTypes[list.Type] {
+		return list, errors.New("type must be credit or debit")
+	}
+	list.Status = queryParams.Get("status")
+	if list.Status != "" && !transactionStatuses[list.Status] {
+		return list, fmt.Errorf("status %q is not supported", list.Status)
+	}
+	var err error
+	list.AccountNumber, err = positiveIntParam(queryParams, "account_number")
+	if err != nil {
+		return list, err
+	}
+	list.TransferTo, err = positiveIntParam(queryParams, "transfer_to")
+	if err != nil {
+		return list, err
+	}
+	list.MinAmount, err = amountParam(queryParams, "min_amount")
+	if err != nil {
+		return list, err
+	}
+	list.MaxAmount, err = amountParam(queryParams, "max_amount")
+	if err != nil {
+		return list, err
+	}
+	if list.MinAmount != nil && list.MaxAmount != nil && *list.MinAmount > *list.MaxAmount {
+		return list, errors.New("min_amount must not be greater than max_amount")
+	}
+	list.Comment = queryParams.Get("comment")
+	list.BatchId = queryParams.Get("batch_id")
+	list.Sort, err = parseSort(queryParams["sort"])
+	if err != nil {
+		return list, err
+	}
+	list.Keyset = queryParams.Has("cursor")
+	list.Cursor = queryParams.Get("cursor")
+	if list.Keyset && (len(list.Sort) > 1 || len(list.Sort) == 1 && list.Sort[0].Column != "created_at") {
+		return list, errors.New("cursor pagination only supports sorting by created_at")
+	}
+	if v := queryParams.Get("running_balance"); v != "" {
+		list.RunningBalance, err = strconv.ParseBool(v)
+		if err != nil {
+			return list, errors.New("running_balance must be true or false")
+		}
+	}
+	if list.RunningBalance && list.AccountNumber == 0 {
+		return list, errors.New("running_balance requires account_number")
+	}
+	return list, nil
+}
+
 // GetTransactions returns a paginated list of transactions for the user.
-// It extracts the user id from the session and retrieves the transactions using the logic layer.
+// It extracts the user id from the session, validates the filters and retrieves the transactions using the logic layer.
 func (svc transactionManagementService) GetTransactions(w http.ResponseWriter, r *http.Request) {
 	sessionStruct := session.GetSession(r.Context())
 	session, ok := sessionStruct.(model.SessionStruct)
@@ -72,17 +257,52 @@ func (svc transactionManagementService) GetTransactions(w http.ResponseWriter, r
 		return
 	}
 	queryParams := r.URL.Query()
+	list, err := parseListTransactions(queryParams)
+	if err != nil {
+		log.Error(err)
+		response.ToJson(w, http.StatusBadRequest, fmt.Sprintf("%s: %s", codes.GetErr(codes.ErrInvalidFilter), err.Error()), nil)
+		return
+	}
 	limit, err := strconv.Atoi(queryParams.Get("limit"))
-	if err != nil || limit == 0 {
-		log.Info(fmt.Sprintf("setting default limit as %d as error: %+v, query: %s", 5, err, queryParams.Get("limit")))
-		limit = 5
+	if err != nil {
+		log.Info(fmt.Sprintf("setting default limit as %d as error: %+v, query: %s", defaultListLimit, err, queryParams.Get("limit")))
+		limit = defaultListLimit
+	}
+	if limit <= 0 {
+		response.ToJson(w, http.StatusBadRequest, fmt.Sprintf("%s: %s", codes.GetErr(codes.ErrInvalidFilter), "limit must be a positive integer"), nil)
+		return
+	}
+	if limit > maxListLimit {
+		limit = maxListLimit
 	}
 	page, err := strconv.Atoi(queryParams.Get("page"))
 	if err != nil || page == 0 {
 		log.Info(fmt.Sprintf("setting default page as %d as error: %+v, query: %s", 1, err, queryParams.Get("page")))
 		page = 1
 	}
-	resp := svc.logic.GetTransactions(session.UserId, limit, page)
+	list.UserId = session.UserId
+	list.Limit = limit
+	list.Page = page
+	resp := svc.logic.GetTransactions(list)
+	response.ToJson(w, resp.Status, resp.Message, resp.Data)
+}
+
+// GetTransaction returns a single transaction of the logged-in user as json.
+func (svc transactionManagementService) GetTransaction(w http.ResponseWriter, r *http.Request) {
+	// Extract the user session from the request context.
+	sessionStruct := session.GetSession(r.Context())
+	session, ok := sessionStruct.(model.SessionStruct)
+	if !ok {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrAssertUserid), nil)
+		return
+	}
+	// Extract the transaction ID from the request parameters.
+	vars := mux.Vars(r)
+	if len(vars) == 0 {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrGetTransaction), nil)
+		return
+	}
+	resp := svc.logic.GetTransaction(vars["transaction_id"], session.UserId)
 	response.ToJson(w, resp.Status, resp.Message, resp.Data)
 }
 
@@ -110,6 +330,159 @@ func (svc transactionManagementService) NewTransaction(w http.ResponseWriter, r
 	response.ToJson(w, resp.Status, resp.Message, resp.Data)
 }
 
+// NewBatch creates several transactions for the logged-in user with one request, all or nothing in atomic mode and
+// each on its own in partial mode.
+func (svc transactionManagementService) NewBatch(w http.ResponseWriter, r *http.Request) {
+	// Extract the user session from the request context.
+	sessionStruct := session.GetSession(r.Context())
+	session, ok := sessionStruct.(model.SessionStruct)
+	if !ok {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrAssertUserid), nil)
+		return
+	}
+	// Parse the request body and validate the batch along with each of its transactions.
+	var batch model.NewBatch
+	status, err := request.FromJson(r, &batch)
+	if err != nil {
+		log.Error(err)
+		response.ToJson(w, status, err.Error(), nil)
+		return
+	}
+	if len(batch.Transactions) == 0 {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrEmptyBatch), nil)
+		return
+	}
+	batch.UserId = session.UserId
+	resp := svc.logic.NewBatch(batch)
+	response.ToJson(w, resp.Status, resp.Message, resp.Data)
+}
+
+// GetReviewQueue lists the transactions awaiting the decision of the logged-in reviewer as json.
+func (svc transactionManagementService) GetReviewQueue(w http.ResponseWriter, r *http.Request) {
+	// Extract the user session from the request context.
+	sessionStruct := session.GetSession(r.Context())
+	session, ok := sessionStruct.(model.SessionStruct)
+	if !ok {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrAssertUserid), nil)
+		return
+	}
+	resp := svc.logic.GetReviewQueue(session.UserId)
+	response.ToJson(w, resp.Status, resp.Message, resp.Data)
+}
+
+// UpdateTransactionStatus moves a transaction of the logged-in user to the status given in the request body.
+func (svc transactionManagementService) UpdateTransactionStatus(w http.ResponseWriter, r *http.Request) {
+	// Extract the user session from the request context.
+	sessionStruct := session.GetSession(r.Context())
+	session, ok := sessionStruct.(model.SessionStruct)
+	if !ok {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrAssertUserid), nil)
+		return
+	}
+	// Extract the transaction ID from the request parameters.
+	vars := mux.Vars(r)
+	if len(vars) == 0 {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrGetTransaction), nil)
+		return
+	}
+	// Parse the request body and validate the data.
+	var update model.UpdateStatus
+	status, err := request.FromJson(r, &update)
+	if err != nil {
+		log.Error(err)
+		response.ToJson(w, status, err.Error(), nil)
+		return
+	}
+	resp := svc.logic.UpdateStatus(vars["transaction_id"], session.UserId, update)
+	response.ToJson(w, resp.Status, resp.Message, resp.Data)
+}
+
+// ReverseTransaction gives back what is left of a transaction of the logged-in user through a compensating transaction.
+func (svc transactionManagementService) ReverseTransaction(w http.ResponseWriter, r *http.Request) {
+	// Extract the user session from the request context.
+	sessionStruct := session.GetSession(r.Context())
+	session, ok := sessionStruct.(model.SessionStruct)
+	if !ok {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrAssertUserid), nil)
+		return
+	}
+	// Extract the transaction ID from the request parameters.
+	vars := mux.Vars(r)
+	if len(vars) == 0 {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrGetTransaction), nil)
+		return
+	}
+	// Parse the request body and validate the data.
+	var reversal model.Reversal
+	status, err := request.FromJson(r, &reversal)
+	if err != nil {
+		log.Error(err)
+		response.ToJson(w, status, err.Error(), nil)
+		return
+	}
+	resp := svc.logic.Reverse(vars["transaction_id"], session.UserId, reversal)
+	response.ToJson(w, resp.Status, resp.Message, resp.Data)
+}
+
+// RefundTransaction gives back part of a transaction of the logged-in user through a compensating transaction.
+func (svc transactionManagementService) RefundTransaction(w http.ResponseWriter, r *http.Request) {
+	// Extract the user session from the request context.
+	sessionStruct := session.GetSession(r.Context())
+	session, ok := sessionStruct.(model.SessionStruct)
+	if !ok {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrAssertUserid), nil)
+		return
+	}
+	// Extract the transaction ID from the request parameters.
+	vars := mux.Vars(r)
+	if len(vars) == 0 {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrGetTransaction), nil)
+		return
+	}
+	// Parse the request body and validate the data.
+	var refund model.Refund
+	status, err := request.FromJson(r, &refund)
+	if err != nil {
+		log.Error(err)
+		response.ToJson(w, status, err.Error(), nil)
+		return
+	}
+	if refund.Amount <= 0 {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrInvalidAmount), nil)
+		return
+	}
+	resp := svc.logic.Refund(vars["transaction_id"], session.UserId, refund)
+	response.ToJson(w, resp.Status, resp.Message, resp.Data)
+}
+
+// GetBalance returns the balance of an account of the logged-in user, optionally as of a past time.
+func (svc transactionManagementService) GetBalance(w http.ResponseWriter, r *http.Request) {
+	// Extract the user session from the request context.
+	sessionStruct := session.GetSession(r.Context())
+	session, ok := sessionStruct.(model.SessionStruct)
+	if !ok {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrAssertUserid), nil)
+		return
+	}
+	// Extract the account number from the request parameters.
+	accountNumber, err := strconv.Atoi(mux.Vars(r)["account_number"])
+	if err != nil || accountNumber <= 0 {
+		response.ToJson(w, http.StatusBadRequest, fmt.Sprintf("%s: %s", codes.GetErr(codes.ErrInvalidFilter), "account_number must be a positive integer"), nil)
+		return
+	}
+	var asOf *time.Time
+	if v := r.URL.Query().Get("as_of"); v != "" {
+		t, err := parseTime(v, true)
+		if err != nil {
+			response.ToJson(w, http.StatusBadRequest, fmt.Sprintf("%s: %s", codes.GetErr(codes.ErrInvalidFilter), "as_of must be an RFC 3339 timestamp or a YYYY-MM-DD date"), nil)
+			return
+		}
+		asOf = &t
+	}
+	resp := svc.logic.GetBalance(accountNumber, session.UserId, asOf)
+	response.ToJson(w, resp.Status, resp.Message, resp.Data)
+}
+
 // DownloadTransaction downloads a PDF file for a specific transaction ID belonging to the logged-in user.
 func (svc transactionManagementService) DownloadTransaction(w http.ResponseWriter, r *http.Request) {
 	// Extract the user session from the request context.
@@ -125,8 +498,8 @@ func (svc transactionManagementService) DownloadTransaction(w http.ResponseWrite
 		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrGetTransaction), nil)
 		return
 	}
-	// Download the PDF file for the given transaction ID and user session.
-	resp := svc.logic.DownloadTransaction(vars["transaction_id"], session.Cookie)
+	// Download the PDF file for the given transaction ID, scoped to the user of the session.
+	resp := svc.logic.DownloadTransaction(vars["transaction_id"], session.UserId, session.Cookie)
 	if resp.Status != http.StatusOK {
 		response.ToJson(w, resp.Status, resp.Message, resp.Data)
 		return
@@ -147,3 +520,489 @@ func (svc transactionManagementService) DownloadTransaction(w http.ResponseWrite
 	w.Header().Set("Content-Disposition", "attachment; filename="+vars["transaction_id"]+".pdf")
 	w.Header().Set("Content-Type", "application/pdf")
 }
+
+// CreateSchedule creates a one-off future-dated or recurring transaction for the logged-in user using the data from
+// the request body.
+func (svc transactionManagementService) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	// Extract the user session from the request context.
+	sessionStruct := session.GetSession(r.Context())
+	session, ok := sessionStruct.(model.SessionStruct)
+	if !ok {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrAssertUserid), nil)
+		return
+	}
+	// Parse the request body and validate the data.
+	var newSchedule model.NewSchedule
+	status, err := request.FromJson(r, &newSchedule)
+	if err != nil {
+		log.Error(err)
+		response.ToJson(w, status, err.Error(), nil)
+		return
+	}
+	newSchedule.UserId = session.UserId
+	resp := svc.logic.CreateSchedule(newSchedule)
+	response.ToJson(w, resp.Status, resp.Message, resp.Data)
+}
+
+// GetSchedules returns the schedules of the logged-in user as json.
+func (svc transactionManagementService) GetSchedules(w http.ResponseWriter, r *http.Request) {
+	// Extract the user session from the request context.
+	sessionStruct := session.GetSession(r.Context())
+	session, ok := sessionStruct.(model.SessionStruct)
+	if !ok {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrAssertUserid), nil)
+		return
+	}
+	resp := svc.logic.GetSchedules(session.UserId)
+	response.ToJson(w, resp.Status, resp.Message, resp.Data)
+}
+
+// GetSchedule returns a schedule of the logged-in user along with its latest executions as json.
+func (svc transactionManagementService) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	// Extract the user session from the request context.
+	sessionStruct := session.GetSession(r.Context())
+	session, ok := sessionStruct.(model.SessionStruct)
+	if !ok {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrAssertUserid), nil)
+		return
+	}
+	// Extract the schedule ID from the request parameters.
+	vars := mux.Vars(r)
+	if len(vars) == 0 {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrGetSchedule), nil)
+		return
+	}
+	resp := svc.logic.GetSchedule(vars["schedule_id"], session.UserId)
+	response.ToJson(w, resp.Status, resp.Message, resp.Data)
+}
+
+// UpdateSchedule replaces a schedule of the logged-in user with the one in the request body, which also pauses and
+// resumes it.
+func (svc transactionManagementService) UpdateSchedule(w http.ResponseWriter, r *http.Request) {
+	// Extract the user session from the request context.
+	sessionStruct := session.GetSession(r.Context())
+	session, ok := sessionStruct.(model.SessionStruct)
+	if !ok {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrAssertUserid), nil)
+		return
+	}
+	// Extract the schedule ID from the request parameters.
+	vars := mux.Vars(r)
+	if len(vars) == 0 {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrGetSchedule), nil)
+		return
+	}
+	// Parse the request body and validate the data.
+	var newSchedule model.NewSchedule
+	status, err := request.FromJson(r, &newSchedule)
+	if err != nil {
+		log.Error(err)
+		response.ToJson(w, status, err.Error(), nil)
+		return
+	}
+	resp := svc.logic.UpdateSchedule(vars["schedule_id"], session.UserId, newSchedule)
+	response.ToJson(w, resp.Status, resp.Message, resp.Data)
+}
+
+// CancelSchedule cancels a schedule of the logged-in user.
+func (svc transactionManagementService) CancelSchedule(w http.ResponseWriter, r *http.Request) {
+	// Extract the user session from the request context.
+	sessionStruct := session.GetSession(r.Context())
+	session, ok := sessionStruct.(model.SessionStruct)
+	if !ok {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrAssertUserid), nil)
+		return
+	}
+	// Extract the schedule ID from the request parameters.
+	vars := mux.Vars(r)
+	if len(vars) == 0 {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrGetSchedule), nil)
+		return
+	}
+	resp := svc.logic.CancelSchedule(vars["schedule_id"], session.UserId)
+	response.ToJson(w, resp.Status, resp.Message, resp.Data)
+}
+
+// CreateWebhook registers an endpoint of the logged-in user, or of one of the user's client apps, for the events
+// in the request body. The secret signing its deliveries is only returned here.
+func (svc transactionManagementService) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	// Extract the user session from the request context.
+	sessionStruct := session.GetSession(r.Context())
+	session, ok := sessionStruct.(model.SessionStruct)
+	if !ok {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrAssertUserid), nil)
+		return
+	}
+	// Parse the request body and validate the data.
+	var newWebhook model.NewWebhook
+	status, err := request.FromJson(r, &newWebhook)
+	if err != nil {
+		log.Error(err)
+		response.ToJson(w, status, err.Error(), nil)
+		return
+	}
+	newWebhook.UserId = session.UserId
+	resp := svc.logic.CreateWebhook(newWebhook)
+	response.ToJson(w, resp.Status, resp.Message, resp.Data)
+}
+
+// GetWebhooks returns the webhooks of the logged-in user as json.
+func (svc transactionManagementService) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	// Extract the user session from the request context.
+	sessionStruct := session.GetSession(r.Context())
+	session, ok := sessionStruct.(model.SessionStruct)
+	if !ok {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrAssertUserid), nil)
+		return
+	}
+	resp := svc.logic.GetWebhooks(session.UserId)
+	response.ToJson(w, resp.Status, resp.Message, resp.Data)
+}
+
+// DeleteWebhook disables a webhook of the logged-in user.
+func (svc transactionManagementService) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	// Extract the user session from the request context.
+	sessionStruct := session.GetSession(r.Context())
+	session, ok := sessionStruct.(model.SessionStruct)
+	if !ok {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrAssertUserid), nil)
+		return
+	}
+	// Extract the webhook ID from the request parameters.
+	vars := mux.Vars(r)
+	if len(vars) == 0 {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrGetWebhook), nil)
+		return
+	}
+	resp := svc.logic.DeleteWebhook(vars["webhook_id"], session.UserId)
+	response.ToJson(w, resp.Status, resp.Message, resp.Data)
+}
+
+// GetWebhookDeliveries returns the latest deliveries of a webhook of the logged-in user as json.
+func (svc transactionManagementService) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	// Extract the user session from the request context.
+	sessionStruct := session.GetSession(r.Context())
+	session, ok := sessionStruct.(model.SessionStruct)
+	if !ok {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrAssertUserid), nil)
+		return
+	}
+	// Extract the webhook ID from the request parameters.
+	vars := mux.Vars(r)
+	if len(vars) == 0 {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrGetWebhook), nil)
+		return
+	}
+	resp := svc.logic.GetWebhookDeliveries(vars["webhook_id"], session.UserId)
+	response.ToJson(w, resp.Status, resp.Message, resp.Data)
+}
+
+// RedeliverWebhook sends a delivery of a webhook of the logged-in user again.
+func (svc transactionManagementService) RedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	// Extract the user session from the request context.
+	sessionStruct := session.GetSession(r.Context())
+	session, ok := sessionStruct.(model.SessionStruct)
+	if !ok {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrAssertUserid), nil)
+		return
+	}
+	// Extract the webhook and delivery IDs from the request parameters.
+	vars := mux.Vars(r)
+	if len(vars) == 0 {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrGetWebhook), nil)
+		return
+	}
+	resp := svc.logic.RedeliverWebhook(vars["webhook_id"], vars["delivery_id"], session.UserId)
+	response.ToJson(w, resp.Status, resp.Message, resp.Data)
+}
+
+// GetStatement downloads the PDF statement of an account of the logged-in user for a calendar month.
+func (svc transactionManagementService) GetStatement(w http.ResponseWriter, r *http.Request) {
+	// Extract the user session from the request context.
+	sessionStruct := session.GetSession(r.Context())
+	session, ok := sessionStruct.(model.SessionStruct)
+	if !ok {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrAssertUserid), nil)
+		return
+	}
+	// Extract the account number and the month from the query parameters.
+	queryParams := r.URL.Query()
+	accountNumber, err := strconv.Atoi(queryParams.Get("account_number"))
+	if err != nil || accountNumber <= 0 {
+		response.ToJson(w, http.StatusBadRequest, fmt.Sprintf("%s: %s", codes.GetErr(codes.ErrInvalidFilter), "account_number must be a positive integer"), nil)
+		return
+	}
+	month, err := time.Parse("2006-01", queryParams.Get("month"))
+	if err != nil {
+		response.ToJson(w, http.StatusBadRequest, fmt.Sprintf("%s: %s", codes.GetErr(codes.ErrInvalidFilter), "month must be formatted as YYYY-MM"), nil)
+		return
+	}
+	if month.After(time.Now()) {
+		response.ToJson(w, http.StatusBadRequest, fmt.Sprintf("%s: %s", codes.GetErr(codes.ErrInvalidFilter), "month must not be in the future"), nil)
+		return
+	}
+	resp := svc.logic.GetStatement(accountNumber, session.UserId, month, session.Cookie)
+	if resp.Status != http.StatusOK {
+		response.ToJson(w, resp.Status, resp.Message, resp.Data)
+		return
+	}
+	pdf, ok := resp.Data.([]byte)
+	if !ok {
+		response.ToJson(w, http.StatusInternalServerError, codes.GetErr(codes.ErrAssertPdf), nil)
+		return
+	}
+	// Set the headers for the PDF file download before writing it.
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=statement-%d-%s.pdf", accountNumber, month.Format("2006-01")))
+	w.Header().Set("Content-Type", "application/pdf")
+	_, err = w.Write(pdf)
+	if err != nil {
+		log.Error(err)
+	}
+}
+
+// fileDownload sets the headers of a file download on the first write, so an export failing before it writes
+// anything can still be answered with a json error.
+type fileDownload struct {
+	http.ResponseWriter
+	contentType string
+	filename    string
+	written     bool
+}
+
+// Write sets the download headers before writing the first bytes of the file
+func (d *fileDownload) Write(p []byte) (int, error) {
+	if !d.written {
+		d.written = true
+		d.Header().Set("Content-Type", d.contentType)
+		d.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", d.filename))
+		d.WriteHeader(http.StatusOK)
+	}
+	return d.ResponseWriter.Write(p)
+}
+
+// ExportTransactions downloads the user's transactions as a CSV file.
+// It accepts the filters and sort keys of the list endpoint along with the columns to write and the date format,
+// and streams the rows into the response as the logic layer reads them.
+func (svc transactionManagementService) ExportTransactions(w http.ResponseWriter, r *http.Request) {
+	sessionStruct := session.GetSession(r.Context())
+	session, ok := sessionStruct.(model.SessionStruct)
+	if !ok {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrAssertUserid), nil)
+		return
+	}
+	queryParams := r.URL.Query()
+	list, err := parseListTransactions(queryParams)
+	if err == nil && list.Keyset {
+		err = errors.New("cursor is not supported by exports")
+	}
+	if err == nil && list.RunningBalance {
+		err = errors.New("running_balance is not supported by exports")
+	}
+	if err != nil {
+		log.Error(err)
+		response.ToJson(w, http.StatusBadRequest, fmt.Sprintf("%s: %s", codes.GetErr(codes.ErrInvalidFilter), err.Error()), nil)
+		return
+	}
+	list.UserId = session.UserId
+	var export model.CsvExport
+	if v := queryParams.Get("columns"); v != "" {
+		export.Columns = strings.Split(v, ",")
+	}
+	export.DateFormat = queryParams.Get("date_format")
+	download := &fileDownload{
+		ResponseWriter: w,
+		contentType:    "text/csv; charset=utf-8",
+		filename:       fmt.Sprintf("transactions-%s.csv", time.Now().UTC().Format("2006-01-02")),
+	}
+	resp := svc.logic.ExportTransactions(list, export, download)
+	download.finish(resp)
+}
+
+// finish answers with the error of a failed export when nothing was sent yet. Once the file is partly sent the error
+// can only be logged, and the download is left truncated.
+func (d *fileDownload) finish(resp *respModel.Response) {
+	if resp.Status == http.StatusOK {
+		return
+	}
+	if d.written {
+		log.Error(resp.Message)
+		return
+	}
+	response.ToJson(d.ResponseWriter, resp.Status, resp.Message, resp.Data)
+}
+
+// accountExportFiles are the content types and file extensions of the account files exported for personal finance
+// software and ERPs
+var accountExportFiles = map[string]struct {
+	contentType string
+	extension   string
+}{
+	model.ExportOfx:     {contentType: "application/x-ofx", extension: "ofx"},
+	model.ExportQfx:     {contentType: "application/vnd.intu.qfx", extension: "qfx"},
+	model.ExportQif:     {contentType: "application/qif", extension: "qif"},
+	model.ExportCamt053: {contentType: "application/xml", extension: "xml"},
+	model.ExportMt940:   {contentType: "text/plain", extension: "sta"},
+}
+
+// ExportAccount downloads the settled transactions of one of the user's accounts over a date range as an OFX, QFX,
+// QIF, camt.053 or MT940 file, the format being taken from the extension of the path.
+func (svc transactionManagementService) ExportAccount(w http.ResponseWriter, r *http.Request) {
+	sessionStruct := session.GetSession(r.Context())
+	session, ok := sessionStruct.(model.SessionStruct)
+	if !ok {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrAssertUserid), nil)
+		return
+	}
+	format := mux.Vars(r)["format"]
+	exportFile, ok := accountExportFiles[format]
+	if !ok {
+		response.ToJson(w, http.StatusBadRequest, fmt.Sprintf("%s: format %q is not supported", codes.GetErr(codes.ErrInvalidFilter), format), nil)
+		return
+	}
+	export, err := parseAccountExport(r.URL.Query())
+	if err != nil {
+		log.Error(err)
+		response.ToJson(w, http.StatusBadRequest, fmt.Sprintf("%s: %s", codes.GetErr(codes.ErrInvalidFilter), err.Error()), nil)
+		return
+	}
+	export.UserId = session.UserId
+	export.Format = format
+	download := &fileDownload{
+		ResponseWriter: w,
+		contentType:    exportFile.contentType,
+		filename:       fmt.Sprintf("transactions-%d-%s-%s.%s", export.AccountNumber, export.From.Format("20060102"), export.To.Format("20060102"), exportFile.extension),
+	}
+	resp := svc.logic.ExportAccount(export, download)
+	download.finish(resp)
+}
+
+// parseAccountExport reads and validates the account, date range and currency of an account export.
+func parseAccountExport(queryParams url.Values) (model.AccountExport, error) {
+	var export model.AccountExport
+	var err error
+	export.AccountNumber, err = positiveIntParam(queryParams, "account_number")
+	if err != nil {
+		return export, err
+	}
+	if export.AccountNumber == 0 {
+		return export, errors.New("account_number is required")
+	}
+	export.From, err = parseTime(queryParams.Get("from"), false)
+	if err != nil {
+		return export, errors.New("from must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+	}
+	export.To, err = parseTime(queryParams.Get("to"), true)
+	if err != nil {
+		return export, errors.New("to must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+	}
+	if export.From.After(export.To) {
+		return export, errors.New("from must not be after to")
+	}
+	export.Currency = strings.ToUpper(queryParams.Get("currency"))
+	return export, nil
+}
+
+// ImportTransactions imports the history of the logged-in user's transactions from a CSV or OFX file, sent as the
+// request body or as the part named file of a multipart form, and responds with a report of the rows.
+func (svc transactionManagementService) ImportTransactions(w http.ResponseWriter, r *http.Request) {
+	sessionStruct := session.GetSession(r.Context())
+	session, ok := sessionStruct.(model.SessionStruct)
+	if !ok {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrAssertUserid), nil)
+		return
+	}
+	imp, err := parseImportTransactions(r.URL.Query())
+	if err != nil {
+		log.Error(err)
+		response.ToJson(w, http.StatusBadRequest, fmt.Sprintf("%s: %s", codes.GetErr(codes.ErrInvalidImport), err.Error()), nil)
+		return
+	}
+	imp.UserId = session.UserId
+	var file io.Reader = r.Body
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		file, err = uploadedFile(r)
+		if err != nil {
+			log.Error(err)
+			response.ToJson(w, http.StatusBadRequest, fmt.Sprintf("%s: %s", codes.GetErr(codes.ErrInvalidImport), err.Error()), nil)
+			return
+		}
+	}
+	resp := svc.logic.ImportTransactions(imp, file)
+	response.ToJson(w, resp.Status, resp.Message, resp.Data)
+}
+
+// uploadedFile returns the part named file of a multipart form, read as it is streamed
+func uploadedFile(r *http.Request) (io.Reader, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, errors.New("the form holds no file")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == "file" {
+			return part, nil
+		}
+	}
+}
+
+// parseImportTransactions reads the format, account, CSV layout and dry run flag of an import. Each column parameter
+// maps a field to the header of the CSV column holding it, as field:header.
+func parseImportTransactions(queryParams url.Values) (model.ImportTransactions, error) {
+	imp := model.ImportTransactions{
+		Format:     strings.ToLower(queryParams.Get("format")),
+		DateFormat: queryParams.Get("date_format"),
+	}
+	if imp.Format == "" {
+		imp.Format = model.ImportCsv
+	}
+	var err error
+	imp.AccountNumber, err = positiveIntParam(queryParams, "account_number")
+	if err != nil {
+		return imp, err
+	}
+	if v := queryParams.Get("dry_run"); v != "" {
+		imp.DryRun, err = strconv.ParseBool(v)
+		if err != nil {
+			return imp, errors.New("dry_run must be true or false")
+		}
+	}
+	for _, column := range queryParams["column"] {
+		field, header, ok := strings.Cut(column, ":")
+		if !ok || field == "" || header == "" {
+			return imp, fmt.Errorf("column %q must be given as field:header", column)
+		}
+		if imp.Columns == nil {
+			imp.Columns = map[string]string{}
+		}
+		imp.Columns[field] = header
+	}
+	return imp, nil
+}
+
+// RegisterAccount records the owner of an account, on behalf of the account service. It is only served on the admin
+// address.
+func (svc transactionManagementService) RegisterAccount(w http.ResponseWriter, r *http.Request) {
+	// Extract the account number from the request parameters.
+	accountNumber, err := strconv.Atoi(mux.Vars(r)["account_number"])
+	if err != nil || accountNumber <= 0 {
+		response.ToJson(w, http.StatusBadRequest, fmt.Sprintf("%s: %s", codes.GetErr(codes.ErrInvalidFilter), "account_number must be a positive integer"), nil)
+		return
+	}
+	// Parse the request body and validate the data.
+	var newAccount model.NewAccount
+	status, err := request.FromJson(r, &newAccount)
+	if err != nil {
+		log.Error(err)
+		response.ToJson(w, status, err.Error(), nil)
+		return
+	}
+	newAccount.AccountNumber = accountNumber
+	resp := svc.logic.RegisterAccount(newAccount)
+	response.ToJson(w, resp.Status, resp.Message, resp.Data)
+}
@@ -4,18 +4,22 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	respModel "github.com/PereRohit/util/model"
 	"github.com/gorilla/mux"
 	"github.com/vatsal278/TransactionManagementService/internal/codes"
 	"github.com/vatsal278/TransactionManagementService/internal/config"
 	"github.com/vatsal278/TransactionManagementService/internal/model"
 	"github.com/vatsal278/TransactionManagementService/pkg/session"
+	"io"
 	"io/ioutil"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/PereRohit/util/testutil"
 	"github.com/golang/mock/gomock"
@@ -141,9 +145,9 @@ func TestTransactionManagementService_NewTransaction(t *testing.T) {
 				mockLogic.EXPECT().NewTransaction(model.NewTransaction{
 					UserId:        "1234",
 					AccountNumber: 1,
-					Amount:        1000,
+					Amount:        1000 * model.MajorUnit,
 					TransferTo:    2,
-					Status:        "approved",
+					Status:        "pending",
 					Type:          "debit",
 					Comment:       "shopping",
 				}).Times(1).Return(&respModel.Response{
@@ -156,9 +160,9 @@ func TestTransactionManagementService_NewTransaction(t *testing.T) {
 				}
 				by, err := json.Marshal(model.NewTransaction{
 					AccountNumber: 1,
-					Amount:        1000,
+					Amount:        1000 * model.MajorUnit,
 					TransferTo:    2,
-					Status:        "approved",
+					Status:        "pending",
 					Type:          "debit",
 					Comment:       "shopping",
 				})
@@ -192,6 +196,23 @@ func TestTransactionManagementService_NewTransaction(t *testing.T) {
 
 			},
 		},
+		{
+			name: "Failure :: NewTransaction:: approved status requested",
+			setup: func() (*transactionManagementService, *http.Request) {
+				// New transactions start pending, so the logic is never reached
+				svc := &transactionManagementService{
+					logic: mock.NewMockTransactionManagementServiceLogicIer(mockCtrl),
+				}
+				r := httptest.NewRequest("POST", "/transactions/new", bytes.NewBufferString(`{"account_number":1,"amount":10,"status":"approved","type":"debit"}`))
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if rec.Code != http.StatusBadRequest {
+					t.Errorf("Want: %v, Got: %v", http.StatusBadRequest, rec.Code)
+				}
+			},
+		},
 		{
 			name: "Failure :: NewTransaction:: Failure assert user_id",
 			setup: func() (*transactionManagementService, *http.Request) {
@@ -312,7 +333,7 @@ func TestTransactionManagementService_GetTransactions(t *testing.T) {
 			name: "Success::GetTransaction",
 			setup: func() (*transactionManagementService, *http.Request) {
 				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
-				mockLogic.EXPECT().GetTransactions("1234", 2, 2).Times(1).Return(&respModel.Response{
+				mockLogic.EXPECT().GetTransactions(model.ListTransactions{UserId: "1234", Limit: 2, Page: 2}).Times(1).Return(&respModel.Response{
 					Status:  http.StatusOK,
 					Message: codes.GetErr(codes.Success),
 					Data: model.PaginatedResponse{Response: []model.Transaction{{Amount: 1000, AccountNumber: 1}}, Pagination: model.Paginate{
@@ -364,7 +385,7 @@ func TestTransactionManagementService_GetTransactions(t *testing.T) {
 			name: "Success::GetTransaction:: default limit and page",
 			setup: func() (*transactionManagementService, *http.Request) {
 				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
-				mockLogic.EXPECT().GetTransactions("1234", 5, 1).Times(1).Return(&respModel.Response{
+				mockLogic.EXPECT().GetTransactions(model.ListTransactions{UserId: "1234", Limit: 5, Page: 1}).Times(1).Return(&respModel.Response{
 					Status:  http.StatusOK,
 					Message: codes.GetErr(codes.Success),
 					Data: model.PaginatedResponse{Response: []model.Transaction{{Amount: 1000, AccountNumber: 1}}, Pagination: model.Paginate{
@@ -410,6 +431,44 @@ func TestTransactionManagementService_GetTransactions(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "Success::GetTransaction:: limit capped",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				mockLogic.EXPECT().GetTransactions(model.ListTransactions{UserId: "1234", Limit: maxListLimit, Page: 1, Keyset: true}).Times(1).Return(&respModel.Response{
+					Status:  http.StatusOK,
+					Message: codes.GetErr(codes.Success),
+				})
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("GET", "/transactions?cursor=&limit=1000000", nil)
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if !reflect.DeepEqual(rec.Code, http.StatusOK) {
+					t.Errorf("Want: %v, Got: %v", http.StatusOK, rec.Code)
+				}
+			},
+		},
+		{
+			name: "Failure::GetTransaction:: negative limit",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("GET", "/transactions?cursor=&limit=-1", nil)
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if !reflect.DeepEqual(rec.Code, http.StatusBadRequest) {
+					t.Errorf("Want: %v, Got: %v", http.StatusBadRequest, rec.Code)
+				}
+			},
+		},
 		{
 			name: "Failure::GetTransaction:: logic-internal server error",
 			setup: func() (*transactionManagementService, *http.Request) {
@@ -474,110 +533,155 @@ func TestTransactionManagementService_GetTransactions(t *testing.T) {
 				}
 			},
 		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			w := httptest.NewRecorder()
-			x, r := tt.setup()
-			x.GetTransactions(w, r)
-			tt.want(*w)
-		})
-	}
-}
-func TestTransactionManagementService_DownloadTransaction(t *testing.T) {
-	mockCtrl := gomock.NewController(t)
-	defer mockCtrl.Finish()
-
-	tests := []struct {
-		name           string
-		model          model.Transaction
-		hijackedWriter bool
-		setup          func() (*transactionManagementService, *http.Request)
-		want           func(recorder httptest.ResponseRecorder)
-	}{
 		{
-			name: "Success",
+			name: "Success::GetTransaction:: filters",
 			setup: func() (*transactionManagementService, *http.Request) {
+				from := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+				to := time.Date(2023, time.January, 31, 0, 0, 0, 0, time.UTC).Add(24*time.Hour - time.Nanosecond)
+				minAmount, maxAmount := model.Money(1050), 100*model.MajorUnit
 				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
-				mockLogic.EXPECT().DownloadTransaction("123", "456").Times(1).Return(&respModel.Response{
+				mockLogic.EXPECT().GetTransactions(model.ListTransactions{
+					UserId:        "1234",
+					Limit:         5,
+					Page:          1,
+					From:          &from,
+					To:            &to,
+					Type:          "debit",
+					Status:        "approved",
+					AccountNumber: 1,
+					TransferTo:    2,
+					MinAmount:     &minAmount,
+					MaxAmount:     &maxAmount,
+					Comment:       "rent",
+					BatchId:       "b1",
+					Sort:          []model.OrderBy{{Column: "amount", Desc: true}, {Column: "created_at"}},
+				}).Times(1).Return(&respModel.Response{
 					Status:  http.StatusOK,
 					Message: codes.GetErr(codes.Success),
-					Data:    []byte("PDF"),
+					Data:    model.PaginatedResponse{},
 				})
 				svc := &transactionManagementService{
 					logic: mockLogic,
 				}
-				r := httptest.NewRequest("GET", "/transactions/download/:123", nil)
-				r = mux.SetURLVars(r, map[string]string{"transaction_id": "123"})
-				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234", Cookie: "456"})
+				r := httptest.NewRequest("GET", "/transactions?from=2023-01-01T00:00:00Z&to=2023-01-31&type=debit&status=approved&account_number=1&transfer_to=2&min_amount=10.5&max_amount=100&comment=rent&batch_id=b1&sort=amount:desc,created_at", nil)
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if !reflect.DeepEqual(rec.Code, http.StatusOK) {
+					t.Errorf("Want: %v, Got: %v", http.StatusOK, rec.Code)
+				}
+			},
+		},
+		{
+			name: "Failure::GetTransaction:: invalid from",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("GET", "/transactions?from=yesterday", nil)
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
 				return svc, r.WithContext(ctx)
 			},
 			want: func(rec httptest.ResponseRecorder) {
 				b, err := ioutil.ReadAll(rec.Body)
 				if err != nil {
-					return
+					t.Log(err)
+					t.Fail()
 				}
-				if strings.Compare("PDF", string(b)) != 0 {
-					t.Errorf("Want: %v, Got: %v", "PDF", string(b))
-					return
+				var response respModel.Response
+				err = json.Unmarshal(b, &response)
+				tempResp := &respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: codes.GetErr(codes.ErrInvalidFilter) + ": from must be an RFC 3339 timestamp or a YYYY-MM-DD date",
+					Data:    nil,
 				}
-				cd := rec.Header().Get("Content-Disposition")
-				if cd != "attachment; filename=123.pdf" {
-					t.Errorf("Want: %v, Got: %v", "attachment; filename=123.pdf", cd)
-					return
+				if !reflect.DeepEqual(&response, tempResp) {
+					t.Errorf("Want: %v, Got: %v", tempResp, &response)
 				}
-				ct := rec.Header().Get("Content-Type")
-				if ct != "application/pdf" {
-					t.Errorf("Want: %v, Got: %v", "application/pdf", cd)
-					return
+			},
+		},
+		{
+			name: "Failure::GetTransaction:: from after to",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("GET", "/transactions?from=2023-02-01&to=2023-01-01", nil)
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				b, err := ioutil.ReadAll(rec.Body)
+				if err != nil {
+					t.Log(err)
+					t.Fail()
+				}
+				var response respModel.Response
+				err = json.Unmarshal(b, &response)
+				tempResp := &respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: codes.GetErr(codes.ErrInvalidFilter) + ": from must not be after to",
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(&response, tempResp) {
+					t.Errorf("Want: %v, Got: %v", tempResp, &response)
 				}
 			},
 		},
 		{
-			name:           "Failure:: DownloadTransaction ::response writer write failure",
-			hijackedWriter: true,
+			name: "Failure::GetTransaction:: invalid type",
 			setup: func() (*transactionManagementService, *http.Request) {
 				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
-				mockLogic.EXPECT().DownloadTransaction("123", "456").Times(1).Return(&respModel.Response{
-					Status:  http.StatusOK,
-					Message: codes.GetErr(codes.Success),
-					Data:    []byte("PDF"),
-				})
 				svc := &transactionManagementService{
 					logic: mockLogic,
 				}
-				r := httptest.NewRequest("GET", "/transactions/download/:123", nil)
-				r = mux.SetURLVars(r, map[string]string{"transaction_id": "123"})
-				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234", Cookie: "456"})
+				r := httptest.NewRequest("GET", "/transactions?type=transfer", nil)
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
 				return svc, r.WithContext(ctx)
 			},
 			want: func(rec httptest.ResponseRecorder) {
-				if !reflect.DeepEqual(rec.Code, http.StatusInternalServerError) {
-					t.Errorf("Want: %v, Got: %v", http.StatusInternalServerError, rec.Code)
+				b, err := ioutil.ReadAll(rec.Body)
+				if err != nil {
+					t.Log(err)
+					t.Fail()
+				}
+				var response respModel.Response
+				err = json.Unmarshal(b, &response)
+				tempResp := &respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: codes.GetErr(codes.ErrInvalidFilter) + ": type must be credit or debit",
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(&response, tempResp) {
+					t.Errorf("Want: %v, Got: %v", tempResp, &response)
 				}
 			},
 		},
 		{
-			name: "Failure:: DownloadTransaction :: id not found in url",
+			name: "Failure::GetTransaction:: invalid status",
 			setup: func() (*transactionManagementService, *http.Request) {
 				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
 				svc := &transactionManagementService{
 					logic: mockLogic,
 				}
-				r := httptest.NewRequest("GET", "/transactions/download/:123", nil)
-				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234", Cookie: "456"})
+				r := httptest.NewRequest("GET", "/transactions?status=settled", nil)
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
 				return svc, r.WithContext(ctx)
 			},
 			want: func(rec httptest.ResponseRecorder) {
 				b, err := ioutil.ReadAll(rec.Body)
 				if err != nil {
-					return
+					t.Log(err)
+					t.Fail()
 				}
 				var response respModel.Response
 				err = json.Unmarshal(b, &response)
 				tempResp := &respModel.Response{
 					Status:  http.StatusBadRequest,
-					Message: codes.GetErr(codes.ErrGetTransaction),
+					Message: codes.GetErr(codes.ErrInvalidFilter) + ": status \"settled\" is not supported",
 					Data:    nil,
 				}
 				if !reflect.DeepEqual(&response, tempResp) {
@@ -586,26 +690,27 @@ func TestTransactionManagementService_DownloadTransaction(t *testing.T) {
 			},
 		},
 		{
-			name: "Failure:: DownloadTransaction :: err assert userid",
+			name: "Failure::GetTransaction:: invalid account number",
 			setup: func() (*transactionManagementService, *http.Request) {
 				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
 				svc := &transactionManagementService{
 					logic: mockLogic,
 				}
-				r := httptest.NewRequest("GET", "/transactions/download/:123", nil)
-				ctx := session.SetSession(r.Context(), "")
+				r := httptest.NewRequest("GET", "/transactions?account_number=-1", nil)
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
 				return svc, r.WithContext(ctx)
 			},
 			want: func(rec httptest.ResponseRecorder) {
 				b, err := ioutil.ReadAll(rec.Body)
 				if err != nil {
-					return
+					t.Log(err)
+					t.Fail()
 				}
 				var response respModel.Response
 				err = json.Unmarshal(b, &response)
 				tempResp := &respModel.Response{
 					Status:  http.StatusBadRequest,
-					Message: codes.GetErr(codes.ErrAssertUserid),
+					Message: codes.GetErr(codes.ErrInvalidFilter) + ": account_number must be a positive integer",
 					Data:    nil,
 				}
 				if !reflect.DeepEqual(&response, tempResp) {
@@ -614,32 +719,56 @@ func TestTransactionManagementService_DownloadTransaction(t *testing.T) {
 			},
 		},
 		{
-			name: "Failure:: DownloadTransaction :: not ok status code",
+			name: "Failure::GetTransaction:: invalid amount",
 			setup: func() (*transactionManagementService, *http.Request) {
 				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
-				mockLogic.EXPECT().DownloadTransaction("123", "4321").Return(&respModel.Response{
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("GET", "/transactions?min_amount=abc", nil)
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				b, err := ioutil.ReadAll(rec.Body)
+				if err != nil {
+					t.Log(err)
+					t.Fail()
+				}
+				var response respModel.Response
+				err = json.Unmarshal(b, &response)
+				tempResp := &respModel.Response{
 					Status:  http.StatusBadRequest,
-					Message: "",
+					Message: codes.GetErr(codes.ErrInvalidFilter) + ": min_amount must be a non negative amount with at most 2 decimal places",
 					Data:    nil,
-				})
+				}
+				if !reflect.DeepEqual(&response, tempResp) {
+					t.Errorf("Want: %v, Got: %v", tempResp, &response)
+				}
+			},
+		},
+		{
+			name: "Failure::GetTransaction:: min amount above max amount",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
 				svc := &transactionManagementService{
 					logic: mockLogic,
 				}
-				r := httptest.NewRequest("GET", "/transactions/download/:123", nil)
-				r = mux.SetURLVars(r, map[string]string{"transaction_id": "123"})
-				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234", Cookie: "4321"})
+				r := httptest.NewRequest("GET", "/transactions?min_amount=10&max_amount=1", nil)
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
 				return svc, r.WithContext(ctx)
 			},
 			want: func(rec httptest.ResponseRecorder) {
 				b, err := ioutil.ReadAll(rec.Body)
 				if err != nil {
-					return
+					t.Log(err)
+					t.Fail()
 				}
 				var response respModel.Response
 				err = json.Unmarshal(b, &response)
 				tempResp := &respModel.Response{
 					Status:  http.StatusBadRequest,
-					Message: "",
+					Message: codes.GetErr(codes.ErrInvalidFilter) + ": min_amount must not be greater than max_amount",
 					Data:    nil,
 				}
 				if !reflect.DeepEqual(&response, tempResp) {
@@ -648,72 +777,2234 @@ func TestTransactionManagementService_DownloadTransaction(t *testing.T) {
 			},
 		},
 		{
-			name: "Failure:: DownloadTransaction :: err asserting pdf data",
+			name: "Failure::GetTransaction:: unsupported sort column",
 			setup: func() (*transactionManagementService, *http.Request) {
 				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
-				mockLogic.EXPECT().DownloadTransaction("123", "4321").Return(&respModel.Response{
-					Status:  http.StatusOK,
-					Message: "Success",
-					Data:    123,
-				})
 				svc := &transactionManagementService{
 					logic: mockLogic,
 				}
-				r := httptest.NewRequest("GET", "/transactions/download/123", nil)
-				r = mux.SetURLVars(r, map[string]string{"transaction_id": "123"})
-				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234", Cookie: "4321"})
+				r := httptest.NewRequest("GET", "/transactions?sort=comment", nil)
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
 				return svc, r.WithContext(ctx)
 			},
 			want: func(rec httptest.ResponseRecorder) {
 				b, err := ioutil.ReadAll(rec.Body)
 				if err != nil {
+					t.Log(err)
 					t.Fail()
-					return
 				}
 				var response respModel.Response
 				err = json.Unmarshal(b, &response)
 				tempResp := &respModel.Response{
 					Status:  http.StatusBadRequest,
-					Message: codes.GetErr(codes.ErrAssertPdf),
+					Message: codes.GetErr(codes.ErrInvalidFilter) + ": cannot sort by \"comment\"",
 					Data:    nil,
 				}
-				t.Log(response)
 				if !reflect.DeepEqual(&response, tempResp) {
 					t.Errorf("Want: %v, Got: %v", tempResp, &response)
-					return
 				}
 			},
 		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			w := httptest.NewRecorder()
-			x, r := tt.setup()
-			if tt.hijackedWriter {
-				hijackedWriter := &respWriterWithStatus{-1, "", w}
-				x.DownloadTransaction(hijackedWriter, r)
-				tt.want(*w)
-				return
-			}
-			x.DownloadTransaction(w, r)
-			tt.want(*w)
-
-		})
-	}
-}
-
-type respWriterWithStatus struct {
-	status   int
-	response string
-	http.ResponseWriter
-}
-
-func (w *respWriterWithStatus) WriteHeader(code int) {
+		{
+			name: "Failure::GetTransaction:: invalid sort direction",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("GET", "/transactions?sort=amount:up", nil)
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				b, err := ioutil.ReadAll(rec.Body)
+				if err != nil {
+					t.Log(err)
+					t.Fail()
+				}
+				var response respModel.Response
+				err = json.Unmarshal(b, &response)
+				tempResp := &respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: codes.GetErr(codes.ErrInvalidFilter) + ": sort direction \"up\" must be asc or desc",
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(&response, tempResp) {
+					t.Errorf("Want: %v, Got: %v", tempResp, &response)
+				}
+			},
+		},
+		{
+			name: "Failure::GetTransaction:: repeated sort key",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("GET", "/transactions?sort=amount&sort=amount:desc", nil)
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				b, err := ioutil.ReadAll(rec.Body)
+				if err != nil {
+					t.Log(err)
+					t.Fail()
+				}
+				var response respModel.Response
+				err = json.Unmarshal(b, &response)
+				tempResp := &respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: codes.GetErr(codes.ErrInvalidFilter) + ": sort key \"amount\" given more than once",
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(&response, tempResp) {
+					t.Errorf("Want: %v, Got: %v", tempResp, &response)
+				}
+			},
+		},
+		{
+			name: "Failure::GetTransaction:: cursor with amount sort",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("GET", "/transactions?cursor=&sort=amount:desc", nil)
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				b, err := ioutil.ReadAll(rec.Body)
+				if err != nil {
+					t.Log(err)
+					t.Fail()
+				}
+				var response respModel.Response
+				err = json.Unmarshal(b, &response)
+				tempResp := &respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: codes.GetErr(codes.ErrInvalidFilter) + ": cursor pagination only supports sorting by created_at",
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(&response, tempResp) {
+					t.Errorf("Want: %v, Got: %v", tempResp, &response)
+				}
+			},
+		},
+		{
+			name: "Failure::GetTransaction:: running balance without account",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("GET", "/transactions?running_balance=true", nil)
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				b, err := ioutil.ReadAll(rec.Body)
+				if err != nil {
+					t.Log(err)
+					t.Fail()
+				}
+				var response respModel.Response
+				err = json.Unmarshal(b, &response)
+				tempResp := &respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: codes.GetErr(codes.ErrInvalidFilter) + ": running_balance requires account_number",
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(&response, tempResp) {
+					t.Errorf("Want: %v, Got: %v", tempResp, &response)
+				}
+			},
+		},
+		{
+			name: "Success::GetTransaction:: running balance",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				mockLogic.EXPECT().GetTransactions(model.ListTransactions{UserId: "1234", Limit: 5, Page: 1, AccountNumber: 1, RunningBalance: true}).Times(1).Return(&respModel.Response{
+					Status:  http.StatusOK,
+					Message: codes.GetErr(codes.Success),
+					Data:    model.PaginatedResponse{},
+				})
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("GET", "/transactions?account_number=1&running_balance=true", nil)
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if !reflect.DeepEqual(rec.Code, http.StatusOK) {
+					t.Errorf("Want: %v, Got: %v", http.StatusOK, rec.Code)
+				}
+			},
+		},
+		{
+			name: "Success::GetTransaction:: cursor",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				mockLogic.EXPECT().GetTransactions(model.ListTransactions{UserId: "1234", Limit: 5, Page: 1, Keyset: true, Cursor: "abc.def", Sort: []model.OrderBy{{Column: "created_at"}}}).Times(1).Return(&respModel.Response{
+					Status:  http.StatusOK,
+					Message: codes.GetErr(codes.Success),
+					Data:    model.PaginatedResponse{},
+				})
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("GET", "/transactions?cursor=abc.def&sort=created_at:asc", nil)
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if !reflect.DeepEqual(rec.Code, http.StatusOK) {
+					t.Errorf("Want: %v, Got: %v", http.StatusOK, rec.Code)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			x, r := tt.setup()
+			x.GetTransactions(w, r)
+			tt.want(*w)
+		})
+	}
+}
+func TestTransactionManagementService_GetTransaction(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	tests := []struct {
+		name  string
+		setup func() (*transactionManagementService, *http.Request)
+		want  func(recorder httptest.ResponseRecorder)
+	}{
+		{
+			name: "Success::GetTransaction",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				mockLogic.EXPECT().GetTransaction("123", "1234").Times(1).Return(&respModel.Response{
+					Status:  http.StatusOK,
+					Message: codes.GetErr(codes.Success),
+					Data:    model.Transaction{TransactionId: "123", Amount: 1000, AccountNumber: 1},
+				})
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("GET", "/transactions/123", nil)
+				r = mux.SetURLVars(r, map[string]string{"transaction_id": "123"})
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				b, err := ioutil.ReadAll(rec.Body)
+				if err != nil {
+					t.Log(err)
+					t.Fail()
+				}
+				tempResp := &respModel.Response{
+					Status:  http.StatusOK,
+					Message: codes.GetErr(codes.Success),
+					Data:    model.Transaction{TransactionId: "123", Amount: 1000, AccountNumber: 1},
+				}
+				marshal, err := json.Marshal(&tempResp)
+				if err != nil {
+					t.Log(err)
+					t.Fail()
+				}
+				if !reflect.DeepEqual(rec.Code, http.StatusOK) {
+					t.Errorf("Want: %v, Got: %v", http.StatusOK, rec.Code)
+				}
+				if strings.TrimSpace(string(b)) != string(marshal) {
+					t.Errorf("Want: %v, Got: %v", string(marshal), string(b))
+				}
+			},
+		},
+		{
+			name: "Failure::GetTransaction:: not found",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				mockLogic.EXPECT().GetTransaction("123", "1234").Times(1).Return(&respModel.Response{
+					Status:  http.StatusNotFound,
+					Message: codes.GetErr(codes.ErrNoTransaction),
+					Data:    nil,
+				})
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("GET", "/transactions/123", nil)
+				r = mux.SetURLVars(r, map[string]string{"transaction_id": "123"})
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				b, err := ioutil.ReadAll(rec.Body)
+				if err != nil {
+					t.Log(err)
+					t.Fail()
+				}
+				var response respModel.Response
+				err = json.Unmarshal(b, &response)
+				tempResp := &respModel.Response{
+					Status:  http.StatusNotFound,
+					Message: codes.GetErr(codes.ErrNoTransaction),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(rec.Code, http.StatusNotFound) {
+					t.Errorf("Want: %v, Got: %v", http.StatusNotFound, rec.Code)
+				}
+				if !reflect.DeepEqual(&response, tempResp) {
+					t.Errorf("Want: %v, Got: %v", tempResp, &response)
+				}
+			},
+		},
+		{
+			name: "Failure::GetTransaction:: id not found in url",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("GET", "/transactions/123", nil)
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				b, err := ioutil.ReadAll(rec.Body)
+				if err != nil {
+					t.Log(err)
+					t.Fail()
+				}
+				var response respModel.Response
+				err = json.Unmarshal(b, &response)
+				tempResp := &respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: codes.GetErr(codes.ErrGetTransaction),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(&response, tempResp) {
+					t.Errorf("Want: %v, Got: %v", tempResp, &response)
+				}
+			},
+		},
+		{
+			name: "Failure::GetTransaction:: err assert userid",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("GET", "/transactions/123", nil)
+				ctx := session.SetSession(r.Context(), "")
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				b, err := ioutil.ReadAll(rec.Body)
+				if err != nil {
+					t.Log(err)
+					t.Fail()
+				}
+				var response respModel.Response
+				err = json.Unmarshal(b, &response)
+				tempResp := &respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: codes.GetErr(codes.ErrAssertUserid),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(&response, tempResp) {
+					t.Errorf("Want: %v, Got: %v", tempResp, &response)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			x, r := tt.setup()
+			x.GetTransaction(w, r)
+			tt.want(*w)
+		})
+	}
+}
+
+func TestTransactionManagementService_DownloadTransaction(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	tests := []struct {
+		name           string
+		model          model.Transaction
+		hijackedWriter bool
+		setup          func() (*transactionManagementService, *http.Request)
+		want           func(recorder httptest.ResponseRecorder)
+	}{
+		{
+			name: "Success",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				mockLogic.EXPECT().DownloadTransaction("123", "1234", "456").Times(1).Return(&respModel.Response{
+					Status:  http.StatusOK,
+					Message: codes.GetErr(codes.Success),
+					Data:    []byte("PDF"),
+				})
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("GET", "/transactions/download/:123", nil)
+				r = mux.SetURLVars(r, map[string]string{"transaction_id": "123"})
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234", Cookie: "456"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				b, err := ioutil.ReadAll(rec.Body)
+				if err != nil {
+					return
+				}
+				if strings.Compare("PDF", string(b)) != 0 {
+					t.Errorf("Want: %v, Got: %v", "PDF", string(b))
+					return
+				}
+				cd := rec.Header().Get("Content-Disposition")
+				if cd != "attachment; filename=123.pdf" {
+					t.Errorf("Want: %v, Got: %v", "attachment; filename=123.pdf", cd)
+					return
+				}
+				ct := rec.Header().Get("Content-Type")
+				if ct != "application/pdf" {
+					t.Errorf("Want: %v, Got: %v", "application/pdf", cd)
+					return
+				}
+			},
+		},
+		{
+			name:           "Failure:: DownloadTransaction ::response writer write failure",
+			hijackedWriter: true,
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				mockLogic.EXPECT().DownloadTransaction("123", "1234", "456").Times(1).Return(&respModel.Response{
+					Status:  http.StatusOK,
+					Message: codes.GetErr(codes.Success),
+					Data:    []byte("PDF"),
+				})
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("GET", "/transactions/download/:123", nil)
+				r = mux.SetURLVars(r, map[string]string{"transaction_id": "123"})
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234", Cookie: "456"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if !reflect.DeepEqual(rec.Code, http.StatusInternalServerError) {
+					t.Errorf("Want: %v, Got: %v", http.StatusInternalServerError, rec.Code)
+				}
+			},
+		},
+		{
+			name: "Failure:: DownloadTransaction :: id not found in url",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("GET", "/transactions/download/:123", nil)
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234", Cookie: "456"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				b, err := ioutil.ReadAll(rec.Body)
+				if err != nil {
+					return
+				}
+				var response respModel.Response
+				err = json.Unmarshal(b, &response)
+				tempResp := &respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: codes.GetErr(codes.ErrGetTransaction),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(&response, tempResp) {
+					t.Errorf("Want: %v, Got: %v", tempResp, &response)
+				}
+			},
+		},
+		{
+			name: "Failure:: DownloadTransaction :: err assert userid",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("GET", "/transactions/download/:123", nil)
+				ctx := session.SetSession(r.Context(), "")
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				b, err := ioutil.ReadAll(rec.Body)
+				if err != nil {
+					return
+				}
+				var response respModel.Response
+				err = json.Unmarshal(b, &response)
+				tempResp := &respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: codes.GetErr(codes.ErrAssertUserid),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(&response, tempResp) {
+					t.Errorf("Want: %v, Got: %v", tempResp, &response)
+				}
+			},
+		},
+		{
+			name: "Failure:: DownloadTransaction :: not ok status code",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				mockLogic.EXPECT().DownloadTransaction("123", "1234", "4321").Return(&respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: "",
+					Data:    nil,
+				})
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("GET", "/transactions/download/:123", nil)
+				r = mux.SetURLVars(r, map[string]string{"transaction_id": "123"})
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234", Cookie: "4321"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				b, err := ioutil.ReadAll(rec.Body)
+				if err != nil {
+					return
+				}
+				var response respModel.Response
+				err = json.Unmarshal(b, &response)
+				tempResp := &respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: "",
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(&response, tempResp) {
+					t.Errorf("Want: %v, Got: %v", tempResp, &response)
+				}
+			},
+		},
+		{
+			name: "Failure:: DownloadTransaction :: err asserting pdf data",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				mockLogic.EXPECT().DownloadTransaction("123", "1234", "4321").Return(&respModel.Response{
+					Status:  http.StatusOK,
+					Message: "Success",
+					Data:    123,
+				})
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("GET", "/transactions/download/123", nil)
+				r = mux.SetURLVars(r, map[string]string{"transaction_id": "123"})
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234", Cookie: "4321"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				b, err := ioutil.ReadAll(rec.Body)
+				if err != nil {
+					t.Fail()
+					return
+				}
+				var response respModel.Response
+				err = json.Unmarshal(b, &response)
+				tempResp := &respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: codes.GetErr(codes.ErrAssertPdf),
+					Data:    nil,
+				}
+				t.Log(response)
+				if !reflect.DeepEqual(&response, tempResp) {
+					t.Errorf("Want: %v, Got: %v", tempResp, &response)
+					return
+				}
+			},
+		},
+		{
+			name: "Failure:: DownloadTransaction :: transaction of another user not found",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				mockLogic.EXPECT().DownloadTransaction("123", "5678", "4321").Return(&respModel.Response{
+					Status:  http.StatusNotFound,
+					Message: codes.GetErr(codes.ErrNoTransaction),
+					Data:    nil,
+				})
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("GET", "/transactions/download/123", nil)
+				r = mux.SetURLVars(r, map[string]string{"transaction_id": "123"})
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "5678", Cookie: "4321"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				b, err := ioutil.ReadAll(rec.Body)
+				if err != nil {
+					t.Fail()
+					return
+				}
+				var response respModel.Response
+				err = json.Unmarshal(b, &response)
+				tempResp := &respModel.Response{
+					Status:  http.StatusNotFound,
+					Message: codes.GetErr(codes.ErrNoTransaction),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(rec.Code, http.StatusNotFound) {
+					t.Errorf("Want: %v, Got: %v", http.StatusNotFound, rec.Code)
+				}
+				if !reflect.DeepEqual(&response, tempResp) {
+					t.Errorf("Want: %v, Got: %v", tempResp, &response)
+				}
+				if rec.Header().Get("Content-Type") == "application/pdf" {
+					t.Errorf("Want: %v, Got: %v", "application/json", rec.Header().Get("Content-Type"))
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			x, r := tt.setup()
+			if tt.hijackedWriter {
+				hijackedWriter := &respWriterWithStatus{-1, "", w}
+				x.DownloadTransaction(hijackedWriter, r)
+				tt.want(*w)
+				return
+			}
+			x.DownloadTransaction(w, r)
+			tt.want(*w)
+
+		})
+	}
+}
+
+type respWriterWithStatus struct {
+	status   int
+	response string
+	http.ResponseWriter
+}
+
+func (w *respWriterWithStatus) WriteHeader(code int) {
 	w.status = code
 	w.ResponseWriter.WriteHeader(code)
 }
 
-func (w *respWriterWithStatus) Write(d []byte) (int, error) {
-	w.response = string(d)
-	return 0, errors.New("")
+func (w *respWriterWithStatus) Write(d []byte) (int, error) {
+	w.response = string(d)
+	return 0, errors.New("")
+}
+
+func TestTransactionManagementService_GetReviewQueue(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	tests := []struct {
+		name     string
+		setup    func(*mock.MockTransactionManagementServiceLogicIer)
+		session  interface{}
+		wantCode int
+	}{
+		{
+			name: "Success::GetReviewQueue",
+			setup: func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {
+				mockLogic.EXPECT().GetReviewQueue("1234").Times(1).Return(&respModel.Response{Status: http.StatusOK, Message: codes.GetErr(codes.Success), Data: []model.ReviewItem{}})
+			},
+			session:  model.SessionStruct{UserId: "1234"},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "Failure::GetReviewQueue:: not a reviewer",
+			setup: func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {
+				mockLogic.EXPECT().GetReviewQueue("1234").Times(1).Return(&respModel.Response{Status: http.StatusForbidden, Message: codes.GetErr(codes.ErrNotReviewer)})
+			},
+			session:  model.SessionStruct{UserId: "1234"},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "Failure::GetReviewQueue:: Failure assert user_id",
+			setup:    func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {},
+			session:  "",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+			tt.setup(mockLogic)
+			svc := &transactionManagementService{
+				logic: mockLogic,
+			}
+			r := httptest.NewRequest("GET", "/reviews", nil)
+			r = r.WithContext(session.SetSession(r.Context(), tt.session))
+			w := httptest.NewRecorder()
+			svc.GetReviewQueue(w, r)
+			if !reflect.DeepEqual(w.Code, tt.wantCode) {
+				t.Errorf("Want: %v, Got: %v", tt.wantCode, w.Code)
+			}
+		})
+	}
+}
+
+func TestTransactionManagementService_UpdateTransactionStatus(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	tests := []struct {
+		name  string
+		setup func() (*transactionManagementService, *http.Request)
+		want  func(recorder httptest.ResponseRecorder)
+	}{
+		{
+			name: "Success::UpdateTransactionStatus",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				mockLogic.EXPECT().UpdateStatus("123", "1234", model.UpdateStatus{Status: "approved", Reason: "verified"}).Times(1).Return(&respModel.Response{
+					Status:  http.StatusOK,
+					Message: codes.GetErr(codes.Success),
+					Data:    model.Transaction{TransactionId: "123", Status: "approved"},
+				})
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("PATCH", "/transactions/123", bytes.NewBufferString(`{"status":"approved","reason":"verified"}`))
+				r = mux.SetURLVars(r, map[string]string{"transaction_id": "123"})
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				b, err := ioutil.ReadAll(rec.Body)
+				if err != nil {
+					t.Log(err)
+					t.Fail()
+				}
+				tempResp := &respModel.Response{
+					Status:  http.StatusOK,
+					Message: codes.GetErr(codes.Success),
+					Data:    model.Transaction{TransactionId: "123", Status: "approved"},
+				}
+				marshal, err := json.Marshal(&tempResp)
+				if err != nil {
+					t.Log(err)
+					t.Fail()
+				}
+				if !reflect.DeepEqual(rec.Code, http.StatusOK) {
+					t.Errorf("Want: %v, Got: %v", http.StatusOK, rec.Code)
+				}
+				if strings.TrimSpace(string(b)) != string(marshal) {
+					t.Errorf("Want: %v, Got: %v", string(marshal), string(b))
+				}
+			},
+		},
+		{
+			name: "Failure::UpdateTransactionStatus:: invalid transition",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				mockLogic.EXPECT().UpdateStatus("123", "1234", model.UpdateStatus{Status: "failed"}).Times(1).Return(&respModel.Response{
+					Status:  http.StatusConflict,
+					Message: codes.GetErr(codes.ErrInvalidStatusTransition),
+					Data:    nil,
+				})
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("PATCH", "/transactions/123", bytes.NewBufferString(`{"status":"failed"}`))
+				r = mux.SetURLVars(r, map[string]string{"transaction_id": "123"})
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if !reflect.DeepEqual(rec.Code, http.StatusConflict) {
+					t.Errorf("Want: %v, Got: %v", http.StatusConflict, rec.Code)
+				}
+			},
+		},
+		{
+			name: "Failure::UpdateTransactionStatus:: status not settable",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("PATCH", "/transactions/123", bytes.NewBufferString(`{"status":"reversed"}`))
+				r = mux.SetURLVars(r, map[string]string{"transaction_id": "123"})
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if !reflect.DeepEqual(rec.Code, http.StatusBadRequest) {
+					t.Errorf("Want: %v, Got: %v", http.StatusBadRequest, rec.Code)
+				}
+			},
+		},
+		{
+			name: "Failure::UpdateTransactionStatus:: no transaction id",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("PATCH", "/transactions/123", bytes.NewBufferString(`{"status":"approved"}`))
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if !reflect.DeepEqual(rec.Code, http.StatusBadRequest) {
+					t.Errorf("Want: %v, Got: %v", http.StatusBadRequest, rec.Code)
+				}
+			},
+		},
+		{
+			name: "Failure::UpdateTransactionStatus:: Failure assert user_id",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("PATCH", "/transactions/123", nil)
+				ctx := session.SetSession(r.Context(), "")
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if !reflect.DeepEqual(rec.Code, http.StatusBadRequest) {
+					t.Errorf("Want: %v, Got: %v", http.StatusBadRequest, rec.Code)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			x, r := tt.setup()
+			x.UpdateTransactionStatus(w, r)
+			tt.want(*w)
+		})
+	}
+}
+
+func TestTransactionManagementService_ReverseTransaction(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	tests := []struct {
+		name  string
+		setup func() (*transactionManagementService, *http.Request)
+		want  func(recorder httptest.ResponseRecorder)
+	}{
+		{
+			name: "Success::ReverseTransaction",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				mockLogic.EXPECT().Reverse("123", "1234", model.Reversal{Reason: "mistake"}).Times(1).Return(&respModel.Response{
+					Status:  http.StatusCreated,
+					Message: codes.GetErr(codes.Success),
+					Data:    []model.Transaction{{TransactionId: "456", ReferenceId: "123", Amount: 10, Type: "credit", Status: "approved"}},
+				})
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("POST", "/transactions/123/reverse", bytes.NewBufferString(`{"reason":"mistake"}`))
+				r = mux.SetURLVars(r, map[string]string{"transaction_id": "123"})
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				b, err := ioutil.ReadAll(rec.Body)
+				if err != nil {
+					t.Log(err)
+					t.Fail()
+				}
+				tempResp := &respModel.Response{
+					Status:  http.StatusCreated,
+					Message: codes.GetErr(codes.Success),
+					Data:    []model.Transaction{{TransactionId: "456", ReferenceId: "123", Amount: 10, Type: "credit", Status: "approved"}},
+				}
+				marshal, err := json.Marshal(&tempResp)
+				if err != nil {
+					t.Log(err)
+					t.Fail()
+				}
+				if !reflect.DeepEqual(rec.Code, http.StatusCreated) {
+					t.Errorf("Want: %v, Got: %v", http.StatusCreated, rec.Code)
+				}
+				if strings.TrimSpace(string(b)) != string(marshal) {
+					t.Errorf("Want: %v, Got: %v", string(marshal), string(b))
+				}
+			},
+		},
+		{
+			name: "Failure::ReverseTransaction:: json unmarshall failure",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("POST", "/transactions/123/reverse", bytes.NewBufferString(""))
+				r = mux.SetURLVars(r, map[string]string{"transaction_id": "123"})
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if !reflect.DeepEqual(rec.Code, http.StatusBadRequest) {
+					t.Errorf("Want: %v, Got: %v", http.StatusBadRequest, rec.Code)
+				}
+			},
+		},
+		{
+			name: "Failure::ReverseTransaction:: no transaction id",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("POST", "/transactions/123/reverse", bytes.NewBufferString("{}"))
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if !reflect.DeepEqual(rec.Code, http.StatusBadRequest) {
+					t.Errorf("Want: %v, Got: %v", http.StatusBadRequest, rec.Code)
+				}
+			},
+		},
+		{
+			name: "Failure::ReverseTransaction:: Failure assert user_id",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("POST", "/transactions/123/reverse", nil)
+				ctx := session.SetSession(r.Context(), "")
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if !reflect.DeepEqual(rec.Code, http.StatusBadRequest) {
+					t.Errorf("Want: %v, Got: %v", http.StatusBadRequest, rec.Code)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			x, r := tt.setup()
+			x.ReverseTransaction(w, r)
+			tt.want(*w)
+		})
+	}
+}
+
+func TestTransactionManagementService_RefundTransaction(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	tests := []struct {
+		name  string
+		setup func() (*transactionManagementService, *http.Request)
+		want  func(recorder httptest.ResponseRecorder)
+	}{
+		{
+			name: "Success::RefundTransaction",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				mockLogic.EXPECT().Refund("123", "1234", model.Refund{Amount: 250, Reason: "damaged"}).Times(1).Return(&respModel.Response{
+					Status:  http.StatusCreated,
+					Message: codes.GetErr(codes.Success),
+					Data:    nil,
+				})
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("POST", "/transactions/123/refund", bytes.NewBufferString(`{"amount":2.5,"reason":"damaged"}`))
+				r = mux.SetURLVars(r, map[string]string{"transaction_id": "123"})
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if !reflect.DeepEqual(rec.Code, http.StatusCreated) {
+					t.Errorf("Want: %v, Got: %v", http.StatusCreated, rec.Code)
+				}
+			},
+		},
+		{
+			name: "Failure::RefundTransaction:: exceeds what is left",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				mockLogic.EXPECT().Refund("123", "1234", model.Refund{Amount: 100 * model.MajorUnit}).Times(1).Return(&respModel.Response{
+					Status:  http.StatusUnprocessableEntity,
+					Message: codes.GetErr(codes.ErrRefundExceeds),
+					Data:    nil,
+				})
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("POST", "/transactions/123/refund", bytes.NewBufferString(`{"amount":100}`))
+				r = mux.SetURLVars(r, map[string]string{"transaction_id": "123"})
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if !reflect.DeepEqual(rec.Code, http.StatusUnprocessableEntity) {
+					t.Errorf("Want: %v, Got: %v", http.StatusUnprocessableEntity, rec.Code)
+				}
+			},
+		},
+		{
+			name: "Failure::RefundTransaction:: amount below a cent",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("POST", "/transactions/123/refund", bytes.NewBufferString(`{"amount":0.001}`))
+				r = mux.SetURLVars(r, map[string]string{"transaction_id": "123"})
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				b, err := ioutil.ReadAll(rec.Body)
+				if err != nil {
+					t.Log(err)
+					t.Fail()
+				}
+				var response respModel.Response
+				err = json.Unmarshal(b, &response)
+				tempResp := &respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: "put data into data: amount \"0.001\" has more than 2 decimal places",
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(&response, tempResp) {
+					t.Errorf("Want: %v, Got: %v", tempResp, &response)
+				}
+			},
+		},
+		{
+			name: "Failure::RefundTransaction:: amount not positive",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("POST", "/transactions/123/refund", bytes.NewBufferString(`{"amount":-1}`))
+				r = mux.SetURLVars(r, map[string]string{"transaction_id": "123"})
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				b, err := ioutil.ReadAll(rec.Body)
+				if err != nil {
+					t.Log(err)
+					t.Fail()
+				}
+				var response respModel.Response
+				err = json.Unmarshal(b, &response)
+				tempResp := &respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: codes.GetErr(codes.ErrInvalidAmount),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(&response, tempResp) {
+					t.Errorf("Want: %v, Got: %v", tempResp, &response)
+				}
+			},
+		},
+		{
+			name: "Failure::RefundTransaction:: Failure assert user_id",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("POST", "/transactions/123/refund", nil)
+				ctx := session.SetSession(r.Context(), "")
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if !reflect.DeepEqual(rec.Code, http.StatusBadRequest) {
+					t.Errorf("Want: %v, Got: %v", http.StatusBadRequest, rec.Code)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			x, r := tt.setup()
+			x.RefundTransaction(w, r)
+			tt.want(*w)
+		})
+	}
+}
+
+func TestTransactionManagementService_GetBalance(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	asOf := time.Date(2023, time.March, 1, 0, 0, 0, 0, time.UTC).Add(24*time.Hour - time.Nanosecond)
+
+	tests := []struct {
+		name  string
+		setup func() (*transactionManagementService, *http.Request)
+		want  func(recorder httptest.ResponseRecorder)
+	}{
+		{
+			name: "Success::GetBalance",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				mockLogic.EXPECT().GetBalance(1, "1234", &asOf).Times(1).Return(&respModel.Response{
+					Status:  http.StatusOK,
+					Message: codes.GetErr(codes.Success),
+					Data:    model.AccountBalance{AccountNumber: 1, AsOf: asOf, Balances: []model.Balance{{Currency: "USD", Balance: 1050}}},
+				})
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("GET", "/accounts/1/balance?as_of=2023-03-01", nil)
+				r = mux.SetURLVars(r, map[string]string{"account_number": "1"})
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				b, err := ioutil.ReadAll(rec.Body)
+				if err != nil {
+					t.Log(err)
+					t.Fail()
+				}
+				tempResp := &respModel.Response{
+					Status:  http.StatusOK,
+					Message: codes.GetErr(codes.Success),
+					Data:    model.AccountBalance{AccountNumber: 1, AsOf: asOf, Balances: []model.Balance{{Currency: "USD", Balance: 1050}}},
+				}
+				marshal, err := json.Marshal(&tempResp)
+				if err != nil {
+					t.Log(err)
+					t.Fail()
+				}
+				if !reflect.DeepEqual(rec.Code, http.StatusOK) {
+					t.Errorf("Want: %v, Got: %v", http.StatusOK, rec.Code)
+				}
+				if strings.TrimSpace(string(b)) != string(marshal) {
+					t.Errorf("Want: %v, Got: %v", string(marshal), string(b))
+				}
+			},
+		},
+		{
+			name: "Success::GetBalance:: now",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				mockLogic.EXPECT().GetBalance(1, "1234", nil).Times(1).Return(&respModel.Response{
+					Status:  http.StatusNotFound,
+					Message: codes.GetErr(codes.ErrNoAccount),
+				})
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("GET", "/accounts/1/balance", nil)
+				r = mux.SetURLVars(r, map[string]string{"account_number": "1"})
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if !reflect.DeepEqual(rec.Code, http.StatusNotFound) {
+					t.Errorf("Want: %v, Got: %v", http.StatusNotFound, rec.Code)
+				}
+			},
+		},
+		{
+			name: "Failure::GetBalance:: invalid account number",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("GET", "/accounts/abc/balance", nil)
+				r = mux.SetURLVars(r, map[string]string{"account_number": "abc"})
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if !reflect.DeepEqual(rec.Code, http.StatusBadRequest) {
+					t.Errorf("Want: %v, Got: %v", http.StatusBadRequest, rec.Code)
+				}
+			},
+		},
+		{
+			name: "Failure::GetBalance:: invalid as_of",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("GET", "/accounts/1/balance?as_of=yesterday", nil)
+				r = mux.SetURLVars(r, map[string]string{"account_number": "1"})
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if !reflect.DeepEqual(rec.Code, http.StatusBadRequest) {
+					t.Errorf("Want: %v, Got: %v", http.StatusBadRequest, rec.Code)
+				}
+			},
+		},
+		{
+			name: "Failure::GetBalance:: Failure assert user_id",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("GET", "/accounts/1/balance", nil)
+				ctx := session.SetSession(r.Context(), "")
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if !reflect.DeepEqual(rec.Code, http.StatusBadRequest) {
+					t.Errorf("Want: %v, Got: %v", http.StatusBadRequest, rec.Code)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			x, r := tt.setup()
+			x.GetBalance(w, r)
+			tt.want(*w)
+		})
+	}
+}
+
+func TestTransactionManagementService_RegisterAccount(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	tests := []struct {
+		name  string
+		setup func() (*transactionManagementService, *http.Request)
+		want  func(recorder httptest.ResponseRecorder)
+	}{
+		{
+			name: "Success::RegisterAccount",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				mockLogic.EXPECT().RegisterAccount(model.NewAccount{AccountNumber: 1, UserId: "1234", Currency: "EUR"}).Times(1).Return(&respModel.Response{
+					Status:  http.StatusCreated,
+					Message: codes.GetErr(codes.Success),
+				})
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("PUT", "/accounts/1", strings.NewReader(`{"user_id":"1234","currency":"EUR"}`))
+				r = mux.SetURLVars(r, map[string]string{"account_number": "1"})
+				return svc, r
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if !reflect.DeepEqual(rec.Code, http.StatusCreated) {
+					t.Errorf("Want: %v, Got: %v", http.StatusCreated, rec.Code)
+				}
+			},
+		},
+		{
+			name: "Failure::RegisterAccount:: invalid account number",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("PUT", "/accounts/abc", strings.NewReader(`{"user_id":"1234"}`))
+				r = mux.SetURLVars(r, map[string]string{"account_number": "abc"})
+				return svc, r
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if !reflect.DeepEqual(rec.Code, http.StatusBadRequest) {
+					t.Errorf("Want: %v, Got: %v", http.StatusBadRequest, rec.Code)
+				}
+			},
+		},
+		{
+			name: "Failure::RegisterAccount:: no user_id",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("PUT", "/accounts/1", strings.NewReader(`{"currency":"EUR"}`))
+				r = mux.SetURLVars(r, map[string]string{"account_number": "1"})
+				return svc, r
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if !reflect.DeepEqual(rec.Code, http.StatusBadRequest) {
+					t.Errorf("Want: %v, Got: %v", http.StatusBadRequest, rec.Code)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			x, r := tt.setup()
+			x.RegisterAccount(w, r)
+			tt.want(*w)
+		})
+	}
+}
+
+func TestTransactionManagementService_CreateSchedule(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	startAt := time.Date(2023, time.July, 1, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		setup func() (*transactionManagementService, *http.Request)
+		want  func(recorder httptest.ResponseRecorder)
+	}{
+		{
+			name: "Success::CreateSchedule",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				mockLogic.EXPECT().CreateSchedule(model.NewSchedule{UserId: "1234", AccountNumber: 1, Amount: 1000, Type: "debit", TransactionStatus: "pending", Frequency: "monthly", StartAt: startAt}).Times(1).Return(&respModel.Response{
+					Status:  http.StatusCreated,
+					Message: codes.GetErr(codes.Success),
+					Data:    model.Schedule{ScheduleId: "s1"},
+				})
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				body := `{"account_number":1,"amount":10,"type":"debit","transaction_status":"pending","frequency":"monthly","start_at":"2023-07-01T09:00:00Z"}`
+				r := httptest.NewRequest("POST", "/schedules", bytes.NewBufferString(body))
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if !reflect.DeepEqual(rec.Code, http.StatusCreated) {
+					t.Errorf("Want: %v, Got: %v", http.StatusCreated, rec.Code)
+				}
+			},
+		},
+		{
+			name: "Failure::CreateSchedule:: invalid frequency",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				body := `{"account_number":1,"amount":10,"type":"debit","transaction_status":"pending","frequency":"yearly","start_at":"2023-07-01T09:00:00Z"}`
+				r := httptest.NewRequest("POST", "/schedules", bytes.NewBufferString(body))
+				ctx := session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"})
+				return svc, r.WithContext(ctx)
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if !reflect.DeepEqual(rec.Code, http.StatusBadRequest) {
+					t.Errorf("Want: %v, Got: %v", http.StatusBadRequest, rec.Code)
+				}
+			},
+		},
+		{
+			name: "Failure::CreateSchedule:: no session",
+			setup: func() (*transactionManagementService, *http.Request) {
+				mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+				svc := &transactionManagementService{
+					logic: mockLogic,
+				}
+				r := httptest.NewRequest("POST", "/schedules", bytes.NewBufferString(`{}`))
+				return svc, r
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if !reflect.DeepEqual(rec.Code, http.StatusBadRequest) {
+					t.Errorf("Want: %v, Got: %v", http.StatusBadRequest, rec.Code)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			svc, r := tt.setup()
+			svc.CreateSchedule(w, r)
+			tt.want(*w)
+		})
+	}
+}
+
+func TestTransactionManagementService_Schedules(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	startAt := time.Date(2023, time.July, 1, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		setup    func(*mock.MockTransactionManagementServiceLogicIer)
+		handler  func(*transactionManagementService) http.HandlerFunc
+		request  func() *http.Request
+		wantCode int
+	}{
+		{
+			name: "Success::GetSchedules",
+			setup: func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {
+				mockLogic.EXPECT().GetSchedules("1234").Times(1).Return(&respModel.Response{Status: http.StatusOK, Message: codes.GetErr(codes.Success), Data: []model.Schedule{}})
+			},
+			handler: func(svc *transactionManagementService) http.HandlerFunc { return svc.GetSchedules },
+			request: func() *http.Request {
+				return httptest.NewRequest("GET", "/schedules", nil)
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "Success::GetSchedule",
+			setup: func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {
+				mockLogic.EXPECT().GetSchedule("s1", "1234").Times(1).Return(&respModel.Response{Status: http.StatusOK, Message: codes.GetErr(codes.Success), Data: model.ScheduleDetail{}})
+			},
+			handler: func(svc *transactionManagementService) http.HandlerFunc { return svc.GetSchedule },
+			request: func() *http.Request {
+				return mux.SetURLVars(httptest.NewRequest("GET", "/schedules/s1", nil), map[string]string{"schedule_id": "s1"})
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:    "Failure::GetSchedule:: no schedule id",
+			setup:   func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {},
+			handler: func(svc *transactionManagementService) http.HandlerFunc { return svc.GetSchedule },
+			request: func() *http.Request {
+				return httptest.NewRequest("GET", "/schedules/s1", nil)
+			},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "Success::UpdateSchedule",
+			setup: func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {
+				mockLogic.EXPECT().UpdateSchedule("s1", "1234", model.NewSchedule{AccountNumber: 1, Amount: 1000, Type: "debit", TransactionStatus: "pending", Frequency: "weekly", StartAt: startAt, Paused: true}).Times(1).Return(&respModel.Response{Status: http.StatusOK, Message: codes.GetErr(codes.Success), Data: model.Schedule{ScheduleId: "s1"}})
+			},
+			handler: func(svc *transactionManagementService) http.HandlerFunc { return svc.UpdateSchedule },
+			request: func() *http.Request {
+				body := `{"account_number":1,"amount":10,"type":"debit","transaction_status":"pending","frequency":"weekly","start_at":"2023-07-01T09:00:00Z","paused":true}`
+				return mux.SetURLVars(httptest.NewRequest("PUT", "/schedules/s1", bytes.NewBufferString(body)), map[string]string{"schedule_id": "s1"})
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:    "Failure::UpdateSchedule:: invalid body",
+			setup:   func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {},
+			handler: func(svc *transactionManagementService) http.HandlerFunc { return svc.UpdateSchedule },
+			request: func() *http.Request {
+				return mux.SetURLVars(httptest.NewRequest("PUT", "/schedules/s1", bytes.NewBufferString(`{"frequency":`)), map[string]string{"schedule_id": "s1"})
+			},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:    "Failure::UpdateSchedule:: no schedule id",
+			setup:   func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {},
+			handler: func(svc *transactionManagementService) http.HandlerFunc { return svc.UpdateSchedule },
+			request: func() *http.Request {
+				return httptest.NewRequest("PUT", "/schedules/s1", bytes.NewBufferString(`{}`))
+			},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "Success::CancelSchedule",
+			setup: func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {
+				mockLogic.EXPECT().CancelSchedule("s1", "1234").Times(1).Return(&respModel.Response{Status: http.StatusConflict, Message: codes.GetErr(codes.ErrScheduleClosed)})
+			},
+			handler: func(svc *transactionManagementService) http.HandlerFunc { return svc.CancelSchedule },
+			request: func() *http.Request {
+				return mux.SetURLVars(httptest.NewRequest("DELETE", "/schedules/s1", nil), map[string]string{"schedule_id": "s1"})
+			},
+			wantCode: http.StatusConflict,
+		},
+		{
+			name:    "Failure::CancelSchedule:: no schedule id",
+			setup:   func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {},
+			handler: func(svc *transactionManagementService) http.HandlerFunc { return svc.CancelSchedule },
+			request: func() *http.Request {
+				return httptest.NewRequest("DELETE", "/schedules/s1", nil)
+			},
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+			tt.setup(mockLogic)
+			svc := &transactionManagementService{
+				logic: mockLogic,
+			}
+			r := tt.request()
+			r = r.WithContext(session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"}))
+			w := httptest.NewRecorder()
+			tt.handler(svc)(w, r)
+			if !reflect.DeepEqual(w.Code, tt.wantCode) {
+				t.Errorf("Want: %v, Got: %v", tt.wantCode, w.Code)
+			}
+		})
+	}
+	// Every schedule route needs the user of the session
+	svc := &transactionManagementService{logic: mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)}
+	for _, handler := range []http.HandlerFunc{svc.GetSchedules, svc.GetSchedule, svc.UpdateSchedule, svc.CancelSchedule} {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest("GET", "/schedules", nil))
+		if !reflect.DeepEqual(w.Code, http.StatusBadRequest) {
+			t.Errorf("Want: %v, Got: %v", http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+func TestTransactionManagementService_GetStatement(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	month := time.Date(2023, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		url   string
+		setup func(*mock.MockTransactionManagementServiceLogicIer)
+		want  func(recorder httptest.ResponseRecorder)
+	}{
+		{
+			name: "Success::GetStatement",
+			url:  "/statements?account_number=1&month=2023-06",
+			setup: func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {
+				mockLogic.EXPECT().GetStatement(1, "1234", month, "token").Times(1).Return(&respModel.Response{
+					Status:  http.StatusOK,
+					Message: codes.GetErr(codes.Success),
+					Data:    []byte("PDF"),
+				})
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if !reflect.DeepEqual(rec.Code, http.StatusOK) {
+					t.Errorf("Want: %v, Got: %v", http.StatusOK, rec.Code)
+				}
+				if rec.Body.String() != "PDF" || rec.Header().Get("Content-Type") != "application/pdf" || rec.Header().Get("Content-Disposition") != "attachment; filename=statement-1-2023-06.pdf" {
+					t.Errorf("Want: %v, Got: %v %v", "the statement pdf", rec.Header(), rec.Body.String())
+				}
+			},
+		},
+		{
+			name: "Failure::GetStatement:: logic error",
+			url:  "/statements?account_number=1&month=2023-06",
+			setup: func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {
+				mockLogic.EXPECT().GetStatement(1, "1234", month, "token").Times(1).Return(&respModel.Response{
+					Status:  http.StatusNotFound,
+					Message: codes.GetErr(codes.ErrNoAccount),
+				})
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if !reflect.DeepEqual(rec.Code, http.StatusNotFound) {
+					t.Errorf("Want: %v, Got: %v", http.StatusNotFound, rec.Code)
+				}
+			},
+		},
+		{
+			name: "Failure::GetStatement:: not a pdf",
+			url:  "/statements?account_number=1&month=2023-06",
+			setup: func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {
+				mockLogic.EXPECT().GetStatement(1, "1234", month, "token").Times(1).Return(&respModel.Response{
+					Status:  http.StatusOK,
+					Message: codes.GetErr(codes.Success),
+				})
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if !reflect.DeepEqual(rec.Code, http.StatusInternalServerError) {
+					t.Errorf("Want: %v, Got: %v", http.StatusInternalServerError, rec.Code)
+				}
+			},
+		},
+		{
+			name:  "Failure::GetStatement:: invalid account number",
+			url:   "/statements?account_number=abc&month=2023-06",
+			setup: func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {},
+			want: func(rec httptest.ResponseRecorder) {
+				b, _ := ioutil.ReadAll(rec.Body)
+				if !reflect.DeepEqual(rec.Code, http.StatusBadRequest) || !strings.Contains(string(b), "account_number must be a positive integer") {
+					t.Errorf("Want: %v, Got: %v %v", http.StatusBadRequest, rec.Code, string(b))
+				}
+			},
+		},
+		{
+			name:  "Failure::GetStatement:: invalid month",
+			url:   "/statements?account_number=1&month=2023-6-1",
+			setup: func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {},
+			want: func(rec httptest.ResponseRecorder) {
+				b, _ := ioutil.ReadAll(rec.Body)
+				if !reflect.DeepEqual(rec.Code, http.StatusBadRequest) || !strings.Contains(string(b), "month must be formatted as YYYY-MM") {
+					t.Errorf("Want: %v, Got: %v %v", http.StatusBadRequest, rec.Code, string(b))
+				}
+			},
+		},
+		{
+			name:  "Failure::GetStatement:: future month",
+			url:   "/statements?account_number=1&month=" + time.Now().AddDate(0, 2, 0).Format("2006-01"),
+			setup: func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {},
+			want: func(rec httptest.ResponseRecorder) {
+				b, _ := ioutil.ReadAll(rec.Body)
+				if !reflect.DeepEqual(rec.Code, http.StatusBadRequest) || !strings.Contains(string(b), "month must not be in the future") {
+					t.Errorf("Want: %v, Got: %v %v", http.StatusBadRequest, rec.Code, string(b))
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+			tt.setup(mockLogic)
+			svc := &transactionManagementService{
+				logic: mockLogic,
+			}
+			r := httptest.NewRequest("GET", tt.url, nil)
+			r = r.WithContext(session.SetSession(r.Context(), model.SessionStruct{UserId: "1234", Cookie: "token"}))
+			w := httptest.NewRecorder()
+			svc.GetStatement(w, r)
+			tt.want(*w)
+		})
+	}
+}
+
+func TestTransactionManagementService_ExportTransactions(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	list := model.ListTransactions{UserId: "1234", Type: "debit"}
+	export := model.CsvExport{Columns: []string{"transaction_id", "amount"}, DateFormat: "date"}
+	filename := fmt.Sprintf(`attachment; filename="transactions-%s.csv"`, time.Now().UTC().Format("2006-01-02"))
+
+	tests := []struct {
+		name  string
+		url   string
+		setup func(*mock.MockTransactionManagementServiceLogicIer)
+		want  func(recorder httptest.ResponseRecorder)
+	}{
+		{
+			name: "Success::ExportTransactions",
+			url:  "/export.csv?type=debit&columns=transaction_id,amount&date_format=date",
+			setup: func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {
+				mockLogic.EXPECT().ExportTransactions(list, export, gomock.Any()).Times(1).DoAndReturn(func(_ model.ListTransactions, _ model.CsvExport, w io.Writer) *respModel.Response {
+					_, _ = w.Write([]byte("transaction_id,amount\r\na,1.00\r\n"))
+					return &respModel.Response{Status: http.StatusOK, Message: codes.GetErr(codes.Success), Data: 1}
+				})
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if !reflect.DeepEqual(rec.Code, http.StatusOK) {
+					t.Errorf("Want: %v, Got: %v", http.StatusOK, rec.Code)
+				}
+				if rec.Body.String() != "transaction_id,amount\r\na,1.00\r\n" || rec.Header().Get("Content-Type") != "text/csv; charset=utf-8" || rec.Header().Get("Content-Disposition") != filename {
+					t.Errorf("Want: %v, Got: %v %v", "the csv file", rec.Header(), rec.Body.String())
+				}
+			},
+		},
+		{
+			name: "Failure::ExportTransactions:: logic error before writing",
+			url:  "/export.csv?type=debit&columns=transaction_id,amount&date_format=date",
+			setup: func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {
+				mockLogic.EXPECT().ExportTransactions(list, export, gomock.Any()).Times(1).Return(&respModel.Response{
+					Status:  http.StatusInternalServerError,
+					Message: codes.GetErr(codes.ErrExport),
+				})
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if !reflect.DeepEqual(rec.Code, http.StatusInternalServerError) {
+					t.Errorf("Want: %v, Got: %v", http.StatusInternalServerError, rec.Code)
+				}
+				if rec.Header().Get("Content-Disposition") != "" || !strings.Contains(rec.Body.String(), codes.GetErr(codes.ErrExport)) {
+					t.Errorf("Want: %v, Got: %v %v", "a json error", rec.Header(), rec.Body.String())
+				}
+			},
+		},
+		{
+			name: "Failure::ExportTransactions:: logic error after writing",
+			url:  "/export.csv?type=debit&columns=transaction_id,amount&date_format=date",
+			setup: func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {
+				mockLogic.EXPECT().ExportTransactions(list, export, gomock.Any()).Times(1).DoAndReturn(func(_ model.ListTransactions, _ model.CsvExport, w io.Writer) *respModel.Response {
+					_, _ = w.Write([]byte("transaction_id,amount\r\n"))
+					return &respModel.Response{Status: http.StatusInternalServerError, Message: codes.GetErr(codes.ErrExport)}
+				})
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if !reflect.DeepEqual(rec.Code, http.StatusOK) {
+					t.Errorf("Want: %v, Got: %v", http.StatusOK, rec.Code)
+				}
+				if rec.Body.String() != "transaction_id,amount\r\n" {
+					t.Errorf("Want: %v, Got: %v", "the truncated csv file", rec.Body.String())
+				}
+			},
+		},
+		{
+			name:  "Failure::ExportTransactions:: invalid filter",
+			url:   "/export.csv?type=refund",
+			setup: func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {},
+			want: func(rec httptest.ResponseRecorder) {
+				b, _ := ioutil.ReadAll(rec.Body)
+				if !reflect.DeepEqual(rec.Code, http.StatusBadRequest) || !strings.Contains(string(b), "type must be credit or debit") {
+					t.Errorf("Want: %v, Got: %v %v", http.StatusBadRequest, rec.Code, string(b))
+				}
+			},
+		},
+		{
+			name:  "Failure::ExportTransactions:: cursor",
+			url:   "/export.csv?cursor=",
+			setup: func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {},
+			want: func(rec httptest.ResponseRecorder) {
+				b, _ := ioutil.ReadAll(rec.Body)
+				if !reflect.DeepEqual(rec.Code, http.StatusBadRequest) || !strings.Contains(string(b), "cursor is not supported by exports") {
+					t.Errorf("Want: %v, Got: %v %v", http.StatusBadRequest, rec.Code, string(b))
+				}
+			},
+		},
+		{
+			name:  "Failure::ExportTransactions:: running balance",
+			url:   "/export.csv?account_number=1&running_balance=true",
+			setup: func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {},
+			want: func(rec httptest.ResponseRecorder) {
+				b, _ := ioutil.ReadAll(rec.Body)
+				if !reflect.DeepEqual(rec.Code, http.StatusBadRequest) || !strings.Contains(string(b), "running_balance is not supported by exports") {
+					t.Errorf("Want: %v, Got: %v %v", http.StatusBadRequest, rec.Code, string(b))
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+			tt.setup(mockLogic)
+			svc := &transactionManagementService{
+				logic: mockLogic,
+			}
+			r := httptest.NewRequest("GET", tt.url, nil)
+			r = r.WithContext(session.SetSession(r.Context(), model.SessionStruct{UserId: "1234", Cookie: "token"}))
+			w := httptest.NewRecorder()
+			svc.ExportTransactions(w, r)
+			tt.want(*w)
+		})
+	}
+}
+
+func TestTransactionManagementService_ExportAccount(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	export := model.AccountExport{
+		UserId:        "1234",
+		AccountNumber: 1,
+		From:          time.Date(2023, time.June, 1, 0, 0, 0, 0, time.UTC),
+		To:            time.Date(2023, time.June, 30, 23, 59, 59, 999999999, time.UTC),
+		Currency:      "EUR",
+		Format:        model.ExportOfx,
+	}
+
+	tests := []struct {
+		name   string
+		format string
+		url    string
+		setup  func(*mock.MockTransactionManagementServiceLogicIer)
+		want   func(recorder httptest.ResponseRecorder)
+	}{
+		{
+			name:   "Success::ExportAccount",
+			format: "ofx",
+			url:    "/export.ofx?account_number=1&from=2023-06-01&to=2023-06-30&currency=eur",
+			setup: func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {
+				mockLogic.EXPECT().ExportAccount(export, gomock.Any()).Times(1).DoAndReturn(func(_ model.AccountExport, w io.Writer) *respModel.Response {
+					_, _ = w.Write([]byte("<OFX></OFX>"))
+					return &respModel.Response{Status: http.StatusOK, Message: codes.GetErr(codes.Success), Data: 0}
+				})
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if !reflect.DeepEqual(rec.Code, http.StatusOK) {
+					t.Errorf("Want: %v, Got: %v", http.StatusOK, rec.Code)
+				}
+				if rec.Body.String() != "<OFX></OFX>" || rec.Header().Get("Content-Type") != "application/x-ofx" || rec.Header().Get("Content-Disposition") != `attachment; filename="transactions-1-20230601-20230630.ofx"` {
+					t.Errorf("Want: %v, Got: %v %v", "the ofx file", rec.Header(), rec.Body.String())
+				}
+			},
+		},
+		{
+			name:   "Success::ExportAccount:: camt053",
+			format: "camt053",
+			url:    "/export.camt053?account_number=1&from=2023-06-01&to=2023-06-30",
+			setup: func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {
+				mockLogic.EXPECT().ExportAccount(gomock.Any(), gomock.Any()).Times(1).DoAndReturn(func(_ model.AccountExport, w io.Writer) *respModel.Response {
+					_, _ = w.Write([]byte("<Document></Document>"))
+					return &respModel.Response{Status: http.StatusOK, Message: codes.GetErr(codes.Success), Data: 0}
+				})
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if rec.Header().Get("Content-Type") != "application/xml" || rec.Header().Get("Content-Disposition") != `attachment; filename="transactions-1-20230601-20230630.xml"` {
+					t.Errorf("Want: %v, Got: %v", "the camt.053 file", rec.Header())
+				}
+			},
+		},
+		{
+			name:   "Success::ExportAccount:: mt940",
+			format: "mt940",
+			url:    "/export.mt940?account_number=1&from=2023-06-01&to=2023-06-30",
+			setup: func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {
+				mockLogic.EXPECT().ExportAccount(gomock.Any(), gomock.Any()).Times(1).DoAndReturn(func(_ model.AccountExport, w io.Writer) *respModel.Response {
+					_, _ = w.Write([]byte(":20:1-230630\r\n-\r\n"))
+					return &respModel.Response{Status: http.StatusOK, Message: codes.GetErr(codes.Success), Data: 0}
+				})
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if rec.Header().Get("Content-Type") != "text/plain" || rec.Header().Get("Content-Disposition") != `attachment; filename="transactions-1-20230601-20230630.sta"` {
+					t.Errorf("Want: %v, Got: %v", "the MT940 file", rec.Header())
+				}
+			},
+		},
+		{
+			name:   "Failure::ExportAccount:: logic error",
+			format: "qfx",
+			url:    "/export.qfx?account_number=1&from=2023-06-01&to=2023-06-30",
+			setup: func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {
+				mockLogic.EXPECT().ExportAccount(gomock.Any(), gomock.Any()).Times(1).Return(&respModel.Response{
+					Status:  http.StatusNotImplemented,
+					Message: codes.GetErr(codes.ErrNoQfxBankId),
+				})
+			},
+			want: func(rec httptest.ResponseRecorder) {
+				if !reflect.DeepEqual(rec.Code, http.StatusNotImplemented) {
+					t.Errorf("Want: %v, Got: %v", http.StatusNotImplemented, rec.Code)
+				}
+			},
+		},
+		{
+			name:   "Failure::ExportAccount:: unsupported format",
+			format: "xls",
+			url:    "/export.xls?account_number=1&from=2023-06-01&to=2023-06-30",
+			setup:  func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {},
+			want: func(rec httptest.ResponseRecorder) {
+				b, _ := ioutil.ReadAll(rec.Body)
+				if !reflect.DeepEqual(rec.Code, http.StatusBadRequest) || !strings.Contains(string(b), "is not supported") {
+					t.Errorf("Want: %v, Got: %v %v", http.StatusBadRequest, rec.Code, string(b))
+				}
+			},
+		},
+		{
+			name:   "Failure::ExportAccount:: missing account number",
+			format: "qif",
+			url:    "/export.qif?from=2023-06-01&to=2023-06-30",
+			setup:  func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {},
+			want: func(rec httptest.ResponseRecorder) {
+				b, _ := ioutil.ReadAll(rec.Body)
+				if !reflect.DeepEqual(rec.Code, http.StatusBadRequest) || !strings.Contains(string(b), "account_number is required") {
+					t.Errorf("Want: %v, Got: %v %v", http.StatusBadRequest, rec.Code, string(b))
+				}
+			},
+		},
+		{
+			name:   "Failure::ExportAccount:: missing from",
+			format: "qif",
+			url:    "/export.qif?account_number=1&to=2023-06-30",
+			setup:  func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {},
+			want: func(rec httptest.ResponseRecorder) {
+				b, _ := ioutil.ReadAll(rec.Body)
+				if !reflect.DeepEqual(rec.Code, http.StatusBadRequest) || !strings.Contains(string(b), "from must be an RFC 3339 timestamp") {
+					t.Errorf("Want: %v, Got: %v %v", http.StatusBadRequest, rec.Code, string(b))
+				}
+			},
+		},
+		{
+			name:   "Failure::ExportAccount:: inverted range",
+			format: "qif",
+			url:    "/export.qif?account_number=1&from=2023-07-01&to=2023-06-30",
+			setup:  func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {},
+			want: func(rec httptest.ResponseRecorder) {
+				b, _ := ioutil.ReadAll(rec.Body)
+				if !reflect.DeepEqual(rec.Code, http.StatusBadRequest) || !strings.Contains(string(b), "from must not be after to") {
+					t.Errorf("Want: %v, Got: %v %v", http.StatusBadRequest, rec.Code, string(b))
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+			tt.setup(mockLogic)
+			svc := &transactionManagementService{
+				logic: mockLogic,
+			}
+			r := httptest.NewRequest("GET", tt.url, nil)
+			r = r.WithContext(session.SetSession(r.Context(), model.SessionStruct{UserId: "1234", Cookie: "token"}))
+			r = mux.SetURLVars(r, map[string]string{"format": tt.format})
+			w := httptest.NewRecorder()
+			svc.ExportAccount(w, r)
+			tt.want(*w)
+		})
+	}
+}
+
+func TestTransactionManagementService_ImportTransactions(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	// form builds a multipart form holding the given parts, in order
+	form := func(parts map[string]string, names ...string) (string, string) {
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		for _, name := range names {
+			part, err := writer.CreateFormFile(name, name+".csv")
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _ = part.Write([]byte(parts[name]))
+		}
+		_ = writer.Close()
+		return buf.String(), writer.FormDataContentType()
+	}
+	// expectImport makes the mock logic check the import it is given along with the file it reads
+	expectImport := func(mockLogic *mock.MockTransactionManagementServiceLogicIer, want model.ImportTransactions, wantFile string) {
+		mockLogic.EXPECT().ImportTransactions(gomock.Any(), gomock.Any()).Times(1).DoAndReturn(func(imp model.ImportTransactions, r io.Reader) *respModel.Response {
+			diff := testutil.Diff(imp, want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+			file, _ := ioutil.ReadAll(r)
+			if string(file) != wantFile {
+				t.Errorf("Want: %v, Got: %v", wantFile, string(file))
+			}
+			return &respModel.Response{Status: http.StatusCreated, Message: "SUCCESS", Data: model.ImportReport{Rows: 1, Accepted: 1, Imported: 1}}
+		})
+	}
+	multipartFile, multipartType := form(map[string]string{"notes": "ignored", "file": "created_at,amount\n"}, "notes", "file")
+	noFile, noFileType := form(map[string]string{"notes": "ignored"}, "notes")
+	tests := []struct {
+		name        string
+		url         string
+		body        string
+		contentType string
+		noSession   bool
+		setup       func(*mock.MockTransactionManagementServiceLogicIer)
+		wantStatus  int
+		wantBody    string
+	}{
+		{
+			name:        "Success::ImportTransactions:: csv body",
+			url:         "/import?account_number=1&date_format=eu&column=created_at:Datum&column=amount:Betrag:EUR",
+			body:        "Datum,Betrag:EUR\n",
+			contentType: "text/csv",
+			setup: func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {
+				expectImport(mockLogic, model.ImportTransactions{
+					UserId:        "1234",
+					Format:        model.ImportCsv,
+					AccountNumber: 1,
+					Columns:       map[string]string{"created_at": "Datum", "amount": "Betrag:EUR"},
+					DateFormat:    "eu",
+				}, "Datum,Betrag:EUR\n")
+			},
+			wantStatus: http.StatusCreated,
+			wantBody:   `"imported":1`,
+		},
+		{
+			name:        "Success::ImportTransactions:: multipart upload",
+			url:         "/import?format=OFX&account_number=2&dry_run=true",
+			body:        multipartFile,
+			contentType: multipartType,
+			setup: func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {
+				expectImport(mockLogic, model.ImportTransactions{UserId: "1234", Format: model.ImportOfx, AccountNumber: 2, DryRun: true}, "created_at,amount\n")
+			},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name: "Failure::ImportTransactions:: logic error",
+			url:  "/import",
+			setup: func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {
+				mockLogic.EXPECT().ImportTransactions(gomock.Any(), gomock.Any()).Times(1).Return(&respModel.Response{
+					Status:  http.StatusRequestEntityTooLarge,
+					Message: codes.GetErr(codes.ErrImportTooLarge),
+				})
+			},
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantBody:   codes.GetErr(codes.ErrImportTooLarge),
+		},
+		{
+			name:        "Failure::ImportTransactions:: form without a file",
+			url:         "/import",
+			body:        noFile,
+			contentType: noFileType,
+			setup:       func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {},
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    "the form holds no file",
+		},
+		{
+			name:       "Failure::ImportTransactions:: invalid dry_run",
+			url:        "/import?dry_run=maybe",
+			setup:      func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "dry_run must be true or false",
+		},
+		{
+			name:       "Failure::ImportTransactions:: invalid account number",
+			url:        "/import?account_number=-1",
+			setup:      func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "account_number must be a positive integer",
+		},
+		{
+			name:       "Failure::ImportTransactions:: invalid column",
+			url:        "/import?column=amount",
+			setup:      func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `column \"amount\" must be given as field:header`,
+		},
+		{
+			name:       "Failure::ImportTransactions:: no session",
+			url:        "/import",
+			noSession:  true,
+			setup:      func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   codes.GetErr(codes.ErrAssertUserid),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+			tt.setup(mockLogic)
+			svc := &transactionManagementService{
+				logic: mockLogic,
+			}
+			r := httptest.NewRequest("POST", tt.url, strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+			if !tt.noSession {
+				r = r.WithContext(session.SetSession(r.Context(), model.SessionStruct{UserId: "1234", Cookie: "token"}))
+			}
+			w := httptest.NewRecorder()
+			svc.ImportTransactions(w, r)
+			b, _ := ioutil.ReadAll(w.Body)
+			if w.Code != tt.wantStatus || !strings.Contains(string(b), tt.wantBody) {
+				t.Errorf("Want: %v %v, Got: %v %v", tt.wantStatus, tt.wantBody, w.Code, string(b))
+			}
+		})
+	}
+}
+
+func TestTransactionManagementService_NewBatch(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	tests := []struct {
+		name       string
+		body       string
+		noSession  bool
+		setup      func(*mock.MockTransactionManagementServiceLogicIer)
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name: "Success::NewBatch",
+			body: `{"mode":"atomic","transactions":[{"account_number":1,"amount":"10.50","type":"debit","transfer_to":2},{"account_number":1,"amount":"5","status":"pending","type":"credit","currency":"EUR"}]}`,
+			setup: func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {
+				mockLogic.EXPECT().NewBatch(model.NewBatch{
+					UserId: "1234",
+					Mode:   model.BatchAtomic,
+					Transactions: []model.NewTransaction{
+						{AccountNumber: 1, Amount: 1050, Type: "debit", TransferTo: 2},
+						{AccountNumber: 1, Amount: 500, Status: "pending", Type: "credit", Currency: "EUR"},
+					},
+				}).Times(1).Return(&respModel.Response{
+					Status:  http.StatusCreated,
+					Message: "SUCCESS",
+					Data:    model.BatchResult{BatchId: "b1", Mode: model.BatchAtomic, Created: 2},
+				})
+			},
+			wantStatus: http.StatusCreated,
+			wantBody:   `"batch_id":"b1"`,
+		},
+		{
+			name: "Success::NewBatch:: partial outcome passed through",
+			body: `{"mode":"partial","transactions":[{"account_number":1,"amount":"5","status":"pending","type":"credit"}]}`,
+			setup: func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {
+				mockLogic.EXPECT().NewBatch(gomock.Any()).Times(1).Return(&respModel.Response{
+					Status:  http.StatusMultiStatus,
+					Message: codes.GetErr(codes.ErrBatchPartial),
+					Data:    model.BatchResult{BatchId: "b1", Mode: model.BatchPartial, Failed: 1},
+				})
+			},
+			wantStatus: http.StatusMultiStatus,
+			wantBody:   codes.GetErr(codes.ErrBatchPartial),
+		},
+		{
+			name:       "Failure::NewBatch:: unknown mode",
+			body:       `{"mode":"eventual","transactions":[{"account_number":1,"amount":"5","status":"pending","type":"credit"}]}`,
+			setup:      func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "Mode",
+		},
+		{
+			name:       "Failure::NewBatch:: invalid transaction",
+			body:       `{"mode":"atomic","transactions":[{"account_number":1,"amount":"5","status":"done","type":"credit"}]}`,
+			setup:      func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "Status",
+		},
+		{
+			name:       "Failure::NewBatch:: no transactions",
+			body:       `{"mode":"atomic","transactions":[]}`,
+			setup:      func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   codes.GetErr(codes.ErrEmptyBatch),
+		},
+		{
+			name:       "Failure::NewBatch:: no session",
+			body:       `{"mode":"atomic","transactions":[{"account_number":1,"amount":"5","status":"pending","type":"credit"}]}`,
+			noSession:  true,
+			setup:      func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   codes.GetErr(codes.ErrAssertUserid),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+			tt.setup(mockLogic)
+			svc := &transactionManagementService{
+				logic: mockLogic,
+			}
+			r := httptest.NewRequest("POST", "/batch", strings.NewReader(tt.body))
+			if !tt.noSession {
+				r = r.WithContext(session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"}))
+			}
+			w := httptest.NewRecorder()
+			svc.NewBatch(w, r)
+			b, _ := ioutil.ReadAll(w.Body)
+			if w.Code != tt.wantStatus || !strings.Contains(string(b), tt.wantBody) {
+				t.Errorf("Want: %v %v, Got: %v %v", tt.wantStatus, tt.wantBody, w.Code, string(b))
+			}
+		})
+	}
+}
+
+func TestTransactionManagementService_Webhooks(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	ids := map[string]string{"webhook_id": "w1", "delivery_id": "d1"}
+
+	tests := []struct {
+		name     string
+		setup    func(*mock.MockTransactionManagementServiceLogicIer)
+		handler  func(*transactionManagementService) http.HandlerFunc
+		request  func() *http.Request
+		wantCode int
+	}{
+		{
+			name: "Success::CreateWebhook",
+			setup: func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {
+				mockLogic.EXPECT().CreateWebhook(model.NewWebhook{UserId: "1234", ClientId: "payroll", Url: "https://example.com/hook", Events: []string{model.EventTransactionCreated}}).Times(1).Return(&respModel.Response{Status: http.StatusCreated, Message: codes.GetErr(codes.Success), Data: model.Webhook{WebhookId: "w1"}})
+			},
+			handler: func(svc *transactionManagementService) http.HandlerFunc { return svc.CreateWebhook },
+			request: func() *http.Request {
+				body := `{"client_id":"payroll","url":"https://example.com/hook","events":["transaction.created"]}`
+				return httptest.NewRequest("POST", "/webhooks", bytes.NewBufferString(body))
+			},
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:    "Failure::CreateWebhook:: no url",
+			setup:   func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {},
+			handler: func(svc *transactionManagementService) http.HandlerFunc { return svc.CreateWebhook },
+			request: func() *http.Request {
+				return httptest.NewRequest("POST", "/webhooks", bytes.NewBufferString(`{"events":["transaction.created"]}`))
+			},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "Success::GetWebhooks",
+			setup: func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {
+				mockLogic.EXPECT().GetWebhooks("1234").Times(1).Return(&respModel.Response{Status: http.StatusOK, Message: codes.GetErr(codes.Success), Data: []model.Webhook{}})
+			},
+			handler: func(svc *transactionManagementService) http.HandlerFunc { return svc.GetWebhooks },
+			request: func() *http.Request {
+				return httptest.NewRequest("GET", "/webhooks", nil)
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "Success::DeleteWebhook",
+			setup: func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {
+				mockLogic.EXPECT().DeleteWebhook("w1", "1234").Times(1).Return(&respModel.Response{Status: http.StatusNotFound, Message: codes.GetErr(codes.ErrNoWebhook)})
+			},
+			handler: func(svc *transactionManagementService) http.HandlerFunc { return svc.DeleteWebhook },
+			request: func() *http.Request {
+				return mux.SetURLVars(httptest.NewRequest("DELETE", "/webhooks/w1", nil), ids)
+			},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:    "Failure::DeleteWebhook:: no webhook id",
+			setup:   func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {},
+			handler: func(svc *transactionManagementService) http.HandlerFunc { return svc.DeleteWebhook },
+			request: func() *http.Request {
+				return httptest.NewRequest("DELETE", "/webhooks/w1", nil)
+			},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "Success::GetWebhookDeliveries",
+			setup: func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {
+				mockLogic.EXPECT().GetWebhookDeliveries("w1", "1234").Times(1).Return(&respModel.Response{Status: http.StatusOK, Message: codes.GetErr(codes.Success), Data: []model.WebhookDelivery{}})
+			},
+			handler: func(svc *transactionManagementService) http.HandlerFunc { return svc.GetWebhookDeliveries },
+			request: func() *http.Request {
+				return mux.SetURLVars(httptest.NewRequest("GET", "/webhooks/w1/deliveries", nil), ids)
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:    "Failure::GetWebhookDeliveries:: no webhook id",
+			setup:   func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {},
+			handler: func(svc *transactionManagementService) http.HandlerFunc { return svc.GetWebhookDeliveries },
+			request: func() *http.Request {
+				return httptest.NewRequest("GET", "/webhooks/w1/deliveries", nil)
+			},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "Success::RedeliverWebhook",
+			setup: func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {
+				mockLogic.EXPECT().RedeliverWebhook("w1", "d1", "1234").Times(1).Return(&respModel.Response{Status: http.StatusAccepted, Message: codes.GetErr(codes.Success), Data: model.WebhookDelivery{DeliveryId: "d1"}})
+			},
+			handler: func(svc *transactionManagementService) http.HandlerFunc { return svc.RedeliverWebhook },
+			request: func() *http.Request {
+				return mux.SetURLVars(httptest.NewRequest("POST", "/webhooks/w1/deliveries/d1/redeliver", nil), ids)
+			},
+			wantCode: http.StatusAccepted,
+		},
+		{
+			name:    "Failure::RedeliverWebhook:: no ids",
+			setup:   func(mockLogic *mock.MockTransactionManagementServiceLogicIer) {},
+			handler: func(svc *transactionManagementService) http.HandlerFunc { return svc.RedeliverWebhook },
+			request: func() *http.Request {
+				return httptest.NewRequest("POST", "/webhooks/w1/deliveries/d1/redeliver", nil)
+			},
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+			tt.setup(mockLogic)
+			svc := &transactionManagementService{
+				logic: mockLogic,
+			}
+			r := tt.request()
+			r = r.WithContext(session.SetSession(r.Context(), model.SessionStruct{UserId: "1234"}))
+			w := httptest.NewRecorder()
+			tt.handler(svc)(w, r)
+			if !reflect.DeepEqual(w.Code, tt.wantCode) {
+				t.Errorf("Want: %v, Got: %v", tt.wantCode, w.Code)
+			}
+		})
+	}
+	// Every webhook route needs the user of the session
+	svc := &transactionManagementService{logic: mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)}
+	for _, handler := range []http.HandlerFunc{svc.CreateWebhook, svc.GetWebhooks, svc.DeleteWebhook, svc.GetWebhookDeliveries, svc.RedeliverWebhook} {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest("GET", "/webhooks", nil))
+		if !reflect.DeepEqual(w.Code, http.StatusBadRequest) {
+			t.Errorf("Want: %v, Got: %v", http.StatusBadRequest, w.Code)
+		}
+	}
 }
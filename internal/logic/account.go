@@ -0,0 +1,59 @@
+package logic
+
+import (
+	"github.com/PereRohit/util/log"
+	respModel "github.com/PereRohit/util/model"
+	"github.com/vatsal278/TransactionManagementService/internal/codes"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"net/http"
+)
+
+// RegisterAccount records the owner of an account on behalf of the account service. Registering an account again
+// with the same owner changes nothing, while an account cannot be moved to another owner.
+func (l transactionManagementServiceLogic) RegisterAccount(newAccount model.NewAccount) *respModel.Response {
+	account := model.Account{AccountNumber: newAccount.AccountNumber, UserId: newAccount.UserId, Currency: newAccount.Currency}
+	if account.Currency == "" {
+		account.Currency = model.DefaultCurrency
+	}
+	if _, ok := model.CurrencyExponent(account.Currency); !ok {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidCurrency),
+			Data:    nil,
+		}
+	}
+	inserted, err := l.DsSvc.InsertAccount(account)
+	if err != nil {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrAccount),
+			Data:    nil,
+		}
+	}
+	status := http.StatusCreated
+	if !inserted {
+		registered, err := l.DsSvc.GetAccount(account.AccountNumber)
+		if err != nil {
+			log.Error(err)
+			return &respModel.Response{
+				Status:  http.StatusInternalServerError,
+				Message: codes.GetErr(codes.ErrAccount),
+				Data:    nil,
+			}
+		}
+		if registered == nil || registered.UserId != account.UserId {
+			return &respModel.Response{
+				Status:  http.StatusConflict,
+				Message: codes.GetErr(codes.ErrAccountOwner),
+				Data:    nil,
+			}
+		}
+		status = http.StatusOK
+	}
+	return &respModel.Response{
+		Status:  status,
+		Message: "SUCCESS",
+		Data:    nil,
+	}
+}
@@ -0,0 +1,182 @@
+package logic
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/PereRohit/util/log"
+	respModel "github.com/PereRohit/util/model"
+	"github.com/vatsal278/TransactionManagementService/internal/codes"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// accountFile describes the account and period an exported file covers
+type accountFile struct {
+	AccountNumber int
+	Currency      string
+	From          time.Time
+	To            time.Time
+	Opening       model.Money // Balance in Currency right before From
+	Closing       model.Money // Balance in Currency at To
+	GeneratedAt   time.Time
+}
+
+// accountFileWriter writes the settled transactions of an account in one of the formats read by personal finance
+// software and ERPs. The header is written first, then every transaction in the order they were made, then the footer.
+type accountFileWriter interface {
+	header(file accountFile) error
+	transaction(transaction model.Transaction) error
+	footer(file accountFile) error
+}
+
+// signedAmount returns the amount of the transaction as it moved the balance, negative for debits
+func signedAmount(transaction model.Transaction) model.Money {
+	if transaction.Type == "debit" {
+		return -transaction.Amount
+	}
+	return transaction.Amount
+}
+
+// counterparty names the other account of a transfer, or returns an empty string for other transactions
+func counterparty(transaction model.Transaction) string {
+	if transaction.TransferTo == 0 {
+		return ""
+	}
+	return "Account " + strconv.Itoa(transaction.TransferTo)
+}
+
+// truncate shortens s to at most n characters, for formats limiting the length of text fields
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
+
+// accountFileWriter returns the writer of the requested format, or the response to send when it cannot be served
+func (l transactionManagementServiceLogic) accountFileWriter(format string, w *bufio.Writer) (accountFileWriter, *respModel.Response) {
+	switch format {
+	case model.ExportOfx:
+		return newOfxWriter(w, l.UtilSvc.Ofx, false), nil
+	case model.ExportQfx:
+		if l.UtilSvc.Ofx.IntuBid == "" {
+			return nil, &respModel.Response{
+				Status:  http.StatusNotImplemented,
+				Message: codes.GetErr(codes.ErrNoQfxBankId),
+				Data:    nil,
+			}
+		}
+		return newOfxWriter(w, l.UtilSvc.Ofx, true), nil
+	case model.ExportQif:
+		return qifWriter{w: w}, nil
+	case model.ExportCamt053:
+		return camtWriter{w: w}, nil
+	case model.ExportMt940:
+		return &mt940Writer{w: w}, nil
+	}
+	return nil, &respModel.Response{
+		Status:  http.StatusBadRequest,
+		Message: fmt.Sprintf("%s: format %q is not supported", codes.GetErr(codes.ErrInvalidFilter), format),
+		Data:    nil,
+	}
+}
+
+// ExportAccount writes the settled transactions made on one of the user's accounts in one currency between From and
+// To to w, in the format read by personal finance software or ERPs that was asked for. Transactions in other currencies are
+// left out as the formats hold a single currency per account. Rows are streamed from the datasource and buffered, so
+// nothing reaches w when the export fails before the first few kilobytes are written.
+func (l transactionManagementServiceLogic) ExportAccount(export model.AccountExport, w io.Writer) *respModel.Response {
+	currency := export.Currency
+	if currency == "" {
+		currency = model.DefaultCurrency
+	}
+	if _, ok := model.CurrencyExponent(currency); !ok {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: fmt.Sprintf("%s: currency %q is not supported", codes.GetErr(codes.ErrInvalidFilter), currency),
+			Data:    nil,
+		}
+	}
+	buf := bufio.NewWriter(w)
+	writer, errResp := l.accountFileWriter(export.Format, buf)
+	if errResp != nil {
+		return errResp
+	}
+	errResp = l.checkAccountOwner(export.UserId, export.AccountNumber, codes.GetErr(codes.ErrGetBalance))
+	if errResp != nil {
+		return errResp
+	}
+	file := accountFile{
+		AccountNumber: export.AccountNumber,
+		Currency:      currency,
+		From:          export.From,
+		To:            export.To,
+		GeneratedAt:   time.Now(),
+	}
+	for _, b := range []struct {
+		asOf    time.Time
+		balance *model.Money
+	}{{export.From.Add(-time.Nanosecond), &file.Opening}, {export.To, &file.Closing}} {
+		balances, err := l.DsSvc.GetBalances(export.AccountNumber, export.UserId, b.asOf)
+		if err != nil {
+			log.Error(err)
+			return &respModel.Response{
+				Status:  http.StatusInternalServerError,
+				Message: codes.GetErr(codes.ErrExport),
+				Data:    nil,
+			}
+		}
+		for _, balance := range balances {
+			if balance.Currency == currency {
+				*b.balance = balance.Balance
+			}
+		}
+	}
+	statuses := make([]interface{}, 0, len(model.SettledStatuses))
+	for _, status := range model.SettledStatuses {
+		statuses = append(statuses, status)
+	}
+	// The transactions are selected as the list endpoint would list them, oldest first, narrowed to the settled
+	// ones in the currency of the file.
+	list := model.ListTransactions{
+		UserId:        export.UserId,
+		AccountNumber: export.AccountNumber,
+		From:          &export.From,
+		To:            &export.To,
+		Sort:          []model.OrderBy{{Column: "created_at"}},
+	}
+	rows := 0
+	err := writer.header(file)
+	if err == nil {
+		err = l.DsSvc.Stream(model.Query{
+			Where:   model.And(listFilter(list), model.Eq("currency", currency), model.In("status", statuses...)),
+			OrderBy: listOrder(list),
+		}, func(transaction model.Transaction) error {
+			rows++
+			return writer.transaction(transaction)
+		})
+	}
+	if err == nil {
+		err = writer.footer(file)
+	}
+	if err == nil {
+		err = buf.Flush()
+	}
+	if err != nil {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrExport),
+			Data:    nil,
+		}
+	}
+	return &respModel.Response{
+		Status:  http.StatusOK,
+		Message: "SUCCESS",
+		Data:    rows,
+	}
+}
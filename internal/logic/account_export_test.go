@@ -0,0 +1,178 @@
+package logic
+
+import (
+	"bytes"
+	"errors"
+	respModel "github.com/PereRohit/util/model"
+	"github.com/PereRohit/util/testutil"
+	"github.com/golang/mock/gomock"
+	"github.com/vatsal278/TransactionManagementService/internal/codes"
+	"github.com/vatsal278/TransactionManagementService/internal/config"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"github.com/vatsal278/TransactionManagementService/pkg/mock"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTransactionManagementServiceLogic_ExportAccount(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	from := time.Date(2023, time.June, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, time.June, 30, 23, 59, 59, 0, time.UTC)
+	owned := &model.Account{AccountNumber: 1, UserId: "123", Currency: "USD"}
+	settled := model.Query{
+		Where: model.And(
+			model.And(model.Eq("user_id", "123"), model.Gte("created_at", from), model.Lte("created_at", to), model.Eq("account_number", 1)),
+			model.Eq("currency", "EUR"),
+			model.In("status", model.StatusApproved, model.StatusReversed),
+		),
+		OrderBy: []model.OrderBy{{Column: "created_at"}, {Column: "transaction_id"}},
+	}
+	createdAt := time.Date(2023, time.June, 15, 10, 30, 0, 0, time.UTC)
+	transactions := []model.Transaction{
+		{TransactionId: "a", Type: "credit", Amount: 5000, CreatedAt: createdAt, Comment: "salary"},
+		{TransactionId: "b", Type: "debit", Amount: 1250, CreatedAt: createdAt.Add(time.Hour), TransferTo: 2},
+	}
+	stream := func(transactions []model.Transaction, err error) func(model.Query, func(model.Transaction) error) error {
+		return func(_ model.Query, fn func(model.Transaction) error) error {
+			for _, transaction := range transactions {
+				streamErr := fn(transaction)
+				if streamErr != nil {
+					return streamErr
+				}
+			}
+			return err
+		}
+	}
+	tests := []struct {
+		name     string
+		export   model.AccountExport
+		ofx      config.OfxCfg
+		setup    func() *mock.MockDataSourceI
+		want     respModel.Response
+		validate func(data []byte)
+	}{
+		{
+			name:   "Success::ofx",
+			export: model.AccountExport{UserId: "123", AccountNumber: 1, From: from, To: to, Currency: "EUR", Format: model.ExportOfx},
+			ofx:    config.OfxCfg{Org: "MicroBank", BankId: "000000000"},
+			setup: func() *mock.MockDataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetAccount(1).Times(1).Return(owned, nil)
+				mockDs.EXPECT().GetBalances(1, "123", from.Add(-time.Nanosecond)).Times(1).Return([]model.Balance{{Currency: "EUR", Balance: 0}}, nil)
+				mockDs.EXPECT().GetBalances(1, "123", to).Times(1).Return([]model.Balance{{Currency: "EUR", Balance: 3750}, {Currency: "USD", Balance: 100}}, nil)
+				mockDs.EXPECT().Stream(settled, gomock.Any()).Times(1).DoAndReturn(stream(transactions, nil))
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusOK, Message: "SUCCESS", Data: 2},
+			validate: func(data []byte) {
+				doc, got := parseOfx(t, data)
+				diff := testutil.Diff(got, transactions)
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+				diff = testutil.Diff([]string{doc.Statement.Currency, doc.Statement.Balance}, []string{"EUR", "37.50"})
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+			},
+		},
+		{
+			name:   "Success::qif",
+			export: model.AccountExport{UserId: "123", AccountNumber: 1, From: from, To: to, Currency: "EUR", Format: model.ExportQif},
+			setup: func() *mock.MockDataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetAccount(1).Times(1).Return(owned, nil)
+				mockDs.EXPECT().GetBalances(1, "123", from.Add(-time.Nanosecond)).Times(1).Return(nil, nil)
+				mockDs.EXPECT().GetBalances(1, "123", to).Times(1).Return(nil, nil)
+				mockDs.EXPECT().Stream(settled, gomock.Any()).Times(1).DoAndReturn(stream(transactions, nil))
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusOK, Message: "SUCCESS", Data: 2},
+			validate: func(data []byte) {
+				diff := testutil.Diff(string(data), "!Type:Bank\nD06/15/2023\nT50.00\nMsalary\n^\nD06/15/2023\nT-12.50\nPAccount 2\n^\n")
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+			},
+		},
+		{
+			name:   "Failure::unsupported currency",
+			export: model.AccountExport{UserId: "123", AccountNumber: 1, From: from, To: to, Currency: "XYZ", Format: model.ExportOfx},
+			setup: func() *mock.MockDataSourceI {
+				return mock.NewMockDataSourceI(mockCtrl)
+			},
+			want: respModel.Response{Status: http.StatusBadRequest, Message: codes.GetErr(codes.ErrInvalidFilter) + `: currency "XYZ" is not supported`},
+		},
+		{
+			name:   "Failure::unsupported format",
+			export: model.AccountExport{UserId: "123", AccountNumber: 1, From: from, To: to, Format: "csv"},
+			setup: func() *mock.MockDataSourceI {
+				return mock.NewMockDataSourceI(mockCtrl)
+			},
+			want: respModel.Response{Status: http.StatusBadRequest, Message: codes.GetErr(codes.ErrInvalidFilter) + `: format "csv" is not supported`},
+		},
+		{
+			name:   "Failure::qfx without a bank id",
+			export: model.AccountExport{UserId: "123", AccountNumber: 1, From: from, To: to, Format: model.ExportQfx},
+			setup: func() *mock.MockDataSourceI {
+				return mock.NewMockDataSourceI(mockCtrl)
+			},
+			want: respModel.Response{Status: http.StatusNotImplemented, Message: codes.GetErr(codes.ErrNoQfxBankId)},
+		},
+		{
+			name:   "Failure::account of another user",
+			export: model.AccountExport{UserId: "123", AccountNumber: 1, From: from, To: to, Format: model.ExportQif},
+			setup: func() *mock.MockDataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetAccount(1).Times(1).Return(&model.Account{AccountNumber: 1, UserId: "999"}, nil)
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusNotFound, Message: codes.GetErr(codes.ErrNoAccount)},
+		},
+		{
+			name:   "Failure::balance error",
+			export: model.AccountExport{UserId: "123", AccountNumber: 1, From: from, To: to, Currency: "EUR", Format: model.ExportOfx},
+			setup: func() *mock.MockDataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetAccount(1).Times(1).Return(owned, nil)
+				mockDs.EXPECT().GetBalances(1, "123", from.Add(-time.Nanosecond)).Times(1).Return(nil, errors.New("connection reset"))
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusInternalServerError, Message: codes.GetErr(codes.ErrExport)},
+		},
+		{
+			name:   "Failure::stream error",
+			export: model.AccountExport{UserId: "123", AccountNumber: 1, From: from, To: to, Currency: "EUR", Format: model.ExportOfx},
+			setup: func() *mock.MockDataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetAccount(1).Times(1).Return(owned, nil)
+				mockDs.EXPECT().GetBalances(1, "123", from.Add(-time.Nanosecond)).Times(1).Return(nil, nil)
+				mockDs.EXPECT().GetBalances(1, "123", to).Times(1).Return(nil, nil)
+				mockDs.EXPECT().Stream(settled, gomock.Any()).Times(1).DoAndReturn(stream(transactions, errors.New("connection reset")))
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusInternalServerError, Message: codes.GetErr(codes.ErrExport)},
+			validate: func(data []byte) {
+				if len(data) != 0 {
+					t.Errorf("Want: %v, Got: %s", "nothing written", data)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := NewTransactionManagementServiceLogic(tt.setup(), config.ExternalSvc{Ofx: tt.ofx})
+			var buf bytes.Buffer
+			got := rec.ExportAccount(tt.export, &buf)
+			if !reflect.DeepEqual(got, &tt.want) {
+				t.Errorf("Want: %v, Got: %v", &tt.want, got)
+			}
+			if tt.validate != nil {
+				tt.validate(buf.Bytes())
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package logic
+
+import (
+	"errors"
+	respModel "github.com/PereRohit/util/model"
+	"github.com/PereRohit/util/testutil"
+	"github.com/golang/mock/gomock"
+	"github.com/vatsal278/TransactionManagementService/internal/codes"
+	"github.com/vatsal278/TransactionManagementService/internal/config"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"github.com/vatsal278/TransactionManagementService/pkg/mock"
+	"net/http"
+	"testing"
+)
+
+func TestTransactionManagementServiceLogic_RegisterAccount(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	account := model.Account{AccountNumber: 1, UserId: "123", Currency: "USD"}
+	tests := []struct {
+		name       string
+		newAccount model.NewAccount
+		setup      func(*mock.MockDataSourceI)
+		want       respModel.Response
+	}{
+		{
+			name:       "Success::account registered",
+			newAccount: model.NewAccount{AccountNumber: 1, UserId: "123"},
+			setup: func(mockDs *mock.MockDataSourceI) {
+				mockDs.EXPECT().InsertAccount(account).Times(1).Return(true, nil)
+			},
+			want: respModel.Response{Status: http.StatusCreated, Message: "SUCCESS"},
+		},
+		{
+			name:       "Success::account registered again to the same user",
+			newAccount: model.NewAccount{AccountNumber: 1, UserId: "123", Currency: "USD"},
+			setup: func(mockDs *mock.MockDataSourceI) {
+				mockDs.EXPECT().InsertAccount(account).Times(1).Return(false, nil)
+				mockDs.EXPECT().GetAccount(1).Times(1).Return(&account, nil)
+			},
+			want: respModel.Response{Status: http.StatusOK, Message: "SUCCESS"},
+		},
+		{
+			name:       "Failure::account registered to another user",
+			newAccount: model.NewAccount{AccountNumber: 1, UserId: "999"},
+			setup: func(mockDs *mock.MockDataSourceI) {
+				mockDs.EXPECT().InsertAccount(model.Account{AccountNumber: 1, UserId: "999", Currency: "USD"}).Times(1).Return(false, nil)
+				mockDs.EXPECT().GetAccount(1).Times(1).Return(&account, nil)
+			},
+			want: respModel.Response{Status: http.StatusConflict, Message: codes.GetErr(codes.ErrAccountOwner)},
+		},
+		{
+			name:       "Failure::unsupported currency",
+			newAccount: model.NewAccount{AccountNumber: 1, UserId: "123", Currency: "XYZ"},
+			setup:      func(mockDs *mock.MockDataSourceI) {},
+			want:       respModel.Response{Status: http.StatusBadRequest, Message: codes.GetErr(codes.ErrInvalidCurrency)},
+		},
+		{
+			name:       "Failure::insert error",
+			newAccount: model.NewAccount{AccountNumber: 1, UserId: "123"},
+			setup: func(mockDs *mock.MockDataSourceI) {
+				mockDs.EXPECT().InsertAccount(account).Times(1).Return(false, errors.New("error"))
+			},
+			want: respModel.Response{Status: http.StatusInternalServerError, Message: codes.GetErr(codes.ErrAccount)},
+		},
+		{
+			name:       "Failure::lookup error",
+			newAccount: model.NewAccount{AccountNumber: 1, UserId: "123"},
+			setup: func(mockDs *mock.MockDataSourceI) {
+				mockDs.EXPECT().InsertAccount(account).Times(1).Return(false, nil)
+				mockDs.EXPECT().GetAccount(1).Times(1).Return(nil, errors.New("error"))
+			},
+			want: respModel.Response{Status: http.StatusInternalServerError, Message: codes.GetErr(codes.ErrAccount)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDs := mock.NewMockDataSourceI(mockCtrl)
+			tt.setup(mockDs)
+			rec := NewTransactionManagementServiceLogic(mockDs, config.ExternalSvc{})
+			diff := testutil.Diff(*rec.RegisterAccount(tt.newAccount), tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package logic
+
+import (
+	"github.com/PereRohit/util/log"
+	respModel "github.com/PereRohit/util/model"
+	"github.com/vatsal278/TransactionManagementService/internal/codes"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// checkAccountOwner responds with not found unless the user owns the account, so accounts of other users cannot be
+// told apart from accounts that do not exist. When ownership cannot be checked it responds with errMessage.
+func (l transactionManagementServiceLogic) checkAccountOwner(userId string, accountNumber int, errMessage string) *respModel.Response {
+	owner, err := l.ownsAccount(userId, accountNumber)
+	if err != nil {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: errMessage,
+			Data:    nil,
+		}
+	}
+	if !owner {
+		return &respModel.Response{
+			Status:  http.StatusNotFound,
+			Message: codes.GetErr(codes.ErrNoAccount),
+			Data:    nil,
+		}
+	}
+	return nil
+}
+
+// GetBalance computes the balance of one of the user's accounts from the ledger, counting the settled transactions
+// created up to asOf, or up to now when asOf is nil
+func (l transactionManagementServiceLogic) GetBalance(accountNumber int, userId string, asOf *time.Time) *respModel.Response {
+	errResp := l.checkAccountOwner(userId, accountNumber, codes.GetErr(codes.ErrGetBalance))
+	if errResp != nil {
+		return errResp
+	}
+	at := time.Now()
+	if asOf != nil {
+		at = *asOf
+	}
+	balances, err := l.DsSvc.GetBalances(accountNumber, userId, at)
+	if err != nil {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrGetBalance),
+			Data:    nil,
+		}
+	}
+	return &respModel.Response{
+		Status:  http.StatusOK,
+		Message: "SUCCESS",
+		Data:    model.AccountBalance{AccountNumber: accountNumber, AsOf: at, Balances: balances},
+	}
+}
+
+// addRunningBalances sets the balance of the user's account right after each of the transactions listed
+func (l transactionManagementServiceLogic) addRunningBalances(accountNumber int, userId string, transactions []model.Transaction) error {
+	ids := make([]string, 0, len(transactions))
+	for _, transaction := range transactions {
+		ids = append(ids, transaction.TransactionId)
+	}
+	balances, err := l.DsSvc.GetRunningBalances(accountNumber, userId, ids)
+	if err != nil {
+		return err
+	}
+	for i := range transactions {
+		balance, ok := balances[transactions[i].TransactionId]
+		if ok {
+			transactions[i].RunningBalance = &balance
+		}
+	}
+	return nil
+}
+
+// invalidateCache moves the cache generation of every user owning one of the legs written, so their cached listings
+// and balances are no longer served. Failures are only logged as the write has already happened, the cached
+// responses then expire on their own.
+func (l transactionManagementServiceLogic) invalidateCache(legs []model.Transaction) {
+	if l.UtilSvc.Cacher == nil {
+		return
+	}
+	generation := strconv.FormatInt(time.Now().UnixNano(), 10)
+	invalidated := map[string]bool{}
+	for _, leg := range legs {
+		if invalidated[leg.UserId] {
+			continue
+		}
+		invalidated[leg.UserId] = true
+		err := l.UtilSvc.Cacher.Set(model.CacheGenerationKey(leg.UserId), generation, 0)
+		if err != nil {
+			log.Error(err)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package logic
+
+import (
+	"errors"
+	respModel "github.com/PereRohit/util/model"
+	"github.com/PereRohit/util/testutil"
+	"github.com/golang/mock/gomock"
+	"github.com/vatsal278/TransactionManagementService/internal/codes"
+	"github.com/vatsal278/TransactionManagementService/internal/config"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"github.com/vatsal278/TransactionManagementService/internal/repo/datasource"
+	"github.com/vatsal278/TransactionManagementService/pkg/mock"
+	redisMock "github.com/vatsal278/go-redis-cache/mocks"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTransactionManagementServiceLogic_GetBalance(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	owned := &model.Account{AccountNumber: 1, UserId: "123", Currency: "USD"}
+	asOf := time.Date(2023, time.March, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		asOf  *time.Time
+		setup func() datasource.DataSourceI
+		want  func(*respModel.Response)
+	}{
+		{
+			name: "Success::balance as of a time",
+			asOf: &asOf,
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetAccount(1).Times(1).Return(owned, nil)
+				mockDs.EXPECT().GetBalances(1, "123", asOf).Times(1).Return([]model.Balance{{Currency: "USD", Balance: 1050}}, nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusOK,
+					Message: "SUCCESS",
+					Data:    model.AccountBalance{AccountNumber: 1, AsOf: asOf, Balances: []model.Balance{{Currency: "USD", Balance: 1050}}},
+				}
+				diff := testutil.Diff(resp, &temp)
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+			},
+		},
+		{
+			name: "Success::balance now",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetAccount(1).Times(1).Return(owned, nil)
+				mockDs.EXPECT().GetBalances(1, "123", gomock.Any()).Times(1).Return([]model.Balance{}, nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				balance, ok := resp.Data.(model.AccountBalance)
+				if resp.Status != http.StatusOK || !ok || time.Since(balance.AsOf) > time.Minute {
+					t.Errorf("Want: %v, Got: %v", "the balance as of now", resp)
+				}
+			},
+		},
+		{
+			name: "Failure::account of another user",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetAccount(1).Times(1).Return(&model.Account{AccountNumber: 1, UserId: "999"}, nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusNotFound,
+					Message: codes.GetErr(codes.ErrNoAccount),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+		{
+			name: "Failure::unregistered account",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetAccount(1).Times(1).Return(nil, nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusNotFound,
+					Message: codes.GetErr(codes.ErrNoAccount),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+		{
+			name: "Failure::owner lookup error",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetAccount(1).Times(1).Return(nil, errors.New("error"))
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				if resp.Status != http.StatusInternalServerError {
+					t.Errorf("Want: %v, Got: %v", http.StatusInternalServerError, resp.Status)
+				}
+			},
+		},
+		{
+			name: "Failure::balance error",
+			asOf: &asOf,
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetAccount(1).Times(1).Return(owned, nil)
+				mockDs.EXPECT().GetBalances(1, "123", asOf).Times(1).Return(nil, errors.New("error"))
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusInternalServerError,
+					Message: codes.GetErr(codes.ErrGetBalance),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := NewTransactionManagementServiceLogic(tt.setup(), config.ExternalSvc{})
+			tt.want(rec.GetBalance(1, "123", tt.asOf))
+		})
+	}
+}
+
+func TestTransactionManagementServiceLogic_GetTransactionsRunningBalance(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	owned := &model.Account{AccountNumber: 1, UserId: "123", Currency: "USD"}
+	list := model.ListTransactions{UserId: "123", AccountNumber: 1, RunningBalance: true, Limit: 5, Page: 1}
+	page := model.Query{Where: model.And(model.Eq("user_id", "123"), model.Eq("account_number", 1)), OrderBy: []model.OrderBy{{Column: "created_at", Desc: true}, {Column: "transaction_id"}}, Limit: 5}
+	balance := func(m model.Money) *model.Money { return &m }
+	tests := []struct {
+		name  string
+		setup func() datasource.DataSourceI
+		want  func(*respModel.Response)
+	}{
+		{
+			name: "Success::running balance of each transaction",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetAccount(1).Times(1).Return(owned, nil)
+				mockDs.EXPECT().Get(page).Times(1).Return([]model.Transaction{{TransactionId: "b"}, {TransactionId: "a"}}, 2, nil)
+				mockDs.EXPECT().GetRunningBalances(1, "123", []string{"b", "a"}).Times(1).Return(map[string]model.Money{"a": 1000, "b": 750}, nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				res, _ := resp.Data.(model.PaginatedResponse)
+				diff := testutil.Diff(res.Response, []model.Transaction{{TransactionId: "b", RunningBalance: balance(750)}, {TransactionId: "a", RunningBalance: balance(1000)}})
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+			},
+		},
+		{
+			name: "Failure::account of another user",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetAccount(1).Times(1).Return(&model.Account{AccountNumber: 1, UserId: "999"}, nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				if resp.Status != http.StatusNotFound {
+					t.Errorf("Want: %v, Got: %v", http.StatusNotFound, resp.Status)
+				}
+			},
+		},
+		{
+			name: "Failure::running balance error",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetAccount(1).Times(1).Return(owned, nil)
+				mockDs.EXPECT().Get(page).Times(1).Return([]model.Transaction{{TransactionId: "a"}}, 1, nil)
+				mockDs.EXPECT().GetRunningBalances(1, "123", []string{"a"}).Times(1).Return(nil, errors.New("error"))
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusInternalServerError,
+					Message: codes.GetErr(codes.ErrGetBalance),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := NewTransactionManagementServiceLogic(tt.setup(), config.ExternalSvc{})
+			tt.want(rec.GetTransactions(list))
+		})
+	}
+}
+
+func TestTransactionManagementServiceLogic_InvalidateCache(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockCacher := redisMock.NewMockCacher(mockCtrl)
+	var generation interface{}
+	mockCacher.EXPECT().Set(model.CacheGenerationKey("123"), gomock.Any(), time.Duration(0)).Times(1).DoAndReturn(func(key string, value interface{}, expiry time.Duration) error {
+		generation = value
+		return nil
+	})
+	mockCacher.EXPECT().Set(model.CacheGenerationKey("456"), gomock.Any(), time.Duration(0)).Times(1).DoAndReturn(func(key string, value interface{}, expiry time.Duration) error {
+		if value != generation {
+			t.Errorf("Want: %v, Got: %v", generation, value)
+		}
+		return errors.New("error")
+	})
+	l := transactionManagementServiceLogic{UtilSvc: config.ExternalSvc{Cacher: mockCacher}}
+	l.invalidateCache([]model.Transaction{{UserId: "123"}, {UserId: "456"}, {UserId: "123"}})
+}
@@ -0,0 +1,127 @@
+package logic
+
+import (
+	"errors"
+	"fmt"
+	"github.com/PereRohit/util/log"
+	respModel "github.com/PereRohit/util/model"
+	"github.com/google/uuid"
+	"github.com/vatsal278/TransactionManagementService/internal/codes"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"github.com/vatsal278/TransactionManagementService/internal/repo/datasource"
+	"net/http"
+)
+
+// batchAbort is returned to roll back an atomic batch once one of its transactions is not created
+type batchAbort struct {
+	item model.BatchItem
+}
+
+func (e batchAbort) Error() string {
+	return fmt.Sprintf("transaction %d of the batch failed: %s", e.item.Index, e.item.Message)
+}
+
+// batchItem returns the outcome of creating one transaction of a batch. Transactions held for review are stored and
+// count as created, while those the risk rules rejected are not. A partial batch stores a rejected transaction and its
+// rule hits for audit, but an atomic batch rolls them back along with the rest of the batch.
+func batchItem(index int, legs []model.Transaction, resp *respModel.Response) (model.BatchItem, bool) {
+	item := model.BatchItem{Index: index, Status: resp.Status, Message: resp.Message}
+	if len(legs) > 0 {
+		item.TransactionId = legs[0].TransactionId
+	}
+	return item, resp.Status < http.StatusMultipleChoices
+}
+
+// NewBatch creates several transactions of the user through the same logic as NewTransaction, linked by a new batch
+// id. An atomic batch creates every transaction in one database transaction and rolls them all back once one of them
+// is not created, while a partial batch creates each one on its own and reports the outcome of each.
+func (l transactionManagementServiceLogic) NewBatch(batch model.NewBatch) *respModel.Response {
+	if len(batch.Transactions) > l.UtilSvc.Batch.MaxSize {
+		return &respModel.Response{
+			Status:  http.StatusRequestEntityTooLarge,
+			Message: fmt.Sprintf("%s: %d transactions, at most %d", codes.GetErr(codes.ErrBatchTooLarge), len(batch.Transactions), l.UtilSvc.Batch.MaxSize),
+			Data:    nil,
+		}
+	}
+	result := model.BatchResult{BatchId: uuid.NewString(), Mode: batch.Mode, Items: []model.BatchItem{}}
+	for i := range batch.Transactions {
+		batch.Transactions[i].UserId = batch.UserId
+		batch.Transactions[i].BatchId = result.BatchId
+	}
+	if batch.Mode == model.BatchAtomic {
+		return l.newAtomicBatch(batch, result)
+	}
+
+	var created []model.Transaction
+	for i, newTransaction := range batch.Transactions {
+		legs, resp := l.createTransaction(newTransaction)
+		created = append(created, legs...)
+		item, ok := batchItem(i, legs, resp)
+		if ok {
+			result.Created++
+		} else {
+			result.Failed++
+		}
+		result.Items = append(result.Items, item)
+	}
+	l.invalidateCache(created)
+	if result.Failed > 0 {
+		return &respModel.Response{
+			Status:  http.StatusMultiStatus,
+			Message: codes.GetErr(codes.ErrBatchPartial),
+			Data:    result,
+		}
+	}
+	return &respModel.Response{
+		Status:  http.StatusCreated,
+		Message: "SUCCESS",
+		Data:    result,
+	}
+}
+
+// newAtomicBatch creates every transaction of a batch in one database transaction, or none of them. A batch rolled
+// back responds with the status of the transaction that failed, along with its outcome. Nothing of a transaction the
+// risk rules rejected is kept, its outcome in the response being the only record of the rejection.
+func (l transactionManagementServiceLogic) newAtomicBatch(batch model.NewBatch, result model.BatchResult) *respModel.Response {
+	var created []model.Transaction
+	err := l.DsSvc.Transaction(func(ds datasource.DataSourceI) error {
+		// Bind the transaction logic to the database transaction, so every transaction of the batch is written in it
+		bound := transactionManagementServiceLogic{DsSvc: ds, UtilSvc: l.UtilSvc}
+		created = nil
+		result.Items = []model.BatchItem{}
+		for i, newTransaction := range batch.Transactions {
+			legs, resp := bound.createTransaction(newTransaction)
+			item, ok := batchItem(i, legs, resp)
+			if !ok {
+				return batchAbort{item: item}
+			}
+			created = append(created, legs...)
+			result.Items = append(result.Items, item)
+		}
+		return nil
+	})
+	var abort batchAbort
+	if errors.As(err, &abort) {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  abort.item.Status,
+			Message: codes.GetErr(codes.ErrBatchRolledBack),
+			Data:    model.BatchResult{BatchId: result.BatchId, Mode: result.Mode, Failed: 1, Items: []model.BatchItem{abort.item}},
+		}
+	}
+	if err != nil {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrBatch),
+			Data:    nil,
+		}
+	}
+	l.invalidateCache(created)
+	result.Created = len(result.Items)
+	return &respModel.Response{
+		Status:  http.StatusCreated,
+		Message: "SUCCESS",
+		Data:    result,
+	}
+}
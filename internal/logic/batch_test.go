@@ -0,0 +1,168 @@
+package logic
+
+import (
+	"errors"
+	respModel "github.com/PereRohit/util/model"
+	"github.com/PereRohit/util/testutil"
+	"github.com/golang/mock/gomock"
+	"github.com/vatsal278/TransactionManagementService/internal/codes"
+	"github.com/vatsal278/TransactionManagementService/internal/config"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"github.com/vatsal278/TransactionManagementService/internal/repo/datasource"
+	"github.com/vatsal278/TransactionManagementService/pkg/mock"
+	"net/http"
+	"testing"
+)
+
+func TestTransactionManagementServiceLogic_NewBatch(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	// inTransaction makes the mock run the functions given to Transaction against itself
+	inTransaction := func(mockDs *mock.MockDataSourceI, times int) {
+		mockDs.EXPECT().Transaction(gomock.Any()).Times(times).DoAndReturn(func(fn func(datasource.DataSourceI) error) error {
+			return fn(mockDs)
+		})
+	}
+	// owned registers account 1 to the user, once for every transaction that gets past validation
+	owned := func(mockDs *mock.MockDataSourceI, times int) {
+		mockDs.EXPECT().GetAccount(1).Times(times).Return(&model.Account{AccountNumber: 1, UserId: "123", Currency: "USD"}, nil)
+	}
+	// batchIds collects the batch id of every transaction inserted
+	var batchIds []string
+	insert := func(mockDs *mock.MockDataSourceI, err error) {
+		mockDs.EXPECT().Insert(gomock.Any()).Times(1).DoAndReturn(func(tr model.Transaction) error {
+			batchIds = append(batchIds, tr.BatchId)
+			return err
+		})
+	}
+	pending := model.NewTransaction{AccountNumber: 1, Amount: 1000, Status: "pending", Type: "credit"}
+	euro := model.NewTransaction{AccountNumber: 1, Amount: 2000, Type: "credit", Currency: "EUR"}
+	invalid := model.NewTransaction{AccountNumber: 1, Amount: 1000, Status: "pending", Type: "credit", Currency: "XYZ"}
+	cfg := config.ExternalSvc{Batch: config.BatchCfg{MaxSize: 2}}
+	tests := []struct {
+		name  string
+		batch model.NewBatch
+		setup func(*mock.MockDataSourceI)
+		want  respModel.Response
+	}{
+		{
+			name:  "Success::atomic",
+			batch: model.NewBatch{UserId: "123", Mode: model.BatchAtomic, Transactions: []model.NewTransaction{pending, euro}},
+			setup: func(mockDs *mock.MockDataSourceI) {
+				inTransaction(mockDs, 3)
+				owned(mockDs, 2)
+				insert(mockDs, nil)
+				insert(mockDs, nil)
+				mockDs.EXPECT().InsertStatusHistory(gomock.Any()).Times(2).Return(nil)
+				mockDs.EXPECT().InsertWebhookDeliveries(gomock.Any(), gomock.Any()).Times(2).Return(nil)
+			},
+			want: respModel.Response{Status: http.StatusCreated, Message: "SUCCESS", Data: model.BatchResult{Mode: model.BatchAtomic, Created: 2, Items: []model.BatchItem{
+				{Index: 0, Status: http.StatusCreated, Message: "SUCCESS"},
+				{Index: 1, Status: http.StatusCreated, Message: "SUCCESS"},
+			}}},
+		},
+		{
+			name:  "Success::partial",
+			batch: model.NewBatch{UserId: "123", Mode: model.BatchPartial, Transactions: []model.NewTransaction{pending, pending}},
+			setup: func(mockDs *mock.MockDataSourceI) {
+				inTransaction(mockDs, 2)
+				owned(mockDs, 2)
+				insert(mockDs, nil)
+				insert(mockDs, nil)
+				mockDs.EXPECT().InsertStatusHistory(gomock.Any()).Times(2).Return(nil)
+				mockDs.EXPECT().InsertWebhookDeliveries(gomock.Any(), gomock.Any()).Times(2).Return(nil)
+			},
+			want: respModel.Response{Status: http.StatusCreated, Message: "SUCCESS", Data: model.BatchResult{Mode: model.BatchPartial, Created: 2, Items: []model.BatchItem{
+				{Index: 0, Status: http.StatusCreated, Message: "SUCCESS"},
+				{Index: 1, Status: http.StatusCreated, Message: "SUCCESS"},
+			}}},
+		},
+		{
+			name:  "Success::partial with a failed transaction",
+			batch: model.NewBatch{UserId: "123", Mode: model.BatchPartial, Transactions: []model.NewTransaction{invalid, pending}},
+			setup: func(mockDs *mock.MockDataSourceI) {
+				inTransaction(mockDs, 1)
+				owned(mockDs, 1)
+				insert(mockDs, nil)
+				mockDs.EXPECT().InsertStatusHistory(gomock.Any()).Times(1).Return(nil)
+				mockDs.EXPECT().InsertWebhookDeliveries(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			},
+			want: respModel.Response{Status: http.StatusMultiStatus, Message: codes.GetErr(codes.ErrBatchPartial), Data: model.BatchResult{Mode: model.BatchPartial, Created: 1, Failed: 1, Items: []model.BatchItem{
+				{Index: 0, Status: http.StatusBadRequest, Message: codes.GetErr(codes.ErrInvalidCurrency)},
+				{Index: 1, Status: http.StatusCreated, Message: "SUCCESS"},
+			}}},
+		},
+		{
+			name:  "Failure::atomic rolled back on an invalid transaction",
+			batch: model.NewBatch{UserId: "123", Mode: model.BatchAtomic, Transactions: []model.NewTransaction{pending, invalid}},
+			setup: func(mockDs *mock.MockDataSourceI) {
+				inTransaction(mockDs, 2)
+				owned(mockDs, 1)
+				insert(mockDs, nil)
+				mockDs.EXPECT().InsertStatusHistory(gomock.Any()).Times(1).Return(nil)
+				mockDs.EXPECT().InsertWebhookDeliveries(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			},
+			want: respModel.Response{Status: http.StatusBadRequest, Message: codes.GetErr(codes.ErrBatchRolledBack), Data: model.BatchResult{Mode: model.BatchAtomic, Failed: 1, Items: []model.BatchItem{
+				{Index: 1, Status: http.StatusBadRequest, Message: codes.GetErr(codes.ErrInvalidCurrency)},
+			}}},
+		},
+		{
+			name:  "Failure::atomic rolled back on a database error",
+			batch: model.NewBatch{UserId: "123", Mode: model.BatchAtomic, Transactions: []model.NewTransaction{euro}},
+			setup: func(mockDs *mock.MockDataSourceI) {
+				inTransaction(mockDs, 2)
+				owned(mockDs, 1)
+				insert(mockDs, errors.New("sql error"))
+			},
+			want: respModel.Response{Status: http.StatusInternalServerError, Message: codes.GetErr(codes.ErrBatchRolledBack), Data: model.BatchResult{Mode: model.BatchAtomic, Failed: 1, Items: []model.BatchItem{
+				{Index: 0, Status: http.StatusInternalServerError, Message: codes.GetErr(codes.ErrNewTransaction)},
+			}}},
+		},
+		{
+			name:  "Failure::atomic commit error",
+			batch: model.NewBatch{UserId: "123", Mode: model.BatchAtomic, Transactions: []model.NewTransaction{pending}},
+			setup: func(mockDs *mock.MockDataSourceI) {
+				mockDs.EXPECT().Transaction(gomock.Any()).Times(1).Return(errors.New("commit failed"))
+			},
+			want: respModel.Response{Status: http.StatusInternalServerError, Message: codes.GetErr(codes.ErrBatch)},
+		},
+		{
+			name:  "Failure::too many transactions",
+			batch: model.NewBatch{UserId: "123", Mode: model.BatchPartial, Transactions: []model.NewTransaction{pending, pending, pending}},
+			setup: func(mockDs *mock.MockDataSourceI) {},
+			want:  respModel.Response{Status: http.StatusRequestEntityTooLarge, Message: codes.GetErr(codes.ErrBatchTooLarge) + ": 3 transactions, at most 2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batchIds = nil
+			mockDs := mock.NewMockDataSourceI(mockCtrl)
+			tt.setup(mockDs)
+			rec := NewTransactionManagementServiceLogic(mockDs, cfg)
+			got := rec.NewBatch(tt.batch)
+			// Batch and transaction ids are random, so check they are set and link the transactions inserted
+			if result, ok := got.Data.(model.BatchResult); ok {
+				if result.BatchId == "" {
+					t.Errorf("Want: %v, Got: %v", "a batch id", result.BatchId)
+				}
+				for _, batchId := range batchIds {
+					if batchId != result.BatchId {
+						t.Errorf("Want: %v, Got: %v", result.BatchId, batchId)
+					}
+				}
+				result.BatchId = ""
+				for i, item := range result.Items {
+					if item.Status < http.StatusMultipleChoices && item.TransactionId == "" {
+						t.Errorf("Want: %v, Got: %v", "a transaction id", item.TransactionId)
+					}
+					result.Items[i].TransactionId = ""
+				}
+				got.Data = result
+			}
+			diff := testutil.Diff(*got, tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package logic
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// camt053Namespace is the namespace of the version of camt.053 written, the one most ERPs read
+const camt053Namespace = "urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"
+
+// Lengths camt.053 limits text fields to
+const (
+	camtReferenceLength  = 35
+	camtRemittanceLength = 140
+)
+
+// camtAmount is an amount with its currency, always positive as the direction is given by a credit debit indicator
+type camtAmount struct {
+	Ccy   string `xml:"Ccy,attr"`
+	Value string `xml:",chardata"`
+}
+
+// camtDate is a DateAndDateTimeChoice, holding either a date or a date and time
+type camtDate struct {
+	Dt   string `xml:"Dt,omitempty"`
+	DtTm string `xml:"DtTm,omitempty"`
+}
+
+// camtText is a simple element holding text
+type camtText struct {
+	XMLName xml.Name
+	Value   string `xml:",chardata"`
+}
+
+// camtAccount identifies an account by its account number
+type camtAccount struct {
+	XMLName xml.Name // Named by the field holding it when empty
+	Id      string   `xml:"Id>Othr>Id"`
+	Ccy     string   `xml:"Ccy,omitempty"`
+}
+
+type camtGroupHeader struct {
+	XMLName xml.Name `xml:"GrpHdr"`
+	MsgId   string   `xml:"MsgId"`
+	CreDtTm string   `xml:"CreDtTm"`
+}
+
+type camtPeriod struct {
+	XMLName xml.Name `xml:"FrToDt"`
+	FrDtTm  string   `xml:"FrDtTm"`
+	ToDtTm  string   `xml:"ToDtTm"`
+}
+
+type camtBalance struct {
+	XMLName   xml.Name   `xml:"Bal"`
+	Code      string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amt       camtAmount `xml:"Amt"`
+	CdtDbtInd string     `xml:"CdtDbtInd"`
+	Dt        camtDate   `xml:"Dt"`
+}
+
+type camtTransactionDetails struct {
+	AcctSvcrRef string          `xml:"Refs>AcctSvcrRef"`
+	DbtrAcct    *camtAccount    `xml:"RltdPties>DbtrAcct,omitempty"`
+	CdtrAcct    *camtAccount    `xml:"RltdPties>CdtrAcct,omitempty"`
+	RmtInf      *camtRemittance `xml:"RmtInf,omitempty"`
+}
+
+// camtRemittance holds the comment of a transaction as unstructured remittance information
+type camtRemittance struct {
+	Ustrd string `xml:"Ustrd"`
+}
+
+type camtEntry struct {
+	XMLName     xml.Name               `xml:"Ntry"`
+	NtryRef     string                 `xml:"NtryRef"`
+	Amt         camtAmount             `xml:"Amt"`
+	CdtDbtInd   string                 `xml:"CdtDbtInd"`
+	Sts         string                 `xml:"Sts"`
+	BookgDt     camtDate               `xml:"BookgDt"`
+	AcctSvcrRef string                 `xml:"AcctSvcrRef"`
+	Domain      string                 `xml:"BkTxCd>Domn>Cd"`
+	Family      string                 `xml:"BkTxCd>Domn>Fmly>Cd"`
+	SubFamily   string                 `xml:"BkTxCd>Domn>Fmly>SubFmlyCd"`
+	Details     camtTransactionDetails `xml:"NtryDtls>TxDtls"`
+}
+
+// camtWriter writes an ISO 20022 camt.053 bank to customer statement holding a single statement of the account.
+// Every exported transaction is booked, so entries are given the BOOK status.
+type camtWriter struct {
+	w *bufio.Writer
+}
+
+// camtTime formats t as an ISODateTime in UTC
+func camtTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// camtReference fits a transaction id into the 35 characters of a camt.053 reference. Ids in the UUID format are
+// 36 characters long, so longer ids lose their hyphens first.
+func camtReference(id string) string {
+	if len(id) > camtReferenceLength {
+		id = strings.ReplaceAll(id, "-", "")
+	}
+	return truncate(id, camtReferenceLength)
+}
+
+// camtIndicator returns the credit debit indicator and the positive amount of a signed amount
+func camtIndicator(amount model.Money) (string, model.Money) {
+	if amount < 0 {
+		return "DBIT", -amount
+	}
+	return "CRDT", amount
+}
+
+// marshal writes v indented by depth levels, followed by a line break
+func (c camtWriter) marshal(v interface{}, depth int) error {
+	data, err := xml.MarshalIndent(v, strings.Repeat("  ", depth), "  ")
+	if err != nil {
+		return err
+	}
+	_, err = c.w.Write(append(data, '\n'))
+	return err
+}
+
+// balance returns the Bal element of the balance with the given code on day
+func (c camtWriter) balance(code string, currency string, amount model.Money, day time.Time) camtBalance {
+	indicator, amount := camtIndicator(amount)
+	return camtBalance{
+		Code:      code,
+		Amt:       camtAmount{Ccy: currency, Value: amount.String()},
+		CdtDbtInd: indicator,
+		Dt:        camtDate{Dt: day.UTC().Format("2006-01-02")},
+	}
+}
+
+func (c camtWriter) header(file accountFile) error {
+	_, err := c.w.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+		`<Document xmlns="` + camt053Namespace + `">` + "\n  <BkToCstmrStmt>\n")
+	if err != nil {
+		return err
+	}
+	id := fmt.Sprintf("%d-%s-%s", file.AccountNumber, file.From.UTC().Format("20060102"), file.To.UTC().Format("20060102"))
+	err = c.marshal(camtGroupHeader{MsgId: truncate(id+"-"+file.GeneratedAt.UTC().Format("150405"), camtReferenceLength), CreDtTm: camtTime(file.GeneratedAt)}, 2)
+	if err != nil {
+		return err
+	}
+	_, err = c.w.WriteString("    <Stmt>\n")
+	if err != nil {
+		return err
+	}
+	// The elements of the statement are marshalled one by one, leaving it open for the balances and entries.
+	for _, v := range []interface{}{
+		camtText{XMLName: xml.Name{Local: "Id"}, Value: truncate(id, camtReferenceLength)},
+		camtText{XMLName: xml.Name{Local: "CreDtTm"}, Value: camtTime(file.GeneratedAt)},
+		camtPeriod{FrDtTm: camtTime(file.From), ToDtTm: camtTime(file.To)},
+		camtAccount{XMLName: xml.Name{Local: "Acct"}, Id: strconv.Itoa(file.AccountNumber), Ccy: file.Currency},
+		c.balance("OPBD", file.Currency, file.Opening, file.From),
+		c.balance("CLBD", file.Currency, file.Closing, file.To),
+	} {
+		err = c.marshal(v, 3)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c camtWriter) transaction(transaction model.Transaction) error {
+	indicator, amount := camtIndicator(signedAmount(transaction))
+	reference := camtReference(transaction.TransactionId)
+	entry := camtEntry{
+		NtryRef:     reference,
+		Amt:         camtAmount{Ccy: transaction.Currency, Value: amount.String()},
+		CdtDbtInd:   indicator,
+		Sts:         "BOOK",
+		BookgDt:     camtDate{DtTm: camtTime(transaction.CreatedAt)},
+		AcctSvcrRef: reference,
+		Domain:      "PMNT",
+		Family:      "RCDT",
+		SubFamily:   "OTHR",
+		Details:     camtTransactionDetails{AcctSvcrRef: reference},
+	}
+	if transaction.Comment != "" {
+		entry.Details.RmtInf = &camtRemittance{Ustrd: truncate(transaction.Comment, camtRemittanceLength)}
+	}
+	if indicator == "DBIT" {
+		entry.Family = "ICDT"
+	}
+	if transaction.TransferTo != 0 {
+		entry.SubFamily = "DMCT"
+		counterparty := &camtAccount{Id: strconv.Itoa(transaction.TransferTo)}
+		if indicator == "DBIT" {
+			entry.Details.CdtrAcct = counterparty
+		} else {
+			entry.Details.DbtrAcct = counterparty
+		}
+	}
+	return c.marshal(entry, 3)
+}
+
+func (c camtWriter) footer(accountFile) error {
+	_, err := c.w.WriteString("    </Stmt>\n  </BkToCstmrStmt>\n</Document>\n")
+	return err
+}
@@ -0,0 +1,293 @@
+package logic
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"github.com/PereRohit/util/testutil"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files of the export tests")
+
+// camtField is an element of an XSD sequence or choice, of the named complex or simple type
+type camtField struct {
+	name     string
+	typ      string
+	min, max int // max of -1 is unbounded
+}
+
+// camtComplexType is the content model of an XSD complex type, a sequence of fields unless it is a choice
+type camtComplexType struct {
+	choice bool
+	fields []camtField
+}
+
+// optional and repeated fields of types the writer never fills in are only checked to be in place
+func opt(name string) camtField  { return camtField{name: name, typ: "any", max: 1} }
+func many(name string) camtField { return camtField{name: name, typ: "any", max: -1} }
+
+// camtSchema holds the content models of the camt.053.001.02 XSD for the parts of the document written by
+// camtWriter, with every element of their sequences so the order is checked as the schema would.
+var camtSchema = map[string]camtComplexType{
+	"Document":                   {fields: []camtField{{"BkToCstmrStmt", "BankToCustomerStatementV02", 1, 1}}},
+	"BankToCustomerStatementV02": {fields: []camtField{{"GrpHdr", "GroupHeader42", 1, 1}, {"Stmt", "AccountStatement2", 1, -1}}},
+	"GroupHeader42":              {fields: []camtField{{"MsgId", "Max35Text", 1, 1}, {"CreDtTm", "ISODateTime", 1, 1}, opt("MsgRcpt"), opt("MsgPgntn"), opt("AddtlInf")}},
+	"AccountStatement2": {fields: []camtField{
+		{"Id", "Max35Text", 1, 1}, opt("ElctrncSeqNb"), opt("LglSeqNb"), {"CreDtTm", "ISODateTime", 1, 1},
+		{"FrToDt", "DateTimePeriodDetails", 0, 1}, opt("CpyDplctInd"), opt("RptgSrc"), {"Acct", "CashAccount20", 1, 1},
+		opt("RltdAcct"), many("Intrst"), {"Bal", "CashBalance3", 1, -1}, opt("TxsSummry"), {"Ntry", "ReportEntry2", 0, -1},
+		opt("AddtlStmtInf"),
+	}},
+	"DateTimePeriodDetails":         {fields: []camtField{{"FrDtTm", "ISODateTime", 1, 1}, {"ToDtTm", "ISODateTime", 1, 1}}},
+	"CashAccount20":                 {fields: []camtField{{"Id", "AccountIdentification4Choice", 1, 1}, opt("Tp"), {"Ccy", "CurrencyCode", 0, 1}, opt("Nm"), opt("Ownr"), opt("Svcr")}},
+	"CashAccount16":                 {fields: []camtField{{"Id", "AccountIdentification4Choice", 1, 1}, opt("Tp"), {"Ccy", "CurrencyCode", 0, 1}, opt("Nm")}},
+	"AccountIdentification4Choice":  {choice: true, fields: []camtField{{"IBAN", "any", 1, 1}, {"Othr", "GenericAccountIdentification1", 1, 1}}},
+	"GenericAccountIdentification1": {fields: []camtField{{"Id", "Max34Text", 1, 1}, opt("SchmeNm"), opt("Issr")}},
+	"CashBalance3": {fields: []camtField{
+		{"Tp", "BalanceType12", 1, 1}, opt("CdtLine"), {"Amt", "Amount", 1, 1}, {"CdtDbtInd", "CreditDebitCode", 1, 1},
+		{"Dt", "DateAndDateTimeChoice", 1, 1}, many("Avlbty"),
+	}},
+	"BalanceType12":         {fields: []camtField{{"CdOrPrtry", "BalanceType5Choice", 1, 1}, opt("SubTp")}},
+	"BalanceType5Choice":    {choice: true, fields: []camtField{{"Cd", "BalanceType12Code", 1, 1}, {"Prtry", "Max35Text", 1, 1}}},
+	"DateAndDateTimeChoice": {choice: true, fields: []camtField{{"Dt", "ISODate", 1, 1}, {"DtTm", "ISODateTime", 1, 1}}},
+	"ReportEntry2": {fields: []camtField{
+		{"NtryRef", "Max35Text", 0, 1}, {"Amt", "Amount", 1, 1}, {"CdtDbtInd", "CreditDebitCode", 1, 1}, opt("RvslInd"),
+		{"Sts", "EntryStatus2Code", 1, 1}, {"BookgDt", "DateAndDateTimeChoice", 0, 1}, {"ValDt", "DateAndDateTimeChoice", 0, 1},
+		{"AcctSvcrRef", "Max35Text", 0, 1}, many("Avlbty"), {"BkTxCd", "BankTransactionCodeStructure4", 1, 1},
+		opt("ComssnWvrInd"), opt("AddtlInfInd"), opt("AmtDtls"), opt("Chrgs"), opt("TechInptChanl"), opt("Intrst"),
+		{"NtryDtls", "EntryDetails1", 0, -1}, opt("AddtlNtryInf"),
+	}},
+	"BankTransactionCodeStructure4": {fields: []camtField{{"Domn", "BankTransactionCodeStructure5", 0, 1}, opt("Prtry")}},
+	"BankTransactionCodeStructure5": {fields: []camtField{{"Cd", "Max4Text", 1, 1}, {"Fmly", "BankTransactionCodeStructure6", 1, 1}}},
+	"BankTransactionCodeStructure6": {fields: []camtField{{"Cd", "Max4Text", 1, 1}, {"SubFmlyCd", "Max4Text", 1, 1}}},
+	"EntryDetails1":                 {fields: []camtField{opt("Btch"), {"TxDtls", "EntryTransaction2", 0, -1}}},
+	"EntryTransaction2": {fields: []camtField{
+		{"Refs", "TransactionReferences2", 0, 1}, opt("AmtDtls"), many("Avlbty"), opt("BkTxCd"), opt("Chrgs"), opt("Intrst"),
+		{"RltdPties", "TransactionParty2", 0, 1}, opt("RltdAgts"), opt("Purp"), many("RltdRmtInf"),
+		{"RmtInf", "RemittanceInformation5", 0, 1}, opt("RltdDts"), opt("RltdPric"), many("RltdQties"), opt("FinInstrmId"),
+		opt("Tax"), opt("RtrInf"), opt("CorpActn"), opt("SfkpgAcct"), opt("AddtlTxInf"),
+	}},
+	"TransactionReferences2": {fields: []camtField{
+		opt("MsgId"), {"AcctSvcrRef", "Max35Text", 0, 1}, opt("PmtInfId"), opt("InstrId"), opt("EndToEndId"), opt("TxId"),
+		opt("MndtId"), opt("ChqNb"), opt("ClrSysRef"), opt("Prtry"),
+	}},
+	"TransactionParty2": {fields: []camtField{
+		opt("InitgPty"), opt("Dbtr"), {"DbtrAcct", "CashAccount16", 0, 1}, opt("UltmtDbtr"), opt("Cdtr"),
+		{"CdtrAcct", "CashAccount16", 0, 1}, opt("UltmtCdtr"), opt("TradgPty"), many("Prtry"),
+	}},
+	"RemittanceInformation5": {fields: []camtField{{"Ustrd", "Max140Text", 0, -1}, many("Strd")}},
+}
+
+// camtSimpleTypes checks the text of the simple types used by camtWriter
+var camtSimpleTypes = map[string]func(string) bool{
+	"Max4Text":         maxText(4),
+	"Max34Text":        maxText(34),
+	"Max35Text":        maxText(35),
+	"Max140Text":       maxText(140),
+	"ISODate":          func(s string) bool { _, err := time.Parse("2006-01-02", s); return err == nil },
+	"ISODateTime":      func(s string) bool { _, err := time.Parse(time.RFC3339, s); return err == nil },
+	"CurrencyCode":     regexp.MustCompile(`^[A-Z]{3}$`).MatchString,
+	"CreditDebitCode":  func(s string) bool { return s == "CRDT" || s == "DBIT" },
+	"EntryStatus2Code": func(s string) bool { return s == "BOOK" || s == "PDNG" || s == "INFO" },
+	"BalanceType12Code": func(s string) bool {
+		return strings.Contains(" ITBD OPBD CLBD PRCD XPCD CLAV FWAV INFO OPAV ITAV ", " "+s+" ")
+	},
+}
+
+func maxText(n int) func(string) bool {
+	return func(s string) bool { return len([]rune(s)) >= 1 && len([]rune(s)) <= n }
+}
+
+// camtAmountValue matches an ActiveOrHistoricCurrencyAndAmount, at most 18 digits of which 5 are fractional
+var camtAmountValue = regexp.MustCompile(`^[0-9]{1,13}(\.[0-9]{1,5})?$`)
+
+// xmlNode is an element of a parsed document
+type xmlNode struct {
+	name     xml.Name
+	attrs    []xml.Attr
+	text     string
+	children []*xmlNode
+}
+
+// parseXmlTree parses a document into its tree of elements
+func parseXmlTree(t *testing.T, data []byte) *xmlNode {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var stack []*xmlNode
+	var root *xmlNode
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: token.Name, attrs: token.Attr}
+			if len(stack) == 0 {
+				root = node
+			} else {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(token)
+			}
+		}
+	}
+	return root
+}
+
+// validateCamt checks node against the type typ of the schema, returning the problems found
+func validateCamt(node *xmlNode, typ string, path string) []string {
+	if node.name.Space != camt053Namespace {
+		return []string{fmt.Sprintf("%s: namespace %q", path, node.name.Space)}
+	}
+	if typ == "any" {
+		return nil
+	}
+	if typ == "Amount" {
+		if len(node.attrs) != 1 || node.attrs[0].Name.Local != "Ccy" || !camtSimpleTypes["CurrencyCode"](node.attrs[0].Value) {
+			return []string{fmt.Sprintf("%s: Ccy attribute %v", path, node.attrs)}
+		}
+		if !camtAmountValue.MatchString(node.text) {
+			return []string{fmt.Sprintf("%s: amount %q", path, node.text)}
+		}
+		return nil
+	}
+	if check, ok := camtSimpleTypes[typ]; ok {
+		if len(node.children) != 0 || !check(node.text) {
+			return []string{fmt.Sprintf("%s: %q is not a %s", path, node.text, typ)}
+		}
+		return nil
+	}
+	complexType, ok := camtSchema[typ]
+	if !ok {
+		return []string{fmt.Sprintf("%s: unknown type %s", path, typ)}
+	}
+	if strings.TrimSpace(node.text) != "" {
+		return []string{fmt.Sprintf("%s: unexpected text %q", path, node.text)}
+	}
+	var problems []string
+	if complexType.choice {
+		if len(node.children) != 1 {
+			return []string{fmt.Sprintf("%s: choice holds %d elements", path, len(node.children))}
+		}
+		for _, field := range complexType.fields {
+			if field.name == node.children[0].name.Local {
+				return validateCamt(node.children[0], field.typ, path+"/"+field.name)
+			}
+		}
+		return []string{fmt.Sprintf("%s: %s is not a choice", path, node.children[0].name.Local)}
+	}
+	i := 0
+	for _, field := range complexType.fields {
+		count := 0
+		for i < len(node.children) && node.children[i].name.Local == field.name {
+			problems = append(problems, validateCamt(node.children[i], field.typ, path+"/"+field.name)...)
+			count++
+			i++
+		}
+		if count < field.min || (field.max >= 0 && count > field.max) {
+			problems = append(problems, fmt.Sprintf("%s: %d %s elements", path, count, field.name))
+		}
+	}
+	if i < len(node.children) {
+		problems = append(problems, fmt.Sprintf("%s: unexpected %s", path, node.children[i].name.Local))
+	}
+	return problems
+}
+
+func TestCamtReference(t *testing.T) {
+	tests := []struct {
+		id   string
+		want string
+	}{
+		{id: "a", want: "a"},
+		{id: "0f8fad5b-d9cb-469f-a165-70867728950e", want: "0f8fad5bd9cb469fa16570867728950e"},
+		{id: strings.Repeat("x", 40), want: strings.Repeat("x", 35)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			diff := testutil.Diff(camtReference(tt.id), tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestCamtWriter(t *testing.T) {
+	file := accountFile{
+		AccountNumber: 1,
+		Currency:      "EUR",
+		From:          time.Date(2023, time.June, 1, 0, 0, 0, 0, time.UTC),
+		To:            time.Date(2023, time.June, 30, 23, 59, 59, 0, time.UTC),
+		Opening:       10000,
+		Closing:       -1250,
+		GeneratedAt:   time.Date(2023, time.July, 1, 8, 0, 0, 0, time.UTC),
+	}
+	createdAt := time.Date(2023, time.June, 15, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		golden       string
+		transactions []model.Transaction
+	}{
+		{
+			name:   "entries",
+			golden: "camt053.xml",
+			transactions: []model.Transaction{
+				{TransactionId: "0f8fad5b-d9cb-469f-a165-70867728950e", Type: "credit", Amount: 5000, Currency: "EUR", CreatedAt: createdAt, TransferTo: 2, Comment: "invoice <42> & co"},
+				{TransactionId: "b", Type: "debit", Amount: 16250, Currency: "EUR", CreatedAt: createdAt.Add(time.Hour), TransferTo: 3, Comment: strings.Repeat("x", 150)},
+				{TransactionId: "c", Type: "debit", Amount: 1, Currency: "EUR", CreatedAt: createdAt.Add(2 * time.Hour)},
+			},
+		},
+		{
+			name:   "no entries",
+			golden: "camt053-empty.xml",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := writeAccountFile(t, func(w *bufio.Writer) accountFileWriter { return camtWriter{w: w} }, file, tt.transactions)
+			golden := filepath.Join("testdata", tt.golden)
+			if *updateGolden {
+				err := os.WriteFile(golden, data, 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			diff := testutil.Diff(string(data), string(want))
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+			root := parseXmlTree(t, data)
+			if root.name.Local != "Document" {
+				t.Fatalf("Want: %v, Got: %v", "Document", root.name.Local)
+			}
+			problems := validateCamt(root, "Document", "Document")
+			if len(problems) != 0 {
+				t.Errorf("Want: %v, Got: %v", "a document of the camt.053 shape", problems)
+			}
+		})
+	}
+}
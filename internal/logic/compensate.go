@@ -0,0 +1,250 @@
+package logic
+
+import (
+	"errors"
+	"github.com/PereRohit/util/log"
+	respModel "github.com/PereRohit/util/model"
+	"github.com/google/uuid"
+	"github.com/vatsal278/TransactionManagementService/internal/codes"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"github.com/vatsal278/TransactionManagementService/internal/repo/datasource"
+	"net/http"
+	"time"
+)
+
+var errRecipientBalance = errors.New("recipient balance too low to give back the transfer")
+
+// checkRecipientBalance makes sure the recipient of a transfer still holds what a compensation takes back from the
+// credit leg, so a sender never overdraws the account of someone else
+func checkRecipientBalance(ds datasource.DataSourceI, leg model.Transaction, give model.Money) error {
+	balances, err := ds.GetBalances(leg.AccountNumber, leg.UserId, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, balance := range balances {
+		if balance.Currency == leg.Currency && balance.Balance >= give {
+			return nil
+		}
+	}
+	return errRecipientBalance
+}
+
+// oppositeType returns the transaction type undoing the given one
+func oppositeType(transactionType string) string {
+	if transactionType == "debit" {
+		return "credit"
+	}
+	return "debit"
+}
+
+// Reverse gives back whatever is left of a transaction after earlier refunds and marks it reversed
+func (l transactionManagementServiceLogic) Reverse(id string, userId string, reversal model.Reversal) *respModel.Response {
+	return l.compensate(id, userId, nil, reversal.Reason)
+}
+
+// Refund gives back part of a transaction. A transaction may be refunded several times until its amount is
+// exhausted, and is marked reversed by the refund exhausting it.
+func (l transactionManagementServiceLogic) Refund(id string, userId string, refund model.Refund) *respModel.Response {
+	return l.compensate(id, userId, &refund.Amount, refund.Reason)
+}
+
+// compensationAmount returns how much of a leg of the transaction is given back when give is given back of the
+// transaction itself. The other leg of a cross-currency transfer is given back in its own currency at the rate the
+// transfer was made at, and exactly what is left of it once the transaction is exhausted so no rounding remains.
+func compensationAmount(transaction model.Transaction, leg model.Transaction, give model.Money, exhausted bool) (model.Money, error) {
+	switch {
+	case leg.TransactionId == transaction.TransactionId || leg.Currency == transaction.Currency:
+		return give, nil
+	case exhausted:
+		return leg.Amount - leg.RefundedAmount, nil
+	default:
+		return give.Convert(transaction.FxRate, leg.Currency)
+	}
+}
+
+// compensate records approved transactions of the opposite type giving back the amount of a transaction, or what
+// is left of it when amount is nil. Each compensating transaction references the one it compensates, and a transfer
+// is compensated by a transfer in the opposite direction. The account updates are written to the outbox like those
+// of an approval, and the original legs track how much was given back so a transaction is never refunded twice.
+// Giving back a transfer is refused when it would take the balance of the recipient below zero.
+func (l transactionManagementServiceLogic) compensate(id string, userId string, amount *model.Money, reason string) *respModel.Response {
+	transaction, errResp := l.getUserTransaction(id, userId)
+	if errResp != nil {
+		return errResp
+	}
+	// Transactions seen through a transfer to the user, and the credit legs of transfers, are compensated by the sender
+	if transaction.UserId != userId || (transaction.TransferId != "" && transaction.Type == "credit") {
+		return &respModel.Response{
+			Status:  http.StatusForbidden,
+			Message: codes.GetErr(codes.ErrUnauthorized),
+			Data:    nil,
+		}
+	}
+	if transaction.Status == model.StatusReversed {
+		return &respModel.Response{
+			Status:  http.StatusConflict,
+			Message: codes.GetErr(codes.ErrAlreadyReversed),
+			Data:    nil,
+		}
+	}
+	if transaction.Status != model.StatusApproved || transaction.ReferenceId != "" {
+		return &respModel.Response{
+			Status:  http.StatusConflict,
+			Message: codes.GetErr(codes.ErrNotCompensable),
+			Data:    nil,
+		}
+	}
+	remaining := transaction.Amount - transaction.RefundedAmount
+	give := remaining
+	if amount != nil {
+		give = *amount
+	}
+	if !give.FitsCurrency(transaction.Currency) {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrCurrencyPrecision),
+			Data:    nil,
+		}
+	}
+	if give > remaining {
+		return &respModel.Response{
+			Status:  http.StatusUnprocessableEntity,
+			Message: codes.GetErr(codes.ErrRefundExceeds),
+			Data:    nil,
+		}
+	}
+	// The transaction is reversed once nothing is left of it
+	exhausted := give == remaining
+
+	legs := []model.Transaction{*transaction}
+	if transaction.TransferId != "" {
+		var err error
+		legs, _, err = l.DsSvc.Get(model.Query{Where: model.Eq("transfer_id", transaction.TransferId), SkipCount: true})
+		if err != nil {
+			log.Error(err)
+			return &respModel.Response{
+				Status:  http.StatusInternalServerError,
+				Message: codes.GetErr(codes.ErrNewTransaction),
+				Data:    nil,
+			}
+		}
+	}
+	var transferId string
+	if transaction.TransferId != "" {
+		transferId = uuid.NewString()
+	}
+	compensations := make([]model.Transaction, 0, len(legs))
+	for _, leg := range legs {
+		legGive, err := compensationAmount(*transaction, leg, give, exhausted)
+		if err != nil || legGive <= 0 {
+			return &respModel.Response{
+				Status:  http.StatusBadRequest,
+				Message: codes.GetErr(codes.ErrInvalidAmount),
+				Data:    nil,
+			}
+		}
+		compensations = append(compensations, model.Transaction{
+			UserId:        leg.UserId,
+			AccountNumber: leg.AccountNumber,
+			TransactionId: uuid.NewString(),
+			Amount:        legGive,
+			TransferTo:    leg.TransferTo,
+			Status:        model.StatusApproved,
+			Type:          oppositeType(leg.Type),
+			Comment:       reason,
+			TransferId:    transferId,
+			ReferenceId:   leg.TransactionId,
+			Currency:      leg.Currency,
+		})
+	}
+	// A cross-currency transfer is given back at the rate it was made at, each compensating leg recording the other
+	if len(compensations) == 2 && compensations[0].Currency != compensations[1].Currency {
+		for i := range compensations {
+			other := compensations[1-i]
+			compensations[i].FxRate = transaction.FxRate
+			compensations[i].CounterAmount, compensations[i].CounterCurrency = other.Amount, other.Currency
+		}
+	}
+	if transferId != "" {
+		err := checkBalanced(compensations)
+		if err != nil {
+			log.Error(err)
+			return &respModel.Response{
+				Status:  http.StatusInternalServerError,
+				Message: codes.GetErr(codes.ErrUnbalancedTransfer),
+				Data:    nil,
+			}
+		}
+	}
+
+	err := l.DsSvc.Transaction(func(ds datasource.DataSourceI) error {
+		for i, leg := range legs {
+			if leg.TransferId != "" && leg.Type == "credit" {
+				err := checkRecipientBalance(ds, leg, compensations[i].Amount)
+				if err != nil {
+					return err
+				}
+			}
+			set := map[string]interface{}{"refunded_amount": leg.RefundedAmount + compensations[i].Amount}
+			if exhausted {
+				set["status"] = model.StatusReversed
+			}
+			// Only apply to legs still as they were read, so concurrent refunds cannot give back more than the amount
+			n, err := ds.Update(model.And(model.Eq("transaction_id", leg.TransactionId), model.Eq("status", model.StatusApproved), model.Eq("refunded_amount", leg.RefundedAmount)), set)
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				return errStatusChanged
+			}
+			if exhausted {
+				err = recordStatusChange(ds, model.StatusChange{TransactionId: leg.TransactionId, FromStatus: model.StatusApproved, ToStatus: model.StatusReversed, ChangedBy: userId, Reason: reason}, leg)
+				if err != nil {
+					return err
+				}
+			}
+			compensation := compensations[i]
+			err = ds.Insert(compensation)
+			if err != nil {
+				return err
+			}
+			err = recordStatusChange(ds, model.StatusChange{TransactionId: compensation.TransactionId, ToStatus: compensation.Status, ChangedBy: userId, Reason: reason}, compensation)
+			if err != nil {
+				return err
+			}
+			err = insertAccountUpdate(ds, compensation, compensation.Type)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errStatusChanged) {
+		return &respModel.Response{
+			Status:  http.StatusConflict,
+			Message: codes.GetErr(codes.ErrStatusChanged),
+			Data:    nil,
+		}
+	}
+	if errors.Is(err, errRecipientBalance) {
+		return &respModel.Response{
+			Status:  http.StatusUnprocessableEntity,
+			Message: codes.GetErr(codes.ErrRecipientBalance),
+			Data:    nil,
+		}
+	}
+	if err != nil {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrNewTransaction),
+			Data:    nil,
+		}
+	}
+	l.invalidateCache(legs)
+	return &respModel.Response{
+		Status:  http.StatusCreated,
+		Message: "SUCCESS",
+		Data:    compensations,
+	}
+}
@@ -0,0 +1,458 @@
+package logic
+
+import (
+	"encoding/json"
+	"errors"
+	respModel "github.com/PereRohit/util/model"
+	"github.com/PereRohit/util/testutil"
+	"github.com/golang/mock/gomock"
+	"github.com/vatsal278/TransactionManagementService/internal/codes"
+	"github.com/vatsal278/TransactionManagementService/internal/config"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"github.com/vatsal278/TransactionManagementService/internal/repo/datasource"
+	"github.com/vatsal278/TransactionManagementService/pkg/mock"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestTransactionManagementServiceLogic_Compensate(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	// inTransaction makes the mock run the function given to Transaction against itself
+	inTransaction := func(mockDs *mock.MockDataSourceI) {
+		mockDs.EXPECT().Transaction(gomock.Any()).Times(1).DoAndReturn(func(fn func(datasource.DataSourceI) error) error {
+			return fn(mockDs)
+		})
+	}
+	byId := model.Query{Where: model.Eq("transaction_id", "abc")}
+	casFilter := func(id string, refunded model.Money) model.Filter {
+		return model.And(model.Eq("transaction_id", id), model.Eq("status", model.StatusApproved), model.Eq("refunded_amount", refunded))
+	}
+	original := model.Transaction{TransactionId: "abc", UserId: "123", AccountNumber: 1, Amount: 10, RefundedAmount: 4, Status: model.StatusApproved, Currency: "USD", Type: "debit"}
+	debitLeg := model.Transaction{TransactionId: "abc", UserId: "123", AccountNumber: 1, TransferTo: 2, Amount: 10, Status: model.StatusApproved, Currency: "USD", Type: "debit", TransferId: "t1"}
+	creditLeg := model.Transaction{TransactionId: "def", UserId: "456", AccountNumber: 2, TransferTo: 1, Amount: 10, Status: model.StatusApproved, Currency: "USD", Type: "credit", TransferId: "t1"}
+	fxDebitLeg := model.Transaction{TransactionId: "abc", UserId: "123", AccountNumber: 1, TransferTo: 2, Amount: 1000, Status: model.StatusApproved, Currency: "USD", Type: "debit", TransferId: "t1", FxRate: 92000000, CounterAmount: 920, CounterCurrency: "EUR"}
+	fxCreditLeg := model.Transaction{TransactionId: "def", UserId: "456", AccountNumber: 2, TransferTo: 1, Amount: 920, Status: model.StatusApproved, Currency: "EUR", Type: "credit", TransferId: "t1", FxRate: 92000000, CounterAmount: 1000, CounterCurrency: "USD"}
+	amount := func(a model.Money) *model.Money { return &a }
+	tests := []struct {
+		name   string
+		userId string
+		amount *model.Money
+		setup  func() datasource.DataSourceI
+		want   func(*respModel.Response)
+	}{
+		{
+			name:   "Success::reverse what is left",
+			userId: "123",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().Get(byId).Times(1).Return([]model.Transaction{original}, 1, nil)
+				inTransaction(mockDs)
+				mockDs.EXPECT().Update(casFilter("abc", 4), map[string]interface{}{"refunded_amount": model.Money(10), "status": model.StatusReversed}).Times(1).Return(int64(1), nil)
+				mockDs.EXPECT().InsertStatusHistory(model.StatusChange{TransactionId: "abc", FromStatus: model.StatusApproved, ToStatus: model.StatusReversed, ChangedBy: "123", Reason: "mistake"}).Times(1).Return(nil)
+				mockDs.EXPECT().InsertWebhookDeliveries(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				var compensationId string
+				mockDs.EXPECT().Insert(gomock.Any()).Times(1).DoAndReturn(func(tr model.Transaction) error {
+					compensationId = tr.TransactionId
+					tr.TransactionId = ""
+					diff := testutil.Diff(tr, model.Transaction{UserId: "123", AccountNumber: 1, Amount: 6, Status: model.StatusApproved, Type: "credit", Comment: "mistake", ReferenceId: "abc", Currency: "USD"})
+					if diff != "" {
+						t.Error(testutil.Callers(), diff)
+					}
+					return nil
+				})
+				mockDs.EXPECT().InsertStatusHistory(gomock.Any()).Times(1).Return(nil)
+				mockDs.EXPECT().InsertWebhookDeliveries(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				mockDs.EXPECT().InsertOutbox(gomock.Any()).Times(1).DoAndReturn(func(message model.OutboxMessage) error {
+					payload, _ := json.Marshal(model.UpdateTransaction{AccountNumber: 1, Amount: 6, TransactionType: "credit", Currency: "USD"})
+					diff := testutil.Diff(message, model.OutboxMessage{TransactionId: compensationId, EventType: model.EventAccountUpdate, Payload: string(payload)})
+					if diff != "" {
+						t.Error(testutil.Callers(), diff)
+					}
+					return nil
+				})
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				if resp.Status != http.StatusCreated {
+					t.Errorf("Want: %v, Got: %v", http.StatusCreated, resp.Status)
+				}
+				compensations, ok := resp.Data.([]model.Transaction)
+				if !ok || len(compensations) != 1 || compensations[0].ReferenceId != "abc" {
+					t.Errorf("Want: %v, Got: %v", "one compensation of abc", resp.Data)
+				}
+			},
+		},
+		{
+			name:   "Success::partial refund keeps the transaction approved",
+			userId: "123",
+			amount: amount(3),
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().Get(byId).Times(1).Return([]model.Transaction{original}, 1, nil)
+				inTransaction(mockDs)
+				mockDs.EXPECT().Update(casFilter("abc", 4), map[string]interface{}{"refunded_amount": model.Money(7)}).Times(1).Return(int64(1), nil)
+				mockDs.EXPECT().Insert(gomock.Any()).Times(1).Return(nil)
+				mockDs.EXPECT().InsertStatusHistory(gomock.Any()).Times(1).Return(nil)
+				mockDs.EXPECT().InsertWebhookDeliveries(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				mockDs.EXPECT().InsertOutbox(gomock.Any()).Times(1).Return(nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				if resp.Status != http.StatusCreated {
+					t.Errorf("Want: %v, Got: %v", http.StatusCreated, resp.Status)
+				}
+			},
+		},
+		{
+			name:   "Success::transfer compensated by a transfer back",
+			userId: "123",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().Get(byId).Times(1).Return([]model.Transaction{debitLeg}, 1, nil)
+				mockDs.EXPECT().Get(model.Query{Where: model.Eq("transfer_id", "t1"), SkipCount: true}).Times(1).Return([]model.Transaction{debitLeg, creditLeg}, 0, nil)
+				inTransaction(mockDs)
+				mockDs.EXPECT().GetBalances(2, "456", gomock.Any()).Times(1).Return([]model.Balance{{Currency: "USD", Balance: 10}}, nil)
+				mockDs.EXPECT().Update(casFilter("abc", 0), map[string]interface{}{"refunded_amount": model.Money(10), "status": model.StatusReversed}).Times(1).Return(int64(1), nil)
+				mockDs.EXPECT().Update(casFilter("def", 0), map[string]interface{}{"refunded_amount": model.Money(10), "status": model.StatusReversed}).Times(1).Return(int64(1), nil)
+				mockDs.EXPECT().InsertStatusHistory(gomock.Any()).Times(4).Return(nil)
+				mockDs.EXPECT().InsertWebhookDeliveries(gomock.Any(), gomock.Any()).Times(4).Return(nil)
+				var legs []model.Transaction
+				mockDs.EXPECT().Insert(gomock.Any()).Times(2).DoAndReturn(func(tr model.Transaction) error {
+					legs = append(legs, tr)
+					return nil
+				})
+				mockDs.EXPECT().InsertOutbox(gomock.Any()).Times(2).Return(nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				if resp.Status != http.StatusCreated {
+					t.Errorf("Want: %v, Got: %v", http.StatusCreated, resp.Status)
+				}
+				legs, _ := resp.Data.([]model.Transaction)
+				if len(legs) != 2 || legs[0].TransferId == "" || legs[0].TransferId == "t1" {
+					t.Errorf("Want: %v, Got: %v", "a new transfer", resp.Data)
+					return
+				}
+				for i := range legs {
+					legs[i].TransactionId = ""
+					legs[i].TransferId = ""
+				}
+				diff := testutil.Diff(legs, []model.Transaction{
+					{UserId: "123", AccountNumber: 1, Amount: 10, TransferTo: 2, Status: model.StatusApproved, Type: "credit", Comment: "mistake", ReferenceId: "abc", Currency: "USD"},
+					{UserId: "456", AccountNumber: 2, Amount: 10, TransferTo: 1, Status: model.StatusApproved, Type: "debit", Comment: "mistake", ReferenceId: "def", Currency: "USD"},
+				})
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+			},
+		},
+		{
+			name:   "Success::cross-currency transfer refunded at the rate it was made at",
+			userId: "123",
+			amount: amount(333),
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().Get(byId).Times(1).Return([]model.Transaction{fxDebitLeg}, 1, nil)
+				mockDs.EXPECT().Get(model.Query{Where: model.Eq("transfer_id", "t1"), SkipCount: true}).Times(1).Return([]model.Transaction{fxDebitLeg, fxCreditLeg}, 0, nil)
+				inTransaction(mockDs)
+				mockDs.EXPECT().GetBalances(2, "456", gomock.Any()).Times(1).Return([]model.Balance{{Currency: "EUR", Balance: 306}, {Currency: "USD", Balance: 5000}}, nil)
+				mockDs.EXPECT().Update(casFilter("abc", 0), map[string]interface{}{"refunded_amount": model.Money(333)}).Times(1).Return(int64(1), nil)
+				// 3.33 USD at 0.92 is 3.0636 EUR, rounded to the cent
+				mockDs.EXPECT().Update(casFilter("def", 0), map[string]interface{}{"refunded_amount": model.Money(306)}).Times(1).Return(int64(1), nil)
+				mockDs.EXPECT().InsertStatusHistory(gomock.Any()).Times(2).Return(nil)
+				mockDs.EXPECT().InsertWebhookDeliveries(gomock.Any(), gomock.Any()).Times(2).Return(nil)
+				mockDs.EXPECT().Insert(gomock.Any()).Times(2).Return(nil)
+				mockDs.EXPECT().InsertOutbox(gomock.Any()).Times(2).Return(nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				if resp.Status != http.StatusCreated {
+					t.Errorf("Want: %v, Got: %v", http.StatusCreated, resp.Status)
+				}
+				legs, _ := resp.Data.([]model.Transaction)
+				if len(legs) != 2 {
+					t.Errorf("Want: %v, Got: %v", "two compensating legs", resp.Data)
+					return
+				}
+				for i := range legs {
+					legs[i].TransactionId = ""
+					legs[i].TransferId = ""
+				}
+				diff := testutil.Diff(legs, []model.Transaction{
+					{UserId: "123", AccountNumber: 1, Amount: 333, TransferTo: 2, Status: model.StatusApproved, Type: "credit", Comment: "mistake", ReferenceId: "abc", Currency: "USD", FxRate: 92000000, CounterAmount: 306, CounterCurrency: "EUR"},
+					{UserId: "456", AccountNumber: 2, Amount: 306, TransferTo: 1, Status: model.StatusApproved, Type: "debit", Comment: "mistake", ReferenceId: "def", Currency: "EUR", FxRate: 92000000, CounterAmount: 333, CounterCurrency: "USD"},
+				})
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+			},
+		},
+		{
+			name:   "Success::cross-currency transfer reversal gives back exactly what is left",
+			userId: "123",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				debit, credit := fxDebitLeg, fxCreditLeg
+				debit.RefundedAmount, credit.RefundedAmount = 333, 306
+				mockDs.EXPECT().Get(byId).Times(1).Return([]model.Transaction{debit}, 1, nil)
+				mockDs.EXPECT().Get(model.Query{Where: model.Eq("transfer_id", "t1"), SkipCount: true}).Times(1).Return([]model.Transaction{debit, credit}, 0, nil)
+				inTransaction(mockDs)
+				mockDs.EXPECT().GetBalances(2, "456", gomock.Any()).Times(1).Return([]model.Balance{{Currency: "EUR", Balance: 614}}, nil)
+				mockDs.EXPECT().Update(casFilter("abc", 333), map[string]interface{}{"refunded_amount": model.Money(1000), "status": model.StatusReversed}).Times(1).Return(int64(1), nil)
+				mockDs.EXPECT().Update(casFilter("def", 306), map[string]interface{}{"refunded_amount": model.Money(920), "status": model.StatusReversed}).Times(1).Return(int64(1), nil)
+				mockDs.EXPECT().InsertStatusHistory(gomock.Any()).Times(4).Return(nil)
+				mockDs.EXPECT().InsertWebhookDeliveries(gomock.Any(), gomock.Any()).Times(4).Return(nil)
+				mockDs.EXPECT().Insert(gomock.Any()).Times(2).Return(nil)
+				mockDs.EXPECT().InsertOutbox(gomock.Any()).Times(2).Return(nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				if resp.Status != http.StatusCreated {
+					t.Errorf("Want: %v, Got: %v", http.StatusCreated, resp.Status)
+				}
+			},
+		},
+		{
+			name:   "Failure::transfer reversal overdraws the recipient",
+			userId: "123",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().Get(byId).Times(1).Return([]model.Transaction{debitLeg}, 1, nil)
+				mockDs.EXPECT().Get(model.Query{Where: model.Eq("transfer_id", "t1"), SkipCount: true}).Times(1).Return([]model.Transaction{debitLeg, creditLeg}, 0, nil)
+				inTransaction(mockDs)
+				mockDs.EXPECT().Update(casFilter("abc", 0), gomock.Any()).Times(1).Return(int64(1), nil)
+				mockDs.EXPECT().InsertStatusHistory(gomock.Any()).Times(2).Return(nil)
+				mockDs.EXPECT().InsertWebhookDeliveries(gomock.Any(), gomock.Any()).Times(2).Return(nil)
+				mockDs.EXPECT().Insert(gomock.Any()).Times(1).Return(nil)
+				mockDs.EXPECT().InsertOutbox(gomock.Any()).Times(1).Return(nil)
+				// The recipient spent part of the transfer, and only the EUR balance would cover it
+				mockDs.EXPECT().GetBalances(2, "456", gomock.Any()).Times(1).Return([]model.Balance{{Currency: "EUR", Balance: 5000}, {Currency: "USD", Balance: 9}}, nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusUnprocessableEntity,
+					Message: codes.GetErr(codes.ErrRecipientBalance),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+		{
+			name:   "Failure::recipient balance err",
+			userId: "123",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().Get(byId).Times(1).Return([]model.Transaction{debitLeg}, 1, nil)
+				mockDs.EXPECT().Get(model.Query{Where: model.Eq("transfer_id", "t1"), SkipCount: true}).Times(1).Return([]model.Transaction{debitLeg, creditLeg}, 0, nil)
+				inTransaction(mockDs)
+				mockDs.EXPECT().Update(casFilter("abc", 0), gomock.Any()).Times(1).Return(int64(1), nil)
+				mockDs.EXPECT().InsertStatusHistory(gomock.Any()).Times(2).Return(nil)
+				mockDs.EXPECT().InsertWebhookDeliveries(gomock.Any(), gomock.Any()).Times(2).Return(nil)
+				mockDs.EXPECT().Insert(gomock.Any()).Times(1).Return(nil)
+				mockDs.EXPECT().InsertOutbox(gomock.Any()).Times(1).Return(nil)
+				mockDs.EXPECT().GetBalances(2, "456", gomock.Any()).Times(1).Return(nil, errors.New("error"))
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				if resp.Status != http.StatusInternalServerError {
+					t.Errorf("Want: %v, Got: %v", http.StatusInternalServerError, resp.Status)
+				}
+			},
+		},
+		{
+			name:   "Failure::refund finer than the currency allows",
+			userId: "123",
+			amount: amount(50),
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				yen := original
+				yen.Currency, yen.Amount, yen.RefundedAmount = "JPY", 1000*model.MajorUnit, 0
+				mockDs.EXPECT().Get(byId).Times(1).Return([]model.Transaction{yen}, 1, nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: codes.GetErr(codes.ErrCurrencyPrecision),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+		{
+			name:   "Failure::already reversed",
+			userId: "123",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				reversed := original
+				reversed.Status = model.StatusReversed
+				mockDs.EXPECT().Get(byId).Times(1).Return([]model.Transaction{reversed}, 1, nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusConflict,
+					Message: codes.GetErr(codes.ErrAlreadyReversed),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+		{
+			name:   "Failure::pending transaction",
+			userId: "123",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				pending := original
+				pending.Status = model.StatusPending
+				mockDs.EXPECT().Get(byId).Times(1).Return([]model.Transaction{pending}, 1, nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusConflict,
+					Message: codes.GetErr(codes.ErrNotCompensable),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+		{
+			name:   "Failure::refund of a refund",
+			userId: "123",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				refund := original
+				refund.ReferenceId = "xyz"
+				mockDs.EXPECT().Get(byId).Times(1).Return([]model.Transaction{refund}, 1, nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				if resp.Status != http.StatusConflict {
+					t.Errorf("Want: %v, Got: %v", http.StatusConflict, resp.Status)
+				}
+			},
+		},
+		{
+			name:   "Failure::refund exceeds what is left",
+			userId: "123",
+			amount: amount(7),
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().Get(byId).Times(1).Return([]model.Transaction{original}, 1, nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusUnprocessableEntity,
+					Message: codes.GetErr(codes.ErrRefundExceeds),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+		{
+			name:   "Failure::foreign transaction",
+			userId: "123",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				foreign := original
+				foreign.UserId = "999"
+				mockDs.EXPECT().Get(byId).Times(1).Return([]model.Transaction{foreign}, 1, nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				if resp.Status != http.StatusNotFound {
+					t.Errorf("Want: %v, Got: %v", http.StatusNotFound, resp.Status)
+				}
+			},
+		},
+		{
+			name:   "Failure::credit leg of a transfer",
+			userId: "456",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				credit := creditLeg
+				credit.TransactionId = "abc"
+				mockDs.EXPECT().Get(byId).Times(1).Return([]model.Transaction{credit}, 1, nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				if resp.Status != http.StatusForbidden {
+					t.Errorf("Want: %v, Got: %v", http.StatusForbidden, resp.Status)
+				}
+			},
+		},
+		{
+			name:   "Failure::refunded concurrently",
+			userId: "123",
+			amount: amount(3),
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().Get(byId).Times(1).Return([]model.Transaction{original}, 1, nil)
+				inTransaction(mockDs)
+				mockDs.EXPECT().Update(gomock.Any(), gomock.Any()).Times(1).Return(int64(0), nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusConflict,
+					Message: codes.GetErr(codes.ErrStatusChanged),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+		{
+			name:   "Failure::insert err",
+			userId: "123",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().Get(byId).Times(1).Return([]model.Transaction{original}, 1, nil)
+				inTransaction(mockDs)
+				mockDs.EXPECT().Update(gomock.Any(), gomock.Any()).Times(1).Return(int64(1), nil)
+				mockDs.EXPECT().InsertStatusHistory(gomock.Any()).Times(1).Return(nil)
+				mockDs.EXPECT().InsertWebhookDeliveries(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				mockDs.EXPECT().Insert(gomock.Any()).Times(1).Return(errors.New("error"))
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusInternalServerError,
+					Message: codes.GetErr(codes.ErrNewTransaction),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := NewTransactionManagementServiceLogic(tt.setup(), config.ExternalSvc{})
+			var got *respModel.Response
+			if tt.amount == nil {
+				got = rec.Reverse("abc", tt.userId, model.Reversal{Reason: "mistake"})
+			} else {
+				got = rec.Refund("abc", tt.userId, model.Refund{Amount: *tt.amount, Reason: "mistake"})
+			}
+			tt.want(got)
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package logic
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSearchYears bounds the search for the next time matching a cron expression, so expressions that never match,
+// such as the 31st of February, do not loop forever
+const cronSearchYears = 5
+
+// cronExpr is a parsed five field cron expression: minute, hour, day of month, month and day of week, in UTC.
+// Each field is a bitset of the values it matches.
+type cronExpr struct {
+	minute, hour, dom, month, dow uint64
+	// anyDay is set when the day of month or the day of week is unrestricted, both must then match a day.
+	// Otherwise a day matching either of them matches, as in cron.
+	anyDay bool
+}
+
+// parseCron parses a cron expression made of five space separated fields. A field is a comma separated list of
+// values, ranges (1-5) or *, each optionally followed by a step (*/15). Sunday is 0 or 7 in the day of week.
+func parseCron(expr string) (cronExpr, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return cronExpr{}, fmt.Errorf("cron expression %q must have 5 fields", expr)
+	}
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	var sets [5]uint64
+	for i, field := range fields {
+		set, err := parseCronField(field, bounds[i][0], bounds[i][1])
+		if err != nil {
+			return cronExpr{}, fmt.Errorf("cron expression %q: %v", expr, err)
+		}
+		sets[i] = set
+	}
+	// Sunday may be written as 7
+	if sets[4]&(1<<7) != 0 {
+		sets[4] = sets[4]&^(1<<7) | 1
+	}
+	return cronExpr{
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    sets[4],
+		anyDay: strings.HasPrefix(fields[2], "*") || strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+// parseCronField returns the bitset of the values between min and max matched by a field
+func parseCronField(field string, min int, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rng = part[:i]
+		}
+		lo, hi := min, max
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			bounds := strings.SplitN(rng, "-", 2)
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(bounds[0])
+			hi, err2 = strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("invalid range %q", rng)
+			}
+		default:
+			var err error
+			lo, err = strconv.Atoi(rng)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", rng)
+			}
+			// A single value with a step runs from the value to the end of the field
+			hi = lo
+			if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q is outside %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+// matchesDay reports whether the day of t matches the day of month and day of week fields
+func (c cronExpr) matchesDay(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.anyDay {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// next returns the first minute strictly after the given time matching the expression, or false when none does
+// within cronSearchYears
+func (c cronExpr) next(after time.Time) (time.Time, bool) {
+	t := after.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(cronSearchYears, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !c.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, time.UTC)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,72 @@
+package logic
+
+import (
+	"github.com/PereRohit/util/testutil"
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		wantErr bool
+	}{
+		{name: "every minute", expr: "* * * * *"},
+		{name: "lists ranges and steps", expr: "0,30 9-17/2 1-7 */3 1-5"},
+		{name: "sunday as 7", expr: "0 0 * * 7"},
+		{name: "value with a step", expr: "5/15 * * * *"},
+		{name: "missing field", expr: "* * * *", wantErr: true},
+		{name: "out of range", expr: "60 * * * *", wantErr: true},
+		{name: "inverted range", expr: "* 5-1 * * *", wantErr: true},
+		{name: "zero step", expr: "*/0 * * * *", wantErr: true},
+		{name: "not a number", expr: "a * * * *", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseCron(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCronExpr_Next(t *testing.T) {
+	// 2023-03-15 is a Wednesday
+	after := time.Date(2023, time.March, 15, 10, 17, 30, 0, time.UTC)
+	tests := []struct {
+		name   string
+		expr   string
+		after  time.Time
+		want   time.Time
+		wantOk bool
+	}{
+		{name: "next minute", expr: "* * * * *", after: after, want: time.Date(2023, time.March, 15, 10, 18, 0, 0, time.UTC), wantOk: true},
+		{name: "strictly after", expr: "18 10 * * *", after: time.Date(2023, time.March, 15, 10, 18, 0, 0, time.UTC), want: time.Date(2023, time.March, 16, 10, 18, 0, 0, time.UTC), wantOk: true},
+		{name: "step", expr: "*/15 * * * *", after: after, want: time.Date(2023, time.March, 15, 10, 30, 0, 0, time.UTC), wantOk: true},
+		{name: "next weekday", expr: "0 9 * * 1-5", after: time.Date(2023, time.March, 17, 9, 0, 0, 0, time.UTC), want: time.Date(2023, time.March, 20, 9, 0, 0, 0, time.UTC), wantOk: true},
+		{name: "sunday as 7", expr: "0 0 * * 7", after: after, want: time.Date(2023, time.March, 19, 0, 0, 0, 0, time.UTC), wantOk: true},
+		{name: "next month", expr: "0 0 1 * *", after: after, want: time.Date(2023, time.April, 1, 0, 0, 0, 0, time.UTC), wantOk: true},
+		{name: "next year", expr: "0 12 1 1 *", after: after, want: time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC), wantOk: true},
+		{name: "day of month or day of week", expr: "0 0 20 * 5", after: after, want: time.Date(2023, time.March, 17, 0, 0, 0, 0, time.UTC), wantOk: true},
+		{name: "leap day", expr: "0 0 29 2 *", after: after, want: time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC), wantOk: true},
+		{name: "never", expr: "0 0 31 2 *", after: after, wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := parseCron(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, ok := c.next(tt.after)
+			if ok != tt.wantOk {
+				t.Errorf("Want: %v, Got: %v", tt.wantOk, ok)
+			}
+			diff := testutil.Diff(got, tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
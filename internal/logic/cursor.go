@@ -0,0 +1,62 @@
+package logic
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// cursor marks a position in a keyset paginated listing.
+// It holds the sort values of the row the page starts after and the direction the page is read in.
+type cursor struct {
+	CreatedAt     time.Time `json:"c"`
+	TransactionId string    `json:"t"`
+	Desc          bool      `json:"d,omitempty"` // Listing is sorted newest first
+	Prev          bool      `json:"p,omitempty"` // Page lies before the position rather than after it
+}
+
+// errInvalidCursor is returned for cursors that are malformed or were not signed by this service
+var errInvalidCursor = errors.New("invalid cursor")
+
+// signCursor returns the HMAC-SHA256 of the encoded cursor payload
+func signCursor(secret []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// encodeCursor serializes the cursor into an opaque URL safe token signed with secret
+func encodeCursor(secret []byte, c cursor) (string, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(signCursor(secret, payload)), nil
+}
+
+// decodeCursor verifies the signature of a token produced by encodeCursor and returns the cursor it holds
+func decodeCursor(secret []byte, token string) (cursor, error) {
+	var c cursor
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return c, errInvalidCursor
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, signCursor(secret, payload)) {
+		return c, errInvalidCursor
+	}
+	b, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return c, errInvalidCursor
+	}
+	err = json.Unmarshal(b, &c)
+	if err != nil || c.TransactionId == "" {
+		return c, errInvalidCursor
+	}
+	return c, nil
+}
@@ -0,0 +1,78 @@
+package logic
+
+import (
+	"github.com/PereRohit/util/testutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCursor(t *testing.T) {
+	secret := []byte("secret")
+	c := cursor{CreatedAt: time.Date(2023, time.January, 1, 10, 0, 0, 0, time.UTC), TransactionId: "abc", Desc: true, Prev: true}
+	token, err := encodeCursor(secret, c)
+	if err != nil {
+		t.Fatalf("Want: %v, Got: %v", nil, err)
+	}
+	payload, _, _ := strings.Cut(token, ".")
+	other, err := encodeCursor(secret, cursor{CreatedAt: c.CreatedAt, TransactionId: "xyz"})
+	if err != nil {
+		t.Fatalf("Want: %v, Got: %v", nil, err)
+	}
+	_, otherSignature, _ := strings.Cut(other, ".")
+
+	tests := []struct {
+		name    string
+		secret  []byte
+		token   string
+		want    cursor
+		wantErr bool
+	}{
+		{
+			name:   "SUCCESS::round trip",
+			secret: secret,
+			token:  token,
+			want:   c,
+		},
+		{
+			name:    "FAILURE::signed with another secret",
+			secret:  []byte("other"),
+			token:   token,
+			wantErr: true,
+		},
+		{
+			name:    "FAILURE::signature of another cursor",
+			secret:  secret,
+			token:   payload + "." + otherSignature,
+			wantErr: true,
+		},
+		{
+			name:    "FAILURE::no signature",
+			secret:  secret,
+			token:   payload,
+			wantErr: true,
+		},
+		{
+			name:    "FAILURE::garbage",
+			secret:  secret,
+			token:   "not-a-cursor.!!",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeCursor(tt.secret, tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			diff := testutil.Diff(got, tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package logic
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/PereRohit/util/log"
+	respModel "github.com/PereRohit/util/model"
+	"github.com/vatsal278/TransactionManagementService/internal/codes"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultExportColumns are the columns of a CSV export that does not pick its own
+var defaultExportColumns = []string{"transaction_id", "created_at", "account_number", "type", "status", "amount", "currency", "transfer_to", "comment"}
+
+// exportDateFormats formats the timestamps of a CSV export, always in UTC, by the name of the format
+var exportDateFormats = map[string]func(time.Time) string{
+	"rfc3339":  func(t time.Time) string { return t.UTC().Format(time.RFC3339) },
+	"date":     func(t time.Time) string { return t.UTC().Format("2006-01-02") },
+	"datetime": func(t time.Time) string { return t.UTC().Format("2006-01-02 15:04:05") },
+	"us":       func(t time.Time) string { return t.UTC().Format("01/02/2006") },
+	"eu":       func(t time.Time) string { return t.UTC().Format("02/01/2006") },
+	"unix":     func(t time.Time) string { return strconv.FormatInt(t.Unix(), 10) },
+}
+
+// exportColumns formats each column a CSV export can hold from a transaction and the chosen date format
+var exportColumns = map[string]func(transaction model.Transaction, date func(time.Time) string) string{
+	"transaction_id": func(t model.Transaction, _ func(time.Time) string) string { return t.TransactionId },
+	"created_at":     func(t model.Transaction, date func(time.Time) string) string { return date(t.CreatedAt) },
+	"updated_at":     func(t model.Transaction, date func(time.Time) string) string { return date(t.UpdatedAt) },
+	"account_number": func(t model.Transaction, _ func(time.Time) string) string { return strconv.Itoa(t.AccountNumber) },
+	"type":           func(t model.Transaction, _ func(time.Time) string) string { return t.Type },
+	"status":         func(t model.Transaction, _ func(time.Time) string) string { return t.Status },
+	"amount":         func(t model.Transaction, _ func(time.Time) string) string { return t.Amount.String() },
+	"currency":       func(t model.Transaction, _ func(time.Time) string) string { return t.Currency },
+	"transfer_to": func(t model.Transaction, _ func(time.Time) string) string {
+		if t.TransferTo == 0 {
+			return ""
+		}
+		return strconv.Itoa(t.TransferTo)
+	},
+	"comment":         func(t model.Transaction, _ func(time.Time) string) string { return neutraliseFormula(t.Comment) },
+	"transfer_id":     func(t model.Transaction, _ func(time.Time) string) string { return t.TransferId },
+	"reference_id":    func(t model.Transaction, _ func(time.Time) string) string { return t.ReferenceId },
+	"refunded_amount": func(t model.Transaction, _ func(time.Time) string) string { return t.RefundedAmount.String() },
+	"fx_rate": func(t model.Transaction, _ func(time.Time) string) string {
+		if t.FxRate == 0 {
+			return ""
+		}
+		return t.FxRate.String()
+	},
+	"counter_amount": func(t model.Transaction, _ func(time.Time) string) string {
+		if t.CounterCurrency == "" {
+			return ""
+		}
+		return t.CounterAmount.String()
+	},
+	"counter_currency": func(t model.Transaction, _ func(time.Time) string) string { return t.CounterCurrency },
+	"batch_id":         func(t model.Transaction, _ func(time.Time) string) string { return t.BatchId },
+}
+
+// neutraliseFormula prefixes free text that a spreadsheet would run as a formula with a quote so it is shown as text
+func neutraliseFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// ExportTransactions writes the user's transactions matching the list filters to w as CSV with CRLF line endings
+// and quoting as in RFC 4180, in the order of the listing. Rows are streamed from the datasource as they are read,
+// so exports of any size are never held in memory. The header row is only written along with the first row, or at
+// the end when there is none, so nothing reaches w when the export fails before any row is read.
+func (l transactionManagementServiceLogic) ExportTransactions(list model.ListTransactions, export model.CsvExport, w io.Writer) *respModel.Response {
+	columns := export.Columns
+	if len(columns) == 0 {
+		columns = defaultExportColumns
+	}
+	for _, column := range columns {
+		if exportColumns[column] == nil {
+			return &respModel.Response{
+				Status:  http.StatusBadRequest,
+				Message: fmt.Sprintf("%s: column %q is not supported", codes.GetErr(codes.ErrInvalidFilter), column),
+				Data:    nil,
+			}
+		}
+	}
+	dateFormat := export.DateFormat
+	if dateFormat == "" {
+		dateFormat = "rfc3339"
+	}
+	date, ok := exportDateFormats[dateFormat]
+	if !ok {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: fmt.Sprintf("%s: date_format %q is not supported", codes.GetErr(codes.ErrInvalidFilter), dateFormat),
+			Data:    nil,
+		}
+	}
+	writer := csv.NewWriter(w)
+	writer.UseCRLF = true
+	rows := 0
+	record := make([]string, len(columns))
+	err := l.DsSvc.Stream(model.Query{Where: listFilter(list), OrderBy: listOrder(list)}, func(transaction model.Transaction) error {
+		if rows == 0 {
+			err := writer.Write(columns)
+			if err != nil {
+				return err
+			}
+		}
+		for i, column := range columns {
+			record[i] = exportColumns[column](transaction, date)
+		}
+		rows++
+		return writer.Write(record)
+	})
+	if err == nil && rows == 0 {
+		err = writer.Write(columns)
+	}
+	if err == nil {
+		writer.Flush()
+		err = writer.Error()
+	}
+	if err != nil {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrExport),
+			Data:    nil,
+		}
+	}
+	return &respModel.Response{
+		Status:  http.StatusOK,
+		Message: "SUCCESS",
+		Data:    rows,
+	}
+}
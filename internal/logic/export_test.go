@@ -0,0 +1,150 @@
+package logic
+
+import (
+	"bytes"
+	"errors"
+	respModel "github.com/PereRohit/util/model"
+	"github.com/PereRohit/util/testutil"
+	"github.com/golang/mock/gomock"
+	"github.com/vatsal278/TransactionManagementService/internal/codes"
+	"github.com/vatsal278/TransactionManagementService/internal/config"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"github.com/vatsal278/TransactionManagementService/pkg/mock"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNeutraliseFormula(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "", want: ""},
+		{in: "rent", want: "rent"},
+		{in: "=SUM(A1:A2)", want: "'=SUM(A1:A2)"},
+		{in: "+1", want: "'+1"},
+		{in: "-1", want: "'-1"},
+		{in: "@cmd", want: "'@cmd"},
+		{in: "\tx", want: "'\tx"},
+		{in: "a=b", want: "a=b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			diff := testutil.Diff(neutraliseFormula(tt.in), tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestTransactionManagementServiceLogic_ExportTransactions(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	list := model.ListTransactions{UserId: "123", Type: "debit"}
+	query := model.Query{
+		Where:   model.And(model.Eq("user_id", "123"), model.Eq("type", "debit")),
+		OrderBy: []model.OrderBy{{Column: "created_at", Desc: true}, {Column: "transaction_id"}},
+	}
+	createdAt := time.Date(2023, time.June, 15, 10, 30, 0, 0, time.UTC)
+	transactions := []model.Transaction{
+		{TransactionId: "b", AccountNumber: 1, Type: "debit", Status: model.StatusApproved, Amount: 5000, Currency: "USD", TransferTo: 2, Comment: `rent, "june"`, CreatedAt: createdAt},
+		{TransactionId: "a", AccountNumber: 1, Type: "debit", Status: model.StatusPending, Amount: 125, Currency: "EUR", Comment: "=HYPERLINK()", CreatedAt: createdAt.Add(-time.Hour), FxRate: 108250000, CounterAmount: 135, CounterCurrency: "USD"},
+	}
+	stream := func(transactions []model.Transaction, err error) func(model.Query, func(model.Transaction) error) error {
+		return func(_ model.Query, fn func(model.Transaction) error) error {
+			for _, transaction := range transactions {
+				streamErr := fn(transaction)
+				if streamErr != nil {
+					return streamErr
+				}
+			}
+			return err
+		}
+	}
+	tests := []struct {
+		name    string
+		export  model.CsvExport
+		setup   func() *mock.MockDataSourceI
+		want    respModel.Response
+		wantCsv string
+	}{
+		{
+			name:   "Success::default columns",
+			export: model.CsvExport{},
+			setup: func() *mock.MockDataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().Stream(query, gomock.Any()).Times(1).DoAndReturn(stream(transactions, nil))
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusOK, Message: "SUCCESS", Data: 2},
+			wantCsv: "transaction_id,created_at,account_number,type,status,amount,currency,transfer_to,comment\r\n" +
+				"b,2023-06-15T10:30:00Z,1,debit,approved,50.00,USD,2,\"rent, \"\"june\"\"\"\r\n" +
+				"a,2023-06-15T09:30:00Z,1,debit,pending,1.25,EUR,,'=HYPERLINK()\r\n",
+		},
+		{
+			name:   "Success::chosen columns and date format",
+			export: model.CsvExport{Columns: []string{"transaction_id", "created_at", "fx_rate", "counter_amount", "counter_currency"}, DateFormat: "eu"},
+			setup: func() *mock.MockDataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().Stream(query, gomock.Any()).Times(1).DoAndReturn(stream(transactions, nil))
+				return mockDs
+			},
+			want:    respModel.Response{Status: http.StatusOK, Message: "SUCCESS", Data: 2},
+			wantCsv: "transaction_id,created_at,fx_rate,counter_amount,counter_currency\r\nb,15/06/2023,,,\r\na,15/06/2023,1.0825,1.35,USD\r\n",
+		},
+		{
+			name:   "Success::header only without rows",
+			export: model.CsvExport{Columns: []string{"transaction_id", "updated_at"}, DateFormat: "unix"},
+			setup: func() *mock.MockDataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().Stream(query, gomock.Any()).Times(1).DoAndReturn(stream(nil, nil))
+				return mockDs
+			},
+			want:    respModel.Response{Status: http.StatusOK, Message: "SUCCESS", Data: 0},
+			wantCsv: "transaction_id,updated_at\r\n",
+		},
+		{
+			name:   "Failure::unknown column",
+			export: model.CsvExport{Columns: []string{"user_id"}},
+			setup: func() *mock.MockDataSourceI {
+				return mock.NewMockDataSourceI(mockCtrl)
+			},
+			want: respModel.Response{Status: http.StatusBadRequest, Message: codes.GetErr(codes.ErrInvalidFilter) + `: column "user_id" is not supported`},
+		},
+		{
+			name:   "Failure::unknown date format",
+			export: model.CsvExport{DateFormat: "iso"},
+			setup: func() *mock.MockDataSourceI {
+				return mock.NewMockDataSourceI(mockCtrl)
+			},
+			want: respModel.Response{Status: http.StatusBadRequest, Message: codes.GetErr(codes.ErrInvalidFilter) + `: date_format "iso" is not supported`},
+		},
+		{
+			name:   "Failure::stream error",
+			export: model.CsvExport{},
+			setup: func() *mock.MockDataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().Stream(query, gomock.Any()).Times(1).DoAndReturn(stream(transactions, errors.New("connection reset")))
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusInternalServerError, Message: codes.GetErr(codes.ErrExport)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := NewTransactionManagementServiceLogic(tt.setup(), config.ExternalSvc{})
+			var buf bytes.Buffer
+			got := rec.ExportTransactions(list, tt.export, &buf)
+			if !reflect.DeepEqual(got, &tt.want) {
+				t.Errorf("Want: %v, Got: %v", &tt.want, got)
+			}
+			diff := testutil.Diff(buf.String(), tt.wantCsv)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,373 @@
+package logic
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/PereRohit/util/log"
+	respModel "github.com/PereRohit/util/model"
+	"github.com/PereRohit/util/validator"
+	"github.com/google/uuid"
+	"github.com/vatsal278/TransactionManagementService/internal/codes"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"github.com/vatsal278/TransactionManagementService/internal/repo/datasource"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// importFields are the fields a row of a CSV import sets, each read from the column named after it unless the import
+// maps it to another header
+var importFields = []string{"created_at", "amount", "type", "status", "account_number", "currency", "comment"}
+
+// importStatuses are the statuses imported history may be recorded with. Unlike new transactions, history keeps the
+// status it was made with.
+var importStatuses = map[string]bool{model.StatusPending: true, model.StatusApproved: true, model.StatusRejected: true}
+
+// importDateFormats reads the timestamps of a CSV import by the name of the format. The names and layouts are those
+// of exportDateFormats, so exported files can be imported back, and timestamps without a zone are read in UTC.
+var importDateFormats = map[string]func(string) (time.Time, error){
+	"rfc3339":  func(s string) (time.Time, error) { return time.Parse(time.RFC3339, s) },
+	"date":     func(s string) (time.Time, error) { return time.Parse("2006-01-02", s) },
+	"datetime": func(s string) (time.Time, error) { return time.Parse("2006-01-02 15:04:05", s) },
+	"us":       func(s string) (time.Time, error) { return time.Parse("01/02/2006", s) },
+	"eu":       func(s string) (time.Time, error) { return time.Parse("02/01/2006", s) },
+	"unix": func(s string) (time.Time, error) {
+		seconds, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(seconds, 0).UTC(), nil
+	},
+}
+
+// maxCommentLength is the number of characters the comment column of the ledger holds
+const maxCommentLength = 255
+
+// importRow is a row of an import file read as the transaction it stands for, or the reason it cannot be imported
+type importRow struct {
+	row         int
+	transaction model.Transaction
+	err         string
+}
+
+// invalidImport returns the response to an import file that cannot be read at all
+func invalidImport(reason string) *respModel.Response {
+	return &respModel.Response{
+		Status:  http.StatusBadRequest,
+		Message: fmt.Sprintf("%s: %s", codes.GetErr(codes.ErrInvalidImport), reason),
+		Data:    nil,
+	}
+}
+
+// signAmount gives a row without a type the type its signed amount stands for, debits being negative
+func signAmount(transaction *model.Transaction, amount model.Money) error {
+	if transaction.Type == "" {
+		transaction.Type = "credit"
+		if amount < 0 {
+			transaction.Type = "debit"
+			amount = -amount
+		}
+	} else if amount < 0 {
+		return errors.New("amount must not be negative when the type is given")
+	}
+	transaction.Amount = amount
+	return nil
+}
+
+// restoreFormula removes the quote neutraliseFormula puts in front of free text a spreadsheet would run as a formula
+func restoreFormula(s string) string {
+	if len(s) > 1 && s[0] == '\'' && strings.ContainsRune("=+-@\t\r", rune(s[1])) {
+		return s[1:]
+	}
+	return s
+}
+
+// readCsvImport reads the rows of a CSV import, the header naming the column of each field. A row whose fields
+// cannot be read is kept with the reason, while a file that is not valid CSV fails as a whole.
+func readCsvImport(r io.Reader, imp model.ImportTransactions, dateFormat string) ([]importRow, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("the file is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	columns := map[string]int{}
+	for _, field := range importFields {
+		name, ok := imp.Columns[field]
+		if !ok {
+			name = field
+		}
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), name) {
+				columns[field] = i
+				break
+			}
+		}
+	}
+	for _, field := range []string{"created_at", "amount", "status"} {
+		if _, ok := columns[field]; !ok {
+			return nil, fmt.Errorf("no column holds the %s field", field)
+		}
+	}
+	if _, ok := columns["account_number"]; !ok && imp.AccountNumber == 0 {
+		return nil, errors.New("no column holds the account_number field and no account_number is given")
+	}
+	var rows []importRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) && errors.Is(parseErr.Err, csv.ErrFieldCount) {
+			rows = append(rows, importRow{row: parseErr.StartLine, err: fmt.Sprintf("row has %d fields, the header has %d", len(record), len(header))})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, csvImportRow(record, columns, imp, dateFormat, line))
+	}
+}
+
+// csvImportRow reads the transaction a row of a CSV import stands for
+func csvImportRow(record []string, columns map[string]int, imp model.ImportTransactions, dateFormat string, line int) importRow {
+	value := func(field string) string {
+		i, ok := columns[field]
+		if !ok {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	row := importRow{row: line, transaction: model.Transaction{
+		UserId:        imp.UserId,
+		AccountNumber: imp.AccountNumber,
+		Status:        strings.ToLower(value("status")),
+		Type:          strings.ToLower(value("type")),
+		Currency:      strings.ToUpper(value("currency")),
+		Comment:       restoreFormula(value("comment")),
+	}}
+	var err error
+	row.transaction.CreatedAt, err = importDateFormats[dateFormat](value("created_at"))
+	if err != nil {
+		row.err = fmt.Sprintf("created_at %q is not in the %s format", value("created_at"), dateFormat)
+		return row
+	}
+	row.transaction.CreatedAt = row.transaction.CreatedAt.UTC()
+	if v := value("account_number"); v != "" {
+		row.transaction.AccountNumber, err = strconv.Atoi(v)
+		if err != nil {
+			row.err = fmt.Sprintf("account_number %q is not a number", v)
+			return row
+		}
+	}
+	amount, err := model.ParseMoney(value("amount"))
+	if err == nil {
+		err = signAmount(&row.transaction, amount)
+	}
+	if err != nil {
+		row.err = err.Error()
+	}
+	return row
+}
+
+// validateImportRow checks the transaction of a row with the rules of new transactions but for its status, returning
+// why it cannot be imported or an empty string
+func validateImportRow(transaction model.Transaction) string {
+	err := validator.Validate(model.NewTransaction{
+		UserId:        transaction.UserId,
+		AccountNumber: transaction.AccountNumber,
+		Amount:        transaction.Amount,
+		Type:          transaction.Type,
+		Comment:       transaction.Comment,
+		Currency:      transaction.Currency,
+	})
+	if err != nil {
+		return strings.TrimSpace(err.Error())
+	}
+	if !importStatuses[transaction.Status] {
+		return fmt.Sprintf("status %q is not one of pending, approved or rejected", transaction.Status)
+	}
+	if transaction.AccountNumber <= 0 {
+		return "account_number is required"
+	}
+	errResp := checkCurrency(transaction.Amount, transaction.Currency)
+	if errResp != nil {
+		return errResp.Message
+	}
+	if utf8.RuneCountInString(transaction.Comment) > maxCommentLength {
+		return fmt.Sprintf("comment is longer than %d characters", maxCommentLength)
+	}
+	return ""
+}
+
+// foreignAccounts returns the accounts of the valid rows that are not registered to the user
+func (l transactionManagementServiceLogic) foreignAccounts(userId string, rows []importRow) (map[int]bool, error) {
+	foreign := map[int]bool{}
+	checked := map[int]bool{}
+	for _, row := range rows {
+		account := row.transaction.AccountNumber
+		if row.err != "" || checked[account] {
+			continue
+		}
+		checked[account] = true
+		owner, err := l.ownsAccount(userId, account)
+		if err != nil {
+			return nil, err
+		}
+		if !owner {
+			foreign[account] = true
+		}
+	}
+	return foreign, nil
+}
+
+// ImportTransactions reads the history of a user's transactions from a CSV or OFX file of another system and reports
+// every row that cannot be imported along with the reason. Rows are checked with the rules of new transactions and
+// must be on accounts registered to the user. Unless it is a dry run, the valid rows are then stored in batches, each
+// in its own database transaction, keeping the time they were made at. Imported transactions are only history: the
+// spending limits and risk rules are not applied to them and the account service is not updated.
+func (l transactionManagementServiceLogic) ImportTransactions(imp model.ImportTransactions, r io.Reader) *respModel.Response {
+	if imp.Format != model.ImportCsv && imp.Format != model.ImportOfx {
+		return invalidImport(fmt.Sprintf("format %q is not supported", imp.Format))
+	}
+	if imp.Format == model.ImportOfx && imp.AccountNumber == 0 {
+		return invalidImport("account_number is required by OFX files")
+	}
+	dateFormat := imp.DateFormat
+	if dateFormat == "" {
+		dateFormat = "rfc3339"
+	}
+	if _, ok := importDateFormats[dateFormat]; !ok {
+		return invalidImport(fmt.Sprintf("date_format %q is not supported", dateFormat))
+	}
+	for field := range imp.Columns {
+		known := false
+		for _, f := range importFields {
+			known = known || f == field
+		}
+		if !known {
+			return invalidImport(fmt.Sprintf("field %q cannot be imported", field))
+		}
+	}
+	data, err := io.ReadAll(io.LimitReader(r, l.UtilSvc.Import.MaxBytes+1))
+	if err != nil {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrReadingReqBody),
+			Data:    nil,
+		}
+	}
+	if int64(len(data)) > l.UtilSvc.Import.MaxBytes {
+		return &respModel.Response{
+			Status:  http.StatusRequestEntityTooLarge,
+			Message: codes.GetErr(codes.ErrImportTooLarge),
+			Data:    nil,
+		}
+	}
+	var rows []importRow
+	if imp.Format == model.ImportOfx {
+		rows, err = readOfx(string(data), imp)
+	} else {
+		rows, err = readCsvImport(bytes.NewReader(data), imp, dateFormat)
+	}
+	if err != nil {
+		return invalidImport(err.Error())
+	}
+	if len(rows) == 0 {
+		return invalidImport("the file holds no transactions")
+	}
+
+	for i := range rows {
+		if rows[i].err != "" {
+			continue
+		}
+		if rows[i].transaction.Currency == "" {
+			rows[i].transaction.Currency = model.DefaultCurrency
+		}
+		rows[i].err = validateImportRow(rows[i].transaction)
+	}
+	foreign, err := l.foreignAccounts(imp.UserId, rows)
+	if err != nil {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrImport),
+			Data:    nil,
+		}
+	}
+	report := model.ImportReport{DryRun: imp.DryRun, Rows: len(rows), Errors: []model.ImportError{}}
+	var accepted []model.Transaction
+	for _, row := range rows {
+		if row.err == "" && foreign[row.transaction.AccountNumber] {
+			row.err = fmt.Sprintf("account %d is not an account of the user", row.transaction.AccountNumber)
+		}
+		if row.err != "" {
+			report.Errors = append(report.Errors, model.ImportError{Row: row.row, Error: row.err})
+			continue
+		}
+		row.transaction.TransactionId = uuid.NewString()
+		accepted = append(accepted, row.transaction)
+	}
+	report.Accepted = len(accepted)
+	if len(accepted) == 0 {
+		return &respModel.Response{
+			Status:  http.StatusUnprocessableEntity,
+			Message: codes.GetErr(codes.ErrNothingImported),
+			Data:    report,
+		}
+	}
+	if imp.DryRun {
+		return &respModel.Response{
+			Status:  http.StatusOK,
+			Message: "SUCCESS",
+			Data:    report,
+		}
+	}
+
+	// Batches already committed stay imported when a later one fails, and the report tells how many rows they held
+	defer func() { l.invalidateCache(accepted[:report.Imported]) }()
+	for start := 0; start < len(accepted); start += l.UtilSvc.Import.BatchSize {
+		batch := accepted[start:]
+		if len(batch) > l.UtilSvc.Import.BatchSize {
+			batch = batch[:l.UtilSvc.Import.BatchSize]
+		}
+		err = l.DsSvc.Transaction(func(ds datasource.DataSourceI) error {
+			err := ds.InsertBatch(batch)
+			if err != nil {
+				return err
+			}
+			for _, transaction := range batch {
+				err = ds.InsertStatusHistory(model.StatusChange{TransactionId: transaction.TransactionId, ToStatus: transaction.Status, ChangedBy: imp.UserId, Reason: "imported"})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			log.Error(err)
+			return &respModel.Response{
+				Status:  http.StatusInternalServerError,
+				Message: codes.GetErr(codes.ErrImport),
+				Data:    report,
+			}
+		}
+		report.Imported += len(batch)
+	}
+	return &respModel.Response{
+		Status:  http.StatusCreated,
+		Message: "SUCCESS",
+		Data:    report,
+	}
+}
@@ -0,0 +1,278 @@
+package logic
+
+import (
+	"errors"
+	respModel "github.com/PereRohit/util/model"
+	"github.com/PereRohit/util/testutil"
+	"github.com/golang/mock/gomock"
+	"github.com/vatsal278/TransactionManagementService/internal/codes"
+	"github.com/vatsal278/TransactionManagementService/internal/config"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"github.com/vatsal278/TransactionManagementService/internal/repo/datasource"
+	"github.com/vatsal278/TransactionManagementService/pkg/mock"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTransactionManagementServiceLogic_ImportTransactions(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	// owned returns the registration of an account of the user importing
+	owned := func(account int) *model.Account {
+		return &model.Account{AccountNumber: account, UserId: "123", Currency: "USD"}
+	}
+	// insertBatch makes the mock expect a batch inserted in a database transaction of its own, along with the
+	// status history of its rows
+	insertBatch := func(mockDs *mock.MockDataSourceI, want []model.Transaction, err error) {
+		mockDs.EXPECT().Transaction(gomock.Any()).Times(1).DoAndReturn(func(fn func(datasource.DataSourceI) error) error {
+			return fn(mockDs)
+		})
+		mockDs.EXPECT().InsertBatch(gomock.Any()).Times(1).DoAndReturn(func(transactions []model.Transaction) error {
+			got := make([]model.Transaction, 0, len(transactions))
+			for _, transaction := range transactions {
+				if transaction.TransactionId == "" {
+					t.Errorf("Want: %v, Got: %v", "a transaction id", transaction)
+				}
+				transaction.TransactionId = ""
+				got = append(got, transaction)
+			}
+			diff := testutil.Diff(got, want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+			return err
+		})
+		if err == nil {
+			mockDs.EXPECT().InsertStatusHistory(gomock.Any()).Times(len(want)).DoAndReturn(func(change model.StatusChange) error {
+				if change.ChangedBy != "123" || change.Reason != "imported" || change.FromStatus != "" {
+					t.Errorf("Want: %v, Got: %v", "a status change of an imported transaction", change)
+				}
+				return nil
+			})
+		}
+	}
+	createdAt := time.Date(2020, time.January, 2, 3, 4, 5, 0, time.UTC)
+	csvFile := "created_at,account_number,type,status,amount,currency,comment\r\n" +
+		"2020-01-02T03:04:05Z,1,credit,approved,10.00,EUR,salary\r\n" +
+		"2020-01-03T00:00:00+01:00,1,DEBIT,Pending,2.50,,'=rent\r\n"
+	threeRows := "created_at,amount,status\n" +
+		"2020-01-02T03:04:05Z,1,approved\n" +
+		"2020-01-02T03:04:05Z,2,approved\n" +
+		"2020-01-02T03:04:05Z,3,approved\n"
+	imported := func(amount model.Money) model.Transaction {
+		return model.Transaction{UserId: "123", AccountNumber: 1, Amount: amount, Status: "approved", Type: "credit", Currency: "USD", CreatedAt: createdAt}
+	}
+	tests := []struct {
+		name  string
+		imp   model.ImportTransactions
+		file  string
+		cfg   config.ImportCfg
+		setup func(mockDs *mock.MockDataSourceI)
+		want  respModel.Response
+	}{
+		{
+			name: "Success::csv",
+			imp:  model.ImportTransactions{UserId: "123", Format: model.ImportCsv},
+			file: csvFile,
+			setup: func(mockDs *mock.MockDataSourceI) {
+				mockDs.EXPECT().GetAccount(1).Times(1).Return(owned(1), nil)
+				insertBatch(mockDs, []model.Transaction{
+					{UserId: "123", AccountNumber: 1, Amount: 1000, Status: "approved", Type: "credit", Currency: "EUR", Comment: "salary", CreatedAt: createdAt},
+					{UserId: "123", AccountNumber: 1, Amount: 250, Status: "pending", Type: "debit", Currency: "USD", Comment: "=rent", CreatedAt: time.Date(2020, time.January, 2, 23, 0, 0, 0, time.UTC)},
+				}, nil)
+			},
+			want: respModel.Response{Status: http.StatusCreated, Message: "SUCCESS", Data: model.ImportReport{Rows: 2, Accepted: 2, Imported: 2, Errors: []model.ImportError{}}},
+		},
+		{
+			name: "Success::dry run with mapped columns and signed amounts",
+			imp: model.ImportTransactions{
+				UserId:        "123",
+				Format:        model.ImportCsv,
+				AccountNumber: 7,
+				Columns:       map[string]string{"created_at": "Datum", "amount": "Betrag", "comment": "Text"},
+				DateFormat:    "eu",
+				DryRun:        true,
+			},
+			file: "\ufeffDatum,Betrag,Status,Text\n15/06/2023,-12.50,approved,groceries\n16/06/2023,100,approved,refund\n",
+			setup: func(mockDs *mock.MockDataSourceI) {
+				mockDs.EXPECT().GetAccount(7).Times(1).Return(owned(7), nil)
+			},
+			want: respModel.Response{Status: http.StatusOK, Message: "SUCCESS", Data: model.ImportReport{DryRun: true, Rows: 2, Accepted: 2, Errors: []model.ImportError{}}},
+		},
+		{
+			name: "Success::ofx in batches",
+			imp:  model.ImportTransactions{UserId: "123", Format: model.ImportOfx, AccountNumber: 3},
+			file: "OFXHEADER:100\r\nDATA:OFXSGML\r\n\r\n<OFX>\r\n<BANKMSGSRSV1><STMTTRNRS><STMTRS><CURDEF>EUR\r\n<BANKTRANLIST>\r\n" +
+				"<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20230615103000.000[-5:EST]<TRNAMT>-12.50<FITID>1<NAME>Grocer &amp; Co</STMTTRN>\r\n" +
+				"<STMTTRN><TRNTYPE>CREDIT<DTPOSTED>20230616<TRNAMT>100<FITID>2<NAME>Employer<MEMO>salary</STMTTRN>\r\n" +
+				"</BANKTRANLIST></STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>\r\n",
+			cfg: config.ImportCfg{MaxBytes: 1 << 20, BatchSize: 1},
+			setup: func(mockDs *mock.MockDataSourceI) {
+				mockDs.EXPECT().GetAccount(3).Times(1).Return(owned(3), nil)
+				insertBatch(mockDs, []model.Transaction{
+					{UserId: "123", AccountNumber: 3, Amount: 1250, Status: "approved", Type: "debit", Currency: "EUR", Comment: "Grocer & Co", CreatedAt: time.Date(2023, time.June, 15, 15, 30, 0, 0, time.UTC)},
+				}, nil)
+				insertBatch(mockDs, []model.Transaction{
+					{UserId: "123", AccountNumber: 3, Amount: 10000, Status: "approved", Type: "credit", Currency: "EUR", Comment: "salary", CreatedAt: time.Date(2023, time.June, 16, 0, 0, 0, 0, time.UTC)},
+				}, nil)
+			},
+			want: respModel.Response{Status: http.StatusCreated, Message: "SUCCESS", Data: model.ImportReport{Rows: 2, Accepted: 2, Imported: 2, Errors: []model.ImportError{}}},
+		},
+		{
+			name: "Success::rows with errors",
+			imp:  model.ImportTransactions{UserId: "123", Format: model.ImportCsv},
+			file: "created_at,account_number,type,status,amount,currency,comment\n" +
+				"2020-01-02T03:04:05Z,1,credit,approved,1.00,,\n" +
+				"yesterday,1,credit,approved,1.00,,\n" +
+				"2020-01-02T03:04:05Z,one,credit,approved,1.00,,\n" +
+				"2020-01-02T03:04:05Z,1,credit,approved,1.001,,\n" +
+				"2020-01-02T03:04:05Z,1,debit,approved,-1.00,,\n" +
+				"2020-01-02T03:04:05Z,1,credit,reversed,1.00,,\n" +
+				"2020-01-02T03:04:05Z,1,credit,approved,1.00,XYZ,\n" +
+				"2020-01-02T03:04:05Z,1,credit,approved,1.50,JPY,\n" +
+				"2020-01-02T03:04:05Z,,credit,approved,1.00,,\n" +
+				"2020-01-02T03:04:05Z,1,credit,approved,1.00,," + strings.Repeat("x", 256) + "\n" +
+				"2020-01-02T03:04:05Z,1,credit\n" +
+				"2020-01-02T03:04:05Z,2,credit,approved,1.00,,\n",
+			setup: func(mockDs *mock.MockDataSourceI) {
+				mockDs.EXPECT().GetAccount(1).Times(1).Return(owned(1), nil)
+				mockDs.EXPECT().GetAccount(2).Times(1).Return(&model.Account{AccountNumber: 2, UserId: "999", Currency: "USD"}, nil)
+				insertBatch(mockDs, []model.Transaction{imported(100)}, nil)
+			},
+			want: respModel.Response{Status: http.StatusCreated, Message: "SUCCESS", Data: model.ImportReport{Rows: 12, Accepted: 1, Imported: 1, Errors: []model.ImportError{
+				{Row: 3, Error: `created_at "yesterday" is not in the rfc3339 format`},
+				{Row: 4, Error: `account_number "one" is not a number`},
+				{Row: 5, Error: `amount "1.001" has more than 2 decimal places`},
+				{Row: 6, Error: "amount must not be negative when the type is given"},
+				{Row: 7, Error: "status \"reversed\" is not one of pending, approved or rejected"},
+				{Row: 8, Error: codes.GetErr(codes.ErrInvalidCurrency)},
+				{Row: 9, Error: codes.GetErr(codes.ErrCurrencyPrecision)},
+				{Row: 10, Error: "account_number is required"},
+				{Row: 11, Error: "comment is longer than 255 characters"},
+				{Row: 12, Error: "row has 3 fields, the header has 7"},
+				{Row: 13, Error: "account 2 is not an account of the user"},
+			}}},
+		},
+		{
+			name:  "Failure::no valid row",
+			imp:   model.ImportTransactions{UserId: "123", Format: model.ImportCsv, AccountNumber: 1},
+			file:  "created_at,amount,status\n2020-01-02T03:04:05Z,1,done\n",
+			setup: func(mockDs *mock.MockDataSourceI) {},
+			want: respModel.Response{Status: http.StatusUnprocessableEntity, Message: codes.GetErr(codes.ErrNothingImported), Data: model.ImportReport{Rows: 1, Errors: []model.ImportError{
+				{Row: 2, Error: "status \"done\" is not one of pending, approved or rejected"},
+			}}},
+		},
+		{
+			name: "Failure::batch error",
+			imp:  model.ImportTransactions{UserId: "123", Format: model.ImportCsv, AccountNumber: 1},
+			file: threeRows,
+			cfg:  config.ImportCfg{MaxBytes: 1 << 20, BatchSize: 2},
+			setup: func(mockDs *mock.MockDataSourceI) {
+				mockDs.EXPECT().GetAccount(1).Times(1).Return(owned(1), nil)
+				insertBatch(mockDs, []model.Transaction{imported(100), imported(200)}, nil)
+				insertBatch(mockDs, []model.Transaction{imported(300)}, errors.New("connection reset"))
+			},
+			want: respModel.Response{Status: http.StatusInternalServerError, Message: codes.GetErr(codes.ErrImport), Data: model.ImportReport{Rows: 3, Accepted: 3, Imported: 2, Errors: []model.ImportError{}}},
+		},
+		{
+			name: "Failure::account lookup error",
+			imp:  model.ImportTransactions{UserId: "123", Format: model.ImportCsv, AccountNumber: 1},
+			file: threeRows,
+			setup: func(mockDs *mock.MockDataSourceI) {
+				mockDs.EXPECT().GetAccount(1).Times(1).Return(nil, errors.New("connection reset"))
+			},
+			want: respModel.Response{Status: http.StatusInternalServerError, Message: codes.GetErr(codes.ErrImport)},
+		},
+		{
+			name:  "Failure::file too large",
+			imp:   model.ImportTransactions{UserId: "123", Format: model.ImportCsv, AccountNumber: 1},
+			file:  threeRows,
+			cfg:   config.ImportCfg{MaxBytes: 10, BatchSize: 500},
+			setup: func(mockDs *mock.MockDataSourceI) {},
+			want:  respModel.Response{Status: http.StatusRequestEntityTooLarge, Message: codes.GetErr(codes.ErrImportTooLarge)},
+		},
+		{
+			name:  "Failure::unsupported format",
+			imp:   model.ImportTransactions{UserId: "123", Format: "xls"},
+			setup: func(mockDs *mock.MockDataSourceI) {},
+			want:  respModel.Response{Status: http.StatusBadRequest, Message: codes.GetErr(codes.ErrInvalidImport) + `: format "xls" is not supported`},
+		},
+		{
+			name:  "Failure::ofx without an account",
+			imp:   model.ImportTransactions{UserId: "123", Format: model.ImportOfx},
+			setup: func(mockDs *mock.MockDataSourceI) {},
+			want:  respModel.Response{Status: http.StatusBadRequest, Message: codes.GetErr(codes.ErrInvalidImport) + ": account_number is required by OFX files"},
+		},
+		{
+			name:  "Failure::unsupported date format",
+			imp:   model.ImportTransactions{UserId: "123", Format: model.ImportCsv, DateFormat: "iso"},
+			setup: func(mockDs *mock.MockDataSourceI) {},
+			want:  respModel.Response{Status: http.StatusBadRequest, Message: codes.GetErr(codes.ErrInvalidImport) + `: date_format "iso" is not supported`},
+		},
+		{
+			name:  "Failure::unknown field",
+			imp:   model.ImportTransactions{UserId: "123", Format: model.ImportCsv, Columns: map[string]string{"transfer_to": "Payee"}},
+			setup: func(mockDs *mock.MockDataSourceI) {},
+			want:  respModel.Response{Status: http.StatusBadRequest, Message: codes.GetErr(codes.ErrInvalidImport) + `: field "transfer_to" cannot be imported`},
+		},
+		{
+			name:  "Failure::missing column",
+			imp:   model.ImportTransactions{UserId: "123", Format: model.ImportCsv, AccountNumber: 1},
+			file:  "created_at,amount\n2020-01-02T03:04:05Z,1\n",
+			setup: func(mockDs *mock.MockDataSourceI) {},
+			want:  respModel.Response{Status: http.StatusBadRequest, Message: codes.GetErr(codes.ErrInvalidImport) + ": no column holds the status field"},
+		},
+		{
+			name:  "Failure::no account",
+			imp:   model.ImportTransactions{UserId: "123", Format: model.ImportCsv},
+			file:  "created_at,amount,status\n2020-01-02T03:04:05Z,1,approved\n",
+			setup: func(mockDs *mock.MockDataSourceI) {},
+			want:  respModel.Response{Status: http.StatusBadRequest, Message: codes.GetErr(codes.ErrInvalidImport) + ": no column holds the account_number field and no account_number is given"},
+		},
+		{
+			name:  "Failure::empty file",
+			imp:   model.ImportTransactions{UserId: "123", Format: model.ImportCsv, AccountNumber: 1},
+			setup: func(mockDs *mock.MockDataSourceI) {},
+			want:  respModel.Response{Status: http.StatusBadRequest, Message: codes.GetErr(codes.ErrInvalidImport) + ": the file is empty"},
+		},
+		{
+			name:  "Failure::no rows",
+			imp:   model.ImportTransactions{UserId: "123", Format: model.ImportCsv, AccountNumber: 1},
+			file:  "created_at,amount,status\n",
+			setup: func(mockDs *mock.MockDataSourceI) {},
+			want:  respModel.Response{Status: http.StatusBadRequest, Message: codes.GetErr(codes.ErrInvalidImport) + ": the file holds no transactions"},
+		},
+		{
+			name:  "Failure::malformed csv",
+			imp:   model.ImportTransactions{UserId: "123", Format: model.ImportCsv, AccountNumber: 1},
+			file:  "created_at,amount,status\n2020-01-02T03:04:05Z,1 \"x\",approved\n",
+			setup: func(mockDs *mock.MockDataSourceI) {},
+			want:  respModel.Response{Status: http.StatusBadRequest, Message: codes.GetErr(codes.ErrInvalidImport) + `: parse error on line 2, column 24: bare " in non-quoted-field`},
+		},
+		{
+			name:  "Failure::not ofx",
+			imp:   model.ImportTransactions{UserId: "123", Format: model.ImportOfx, AccountNumber: 1},
+			file:  threeRows,
+			setup: func(mockDs *mock.MockDataSourceI) {},
+			want:  respModel.Response{Status: http.StatusBadRequest, Message: codes.GetErr(codes.ErrInvalidImport) + ": the file is not an OFX document"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDs := mock.NewMockDataSourceI(mockCtrl)
+			tt.setup(mockDs)
+			cfg := tt.cfg
+			if cfg == (config.ImportCfg{}) {
+				cfg = config.ImportCfg{MaxBytes: 1 << 20, BatchSize: 500}
+			}
+			rec := NewTransactionManagementServiceLogic(mockDs, config.ExternalSvc{Import: cfg})
+			got := rec.ImportTransactions(tt.imp, strings.NewReader(tt.file))
+			if !reflect.DeepEqual(got, &tt.want) {
+				t.Errorf("Want: %v, Got: %v", &tt.want, got)
+			}
+		})
+	}
+}
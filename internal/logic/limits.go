@@ -0,0 +1,119 @@
+package logic
+
+import (
+	"github.com/vatsal278/TransactionManagementService/internal/codes"
+	"github.com/vatsal278/TransactionManagementService/internal/config"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"github.com/vatsal278/TransactionManagementService/internal/repo/datasource"
+	"time"
+)
+
+// limitError is returned from inside the database transaction when a debit breaks one of the spending limits
+type limitError struct {
+	message string
+}
+
+func (e limitError) Error() string {
+	return e.message
+}
+
+// limitKey identifies the code of a spending limit by its scope, window and whether it caps the number of debits
+type limitKey struct {
+	account bool
+	window  string
+	count   bool
+}
+
+var limitMessages = map[limitKey]string{
+	{false, model.WindowDaily, false}:   codes.GetErr(codes.ErrUserDailyAmountLimit),
+	{false, model.WindowDaily, true}:    codes.GetErr(codes.ErrUserDailyCountLimit),
+	{false, model.WindowWeekly, false}:  codes.GetErr(codes.ErrUserWeeklyAmountLimit),
+	{false, model.WindowWeekly, true}:   codes.GetErr(codes.ErrUserWeeklyCountLimit),
+	{false, model.WindowMonthly, false}: codes.GetErr(codes.ErrUserMonthlyAmountLimit),
+	{false, model.WindowMonthly, true}:  codes.GetErr(codes.ErrUserMonthlyCountLimit),
+	{true, model.WindowDaily, false}:    codes.GetErr(codes.ErrAccountDailyAmountLimit),
+	{true, model.WindowDaily, true}:     codes.GetErr(codes.ErrAccountDailyCountLimit),
+	{true, model.WindowWeekly, false}:   codes.GetErr(codes.ErrAccountWeeklyAmountLimit),
+	{true, model.WindowWeekly, true}:    codes.GetErr(codes.ErrAccountWeeklyCountLimit),
+	{true, model.WindowMonthly, false}:  codes.GetErr(codes.ErrAccountMonthlyAmountLimit),
+	{true, model.WindowMonthly, true}:   codes.GetErr(codes.ErrAccountMonthlyCountLimit),
+}
+
+// windowStart returns the start of the calendar window, in UTC, the given time falls in
+func windowStart(window string, now time.Time) time.Time {
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch window {
+	case model.WindowWeekly:
+		// Weeks start on Monday
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case model.WindowMonthly:
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return day
+}
+
+// spendingFilter matches the debits made within the window that count towards spending limits. Reversals and
+// refunds are left out, so giving money back does not use up a limit, and so are debits of zero or less stored before
+// they were rejected, so they cannot lower the amount spent.
+func spendingFilter(scope model.Filter, start time.Time) model.Filter {
+	statuses := make([]interface{}, 0, len(model.SpendingStatuses))
+	for _, status := range model.SpendingStatuses {
+		statuses = append(statuses, status)
+	}
+	return model.And(scope, model.Eq("type", "debit"), model.In("status", statuses...), model.Eq("reference_id", ""), model.Gt("amount", model.Money(0)), model.Gte("created_at", start))
+}
+
+// checkLimitRules checks the debit against the rules of one scope, the filter selecting the debits of that scope
+func checkLimitRules(ds datasource.DataSourceI, rules []config.LimitRule, account bool, scope model.Filter, debit model.Transaction, now time.Time) error {
+	for _, rule := range rules {
+		start := windowStart(rule.Window, now)
+		// Amount caps only apply to debits in their own currency, and only add up debits in it
+		if rule.MaxAmount > 0 && debit.Currency == rule.AmountCurrency() {
+			spent, _, err := ds.GetSpending(model.And(spendingFilter(scope, start), model.Eq("currency", debit.Currency)))
+			if err != nil {
+				return err
+			}
+			if spent+debit.Amount > rule.MaxAmount {
+				return limitError{message: limitMessages[limitKey{account, rule.Window, false}]}
+			}
+		}
+		if rule.MaxCount > 0 {
+			_, count, err := ds.GetSpending(spendingFilter(scope, start))
+			if err != nil {
+				return err
+			}
+			if count+1 > rule.MaxCount {
+				return limitError{message: limitMessages[limitKey{account, rule.Window, true}]}
+			}
+		}
+	}
+	return nil
+}
+
+// checkLimits rejects a debit breaking one of the user's spending limits with a limitError. It runs inside the
+// database transaction inserting the debit and first locks the user, so concurrent debits of the same user cannot
+// both pass the check before either is inserted.
+func (l transactionManagementServiceLogic) checkLimits(ds datasource.DataSourceI, debit model.Transaction) error {
+	if debit.Type != "debit" {
+		return nil
+	}
+	// Debits of zero or less are refused before they get here, a negative one would otherwise pass every limit
+	if debit.Amount <= 0 {
+		return limitError{message: codes.GetErr(codes.ErrInvalidAmount)}
+	}
+	rules := l.UtilSvc.Limits.For(debit.UserId)
+	if len(rules.User) == 0 && len(rules.Account) == 0 {
+		return nil
+	}
+	err := ds.LockUser(debit.UserId)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	err = checkLimitRules(ds, rules.User, false, model.Eq("user_id", debit.UserId), debit, now)
+	if err != nil {
+		return err
+	}
+	return checkLimitRules(ds, rules.Account, true, model.And(model.Eq("user_id", debit.UserId), model.Eq("account_number", debit.AccountNumber)), debit, now)
+}
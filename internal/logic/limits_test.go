@@ -0,0 +1,310 @@
+package logic
+
+import (
+	"errors"
+	respModel "github.com/PereRohit/util/model"
+	"github.com/PereRohit/util/testutil"
+	"github.com/golang/mock/gomock"
+	"github.com/vatsal278/TransactionManagementService/internal/codes"
+	"github.com/vatsal278/TransactionManagementService/internal/config"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"github.com/vatsal278/TransactionManagementService/internal/repo/datasource"
+	"github.com/vatsal278/TransactionManagementService/pkg/mock"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWindowStart(t *testing.T) {
+	// Thursday 15 June 2023, already Friday in UTC
+	now := time.Date(2023, time.June, 15, 22, 30, 0, 0, time.FixedZone("UTC-4", -4*60*60))
+	tests := []struct {
+		window string
+		want   time.Time
+	}{
+		{window: model.WindowDaily, want: time.Date(2023, time.June, 16, 0, 0, 0, 0, time.UTC)},
+		{window: model.WindowWeekly, want: time.Date(2023, time.June, 12, 0, 0, 0, 0, time.UTC)},
+		{window: model.WindowMonthly, want: time.Date(2023, time.June, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.window, func(t *testing.T) {
+			diff := testutil.Diff(windowStart(tt.window, now), tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+	// A week starting on Monday includes Sunday
+	sunday := time.Date(2023, time.June, 18, 12, 0, 0, 0, time.UTC)
+	diff := testutil.Diff(windowStart(model.WindowWeekly, sunday), time.Date(2023, time.June, 12, 0, 0, 0, 0, time.UTC))
+	if diff != "" {
+		t.Error(testutil.Callers(), diff)
+	}
+}
+
+func TestSpendingFilter(t *testing.T) {
+	start := time.Date(2023, time.June, 12, 0, 0, 0, 0, time.UTC)
+	// Only positive debits count, so a negative debit stored in the window cannot lower the amount spent
+	want := model.And(model.Eq("user_id", "123"), model.Eq("type", "debit"), model.In("status", model.StatusPending, model.StatusReview, model.StatusApproved, model.StatusReversed), model.Eq("reference_id", ""), model.Gt("amount", model.Money(0)), model.Gte("created_at", start))
+	diff := testutil.Diff(spendingFilter(model.Eq("user_id", "123"), start), want)
+	if diff != "" {
+		t.Error(testutil.Callers(), diff)
+	}
+}
+
+func TestTransactionManagementServiceLogic_NewTransactionLimits(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	inTransaction := func(mockDs *mock.MockDataSourceI) {
+		mockDs.EXPECT().Transaction(gomock.Any()).Times(1).DoAndReturn(func(fn func(datasource.DataSourceI) error) error {
+			return fn(mockDs)
+		})
+	}
+	debit := model.NewTransaction{UserId: "123", AccountNumber: 1, Type: "debit", Status: "pending", Amount: 2500}
+	user := model.Eq("user_id", "123")
+	account := model.And(model.Eq("user_id", "123"), model.Eq("account_number", 1))
+	// spent matches the debits of the scope within the window, in the currency of the cap for amount limits
+	spent := func(scope model.Filter, window string, currency string) model.Filter {
+		filter := spendingFilter(scope, windowStart(window, time.Now()))
+		if currency != "" {
+			filter = model.And(filter, model.Eq("currency", currency))
+		}
+		return filter
+	}
+	limits := config.LimitsCfg{
+		LimitRules: config.LimitRules{
+			User:    []config.LimitRule{{Window: model.WindowDaily, MaxAmount: 10000, MaxCount: 3}, {Window: model.WindowDaily, MaxAmount: 2000, Currency: "EUR"}},
+			Account: []config.LimitRule{{Window: model.WindowMonthly, MaxAmount: 50000}},
+		},
+		Overrides: map[string]config.LimitRules{"456": {User: []config.LimitRule{}, Account: []config.LimitRule{}}},
+	}
+	rejected := func(message string) func(*respModel.Response) {
+		return func(resp *respModel.Response) {
+			temp := respModel.Response{
+				Status:  http.StatusUnprocessableEntity,
+				Message: message,
+				Data:    nil,
+			}
+			if !reflect.DeepEqual(resp, &temp) {
+				t.Errorf("Want: %v, Got: %v", &temp, resp)
+			}
+		}
+	}
+	// owned registers the account to the user transacting from it
+	owned := func(mockDs *mock.MockDataSourceI, userId string, accountNumber int) {
+		mockDs.EXPECT().GetAccount(accountNumber).Times(1).Return(&model.Account{AccountNumber: accountNumber, UserId: userId, Currency: "USD"}, nil)
+	}
+	tests := []struct {
+		name        string
+		credentials model.NewTransaction
+		setup       func() datasource.DataSourceI
+		want        func(*respModel.Response)
+	}{
+		{
+			name:        "Success::within limits",
+			credentials: debit,
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				owned(mockDs, "123", 1)
+				inTransaction(mockDs)
+				gomock.InOrder(
+					mockDs.EXPECT().LockUser("123").Times(1).Return(nil),
+					mockDs.EXPECT().GetSpending(spent(user, model.WindowDaily, "USD")).Times(1).Return(model.Money(7500), 2, nil),
+					mockDs.EXPECT().GetSpending(spent(user, model.WindowDaily, "")).Times(1).Return(model.Money(7500), 2, nil),
+					mockDs.EXPECT().GetSpending(spent(account, model.WindowMonthly, "USD")).Times(1).Return(model.Money(47500), 9, nil),
+					mockDs.EXPECT().Insert(gomock.Any()).Times(1).Return(nil),
+				)
+				mockDs.EXPECT().InsertStatusHistory(gomock.Any()).Times(1).Return(nil)
+				mockDs.EXPECT().InsertWebhookDeliveries(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				if resp.Status != http.StatusCreated {
+					t.Errorf("Want: %v, Got: %v", http.StatusCreated, resp)
+				}
+			},
+		},
+		{
+			name:        "Failure::user daily amount",
+			credentials: debit,
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				owned(mockDs, "123", 1)
+				inTransaction(mockDs)
+				mockDs.EXPECT().LockUser("123").Times(1).Return(nil)
+				mockDs.EXPECT().GetSpending(spent(user, model.WindowDaily, "USD")).Times(1).Return(model.Money(7501), 1, nil)
+				return mockDs
+			},
+			want: rejected(codes.GetErr(codes.ErrUserDailyAmountLimit)),
+		},
+		{
+			name:        "Failure::user daily count",
+			credentials: debit,
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				owned(mockDs, "123", 1)
+				inTransaction(mockDs)
+				mockDs.EXPECT().LockUser("123").Times(1).Return(nil)
+				mockDs.EXPECT().GetSpending(spent(user, model.WindowDaily, "USD")).Times(1).Return(model.Money(0), 0, nil)
+				mockDs.EXPECT().GetSpending(spent(user, model.WindowDaily, "")).Times(1).Return(model.Money(300), 3, nil)
+				return mockDs
+			},
+			want: rejected(codes.GetErr(codes.ErrUserDailyCountLimit)),
+		},
+		{
+			name:        "Failure::account monthly amount",
+			credentials: debit,
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				owned(mockDs, "123", 1)
+				inTransaction(mockDs)
+				mockDs.EXPECT().LockUser("123").Times(1).Return(nil)
+				mockDs.EXPECT().GetSpending(spent(user, model.WindowDaily, "USD")).Times(1).Return(model.Money(0), 0, nil)
+				mockDs.EXPECT().GetSpending(spent(user, model.WindowDaily, "")).Times(1).Return(model.Money(0), 0, nil)
+				mockDs.EXPECT().GetSpending(spent(account, model.WindowMonthly, "USD")).Times(1).Return(model.Money(47501), 9, nil)
+				return mockDs
+			},
+			want: rejected(codes.GetErr(codes.ErrAccountMonthlyAmountLimit)),
+		},
+		{
+			name:        "Failure::debit capped in its own currency only",
+			credentials: model.NewTransaction{UserId: "123", AccountNumber: 1, Type: "debit", Status: "pending", Amount: 2500, Currency: "EUR"},
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				owned(mockDs, "123", 1)
+				inTransaction(mockDs)
+				// Within the USD cap of 100.00, but not within the EUR cap of 20.00
+				gomock.InOrder(
+					mockDs.EXPECT().LockUser("123").Times(1).Return(nil),
+					mockDs.EXPECT().GetSpending(spent(user, model.WindowDaily, "")).Times(1).Return(model.Money(0), 0, nil),
+					mockDs.EXPECT().GetSpending(spent(user, model.WindowDaily, "EUR")).Times(1).Return(model.Money(0), 0, nil),
+				)
+				return mockDs
+			},
+			want: rejected(codes.GetErr(codes.ErrUserDailyAmountLimit)),
+		},
+		{
+			name:        "Success::spending in other currencies does not count",
+			credentials: model.NewTransaction{UserId: "123", AccountNumber: 1, Type: "debit", Status: "pending", Amount: 1500, Currency: "EUR"},
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				owned(mockDs, "123", 1)
+				inTransaction(mockDs)
+				// Only EUR debits are added up against the EUR cap, and the USD caps are not checked at all
+				gomock.InOrder(
+					mockDs.EXPECT().LockUser("123").Times(1).Return(nil),
+					mockDs.EXPECT().GetSpending(spent(user, model.WindowDaily, "")).Times(1).Return(model.Money(9000), 2, nil),
+					mockDs.EXPECT().GetSpending(spent(user, model.WindowDaily, "EUR")).Times(1).Return(model.Money(500), 1, nil),
+					mockDs.EXPECT().Insert(gomock.Any()).Times(1).Return(nil),
+				)
+				mockDs.EXPECT().InsertStatusHistory(gomock.Any()).Times(1).Return(nil)
+				mockDs.EXPECT().InsertWebhookDeliveries(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				if resp.Status != http.StatusCreated {
+					t.Errorf("Want: %v, Got: %v", http.StatusCreated, resp)
+				}
+			},
+		},
+		{
+			name:        "Failure::negative debit frees no headroom",
+			credentials: model.NewTransaction{UserId: "123", AccountNumber: 1, Type: "debit", Status: "pending", Amount: -5000},
+			setup: func() datasource.DataSourceI {
+				// Refused before the limits are read, so it is never stored to lower the amount spent
+				return mock.NewMockDataSourceI(mockCtrl)
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: codes.GetErr(codes.ErrInvalidAmount),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+		{
+			name:        "Success::limits lifted for the user",
+			credentials: model.NewTransaction{UserId: "456", AccountNumber: 2, Type: "debit", Status: "pending", Amount: 1000000},
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				owned(mockDs, "456", 2)
+				inTransaction(mockDs)
+				mockDs.EXPECT().Insert(gomock.Any()).Times(1).Return(nil)
+				mockDs.EXPECT().InsertStatusHistory(gomock.Any()).Times(1).Return(nil)
+				mockDs.EXPECT().InsertWebhookDeliveries(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				if resp.Status != http.StatusCreated {
+					t.Errorf("Want: %v, Got: %v", http.StatusCreated, resp)
+				}
+			},
+		},
+		{
+			name:        "Success::credits are not limited",
+			credentials: model.NewTransaction{UserId: "123", AccountNumber: 1, Type: "credit", Status: "pending", Amount: 1000000},
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				owned(mockDs, "123", 1)
+				inTransaction(mockDs)
+				mockDs.EXPECT().Insert(gomock.Any()).Times(1).Return(nil)
+				mockDs.EXPECT().InsertStatusHistory(gomock.Any()).Times(1).Return(nil)
+				mockDs.EXPECT().InsertWebhookDeliveries(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				if resp.Status != http.StatusCreated {
+					t.Errorf("Want: %v, Got: %v", http.StatusCreated, resp)
+				}
+			},
+		},
+		{
+			name:        "Failure::lock error",
+			credentials: debit,
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				owned(mockDs, "123", 1)
+				inTransaction(mockDs)
+				mockDs.EXPECT().LockUser("123").Times(1).Return(errors.New("error"))
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusInternalServerError,
+					Message: codes.GetErr(codes.ErrNewTransaction),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+		{
+			name:        "Failure::spending error",
+			credentials: debit,
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				owned(mockDs, "123", 1)
+				inTransaction(mockDs)
+				mockDs.EXPECT().LockUser("123").Times(1).Return(nil)
+				mockDs.EXPECT().GetSpending(spent(user, model.WindowDaily, "USD")).Times(1).Return(model.Money(0), 0, errors.New("error"))
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				if resp.Status != http.StatusInternalServerError {
+					t.Errorf("Want: %v, Got: %v", http.StatusInternalServerError, resp)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := NewTransactionManagementServiceLogic(tt.setup(), config.ExternalSvc{Limits: limits})
+			tt.want(rec.NewTransaction(tt.credentials))
+		})
+	}
+}
@@ -1,8 +1,9 @@
 package logic
 
 import (
-	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/PereRohit/util/log"
 	respModel "github.com/PereRohit/util/model"
 	"github.com/google/uuid"
@@ -10,9 +11,11 @@ import (
 	"github.com/vatsal278/TransactionManagementService/internal/config"
 	"github.com/vatsal278/TransactionManagementService/internal/model"
 	"github.com/vatsal278/TransactionManagementService/internal/repo/datasource"
+	"io"
 	"io/ioutil"
 	"math"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -21,9 +24,32 @@ import (
 // TransactionManagementServiceLogicIer defines the interface for the transaction management service logic
 type TransactionManagementServiceLogicIer interface {
 	HealthCheck() bool
-	GetTransactions(id string, limit int, page int) *respModel.Response
-	DownloadTransaction(id string, cookie string) *respModel.Response
+	GetTransactions(list model.ListTransactions) *respModel.Response
+	GetTransaction(id string, userId string) *respModel.Response
+	DownloadTransaction(id string, userId string, cookie string) *respModel.Response
 	NewTransaction(transaction model.NewTransaction) *respModel.Response
+	UpdateStatus(id string, userId string, update model.UpdateStatus) *respModel.Response
+	GetReviewQueue(userId string) *respModel.Response
+	Reverse(id string, userId string, reversal model.Reversal) *respModel.Response
+	Refund(id string, userId string, refund model.Refund) *respModel.Response
+	GetBalance(accountNumber int, userId string, asOf *time.Time) *respModel.Response
+	CreateSchedule(schedule model.NewSchedule) *respModel.Response
+	GetSchedules(userId string) *respModel.Response
+	GetSchedule(id string, userId string) *respModel.Response
+	UpdateSchedule(id string, userId string, schedule model.NewSchedule) *respModel.Response
+	CancelSchedule(id string, userId string) *respModel.Response
+	RunDueSchedules() ([]model.ScheduleExecution, error)
+	GetStatement(accountNumber int, userId string, month time.Time, cookie string) *respModel.Response
+	ExportTransactions(list model.ListTransactions, export model.CsvExport, w io.Writer) *respModel.Response
+	ExportAccount(export model.AccountExport, w io.Writer) *respModel.Response
+	ImportTransactions(imp model.ImportTransactions, r io.Reader) *respModel.Response
+	NewBatch(batch model.NewBatch) *respModel.Response
+	CreateWebhook(webhook model.NewWebhook) *respModel.Response
+	GetWebhooks(userId string) *respModel.Response
+	DeleteWebhook(id string, userId string) *respModel.Response
+	GetWebhookDeliveries(id string, userId string) *respModel.Response
+	RedeliverWebhook(id string, deliveryId string, userId string) *respModel.Response
+	RegisterAccount(account model.NewAccount) *respModel.Response
 }
 
 // transactionManagementServiceLogic implements the logic for the transaction management service
@@ -45,10 +71,180 @@ func (l transactionManagementServiceLogic) HealthCheck() bool {
 	return l.DsSvc.HealthCheck()
 }
 
-// GetTransactions retrieves the transactions for the given user id, limit, and page
-func (l transactionManagementServiceLogic) GetTransactions(id string, limit int, page int) *respModel.Response {
+// likeEscaper escapes the wildcard characters of a LIKE pattern so user input only matches literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// listFilter builds the filter selecting the user's transactions that match the list parameters
+func listFilter(list model.ListTransactions) model.Filter {
+	filters := []model.Filter{model.Eq("user_id", list.UserId)}
+	if list.From != nil {
+		filters = append(filters, model.Gte("created_at", *list.From))
+	}
+	if list.To != nil {
+		filters = append(filters, model.Lte("created_at", *list.To))
+	}
+	if list.Type != "" {
+		filters = append(filters, model.Eq("type", list.Type))
+	}
+	if list.Status != "" {
+		filters = append(filters, model.Eq("status", list.Status))
+	}
+	if list.AccountNumber != 0 {
+		filters = append(filters, model.Eq("account_number", list.AccountNumber))
+	}
+	if list.TransferTo != 0 {
+		filters = append(filters, model.Eq("transfer_to", list.TransferTo))
+	}
+	if list.MinAmount != nil {
+		filters = append(filters, model.Gte("amount", *list.MinAmount))
+	}
+	if list.MaxAmount != nil {
+		filters = append(filters, model.Lte("amount", *list.MaxAmount))
+	}
+	if list.Comment != "" {
+		filters = append(filters, model.Like("comment", "%"+likeEscaper.Replace(list.Comment)+"%"))
+	}
+	if list.BatchId != "" {
+		filters = append(filters, model.Eq("batch_id", list.BatchId))
+	}
+	if len(filters) == 1 {
+		return filters[0]
+	}
+	return model.And(filters...)
+}
+
+// listOrder returns the sort keys of a listing, newest first by default.
+// transaction_id is always appended as the last key so rows with equal sort values keep a stable order across pages.
+func listOrder(list model.ListTransactions) []model.OrderBy {
+	orderBy := append([]model.OrderBy{}, list.Sort...)
+	if len(orderBy) == 0 {
+		orderBy = append(orderBy, model.OrderBy{Column: "created_at", Desc: true})
+	}
+	return append(orderBy, model.OrderBy{Column: "transaction_id"})
+}
+
+// seekFilter selects the rows that come after the cursor position when scanning in the given direction.
+// Rows are ordered by (created_at, transaction_id), so rows sharing the cursor's created_at are compared on transaction_id.
+func seekFilter(c cursor, desc bool) model.Filter {
+	after := model.Gt
+	if desc {
+		after = model.Lt
+	}
+	return model.Or(
+		after("created_at", c.CreatedAt),
+		model.And(model.Eq("created_at", c.CreatedAt), after("transaction_id", c.TransactionId)),
+	)
+}
+
+// getTransactionsByCursor retrieves a page of the user's transactions using keyset pagination.
+// The page after or before the cursor is found with a seek on (created_at, transaction_id) and one extra row is
+// fetched to tell whether more rows follow, so neither an offset nor a count is needed.
+func (l transactionManagementServiceLogic) getTransactionsByCursor(list model.ListTransactions) *respModel.Response {
+	// A limit below one would run the query unbounded and slice the page with a negative index
+	if list.Limit <= 0 {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidFilter),
+			Data:    nil,
+		}
+	}
+	secret := []byte(l.UtilSvc.CursorSecret)
+	desc := len(list.Sort) == 0 || list.Sort[0].Desc
+	filter := listFilter(list)
+	var c cursor
+	if list.Cursor != "" {
+		var err error
+		c, err = decodeCursor(secret, list.Cursor)
+		if err != nil || c.Desc != desc {
+			log.Error(errInvalidCursor)
+			return &respModel.Response{
+				Status:  http.StatusBadRequest,
+				Message: codes.GetErr(codes.ErrInvalidCursor),
+				Data:    nil,
+			}
+		}
+	}
+	// Pages before the cursor are read in reverse order and flipped back afterwards
+	scanDesc := desc != c.Prev
+	if list.Cursor != "" {
+		filter = model.And(filter, seekFilter(c, scanDesc))
+	}
+	orderBy := []model.OrderBy{{Column: "created_at", Desc: scanDesc}, {Column: "transaction_id", Desc: scanDesc}}
+	transactions, _, err := l.DsSvc.Get(model.Query{Where: filter, OrderBy: orderBy, Limit: list.Limit + 1, SkipCount: true})
+	if err != nil {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrGetTransaction),
+			Data:    nil,
+		}
+	}
+	more := len(transactions) > list.Limit
+	if more {
+		transactions = transactions[:list.Limit]
+	}
+	if list.RunningBalance {
+		err = l.addRunningBalances(list.AccountNumber, list.UserId, transactions)
+		if err != nil {
+			log.Error(err)
+			return &respModel.Response{
+				Status:  http.StatusInternalServerError,
+				Message: codes.GetErr(codes.ErrGetBalance),
+				Data:    nil,
+			}
+		}
+	}
+	if c.Prev {
+		for i, j := 0, len(transactions)-1; i < j; i, j = i+1, j-1 {
+			transactions[i], transactions[j] = transactions[j], transactions[i]
+		}
+	}
+	pagination := model.Paginate{NextPage: -1}
+	if len(transactions) > 0 {
+		first, last := transactions[0], transactions[len(transactions)-1]
+		// Moving forward there is a previous page whenever a cursor was followed, moving backward there is a next one
+		hasNext, hasPrev := more, list.Cursor != ""
+		if c.Prev {
+			hasNext, hasPrev = true, more
+		}
+		if hasNext {
+			pagination.NextCursor, err = encodeCursor(secret, cursor{CreatedAt: last.CreatedAt, TransactionId: last.TransactionId, Desc: desc})
+		}
+		if err == nil && hasPrev {
+			pagination.PrevCursor, err = encodeCursor(secret, cursor{CreatedAt: first.CreatedAt, TransactionId: first.TransactionId, Desc: desc, Prev: true})
+		}
+		if err != nil {
+			log.Error(err)
+			return &respModel.Response{
+				Status:  http.StatusInternalServerError,
+				Message: codes.GetErr(codes.ErrGetTransaction),
+				Data:    nil,
+			}
+		}
+	}
+	return &respModel.Response{
+		Status:  http.StatusOK,
+		Message: "SUCCESS",
+		Data:    model.PaginatedResponse{Response: transactions, Pagination: pagination},
+	}
+}
+
+// GetTransactions retrieves a page of the user's transactions matching the list parameters.
+// Listings asking for keyset pagination are served by cursor, others by page number. Listings asking for the running
+// balance are of a single account, which the user must own.
+func (l transactionManagementServiceLogic) GetTransactions(list model.ListTransactions) *respModel.Response {
+	if list.RunningBalance {
+		errResp := l.checkAccountOwner(list.UserId, list.AccountNumber, codes.GetErr(codes.ErrGetBalance))
+		if errResp != nil {
+			return errResp
+		}
+	}
+	if list.Keyset {
+		return l.getTransactionsByCursor(list)
+	}
+	limit, page := list.Limit, list.Page
 	offset := (page - 1) * limit
-	transactions, count, err := l.DsSvc.Get(map[string]interface{}{"user_id": id}, limit, offset)
+	transactions, count, err := l.DsSvc.Get(model.Query{Where: listFilter(list), OrderBy: listOrder(list), Limit: limit, Offset: offset})
 	if err != nil {
 		log.Error(err)
 		return &respModel.Response{
@@ -57,6 +253,17 @@ func (l transactionManagementServiceLogic) GetTransactions(id string, limit int,
 			Data:    nil,
 		}
 	}
+	if list.RunningBalance {
+		err = l.addRunningBalances(list.AccountNumber, list.UserId, transactions)
+		if err != nil {
+			log.Error(err)
+			return &respModel.Response{
+				Status:  http.StatusInternalServerError,
+				Message: codes.GetErr(codes.ErrGetBalance),
+				Data:    nil,
+			}
+		}
+	}
 	totalPages := int(math.Ceil(float64(count) / float64(limit)))
 	nextPage := -1
 	if count-offset > limit {
@@ -70,8 +277,184 @@ func (l transactionManagementServiceLogic) GetTransactions(id string, limit int,
 	}
 }
 
-// NewTransaction creates a new transaction and updates the account service if status is "approved"
+// checkBalanced verifies the legs of a transfer cancel out: a debit and a credit linked by the same transfer id,
+// of the same amount when they share a currency. Legs in different currencies each record the amount and currency
+// of the other leg along with the rate used, so both sides balance in their own currency.
+func checkBalanced(legs []model.Transaction) error {
+	if len(legs) != 2 {
+		return fmt.Errorf("transfer has %d legs, want 2", len(legs))
+	}
+	var debit, credit *model.Transaction
+	for i, leg := range legs {
+		if leg.TransferId == "" || leg.TransferId != legs[0].TransferId {
+			return errors.New("transfer legs are not linked by the same transfer id")
+		}
+		switch leg.Type {
+		case "debit":
+			debit = &legs[i]
+		case "credit":
+			credit = &legs[i]
+		default:
+			return fmt.Errorf("transfer leg has type %q", leg.Type)
+		}
+	}
+	if debit == nil || credit == nil {
+		return errors.New("transfer needs one debit and one credit leg")
+	}
+	if debit.Currency == credit.Currency {
+		if debit.Amount != credit.Amount {
+			return fmt.Errorf("transfer debits %v and credits %v differ", debit.Amount, credit.Amount)
+		}
+		return nil
+	}
+	if debit.FxRate <= 0 || debit.FxRate != credit.FxRate ||
+		debit.CounterAmount != credit.Amount || debit.CounterCurrency != credit.Currency ||
+		credit.CounterAmount != debit.Amount || credit.CounterCurrency != debit.Currency {
+		return errors.New("cross-currency transfer legs do not record each other")
+	}
+	return nil
+}
+
+// fxRate returns the rate converting base into quote in effect at the given time, derived from the rate of the
+// inverse pair when only that one is known. It returns zero when neither is known.
+func (l transactionManagementServiceLogic) fxRate(base string, quote string, at time.Time) (model.Rate, error) {
+	rate, err := l.DsSvc.GetFxRate(base, quote, at)
+	if err != nil {
+		return 0, err
+	}
+	if rate != nil {
+		return rate.Rate, nil
+	}
+	rate, err = l.DsSvc.GetFxRate(quote, base, at)
+	if err != nil {
+		return 0, err
+	}
+	if rate != nil {
+		return rate.Rate.Invert(), nil
+	}
+	return 0, nil
+}
+
+// transferLegs splits a transfer into the sender's debit leg and a credit leg owned by the user the receiving
+// account belongs to, linked by a new transfer id. The owner and currency of the receiving account are those it was
+// registered with. When the currencies differ the credit leg is converted at the rate in effect now.
+func (l transactionManagementServiceLogic) transferLegs(debit model.Transaction) ([]model.Transaction, *respModel.Response) {
+	if debit.Type != "debit" || debit.TransferTo == debit.AccountNumber {
+		return nil, &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidTransfer),
+			Data:    nil,
+		}
+	}
+	recipient, err := l.DsSvc.GetAccount(debit.TransferTo)
+	if err != nil {
+		log.Error(err)
+		return nil, &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrNewTransaction),
+			Data:    nil,
+		}
+	}
+	if recipient == nil {
+		return nil, &respModel.Response{
+			Status:  http.StatusUnprocessableEntity,
+			Message: codes.GetErr(codes.ErrUnknownRecipient),
+			Data:    nil,
+		}
+	}
+	debit.TransferId = uuid.NewString()
+	credit := model.Transaction{
+		UserId:        recipient.UserId,
+		AccountNumber: debit.TransferTo,
+		TransactionId: uuid.NewString(),
+		Amount:        debit.Amount,
+		TransferTo:    debit.AccountNumber,
+		Status:        debit.Status,
+		Type:          "credit",
+		Comment:       debit.Comment,
+		TransferId:    debit.TransferId,
+		Currency:      recipient.Currency,
+		BatchId:       debit.BatchId,
+	}
+	if credit.Currency == "" {
+		credit.Currency = model.DefaultCurrency
+	}
+	if credit.Currency != debit.Currency {
+		rate, err := l.fxRate(debit.Currency, credit.Currency, time.Now())
+		if err != nil {
+			log.Error(err)
+			return nil, &respModel.Response{
+				Status:  http.StatusInternalServerError,
+				Message: codes.GetErr(codes.ErrNewTransaction),
+				Data:    nil,
+			}
+		}
+		if rate == 0 {
+			return nil, &respModel.Response{
+				Status:  http.StatusUnprocessableEntity,
+				Message: codes.GetErr(codes.ErrNoFxRate),
+				Data:    nil,
+			}
+		}
+		credit.Amount, err = debit.Amount.Convert(rate, credit.Currency)
+		if err != nil || credit.Amount == 0 {
+			return nil, &respModel.Response{
+				Status:  http.StatusBadRequest,
+				Message: codes.GetErr(codes.ErrInvalidAmount),
+				Data:    nil,
+			}
+		}
+		debit.FxRate, credit.FxRate = rate, rate
+		debit.CounterAmount, debit.CounterCurrency = credit.Amount, credit.Currency
+		credit.CounterAmount, credit.CounterCurrency = debit.Amount, debit.Currency
+	}
+	legs := []model.Transaction{debit, credit}
+	err = checkBalanced(legs)
+	if err != nil {
+		log.Error(err)
+		return nil, &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrUnbalancedTransfer),
+			Data:    nil,
+		}
+	}
+	return legs, nil
+}
+
+// checkCurrency validates the currency of a new transaction and that its amount fits the decimal places of the
+// currency
+func checkCurrency(amount model.Money, currency string) *respModel.Response {
+	if _, ok := model.CurrencyExponent(currency); !ok {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidCurrency),
+			Data:    nil,
+		}
+	}
+	if !amount.FitsCurrency(currency) {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrCurrencyPrecision),
+			Data:    nil,
+		}
+	}
+	return nil
+}
+
+// NewTransaction creates a new transaction. A transfer is stored as a debit leg for the sender and a credit leg
+// for the recipient, written together. New transactions start pending and change the balance once a reviewer
+// approves them through UpdateStatus.
 func (l transactionManagementServiceLogic) NewTransaction(newTransaction model.NewTransaction) *respModel.Response {
+	legs, resp := l.createTransaction(newTransaction)
+	l.invalidateCache(legs)
+	return resp
+}
+
+// createTransaction stores a new transaction and returns the legs stored, if any, along with the response to the
+// request. It joins the database transaction the datasource is bound to, if any, and leaves invalidating the cache
+// to the caller once that transaction is committed. The transaction starts pending whatever status was requested,
+// unless the risk rules hold or reject it.
+func (l transactionManagementServiceLogic) createTransaction(newTransaction model.NewTransaction) ([]model.Transaction, *respModel.Response) {
 	// Create a new transaction using the input data
 	transaction := model.Transaction{
 		UserId:        newTransaction.UserId,
@@ -79,93 +462,256 @@ func (l transactionManagementServiceLogic) NewTransaction(newTransaction model.N
 		TransactionId: uuid.NewString(),
 		Amount:        newTransaction.Amount,
 		TransferTo:    newTransaction.TransferTo,
-		Status:        newTransaction.Status,
+		Status:        model.StatusPending,
 		Type:          newTransaction.Type,
 		Comment:       newTransaction.Comment,
+		Currency:      newTransaction.Currency,
+		BatchId:       newTransaction.BatchId,
+	}
+	// A negative amount would turn a debit into a credit, and the credit leg of a transfer into a debit of the
+	// recipient
+	if transaction.Amount <= 0 {
+		return nil, &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidAmount),
+			Data:    nil,
+		}
+	}
+	if transaction.Currency == "" {
+		transaction.Currency = model.DefaultCurrency
+	}
+	errResp := checkCurrency(transaction.Amount, transaction.Currency)
+	if errResp != nil {
+		return nil, errResp
+	}
+	// Only the owner of an account transacts from it
+	errResp = l.checkAccountOwner(transaction.UserId, transaction.AccountNumber, codes.GetErr(codes.ErrNewTransaction))
+	if errResp != nil {
+		return nil, errResp
+	}
+	legs := []model.Transaction{transaction}
+	if transaction.TransferTo != 0 {
+		legs, errResp = l.transferLegs(transaction)
+		if errResp != nil {
+			return nil, errResp
+		}
 	}
 
-	// Insert the new transaction into the database
-	err := l.DsSvc.Insert(transaction)
+	// Check the spending limits and the risk rules, then insert the legs and the rule hits atomically
+	decision := transaction.Status
+	err := l.DsSvc.Transaction(func(ds datasource.DataSourceI) error {
+		err := l.checkLimits(ds, transaction)
+		if err != nil {
+			return err
+		}
+		var hits []model.RiskHit
+		decision, hits, err = l.assessRisk(ds, transaction)
+		if err != nil {
+			return err
+		}
+		for i := range legs {
+			legs[i].Status = decision
+		}
+		for _, leg := range legs {
+			err := ds.Insert(leg)
+			if err != nil {
+				return err
+			}
+			err = recordStatusChange(ds, model.StatusChange{TransactionId: leg.TransactionId, ToStatus: leg.Status, ChangedBy: newTransaction.UserId}, leg)
+			if err != nil {
+				return err
+			}
+		}
+		for _, hit := range hits {
+			err = ds.InsertRiskHit(hit)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	var limitErr limitError
+	if errors.As(err, &limitErr) {
+		log.Error(err)
+		return nil, &respModel.Response{
+			Status:  http.StatusUnprocessableEntity,
+			Message: limitErr.message,
+			Data:    nil,
+		}
+	}
 	if err != nil {
 		log.Error(err)
-		// If there is an error inserting the transaction, return an error response
-		return &respModel.Response{
+		// If the transaction or its rule hits could not be stored, nothing was written
+		return nil, &respModel.Response{
 			Status:  http.StatusInternalServerError,
 			Message: codes.GetErr(codes.ErrNewTransaction),
 			Data:    nil,
 		}
 	}
-
-	// If the status of the new transaction is not "approved", return a success response
-	if newTransaction.Status != "approved" {
-		return &respModel.Response{
-			Status:  http.StatusCreated,
-			Message: "SUCCESS",
+	// Transactions the risk rules rejected or held are stored for audit, but not created as requested
+	if decision == model.StatusRejected {
+		return legs, &respModel.Response{
+			Status:  http.StatusUnprocessableEntity,
+			Message: codes.GetErr(codes.ErrRiskRejected),
+			Data:    nil,
+		}
+	}
+	if decision == model.StatusReview {
+		return legs, &respModel.Response{
+			Status:  http.StatusAccepted,
+			Message: codes.GetErr(codes.ErrRiskReview),
 			Data:    nil,
 		}
 	}
 
-	// If the status of the new transaction is "approved", update the account service asynchronously
-	upTransaction := model.UpdateTransaction{AccountNumber: newTransaction.AccountNumber, Amount: newTransaction.Amount, TransactionType: newTransaction.Type}
-	by, err := json.Marshal(upTransaction)
+	// Return a success response
+	return legs, &respModel.Response{
+		Status:  http.StatusCreated,
+		Message: "SUCCESS",
+		Data:    nil,
+	}
+}
+
+// ownsAccount reports whether the account is registered to the user.
+func (l transactionManagementServiceLogic) ownsAccount(userId string, accountNumber int) (bool, error) {
+	account, err := l.DsSvc.GetAccount(accountNumber)
+	if err != nil {
+		return false, err
+	}
+	return account != nil && account.UserId == userId, nil
+}
+
+// getTransaction fetches a single transaction, whoever it belongs to
+func (l transactionManagementServiceLogic) getTransaction(id string) (*model.Transaction, *respModel.Response) {
+	transactions, _, err := l.DsSvc.Get(model.Query{Where: model.Eq("transaction_id", id)})
 	if err != nil {
 		log.Error(err)
-		// If there is an error marshaling the update transaction, return an error response
-		return &respModel.Response{
+		return nil, &respModel.Response{
 			Status:  http.StatusInternalServerError,
-			Message: codes.GetErr(codes.ErrNewTransaction),
+			Message: codes.GetErr(codes.ErrGetTransaction),
 			Data:    nil,
 		}
 	}
-	go func(reqBody []byte) {
-		req, err := http.NewRequest("PUT", l.UtilSvc.AccSvcUrl+"/microbank/v1/account/update/transaction", bytes.NewReader(reqBody))
-		if err != nil {
-			log.Error(err)
-			return
+	if len(transactions) == 0 {
+		log.Error("no transaction with specified transaction_id found")
+		return nil, &respModel.Response{
+			Status:  http.StatusNotFound,
+			Message: codes.GetErr(codes.ErrNoTransaction),
+			Data:    nil,
 		}
-		client := http.Client{Timeout: 3 * time.Second}
-		_, err = client.Do(req)
+	}
+	return &transactions[0], nil
+}
+
+// getUserTransaction fetches a single transaction visible to the user, either because the user created it
+// or because the user owns the account it was transferred to. Reviewers are no exception, they see the transactions
+// of others through the review queue only.
+// A transaction that does not exist and one that belongs to someone else both produce the same not found response.
+func (l transactionManagementServiceLogic) getUserTransaction(id string, userId string) (*model.Transaction, *respModel.Response) {
+	transaction, errResp := l.getTransaction(id)
+	if errResp != nil {
+		return nil, errResp
+	}
+	if transaction.UserId == userId {
+		return transaction, nil
+	}
+	if transaction.TransferTo != 0 {
+		owner, err := l.ownsAccount(userId, transaction.TransferTo)
 		if err != nil {
 			log.Error(err)
-			return
+			return nil, &respModel.Response{
+				Status:  http.StatusInternalServerError,
+				Message: codes.GetErr(codes.ErrGetTransaction),
+				Data:    nil,
+			}
 		}
-	}(by)
-	// Return a success response
-	return &respModel.Response{
-		Status:  http.StatusCreated,
-		Message: "SUCCESS",
+		if owner {
+			return transaction, nil
+		}
+	}
+	log.Error("transaction with specified transaction_id is not visible to the user")
+	return nil, &respModel.Response{
+		Status:  http.StatusNotFound,
+		Message: codes.GetErr(codes.ErrNoTransaction),
 		Data:    nil,
 	}
 }
 
-// DownloadTransaction is a method of the transactionManagementServiceLogic struct that downloads a transaction as a PDF.
-func (l transactionManagementServiceLogic) DownloadTransaction(id string, cookie string) *respModel.Response {
-	// Get the transaction with the specified ID from the data store.
-	transactions, _, err := l.DsSvc.Get(map[string]interface{}{"transaction_id": id}, 0, 0)
+// GetTransaction retrieves a single transaction visible to the user along with its status history
+func (l transactionManagementServiceLogic) GetTransaction(id string, userId string) *respModel.Response {
+	transaction, errResp := l.getUserTransaction(id, userId)
+	if errResp != nil {
+		return errResp
+	}
+	history, err := l.DsSvc.GetStatusHistory(transaction.TransactionId)
 	if err != nil {
 		log.Error(err)
-		// If an error occurred, return an internal server error response.
 		return &respModel.Response{
 			Status:  http.StatusInternalServerError,
 			Message: codes.GetErr(codes.ErrGetTransaction),
 			Data:    nil,
 		}
 	}
-	// If no transaction was found with the specified ID, return a bad request response.
-	if len(transactions) == 0 {
-		log.Error("no transaction with specified transaction_id found")
+	return &respModel.Response{
+		Status:  http.StatusOK,
+		Message: "SUCCESS",
+		Data:    model.TransactionDetail{Transaction: *transaction, StatusHistory: history},
+	}
+}
+
+// DownloadTransaction is a method of the transactionManagementServiceLogic struct that downloads a transaction as a PDF.
+// Only transactions visible to the user can be downloaded.
+func (l transactionManagementServiceLogic) DownloadTransaction(id string, userId string, cookie string) *respModel.Response {
+	// Get the transaction with the specified ID from the data store, scoped to the user.
+	transaction, errResp := l.getUserTransaction(id, userId)
+	if errResp != nil {
+		return errResp
+	}
+	user, errResp := l.fetchUser(cookie)
+	if errResp != nil {
+		return errResp
+	}
+	// Generate a PDF with the transaction and user data.
+	pdfSvc := l.UtilSvc.PdfSvc.PdfService
+	pdf, err := pdfSvc.GeneratePdf(map[string]interface{}{
+		"Name":                      user["name"],
+		"TransferFromAccountNumber": transaction.AccountNumber,
+		"TransferToAccountNumber":   transaction.TransferTo,
+		"TransactionId":             transaction.TransactionId,
+		"Amount":                    transaction.Amount.String(),
+		"Currency":                  transaction.Currency,
+		"FxRate":                    transaction.FxRate.String(),
+		"CounterAmount":             transaction.CounterAmount.String(),
+		"CounterCurrency":           transaction.CounterCurrency,
+		"Date":                      transaction.CreatedAt,
+		"Status":                    transaction.Status,
+		"Type":                      transaction.Type,
+		"Comment":                   transaction.Comment,
+	}, l.UtilSvc.PdfSvc.UuId)
+	if err != nil {
+		log.Error(err)
 		return &respModel.Response{
-			Status:  http.StatusBadRequest,
-			Message: codes.GetErr(codes.ErrGetTransaction),
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrPdf),
 			Data:    nil,
 		}
 	}
+	// Return a success response
+	return &respModel.Response{
+		Status:  http.StatusOK,
+		Message: "SUCCESS",
+		Data:    pdf,
+	}
+}
+
+// fetchUser fetches the details of the logged-in user from the user service, authenticated by the session cookie.
+func (l transactionManagementServiceLogic) fetchUser(cookie string) (map[string]interface{}, *respModel.Response) {
 	// Create a new HTTP request to the user service to fetch user data.
 	req, err := http.NewRequest("GET", l.UtilSvc.UserSvc+"/microbank/v1/user", nil)
 	if err != nil {
 		log.Error(err)
 		// If an error occurred, return an internal server error response.
-		return &respModel.Response{
+		return nil, &respModel.Response{
 			Status:  http.StatusInternalServerError,
 			Message: codes.GetErr(codes.ErrFetchinDataUserSvc),
 			Data:    nil,
@@ -180,7 +726,7 @@ func (l transactionManagementServiceLogic) DownloadTransaction(id string, cookie
 	if err != nil {
 		log.Error(err)
 		// If an error occurred, return an internal server error response.
-		return &respModel.Response{
+		return nil, &respModel.Response{
 			Status:  http.StatusInternalServerError,
 			Message: codes.GetErr(codes.ErrFetchinDataUserSvc),
 			Data:    nil,
@@ -189,7 +735,7 @@ func (l transactionManagementServiceLogic) DownloadTransaction(id string, cookie
 	// If the user service did not return an OK status code, return an internal server error response.
 	if response.StatusCode != http.StatusOK {
 		log.Info("Status Not OK", response.StatusCode)
-		return &respModel.Response{
+		return nil, &respModel.Response{
 			Status:  http.StatusInternalServerError,
 			Message: codes.GetErr(codes.ErrFetchinDataUserSvc),
 			Data:    nil,
@@ -199,7 +745,7 @@ func (l transactionManagementServiceLogic) DownloadTransaction(id string, cookie
 	var userResp respModel.Response
 	by, err := ioutil.ReadAll(response.Body)
 	if err != nil {
-		return &respModel.Response{
+		return nil, &respModel.Response{
 			Status:  http.StatusInternalServerError,
 			Message: codes.GetErr(codes.ErrReadingReqBody),
 			Data:    nil,
@@ -208,7 +754,7 @@ func (l transactionManagementServiceLogic) DownloadTransaction(id string, cookie
 	err = json.Unmarshal(by, &userResp)
 	if err != nil {
 		log.Error(err)
-		return &respModel.Response{
+		return nil, &respModel.Response{
 			Status:  http.StatusInternalServerError,
 			Message: codes.GetErr(codes.ErrUnmarshall),
 			Data:    nil,
@@ -217,37 +763,11 @@ func (l transactionManagementServiceLogic) DownloadTransaction(id string, cookie
 	// Assert that the response data is a map.
 	user, ok := userResp.Data.(map[string]interface{})
 	if !ok {
-		return &respModel.Response{
+		return nil, &respModel.Response{
 			Status:  http.StatusInternalServerError,
 			Message: codes.GetErr(codes.ErrAssertResp),
 			Data:    nil,
 		}
 	}
-	// Generate a PDF with the transaction and user data.
-	pdfSvc := l.UtilSvc.PdfSvc.PdfService
-	pdf, err := pdfSvc.GeneratePdf(map[string]interface{}{
-		"Name":                      user["name"],
-		"TransferFromAccountNumber": transactions[0].AccountNumber,
-		"TransferToAccountNumber":   transactions[0].TransferTo,
-		"TransactionId":             transactions[0].TransactionId,
-		"Amount":                    transactions[0].Amount,
-		"Date":                      transactions[0].CreatedAt,
-		"Status":                    transactions[0].Status,
-		"Type":                      transactions[0].Type,
-		"Comment":                   transactions[0].Comment,
-	}, l.UtilSvc.PdfSvc.UuId)
-	if err != nil {
-		log.Error(err)
-		return &respModel.Response{
-			Status:  http.StatusInternalServerError,
-			Message: codes.GetErr(codes.ErrPdf),
-			Data:    nil,
-		}
-	}
-	// Return a success response
-	return &respModel.Response{
-		Status:  http.StatusOK,
-		Message: "SUCCESS",
-		Data:    pdf,
-	}
+	return user, nil
 }
@@ -13,7 +13,6 @@ import (
 	"github.com/vatsal278/TransactionManagementService/internal/model"
 
 	pdfMock "github.com/vatsal278/html-pdf-service/pkg/mock"
-	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
@@ -72,10 +71,14 @@ func TestTransactionManagementServiceLogic_GetTransactions(t *testing.T) {
 	defer mockCtrl.Finish()
 
 	tests := []struct {
-		name   string
-		userId string
-		setup  func() (datasource.DataSourceI, config.ExternalSvc)
-		want   func(*respModel.Response)
+		name    string
+		userId  string
+		comment string
+		txType  string
+		batchId string
+		sort    []model.OrderBy
+		setup   func() (datasource.DataSourceI, config.ExternalSvc)
+		want    func(*respModel.Response)
 	}{
 		{
 			name:   "Success :: Get Transaction",
@@ -84,7 +87,7 @@ func TestTransactionManagementServiceLogic_GetTransactions(t *testing.T) {
 				mockDs := mock.NewMockDataSourceI(mockCtrl)
 				var trans []model.Transaction
 				trans = append(trans, model.Transaction{UserId: "123", AccountNumber: 1})
-				mockDs.EXPECT().Get(map[string]interface{}{"user_id": "123"}, 5, 0).Times(1).Return(trans, 1, nil)
+				mockDs.EXPECT().Get(model.Query{Where: model.Eq("user_id", "123"), OrderBy: []model.OrderBy{{Column: "created_at", Desc: true}, {Column: "transaction_id"}}, Limit: 5}).Times(1).Return(trans, 1, nil)
 				return mockDs, config.ExternalSvc{}
 			},
 			want: func(resp *respModel.Response) {
@@ -111,7 +114,7 @@ func TestTransactionManagementServiceLogic_GetTransactions(t *testing.T) {
 				mockDs := mock.NewMockDataSourceI(mockCtrl)
 				var trans []model.Transaction
 				trans = append(trans, model.Transaction{UserId: "123", AccountNumber: 1})
-				mockDs.EXPECT().Get(map[string]interface{}{"user_id": "123"}, 5, 0).Times(1).Return(trans, 100, nil)
+				mockDs.EXPECT().Get(model.Query{Where: model.Eq("user_id", "123"), OrderBy: []model.OrderBy{{Column: "created_at", Desc: true}, {Column: "transaction_id"}}, Limit: 5}).Times(1).Return(trans, 100, nil)
 				return mockDs, config.ExternalSvc{}
 			},
 			want: func(resp *respModel.Response) {
@@ -131,12 +134,328 @@ func TestTransactionManagementServiceLogic_GetTransactions(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:    "Success :: Get Transaction :: filters and sort",
+			userId:  "123",
+			comment: "50%_off",
+			txType:  "debit",
+			batchId: "b1",
+			sort:    []model.OrderBy{{Column: "amount", Desc: true}, {Column: "created_at"}},
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().Get(model.Query{Where: model.And(model.Eq("user_id", "123"), model.Eq("type", "debit"), model.Like("comment", `%50\%\_off%`), model.Eq("batch_id", "b1")), OrderBy: []model.OrderBy{{Column: "amount", Desc: true}, {Column: "created_at"}, {Column: "transaction_id"}}, Limit: 5}).Times(1).Return(nil, 0, nil)
+				return mockDs, config.ExternalSvc{}
+			},
+			want: func(resp *respModel.Response) {
+				if resp.Status != http.StatusOK {
+					t.Errorf("Want: %v, Got: %v", http.StatusOK, resp.Status)
+				}
+			},
+		},
 		{
 			name:   "Failure :: Get Transaction :: db err",
 			userId: "123",
 			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
 				mockDs := mock.NewMockDataSourceI(mockCtrl)
-				mockDs.EXPECT().Get(map[string]interface{}{"user_id": "123"}, 5, 0).Times(1).Return(nil, 0, errors.New("error"))
+				mockDs.EXPECT().Get(model.Query{Where: model.Eq("user_id", "123"), OrderBy: []model.OrderBy{{Column: "created_at", Desc: true}, {Column: "transaction_id"}}, Limit: 5}).Times(1).Return(nil, 0, errors.New("error"))
+				return mockDs, config.ExternalSvc{}
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusInternalServerError,
+					Message: codes.GetErr(codes.ErrGetTransaction),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := NewTransactionManagementServiceLogic(tt.setup())
+
+			got := rec.GetTransactions(model.ListTransactions{UserId: tt.userId, Limit: 5, Page: 1, Comment: tt.comment, Type: tt.txType, BatchId: tt.batchId, Sort: tt.sort})
+
+			tt.want(got)
+		})
+	}
+}
+
+func TestTransactionManagementServiceLogic_GetTransactionsByCursor(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	secret := []byte("secret")
+	day := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	trans := []model.Transaction{
+		{TransactionId: "c", CreatedAt: day.Add(3 * time.Hour)},
+		{TransactionId: "b", CreatedAt: day.Add(2 * time.Hour)},
+		{TransactionId: "a", CreatedAt: day.Add(time.Hour)},
+	}
+	encode := func(c cursor) string {
+		token, err := encodeCursor(secret, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	newest := []model.OrderBy{{Column: "created_at", Desc: true}, {Column: "transaction_id", Desc: true}}
+	oldest := []model.OrderBy{{Column: "created_at"}, {Column: "transaction_id"}}
+	tests := []struct {
+		name   string
+		list   model.ListTransactions
+		setup  func() datasource.DataSourceI
+		status int
+		want   model.Paginate
+		ids    []string
+	}{
+		{
+			name: "Success :: first page",
+			list: model.ListTransactions{UserId: "123", Limit: 2, Keyset: true},
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().Get(model.Query{Where: model.Eq("user_id", "123"), OrderBy: newest, Limit: 3, SkipCount: true}).Times(1).Return(trans, 0, nil)
+				return mockDs
+			},
+			status: http.StatusOK,
+			want:   model.Paginate{NextPage: -1, NextCursor: encode(cursor{CreatedAt: trans[1].CreatedAt, TransactionId: "b", Desc: true})},
+			ids:    []string{"c", "b"},
+		},
+		{
+			name: "Success :: next page",
+			list: model.ListTransactions{UserId: "123", Limit: 2, Keyset: true, Cursor: encode(cursor{CreatedAt: trans[1].CreatedAt, TransactionId: "b", Desc: true})},
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				seek := model.Or(model.Lt("created_at", trans[1].CreatedAt), model.And(model.Eq("created_at", trans[1].CreatedAt), model.Lt("transaction_id", "b")))
+				mockDs.EXPECT().Get(model.Query{Where: model.And(model.Eq("user_id", "123"), seek), OrderBy: newest, Limit: 3, SkipCount: true}).Times(1).Return(trans[2:], 0, nil)
+				return mockDs
+			},
+			status: http.StatusOK,
+			want:   model.Paginate{NextPage: -1, PrevCursor: encode(cursor{CreatedAt: trans[2].CreatedAt, TransactionId: "a", Desc: true, Prev: true})},
+			ids:    []string{"a"},
+		},
+		{
+			name: "Success :: previous page",
+			list: model.ListTransactions{UserId: "123", Limit: 1, Keyset: true, Cursor: encode(cursor{CreatedAt: trans[2].CreatedAt, TransactionId: "a", Desc: true, Prev: true})},
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				seek := model.Or(model.Gt("created_at", trans[2].CreatedAt), model.And(model.Eq("created_at", trans[2].CreatedAt), model.Gt("transaction_id", "a")))
+				mockDs.EXPECT().Get(model.Query{Where: model.And(model.Eq("user_id", "123"), seek), OrderBy: oldest, Limit: 2, SkipCount: true}).Times(1).Return([]model.Transaction{trans[1], trans[0]}, 0, nil)
+				return mockDs
+			},
+			status: http.StatusOK,
+			want: model.Paginate{
+				NextPage:   -1,
+				NextCursor: encode(cursor{CreatedAt: trans[1].CreatedAt, TransactionId: "b", Desc: true}),
+				PrevCursor: encode(cursor{CreatedAt: trans[1].CreatedAt, TransactionId: "b", Desc: true, Prev: true}),
+			},
+			ids: []string{"b"},
+		},
+		{
+			name: "Success :: oldest first",
+			list: model.ListTransactions{UserId: "123", Limit: 5, Keyset: true, Sort: []model.OrderBy{{Column: "created_at"}}},
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().Get(model.Query{Where: model.Eq("user_id", "123"), OrderBy: oldest, Limit: 6, SkipCount: true}).Times(1).Return(nil, 0, nil)
+				return mockDs
+			},
+			status: http.StatusOK,
+			want:   model.Paginate{NextPage: -1},
+		},
+		{
+			name: "Failure :: tampered cursor",
+			list: model.ListTransactions{UserId: "123", Limit: 2, Keyset: true, Cursor: encode(cursor{TransactionId: "b", Desc: true}) + "x"},
+			setup: func() datasource.DataSourceI {
+				return mock.NewMockDataSourceI(mockCtrl)
+			},
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "Failure :: cursor of another sort direction",
+			list: model.ListTransactions{UserId: "123", Limit: 2, Keyset: true, Cursor: encode(cursor{TransactionId: "b"})},
+			setup: func() datasource.DataSourceI {
+				return mock.NewMockDataSourceI(mockCtrl)
+			},
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "Failure :: negative limit",
+			list: model.ListTransactions{UserId: "123", Limit: -1, Keyset: true},
+			setup: func() datasource.DataSourceI {
+				return mock.NewMockDataSourceI(mockCtrl)
+			},
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "Failure :: db err",
+			list: model.ListTransactions{UserId: "123", Limit: 2, Keyset: true},
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().Get(gomock.Any()).Times(1).Return(nil, 0, errors.New("error"))
+				return mockDs
+			},
+			status: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := NewTransactionManagementServiceLogic(tt.setup(), config.ExternalSvc{CursorSecret: string(secret)})
+
+			got := rec.GetTransactions(tt.list)
+
+			if got.Status != tt.status {
+				t.Errorf("Want: %v, Got: %v", tt.status, got.Status)
+				return
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			resp := got.Data.(model.PaginatedResponse)
+			diff := testutil.Diff(resp.Pagination, tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+			var ids []string
+			for _, transaction := range resp.Response {
+				ids = append(ids, transaction.TransactionId)
+			}
+			diff = testutil.Diff(ids, tt.ids)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestTransactionManagementServiceLogic_GetTransaction(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	tests := []struct {
+		name          string
+		transactionId string
+		userId        string
+		setup         func() (datasource.DataSourceI, config.ExternalSvc)
+		want          func(*respModel.Response)
+	}{
+		{
+			name:          "Success :: Get Transaction",
+			transactionId: "abc",
+			userId:        "123",
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				trans := []model.Transaction{{TransactionId: "abc", UserId: "123", AccountNumber: 1, Amount: 10}}
+				mockDs.EXPECT().Get(model.Query{Where: model.Eq("transaction_id", "abc")}).Times(1).Return(trans, 1, nil)
+				mockDs.EXPECT().GetStatusHistory("abc").Times(1).Return([]model.StatusChange{{TransactionId: "abc", ToStatus: "approved", ChangedBy: "123"}}, nil)
+				return mockDs, config.ExternalSvc{}
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusOK,
+					Message: "SUCCESS",
+					Data: model.TransactionDetail{
+						Transaction:   model.Transaction{TransactionId: "abc", UserId: "123", AccountNumber: 1, Amount: 10},
+						StatusHistory: []model.StatusChange{{TransactionId: "abc", ToStatus: "approved", ChangedBy: "123"}},
+					},
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+		{
+			name:          "Failure :: Get Transaction :: status history err",
+			transactionId: "abc",
+			userId:        "123",
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				trans := []model.Transaction{{TransactionId: "abc", UserId: "123", AccountNumber: 1, Amount: 10}}
+				mockDs.EXPECT().Get(model.Query{Where: model.Eq("transaction_id", "abc")}).Times(1).Return(trans, 1, nil)
+				mockDs.EXPECT().GetStatusHistory("abc").Times(1).Return(nil, errors.New("error"))
+				return mockDs, config.ExternalSvc{}
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusInternalServerError,
+					Message: codes.GetErr(codes.ErrGetTransaction),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+		{
+			name:          "Failure :: Get Transaction :: belongs to another user",
+			transactionId: "abc",
+			userId:        "123",
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				trans := []model.Transaction{{TransactionId: "abc", UserId: "999", AccountNumber: 1}}
+				mockDs.EXPECT().Get(model.Query{Where: model.Eq("transaction_id", "abc")}).Times(1).Return(trans, 1, nil)
+				return mockDs, config.ExternalSvc{}
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusNotFound,
+					Message: codes.GetErr(codes.ErrNoTransaction),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+		{
+			name:          "Failure :: Get Transaction :: reviewer reading another user's transaction",
+			transactionId: "abc",
+			userId:        "risk-1",
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				trans := []model.Transaction{{TransactionId: "abc", UserId: "999", AccountNumber: 1, Status: model.StatusReview}}
+				mockDs.EXPECT().Get(model.Query{Where: model.Eq("transaction_id", "abc")}).Times(1).Return(trans, 1, nil)
+				return mockDs, config.ExternalSvc{Risk: config.RiskCfg{Reviewers: []string{"risk-1"}}}
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusNotFound,
+					Message: codes.GetErr(codes.ErrNoTransaction),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+		{
+			name:          "Failure :: Get Transaction :: not found",
+			transactionId: "abc",
+			userId:        "123",
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().Get(model.Query{Where: model.Eq("transaction_id", "abc")}).Times(1).Return(nil, 0, nil)
+				return mockDs, config.ExternalSvc{}
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusNotFound,
+					Message: codes.GetErr(codes.ErrNoTransaction),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+		{
+			name:          "Failure :: Get Transaction :: db err",
+			transactionId: "abc",
+			userId:        "123",
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().Get(model.Query{Where: model.Eq("transaction_id", "abc")}).Times(1).Return(nil, 0, errors.New("error"))
 				return mockDs, config.ExternalSvc{}
 			},
 			want: func(resp *respModel.Response) {
@@ -155,7 +474,7 @@ func TestTransactionManagementServiceLogic_GetTransactions(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			rec := NewTransactionManagementServiceLogic(tt.setup())
 
-			got := rec.GetTransactions(tt.userId, 5, 1)
+			got := rec.GetTransaction(tt.transactionId, tt.userId)
 
 			tt.want(got)
 		})
@@ -172,28 +491,6 @@ type TestServer struct {
 func testClient(hit *bool) func(*TestServer) {
 	return func(c *TestServer) {
 		router := mux.NewRouter()
-		router.HandleFunc("/microbank/v1/account/update/transaction", func(w http.ResponseWriter, r *http.Request) {
-			defer c.wg.Done()
-			defer c.t.Log("Hit")
-			*hit = true
-			expectedReqBody, _ := json.Marshal(model.UpdateTransaction{
-				AccountNumber:   0,
-				Amount:          1000,
-				TransactionType: "debit",
-			})
-			reqBody, _ := ioutil.ReadAll(r.Body)
-			if string(reqBody) != string(expectedReqBody) {
-				c.t.Errorf("Expected request body '%s', got '%s'", expectedReqBody, reqBody)
-				c.t.Fail()
-				return
-			}
-			w.WriteHeader(http.StatusOK)
-		}).Methods(http.MethodPut)
-		router.HandleFunc("/fail/microbank/v1/account/update/transaction", func(w http.ResponseWriter, r *http.Request) {
-			defer c.wg.Done()
-			defer c.t.Log("Hit")
-			w.WriteHeader(http.StatusInternalServerError)
-		}).Methods(http.MethodPut)
 		router.HandleFunc("/microbank/v1/user", func(w http.ResponseWriter, r *http.Request) {
 			defer c.wg.Done()
 			defer c.t.Log("Hit")
@@ -264,9 +561,15 @@ func testClient(hit *bool) func(*TestServer) {
 func TestTransactionManagementServiceLogic_NewTransaction(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
-	tStruct := &TestServer{
-		t:  t,
-		wg: &sync.WaitGroup{},
+	// inTransaction makes the mock run the function given to Transaction against itself
+	inTransaction := func(mockDs *mock.MockDataSourceI) {
+		mockDs.EXPECT().Transaction(gomock.Any()).Times(1).DoAndReturn(func(fn func(datasource.DataSourceI) error) error {
+			return fn(mockDs)
+		})
+	}
+	// owned registers the account to the user making the requests
+	owned := func(mockDs *mock.MockDataSourceI, accountNumber int) {
+		mockDs.EXPECT().GetAccount(accountNumber).Times(1).Return(&model.Account{AccountNumber: accountNumber, UserId: "123", Currency: "USD"}, nil)
 	}
 	tests := []struct {
 		name        string
@@ -275,121 +578,581 @@ func TestTransactionManagementServiceLogic_NewTransaction(t *testing.T) {
 		want        func(*respModel.Response)
 	}{
 		{
-			name: "Success::transaction status != approved",
+			name: "Success::transaction starts pending",
+			credentials: model.NewTransaction{
+				UserId: "123",
+				Amount: 1000,
+			},
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				owned(mockDs, 0)
+				inTransaction(mockDs)
+				mockDs.EXPECT().Insert(gomock.Any()).Times(1).DoAndReturn(func(tr model.Transaction) error {
+					tr.TransactionId = ""
+					tr.CreatedAt = time.Time{}
+					diff := testutil.Diff(tr, model.Transaction{
+						UserId:        "123",
+						AccountNumber: 0,
+						Status:        "pending",
+						Amount:        1000,
+						Currency:      "USD",
+					})
+					if diff != "" {
+						t.Error(testutil.Callers(), diff)
+					}
+					return nil
+				})
+				mockDs.EXPECT().InsertStatusHistory(gomock.Any()).Times(1).DoAndReturn(func(change model.StatusChange) error {
+					change.TransactionId = ""
+					diff := testutil.Diff(change, model.StatusChange{ToStatus: "pending", ChangedBy: "123"})
+					if diff != "" {
+						t.Error(testutil.Callers(), diff)
+					}
+					return nil
+				})
+				mockDs.EXPECT().InsertWebhookDeliveries("123", gomock.Any()).Times(1).Return(nil)
+				return mockDs, config.ExternalSvc{}
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusCreated,
+					Message: "SUCCESS",
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", temp, resp)
+				}
+			},
+		},
+		{
+			name: "Success::requested approval starts pending",
+			credentials: model.NewTransaction{
+				UserId: "123",
+				Type:   "debit",
+				Status: "approved",
+				Amount: 1000,
+			},
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				owned(mockDs, 0)
+				inTransaction(mockDs)
+				var transactionId string
+				mockDs.EXPECT().Insert(gomock.Any()).Times(1).DoAndReturn(func(tr model.Transaction) error {
+					transactionId = tr.TransactionId
+					tr.TransactionId = ""
+					tr.CreatedAt = time.Time{}
+					diff := testutil.Diff(tr, model.Transaction{
+						UserId:        "123",
+						AccountNumber: 0,
+						Type:          "debit",
+						Status:        "pending",
+						Amount:        1000,
+						Currency:      "USD",
+					})
+					if diff != "" {
+						t.Error(testutil.Callers(), diff)
+					}
+					return nil
+				})
+				mockDs.EXPECT().InsertStatusHistory(gomock.Any()).Times(1).DoAndReturn(func(change model.StatusChange) error {
+					diff := testutil.Diff(change, model.StatusChange{TransactionId: transactionId, ToStatus: "pending", ChangedBy: "123"})
+					if diff != "" {
+						t.Error(testutil.Callers(), diff)
+					}
+					return nil
+				})
+				mockDs.EXPECT().InsertWebhookDeliveries("123", gomock.Any()).Times(1).DoAndReturn(func(userId string, delivery model.WebhookDelivery) error {
+					if delivery.EventType != model.EventTransactionCreated || delivery.TransactionId != transactionId {
+						t.Errorf("Want: %v, Got: %v", model.EventTransactionCreated, delivery)
+					}
+					return nil
+				})
+				// Approving is left to a reviewer, so nothing reaches the account service yet
+				return mockDs, config.ExternalSvc{}
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusCreated,
+					Message: "SUCCESS",
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", temp, resp)
+				}
+			},
+		},
+		{
+			name: "Success::transfer written as two legs",
+			credentials: model.NewTransaction{
+				UserId:        "123",
+				AccountNumber: 1,
+				TransferTo:    2,
+				Type:          "debit",
+				Amount:        1000,
+				Comment:       "rent",
+			},
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				owned(mockDs, 1)
+				mockDs.EXPECT().GetAccount(2).Times(1).Return(&model.Account{AccountNumber: 2, UserId: "456", Currency: "USD"}, nil)
+				inTransaction(mockDs)
+				var legs []model.Transaction
+				mockDs.EXPECT().Insert(gomock.Any()).Times(2).DoAndReturn(func(tr model.Transaction) error {
+					legs = append(legs, tr)
+					if len(legs) < 2 {
+						return nil
+					}
+					if legs[0].TransferId == "" || legs[0].TransferId != legs[1].TransferId {
+						t.Errorf("Want: %v, Got: %v", legs[0].TransferId, legs[1].TransferId)
+					}
+					for i := range legs {
+						legs[i].TransactionId = ""
+						legs[i].TransferId = ""
+					}
+					diff := testutil.Diff(legs, []model.Transaction{
+						{UserId: "123", AccountNumber: 1, Amount: 1000, TransferTo: 2, Status: "pending", Type: "debit", Comment: "rent", Currency: "USD"},
+						{UserId: "456", AccountNumber: 2, Amount: 1000, TransferTo: 1, Status: "pending", Type: "credit", Comment: "rent", Currency: "USD"},
+					})
+					if diff != "" {
+						t.Error(testutil.Callers(), diff)
+					}
+					return nil
+				})
+				mockDs.EXPECT().InsertStatusHistory(gomock.Any()).Times(2).DoAndReturn(func(change model.StatusChange) error {
+					if change.ChangedBy != "123" {
+						t.Errorf("Want: %v, Got: %v", "123", change.ChangedBy)
+					}
+					return nil
+				})
+				// Each leg is announced to the webhooks of its own account holder
+				mockDs.EXPECT().InsertWebhookDeliveries("123", gomock.Any()).Times(1).Return(nil)
+				mockDs.EXPECT().InsertWebhookDeliveries("456", gomock.Any()).Times(1).Return(nil)
+				return mockDs, config.ExternalSvc{}
+			},
+			want: func(resp *respModel.Response) {
+				if resp.Status != http.StatusCreated {
+					t.Errorf("Want: %v, Got: %v", http.StatusCreated, resp.Status)
+				}
+			},
+		},
+		{
+			name: "Success::cross-currency transfer converted at the current rate",
+			credentials: model.NewTransaction{
+				UserId:        "123",
+				AccountNumber: 1,
+				TransferTo:    2,
+				Type:          "debit",
+				Status:        "pending",
+				Amount:        1000,
+				Currency:      "USD",
+			},
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				owned(mockDs, 1)
+				mockDs.EXPECT().GetAccount(2).Times(1).Return(&model.Account{AccountNumber: 2, UserId: "456", Currency: "JPY"}, nil)
+				mockDs.EXPECT().GetFxRate("USD", "JPY", gomock.Any()).Times(1).Return(&model.FxRate{BaseCurrency: "USD", QuoteCurrency: "JPY", Rate: 14925000000}, nil)
+				inTransaction(mockDs)
+				var legs []model.Transaction
+				mockDs.EXPECT().Insert(gomock.Any()).Times(2).DoAndReturn(func(tr model.Transaction) error {
+					tr.TransactionId = ""
+					tr.TransferId = ""
+					legs = append(legs, tr)
+					if len(legs) < 2 {
+						return nil
+					}
+					// 10.00 USD at 149.25 is 1492.5 JPY, rounded half up to whole yen
+					diff := testutil.Diff(legs, []model.Transaction{
+						{UserId: "123", AccountNumber: 1, Amount: 1000, TransferTo: 2, Status: "pending", Type: "debit", Currency: "USD", FxRate: 14925000000, CounterAmount: 1493 * model.MajorUnit, CounterCurrency: "JPY"},
+						{UserId: "456", AccountNumber: 2, Amount: 1493 * model.MajorUnit, TransferTo: 1, Status: "pending", Type: "credit", Currency: "JPY", FxRate: 14925000000, CounterAmount: 1000, CounterCurrency: "USD"},
+					})
+					if diff != "" {
+						t.Error(testutil.Callers(), diff)
+					}
+					return nil
+				})
+				mockDs.EXPECT().InsertStatusHistory(gomock.Any()).Times(2).Return(nil)
+				mockDs.EXPECT().InsertWebhookDeliveries(gomock.Any(), gomock.Any()).Times(2).Return(nil)
+				return mockDs, config.ExternalSvc{}
+			},
+			want: func(resp *respModel.Response) {
+				if resp.Status != http.StatusCreated {
+					t.Errorf("Want: %v, Got: %v", http.StatusCreated, resp.Status)
+				}
+			},
+		},
+		{
+			name: "Success::cross-currency transfer converted at the inverse rate",
+			credentials: model.NewTransaction{
+				UserId:        "123",
+				AccountNumber: 1,
+				TransferTo:    2,
+				Type:          "debit",
+				Status:        "pending",
+				Amount:        1000,
+				Currency:      "EUR",
+			},
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				owned(mockDs, 1)
+				mockDs.EXPECT().GetAccount(2).Times(1).Return(&model.Account{AccountNumber: 2, UserId: "456", Currency: "USD"}, nil)
+				mockDs.EXPECT().GetFxRate("EUR", "USD", gomock.Any()).Times(1).Return(nil, nil)
+				mockDs.EXPECT().GetFxRate("USD", "EUR", gomock.Any()).Times(1).Return(&model.FxRate{BaseCurrency: "USD", QuoteCurrency: "EUR", Rate: 80000000}, nil)
+				inTransaction(mockDs)
+				var legs []model.Transaction
+				mockDs.EXPECT().Insert(gomock.Any()).Times(2).DoAndReturn(func(tr model.Transaction) error {
+					tr.TransactionId = ""
+					tr.TransferId = ""
+					legs = append(legs, tr)
+					if len(legs) < 2 {
+						return nil
+					}
+					diff := testutil.Diff(legs, []model.Transaction{
+						{UserId: "123", AccountNumber: 1, Amount: 1000, TransferTo: 2, Status: "pending", Type: "debit", Currency: "EUR", FxRate: 125000000, CounterAmount: 1250, CounterCurrency: "USD"},
+						{UserId: "456", AccountNumber: 2, Amount: 1250, TransferTo: 1, Status: "pending", Type: "credit", Currency: "USD", FxRate: 125000000, CounterAmount: 1000, CounterCurrency: "EUR"},
+					})
+					if diff != "" {
+						t.Error(testutil.Callers(), diff)
+					}
+					return nil
+				})
+				mockDs.EXPECT().InsertStatusHistory(gomock.Any()).Times(2).Return(nil)
+				mockDs.EXPECT().InsertWebhookDeliveries(gomock.Any(), gomock.Any()).Times(2).Return(nil)
+				return mockDs, config.ExternalSvc{}
+			},
+			want: func(resp *respModel.Response) {
+				if resp.Status != http.StatusCreated {
+					t.Errorf("Want: %v, Got: %v", http.StatusCreated, resp.Status)
+				}
+			},
+		},
+		{
+			name: "Failure::no exchange rate between the currencies",
+			credentials: model.NewTransaction{
+				UserId:        "123",
+				AccountNumber: 1,
+				TransferTo:    2,
+				Type:          "debit",
+				Status:        "pending",
+				Amount:        1000,
+				Currency:      "GBP",
+			},
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				owned(mockDs, 1)
+				mockDs.EXPECT().GetAccount(2).Times(1).Return(&model.Account{AccountNumber: 2, UserId: "456", Currency: "INR"}, nil)
+				mockDs.EXPECT().GetFxRate(gomock.Any(), gomock.Any(), gomock.Any()).Times(2).Return(nil, nil)
+				return mockDs, config.ExternalSvc{}
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusUnprocessableEntity,
+					Message: codes.GetErr(codes.ErrNoFxRate),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+		{
+			name: "Failure::exchange rate lookup error",
+			credentials: model.NewTransaction{
+				UserId:        "123",
+				AccountNumber: 1,
+				TransferTo:    2,
+				Type:          "debit",
+				Status:        "pending",
+				Amount:        1000,
+			},
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				owned(mockDs, 1)
+				mockDs.EXPECT().GetAccount(2).Times(1).Return(&model.Account{AccountNumber: 2, UserId: "456", Currency: "EUR"}, nil)
+				mockDs.EXPECT().GetFxRate("USD", "EUR", gomock.Any()).Times(1).Return(nil, errors.New("error"))
+				return mockDs, config.ExternalSvc{}
+			},
+			want: func(resp *respModel.Response) {
+				if resp.Status != http.StatusInternalServerError {
+					t.Errorf("Want: %v, Got: %v", http.StatusInternalServerError, resp.Status)
+				}
+			},
+		},
+		{
+			name: "Failure::unsupported currency",
+			credentials: model.NewTransaction{
+				UserId:   "123",
+				Amount:   1000,
+				Currency: "XYZ",
+			},
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				return mock.NewMockDataSourceI(mockCtrl), config.ExternalSvc{}
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: codes.GetErr(codes.ErrInvalidCurrency),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+		{
+			name: "Failure::amount finer than the currency allows",
+			credentials: model.NewTransaction{
+				UserId:   "123",
+				Amount:   1050,
+				Currency: "JPY",
+			},
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				return mock.NewMockDataSourceI(mockCtrl), config.ExternalSvc{}
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: codes.GetErr(codes.ErrCurrencyPrecision),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+		{
+			name: "Failure::transfer to own account",
+			credentials: model.NewTransaction{
+				UserId:        "123",
+				AccountNumber: 1,
+				TransferTo:    1,
+				Type:          "debit",
+				Status:        "pending",
+				Amount:        1000,
+			},
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				owned(mockDs, 1)
+				return mockDs, config.ExternalSvc{}
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: codes.GetErr(codes.ErrInvalidTransfer),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+		{
+			name: "Failure::credit transfer",
+			credentials: model.NewTransaction{
+				UserId:        "123",
+				AccountNumber: 1,
+				TransferTo:    2,
+				Type:          "credit",
+				Status:        "pending",
+				Amount:        1000,
+			},
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				owned(mockDs, 1)
+				return mockDs, config.ExternalSvc{}
+			},
+			want: func(resp *respModel.Response) {
+				if resp.Status != http.StatusBadRequest {
+					t.Errorf("Want: %v, Got: %v", http.StatusBadRequest, resp.Status)
+				}
+			},
+		},
+		{
+			name: "Failure::unknown recipient",
+			credentials: model.NewTransaction{
+				UserId:        "123",
+				AccountNumber: 1,
+				TransferTo:    2,
+				Type:          "debit",
+				Status:        "pending",
+				Amount:        1000,
+			},
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				owned(mockDs, 1)
+				mockDs.EXPECT().GetAccount(2).Times(1).Return(nil, nil)
+				return mockDs, config.ExternalSvc{}
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusUnprocessableEntity,
+					Message: codes.GetErr(codes.ErrUnknownRecipient),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+		{
+			name: "Failure::recipient lookup err",
 			credentials: model.NewTransaction{
-				UserId: "123",
+				UserId:        "123",
+				AccountNumber: 1,
+				TransferTo:    2,
+				Type:          "debit",
+				Status:        "pending",
+				Amount:        1000,
 			},
 			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
 				mockDs := mock.NewMockDataSourceI(mockCtrl)
-				mockDs.EXPECT().Insert(gomock.Any()).Times(1).DoAndReturn(func(tr model.Transaction) error {
-					tr.TransactionId = ""
-					tr.CreatedAt = time.Time{}
-					diff := testutil.Diff(tr, model.Transaction{
-						UserId:        "123",
-						AccountNumber: 0,
-						Amount:        0,
-					})
-					if diff != "" {
-						t.Error(testutil.Callers(), diff)
-					}
-					return nil
-				})
+				owned(mockDs, 1)
+				mockDs.EXPECT().GetAccount(2).Times(1).Return(nil, errors.New("error"))
+				return mockDs, config.ExternalSvc{}
+			},
+			want: func(resp *respModel.Response) {
+				if resp.Status != http.StatusInternalServerError {
+					t.Errorf("Want: %v, Got: %v", http.StatusInternalServerError, resp.Status)
+				}
+			},
+		},
+		{
+			name: "Failure::account of another user",
+			credentials: model.NewTransaction{
+				UserId:        "123",
+				AccountNumber: 1,
+				Type:          "debit",
+				Status:        "pending",
+				Amount:        1000,
+			},
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetAccount(1).Times(1).Return(&model.Account{AccountNumber: 1, UserId: "999", Currency: "USD"}, nil)
 				return mockDs, config.ExternalSvc{}
 			},
 			want: func(resp *respModel.Response) {
 				temp := respModel.Response{
-					Status:  http.StatusCreated,
-					Message: "SUCCESS",
+					Status:  http.StatusNotFound,
+					Message: codes.GetErr(codes.ErrNoAccount),
 					Data:    nil,
 				}
 				if !reflect.DeepEqual(resp, &temp) {
-					t.Errorf("Want: %v, Got: %v", temp, resp)
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+		{
+			name: "Failure::unregistered account",
+			credentials: model.NewTransaction{
+				UserId:        "123",
+				AccountNumber: 1,
+				Type:          "debit",
+				Status:        "pending",
+				Amount:        1000,
+			},
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetAccount(1).Times(1).Return(nil, nil)
+				return mockDs, config.ExternalSvc{}
+			},
+			want: func(resp *respModel.Response) {
+				if resp.Status != http.StatusNotFound {
+					t.Errorf("Want: %v, Got: %v", http.StatusNotFound, resp.Status)
 				}
 			},
 		},
 		{
-			name: "Success::transaction status = approved",
+			name: "Failure::webhook deliveries insert err",
 			credentials: model.NewTransaction{
 				UserId: "123",
 				Type:   "debit",
-				Status: "approved",
 				Amount: 1000,
 			},
 			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
-				tStruct.wg.Add(1)
-				x := testClient(&tStruct.hit)
-				x(tStruct)
 				mockDs := mock.NewMockDataSourceI(mockCtrl)
-				mockDs.EXPECT().Insert(gomock.Any()).Times(1).DoAndReturn(func(tr model.Transaction) error {
-					tr.TransactionId = ""
-					tr.CreatedAt = time.Time{}
-					diff := testutil.Diff(tr, model.Transaction{
-						UserId:        "123",
-						AccountNumber: 0,
-						Type:          "debit",
-						Status:        "approved",
-						Amount:        1000,
-					})
-					if diff != "" {
-						t.Error(testutil.Callers(), diff)
-					}
-					return nil
-				})
-
-				return mockDs, config.ExternalSvc{AccSvcUrl: tStruct.srv.URL}
+				owned(mockDs, 0)
+				inTransaction(mockDs)
+				mockDs.EXPECT().Insert(gomock.Any()).Times(1).Return(nil)
+				mockDs.EXPECT().InsertStatusHistory(gomock.Any()).Times(1).Return(nil)
+				mockDs.EXPECT().InsertWebhookDeliveries(gomock.Any(), gomock.Any()).Times(1).Return(errors.New("error"))
+				return mockDs, config.ExternalSvc{}
 			},
 			want: func(resp *respModel.Response) {
-				tStruct.wg.Wait()
-				tStruct.srv.Close()
-				if !tStruct.hit {
-					t.Errorf("Want: %v, Got: %v", true, tStruct.hit)
-				}
 				temp := respModel.Response{
-					Status:  http.StatusCreated,
-					Message: "SUCCESS",
+					Status:  http.StatusInternalServerError,
+					Message: codes.GetErr(codes.ErrNewTransaction),
 					Data:    nil,
 				}
 				if !reflect.DeepEqual(resp, &temp) {
-					t.Errorf("Want: %v, Got: %v", temp, resp)
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
 				}
 			},
 		},
 		{
-			name: "Failure::client do failure",
+			name: "Failure::status history insert err",
 			credentials: model.NewTransaction{
 				UserId: "123",
-				Type:   "debit",
-				Status: "approved",
+				Status: "pending",
 				Amount: 1000,
 			},
 			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
 				mockDs := mock.NewMockDataSourceI(mockCtrl)
-				mockDs.EXPECT().Insert(gomock.Any()).Times(1).DoAndReturn(func(tr model.Transaction) error {
-					tr.TransactionId = ""
-					tr.CreatedAt = time.Time{}
-					diff := testutil.Diff(tr, model.Transaction{
-						UserId:        "123",
-						AccountNumber: 0,
-						Type:          "debit",
-						Status:        "approved",
-						Amount:        1000,
-					})
-					if diff != "" {
-						t.Error(testutil.Callers(), diff)
-					}
-					return nil
-				})
-
+				owned(mockDs, 0)
+				inTransaction(mockDs)
+				mockDs.EXPECT().Insert(gomock.Any()).Times(1).Return(nil)
+				mockDs.EXPECT().InsertStatusHistory(gomock.Any()).Times(1).Return(errors.New("error"))
 				return mockDs, config.ExternalSvc{}
 			},
+			want: func(resp *respModel.Response) {
+				if resp.Status != http.StatusInternalServerError {
+					t.Errorf("Want: %v, Got: %v", http.StatusInternalServerError, resp.Status)
+				}
+			},
+		},
+		{
+			name: "Failure::negative debit",
+			credentials: model.NewTransaction{
+				UserId:        "123",
+				AccountNumber: 1,
+				Type:          "debit",
+				Status:        "pending",
+				Amount:        -1000,
+			},
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				return mock.NewMockDataSourceI(mockCtrl), config.ExternalSvc{}
+			},
 			want: func(resp *respModel.Response) {
 				temp := respModel.Response{
-					Status:  http.StatusCreated,
-					Message: "SUCCESS",
+					Status:  http.StatusBadRequest,
+					Message: codes.GetErr(codes.ErrInvalidAmount),
 					Data:    nil,
 				}
 				if !reflect.DeepEqual(resp, &temp) {
-					t.Errorf("Want: %v, Got: %v", temp, resp)
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+		{
+			name: "Failure::negative transfer",
+			credentials: model.NewTransaction{
+				UserId:        "123",
+				AccountNumber: 1,
+				TransferTo:    2,
+				Type:          "debit",
+				Status:        "pending",
+				Amount:        -1000,
+			},
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				// Nothing is looked up or written, so no credit leg can take money from the recipient
+				return mock.NewMockDataSourceI(mockCtrl), config.ExternalSvc{}
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: codes.GetErr(codes.ErrInvalidAmount),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
 				}
 			},
 		},
@@ -397,16 +1160,21 @@ func TestTransactionManagementServiceLogic_NewTransaction(t *testing.T) {
 			name: "Failure::Get from db err",
 			credentials: model.NewTransaction{
 				UserId: "123",
+				Amount: 1000,
 			},
 			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
 				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				owned(mockDs, 0)
+				inTransaction(mockDs)
 				mockDs.EXPECT().Insert(gomock.Any()).Times(1).DoAndReturn(func(tr model.Transaction) error {
 					tr.TransactionId = ""
 					tr.CreatedAt = time.Time{}
 					diff := testutil.Diff(tr, model.Transaction{
 						UserId:        "123",
 						AccountNumber: 0,
-						Amount:        0,
+						Status:        "pending",
+						Amount:        1000,
+						Currency:      "USD",
 					})
 					if diff != "" {
 						t.Error(testutil.Callers(), diff)
@@ -437,6 +1205,70 @@ func TestTransactionManagementServiceLogic_NewTransaction(t *testing.T) {
 	}
 }
 
+func TestCheckBalanced(t *testing.T) {
+	tests := []struct {
+		name    string
+		legs    []model.Transaction
+		wantErr bool
+	}{
+		{
+			name: "Success::balanced",
+			legs: []model.Transaction{{Type: "debit", Amount: 10, TransferId: "t"}, {Type: "credit", Amount: 10, TransferId: "t"}},
+		},
+		{
+			name:    "Failure::amounts differ",
+			legs:    []model.Transaction{{Type: "debit", Amount: 10, TransferId: "t"}, {Type: "credit", Amount: 9, TransferId: "t"}},
+			wantErr: true,
+		},
+		{
+			name:    "Failure::same direction",
+			legs:    []model.Transaction{{Type: "debit", Amount: 10, TransferId: "t"}, {Type: "debit", Amount: 10, TransferId: "t"}},
+			wantErr: true,
+		},
+		{
+			name:    "Failure::different transfer ids",
+			legs:    []model.Transaction{{Type: "debit", Amount: 10, TransferId: "t"}, {Type: "credit", Amount: 10, TransferId: "u"}},
+			wantErr: true,
+		},
+		{
+			name:    "Failure::single leg",
+			legs:    []model.Transaction{{Type: "debit", Amount: 10, TransferId: "t"}},
+			wantErr: true,
+		},
+		{
+			name: "Success::cross-currency legs record each other",
+			legs: []model.Transaction{
+				{Type: "debit", Amount: 1000, Currency: "USD", FxRate: 92000000, CounterAmount: 920, CounterCurrency: "EUR", TransferId: "t"},
+				{Type: "credit", Amount: 920, Currency: "EUR", FxRate: 92000000, CounterAmount: 1000, CounterCurrency: "USD", TransferId: "t"},
+			},
+		},
+		{
+			name: "Failure::cross-currency counter amount differs",
+			legs: []model.Transaction{
+				{Type: "debit", Amount: 1000, Currency: "USD", FxRate: 92000000, CounterAmount: 900, CounterCurrency: "EUR", TransferId: "t"},
+				{Type: "credit", Amount: 920, Currency: "EUR", FxRate: 92000000, CounterAmount: 1000, CounterCurrency: "USD", TransferId: "t"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Failure::cross-currency without rate",
+			legs: []model.Transaction{
+				{Type: "debit", Amount: 1000, Currency: "USD", CounterAmount: 920, CounterCurrency: "EUR", TransferId: "t"},
+				{Type: "credit", Amount: 920, Currency: "EUR", CounterAmount: 1000, CounterCurrency: "USD", TransferId: "t"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkBalanced(tt.legs)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
 func TestTransactionManagementServiceLogic_DownloadTransactions(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -447,17 +1279,19 @@ func TestTransactionManagementServiceLogic_DownloadTransactions(t *testing.T) {
 	tests := []struct {
 		name          string
 		transactionId string
+		userId        string
 		setup         func() (datasource.DataSourceI, config.ExternalSvc)
 		want          func(*respModel.Response)
 	}{
 		{
 			name:          "Success :: DownloadPdf",
 			transactionId: "123",
+			userId:        "123",
 			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
 				mockDs := mock.NewMockDataSourceI(mockCtrl)
 				var transactions []model.Transaction
 				transactions = append(transactions, model.Transaction{UserId: "123", AccountNumber: 1})
-				mockDs.EXPECT().Get(map[string]interface{}{"transaction_id": "123"}, 0, 0).Times(1).Return(transactions, 1, nil)
+				mockDs.EXPECT().Get(model.Query{Where: model.Eq("transaction_id", "123")}).Times(1).Return(transactions, 1, nil)
 				tStruct.wg.Add(1)
 				x := testClient(&tStruct.hit)
 				x(tStruct)
@@ -468,7 +1302,11 @@ func TestTransactionManagementServiceLogic_DownloadTransactions(t *testing.T) {
 					"TransferFromAccountNumber": transactions[0].AccountNumber,
 					"TransferToAccountNumber":   transactions[0].TransferTo,
 					"TransactionId":             transactions[0].TransactionId,
-					"Amount":                    transactions[0].Amount,
+					"Amount":                    transactions[0].Amount.String(),
+					"Currency":                  transactions[0].Currency,
+					"FxRate":                    transactions[0].FxRate.String(),
+					"CounterAmount":             transactions[0].CounterAmount.String(),
+					"CounterCurrency":           transactions[0].CounterCurrency,
 					"Date":                      transactions[0].CreatedAt,
 					"Status":                    transactions[0].Status,
 					"Type":                      transactions[0].Type,
@@ -493,11 +1331,12 @@ func TestTransactionManagementServiceLogic_DownloadTransactions(t *testing.T) {
 		{
 			name:          "Failure :: DownloadPdf :: error from db",
 			transactionId: "123",
+			userId:        "123",
 			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
 				mockDs := mock.NewMockDataSourceI(mockCtrl)
 				var trans []model.Transaction
 				trans = append(trans, model.Transaction{UserId: "123", AccountNumber: 1})
-				mockDs.EXPECT().Get(map[string]interface{}{"transaction_id": "123"}, 0, 0).Times(1).Return(trans, 1, errors.New("error db"))
+				mockDs.EXPECT().Get(model.Query{Where: model.Eq("transaction_id", "123")}).Times(1).Return(trans, 1, errors.New("error db"))
 				mockPdf := pdfMock.NewMockHtmlToPdfSvcI(mockCtrl)
 				return mockDs, config.ExternalSvc{UserSvc: "", PdfSvc: config.PdfSvc{UuId: "11-22-33-44", PdfService: mockPdf}}
 			},
@@ -515,17 +1354,40 @@ func TestTransactionManagementServiceLogic_DownloadTransactions(t *testing.T) {
 		{
 			name:          "Failure :: DownloadPdf :: no transaction found in db",
 			transactionId: "123",
+			userId:        "123",
 			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
 				mockDs := mock.NewMockDataSourceI(mockCtrl)
 				var trans []model.Transaction
-				mockDs.EXPECT().Get(map[string]interface{}{"transaction_id": "123"}, 0, 0).Times(1).Return(trans, 1, nil)
+				mockDs.EXPECT().Get(model.Query{Where: model.Eq("transaction_id", "123")}).Times(1).Return(trans, 1, nil)
 				mockPdf := pdfMock.NewMockHtmlToPdfSvcI(mockCtrl)
 				return mockDs, config.ExternalSvc{UserSvc: "", PdfSvc: config.PdfSvc{UuId: "11-22-33-44", PdfService: mockPdf}}
 			},
 			want: func(resp *respModel.Response) {
 				temp := respModel.Response{
-					Status:  http.StatusBadRequest,
-					Message: codes.GetErr(codes.ErrGetTransaction),
+					Status:  http.StatusNotFound,
+					Message: codes.GetErr(codes.ErrNoTransaction),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", temp, &resp)
+				}
+			},
+		},
+		{
+			name:          "Failure :: DownloadPdf :: reviewer downloading another user's receipt",
+			transactionId: "123",
+			userId:        "risk-1",
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				trans := []model.Transaction{{TransactionId: "123", UserId: "999", AccountNumber: 1}}
+				mockDs.EXPECT().Get(model.Query{Where: model.Eq("transaction_id", "123")}).Times(1).Return(trans, 1, nil)
+				mockPdf := pdfMock.NewMockHtmlToPdfSvcI(mockCtrl)
+				return mockDs, config.ExternalSvc{Risk: config.RiskCfg{Reviewers: []string{"risk-1"}}, PdfSvc: config.PdfSvc{UuId: "11-22-33-44", PdfService: mockPdf}}
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusNotFound,
+					Message: codes.GetErr(codes.ErrNoTransaction),
 					Data:    nil,
 				}
 				if !reflect.DeepEqual(resp, &temp) {
@@ -536,11 +1398,12 @@ func TestTransactionManagementServiceLogic_DownloadTransactions(t *testing.T) {
 		{
 			name:          "Failure :: DownloadPdf :: error making request to user svc",
 			transactionId: "123",
+			userId:        "123",
 			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
 				mockDs := mock.NewMockDataSourceI(mockCtrl)
 				var trans []model.Transaction
 				trans = append(trans, model.Transaction{UserId: "123", AccountNumber: 1})
-				mockDs.EXPECT().Get(map[string]interface{}{"transaction_id": "123"}, 0, 0).Times(1).Return(trans, 1, nil)
+				mockDs.EXPECT().Get(model.Query{Where: model.Eq("transaction_id", "123")}).Times(1).Return(trans, 1, nil)
 				mockPdf := pdfMock.NewMockHtmlToPdfSvcI(mockCtrl)
 				return mockDs, config.ExternalSvc{UserSvc: "", PdfSvc: config.PdfSvc{UuId: "11-22-33-44", PdfService: mockPdf}}
 			},
@@ -558,11 +1421,12 @@ func TestTransactionManagementServiceLogic_DownloadTransactions(t *testing.T) {
 		{
 			name:          "Failure :: DownloadPdf ::not ok status code",
 			transactionId: "123",
+			userId:        "123",
 			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
 				mockDs := mock.NewMockDataSourceI(mockCtrl)
 				var trans []model.Transaction
 				trans = append(trans, model.Transaction{UserId: "123", AccountNumber: 1})
-				mockDs.EXPECT().Get(map[string]interface{}{"transaction_id": "123"}, 0, 0).Times(1).Return(trans, 1, nil)
+				mockDs.EXPECT().Get(model.Query{Where: model.Eq("transaction_id", "123")}).Times(1).Return(trans, 1, nil)
 				tStruct.wg.Add(1)
 				x := testClient(&tStruct.hit)
 				x(tStruct)
@@ -583,11 +1447,12 @@ func TestTransactionManagementServiceLogic_DownloadTransactions(t *testing.T) {
 		{
 			name:          "Failure :: DownloadPdf :: error unmarshall response",
 			transactionId: "123",
+			userId:        "123",
 			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
 				mockDs := mock.NewMockDataSourceI(mockCtrl)
 				var trans []model.Transaction
 				trans = append(trans, model.Transaction{UserId: "123", AccountNumber: 1})
-				mockDs.EXPECT().Get(map[string]interface{}{"transaction_id": "123"}, 0, 0).Times(1).Return(trans, 1, nil)
+				mockDs.EXPECT().Get(model.Query{Where: model.Eq("transaction_id", "123")}).Times(1).Return(trans, 1, nil)
 				tStruct.wg.Add(1)
 				x := testClient(&tStruct.hit)
 				x(tStruct)
@@ -608,11 +1473,12 @@ func TestTransactionManagementServiceLogic_DownloadTransactions(t *testing.T) {
 		{
 			name:          "Failure :: DownloadPdf :: error assert response data",
 			transactionId: "123",
+			userId:        "123",
 			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
 				mockDs := mock.NewMockDataSourceI(mockCtrl)
 				var trans []model.Transaction
 				trans = append(trans, model.Transaction{UserId: "123", AccountNumber: 1})
-				mockDs.EXPECT().Get(map[string]interface{}{"transaction_id": "123"}, 0, 0).Times(1).Return(trans, 1, nil)
+				mockDs.EXPECT().Get(model.Query{Where: model.Eq("transaction_id", "123")}).Times(1).Return(trans, 1, nil)
 				tStruct.wg.Add(1)
 				x := testClient(&tStruct.hit)
 				x(tStruct)
@@ -633,11 +1499,12 @@ func TestTransactionManagementServiceLogic_DownloadTransactions(t *testing.T) {
 		{
 			name:          "Failure :: DownloadPdf :: error generate pdf",
 			transactionId: "123",
+			userId:        "123",
 			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
 				mockDs := mock.NewMockDataSourceI(mockCtrl)
 				var trans []model.Transaction
 				trans = append(trans, model.Transaction{UserId: "123", AccountNumber: 1})
-				mockDs.EXPECT().Get(map[string]interface{}{"transaction_id": "123"}, 0, 0).Times(1).Return(trans, 1, nil)
+				mockDs.EXPECT().Get(model.Query{Where: model.Eq("transaction_id", "123")}).Times(1).Return(trans, 1, nil)
 				tStruct.wg.Add(1)
 				x := testClient(&tStruct.hit)
 				x(tStruct)
@@ -649,7 +1516,11 @@ func TestTransactionManagementServiceLogic_DownloadTransactions(t *testing.T) {
 					"TransferFromAccountNumber": transactions[0].AccountNumber,
 					"TransferToAccountNumber":   transactions[0].TransferTo,
 					"TransactionId":             transactions[0].TransactionId,
-					"Amount":                    transactions[0].Amount,
+					"Amount":                    transactions[0].Amount.String(),
+					"Currency":                  transactions[0].Currency,
+					"FxRate":                    transactions[0].FxRate.String(),
+					"CounterAmount":             transactions[0].CounterAmount.String(),
+					"CounterCurrency":           transactions[0].CounterCurrency,
 					"Date":                      transactions[0].CreatedAt,
 					"Status":                    transactions[0].Status,
 					"Type":                      transactions[0].Type,
@@ -668,12 +1539,121 @@ func TestTransactionManagementServiceLogic_DownloadTransactions(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:          "Failure :: DownloadPdf :: transaction belongs to another user",
+			transactionId: "123",
+			userId:        "123",
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				trans := []model.Transaction{{UserId: "999", AccountNumber: 7}}
+				mockDs.EXPECT().Get(model.Query{Where: model.Eq("transaction_id", "123")}).Times(1).Return(trans, 1, nil)
+				mockPdf := pdfMock.NewMockHtmlToPdfSvcI(mockCtrl)
+				return mockDs, config.ExternalSvc{UserSvc: "", PdfSvc: config.PdfSvc{UuId: "11-22-33-44", PdfService: mockPdf}}
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusNotFound,
+					Message: codes.GetErr(codes.ErrNoTransaction),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", temp, resp)
+				}
+			},
+		},
+		{
+			name:          "Failure :: DownloadPdf :: transfer to an account the user does not own",
+			transactionId: "123",
+			userId:        "123",
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				trans := []model.Transaction{{UserId: "999", AccountNumber: 7, TransferTo: 55}}
+				mockDs.EXPECT().Get(model.Query{Where: model.Eq("transaction_id", "123")}).Times(1).Return(trans, 1, nil)
+				mockDs.EXPECT().GetAccount(55).Times(1).Return(&model.Account{AccountNumber: 55, UserId: "999", Currency: "USD"}, nil)
+				mockPdf := pdfMock.NewMockHtmlToPdfSvcI(mockCtrl)
+				return mockDs, config.ExternalSvc{UserSvc: "", PdfSvc: config.PdfSvc{UuId: "11-22-33-44", PdfService: mockPdf}}
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusNotFound,
+					Message: codes.GetErr(codes.ErrNoTransaction),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", temp, resp)
+				}
+			},
+		},
+		{
+			name:          "Failure :: DownloadPdf :: error checking account ownership",
+			transactionId: "123",
+			userId:        "123",
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				trans := []model.Transaction{{UserId: "999", AccountNumber: 7, TransferTo: 55}}
+				mockDs.EXPECT().Get(model.Query{Where: model.Eq("transaction_id", "123")}).Times(1).Return(trans, 1, nil)
+				mockDs.EXPECT().GetAccount(55).Times(1).Return(nil, errors.New("error db"))
+				mockPdf := pdfMock.NewMockHtmlToPdfSvcI(mockCtrl)
+				return mockDs, config.ExternalSvc{UserSvc: "", PdfSvc: config.PdfSvc{UuId: "11-22-33-44", PdfService: mockPdf}}
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusInternalServerError,
+					Message: codes.GetErr(codes.ErrGetTransaction),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", temp, resp)
+				}
+			},
+		},
+		{
+			name:          "Success :: DownloadPdf :: recipient of a transfer",
+			transactionId: "123",
+			userId:        "123",
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				transactions := []model.Transaction{{UserId: "999", AccountNumber: 7, TransferTo: 55}}
+				mockDs.EXPECT().Get(model.Query{Where: model.Eq("transaction_id", "123")}).Times(1).Return(transactions, 1, nil)
+				mockDs.EXPECT().GetAccount(55).Times(1).Return(&model.Account{AccountNumber: 55, UserId: "123", Currency: "USD"}, nil)
+				tStruct.wg.Add(1)
+				x := testClient(&tStruct.hit)
+				x(tStruct)
+				mockPdf := pdfMock.NewMockHtmlToPdfSvcI(mockCtrl)
+				mockPdf.EXPECT().GeneratePdf(map[string]interface{}{
+					"Name":                      "abc",
+					"TransferFromAccountNumber": 7,
+					"TransferToAccountNumber":   55,
+					"TransactionId":             transactions[0].TransactionId,
+					"Amount":                    transactions[0].Amount.String(),
+					"Currency":                  transactions[0].Currency,
+					"FxRate":                    transactions[0].FxRate.String(),
+					"CounterAmount":             transactions[0].CounterAmount.String(),
+					"CounterCurrency":           transactions[0].CounterCurrency,
+					"Date":                      transactions[0].CreatedAt,
+					"Status":                    transactions[0].Status,
+					"Type":                      transactions[0].Type,
+					"Comment":                   transactions[0].Comment,
+				}, "11-22-33-44").Return([]byte("PDF"), nil)
+				return mockDs, config.ExternalSvc{UserSvc: tStruct.srv.URL, PdfSvc: config.PdfSvc{UuId: "11-22-33-44", PdfService: mockPdf}}
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusOK,
+					Message: "SUCCESS",
+					Data:    []byte("PDF"),
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			rec := NewTransactionManagementServiceLogic(tt.setup())
 
-			got := rec.DownloadTransaction(tt.transactionId, "123")
+			got := rec.DownloadTransaction(tt.transactionId, tt.userId, "123")
 
 			tt.want(got)
 		})
@@ -0,0 +1,165 @@
+package logic
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"strconv"
+	"strings"
+)
+
+// Limits of MT940 messages
+const (
+	mt940MessageLength   = 2000 // Characters of the text of a message, longer statements are split into pages
+	mt940LineLength      = 65   // Characters of a line of a field
+	mt940InfoLines       = 6    // Lines of the information to account owner of an entry
+	mt940ReferenceLength = 16   // Characters of a reference
+)
+
+// mt940Writer writes a SWIFT MT940 customer statement. A statement longer than a message can hold is split into
+// pages numbered in the :28C: field, each closing with an intermediate balance the next one opens with. Text is
+// limited to the SWIFT character set and wrapped to lines of 65 characters.
+type mt940Writer struct {
+	w       *bufio.Writer
+	file    accountFile
+	page    int
+	length  int         // Characters written in the current page
+	entries int         // Entries written in the current page
+	balance model.Money // Balance after the entries written so far
+	day     string      // Date of the balance, that of the last entry written or the start of the statement
+}
+
+// maxMt940Balance is the longest balance an MT940 field may have to hold
+const maxMt940Balance = model.Money(999999999999999999)
+
+// mt940Charset holds the characters of the SWIFT x character set, other characters are written as dots
+const mt940Charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789/-?:().,'+ "
+
+// mt940Text replaces the characters of s outside the SWIFT character set
+func mt940Text(s string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(mt940Charset, r) {
+			return r
+		}
+		return '.'
+	}, s)
+}
+
+// mt940Wrap splits text into lines of at most 65 characters, up to the given number of lines. A line of a field may
+// not start with ':' or '-', which would be read as the start of a field or the end of the message, so such lines
+// are indented by a space.
+func mt940Wrap(text string, lines int) []string {
+	var wrapped []string
+	for text != "" && len(wrapped) < lines {
+		prefix := ""
+		if text[0] == ':' || text[0] == '-' {
+			prefix = " "
+		}
+		n := mt940LineLength - len(prefix)
+		if n > len(text) {
+			n = len(text)
+		}
+		wrapped = append(wrapped, prefix+text[:n])
+		text = text[n:]
+	}
+	return wrapped
+}
+
+// mt940Amount formats the absolute value of an amount with a decimal comma and the decimal places of its currency
+func mt940Amount(amount model.Money, currency string) string {
+	if amount < 0 {
+		amount = -amount
+	}
+	whole, cents := int64(amount/model.MajorUnit), int64(amount%model.MajorUnit)
+	if exponent, _ := model.CurrencyExponent(currency); exponent == 0 {
+		return strconv.FormatInt(whole, 10) + ","
+	}
+	return fmt.Sprintf("%d,%02d", whole, cents)
+}
+
+// mt940Mark returns the debit credit mark of a signed amount
+func mt940Mark(amount model.Money) string {
+	if amount < 0 {
+		return "D"
+	}
+	return "C"
+}
+
+// balanceField returns the field of a balance, tag being 60F, 60M, 62F or 62M
+func (m *mt940Writer) balanceField(tag string, balance model.Money, day string) string {
+	return ":" + tag + ":" + mt940Mark(balance) + day + m.file.Currency + mt940Amount(balance, m.file.Currency) + "\r\n"
+}
+
+// write writes the fields of the current page
+func (m *mt940Writer) write(fields string) error {
+	m.length += len(fields)
+	_, err := m.w.WriteString(fields)
+	return err
+}
+
+// open starts a new page of the statement, opening with the balance after the entries of the previous pages
+func (m *mt940Writer) open(tag string) error {
+	m.page++
+	m.length = 0
+	m.entries = 0
+	reference := fmt.Sprintf("%d-%s", m.file.AccountNumber, m.file.To.UTC().Format("060102"))
+	return m.write(":20:" + mt940Text(truncate(reference, mt940ReferenceLength)) + "\r\n" +
+		":25:" + strconv.Itoa(m.file.AccountNumber) + "\r\n" +
+		":28C:1/" + strconv.Itoa(m.page) + "\r\n" +
+		m.balanceField(tag, m.balance, m.day))
+}
+
+// close ends the current page with a balance
+func (m *mt940Writer) close(tag string, balance model.Money, day string) error {
+	return m.write(m.balanceField(tag, balance, day) + "-\r\n")
+}
+
+func (m *mt940Writer) header(file accountFile) error {
+	m.file = file
+	m.balance = file.Opening
+	m.day = file.From.UTC().Format("060102")
+	return m.open("60F")
+}
+
+func (m *mt940Writer) transaction(transaction model.Transaction) error {
+	amount := signedAmount(transaction)
+	code := "NMSC"
+	if transaction.TransferTo != 0 {
+		code = "NTRF"
+	}
+	day := transaction.CreatedAt.UTC()
+	reference := strings.ReplaceAll(transaction.TransactionId, "-", "")
+	entry := ":61:" + day.Format("060102") + day.Format("0102") + mt940Mark(amount) + mt940Amount(amount, transaction.Currency) +
+		code + "NONREF//" + mt940Text(truncate(reference, mt940ReferenceLength)) + "\r\n"
+	info := transaction.TransactionId
+	if payee := counterparty(transaction); payee != "" {
+		info += " " + payee
+	}
+	if transaction.Comment != "" {
+		info += " " + transaction.Comment
+	}
+	entry += ":86:" + strings.Join(mt940Wrap(mt940Text(info), mt940InfoLines), "\r\n") + "\r\n"
+	// A page holding entries is closed before this one would leave no room for its closing balance, which may be
+	// as long as any balance in the currency.
+	closing := len(m.balanceField("62F", maxMt940Balance, m.day)) + len("-\r\n")
+	if m.entries > 0 && m.length+len(entry)+closing > mt940MessageLength {
+		err := m.close("62M", m.balance, m.day)
+		if err != nil {
+			return err
+		}
+		err = m.open("60M")
+		if err != nil {
+			return err
+		}
+	}
+	m.balance += amount
+	m.day = day.Format("060102")
+	m.entries++
+	return m.write(entry)
+}
+
+// footer closes the statement with the closing balance of the account, which the entries lead to from the opening
+// balance as both only count the settled transactions exported.
+func (m *mt940Writer) footer(file accountFile) error {
+	return m.close("62F", file.Closing, file.To.UTC().Format("060102"))
+}
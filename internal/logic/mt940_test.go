@@ -0,0 +1,203 @@
+package logic
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/PereRohit/util/testutil"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mt940Tag matches the tag starting a field, which is not counted in the length of its first line
+var mt940Tag = regexp.MustCompile(`^:[0-9]{2}[A-Z]?:`)
+
+// validateMt940 checks the messages of a statement against the limits of MT940, and that every page but the first
+// opens with the balance the previous one closed with
+func validateMt940(data string) []string {
+	var problems []string
+	if !strings.HasSuffix(data, "-\r\n") {
+		return []string{"statement does not end with -"}
+	}
+	messages := strings.Split(strings.TrimSuffix(data, "-\r\n"), "-\r\n")
+	closing := ""
+	for i, message := range messages {
+		if len(message) > mt940MessageLength {
+			problems = append(problems, fmt.Sprintf("message %d is %d characters long", i+1, len(message)))
+		}
+		lines := strings.Split(strings.TrimSuffix(message, "\r\n"), "\r\n")
+		for _, line := range lines {
+			if len(mt940Tag.ReplaceAllString(line, "")) > mt940LineLength {
+				problems = append(problems, fmt.Sprintf("line %q is longer than %d characters", line, mt940LineLength))
+			}
+			if strings.Trim(line, mt940Charset) != "" {
+				problems = append(problems, fmt.Sprintf("line %q is outside the SWIFT character set", line))
+			}
+		}
+		if lines[3][:5] != ":60F:" && lines[3][:5] != ":60M:" {
+			problems = append(problems, fmt.Sprintf("message %d does not open with a balance", i+1))
+		}
+		if closing != "" && lines[3][5:] != closing {
+			problems = append(problems, fmt.Sprintf("message %d opens with %s, not %s", i+1, lines[3][5:], closing))
+		}
+		closing = lines[len(lines)-1][5:]
+	}
+	return problems
+}
+
+func TestMt940Text(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "Invoice 42/2023 (paid)", want: "Invoice 42/2023 (paid)"},
+		{text: "rent & bills_€", want: "rent . bills.."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			diff := testutil.Diff(mt940Text(tt.text), tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestMt940Wrap(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		lines int
+		want  []string
+	}{
+		{name: "short", text: "salary", lines: 6, want: []string{"salary"}},
+		{name: "wrapped", text: strings.Repeat("x", 70), lines: 6, want: []string{strings.Repeat("x", 65), "xxxxx"}},
+		{name: "truncated", text: strings.Repeat("x", 200), lines: 2, want: []string{strings.Repeat("x", 65), strings.Repeat("x", 65)}},
+		{name: "field start", text: strings.Repeat("x", 65) + ":61:" + strings.Repeat("y", 70), lines: 6, want: []string{strings.Repeat("x", 65), " :61:" + strings.Repeat("y", 60), strings.Repeat("y", 10)}},
+		{name: "message end", text: strings.Repeat("x", 65) + "-", lines: 6, want: []string{strings.Repeat("x", 65), " -"}},
+		{name: "empty", text: "", lines: 6, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := testutil.Diff(mt940Wrap(tt.text, tt.lines), tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestMt940Amount(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   model.Money
+		currency string
+		want     string
+	}{
+		{name: "cents", amount: 5005, currency: "EUR", want: "50,05"},
+		{name: "debit", amount: -1250, currency: "EUR", want: "12,50"},
+		{name: "zero", amount: 0, currency: "EUR", want: "0,00"},
+		{name: "no decimal places", amount: 150000, currency: "JPY", want: "1500,"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := testutil.Diff(mt940Amount(tt.amount, tt.currency), tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestMt940Writer(t *testing.T) {
+	file := accountFile{
+		AccountNumber: 1,
+		Currency:      "EUR",
+		From:          time.Date(2023, time.June, 1, 0, 0, 0, 0, time.UTC),
+		To:            time.Date(2023, time.June, 30, 23, 59, 59, 0, time.UTC),
+		Opening:       10000,
+		GeneratedAt:   time.Date(2023, time.July, 1, 8, 0, 0, 0, time.UTC),
+	}
+	createdAt := time.Date(2023, time.June, 15, 10, 30, 0, 0, time.UTC)
+	var paged []model.Transaction
+	for i := 0; i < 12; i++ {
+		transaction := model.Transaction{
+			TransactionId: fmt.Sprintf("%02d8fad5b-d9cb-469f-a165-70867728950e", i),
+			Type:          "credit",
+			Amount:        model.Money(1000 * (i + 1)),
+			Currency:      "EUR",
+			CreatedAt:     createdAt.Add(time.Duration(i) * 24 * time.Hour),
+			Comment:       strings.Repeat(fmt.Sprintf("payment %d ", i), 30),
+		}
+		if i%3 == 0 {
+			transaction.Type = "debit"
+			transaction.TransferTo = 2
+		}
+		paged = append(paged, transaction)
+	}
+	tests := []struct {
+		name         string
+		golden       string
+		transactions []model.Transaction
+		pages        int
+	}{
+		{
+			name:   "entries",
+			golden: "mt940.sta",
+			transactions: []model.Transaction{
+				{TransactionId: "0f8fad5b-d9cb-469f-a165-70867728950e", Type: "credit", Amount: 5000, Currency: "EUR", CreatedAt: createdAt, TransferTo: 2, Comment: "invoice <42> & co"},
+				{TransactionId: "b", Type: "debit", Amount: 16250, Currency: "EUR", CreatedAt: createdAt.Add(time.Hour), TransferTo: 3, Comment: strings.Repeat("x", 400)},
+				{TransactionId: "c", Type: "debit", Amount: 1, Currency: "EUR", CreatedAt: createdAt.Add(2 * time.Hour), Comment: "-:62F:C230630EUR1000,00"},
+			},
+			pages: 1,
+		},
+		{
+			name:         "pages",
+			golden:       "mt940-pages.sta",
+			transactions: paged,
+			pages:        3,
+		},
+		{
+			name:   "no entries",
+			golden: "mt940-empty.sta",
+			pages:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := file
+			file.Closing = file.Opening
+			for _, transaction := range tt.transactions {
+				file.Closing += signedAmount(transaction)
+			}
+			data := writeAccountFile(t, func(w *bufio.Writer) accountFileWriter { return &mt940Writer{w: w} }, file, tt.transactions)
+			golden := filepath.Join("testdata", tt.golden)
+			if *updateGolden {
+				err := os.WriteFile(golden, data, 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			diff := testutil.Diff(string(data), string(want))
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+			problems := validateMt940(string(data))
+			if len(problems) != 0 {
+				t.Errorf("Want: %v, Got: %v", "a statement within the limits of MT940", problems)
+			}
+			pages := strings.Count(string(data), ":28C:")
+			if pages != tt.pages {
+				t.Errorf("Want: %v, Got: %v", tt.pages, pages)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package logic
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/vatsal278/TransactionManagementService/internal/config"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Lengths OFX limits the text fields of a transaction to
+const (
+	ofxNameLength = 32
+	ofxMemoLength = 255
+)
+
+// ofxTransaction is a single STMTTRN aggregate of an OFX bank statement
+type ofxTransaction struct {
+	XMLName  xml.Name `xml:"STMTTRN"`
+	TrnType  string   `xml:"TRNTYPE"`
+	DtPosted string   `xml:"DTPOSTED"`
+	TrnAmt   string   `xml:"TRNAMT"`
+	FitId    string   `xml:"FITID"`
+	Name     string   `xml:"NAME,omitempty"`
+	Memo     string   `xml:"MEMO,omitempty"`
+}
+
+// ofxWriter writes an OFX 2.2 bank statement. The QFX variant read by Quicken adds the id Intuit assigned to the bank
+// to the sign on response.
+type ofxWriter struct {
+	w   *bufio.Writer
+	cfg config.OfxCfg
+	qfx bool
+}
+
+// newOfxWriter returns an ofxWriter writing to w
+func newOfxWriter(w *bufio.Writer, cfg config.OfxCfg, qfx bool) *ofxWriter {
+	return &ofxWriter{w: w, cfg: cfg, qfx: qfx}
+}
+
+// ofxTime formats t as an OFX datetime in UTC
+func ofxTime(t time.Time) string {
+	return t.UTC().Format("20060102150405.000") + "[0:GMT]"
+}
+
+// readOfxTime reads an OFX datetime, a date optionally followed by the time, milliseconds and the offset of its zone
+// from GMT in brackets, such as 20230615103000.000[-5:EST]. Datetimes without a zone are in GMT.
+func readOfxTime(datetime string) (time.Time, error) {
+	s := datetime
+	location := time.UTC
+	if i := strings.IndexByte(s, '['); i >= 0 {
+		zone := s[i+1:]
+		s = s[:i]
+		if !strings.HasSuffix(zone, "]") {
+			return time.Time{}, fmt.Errorf("invalid OFX datetime %q", datetime)
+		}
+		offset, name, _ := strings.Cut(strings.TrimSuffix(zone, "]"), ":")
+		hours, err := strconv.ParseFloat(offset, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid OFX datetime %q", datetime)
+		}
+		location = time.FixedZone(name, int(hours*3600))
+	}
+	layout := "20060102150405"
+	switch {
+	case len(s) == 8 || len(s) == 12:
+		layout = layout[:len(s)]
+	case len(s) > 15 && s[14] == '.':
+		layout += "." + strings.Repeat("0", len(s)-15)
+	}
+	t, err := time.ParseInLocation(layout, s, location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid OFX datetime %q", datetime)
+	}
+	return t.UTC(), nil
+}
+
+// readOfxAmount reads an OFX amount, which may carry a plus sign or use a decimal comma
+func readOfxAmount(s string) (model.Money, error) {
+	amount := strings.Replace(strings.TrimPrefix(s, "+"), ",", ".", 1)
+	if strings.HasPrefix(amount, ".") || strings.HasPrefix(amount, "-.") {
+		amount = strings.Replace(amount, ".", "0.", 1)
+	}
+	money, err := model.ParseMoney(amount)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	return money, nil
+}
+
+// readOfx reads the transactions of an OFX bank or credit card statement, in the SGML syntax of OFX 1, where elements
+// are not closed, as well as in the XML syntax of OFX 2. Each is read as a settled transaction on the account of the
+// import, its sign giving its type and its memo, or its payee when it has none, its comment. Rows are numbered by
+// the position of the transaction in the statement.
+func readOfx(data string, imp model.ImportTransactions) ([]importRow, error) {
+	start := strings.Index(strings.ToUpper(data), "<OFX>")
+	if start < 0 {
+		return nil, errors.New("the file is not an OFX document")
+	}
+	data = data[start:]
+	var rows []importRow
+	currency := ""
+	statements := 0
+	var fields map[string]string // Elements of the transaction being read, nil between transactions
+	for {
+		open := strings.IndexByte(data, '<')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(data[open:], '>')
+		if end < 0 {
+			return nil, errors.New("the file ends within a tag")
+		}
+		tag := strings.ToUpper(strings.TrimSpace(data[open+1 : open+end]))
+		data = data[open+end+1:]
+		next := strings.IndexByte(data, '<')
+		if next < 0 {
+			next = len(data)
+		}
+		value := html.UnescapeString(strings.TrimSpace(data[:next]))
+		switch {
+		case tag == "STMTRS" || tag == "CCSTMTRS":
+			statements++
+			if statements > 1 {
+				return nil, errors.New("files holding more than one statement are not supported")
+			}
+		case tag == "CURDEF":
+			currency = strings.ToUpper(value)
+		case tag == "STMTTRN":
+			fields = map[string]string{}
+		case tag == "/STMTTRN" && fields != nil:
+			rows = append(rows, ofxImportRow(fields, len(rows)+1, currency, imp))
+			fields = nil
+		case fields != nil && !strings.HasPrefix(tag, "/"):
+			fields[tag] = value
+		}
+	}
+	if fields != nil {
+		return nil, errors.New("the file ends within a transaction")
+	}
+	return rows, nil
+}
+
+// ofxImportRow reads the transaction an STMTTRN aggregate stands for
+func ofxImportRow(fields map[string]string, position int, currency string, imp model.ImportTransactions) importRow {
+	row := importRow{row: position, transaction: model.Transaction{
+		UserId:        imp.UserId,
+		AccountNumber: imp.AccountNumber,
+		Status:        model.StatusApproved,
+		Currency:      currency,
+		Comment:       fields["MEMO"],
+	}}
+	if row.transaction.Comment == "" {
+		row.transaction.Comment = fields["NAME"]
+	}
+	var err error
+	row.transaction.CreatedAt, err = readOfxTime(fields["DTPOSTED"])
+	if err != nil {
+		row.err = "DTPOSTED: " + err.Error()
+		return row
+	}
+	amount, err := readOfxAmount(fields["TRNAMT"])
+	if err != nil {
+		row.err = "TRNAMT: " + err.Error()
+		return row
+	}
+	_ = signAmount(&row.transaction, amount)
+	return row
+}
+
+// xmlText escapes s for use as the text of an XML element
+func xmlText(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func (o *ofxWriter) header(file accountFile) error {
+	var intuBid string
+	if o.qfx {
+		intuBid = "<INTU.BID>" + xmlText(o.cfg.IntuBid) + "</INTU.BID>"
+	}
+	var fid string
+	if o.cfg.Fid != "" {
+		fid = "<FID>" + xmlText(o.cfg.Fid) + "</FID>"
+	}
+	_, err := fmt.Fprintf(o.w, `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS><DTSERVER>%s</DTSERVER><LANGUAGE>ENG</LANGUAGE><FI><ORG>%s</ORG>%s</FI>%s</SONRS></SIGNONMSGSRSV1>
+<BANKMSGSRSV1><STMTTRNRS><TRNUID>0</TRNUID><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>
+<STMTRS><CURDEF>%s</CURDEF><BANKACCTFROM><BANKID>%s</BANKID><ACCTID>%d</ACCTID><ACCTTYPE>CHECKING</ACCTTYPE></BANKACCTFROM>
+<BANKTRANLIST><DTSTART>%s</DTSTART><DTEND>%s</DTEND>
+`, ofxTime(file.GeneratedAt), xmlText(o.cfg.Org), fid, intuBid, file.Currency, xmlText(o.cfg.BankId), file.AccountNumber, ofxTime(file.From), ofxTime(file.To))
+	return err
+}
+
+func (o *ofxWriter) transaction(transaction model.Transaction) error {
+	// Marshalled rather than encoded to w, as an xml.Encoder flushes the bufio.Writer it is given.
+	data, err := xml.Marshal(ofxTransaction{
+		TrnType:  strings.ToUpper(transaction.Type),
+		DtPosted: ofxTime(transaction.CreatedAt),
+		TrnAmt:   signedAmount(transaction).String(),
+		FitId:    transaction.TransactionId,
+		Name:     truncate(counterparty(transaction), ofxNameLength),
+		Memo:     truncate(transaction.Comment, ofxMemoLength),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = o.w.Write(append(data, '\n'))
+	return err
+}
+
+func (o *ofxWriter) footer(file accountFile) error {
+	_, err := o.w.WriteString("</BANKTRANLIST>\n<LEDGERBAL><BALAMT>" + file.Closing.String() + "</BALAMT><DTASOF>" + ofxTime(file.To) +
+		"</DTASOF></LEDGERBAL>\n</STMTRS></STMTTRNRS></BANKMSGSRSV1>\n</OFX>\n")
+	return err
+}
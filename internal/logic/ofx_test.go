@@ -0,0 +1,270 @@
+package logic
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"github.com/PereRohit/util/testutil"
+	"github.com/vatsal278/TransactionManagementService/internal/config"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ofxDocument reads back the parts of an OFX bank statement written by ofxWriter
+type ofxDocument struct {
+	XMLName   xml.Name `xml:"OFX"`
+	Org       string   `xml:"SIGNONMSGSRSV1>SONRS>FI>ORG"`
+	Fid       string   `xml:"SIGNONMSGSRSV1>SONRS>FI>FID"`
+	IntuBid   string   `xml:"SIGNONMSGSRSV1>SONRS>INTU.BID"`
+	Statement struct {
+		Currency     string           `xml:"CURDEF"`
+		BankId       string           `xml:"BANKACCTFROM>BANKID"`
+		AccountId    string           `xml:"BANKACCTFROM>ACCTID"`
+		Start        string           `xml:"BANKTRANLIST>DTSTART"`
+		End          string           `xml:"BANKTRANLIST>DTEND"`
+		Transactions []ofxTransaction `xml:"BANKTRANLIST>STMTTRN"`
+		Balance      string           `xml:"LEDGERBAL>BALAMT"`
+		BalanceAsOf  string           `xml:"LEDGERBAL>DTASOF"`
+	} `xml:"BANKMSGSRSV1>STMTTRNRS>STMTRS"`
+}
+
+// parseOfxTime reads back a datetime written by ofxTime
+func parseOfxTime(t *testing.T, s string) time.Time {
+	parsed, err := time.Parse("20060102150405.000", strings.TrimSuffix(s, "[0:GMT]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+// parseOfx reads an OFX bank statement back into the transactions it lists
+func parseOfx(t *testing.T, data []byte) (ofxDocument, []model.Transaction) {
+	var doc ofxDocument
+	err := xml.Unmarshal(data, &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transactions := make([]model.Transaction, 0, len(doc.Statement.Transactions))
+	for _, trn := range doc.Statement.Transactions {
+		amount, err := model.ParseMoney(trn.TrnAmt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if amount < 0 {
+			amount = -amount
+		}
+		transferTo := 0
+		if trn.Name != "" {
+			transferTo, err = strconv.Atoi(strings.TrimPrefix(trn.Name, "Account "))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		transactions = append(transactions, model.Transaction{
+			TransactionId: trn.FitId,
+			Type:          strings.ToLower(trn.TrnType),
+			Amount:        amount,
+			CreatedAt:     parseOfxTime(t, trn.DtPosted),
+			TransferTo:    transferTo,
+			Comment:       trn.Memo,
+		})
+	}
+	return doc, transactions
+}
+
+// writeAccountFile writes the transactions as one file with the given writer
+func writeAccountFile(t *testing.T, newWriter func(w *bufio.Writer) accountFileWriter, file accountFile, transactions []model.Transaction) []byte {
+	var buf bytes.Buffer
+	bw := bufio.NewWriter(&buf)
+	writer := newWriter(bw)
+	err := writer.header(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, transaction := range transactions {
+		err = writer.transaction(transaction)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = writer.footer(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = bw.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestOfxWriter(t *testing.T) {
+	file := accountFile{
+		AccountNumber: 1,
+		Currency:      "USD",
+		From:          time.Date(2023, time.June, 1, 0, 0, 0, 0, time.UTC),
+		To:            time.Date(2023, time.June, 30, 23, 59, 59, 0, time.UTC),
+		Closing:       -1250,
+		GeneratedAt:   time.Date(2023, time.July, 1, 8, 0, 0, 0, time.UTC),
+	}
+	createdAt := time.Date(2023, time.June, 15, 10, 30, 0, 0, time.UTC)
+	transactions := []model.Transaction{
+		{TransactionId: "a", Type: "credit", Amount: 5000, CreatedAt: createdAt, Comment: "salary"},
+		{TransactionId: "b", Type: "debit", Amount: 6250, CreatedAt: createdAt.Add(time.Hour), TransferTo: 2, Comment: `<rent & "bills">`},
+		{TransactionId: "c", Type: "debit", Amount: 1, CreatedAt: createdAt.Add(2 * time.Hour), Comment: "café " + strings.Repeat("x", 300)},
+	}
+	tests := []struct {
+		name        string
+		cfg         config.OfxCfg
+		qfx         bool
+		wantIntuBid string
+	}{
+		{
+			name: "ofx",
+			cfg:  config.OfxCfg{Org: "Micro & Bank", BankId: "000000000"},
+		},
+		{
+			name:        "qfx",
+			cfg:         config.OfxCfg{Org: "MicroBank", Fid: "1234", BankId: "123456789", IntuBid: "3000"},
+			qfx:         true,
+			wantIntuBid: "3000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := writeAccountFile(t, func(w *bufio.Writer) accountFileWriter { return newOfxWriter(w, tt.cfg, tt.qfx) }, file, transactions)
+			if !bytes.HasPrefix(data, []byte(`<?xml version="1.0" encoding="UTF-8" standalone="no"?>`+"\n"+`<?OFX OFXHEADER="200" VERSION="220"`)) {
+				t.Errorf("Want: %v, Got: %s", "the OFX 2.2 headers", data)
+			}
+			doc, got := parseOfx(t, data)
+			want := append([]model.Transaction{}, transactions...)
+			want[2].Comment = truncate(want[2].Comment, ofxMemoLength)
+			diff := testutil.Diff(got, want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+			diff = testutil.Diff([]string{doc.Org, doc.Fid, doc.IntuBid, doc.Statement.Currency, doc.Statement.BankId, doc.Statement.AccountId, doc.Statement.Balance},
+				[]string{tt.cfg.Org, tt.cfg.Fid, tt.wantIntuBid, "USD", tt.cfg.BankId, "1", "-12.50"})
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+			diff = testutil.Diff([]time.Time{parseOfxTime(t, doc.Statement.Start), parseOfxTime(t, doc.Statement.End), parseOfxTime(t, doc.Statement.BalanceAsOf)}, []time.Time{file.From, file.To, file.To})
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestReadOfxTime(t *testing.T) {
+	tests := []struct {
+		datetime string
+		want     time.Time
+		wantErr  string
+	}{
+		{datetime: "20230615", want: time.Date(2023, time.June, 15, 0, 0, 0, 0, time.UTC)},
+		{datetime: "202306151030", want: time.Date(2023, time.June, 15, 10, 30, 0, 0, time.UTC)},
+		{datetime: "20230615103000", want: time.Date(2023, time.June, 15, 10, 30, 0, 0, time.UTC)},
+		{datetime: "20230615103000.250[0:GMT]", want: time.Date(2023, time.June, 15, 10, 30, 0, 250e6, time.UTC)},
+		{datetime: "20230615103000[-5:EST]", want: time.Date(2023, time.June, 15, 15, 30, 0, 0, time.UTC)},
+		{datetime: "20230615103000[+5.5]", want: time.Date(2023, time.June, 15, 5, 0, 0, 0, time.UTC)},
+		{datetime: "", wantErr: `invalid OFX datetime ""`},
+		{datetime: "2023-06-15", wantErr: `invalid OFX datetime "2023-06-15"`},
+		{datetime: "20230615[-5:EST", wantErr: `invalid OFX datetime "20230615[-5:EST"`},
+		{datetime: "20230615[EST]", wantErr: `invalid OFX datetime "20230615[EST]"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.datetime, func(t *testing.T) {
+			got, err := readOfxTime(tt.datetime)
+			gotErr := ""
+			if err != nil {
+				gotErr = err.Error()
+			}
+			diff := testutil.Diff([]interface{}{got, gotErr}, []interface{}{tt.want, tt.wantErr})
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestReadOfx(t *testing.T) {
+	imp := model.ImportTransactions{UserId: "123", Format: model.ImportOfx, AccountNumber: 5}
+	createdAt := time.Date(2023, time.June, 15, 10, 30, 0, 0, time.UTC)
+	// Statements written by ofxWriter read back as the transactions they list, moved to the account of the import
+	exported := writeAccountFile(t, func(w *bufio.Writer) accountFileWriter {
+		return newOfxWriter(w, config.OfxCfg{Org: "MicroBank", BankId: "000000000"}, false)
+	}, accountFile{AccountNumber: 1, Currency: "EUR", From: createdAt, To: createdAt.Add(time.Hour), GeneratedAt: createdAt}, []model.Transaction{
+		{TransactionId: "a", Type: "credit", Amount: 5000, Currency: "EUR", CreatedAt: createdAt, Comment: "salary"},
+		{TransactionId: "b", Type: "debit", Amount: 1250, Currency: "EUR", CreatedAt: createdAt.Add(time.Hour), TransferTo: 2, Comment: `<rent & "bills">`},
+		{TransactionId: "c", Type: "debit", Amount: 1, Currency: "EUR", CreatedAt: createdAt.Add(2 * time.Hour), TransferTo: 3},
+	})
+	tests := []struct {
+		name    string
+		data    string
+		want    []importRow
+		wantErr string
+	}{
+		{
+			name: "exported statement",
+			data: string(exported),
+			want: []importRow{
+				{row: 1, transaction: model.Transaction{UserId: "123", AccountNumber: 5, Amount: 5000, Status: "approved", Type: "credit", Currency: "EUR", Comment: "salary", CreatedAt: createdAt}},
+				{row: 2, transaction: model.Transaction{UserId: "123", AccountNumber: 5, Amount: 1250, Status: "approved", Type: "debit", Currency: "EUR", Comment: `<rent & "bills">`, CreatedAt: createdAt.Add(time.Hour)}},
+				{row: 3, transaction: model.Transaction{UserId: "123", AccountNumber: 5, Amount: 1, Status: "approved", Type: "debit", Currency: "EUR", Comment: "Account 3", CreatedAt: createdAt.Add(2 * time.Hour)}},
+			},
+		},
+		{
+			name: "credit card statement in SGML",
+			data: "OFXHEADER:100\nDATA:OFXSGML\n\n<OFX><CREDITCARDMSGSRSV1><CCSTMTTRNRS><CCSTMTRS><CURDEF>usd<BANKTRANLIST>\n" +
+				"<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20230615<TRNAMT>-,5<NAME>Coffee</STMTTRN>\n" +
+				"<STMTTRN><TRNTYPE>CREDIT<DTPOSTED>yesterday<TRNAMT>+1.00</STMTTRN>\n" +
+				"<STMTTRN><TRNTYPE>CREDIT<DTPOSTED>20230615<TRNAMT>1.001</STMTTRN>\n" +
+				"</BANKTRANLIST></CCSTMTRS></CCSTMTTRNRS></CREDITCARDMSGSRSV1></OFX>",
+			want: []importRow{
+				{row: 1, transaction: model.Transaction{UserId: "123", AccountNumber: 5, Amount: 50, Status: "approved", Type: "debit", Currency: "USD", Comment: "Coffee", CreatedAt: time.Date(2023, time.June, 15, 0, 0, 0, 0, time.UTC)}},
+				{row: 2, transaction: model.Transaction{UserId: "123", AccountNumber: 5, Status: "approved", Currency: "USD"}, err: `DTPOSTED: invalid OFX datetime "yesterday"`},
+				{row: 3, transaction: model.Transaction{UserId: "123", AccountNumber: 5, Status: "approved", Currency: "USD", CreatedAt: time.Date(2023, time.June, 15, 0, 0, 0, 0, time.UTC)}, err: `TRNAMT: invalid amount "1.001"`},
+			},
+		},
+		{
+			name:    "not an OFX document",
+			data:    "created_at,amount\n",
+			wantErr: "the file is not an OFX document",
+		},
+		{
+			name:    "several statements",
+			data:    "<OFX><STMTRS><CURDEF>USD</STMTRS><STMTRS><CURDEF>EUR</STMTRS></OFX>",
+			wantErr: "files holding more than one statement are not supported",
+		},
+		{
+			name:    "truncated tag",
+			data:    "<OFX><STMTRS><CURDEF",
+			wantErr: "the file ends within a tag",
+		},
+		{
+			name:    "truncated transaction",
+			data:    "<OFX><STMTRS><STMTTRN><TRNAMT>1.00",
+			wantErr: "the file ends within a transaction",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readOfx(tt.data, imp)
+			gotErr := ""
+			if err != nil {
+				gotErr = err.Error()
+			}
+			if gotErr != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, gotErr)
+			}
+			diff := testutil.Diff(got, tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
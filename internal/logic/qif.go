@@ -0,0 +1,40 @@
+package logic
+
+import (
+	"bufio"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"strings"
+)
+
+// qifLineBreaks replaces line breaks in text fields, as every QIF field takes exactly one line
+var qifLineBreaks = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ")
+
+// qifWriter writes a QIF bank account register. QIF carries neither the account nor its balance, so the file only
+// lists the transactions.
+type qifWriter struct {
+	w *bufio.Writer
+}
+
+func (q qifWriter) header(accountFile) error {
+	_, err := q.w.WriteString("!Type:Bank\n")
+	return err
+}
+
+func (q qifWriter) transaction(transaction model.Transaction) error {
+	var b strings.Builder
+	b.WriteString("D" + transaction.CreatedAt.UTC().Format("01/02/2006") + "\n")
+	b.WriteString("T" + signedAmount(transaction).String() + "\n")
+	if payee := counterparty(transaction); payee != "" {
+		b.WriteString("P" + payee + "\n")
+	}
+	if transaction.Comment != "" {
+		b.WriteString("M" + qifLineBreaks.Replace(transaction.Comment) + "\n")
+	}
+	b.WriteString("^\n")
+	_, err := q.w.WriteString(b.String())
+	return err
+}
+
+func (q qifWriter) footer(accountFile) error {
+	return nil
+}
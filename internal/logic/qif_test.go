@@ -0,0 +1,87 @@
+package logic
+
+import (
+	"bufio"
+	"github.com/PereRohit/util/testutil"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// parseQif reads a QIF bank account register back into the transactions it lists
+func parseQif(t *testing.T, data string) []model.Transaction {
+	lines := strings.Split(strings.TrimSuffix(data, "\n"), "\n")
+	if lines[0] != "!Type:Bank" {
+		t.Fatalf("Want: %v, Got: %v", "!Type:Bank", lines[0])
+	}
+	var transactions []model.Transaction
+	var current model.Transaction
+	for _, line := range lines[1:] {
+		if line == "" {
+			t.Fatal("empty line")
+		}
+		value := line[1:]
+		var err error
+		switch line[0] {
+		case 'D':
+			current.CreatedAt, err = time.Parse("01/02/2006", value)
+		case 'T':
+			current.Amount, err = model.ParseMoney(value)
+			current.Type = "credit"
+			if current.Amount < 0 {
+				current.Type = "debit"
+				current.Amount = -current.Amount
+			}
+		case 'P':
+			current.TransferTo, err = strconv.Atoi(strings.TrimPrefix(value, "Account "))
+		case 'M':
+			current.Comment = value
+		case '^':
+			transactions = append(transactions, current)
+			current = model.Transaction{}
+		default:
+			t.Fatalf("unknown field %q", line)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return transactions
+}
+
+func TestQifWriter(t *testing.T) {
+	day := time.Date(2023, time.June, 15, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		transactions []model.Transaction
+		want         []model.Transaction
+	}{
+		{
+			name: "transactions",
+			transactions: []model.Transaction{
+				{TransactionId: "a", Type: "credit", Amount: 5000, CreatedAt: day.Add(10 * time.Hour), Comment: "salary"},
+				{TransactionId: "b", Type: "debit", Amount: 6250, CreatedAt: day.Add(11 * time.Hour), TransferTo: 2, Comment: "rent\r\njune"},
+				{TransactionId: "c", Type: "debit", Amount: 1, CreatedAt: day.Add(12 * time.Hour)},
+			},
+			want: []model.Transaction{
+				{Type: "credit", Amount: 5000, CreatedAt: day, Comment: "salary"},
+				{Type: "debit", Amount: 6250, CreatedAt: day, TransferTo: 2, Comment: "rent june"},
+				{Type: "debit", Amount: 1, CreatedAt: day},
+			},
+		},
+		{
+			name: "no transactions",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := writeAccountFile(t, func(w *bufio.Writer) accountFileWriter { return qifWriter{w: w} }, accountFile{}, tt.transactions)
+			diff := testutil.Diff(parseQif(t, string(data)), tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package logic
+
+import (
+	"fmt"
+	"github.com/PereRohit/util/log"
+	respModel "github.com/PereRohit/util/model"
+	"github.com/vatsal278/TransactionManagementService/internal/codes"
+	"github.com/vatsal278/TransactionManagementService/internal/config"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"github.com/vatsal278/TransactionManagementService/internal/repo/datasource"
+	"net/http"
+	"time"
+)
+
+// reviewQueueSize is the most transactions the review queue lists at once
+const reviewQueueSize = 100
+
+// riskRule evaluates one type of risk rule against a new transaction, reporting whether the rule matched along with
+// a detail explaining the match
+type riskRule func(ds datasource.DataSourceI, rule config.RiskRule, transaction model.Transaction, now time.Time) (bool, string, error)
+
+// riskRules maps each risk rule type to its evaluation, new types of rules are plugged in here
+var riskRules = map[string]riskRule{
+	model.RiskAmount:          amountRule,
+	model.RiskNewCounterparty: newCounterpartyRule,
+	model.RiskUnusualHour:     unusualHourRule,
+	model.RiskRapidRepeat:     rapidRepeatRule,
+}
+
+// amountRule matches transactions of at least the rule's amount, in the rule's currency when it names one
+func amountRule(_ datasource.DataSourceI, rule config.RiskRule, transaction model.Transaction, _ time.Time) (bool, string, error) {
+	if transaction.Amount < rule.MinAmount || (rule.Currency != "" && rule.Currency != transaction.Currency) {
+		return false, "", nil
+	}
+	return true, fmt.Sprintf("amount %s %s is at least %s", transaction.Amount, transaction.Currency, rule.MinAmount), nil
+}
+
+// newCounterpartyRule matches transfers to an account the user has no settled transfer to
+func newCounterpartyRule(ds datasource.DataSourceI, _ config.RiskRule, transaction model.Transaction, _ time.Time) (bool, string, error) {
+	if transaction.TransferTo == 0 {
+		return false, "", nil
+	}
+	statuses := make([]interface{}, 0, len(model.SettledStatuses))
+	for _, status := range model.SettledStatuses {
+		statuses = append(statuses, status)
+	}
+	_, count, err := ds.GetSpending(model.And(model.Eq("user_id", transaction.UserId), model.Eq("type", "debit"), model.Eq("transfer_to", transaction.TransferTo), model.In("status", statuses...), model.Eq("reference_id", "")))
+	if err != nil {
+		return false, "", err
+	}
+	if count > 0 {
+		return false, "", nil
+	}
+	return true, fmt.Sprintf("first transfer to account %d", transaction.TransferTo), nil
+}
+
+// unusualHourRule matches transactions created within the rule's hours in UTC, the range wrapping past midnight when
+// it ends before it starts
+func unusualHourRule(_ datasource.DataSourceI, rule config.RiskRule, _ model.Transaction, now time.Time) (bool, string, error) {
+	now = now.UTC()
+	hour := now.Hour()
+	inRange := hour >= rule.FromHour && hour < rule.ToHour
+	if rule.FromHour > rule.ToHour {
+		inRange = hour >= rule.FromHour || hour < rule.ToHour
+	}
+	if !inRange {
+		return false, "", nil
+	}
+	return true, fmt.Sprintf("created at %02d:%02d UTC", hour, now.Minute()), nil
+}
+
+// rapidRepeatRule matches transactions repeating more than the allowed number of earlier transactions of the user
+// made within the rule's window, with the same account, type, amount, currency and recipient
+func rapidRepeatRule(ds datasource.DataSourceI, rule config.RiskRule, transaction model.Transaction, now time.Time) (bool, string, error) {
+	_, count, err := ds.GetSpending(model.And(
+		model.Eq("user_id", transaction.UserId),
+		model.Eq("account_number", transaction.AccountNumber),
+		model.Eq("type", transaction.Type),
+		model.Eq("amount", transaction.Amount),
+		model.Eq("currency", transaction.Currency),
+		model.Eq("transfer_to", transaction.TransferTo),
+		model.Eq("reference_id", ""),
+		model.Gte("created_at", now.Add(-rule.Window)),
+	))
+	if err != nil {
+		return false, "", err
+	}
+	if count <= rule.MaxCount {
+		return false, "", nil
+	}
+	return true, fmt.Sprintf("%d identical transactions in the last %s", count, rule.Window), nil
+}
+
+// assessRisk scores a new transaction against the risk rules and decides the status it is stored with. It is
+// rejected or held for review once its total score reaches the configured thresholds, and keeps the status requested
+// otherwise. The hits returned record the decision.
+func (l transactionManagementServiceLogic) assessRisk(ds datasource.DataSourceI, transaction model.Transaction) (string, []model.RiskHit, error) {
+	risk := l.UtilSvc.Risk
+	if len(risk.Rules) == 0 {
+		return transaction.Status, nil, nil
+	}
+	now := time.Now()
+	score := 0
+	var hits []model.RiskHit
+	for _, rule := range risk.Rules {
+		evaluate, ok := riskRules[rule.Type]
+		if !ok {
+			continue
+		}
+		hit, detail, err := evaluate(ds, rule, transaction, now)
+		if err != nil {
+			return "", nil, err
+		}
+		if !hit {
+			continue
+		}
+		score += rule.Score
+		hits = append(hits, model.RiskHit{TransactionId: transaction.TransactionId, Rule: rule.Name, Type: rule.Type, Score: rule.Score, Detail: detail})
+	}
+	status := transaction.Status
+	switch {
+	case status == model.StatusRejected:
+	case risk.RejectScore > 0 && score >= risk.RejectScore:
+		status = model.StatusRejected
+	case risk.ReviewScore > 0 && score >= risk.ReviewScore:
+		status = model.StatusReview
+	}
+	for i := range hits {
+		hits[i].Decision = status
+	}
+	return status, hits, nil
+}
+
+// GetReviewQueue lists to a reviewer the oldest transactions awaiting a decision, pending or held by the risk rules,
+// along with the risk rules each hit. A transfer is listed once, by the debit leg it is decided through.
+func (l transactionManagementServiceLogic) GetReviewQueue(userId string) *respModel.Response {
+	if !l.UtilSvc.Risk.IsReviewer(userId) {
+		return &respModel.Response{
+			Status:  http.StatusForbidden,
+			Message: codes.GetErr(codes.ErrNotReviewer),
+			Data:    nil,
+		}
+	}
+	transactions, _, err := l.DsSvc.Get(model.Query{
+		Where:     model.In("status", model.StatusPending, model.StatusReview),
+		OrderBy:   []model.OrderBy{{Column: "created_at"}},
+		Limit:     reviewQueueSize,
+		SkipCount: true,
+	})
+	if err != nil {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrGetTransaction),
+			Data:    nil,
+		}
+	}
+	queue := []model.ReviewItem{}
+	for _, transaction := range transactions {
+		if transaction.TransferId != "" && transaction.Type == "credit" {
+			continue
+		}
+		hits, err := l.DsSvc.GetRiskHits(transaction.TransactionId)
+		if err != nil {
+			log.Error(err)
+			return &respModel.Response{
+				Status:  http.StatusInternalServerError,
+				Message: codes.GetErr(codes.ErrGetTransaction),
+				Data:    nil,
+			}
+		}
+		queue = append(queue, model.ReviewItem{Transaction: transaction, RiskHits: hits})
+	}
+	return &respModel.Response{
+		Status:  http.StatusOK,
+		Message: "SUCCESS",
+		Data:    queue,
+	}
+}
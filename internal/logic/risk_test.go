@@ -0,0 +1,411 @@
+package logic
+
+import (
+	"errors"
+	respModel "github.com/PereRohit/util/model"
+	"github.com/PereRohit/util/testutil"
+	"github.com/golang/mock/gomock"
+	"github.com/vatsal278/TransactionManagementService/internal/codes"
+	"github.com/vatsal278/TransactionManagementService/internal/config"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"github.com/vatsal278/TransactionManagementService/internal/repo/datasource"
+	"github.com/vatsal278/TransactionManagementService/pkg/mock"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRiskRules(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	now := time.Date(2023, time.June, 15, 3, 20, 0, 0, time.UTC)
+	transaction := model.Transaction{TransactionId: "abc", UserId: "123", AccountNumber: 1, Amount: 150000, Currency: "USD", Type: "debit", Status: model.StatusPending}
+	transfer := transaction
+	transfer.TransferTo = 2
+	payees := model.And(model.Eq("user_id", "123"), model.Eq("type", "debit"), model.Eq("transfer_to", 2), model.In("status", model.StatusApproved, model.StatusReversed), model.Eq("reference_id", ""))
+	repeats := model.And(model.Eq("user_id", "123"), model.Eq("account_number", 1), model.Eq("type", "debit"), model.Eq("amount", model.Money(150000)), model.Eq("currency", "USD"), model.Eq("transfer_to", 0), model.Eq("reference_id", ""), model.Gte("created_at", now.Add(-10*time.Minute)))
+	tests := []struct {
+		name        string
+		rule        config.RiskRule
+		transaction model.Transaction
+		setup       func() datasource.DataSourceI
+		wantHit     bool
+		wantDetail  string
+		wantErr     bool
+	}{
+		{
+			name:        "amount at threshold",
+			rule:        config.RiskRule{Type: model.RiskAmount, MinAmount: 150000},
+			transaction: transaction,
+			wantHit:     true,
+			wantDetail:  "amount 1500.00 USD is at least 1500.00",
+		},
+		{
+			name:        "amount below threshold",
+			rule:        config.RiskRule{Type: model.RiskAmount, MinAmount: 150001},
+			transaction: transaction,
+		},
+		{
+			name:        "amount in another currency",
+			rule:        config.RiskRule{Type: model.RiskAmount, MinAmount: 100, Currency: "EUR"},
+			transaction: transaction,
+		},
+		{
+			name:        "first transfer to account",
+			rule:        config.RiskRule{Type: model.RiskNewCounterparty},
+			transaction: transfer,
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetSpending(payees).Times(1).Return(model.Money(0), 0, nil)
+				return mockDs
+			},
+			wantHit:    true,
+			wantDetail: "first transfer to account 2",
+		},
+		{
+			name:        "account paid before",
+			rule:        config.RiskRule{Type: model.RiskNewCounterparty},
+			transaction: transfer,
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetSpending(payees).Times(1).Return(model.Money(1000), 1, nil)
+				return mockDs
+			},
+		},
+		{
+			name:        "not a transfer",
+			rule:        config.RiskRule{Type: model.RiskNewCounterparty},
+			transaction: transaction,
+		},
+		{
+			name:        "counterparty lookup error",
+			rule:        config.RiskRule{Type: model.RiskNewCounterparty},
+			transaction: transfer,
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetSpending(payees).Times(1).Return(model.Money(0), 0, errors.New("error"))
+				return mockDs
+			},
+			wantErr: true,
+		},
+		{
+			name:        "hour in range",
+			rule:        config.RiskRule{Type: model.RiskUnusualHour, FromHour: 1, ToHour: 5},
+			transaction: transaction,
+			wantHit:     true,
+			wantDetail:  "created at 03:20 UTC",
+		},
+		{
+			name:        "hour in range past midnight",
+			rule:        config.RiskRule{Type: model.RiskUnusualHour, FromHour: 22, ToHour: 4},
+			transaction: transaction,
+			wantHit:     true,
+			wantDetail:  "created at 03:20 UTC",
+		},
+		{
+			name:        "hour out of range",
+			rule:        config.RiskRule{Type: model.RiskUnusualHour, FromHour: 22, ToHour: 3},
+			transaction: transaction,
+		},
+		{
+			name:        "repeats over the allowed count",
+			rule:        config.RiskRule{Type: model.RiskRapidRepeat, Window: 10 * time.Minute, MaxCount: 2},
+			transaction: transaction,
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetSpending(repeats).Times(1).Return(model.Money(450000), 3, nil)
+				return mockDs
+			},
+			wantHit:    true,
+			wantDetail: "3 identical transactions in the last 10m0s",
+		},
+		{
+			name:        "repeats allowed",
+			rule:        config.RiskRule{Type: model.RiskRapidRepeat, Window: 10 * time.Minute, MaxCount: 2},
+			transaction: transaction,
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetSpending(repeats).Times(1).Return(model.Money(300000), 2, nil)
+				return mockDs
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ds datasource.DataSourceI = mock.NewMockDataSourceI(mockCtrl)
+			if tt.setup != nil {
+				ds = tt.setup()
+			}
+			hit, detail, err := riskRules[tt.rule.Type](ds, tt.rule, tt.transaction, now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			if hit != tt.wantHit || detail != tt.wantDetail {
+				t.Errorf("Want: %v %q, Got: %v %q", tt.wantHit, tt.wantDetail, hit, detail)
+			}
+		})
+	}
+}
+
+func TestTransactionManagementServiceLogic_AssessRisk(t *testing.T) {
+	large := config.RiskRule{Name: "large amount", Type: model.RiskAmount, Score: 60, MinAmount: 100000}
+	huge := config.RiskRule{Name: "huge amount", Type: model.RiskAmount, Score: 50, MinAmount: 500000}
+	risk := config.RiskCfg{ReviewScore: 50, RejectScore: 100, Rules: []config.RiskRule{large, huge}}
+	tests := []struct {
+		name        string
+		risk        config.RiskCfg
+		transaction model.Transaction
+		wantStatus  string
+		wantHits    []model.RiskHit
+	}{
+		{
+			name:        "no rules",
+			transaction: model.Transaction{TransactionId: "abc", Amount: 1000000, Status: model.StatusApproved},
+			wantStatus:  model.StatusApproved,
+		},
+		{
+			name:        "below review score",
+			risk:        risk,
+			transaction: model.Transaction{TransactionId: "abc", Amount: 1000, Currency: "USD", Status: model.StatusApproved},
+			wantStatus:  model.StatusApproved,
+		},
+		{
+			name:        "held for review",
+			risk:        risk,
+			transaction: model.Transaction{TransactionId: "abc", Amount: 100000, Currency: "USD", Status: model.StatusApproved},
+			wantStatus:  model.StatusReview,
+			wantHits:    []model.RiskHit{{TransactionId: "abc", Rule: "large amount", Type: model.RiskAmount, Score: 60, Detail: "amount 1000.00 USD is at least 1000.00", Decision: model.StatusReview}},
+		},
+		{
+			name:        "rejected",
+			risk:        risk,
+			transaction: model.Transaction{TransactionId: "abc", Amount: 500000, Currency: "USD", Status: model.StatusPending},
+			wantStatus:  model.StatusRejected,
+			wantHits: []model.RiskHit{
+				{TransactionId: "abc", Rule: "large amount", Type: model.RiskAmount, Score: 60, Detail: "amount 5000.00 USD is at least 1000.00", Decision: model.StatusRejected},
+				{TransactionId: "abc", Rule: "huge amount", Type: model.RiskAmount, Score: 50, Detail: "amount 5000.00 USD is at least 5000.00", Decision: model.StatusRejected},
+			},
+		},
+		{
+			name:        "review disabled",
+			risk:        config.RiskCfg{RejectScore: 100, Rules: []config.RiskRule{large}},
+			transaction: model.Transaction{TransactionId: "abc", Amount: 100000, Currency: "USD", Status: model.StatusPending},
+			wantStatus:  model.StatusPending,
+			wantHits:    []model.RiskHit{{TransactionId: "abc", Rule: "large amount", Type: model.RiskAmount, Score: 60, Detail: "amount 1000.00 USD is at least 1000.00", Decision: model.StatusPending}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := transactionManagementServiceLogic{UtilSvc: config.ExternalSvc{Risk: tt.risk}}
+			status, hits, err := l.assessRisk(nil, tt.transaction)
+			if err != nil {
+				t.Fatal(err)
+			}
+			diff := testutil.Diff(status, tt.wantStatus)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+			diff = testutil.Diff(hits, tt.wantHits)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestTransactionManagementServiceLogic_NewTransactionRisk(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	inTransaction := func(mockDs *mock.MockDataSourceI) {
+		mockDs.EXPECT().Transaction(gomock.Any()).Times(1).DoAndReturn(func(fn func(datasource.DataSourceI) error) error {
+			return fn(mockDs)
+		})
+	}
+	risk := config.RiskCfg{
+		ReviewScore: 50,
+		RejectScore: 100,
+		Rules: []config.RiskRule{
+			{Name: "large amount", Type: model.RiskAmount, Score: 60, MinAmount: 100000},
+			{Name: "repeats", Type: model.RiskRapidRepeat, Score: 50, Window: time.Minute},
+		},
+	}
+	// stored checks the transaction and its rule hits are stored with the decided status
+	stored := func(mockDs *mock.MockDataSourceI, status string, hits int) {
+		var transactionId string
+		mockDs.EXPECT().Insert(gomock.Any()).Times(1).DoAndReturn(func(tr model.Transaction) error {
+			transactionId = tr.TransactionId
+			if tr.Status != status {
+				t.Errorf("Want: %v, Got: %v", status, tr.Status)
+			}
+			return nil
+		})
+		mockDs.EXPECT().InsertStatusHistory(gomock.Any()).Times(1).Return(nil)
+		mockDs.EXPECT().InsertWebhookDeliveries(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+		mockDs.EXPECT().InsertRiskHit(gomock.Any()).Times(hits).DoAndReturn(func(hit model.RiskHit) error {
+			if hit.TransactionId != transactionId || hit.Decision != status {
+				t.Errorf("Want: %v %v, Got: %v", transactionId, status, hit)
+			}
+			return nil
+		})
+	}
+	// owned registers the account to the user transacting from it
+	owned := func(mockDs *mock.MockDataSourceI, userId string, accountNumber int) {
+		mockDs.EXPECT().GetAccount(accountNumber).Times(1).Return(&model.Account{AccountNumber: accountNumber, UserId: userId, Currency: "USD"}, nil)
+	}
+	tests := []struct {
+		name        string
+		credentials model.NewTransaction
+		setup       func() datasource.DataSourceI
+		want        respModel.Response
+	}{
+		{
+			name:        "Success::no rule hit",
+			credentials: model.NewTransaction{UserId: "123", AccountNumber: 1, Type: "credit", Status: model.StatusPending, Amount: 1000},
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				owned(mockDs, "123", 1)
+				inTransaction(mockDs)
+				mockDs.EXPECT().GetSpending(gomock.Any()).Times(1).Return(model.Money(0), 0, nil)
+				stored(mockDs, model.StatusPending, 0)
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusCreated, Message: "SUCCESS"},
+		},
+		{
+			name:        "Success::held for review",
+			credentials: model.NewTransaction{UserId: "123", AccountNumber: 1, Type: "credit", Status: model.StatusApproved, Amount: 100000},
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				owned(mockDs, "123", 1)
+				inTransaction(mockDs)
+				mockDs.EXPECT().GetSpending(gomock.Any()).Times(1).Return(model.Money(0), 0, nil)
+				stored(mockDs, model.StatusReview, 1)
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusAccepted, Message: codes.GetErr(codes.ErrRiskReview)},
+		},
+		{
+			name:        "Failure::rejected by the rules",
+			credentials: model.NewTransaction{UserId: "123", AccountNumber: 1, Type: "credit", Status: model.StatusPending, Amount: 100000},
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				owned(mockDs, "123", 1)
+				inTransaction(mockDs)
+				mockDs.EXPECT().GetSpending(gomock.Any()).Times(1).Return(model.Money(100000), 1, nil)
+				stored(mockDs, model.StatusRejected, 2)
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusUnprocessableEntity, Message: codes.GetErr(codes.ErrRiskRejected)},
+		},
+		{
+			name:        "Failure::rule error",
+			credentials: model.NewTransaction{UserId: "123", AccountNumber: 1, Type: "credit", Status: model.StatusPending, Amount: 1000},
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				owned(mockDs, "123", 1)
+				inTransaction(mockDs)
+				mockDs.EXPECT().GetSpending(gomock.Any()).Times(1).Return(model.Money(0), 0, errors.New("error"))
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusInternalServerError, Message: codes.GetErr(codes.ErrNewTransaction)},
+		},
+		{
+			name:        "Failure::rule hit insert error",
+			credentials: model.NewTransaction{UserId: "123", AccountNumber: 1, Type: "credit", Status: model.StatusPending, Amount: 100000},
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				owned(mockDs, "123", 1)
+				inTransaction(mockDs)
+				mockDs.EXPECT().GetSpending(gomock.Any()).Times(1).Return(model.Money(0), 0, nil)
+				mockDs.EXPECT().Insert(gomock.Any()).Times(1).Return(nil)
+				mockDs.EXPECT().InsertStatusHistory(gomock.Any()).Times(1).Return(nil)
+				mockDs.EXPECT().InsertWebhookDeliveries(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				mockDs.EXPECT().InsertRiskHit(gomock.Any()).Times(1).Return(errors.New("error"))
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusInternalServerError, Message: codes.GetErr(codes.ErrNewTransaction)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := NewTransactionManagementServiceLogic(tt.setup(), config.ExternalSvc{Risk: risk})
+			got := rec.NewTransaction(tt.credentials)
+			if !reflect.DeepEqual(got, &tt.want) {
+				t.Errorf("Want: %v, Got: %v", &tt.want, got)
+			}
+		})
+	}
+}
+
+func TestTransactionManagementServiceLogic_GetReviewQueue(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	queued := model.Query{
+		Where:     model.In("status", model.StatusPending, model.StatusReview),
+		OrderBy:   []model.OrderBy{{Column: "created_at"}},
+		Limit:     reviewQueueSize,
+		SkipCount: true,
+	}
+	held := model.Transaction{TransactionId: "abc", UserId: "123", AccountNumber: 1, Amount: 100000, Status: model.StatusReview, Type: "debit"}
+	debit := model.Transaction{TransactionId: "def", UserId: "123", AccountNumber: 1, TransferTo: 2, Amount: 1000, Status: model.StatusPending, Type: "debit", TransferId: "t1"}
+	credit := model.Transaction{TransactionId: "ghi", UserId: "456", AccountNumber: 2, TransferTo: 1, Amount: 1000, Status: model.StatusPending, Type: "credit", TransferId: "t1"}
+	hits := []model.RiskHit{{TransactionId: "abc", Rule: "large amount", Type: model.RiskAmount, Score: 60, Decision: model.StatusReview}}
+	tests := []struct {
+		name   string
+		userId string
+		setup  func() datasource.DataSourceI
+		want   respModel.Response
+	}{
+		{
+			name:   "Success::reviewer sees the queue with the rule hits",
+			userId: "risk-1",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().Get(queued).Times(1).Return([]model.Transaction{held, debit, credit}, 0, nil)
+				mockDs.EXPECT().GetRiskHits("abc").Times(1).Return(hits, nil)
+				mockDs.EXPECT().GetRiskHits("def").Times(1).Return(nil, nil)
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusOK, Message: "SUCCESS", Data: []model.ReviewItem{{Transaction: held, RiskHits: hits}, {Transaction: debit}}},
+		},
+		{
+			name:   "Failure::not a reviewer",
+			userId: "123",
+			setup: func() datasource.DataSourceI {
+				return mock.NewMockDataSourceI(mockCtrl)
+			},
+			want: respModel.Response{Status: http.StatusForbidden, Message: codes.GetErr(codes.ErrNotReviewer)},
+		},
+		{
+			name:   "Failure::queue error",
+			userId: "risk-1",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().Get(queued).Times(1).Return(nil, 0, errors.New("error"))
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusInternalServerError, Message: codes.GetErr(codes.ErrGetTransaction)},
+		},
+		{
+			name:   "Failure::rule hits error",
+			userId: "risk-1",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().Get(queued).Times(1).Return([]model.Transaction{held}, 0, nil)
+				mockDs.EXPECT().GetRiskHits("abc").Times(1).Return(nil, errors.New("error"))
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusInternalServerError, Message: codes.GetErr(codes.ErrGetTransaction)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := NewTransactionManagementServiceLogic(tt.setup(), config.ExternalSvc{Risk: config.RiskCfg{Reviewers: []string{"risk-1"}}})
+			got := rec.GetReviewQueue(tt.userId)
+			if !reflect.DeepEqual(got, &tt.want) {
+				t.Errorf("Want: %v, Got: %v", &tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,502 @@
+package logic
+
+import (
+	"errors"
+	"fmt"
+	"github.com/PereRohit/util/log"
+	respModel "github.com/PereRohit/util/model"
+	"github.com/google/uuid"
+	"github.com/vatsal278/TransactionManagementService/internal/codes"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"github.com/vatsal278/TransactionManagementService/internal/outbox"
+	"github.com/vatsal278/TransactionManagementService/internal/repo/datasource"
+	"net/http"
+	"time"
+)
+
+// scheduleExecutionsShown is the number of latest executions returned along with a schedule
+const scheduleExecutionsShown = 50
+
+var (
+	// errScheduleBusy is returned when a schedule is locked by a run in progress
+	errScheduleBusy = errors.New("schedule is locked")
+	// errScheduleClosed is returned when a schedule was completed or cancelled after it was read
+	errScheduleClosed = errors.New("schedule is closed")
+)
+
+// scheduleRetry is returned to roll back an occurrence that failed on a server error, so it can be retried
+type scheduleRetry struct {
+	message string
+}
+
+func (e scheduleRetry) Error() string {
+	return "scheduled transaction failed: " + e.message
+}
+
+// addMonths moves t by a number of months, keeping its day unless the month is shorter, in which case the month's
+// last day is used
+func addMonths(t time.Time, months int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	last := first.AddDate(0, 1, -1).Day()
+	day := t.Day()
+	if day > last {
+		day = last
+	}
+	return time.Date(first.Year(), first.Month(), day, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// nextOccurrence returns the first occurrence of a schedule strictly after the given time, or false when the
+// schedule has none left
+func nextOccurrence(s model.Schedule, after time.Time) (time.Time, bool) {
+	start := s.StartAt.UTC()
+	next := start
+	switch s.Frequency {
+	case model.FrequencyOnce:
+		if !start.After(after) {
+			return time.Time{}, false
+		}
+	case model.FrequencyDaily, model.FrequencyWeekly:
+		days := 1
+		if s.Frequency == model.FrequencyWeekly {
+			days = 7
+		}
+		if after.After(start) {
+			next = start.AddDate(0, 0, int(after.Sub(start)/(time.Duration(days)*24*time.Hour))*days)
+		}
+		for !next.After(after) {
+			next = next.AddDate(0, 0, days)
+		}
+	case model.FrequencyMonthly:
+		months := 0
+		if after.After(start) {
+			months = (after.Year()-start.Year())*12 + int(after.Month()-start.Month()) - 1
+			if months < 0 {
+				months = 0
+			}
+		}
+		next = addMonths(start, months)
+		for !next.After(after) {
+			months++
+			next = addMonths(start, months)
+		}
+	case model.FrequencyCron:
+		expr, err := parseCron(s.Cron)
+		if err != nil {
+			return time.Time{}, false
+		}
+		// The start time itself is an occurrence when it matches the expression
+		from := after
+		if start.After(after) {
+			from = start.Add(-time.Nanosecond)
+		}
+		var ok bool
+		next, ok = expr.next(from)
+		if !ok {
+			return time.Time{}, false
+		}
+	default:
+		return time.Time{}, false
+	}
+	if s.EndAt != nil && next.After(*s.EndAt) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+// invalidSchedule responds with a bad request explaining why the schedule is invalid
+func invalidSchedule(reason string) *respModel.Response {
+	return &respModel.Response{
+		Status:  http.StatusBadRequest,
+		Message: fmt.Sprintf("%s: %s", codes.GetErr(codes.ErrInvalidSchedule), reason),
+		Data:    nil,
+	}
+}
+
+// scheduleDefinition validates a new schedule and returns it as a schedule due at its first occurrence after now.
+// Times are kept in UTC to the second, as stored.
+func scheduleDefinition(newSchedule model.NewSchedule, now time.Time) (model.Schedule, *respModel.Response) {
+	s := model.Schedule{
+		UserId:            newSchedule.UserId,
+		AccountNumber:     newSchedule.AccountNumber,
+		Amount:            newSchedule.Amount,
+		TransferTo:        newSchedule.TransferTo,
+		Type:              newSchedule.Type,
+		TransactionStatus: model.StatusPending,
+		Comment:           newSchedule.Comment,
+		Currency:          newSchedule.Currency,
+		Frequency:         newSchedule.Frequency,
+		Cron:              newSchedule.Cron,
+		StartAt:           newSchedule.StartAt.UTC().Truncate(time.Second),
+		Status:            model.ScheduleActive,
+	}
+	if newSchedule.Paused {
+		s.Status = model.SchedulePaused
+	}
+	if newSchedule.StartAt.IsZero() {
+		return s, invalidSchedule("start_at is required")
+	}
+	if newSchedule.EndAt != nil {
+		endAt := newSchedule.EndAt.UTC().Truncate(time.Second)
+		if endAt.Before(s.StartAt) {
+			return s, invalidSchedule("end_at is before start_at")
+		}
+		s.EndAt = &endAt
+	}
+	if s.Amount <= 0 {
+		return s, &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidAmount),
+			Data:    nil,
+		}
+	}
+	if s.Currency == "" {
+		s.Currency = model.DefaultCurrency
+	}
+	if _, ok := model.CurrencyExponent(s.Currency); !ok {
+		return s, &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidCurrency),
+			Data:    nil,
+		}
+	}
+	if !s.Amount.FitsCurrency(s.Currency) {
+		return s, &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrCurrencyPrecision),
+			Data:    nil,
+		}
+	}
+	if s.TransferTo != 0 && (s.Type != "debit" || s.TransferTo == s.AccountNumber) {
+		return s, &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidTransfer),
+			Data:    nil,
+		}
+	}
+	if s.Frequency == model.FrequencyCron {
+		_, err := parseCron(s.Cron)
+		if err != nil {
+			return s, invalidSchedule(err.Error())
+		}
+	} else if s.Cron != "" {
+		return s, invalidSchedule("cron is only used by the cron frequency")
+	}
+	next, ok := nextOccurrence(s, now)
+	if !ok {
+		return s, invalidSchedule("schedule has no occurrence after now")
+	}
+	s.NextRunAt, s.DueAt = next, next
+	return s, nil
+}
+
+// getUserSchedule fetches a schedule of the user. A schedule that does not exist and one that belongs to someone
+// else both produce the same not found response.
+func (l transactionManagementServiceLogic) getUserSchedule(id string, userId string) (*model.Schedule, *respModel.Response) {
+	schedule, err := l.DsSvc.GetSchedule(id)
+	if err != nil {
+		log.Error(err)
+		return nil, &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrGetSchedule),
+			Data:    nil,
+		}
+	}
+	if schedule == nil || schedule.UserId != userId {
+		return nil, &respModel.Response{
+			Status:  http.StatusNotFound,
+			Message: codes.GetErr(codes.ErrNoSchedule),
+			Data:    nil,
+		}
+	}
+	return schedule, nil
+}
+
+// CreateSchedule stores a one-off future-dated or recurring transaction of the user, first run at its next
+// occurrence unless it is created paused
+func (l transactionManagementServiceLogic) CreateSchedule(newSchedule model.NewSchedule) *respModel.Response {
+	schedule, errResp := scheduleDefinition(newSchedule, time.Now())
+	if errResp != nil {
+		return errResp
+	}
+	errResp = l.checkAccountOwner(schedule.UserId, schedule.AccountNumber, codes.GetErr(codes.ErrSchedule))
+	if errResp != nil {
+		return errResp
+	}
+	schedule.ScheduleId = uuid.NewString()
+	err := l.DsSvc.InsertSchedule(schedule)
+	if err != nil {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrSchedule),
+			Data:    nil,
+		}
+	}
+	return &respModel.Response{
+		Status:  http.StatusCreated,
+		Message: "SUCCESS",
+		Data:    schedule,
+	}
+}
+
+// GetSchedules lists the schedules of the user, oldest first
+func (l transactionManagementServiceLogic) GetSchedules(userId string) *respModel.Response {
+	schedules, err := l.DsSvc.GetSchedules(userId)
+	if err != nil {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrGetSchedule),
+			Data:    nil,
+		}
+	}
+	return &respModel.Response{
+		Status:  http.StatusOK,
+		Message: "SUCCESS",
+		Data:    schedules,
+	}
+}
+
+// GetSchedule returns a schedule of the user along with its latest executions
+func (l transactionManagementServiceLogic) GetSchedule(id string, userId string) *respModel.Response {
+	schedule, errResp := l.getUserSchedule(id, userId)
+	if errResp != nil {
+		return errResp
+	}
+	executions, err := l.DsSvc.GetScheduleExecutions(id, scheduleExecutionsShown)
+	if err != nil {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrGetSchedule),
+			Data:    nil,
+		}
+	}
+	return &respModel.Response{
+		Status:  http.StatusOK,
+		Message: "SUCCESS",
+		Data:    model.ScheduleDetail{Schedule: *schedule, Executions: executions},
+	}
+}
+
+// closedSchedule reports whether a schedule was completed or cancelled
+func closedSchedule(s model.Schedule) bool {
+	return s.Status == model.ScheduleCompleted || s.Status == model.ScheduleCancelled
+}
+
+// changeSchedule applies a change to an open schedule of the user. The schedule is locked while it changes, and a
+// schedule being run is reported busy rather than waited for.
+func (l transactionManagementServiceLogic) changeSchedule(id string, userId string, change func(*model.Schedule)) *respModel.Response {
+	schedule, errResp := l.getUserSchedule(id, userId)
+	if errResp != nil {
+		return errResp
+	}
+	if closedSchedule(*schedule) {
+		return &respModel.Response{
+			Status:  http.StatusConflict,
+			Message: codes.GetErr(codes.ErrScheduleClosed),
+			Data:    nil,
+		}
+	}
+	err := l.DsSvc.Transaction(func(ds datasource.DataSourceI) error {
+		locked, err := ds.LockSchedule(id)
+		if err != nil {
+			return err
+		}
+		if locked == nil {
+			return errScheduleBusy
+		}
+		if closedSchedule(*locked) {
+			return errScheduleClosed
+		}
+		change(locked)
+		schedule = locked
+		return ds.UpdateSchedule(*locked)
+	})
+	switch {
+	case errors.Is(err, errScheduleBusy):
+		return &respModel.Response{
+			Status:  http.StatusConflict,
+			Message: codes.GetErr(codes.ErrScheduleBusy),
+			Data:    nil,
+		}
+	case errors.Is(err, errScheduleClosed):
+		return &respModel.Response{
+			Status:  http.StatusConflict,
+			Message: codes.GetErr(codes.ErrScheduleClosed),
+			Data:    nil,
+		}
+	case err != nil:
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrSchedule),
+			Data:    nil,
+		}
+	}
+	return &respModel.Response{
+		Status:  http.StatusOK,
+		Message: "SUCCESS",
+		Data:    *schedule,
+	}
+}
+
+// UpdateSchedule replaces the definition of an open schedule of the user, which then runs at its next occurrence
+// after now. Pausing and resuming a schedule go through here.
+func (l transactionManagementServiceLogic) UpdateSchedule(id string, userId string, newSchedule model.NewSchedule) *respModel.Response {
+	newSchedule.UserId = userId
+	definition, errResp := scheduleDefinition(newSchedule, time.Now())
+	if errResp != nil {
+		return errResp
+	}
+	errResp = l.checkAccountOwner(userId, definition.AccountNumber, codes.GetErr(codes.ErrSchedule))
+	if errResp != nil {
+		return errResp
+	}
+	return l.changeSchedule(id, userId, func(s *model.Schedule) {
+		definition.ScheduleId, definition.CreatedAt, definition.UpdatedAt = s.ScheduleId, s.CreatedAt, s.UpdatedAt
+		*s = definition
+	})
+}
+
+// CancelSchedule stops an open schedule of the user, its executions are kept
+func (l transactionManagementServiceLogic) CancelSchedule(id string, userId string) *respModel.Response {
+	return l.changeSchedule(id, userId, func(s *model.Schedule) {
+		s.Status = model.ScheduleCancelled
+	})
+}
+
+// advanceSchedule moves a schedule to its first occurrence after both the occurrence just run and now, so
+// occurrences missed while the scheduler was down are skipped rather than run late one after another. A schedule
+// with no occurrence left is completed.
+func advanceSchedule(s *model.Schedule, now time.Time) {
+	after := s.NextRunAt
+	if now.After(after) {
+		after = now
+	}
+	s.Attempts = 0
+	s.LastError = ""
+	next, ok := nextOccurrence(*s, after)
+	if !ok {
+		s.Status = model.ScheduleCompleted
+		return
+	}
+	s.NextRunAt, s.DueAt = next, next
+}
+
+// RunDueSchedules creates the transactions of one batch of schedules whose occurrence is due, and returns the
+// executions recorded. A schedule failing is logged and the batch goes on, the last error is returned.
+func (l transactionManagementServiceLogic) RunDueSchedules() ([]model.ScheduleExecution, error) {
+	schedules, err := l.DsSvc.GetDueSchedules(l.UtilSvc.Scheduler.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	executions := make([]model.ScheduleExecution, 0, len(schedules))
+	var lastErr error
+	for _, schedule := range schedules {
+		execution, err := l.runSchedule(schedule.ScheduleId, time.Now())
+		if err != nil {
+			log.Error(err)
+			lastErr = err
+			continue
+		}
+		if execution != nil {
+			executions = append(executions, *execution)
+		}
+	}
+	return executions, lastErr
+}
+
+// runSchedule creates the transaction of the due occurrence of a schedule through the same logic as NewTransaction,
+// records the execution and moves the schedule to its next occurrence, all in one database transaction holding the
+// schedule locked. An occurrence is therefore run once even by concurrent schedulers, and a schedule locked or no
+// longer due is left alone. Transactions refused by the validation, limits or risk rules skip the occurrence, while
+// server errors roll everything back and retry it.
+func (l transactionManagementServiceLogic) runSchedule(id string, now time.Time) (*model.ScheduleExecution, error) {
+	var schedule *model.Schedule
+	var execution *model.ScheduleExecution
+	var legs []model.Transaction
+	err := l.DsSvc.Transaction(func(ds datasource.DataSourceI) error {
+		var err error
+		schedule, err = ds.LockSchedule(id)
+		if err != nil {
+			return err
+		}
+		if schedule == nil || schedule.Status != model.ScheduleActive || schedule.DueAt.After(now) {
+			return nil
+		}
+		// Bind the transaction logic to the database transaction, so the new transaction is written in it
+		bound := transactionManagementServiceLogic{DsSvc: ds, UtilSvc: l.UtilSvc}
+		var resp *respModel.Response
+		legs, resp = bound.createTransaction(schedule.NewTransaction())
+		if resp.Status >= http.StatusInternalServerError {
+			return scheduleRetry{message: resp.Message}
+		}
+		e := model.ScheduleExecution{ScheduleId: id, RunAt: schedule.NextRunAt, Attempt: schedule.Attempts + 1, Outcome: model.ExecutionSucceeded, Message: resp.Message}
+		if resp.Status >= http.StatusMultipleChoices {
+			e.Outcome = model.ExecutionRejected
+		}
+		if len(legs) > 0 {
+			e.TransactionId = legs[0].TransactionId
+		}
+		err = ds.InsertScheduleExecution(e)
+		if err != nil {
+			return err
+		}
+		advanceSchedule(schedule, now)
+		err = ds.UpdateSchedule(*schedule)
+		if err != nil {
+			return err
+		}
+		execution = &e
+		return nil
+	})
+	var retry scheduleRetry
+	if errors.As(err, &retry) {
+		return l.retrySchedule(*schedule, retry.message, now)
+	}
+	if err != nil {
+		return nil, err
+	}
+	l.invalidateCache(legs)
+	return execution, nil
+}
+
+// retrySchedule records a failed attempt at the due occurrence of a schedule and makes it due again after a backoff,
+// or skips the occurrence once it failed the maximum number of attempts. It does nothing when the schedule changed
+// since the attempt.
+func (l transactionManagementServiceLogic) retrySchedule(attempted model.Schedule, message string, now time.Time) (*model.ScheduleExecution, error) {
+	var execution *model.ScheduleExecution
+	err := l.DsSvc.Transaction(func(ds datasource.DataSourceI) error {
+		schedule, err := ds.LockSchedule(attempted.ScheduleId)
+		if err != nil {
+			return err
+		}
+		if schedule == nil || schedule.Status != model.ScheduleActive || !schedule.NextRunAt.Equal(attempted.NextRunAt) || schedule.Attempts != attempted.Attempts {
+			return nil
+		}
+		schedule.Attempts++
+		e := model.ScheduleExecution{ScheduleId: schedule.ScheduleId, RunAt: schedule.NextRunAt, Attempt: schedule.Attempts, Outcome: model.ExecutionRetrying, Message: message}
+		if schedule.Attempts >= l.UtilSvc.Scheduler.MaxAttempts {
+			e.Outcome = model.ExecutionSkipped
+			advanceSchedule(schedule, now)
+		} else {
+			schedule.DueAt = now.Add(outbox.Backoff(schedule.Attempts, l.UtilSvc.Scheduler.BaseBackoff, l.UtilSvc.Scheduler.MaxBackoff)).UTC().Truncate(time.Second)
+		}
+		schedule.LastError = message
+		err = ds.InsertScheduleExecution(e)
+		if err != nil {
+			return err
+		}
+		err = ds.UpdateSchedule(*schedule)
+		if err != nil {
+			return err
+		}
+		execution = &e
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return execution, nil
+}
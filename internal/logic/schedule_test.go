@@ -0,0 +1,692 @@
+package logic
+
+import (
+	"errors"
+	respModel "github.com/PereRohit/util/model"
+	"github.com/PereRohit/util/testutil"
+	"github.com/golang/mock/gomock"
+	"github.com/vatsal278/TransactionManagementService/internal/codes"
+	"github.com/vatsal278/TransactionManagementService/internal/config"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"github.com/vatsal278/TransactionManagementService/internal/repo/datasource"
+	"github.com/vatsal278/TransactionManagementService/pkg/mock"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAddMonths(t *testing.T) {
+	tests := []struct {
+		name   string
+		give   time.Time
+		months int
+		want   time.Time
+	}{
+		{name: "same day", give: time.Date(2023, time.January, 15, 9, 0, 0, 0, time.UTC), months: 1, want: time.Date(2023, time.February, 15, 9, 0, 0, 0, time.UTC)},
+		{name: "shorter month", give: time.Date(2023, time.January, 31, 9, 0, 0, 0, time.UTC), months: 1, want: time.Date(2023, time.February, 28, 9, 0, 0, 0, time.UTC)},
+		{name: "leap year", give: time.Date(2024, time.January, 31, 9, 0, 0, 0, time.UTC), months: 1, want: time.Date(2024, time.February, 29, 9, 0, 0, 0, time.UTC)},
+		{name: "next year", give: time.Date(2023, time.November, 30, 9, 0, 0, 0, time.UTC), months: 3, want: time.Date(2024, time.February, 29, 9, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := testutil.Diff(addMonths(tt.give, tt.months), tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestNextOccurrence(t *testing.T) {
+	start := time.Date(2023, time.January, 31, 9, 0, 0, 0, time.UTC)
+	endAt := time.Date(2023, time.March, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		schedule model.Schedule
+		after    time.Time
+		want     time.Time
+		wantOk   bool
+	}{
+		{name: "once before start", schedule: model.Schedule{Frequency: model.FrequencyOnce, StartAt: start}, after: start.Add(-time.Hour), want: start, wantOk: true},
+		{name: "once at start", schedule: model.Schedule{Frequency: model.FrequencyOnce, StartAt: start}, after: start, wantOk: false},
+		{name: "daily before start", schedule: model.Schedule{Frequency: model.FrequencyDaily, StartAt: start}, after: start.Add(-48 * time.Hour), want: start, wantOk: true},
+		{name: "daily", schedule: model.Schedule{Frequency: model.FrequencyDaily, StartAt: start}, after: time.Date(2023, time.February, 10, 9, 0, 0, 0, time.UTC), want: time.Date(2023, time.February, 11, 9, 0, 0, 0, time.UTC), wantOk: true},
+		{name: "weekly", schedule: model.Schedule{Frequency: model.FrequencyWeekly, StartAt: start}, after: time.Date(2023, time.February, 10, 9, 0, 0, 0, time.UTC), want: time.Date(2023, time.February, 14, 9, 0, 0, 0, time.UTC), wantOk: true},
+		{name: "monthly on a shorter month", schedule: model.Schedule{Frequency: model.FrequencyMonthly, StartAt: start}, after: start, want: time.Date(2023, time.February, 28, 9, 0, 0, 0, time.UTC), wantOk: true},
+		{name: "monthly back on the day", schedule: model.Schedule{Frequency: model.FrequencyMonthly, StartAt: start}, after: time.Date(2023, time.February, 28, 9, 0, 0, 0, time.UTC), want: time.Date(2023, time.March, 31, 9, 0, 0, 0, time.UTC), wantOk: true},
+		{name: "cron from the start", schedule: model.Schedule{Frequency: model.FrequencyCron, Cron: "0 9 * * *", StartAt: start}, after: start.Add(-72 * time.Hour), want: start, wantOk: true},
+		{name: "cron", schedule: model.Schedule{Frequency: model.FrequencyCron, Cron: "30 8 * * 1", StartAt: start}, after: start, want: time.Date(2023, time.February, 6, 8, 30, 0, 0, time.UTC), wantOk: true},
+		{name: "past the end", schedule: model.Schedule{Frequency: model.FrequencyMonthly, StartAt: start, EndAt: &endAt}, after: time.Date(2023, time.February, 28, 9, 0, 0, 0, time.UTC), wantOk: false},
+		{name: "invalid cron", schedule: model.Schedule{Frequency: model.FrequencyCron, Cron: "bad", StartAt: start}, after: start, wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := nextOccurrence(tt.schedule, tt.after)
+			if ok != tt.wantOk {
+				t.Errorf("Want: %v, Got: %v", tt.wantOk, ok)
+			}
+			diff := testutil.Diff(got, tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestScheduleDefinition(t *testing.T) {
+	now := time.Date(2023, time.June, 1, 12, 0, 0, 0, time.UTC)
+	start := time.Date(2023, time.June, 2, 9, 0, 0, 500, time.FixedZone("UTC+2", 2*60*60))
+	before := start.Add(-time.Hour)
+	valid := model.NewSchedule{UserId: "123", AccountNumber: 1, Amount: 1000, Type: "debit", TransactionStatus: model.StatusApproved, Frequency: model.FrequencyDaily, StartAt: start}
+	tests := []struct {
+		name    string
+		give    func(*model.NewSchedule)
+		want    func(model.Schedule)
+		wantMsg string
+	}{
+		{
+			name: "Success::due at the start",
+			give: func(s *model.NewSchedule) {},
+			want: func(s model.Schedule) {
+				wantAt := time.Date(2023, time.June, 2, 7, 0, 0, 0, time.UTC)
+				if !s.StartAt.Equal(wantAt) || s.StartAt.Location() != time.UTC || !s.NextRunAt.Equal(wantAt) || !s.DueAt.Equal(wantAt) || s.Currency != model.DefaultCurrency || s.Status != model.ScheduleActive {
+					t.Errorf("Want: %v, Got: %v", "an active schedule due at the start", s)
+				}
+			},
+		},
+		{
+			name: "Success::paused",
+			give: func(s *model.NewSchedule) { s.Paused = true },
+			want: func(s model.Schedule) {
+				if s.Status != model.SchedulePaused {
+					t.Errorf("Want: %v, Got: %v", model.SchedulePaused, s.Status)
+				}
+			},
+		},
+		{name: "Failure::no start", give: func(s *model.NewSchedule) { s.StartAt = time.Time{} }, wantMsg: codes.GetErr(codes.ErrInvalidSchedule) + ": start_at is required"},
+		{name: "Failure::end before start", give: func(s *model.NewSchedule) { s.EndAt = &before }, wantMsg: codes.GetErr(codes.ErrInvalidSchedule) + ": end_at is before start_at"},
+		{name: "Failure::no amount", give: func(s *model.NewSchedule) { s.Amount = 0 }, wantMsg: codes.GetErr(codes.ErrInvalidAmount)},
+		{name: "Failure::unsupported currency", give: func(s *model.NewSchedule) { s.Currency = "XYZ" }, wantMsg: codes.GetErr(codes.ErrInvalidCurrency)},
+		{name: "Failure::currency precision", give: func(s *model.NewSchedule) { s.Currency, s.Amount = "JPY", 1050 }, wantMsg: codes.GetErr(codes.ErrCurrencyPrecision)},
+		{name: "Failure::transfer to the same account", give: func(s *model.NewSchedule) { s.TransferTo = 1 }, wantMsg: codes.GetErr(codes.ErrInvalidTransfer)},
+		{name: "Failure::cron without the cron frequency", give: func(s *model.NewSchedule) { s.Cron = "* * * * *" }, wantMsg: codes.GetErr(codes.ErrInvalidSchedule) + ": cron is only used by the cron frequency"},
+		{name: "Failure::invalid cron", give: func(s *model.NewSchedule) { s.Frequency, s.Cron = model.FrequencyCron, "* * *" }, wantMsg: codes.GetErr(codes.ErrInvalidSchedule) + `: cron expression "* * *" must have 5 fields`},
+		{name: "Failure::no occurrence left", give: func(s *model.NewSchedule) { s.Frequency, s.StartAt = model.FrequencyOnce, now.Add(-time.Hour) }, wantMsg: codes.GetErr(codes.ErrInvalidSchedule) + ": schedule has no occurrence after now"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newSchedule := valid
+			tt.give(&newSchedule)
+			got, errResp := scheduleDefinition(newSchedule, now)
+			if tt.wantMsg != "" {
+				if errResp == nil || errResp.Status != http.StatusBadRequest || errResp.Message != tt.wantMsg {
+					t.Errorf("Want: %v, Got: %v", tt.wantMsg, errResp)
+				}
+				return
+			}
+			if errResp != nil {
+				t.Fatalf("Want: %v, Got: %v", nil, errResp)
+			}
+			tt.want(got)
+		})
+	}
+}
+
+func TestTransactionManagementServiceLogic_CreateSchedule(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	newSchedule := model.NewSchedule{UserId: "123", AccountNumber: 1, Amount: 1000, Type: "debit", TransactionStatus: model.StatusApproved, Frequency: model.FrequencyMonthly, StartAt: time.Now().Add(time.Hour)}
+	// owned registers account 1 to the user
+	owned := func(mockDs *mock.MockDataSourceI) {
+		mockDs.EXPECT().GetAccount(1).Times(1).Return(&model.Account{AccountNumber: 1, UserId: "123", Currency: "USD"}, nil)
+	}
+	tests := []struct {
+		name  string
+		give  model.NewSchedule
+		setup func() datasource.DataSourceI
+		want  func(*respModel.Response)
+	}{
+		{
+			name: "Success::schedule created",
+			give: newSchedule,
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				owned(mockDs)
+				mockDs.EXPECT().InsertSchedule(gomock.Any()).Times(1).DoAndReturn(func(s model.Schedule) error {
+					if s.ScheduleId == "" || s.UserId != "123" || s.Status != model.ScheduleActive {
+						t.Errorf("Want: %v, Got: %v", "an active schedule of the user", s)
+					}
+					return nil
+				})
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				s, ok := resp.Data.(model.Schedule)
+				if resp.Status != http.StatusCreated || !ok || s.ScheduleId == "" {
+					t.Errorf("Want: %v, Got: %v", "the schedule created", resp)
+				}
+			},
+		},
+		{
+			name: "Failure::invalid schedule",
+			give: model.NewSchedule{UserId: "123", AccountNumber: 1, Amount: 1000, Type: "debit", Frequency: model.FrequencyDaily},
+			setup: func() datasource.DataSourceI {
+				return mock.NewMockDataSourceI(mockCtrl)
+			},
+			want: func(resp *respModel.Response) {
+				if resp.Status != http.StatusBadRequest {
+					t.Errorf("Want: %v, Got: %v", http.StatusBadRequest, resp.Status)
+				}
+			},
+		},
+		{
+			name: "Failure::account of another user",
+			give: newSchedule,
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetAccount(1).Times(1).Return(&model.Account{AccountNumber: 1, UserId: "999", Currency: "USD"}, nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusNotFound,
+					Message: codes.GetErr(codes.ErrNoAccount),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+		{
+			name: "Failure::insert error",
+			give: newSchedule,
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				owned(mockDs)
+				mockDs.EXPECT().InsertSchedule(gomock.Any()).Times(1).Return(errors.New("error"))
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusInternalServerError,
+					Message: codes.GetErr(codes.ErrSchedule),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := NewTransactionManagementServiceLogic(tt.setup(), config.ExternalSvc{})
+			tt.want(rec.CreateSchedule(tt.give))
+		})
+	}
+}
+
+func TestTransactionManagementServiceLogic_GetSchedule(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	schedule := model.Schedule{ScheduleId: "s1", UserId: "123", Status: model.ScheduleActive}
+	executions := []model.ScheduleExecution{{ScheduleId: "s1", Attempt: 1, Outcome: model.ExecutionSucceeded}}
+	tests := []struct {
+		name  string
+		setup func() datasource.DataSourceI
+		want  respModel.Response
+	}{
+		{
+			name: "Success::schedule with its executions",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetSchedule("s1").Times(1).Return(&schedule, nil)
+				mockDs.EXPECT().GetScheduleExecutions("s1", scheduleExecutionsShown).Times(1).Return(executions, nil)
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusOK, Message: "SUCCESS", Data: model.ScheduleDetail{Schedule: schedule, Executions: executions}},
+		},
+		{
+			name: "Failure::schedule of another user",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetSchedule("s1").Times(1).Return(&model.Schedule{ScheduleId: "s1", UserId: "456"}, nil)
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusNotFound, Message: codes.GetErr(codes.ErrNoSchedule)},
+		},
+		{
+			name: "Failure::no schedule",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetSchedule("s1").Times(1).Return(nil, nil)
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusNotFound, Message: codes.GetErr(codes.ErrNoSchedule)},
+		},
+		{
+			name: "Failure::schedule error",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetSchedule("s1").Times(1).Return(nil, errors.New("error"))
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusInternalServerError, Message: codes.GetErr(codes.ErrGetSchedule)},
+		},
+		{
+			name: "Failure::executions error",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetSchedule("s1").Times(1).Return(&schedule, nil)
+				mockDs.EXPECT().GetScheduleExecutions("s1", scheduleExecutionsShown).Times(1).Return(nil, errors.New("error"))
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusInternalServerError, Message: codes.GetErr(codes.ErrGetSchedule)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := NewTransactionManagementServiceLogic(tt.setup(), config.ExternalSvc{})
+			diff := testutil.Diff(rec.GetSchedule("s1", "123"), &tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestTransactionManagementServiceLogic_GetSchedules(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockDs := mock.NewMockDataSourceI(mockCtrl)
+	mockDs.EXPECT().GetSchedules("123").Times(1).Return([]model.Schedule{{ScheduleId: "s1"}}, nil)
+	mockDs.EXPECT().GetSchedules("123").Times(1).Return(nil, errors.New("error"))
+	rec := NewTransactionManagementServiceLogic(mockDs, config.ExternalSvc{})
+	diff := testutil.Diff(rec.GetSchedules("123"), &respModel.Response{Status: http.StatusOK, Message: "SUCCESS", Data: []model.Schedule{{ScheduleId: "s1"}}})
+	if diff != "" {
+		t.Error(testutil.Callers(), diff)
+	}
+	diff = testutil.Diff(rec.GetSchedules("123"), &respModel.Response{Status: http.StatusInternalServerError, Message: codes.GetErr(codes.ErrGetSchedule)})
+	if diff != "" {
+		t.Error(testutil.Callers(), diff)
+	}
+}
+
+func TestTransactionManagementServiceLogic_ChangeSchedule(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	inTransaction := func(mockDs *mock.MockDataSourceI) {
+		mockDs.EXPECT().Transaction(gomock.Any()).Times(1).DoAndReturn(func(fn func(datasource.DataSourceI) error) error {
+			return fn(mockDs)
+		})
+	}
+	createdAt := time.Date(2023, time.June, 1, 0, 0, 0, 0, time.UTC)
+	open := func() *model.Schedule {
+		return &model.Schedule{ScheduleId: "s1", UserId: "123", Amount: 1000, Frequency: model.FrequencyDaily, Status: model.ScheduleActive, Attempts: 2, LastError: "error", CreatedAt: createdAt}
+	}
+	update := model.NewSchedule{AccountNumber: 1, Amount: 2500, Type: "debit", TransactionStatus: model.StatusApproved, Frequency: model.FrequencyWeekly, StartAt: time.Now().Add(time.Hour), Paused: true}
+	// owned registers account 1 to the user
+	owned := func(mockDs *mock.MockDataSourceI) {
+		mockDs.EXPECT().GetAccount(1).Times(1).Return(&model.Account{AccountNumber: 1, UserId: "123", Currency: "USD"}, nil)
+	}
+	tests := []struct {
+		name   string
+		cancel bool
+		setup  func() datasource.DataSourceI
+		want   func(*respModel.Response)
+	}{
+		{
+			name: "Success::schedule replaced",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				owned(mockDs)
+				mockDs.EXPECT().GetSchedule("s1").Times(1).Return(open(), nil)
+				inTransaction(mockDs)
+				mockDs.EXPECT().LockSchedule("s1").Times(1).Return(open(), nil)
+				mockDs.EXPECT().UpdateSchedule(gomock.Any()).Times(1).DoAndReturn(func(s model.Schedule) error {
+					if s.ScheduleId != "s1" || s.UserId != "123" || s.Amount != 2500 || s.Status != model.SchedulePaused || s.Attempts != 0 || s.LastError != "" || !s.CreatedAt.Equal(createdAt) {
+						t.Errorf("Want: %v, Got: %v", "the schedule replaced", s)
+					}
+					return nil
+				})
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				s, ok := resp.Data.(model.Schedule)
+				if resp.Status != http.StatusOK || !ok || s.Frequency != model.FrequencyWeekly {
+					t.Errorf("Want: %v, Got: %v", "the schedule replaced", resp)
+				}
+			},
+		},
+		{
+			name:   "Success::schedule cancelled",
+			cancel: true,
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetSchedule("s1").Times(1).Return(open(), nil)
+				inTransaction(mockDs)
+				mockDs.EXPECT().LockSchedule("s1").Times(1).Return(open(), nil)
+				mockDs.EXPECT().UpdateSchedule(gomock.Any()).Times(1).DoAndReturn(func(s model.Schedule) error {
+					if s.Status != model.ScheduleCancelled || s.Amount != 1000 {
+						t.Errorf("Want: %v, Got: %v", "the schedule cancelled", s)
+					}
+					return nil
+				})
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				s, ok := resp.Data.(model.Schedule)
+				if resp.Status != http.StatusOK || !ok || s.Status != model.ScheduleCancelled {
+					t.Errorf("Want: %v, Got: %v", "the schedule cancelled", resp)
+				}
+			},
+		},
+		{
+			name:   "Failure::schedule already cancelled",
+			cancel: true,
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetSchedule("s1").Times(1).Return(&model.Schedule{ScheduleId: "s1", UserId: "123", Status: model.ScheduleCancelled}, nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{Status: http.StatusConflict, Message: codes.GetErr(codes.ErrScheduleClosed)}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+		{
+			name: "Failure::schedule completed while changing",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				owned(mockDs)
+				mockDs.EXPECT().GetSchedule("s1").Times(1).Return(open(), nil)
+				inTransaction(mockDs)
+				mockDs.EXPECT().LockSchedule("s1").Times(1).Return(&model.Schedule{ScheduleId: "s1", UserId: "123", Status: model.ScheduleCompleted}, nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{Status: http.StatusConflict, Message: codes.GetErr(codes.ErrScheduleClosed)}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+		{
+			name:   "Failure::schedule being run",
+			cancel: true,
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetSchedule("s1").Times(1).Return(open(), nil)
+				inTransaction(mockDs)
+				mockDs.EXPECT().LockSchedule("s1").Times(1).Return(nil, nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{Status: http.StatusConflict, Message: codes.GetErr(codes.ErrScheduleBusy)}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+		{
+			name:   "Failure::update error",
+			cancel: true,
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetSchedule("s1").Times(1).Return(open(), nil)
+				inTransaction(mockDs)
+				mockDs.EXPECT().LockSchedule("s1").Times(1).Return(open(), nil)
+				mockDs.EXPECT().UpdateSchedule(gomock.Any()).Times(1).Return(errors.New("error"))
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{Status: http.StatusInternalServerError, Message: codes.GetErr(codes.ErrSchedule)}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := NewTransactionManagementServiceLogic(tt.setup(), config.ExternalSvc{})
+			if tt.cancel {
+				tt.want(rec.CancelSchedule("s1", "123"))
+				return
+			}
+			tt.want(rec.UpdateSchedule("s1", "123", update))
+		})
+	}
+}
+
+func TestAdvanceSchedule(t *testing.T) {
+	runAt := time.Date(2023, time.June, 1, 9, 0, 0, 0, time.UTC)
+	endAt := time.Date(2023, time.June, 3, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		now  time.Time
+		end  *time.Time
+		want model.Schedule
+	}{
+		{
+			name: "next occurrence",
+			now:  runAt.Add(time.Minute),
+			want: model.Schedule{Frequency: model.FrequencyDaily, StartAt: runAt, NextRunAt: runAt.AddDate(0, 0, 1), DueAt: runAt.AddDate(0, 0, 1), Status: model.ScheduleActive},
+		},
+		{
+			name: "missed occurrences skipped",
+			now:  runAt.AddDate(0, 0, 3),
+			want: model.Schedule{Frequency: model.FrequencyDaily, StartAt: runAt, NextRunAt: runAt.AddDate(0, 0, 4), DueAt: runAt.AddDate(0, 0, 4), Status: model.ScheduleActive},
+		},
+		{
+			name: "completed",
+			now:  runAt.AddDate(0, 0, 1).Add(time.Minute),
+			end:  &endAt,
+			want: model.Schedule{Frequency: model.FrequencyDaily, StartAt: runAt, EndAt: &endAt, NextRunAt: runAt, DueAt: runAt, Status: model.ScheduleCompleted},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := model.Schedule{Frequency: model.FrequencyDaily, StartAt: runAt, EndAt: tt.end, NextRunAt: runAt, DueAt: runAt, Status: model.ScheduleActive, Attempts: 3, LastError: "error"}
+			advanceSchedule(&s, tt.now)
+			diff := testutil.Diff(s, tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestTransactionManagementServiceLogic_RunDueSchedules(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	inTransaction := func(mockDs *mock.MockDataSourceI, times int) {
+		mockDs.EXPECT().Transaction(gomock.Any()).Times(times).DoAndReturn(func(fn func(datasource.DataSourceI) error) error {
+			return fn(mockDs)
+		})
+	}
+	runAt := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+	due := func() model.Schedule {
+		return model.Schedule{ScheduleId: "s1", UserId: "123", AccountNumber: 1, Amount: 1000, Type: "credit", TransactionStatus: model.StatusPending, Frequency: model.FrequencyDaily, StartAt: runAt, NextRunAt: runAt, DueAt: runAt, Status: model.ScheduleActive}
+	}
+	retrying := due()
+	retrying.Attempts = 1
+	retrying.DueAt = runAt.Add(30 * time.Second)
+	// executed checks the execution recorded and the schedule it leaves
+	executed := func(mockDs *mock.MockDataSourceI, outcome string, attempt int, check func(model.Schedule)) {
+		mockDs.EXPECT().InsertScheduleExecution(gomock.Any()).Times(1).DoAndReturn(func(e model.ScheduleExecution) error {
+			if e.ScheduleId != "s1" || !e.RunAt.Equal(runAt) || e.Outcome != outcome || e.Attempt != attempt {
+				t.Errorf("Want: %v %v, Got: %v", outcome, attempt, e)
+			}
+			return nil
+		})
+		mockDs.EXPECT().UpdateSchedule(gomock.Any()).Times(1).DoAndReturn(func(s model.Schedule) error {
+			check(s)
+			return nil
+		})
+	}
+	advanced := func(s model.Schedule) {
+		if !s.NextRunAt.Equal(runAt.AddDate(0, 0, 1)) || !s.DueAt.Equal(s.NextRunAt) || s.Attempts != 0 || s.Status != model.ScheduleActive {
+			t.Errorf("Want: %v, Got: %v", "the schedule at its next occurrence", s)
+		}
+	}
+	// owned registers account 1 to the user
+	owned := func(mockDs *mock.MockDataSourceI) {
+		mockDs.EXPECT().GetAccount(1).Times(1).Return(&model.Account{AccountNumber: 1, UserId: "123", Currency: "USD"}, nil)
+	}
+	tests := []struct {
+		name     string
+		setup    func() datasource.DataSourceI
+		want     []string
+		wantErr  bool
+		attempts int
+	}{
+		{
+			name: "Success::transaction created",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				owned(mockDs)
+				mockDs.EXPECT().GetDueSchedules(10).Times(1).Return([]model.Schedule{due()}, nil)
+				inTransaction(mockDs, 2)
+				mockDs.EXPECT().LockSchedule("s1").Times(1).Return(func() *model.Schedule { s := due(); return &s }(), nil)
+				mockDs.EXPECT().Insert(gomock.Any()).Times(1).DoAndReturn(func(tr model.Transaction) error {
+					if tr.UserId != "123" || tr.Amount != 1000 || tr.Type != "credit" || tr.Status != model.StatusPending {
+						t.Errorf("Want: %v, Got: %v", "the scheduled transaction", tr)
+					}
+					return nil
+				})
+				mockDs.EXPECT().InsertStatusHistory(gomock.Any()).Times(1).Return(nil)
+				mockDs.EXPECT().InsertWebhookDeliveries(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				executed(mockDs, model.ExecutionSucceeded, 1, advanced)
+				return mockDs
+			},
+			want: []string{model.ExecutionSucceeded},
+		},
+		{
+			name: "Success::transaction refused skips the occurrence",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				owned(mockDs)
+				s := due()
+				s.Type, s.TransferTo = "debit", 2
+				mockDs.EXPECT().GetDueSchedules(10).Times(1).Return([]model.Schedule{s}, nil)
+				inTransaction(mockDs, 1)
+				mockDs.EXPECT().LockSchedule("s1").Times(1).Return(&s, nil)
+				mockDs.EXPECT().GetAccount(2).Times(1).Return(nil, nil)
+				executed(mockDs, model.ExecutionRejected, 1, advanced)
+				return mockDs
+			},
+			want: []string{model.ExecutionRejected},
+		},
+		{
+			name: "Success::server error retried",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				owned(mockDs)
+				mockDs.EXPECT().GetDueSchedules(10).Times(1).Return([]model.Schedule{due()}, nil)
+				inTransaction(mockDs, 3)
+				mockDs.EXPECT().LockSchedule("s1").Times(2).DoAndReturn(func(id string) (*model.Schedule, error) {
+					s := due()
+					return &s, nil
+				})
+				mockDs.EXPECT().Insert(gomock.Any()).Times(1).Return(errors.New("error"))
+				executed(mockDs, model.ExecutionRetrying, 1, func(s model.Schedule) {
+					if !s.NextRunAt.Equal(runAt) || !s.DueAt.After(time.Now()) || s.Attempts != 1 || s.LastError != codes.GetErr(codes.ErrNewTransaction) {
+						t.Errorf("Want: %v, Got: %v", "the occurrence due again later", s)
+					}
+				})
+				return mockDs
+			},
+			want: []string{model.ExecutionRetrying},
+		},
+		{
+			name:     "Success::occurrence skipped after the last attempt",
+			attempts: 2,
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				owned(mockDs)
+				mockDs.EXPECT().GetDueSchedules(10).Times(1).Return([]model.Schedule{retrying}, nil)
+				inTransaction(mockDs, 3)
+				mockDs.EXPECT().LockSchedule("s1").Times(2).DoAndReturn(func(id string) (*model.Schedule, error) {
+					s := retrying
+					return &s, nil
+				})
+				mockDs.EXPECT().Insert(gomock.Any()).Times(1).Return(errors.New("error"))
+				executed(mockDs, model.ExecutionSkipped, 2, func(s model.Schedule) {
+					advanced(s)
+					if s.LastError != codes.GetErr(codes.ErrNewTransaction) {
+						t.Errorf("Want: %v, Got: %v", codes.GetErr(codes.ErrNewTransaction), s.LastError)
+					}
+				})
+				return mockDs
+			},
+			want: []string{model.ExecutionSkipped},
+		},
+		{
+			name: "Success::schedule run elsewhere",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetDueSchedules(10).Times(1).Return([]model.Schedule{due(), due()}, nil)
+				inTransaction(mockDs, 2)
+				mockDs.EXPECT().LockSchedule("s1").Times(1).Return(nil, nil)
+				mockDs.EXPECT().LockSchedule("s1").Times(1).DoAndReturn(func(id string) (*model.Schedule, error) {
+					s := due()
+					s.DueAt = time.Now().Add(time.Hour)
+					return &s, nil
+				})
+				return mockDs
+			},
+			want: []string{},
+		},
+		{
+			name: "Failure::lock error",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetDueSchedules(10).Times(1).Return([]model.Schedule{due()}, nil)
+				inTransaction(mockDs, 1)
+				mockDs.EXPECT().LockSchedule("s1").Times(1).Return(nil, errors.New("error"))
+				return mockDs
+			},
+			want:    []string{},
+			wantErr: true,
+		},
+		{
+			name: "Failure::due schedules error",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetDueSchedules(10).Times(1).Return(nil, errors.New("error"))
+				return mockDs
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := tt.attempts
+			if attempts == 0 {
+				attempts = 3
+			}
+			cfg := config.SchedulerCfg{BatchSize: 10, MaxAttempts: attempts, BaseBackoff: time.Minute, MaxBackoff: time.Hour}
+			rec := NewTransactionManagementServiceLogic(tt.setup(), config.ExternalSvc{Scheduler: cfg})
+			executions, err := rec.RunDueSchedules()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			var outcomes []string
+			if executions != nil {
+				outcomes = []string{}
+			}
+			for _, e := range executions {
+				outcomes = append(outcomes, e.Outcome)
+			}
+			diff := testutil.Diff(outcomes, tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package logic
+
+import (
+	"github.com/PereRohit/util/log"
+	respModel "github.com/PereRohit/util/model"
+	"github.com/vatsal278/TransactionManagementService/internal/codes"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// statementTotals holds the balances and settled totals of an account in one currency over a statement period
+type statementTotals struct {
+	opening, credits, debits, closing model.Money
+}
+
+// statementBalances totals the settled transactions of the period by type and currency, alongside the balances
+// before and after the period. Every currency found in either is listed, in alphabetical order.
+func statementBalances(opening []model.Balance, closing []model.Balance, transactions []model.Transaction) []map[string]interface{} {
+	totals := map[string]*statementTotals{}
+	get := func(currency string) *statementTotals {
+		if totals[currency] == nil {
+			totals[currency] = &statementTotals{}
+		}
+		return totals[currency]
+	}
+	for _, balance := range opening {
+		get(balance.Currency).opening = balance.Balance
+	}
+	for _, balance := range closing {
+		get(balance.Currency).closing = balance.Balance
+	}
+	settled := map[string]bool{}
+	for _, status := range model.SettledStatuses {
+		settled[status] = true
+	}
+	for _, transaction := range transactions {
+		if !settled[transaction.Status] {
+			continue
+		}
+		if transaction.Type == "credit" {
+			get(transaction.Currency).credits += transaction.Amount
+		} else {
+			get(transaction.Currency).debits += transaction.Amount
+		}
+	}
+	currencies := make([]string, 0, len(totals))
+	for currency := range totals {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	balances := make([]map[string]interface{}, 0, len(currencies))
+	for _, currency := range currencies {
+		t := totals[currency]
+		balances = append(balances, map[string]interface{}{
+			"Currency": currency,
+			"Opening":  t.opening.String(),
+			"Credits":  t.credits.String(),
+			"Debits":   t.debits.String(),
+			"Closing":  t.closing.String(),
+		})
+	}
+	return balances
+}
+
+// statementLines lists the transactions of a statement in the order they were made
+func statementLines(transactions []model.Transaction) []map[string]interface{} {
+	lines := make([]map[string]interface{}, 0, len(transactions))
+	for _, transaction := range transactions {
+		lines = append(lines, map[string]interface{}{
+			"Date":          transaction.CreatedAt.UTC().Format("2006-01-02 15:04:05"),
+			"TransactionId": transaction.TransactionId,
+			"Type":          transaction.Type,
+			"Status":        transaction.Status,
+			"Amount":        transaction.Amount.String(),
+			"Currency":      transaction.Currency,
+			"Counterparty":  transaction.TransferTo,
+			"Comment":       transaction.Comment,
+		})
+	}
+	return lines
+}
+
+// GetStatement renders the statement of one of the user's accounts for the calendar month starting at month, in UTC,
+// as a PDF. It lists every transaction made in the month along with the opening and closing balances and the totals
+// of the settled credits and debits in each currency, so the opening balance plus the credits less the debits is
+// the closing balance.
+func (l transactionManagementServiceLogic) GetStatement(accountNumber int, userId string, month time.Time, cookie string) *respModel.Response {
+	if l.UtilSvc.PdfSvc.StatementUuId == "" {
+		return &respModel.Response{
+			Status:  http.StatusNotImplemented,
+			Message: codes.GetErr(codes.ErrNoStatementTemplate),
+			Data:    nil,
+		}
+	}
+	errResp := l.checkAccountOwner(userId, accountNumber, codes.GetErr(codes.ErrGetBalance))
+	if errResp != nil {
+		return errResp
+	}
+	from := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	opening, err := l.DsSvc.GetBalances(accountNumber, userId, from.Add(-time.Nanosecond))
+	if err != nil {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrGetStatement),
+			Data:    nil,
+		}
+	}
+	closing, err := l.DsSvc.GetBalances(accountNumber, userId, to.Add(-time.Nanosecond))
+	if err != nil {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrGetStatement),
+			Data:    nil,
+		}
+	}
+	transactions, _, err := l.DsSvc.Get(model.Query{
+		Where:     model.And(model.Eq("user_id", userId), model.Eq("account_number", accountNumber), model.Gte("created_at", from), model.Lt("created_at", to)),
+		OrderBy:   []model.OrderBy{{Column: "created_at"}, {Column: "transaction_id"}},
+		SkipCount: true,
+	})
+	if err != nil {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrGetStatement),
+			Data:    nil,
+		}
+	}
+	user, errResp := l.fetchUser(cookie)
+	if errResp != nil {
+		return errResp
+	}
+	pdf, err := l.UtilSvc.PdfSvc.PdfService.GeneratePdf(map[string]interface{}{
+		"Name":          user["name"],
+		"AccountNumber": accountNumber,
+		"Period":        from.Format("January 2006"),
+		"From":          from.Format("2006-01-02"),
+		"To":            to.AddDate(0, 0, -1).Format("2006-01-02"),
+		"Balances":      statementBalances(opening, closing, transactions),
+		"Transactions":  statementLines(transactions),
+		"GeneratedAt":   time.Now().UTC().Format("2006-01-02 15:04:05"),
+	}, l.UtilSvc.PdfSvc.StatementUuId)
+	if err != nil {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrPdf),
+			Data:    nil,
+		}
+	}
+	return &respModel.Response{
+		Status:  http.StatusOK,
+		Message: "SUCCESS",
+		Data:    pdf,
+	}
+}
@@ -0,0 +1,174 @@
+package logic
+
+import (
+	"errors"
+	respModel "github.com/PereRohit/util/model"
+	"github.com/PereRohit/util/response"
+	"github.com/PereRohit/util/testutil"
+	"github.com/golang/mock/gomock"
+	"github.com/vatsal278/TransactionManagementService/internal/codes"
+	"github.com/vatsal278/TransactionManagementService/internal/config"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"github.com/vatsal278/TransactionManagementService/internal/repo/datasource"
+	"github.com/vatsal278/TransactionManagementService/pkg/mock"
+	pdfMock "github.com/vatsal278/html-pdf-service/pkg/mock"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStatementBalances(t *testing.T) {
+	opening := []model.Balance{{Currency: "USD", Balance: 10000}}
+	closing := []model.Balance{{Currency: "EUR", Balance: 500}, {Currency: "USD", Balance: 12500}}
+	transactions := []model.Transaction{
+		{Type: "credit", Status: model.StatusApproved, Amount: 5000, Currency: "USD"},
+		{Type: "debit", Status: model.StatusApproved, Amount: 2500, Currency: "USD"},
+		{Type: "debit", Status: model.StatusPending, Amount: 1000, Currency: "USD"},
+		{Type: "credit", Status: model.StatusReversed, Amount: 500, Currency: "EUR"},
+		{Type: "credit", Status: model.StatusRejected, Amount: 700, Currency: "GBP"},
+	}
+	want := []map[string]interface{}{
+		{"Currency": "EUR", "Opening": "0.00", "Credits": "5.00", "Debits": "0.00", "Closing": "5.00"},
+		{"Currency": "USD", "Opening": "100.00", "Credits": "50.00", "Debits": "25.00", "Closing": "125.00"},
+	}
+	diff := testutil.Diff(statementBalances(opening, closing, transactions), want)
+	if diff != "" {
+		t.Error(testutil.Callers(), diff)
+	}
+}
+
+func TestTransactionManagementServiceLogic_GetStatement(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("token")
+		if err != nil || cookie.Value != "123" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		response.ToJson(w, http.StatusOK, "SUCCESS", map[string]interface{}{"name": "abc"})
+	}))
+	defer srv.Close()
+	owned := &model.Account{AccountNumber: 1, UserId: "123", Currency: "USD"}
+	from := time.Date(2023, time.June, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, time.July, 1, 0, 0, 0, 0, time.UTC)
+	period := model.Query{
+		Where:     model.And(model.Eq("user_id", "123"), model.Eq("account_number", 1), model.Gte("created_at", from), model.Lt("created_at", to)),
+		OrderBy:   []model.OrderBy{{Column: "created_at"}, {Column: "transaction_id"}},
+		SkipCount: true,
+	}
+	createdAt := time.Date(2023, time.June, 15, 10, 30, 0, 0, time.UTC)
+	transactions := []model.Transaction{{TransactionId: "a", Type: "credit", Status: model.StatusApproved, Amount: 5000, Currency: "USD", TransferTo: 2, Comment: "salary", CreatedAt: createdAt}}
+	tests := []struct {
+		name   string
+		cookie string
+		setup  func() (datasource.DataSourceI, config.ExternalSvc)
+		want   respModel.Response
+	}{
+		{
+			name:   "Success::statement rendered",
+			cookie: "123",
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetAccount(1).Times(1).Return(owned, nil)
+				mockDs.EXPECT().GetBalances(1, "123", from.Add(-time.Nanosecond)).Times(1).Return([]model.Balance{{Currency: "USD", Balance: 10000}}, nil)
+				mockDs.EXPECT().GetBalances(1, "123", to.Add(-time.Nanosecond)).Times(1).Return([]model.Balance{{Currency: "USD", Balance: 15000}}, nil)
+				mockDs.EXPECT().Get(period).Times(1).Return(transactions, 0, nil)
+				mockPdf := pdfMock.NewMockHtmlToPdfSvcI(mockCtrl)
+				mockPdf.EXPECT().GeneratePdf(gomock.Any(), "statement-uuid").Times(1).DoAndReturn(func(data map[string]interface{}, id string) ([]byte, error) {
+					delete(data, "GeneratedAt")
+					diff := testutil.Diff(data, map[string]interface{}{
+						"Name":          "abc",
+						"AccountNumber": 1,
+						"Period":        "June 2023",
+						"From":          "2023-06-01",
+						"To":            "2023-06-30",
+						"Balances":      []map[string]interface{}{{"Currency": "USD", "Opening": "100.00", "Credits": "50.00", "Debits": "0.00", "Closing": "150.00"}},
+						"Transactions":  []map[string]interface{}{{"Date": "2023-06-15 10:30:00", "TransactionId": "a", "Type": "credit", "Status": model.StatusApproved, "Amount": "50.00", "Currency": "USD", "Counterparty": 2, "Comment": "salary"}},
+					})
+					if diff != "" {
+						t.Error(testutil.Callers(), diff)
+					}
+					return []byte("PDF"), nil
+				})
+				return mockDs, config.ExternalSvc{UserSvc: srv.URL, PdfSvc: config.PdfSvc{PdfService: mockPdf, StatementUuId: "statement-uuid"}}
+			},
+			want: respModel.Response{Status: http.StatusOK, Message: "SUCCESS", Data: []byte("PDF")},
+		},
+		{
+			name: "Failure::statements not configured",
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				return mock.NewMockDataSourceI(mockCtrl), config.ExternalSvc{}
+			},
+			want: respModel.Response{Status: http.StatusNotImplemented, Message: codes.GetErr(codes.ErrNoStatementTemplate)},
+		},
+		{
+			name: "Failure::account of another user",
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetAccount(1).Times(1).Return(&model.Account{AccountNumber: 1, UserId: "999"}, nil)
+				return mockDs, config.ExternalSvc{PdfSvc: config.PdfSvc{StatementUuId: "statement-uuid"}}
+			},
+			want: respModel.Response{Status: http.StatusNotFound, Message: codes.GetErr(codes.ErrNoAccount)},
+		},
+		{
+			name: "Failure::balance error",
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetAccount(1).Times(1).Return(owned, nil)
+				mockDs.EXPECT().GetBalances(1, "123", from.Add(-time.Nanosecond)).Times(1).Return(nil, errors.New("error"))
+				return mockDs, config.ExternalSvc{PdfSvc: config.PdfSvc{StatementUuId: "statement-uuid"}}
+			},
+			want: respModel.Response{Status: http.StatusInternalServerError, Message: codes.GetErr(codes.ErrGetStatement)},
+		},
+		{
+			name: "Failure::transactions error",
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetAccount(1).Times(1).Return(owned, nil)
+				mockDs.EXPECT().GetBalances(1, "123", gomock.Any()).Times(2).Return([]model.Balance{}, nil)
+				mockDs.EXPECT().Get(period).Times(1).Return(nil, 0, errors.New("error"))
+				return mockDs, config.ExternalSvc{PdfSvc: config.PdfSvc{StatementUuId: "statement-uuid"}}
+			},
+			want: respModel.Response{Status: http.StatusInternalServerError, Message: codes.GetErr(codes.ErrGetStatement)},
+		},
+		{
+			name:   "Failure::user service error",
+			cookie: "456",
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetAccount(1).Times(1).Return(owned, nil)
+				mockDs.EXPECT().GetBalances(1, "123", gomock.Any()).Times(2).Return([]model.Balance{}, nil)
+				mockDs.EXPECT().Get(period).Times(1).Return(nil, 0, nil)
+				return mockDs, config.ExternalSvc{UserSvc: srv.URL, PdfSvc: config.PdfSvc{StatementUuId: "statement-uuid"}}
+			},
+			want: respModel.Response{Status: http.StatusInternalServerError, Message: codes.GetErr(codes.ErrFetchinDataUserSvc)},
+		},
+		{
+			name:   "Failure::pdf error",
+			cookie: "123",
+			setup: func() (datasource.DataSourceI, config.ExternalSvc) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetAccount(1).Times(1).Return(owned, nil)
+				mockDs.EXPECT().GetBalances(1, "123", gomock.Any()).Times(2).Return([]model.Balance{}, nil)
+				mockDs.EXPECT().Get(period).Times(1).Return(nil, 0, nil)
+				mockPdf := pdfMock.NewMockHtmlToPdfSvcI(mockCtrl)
+				mockPdf.EXPECT().GeneratePdf(gomock.Any(), "statement-uuid").Times(1).Return(nil, errors.New("error"))
+				return mockDs, config.ExternalSvc{UserSvc: srv.URL, PdfSvc: config.PdfSvc{PdfService: mockPdf, StatementUuId: "statement-uuid"}}
+			},
+			want: respModel.Response{Status: http.StatusInternalServerError, Message: codes.GetErr(codes.ErrPdf)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds, utilSvc := tt.setup()
+			rec := NewTransactionManagementServiceLogic(ds, utilSvc)
+			got := rec.GetStatement(1, "123", time.Date(2023, time.June, 1, 0, 0, 0, 0, time.UTC), tt.cookie)
+			if !reflect.DeepEqual(got, &tt.want) {
+				t.Errorf("Want: %v, Got: %v", &tt.want, got)
+			}
+		})
+	}
+}
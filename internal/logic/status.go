@@ -0,0 +1,155 @@
+package logic
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/PereRohit/util/log"
+	respModel "github.com/PereRohit/util/model"
+	"github.com/vatsal278/TransactionManagementService/internal/codes"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"github.com/vatsal278/TransactionManagementService/internal/repo/datasource"
+	"net/http"
+)
+
+// statusTransitions lists the statuses a transaction may move to from each status.
+// Rejected, failed and reversed transactions are final.
+var statusTransitions = map[string][]string{
+	model.StatusPending:  {model.StatusApproved, model.StatusRejected, model.StatusFailed},
+	model.StatusReview:   {model.StatusApproved, model.StatusRejected},
+	model.StatusApproved: {model.StatusReversed},
+}
+
+// canTransition reports whether a transaction may move from one status to another
+func canTransition(from string, to string) bool {
+	for _, status := range statusTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// errStatusChanged is returned when a transaction no longer has the status it was read with
+var errStatusChanged = errors.New("transaction status changed concurrently")
+
+// insertAccountUpdate records in the outbox an account update applying the amount of the leg as the given type
+func insertAccountUpdate(ds datasource.DataSourceI, leg model.Transaction, transactionType string) error {
+	upTransaction := model.UpdateTransaction{AccountNumber: leg.AccountNumber, Amount: leg.Amount, TransactionType: transactionType, Currency: leg.Currency}
+	by, err := json.Marshal(upTransaction)
+	if err != nil {
+		return err
+	}
+	return ds.InsertOutbox(model.OutboxMessage{TransactionId: leg.TransactionId, EventType: model.EventAccountUpdate, Payload: string(by)})
+}
+
+// UpdateStatus moves a transaction created by the user to another status, following the allowed transitions.
+// Both legs of a transfer change together and only through the sender's debit leg. Every change is recorded in the
+// status history, and approving a transaction records its account update in the outbox.
+// Transactions are approved or rejected by a reviewer other than the sender only, while the sender may mark a pending
+// transaction failed. Reversals create compensating transactions and go through Reverse instead.
+func (l transactionManagementServiceLogic) UpdateStatus(id string, userId string, update model.UpdateStatus) *respModel.Response {
+	if update.Status == model.StatusReversed {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrUseReverse),
+			Data:    nil,
+		}
+	}
+	// Reviewers decide on the transactions of other users, which they cannot otherwise see
+	decision := update.Status == model.StatusApproved || update.Status == model.StatusRejected
+	var transaction *model.Transaction
+	var errResp *respModel.Response
+	if decision && l.UtilSvc.Risk.IsReviewer(userId) {
+		transaction, errResp = l.getTransaction(id)
+	} else {
+		transaction, errResp = l.getUserTransaction(id, userId)
+	}
+	if errResp != nil {
+		return errResp
+	}
+	// Transactions seen through a transfer to the user, and the credit legs of transfers, are changed by the sender.
+	// Approving and rejecting is decided by a reviewer other than the sender instead, so nobody applies their own
+	// transactions to the balance.
+	creditLeg := transaction.TransferId != "" && transaction.Type == "credit"
+	if decision {
+		if creditLeg || transaction.UserId == userId || !l.UtilSvc.Risk.IsReviewer(userId) {
+			return &respModel.Response{
+				Status:  http.StatusForbidden,
+				Message: codes.GetErr(codes.ErrReviewRequired),
+				Data:    nil,
+			}
+		}
+	} else if transaction.UserId != userId || creditLeg {
+		return &respModel.Response{
+			Status:  http.StatusForbidden,
+			Message: codes.GetErr(codes.ErrUnauthorized),
+			Data:    nil,
+		}
+	}
+	if !canTransition(transaction.Status, update.Status) {
+		return &respModel.Response{
+			Status:  http.StatusConflict,
+			Message: codes.GetErr(codes.ErrInvalidStatusTransition),
+			Data:    nil,
+		}
+	}
+	legs := []model.Transaction{*transaction}
+	if transaction.TransferId != "" {
+		var err error
+		legs, _, err = l.DsSvc.Get(model.Query{Where: model.Eq("transfer_id", transaction.TransferId), SkipCount: true})
+		if err != nil {
+			log.Error(err)
+			return &respModel.Response{
+				Status:  http.StatusInternalServerError,
+				Message: codes.GetErr(codes.ErrUpdatingTransaction),
+				Data:    nil,
+			}
+		}
+	}
+
+	err := l.DsSvc.Transaction(func(ds datasource.DataSourceI) error {
+		for _, leg := range legs {
+			// Only move legs still in the status they were read with, so concurrent changes cannot both apply
+			n, err := ds.Update(model.And(model.Eq("transaction_id", leg.TransactionId), model.Eq("status", transaction.Status)), map[string]interface{}{"status": update.Status})
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				return errStatusChanged
+			}
+			err = recordStatusChange(ds, model.StatusChange{TransactionId: leg.TransactionId, FromStatus: transaction.Status, ToStatus: update.Status, ChangedBy: userId, Reason: update.Reason}, leg)
+			if err != nil {
+				return err
+			}
+			if update.Status == model.StatusApproved {
+				err = insertAccountUpdate(ds, leg, leg.Type)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errStatusChanged) {
+		return &respModel.Response{
+			Status:  http.StatusConflict,
+			Message: codes.GetErr(codes.ErrStatusChanged),
+			Data:    nil,
+		}
+	}
+	if err != nil {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrUpdatingTransaction),
+			Data:    nil,
+		}
+	}
+	l.invalidateCache(legs)
+	transaction.Status = update.Status
+	return &respModel.Response{
+		Status:  http.StatusOK,
+		Message: "SUCCESS",
+		Data:    *transaction,
+	}
+}
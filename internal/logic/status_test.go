@@ -0,0 +1,409 @@
+package logic
+
+import (
+	"encoding/json"
+	"errors"
+	respModel "github.com/PereRohit/util/model"
+	"github.com/PereRohit/util/testutil"
+	"github.com/golang/mock/gomock"
+	"github.com/vatsal278/TransactionManagementService/internal/codes"
+	"github.com/vatsal278/TransactionManagementService/internal/config"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"github.com/vatsal278/TransactionManagementService/internal/repo/datasource"
+	"github.com/vatsal278/TransactionManagementService/pkg/mock"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want bool
+	}{
+		{name: "pending to approved", from: model.StatusPending, to: model.StatusApproved, want: true},
+		{name: "pending to rejected", from: model.StatusPending, to: model.StatusRejected, want: true},
+		{name: "pending to failed", from: model.StatusPending, to: model.StatusFailed, want: true},
+		{name: "approved to reversed", from: model.StatusApproved, to: model.StatusReversed, want: true},
+		{name: "review to approved", from: model.StatusReview, to: model.StatusApproved, want: true},
+		{name: "review to rejected", from: model.StatusReview, to: model.StatusRejected, want: true},
+		{name: "review to failed", from: model.StatusReview, to: model.StatusFailed},
+		{name: "pending to reversed", from: model.StatusPending, to: model.StatusReversed},
+		{name: "approved to rejected", from: model.StatusApproved, to: model.StatusRejected},
+		{name: "rejected is final", from: model.StatusRejected, to: model.StatusApproved},
+		{name: "reversed is final", from: model.StatusReversed, to: model.StatusApproved},
+		{name: "same status", from: model.StatusPending, to: model.StatusPending},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := testutil.Diff(canTransition(tt.from, tt.to), tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestTransactionManagementServiceLogic_UpdateStatus(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	// inTransaction makes the mock run the function given to Transaction against itself
+	inTransaction := func(mockDs *mock.MockDataSourceI) {
+		mockDs.EXPECT().Transaction(gomock.Any()).Times(1).DoAndReturn(func(fn func(datasource.DataSourceI) error) error {
+			return fn(mockDs)
+		})
+	}
+	byId := model.Query{Where: model.Eq("transaction_id", "abc")}
+	casFilter := func(id string, status string) model.Filter {
+		return model.And(model.Eq("transaction_id", id), model.Eq("status", status))
+	}
+	pending := model.Transaction{TransactionId: "abc", UserId: "123", AccountNumber: 1, Amount: 10, Status: model.StatusPending, Type: "debit"}
+	approved := pending
+	approved.Status = model.StatusApproved
+	held := pending
+	held.Status = model.StatusReview
+	debitLeg := model.Transaction{TransactionId: "abc", UserId: "123", AccountNumber: 1, TransferTo: 2, Amount: 10, Status: model.StatusApproved, Type: "debit", TransferId: "t1"}
+	creditLeg := model.Transaction{TransactionId: "def", UserId: "456", AccountNumber: 2, TransferTo: 1, Amount: 10, Status: model.StatusApproved, Type: "credit", TransferId: "t1"}
+	tests := []struct {
+		name   string
+		userId string
+		update model.UpdateStatus
+		setup  func() datasource.DataSourceI
+		want   func(*respModel.Response)
+	}{
+		{
+			name:   "Success::pending to approved",
+			userId: "risk-1",
+			update: model.UpdateStatus{Status: model.StatusApproved, Reason: "verified"},
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().Get(byId).Times(1).Return([]model.Transaction{pending}, 1, nil)
+				inTransaction(mockDs)
+				mockDs.EXPECT().Update(casFilter("abc", model.StatusPending), map[string]interface{}{"status": model.StatusApproved}).Times(1).Return(int64(1), nil)
+				mockDs.EXPECT().InsertStatusHistory(model.StatusChange{TransactionId: "abc", FromStatus: model.StatusPending, ToStatus: model.StatusApproved, ChangedBy: "risk-1", Reason: "verified"}).Times(1).Return(nil)
+				mockDs.EXPECT().InsertWebhookDeliveries(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				payload, _ := json.Marshal(model.UpdateTransaction{AccountNumber: 1, Amount: 10, TransactionType: "debit"})
+				mockDs.EXPECT().InsertOutbox(model.OutboxMessage{TransactionId: "abc", EventType: model.EventAccountUpdate, Payload: string(payload)}).Times(1).Return(nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusOK,
+					Message: "SUCCESS",
+					Data:    approved,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+		{
+			name:   "Success::pending to rejected leaves the balance alone",
+			userId: "risk-1",
+			update: model.UpdateStatus{Status: model.StatusRejected},
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().Get(byId).Times(1).Return([]model.Transaction{pending}, 1, nil)
+				inTransaction(mockDs)
+				mockDs.EXPECT().Update(casFilter("abc", model.StatusPending), map[string]interface{}{"status": model.StatusRejected}).Times(1).Return(int64(1), nil)
+				mockDs.EXPECT().InsertStatusHistory(gomock.Any()).Times(1).Return(nil)
+				mockDs.EXPECT().InsertWebhookDeliveries(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				if resp.Status != http.StatusOK {
+					t.Errorf("Want: %v, Got: %v", http.StatusOK, resp.Status)
+				}
+			},
+		},
+		{
+			name:   "Success::transfer approved with both legs",
+			userId: "risk-1",
+			update: model.UpdateStatus{Status: model.StatusApproved},
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				debit, credit := debitLeg, creditLeg
+				debit.Status, credit.Status = model.StatusPending, model.StatusPending
+				mockDs.EXPECT().Get(byId).Times(1).Return([]model.Transaction{debit}, 1, nil)
+				mockDs.EXPECT().Get(model.Query{Where: model.Eq("transfer_id", "t1"), SkipCount: true}).Times(1).Return([]model.Transaction{debit, credit}, 0, nil)
+				inTransaction(mockDs)
+				mockDs.EXPECT().Update(casFilter("abc", model.StatusPending), map[string]interface{}{"status": model.StatusApproved}).Times(1).Return(int64(1), nil)
+				mockDs.EXPECT().Update(casFilter("def", model.StatusPending), map[string]interface{}{"status": model.StatusApproved}).Times(1).Return(int64(1), nil)
+				mockDs.EXPECT().InsertStatusHistory(gomock.Any()).Times(2).Return(nil)
+				mockDs.EXPECT().InsertWebhookDeliveries(gomock.Any(), gomock.Any()).Times(2).Return(nil)
+				spend, _ := json.Marshal(model.UpdateTransaction{AccountNumber: 1, Amount: 10, TransactionType: "debit"})
+				mockDs.EXPECT().InsertOutbox(model.OutboxMessage{TransactionId: "abc", EventType: model.EventAccountUpdate, Payload: string(spend)}).Times(1).Return(nil)
+				income, _ := json.Marshal(model.UpdateTransaction{AccountNumber: 2, Amount: 10, TransactionType: "credit"})
+				mockDs.EXPECT().InsertOutbox(model.OutboxMessage{TransactionId: "def", EventType: model.EventAccountUpdate, Payload: string(income)}).Times(1).Return(nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				if resp.Status != http.StatusOK {
+					t.Errorf("Want: %v, Got: %v", http.StatusOK, resp.Status)
+				}
+			},
+		},
+		{
+			name:   "Success::reviewer approves a held transaction",
+			userId: "risk-1",
+			update: model.UpdateStatus{Status: model.StatusApproved, Reason: "customer confirmed"},
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().Get(byId).Times(1).Return([]model.Transaction{held}, 1, nil)
+				inTransaction(mockDs)
+				mockDs.EXPECT().Update(casFilter("abc", model.StatusReview), map[string]interface{}{"status": model.StatusApproved}).Times(1).Return(int64(1), nil)
+				mockDs.EXPECT().InsertStatusHistory(model.StatusChange{TransactionId: "abc", FromStatus: model.StatusReview, ToStatus: model.StatusApproved, ChangedBy: "risk-1", Reason: "customer confirmed"}).Times(1).Return(nil)
+				mockDs.EXPECT().InsertWebhookDeliveries(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				mockDs.EXPECT().InsertOutbox(gomock.Any()).Times(1).Return(nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusOK,
+					Message: "SUCCESS",
+					Data:    approved,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+		{
+			name:   "Failure::sender decides a held transaction",
+			userId: "123",
+			update: model.UpdateStatus{Status: model.StatusApproved},
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().Get(byId).Times(1).Return([]model.Transaction{held}, 1, nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusForbidden,
+					Message: codes.GetErr(codes.ErrReviewRequired),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+		{
+			name:   "Failure::sender approves a pending transaction",
+			userId: "123",
+			update: model.UpdateStatus{Status: model.StatusApproved},
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().Get(byId).Times(1).Return([]model.Transaction{pending}, 1, nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusForbidden,
+					Message: codes.GetErr(codes.ErrReviewRequired),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+		{
+			name:   "Failure::reviewer approves their own transaction",
+			userId: "risk-1",
+			update: model.UpdateStatus{Status: model.StatusApproved},
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				own := pending
+				own.UserId = "risk-1"
+				mockDs.EXPECT().Get(byId).Times(1).Return([]model.Transaction{own}, 1, nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				if resp.Status != http.StatusForbidden {
+					t.Errorf("Want: %v, Got: %v", http.StatusForbidden, resp.Status)
+				}
+			},
+		},
+		{
+			name:   "Failure::reviewer marks another user's transaction failed",
+			userId: "risk-1",
+			update: model.UpdateStatus{Status: model.StatusFailed},
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().Get(byId).Times(1).Return([]model.Transaction{pending}, 1, nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				if resp.Status != http.StatusNotFound {
+					t.Errorf("Want: %v, Got: %v", http.StatusNotFound, resp.Status)
+				}
+			},
+		},
+		{
+			name:   "Success::sender marks a pending transaction failed",
+			userId: "123",
+			update: model.UpdateStatus{Status: model.StatusFailed},
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().Get(byId).Times(1).Return([]model.Transaction{pending}, 1, nil)
+				inTransaction(mockDs)
+				mockDs.EXPECT().Update(casFilter("abc", model.StatusPending), map[string]interface{}{"status": model.StatusFailed}).Times(1).Return(int64(1), nil)
+				mockDs.EXPECT().InsertStatusHistory(model.StatusChange{TransactionId: "abc", FromStatus: model.StatusPending, ToStatus: model.StatusFailed, ChangedBy: "123"}).Times(1).Return(nil)
+				mockDs.EXPECT().InsertWebhookDeliveries(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				if resp.Status != http.StatusOK {
+					t.Errorf("Want: %v, Got: %v", http.StatusOK, resp.Status)
+				}
+			},
+		},
+		{
+			name:   "Failure::reversal goes through reverse",
+			userId: "123",
+			update: model.UpdateStatus{Status: model.StatusReversed},
+			setup: func() datasource.DataSourceI {
+				return mock.NewMockDataSourceI(mockCtrl)
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: codes.GetErr(codes.ErrUseReverse),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+		{
+			name:   "Failure::not found",
+			userId: "123",
+			update: model.UpdateStatus{Status: model.StatusApproved},
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().Get(byId).Times(1).Return(nil, 0, nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				if resp.Status != http.StatusNotFound {
+					t.Errorf("Want: %v, Got: %v", http.StatusNotFound, resp.Status)
+				}
+			},
+		},
+		{
+			name:   "Failure::credit leg changed by recipient",
+			userId: "456",
+			update: model.UpdateStatus{Status: model.StatusFailed},
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				credit := creditLeg
+				credit.TransactionId = "abc"
+				mockDs.EXPECT().Get(byId).Times(1).Return([]model.Transaction{credit}, 1, nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusForbidden,
+					Message: codes.GetErr(codes.ErrUnauthorized),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+		{
+			name:   "Failure::invalid transition",
+			userId: "risk-1",
+			update: model.UpdateStatus{Status: model.StatusRejected},
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().Get(byId).Times(1).Return([]model.Transaction{approved}, 1, nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusConflict,
+					Message: codes.GetErr(codes.ErrInvalidStatusTransition),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+		{
+			name:   "Failure::changed concurrently",
+			userId: "risk-1",
+			update: model.UpdateStatus{Status: model.StatusApproved},
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().Get(byId).Times(1).Return([]model.Transaction{pending}, 1, nil)
+				inTransaction(mockDs)
+				mockDs.EXPECT().Update(gomock.Any(), gomock.Any()).Times(1).Return(int64(0), nil)
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusConflict,
+					Message: codes.GetErr(codes.ErrStatusChanged),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+		{
+			name:   "Failure::transfer legs lookup err",
+			userId: "risk-1",
+			update: model.UpdateStatus{Status: model.StatusApproved},
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				debit := debitLeg
+				debit.Status = model.StatusPending
+				mockDs.EXPECT().Get(byId).Times(1).Return([]model.Transaction{debit}, 1, nil)
+				mockDs.EXPECT().Get(gomock.Any()).Times(1).Return(nil, 0, errors.New("error"))
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				if resp.Status != http.StatusInternalServerError {
+					t.Errorf("Want: %v, Got: %v", http.StatusInternalServerError, resp.Status)
+				}
+			},
+		},
+		{
+			name:   "Failure::update err",
+			userId: "risk-1",
+			update: model.UpdateStatus{Status: model.StatusApproved},
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().Get(byId).Times(1).Return([]model.Transaction{pending}, 1, nil)
+				inTransaction(mockDs)
+				mockDs.EXPECT().Update(gomock.Any(), gomock.Any()).Times(1).Return(int64(0), errors.New("error"))
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				temp := respModel.Response{
+					Status:  http.StatusInternalServerError,
+					Message: codes.GetErr(codes.ErrUpdatingTransaction),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(resp, &temp) {
+					t.Errorf("Want: %v, Got: %v", &temp, resp)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := NewTransactionManagementServiceLogic(tt.setup(), config.ExternalSvc{Risk: config.RiskCfg{Reviewers: []string{"risk-1"}}})
+			got := rec.UpdateStatus("abc", tt.userId, tt.update)
+			tt.want(got)
+		})
+	}
+}
@@ -0,0 +1,310 @@
+package logic
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/PereRohit/util/log"
+	respModel "github.com/PereRohit/util/model"
+	"github.com/google/uuid"
+	"github.com/vatsal278/TransactionManagementService/internal/codes"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"github.com/vatsal278/TransactionManagementService/internal/repo/datasource"
+	"github.com/vatsal278/TransactionManagementService/internal/webhook"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// webhookDeliveriesShown is the number of latest deliveries returned for a webhook
+const webhookDeliveriesShown = 50
+
+// webhookSecretPrefix starts every webhook secret, so they are recognised when leaked
+const webhookSecretPrefix = "whsec_"
+
+// webhookEventType returns the event announcing a status change
+func webhookEventType(change model.StatusChange) string {
+	switch {
+	case change.FromStatus == "":
+		return model.EventTransactionCreated
+	case change.ToStatus == model.StatusReversed:
+		return model.EventTransactionReversed
+	default:
+		return model.EventTransactionStatusChanged
+	}
+}
+
+// recordStatusChange records a status change of a transaction in its history and queues the event announcing it for
+// the webhooks of the transaction's owner, in the same database transaction, so an event is sent if and only if the
+// change is committed.
+func recordStatusChange(ds datasource.DataSourceI, change model.StatusChange, transaction model.Transaction) error {
+	err := ds.InsertStatusHistory(change)
+	if err != nil {
+		return err
+	}
+	event := model.WebhookEvent{
+		Id:        uuid.NewString(),
+		Type:      webhookEventType(change),
+		CreatedAt: time.Now().UTC(),
+		Data: model.WebhookTransaction{
+			TransactionId:  transaction.TransactionId,
+			AccountNumber:  transaction.AccountNumber,
+			Type:           transaction.Type,
+			Status:         change.ToStatus,
+			PreviousStatus: change.FromStatus,
+			Amount:         transaction.Amount,
+			Currency:       transaction.Currency,
+			TransferTo:     transaction.TransferTo,
+			TransferId:     transaction.TransferId,
+			ReferenceId:    transaction.ReferenceId,
+			BatchId:        transaction.BatchId,
+			Comment:        transaction.Comment,
+		},
+	}
+	by, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return ds.InsertWebhookDeliveries(transaction.UserId, model.WebhookDelivery{EventId: event.Id, EventType: event.Type, TransactionId: transaction.TransactionId, Payload: string(by)})
+}
+
+// invalidWebhook responds with a bad request explaining why the webhook is invalid
+func invalidWebhook(reason string) *respModel.Response {
+	return &respModel.Response{
+		Status:  http.StatusBadRequest,
+		Message: fmt.Sprintf("%s: %s", codes.GetErr(codes.ErrInvalidWebhook), reason),
+		Data:    nil,
+	}
+}
+
+// webhookEvents validates the event types a webhook subscribes to and returns them without repeats
+func webhookEvents(events []string) ([]string, *respModel.Response) {
+	if len(events) == 0 {
+		return nil, invalidWebhook("events are required")
+	}
+	known := make(map[string]bool, len(model.WebhookEvents))
+	for _, event := range model.WebhookEvents {
+		known[event] = true
+	}
+	seen := make(map[string]bool, len(events))
+	unique := make([]string, 0, len(events))
+	for _, event := range events {
+		if !known[event] {
+			return nil, invalidWebhook(fmt.Sprintf("unknown event %q", event))
+		}
+		if seen[event] {
+			continue
+		}
+		seen[event] = true
+		unique = append(unique, event)
+	}
+	return unique, nil
+}
+
+// newWebhookSecret returns a random secret signing the deliveries of a webhook
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return webhookSecretPrefix + hex.EncodeToString(b), nil
+}
+
+// getUserWebhook fetches a webhook of the user. A webhook that does not exist and one that belongs to someone else
+// both produce the same not found response.
+func (l transactionManagementServiceLogic) getUserWebhook(id string, userId string) (*model.Webhook, *respModel.Response) {
+	webhook, err := l.DsSvc.GetWebhook(id)
+	if err != nil {
+		log.Error(err)
+		return nil, &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrGetWebhook),
+			Data:    nil,
+		}
+	}
+	if webhook == nil || webhook.UserId != userId {
+		return nil, &respModel.Response{
+			Status:  http.StatusNotFound,
+			Message: codes.GetErr(codes.ErrNoWebhook),
+			Data:    nil,
+		}
+	}
+	return webhook, nil
+}
+
+// CreateWebhook registers an endpoint of the user or of one of the user's client apps for the events it subscribes
+// to. The secret signing its deliveries is only returned here.
+func (l transactionManagementServiceLogic) CreateWebhook(newWebhook model.NewWebhook) *respModel.Response {
+	u, err := url.Parse(newWebhook.Url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return invalidWebhook("url must be an absolute http or https url")
+	}
+	// Endpoints are checked again on every delivery against the address dialed, this only refuses the obvious ones early
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	ip := net.ParseIP(host)
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") || (ip != nil && !webhook.AllowedIP(ip)) {
+		return invalidWebhook("url must not point at a loopback, private or link-local address")
+	}
+	events, errResp := webhookEvents(newWebhook.Events)
+	if errResp != nil {
+		return errResp
+	}
+	secret, err := newWebhookSecret()
+	if err != nil {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrWebhook),
+			Data:    nil,
+		}
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	webhook := model.Webhook{
+		WebhookId: uuid.NewString(),
+		UserId:    newWebhook.UserId,
+		ClientId:  newWebhook.ClientId,
+		Url:       newWebhook.Url,
+		Events:    events,
+		Secret:    secret,
+		Status:    model.WebhookActive,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	err = l.DsSvc.InsertWebhook(webhook)
+	if err != nil {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrWebhook),
+			Data:    nil,
+		}
+	}
+	return &respModel.Response{
+		Status:  http.StatusCreated,
+		Message: "SUCCESS",
+		Data:    webhook,
+	}
+}
+
+// GetWebhooks lists the webhooks of the user, oldest first, without their secrets
+func (l transactionManagementServiceLogic) GetWebhooks(userId string) *respModel.Response {
+	webhooks, err := l.DsSvc.GetWebhooks(userId)
+	if err != nil {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrGetWebhook),
+			Data:    nil,
+		}
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	return &respModel.Response{
+		Status:  http.StatusOK,
+		Message: "SUCCESS",
+		Data:    webhooks,
+	}
+}
+
+// DeleteWebhook disables a webhook of the user. It receives no more events and its pending deliveries are dropped,
+// while its delivery log is kept.
+func (l transactionManagementServiceLogic) DeleteWebhook(id string, userId string) *respModel.Response {
+	webhook, errResp := l.getUserWebhook(id, userId)
+	if errResp != nil {
+		return errResp
+	}
+	if webhook.Status != model.WebhookDisabled {
+		err := l.DsSvc.UpdateWebhookStatus(id, model.WebhookDisabled)
+		if err != nil {
+			log.Error(err)
+			return &respModel.Response{
+				Status:  http.StatusInternalServerError,
+				Message: codes.GetErr(codes.ErrWebhook),
+				Data:    nil,
+			}
+		}
+		webhook.Status = model.WebhookDisabled
+	}
+	webhook.Secret = ""
+	return &respModel.Response{
+		Status:  http.StatusOK,
+		Message: "SUCCESS",
+		Data:    *webhook,
+	}
+}
+
+// GetWebhookDeliveries returns the latest deliveries of a webhook of the user, newest first
+func (l transactionManagementServiceLogic) GetWebhookDeliveries(id string, userId string) *respModel.Response {
+	_, errResp := l.getUserWebhook(id, userId)
+	if errResp != nil {
+		return errResp
+	}
+	deliveries, err := l.DsSvc.GetWebhookDeliveries(id, webhookDeliveriesShown)
+	if err != nil {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrGetWebhook),
+			Data:    nil,
+		}
+	}
+	return &respModel.Response{
+		Status:  http.StatusOK,
+		Message: "SUCCESS",
+		Data:    deliveries,
+	}
+}
+
+// RedeliverWebhook queues a delivery of a webhook of the user to be sent again right away, whatever its outcome so
+// far, with a fresh budget of attempts. The event keeps its id, so receivers can tell it was already handled.
+func (l transactionManagementServiceLogic) RedeliverWebhook(id string, deliveryId string, userId string) *respModel.Response {
+	webhook, errResp := l.getUserWebhook(id, userId)
+	if errResp != nil {
+		return errResp
+	}
+	delivery, err := l.DsSvc.GetWebhookDelivery(deliveryId)
+	if err != nil {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrGetWebhook),
+			Data:    nil,
+		}
+	}
+	if delivery == nil || delivery.WebhookId != webhook.WebhookId {
+		return &respModel.Response{
+			Status:  http.StatusNotFound,
+			Message: codes.GetErr(codes.ErrNoWebhookDelivery),
+			Data:    nil,
+		}
+	}
+	if webhook.Status == model.WebhookDisabled {
+		return &respModel.Response{
+			Status:  http.StatusConflict,
+			Message: codes.GetErr(codes.ErrWebhookDisabled),
+			Data:    nil,
+		}
+	}
+	delivery.Status = model.WebhookDeliveryPending
+	delivery.Attempts = 0
+	delivery.RetryAfter = 0
+	err = l.DsSvc.UpdateWebhookDelivery(*delivery)
+	if err != nil {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrWebhook),
+			Data:    nil,
+		}
+	}
+	return &respModel.Response{
+		Status:  http.StatusAccepted,
+		Message: "SUCCESS",
+		Data:    *delivery,
+	}
+}
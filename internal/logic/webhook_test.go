@@ -0,0 +1,450 @@
+package logic
+
+import (
+	"encoding/json"
+	"errors"
+	respModel "github.com/PereRohit/util/model"
+	"github.com/PereRohit/util/testutil"
+	"github.com/golang/mock/gomock"
+	"github.com/vatsal278/TransactionManagementService/internal/codes"
+	"github.com/vatsal278/TransactionManagementService/internal/config"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"github.com/vatsal278/TransactionManagementService/internal/repo/datasource"
+	"github.com/vatsal278/TransactionManagementService/pkg/mock"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRecordStatusChange(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	transaction := model.Transaction{UserId: "123", TransactionId: "abc", AccountNumber: 1, Type: "debit", Status: model.StatusApproved, Amount: 1000, Currency: "USD", BatchId: "b1", Comment: "rent"}
+	tests := []struct {
+		name       string
+		change     model.StatusChange
+		setup      func(*mock.MockDataSourceI)
+		wantType   string
+		wantStatus string
+		wantErr    bool
+	}{
+		{
+			name:       "Success::created",
+			change:     model.StatusChange{TransactionId: "abc", ToStatus: model.StatusApproved, ChangedBy: "123"},
+			wantType:   model.EventTransactionCreated,
+			wantStatus: model.StatusApproved,
+		},
+		{
+			name:       "Success::status changed",
+			change:     model.StatusChange{TransactionId: "abc", FromStatus: model.StatusPending, ToStatus: model.StatusApproved, ChangedBy: "123"},
+			wantType:   model.EventTransactionStatusChanged,
+			wantStatus: model.StatusApproved,
+		},
+		{
+			name:       "Success::reversed",
+			change:     model.StatusChange{TransactionId: "abc", FromStatus: model.StatusApproved, ToStatus: model.StatusReversed, ChangedBy: "123"},
+			wantType:   model.EventTransactionReversed,
+			wantStatus: model.StatusReversed,
+		},
+		{
+			name:   "Failure::history error",
+			change: model.StatusChange{TransactionId: "abc", ToStatus: model.StatusApproved, ChangedBy: "123"},
+			setup: func(mockDs *mock.MockDataSourceI) {
+				mockDs.EXPECT().InsertStatusHistory(gomock.Any()).Times(1).Return(errors.New("error"))
+			},
+			wantErr: true,
+		},
+		{
+			name:   "Failure::delivery error",
+			change: model.StatusChange{TransactionId: "abc", ToStatus: model.StatusApproved, ChangedBy: "123"},
+			setup: func(mockDs *mock.MockDataSourceI) {
+				mockDs.EXPECT().InsertStatusHistory(gomock.Any()).Times(1).Return(nil)
+				mockDs.EXPECT().InsertWebhookDeliveries("123", gomock.Any()).Times(1).Return(errors.New("error"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDs := mock.NewMockDataSourceI(mockCtrl)
+			if tt.setup != nil {
+				tt.setup(mockDs)
+			} else {
+				mockDs.EXPECT().InsertStatusHistory(tt.change).Times(1).Return(nil)
+				mockDs.EXPECT().InsertWebhookDeliveries("123", gomock.Any()).Times(1).DoAndReturn(func(userId string, delivery model.WebhookDelivery) error {
+					var event model.WebhookEvent
+					err := json.Unmarshal([]byte(delivery.Payload), &event)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if event.Id == "" || event.Id != delivery.EventId || event.Type != tt.wantType || delivery.EventType != tt.wantType || delivery.TransactionId != "abc" {
+						t.Errorf("Want: %v, Got: %v", tt.wantType, delivery)
+					}
+					diff := testutil.Diff(event.Data, model.WebhookTransaction{TransactionId: "abc", AccountNumber: 1, Type: "debit", Status: tt.wantStatus, PreviousStatus: tt.change.FromStatus, Amount: 1000, Currency: "USD", BatchId: "b1", Comment: "rent"})
+					if diff != "" {
+						t.Error(testutil.Callers(), diff)
+					}
+					return nil
+				})
+			}
+			err := recordStatusChange(mockDs, tt.change, transaction)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestTransactionManagementServiceLogic_CreateWebhook(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	newWebhook := model.NewWebhook{UserId: "123", ClientId: "payroll", Url: "https://example.com/hook", Events: []string{model.EventTransactionCreated, model.EventTransactionReversed, model.EventTransactionCreated}}
+	invalid := func(reason string) func(*respModel.Response) {
+		return func(resp *respModel.Response) {
+			if resp.Status != http.StatusBadRequest || !strings.HasPrefix(resp.Message, codes.GetErr(codes.ErrInvalidWebhook)) || !strings.Contains(resp.Message, reason) {
+				t.Errorf("Want: %v, Got: %v", reason, resp)
+			}
+		}
+	}
+	tests := []struct {
+		name  string
+		give  model.NewWebhook
+		setup func() datasource.DataSourceI
+		want  func(*respModel.Response)
+	}{
+		{
+			name: "Success::webhook created",
+			give: newWebhook,
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().InsertWebhook(gomock.Any()).Times(1).DoAndReturn(func(w model.Webhook) error {
+					if w.WebhookId == "" || w.UserId != "123" || w.ClientId != "payroll" || w.Status != model.WebhookActive || !strings.HasPrefix(w.Secret, webhookSecretPrefix) {
+						t.Errorf("Want: %v, Got: %v", "an active webhook of the user", w)
+					}
+					diff := testutil.Diff(w.Events, []string{model.EventTransactionCreated, model.EventTransactionReversed})
+					if diff != "" {
+						t.Error(testutil.Callers(), diff)
+					}
+					return nil
+				})
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				w, ok := resp.Data.(model.Webhook)
+				if resp.Status != http.StatusCreated || !ok || w.Secret == "" {
+					t.Errorf("Want: %v, Got: %v", "the webhook created along with its secret", resp)
+				}
+			},
+		},
+		{
+			name: "Failure::relative url",
+			give: model.NewWebhook{UserId: "123", Url: "/hook", Events: []string{model.EventTransactionCreated}},
+			setup: func() datasource.DataSourceI {
+				return mock.NewMockDataSourceI(mockCtrl)
+			},
+			want: invalid("url"),
+		},
+		{
+			name: "Failure::unsupported scheme",
+			give: model.NewWebhook{UserId: "123", Url: "ftp://example.com/hook", Events: []string{model.EventTransactionCreated}},
+			setup: func() datasource.DataSourceI {
+				return mock.NewMockDataSourceI(mockCtrl)
+			},
+			want: invalid("url"),
+		},
+		{
+			name: "Failure::localhost",
+			give: model.NewWebhook{UserId: "123", Url: "http://localhost:8080/hook", Events: []string{model.EventTransactionCreated}},
+			setup: func() datasource.DataSourceI {
+				return mock.NewMockDataSourceI(mockCtrl)
+			},
+			want: invalid("url must not point at a loopback, private or link-local address"),
+		},
+		{
+			name: "Failure::cloud metadata address",
+			give: model.NewWebhook{UserId: "123", Url: "http://169.254.169.254/latest/meta-data", Events: []string{model.EventTransactionCreated}},
+			setup: func() datasource.DataSourceI {
+				return mock.NewMockDataSourceI(mockCtrl)
+			},
+			want: invalid("url must not point at a loopback, private or link-local address"),
+		},
+		{
+			name: "Failure::private address",
+			give: model.NewWebhook{UserId: "123", Url: "https://[fd00::1]/hook", Events: []string{model.EventTransactionCreated}},
+			setup: func() datasource.DataSourceI {
+				return mock.NewMockDataSourceI(mockCtrl)
+			},
+			want: invalid("url must not point at a loopback, private or link-local address"),
+		},
+		{
+			name: "Failure::no events",
+			give: model.NewWebhook{UserId: "123", Url: "https://example.com/hook"},
+			setup: func() datasource.DataSourceI {
+				return mock.NewMockDataSourceI(mockCtrl)
+			},
+			want: invalid("events are required"),
+		},
+		{
+			name: "Failure::unknown event",
+			give: model.NewWebhook{UserId: "123", Url: "https://example.com/hook", Events: []string{"transaction.deleted"}},
+			setup: func() datasource.DataSourceI {
+				return mock.NewMockDataSourceI(mockCtrl)
+			},
+			want: invalid(`unknown event "transaction.deleted"`),
+		},
+		{
+			name: "Failure::insert error",
+			give: newWebhook,
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().InsertWebhook(gomock.Any()).Times(1).Return(errors.New("error"))
+				return mockDs
+			},
+			want: func(resp *respModel.Response) {
+				diff := testutil.Diff(resp, &respModel.Response{Status: http.StatusInternalServerError, Message: codes.GetErr(codes.ErrWebhook)})
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := NewTransactionManagementServiceLogic(tt.setup(), config.ExternalSvc{})
+			tt.want(rec.CreateWebhook(tt.give))
+		})
+	}
+}
+
+func TestTransactionManagementServiceLogic_GetWebhooks(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockDs := mock.NewMockDataSourceI(mockCtrl)
+	mockDs.EXPECT().GetWebhooks("123").Times(1).Return([]model.Webhook{{WebhookId: "w1", Secret: "whsec_abc"}}, nil)
+	mockDs.EXPECT().GetWebhooks("123").Times(1).Return(nil, errors.New("error"))
+	rec := NewTransactionManagementServiceLogic(mockDs, config.ExternalSvc{})
+	diff := testutil.Diff(rec.GetWebhooks("123"), &respModel.Response{Status: http.StatusOK, Message: "SUCCESS", Data: []model.Webhook{{WebhookId: "w1"}}})
+	if diff != "" {
+		t.Error(testutil.Callers(), diff)
+	}
+	diff = testutil.Diff(rec.GetWebhooks("123"), &respModel.Response{Status: http.StatusInternalServerError, Message: codes.GetErr(codes.ErrGetWebhook)})
+	if diff != "" {
+		t.Error(testutil.Callers(), diff)
+	}
+}
+
+func TestTransactionManagementServiceLogic_DeleteWebhook(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	webhook := func(status string) *model.Webhook {
+		return &model.Webhook{WebhookId: "w1", UserId: "123", Secret: "whsec_abc", Status: status}
+	}
+	tests := []struct {
+		name  string
+		setup func() datasource.DataSourceI
+		want  respModel.Response
+	}{
+		{
+			name: "Success::webhook disabled",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetWebhook("w1").Times(1).Return(webhook(model.WebhookActive), nil)
+				mockDs.EXPECT().UpdateWebhookStatus("w1", model.WebhookDisabled).Times(1).Return(nil)
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusOK, Message: "SUCCESS", Data: model.Webhook{WebhookId: "w1", UserId: "123", Status: model.WebhookDisabled}},
+		},
+		{
+			name: "Success::already disabled",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetWebhook("w1").Times(1).Return(webhook(model.WebhookDisabled), nil)
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusOK, Message: "SUCCESS", Data: model.Webhook{WebhookId: "w1", UserId: "123", Status: model.WebhookDisabled}},
+		},
+		{
+			name: "Failure::webhook of another user",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetWebhook("w1").Times(1).Return(&model.Webhook{WebhookId: "w1", UserId: "456"}, nil)
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusNotFound, Message: codes.GetErr(codes.ErrNoWebhook)},
+		},
+		{
+			name: "Failure::webhook error",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetWebhook("w1").Times(1).Return(nil, errors.New("error"))
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusInternalServerError, Message: codes.GetErr(codes.ErrGetWebhook)},
+		},
+		{
+			name: "Failure::update error",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetWebhook("w1").Times(1).Return(webhook(model.WebhookActive), nil)
+				mockDs.EXPECT().UpdateWebhookStatus("w1", model.WebhookDisabled).Times(1).Return(errors.New("error"))
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusInternalServerError, Message: codes.GetErr(codes.ErrWebhook)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := NewTransactionManagementServiceLogic(tt.setup(), config.ExternalSvc{})
+			diff := testutil.Diff(rec.DeleteWebhook("w1", "123"), &tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestTransactionManagementServiceLogic_GetWebhookDeliveries(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	webhook := &model.Webhook{WebhookId: "w1", UserId: "123", Status: model.WebhookActive}
+	tests := []struct {
+		name  string
+		setup func() datasource.DataSourceI
+		want  respModel.Response
+	}{
+		{
+			name: "Success::deliveries",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetWebhook("w1").Times(1).Return(webhook, nil)
+				mockDs.EXPECT().GetWebhookDeliveries("w1", webhookDeliveriesShown).Times(1).Return([]model.WebhookDelivery{{DeliveryId: "d1", WebhookId: "w1"}}, nil)
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusOK, Message: "SUCCESS", Data: []model.WebhookDelivery{{DeliveryId: "d1", WebhookId: "w1"}}},
+		},
+		{
+			name: "Failure::no webhook",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetWebhook("w1").Times(1).Return(nil, nil)
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusNotFound, Message: codes.GetErr(codes.ErrNoWebhook)},
+		},
+		{
+			name: "Failure::deliveries error",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetWebhook("w1").Times(1).Return(webhook, nil)
+				mockDs.EXPECT().GetWebhookDeliveries("w1", webhookDeliveriesShown).Times(1).Return(nil, errors.New("error"))
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusInternalServerError, Message: codes.GetErr(codes.ErrGetWebhook)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := NewTransactionManagementServiceLogic(tt.setup(), config.ExternalSvc{})
+			diff := testutil.Diff(rec.GetWebhookDeliveries("w1", "123"), &tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestTransactionManagementServiceLogic_RedeliverWebhook(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	active := &model.Webhook{WebhookId: "w1", UserId: "123", Status: model.WebhookActive}
+	delivery := func() *model.WebhookDelivery {
+		return &model.WebhookDelivery{DeliveryId: "d1", WebhookId: "w1", Status: model.WebhookDeliveryFailed, Attempts: 8, ResponseStatus: 500, LastError: "responded with status 500"}
+	}
+	redelivered := model.WebhookDelivery{DeliveryId: "d1", WebhookId: "w1", Status: model.WebhookDeliveryPending, ResponseStatus: 500, LastError: "responded with status 500"}
+	tests := []struct {
+		name  string
+		setup func() datasource.DataSourceI
+		want  respModel.Response
+	}{
+		{
+			name: "Success::failed delivery queued again",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetWebhook("w1").Times(1).Return(active, nil)
+				mockDs.EXPECT().GetWebhookDelivery("d1").Times(1).Return(delivery(), nil)
+				mockDs.EXPECT().UpdateWebhookDelivery(redelivered).Times(1).Return(nil)
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusAccepted, Message: "SUCCESS", Data: redelivered},
+		},
+		{
+			name: "Failure::delivery of another webhook",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetWebhook("w1").Times(1).Return(active, nil)
+				mockDs.EXPECT().GetWebhookDelivery("d1").Times(1).Return(&model.WebhookDelivery{DeliveryId: "d1", WebhookId: "w2"}, nil)
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusNotFound, Message: codes.GetErr(codes.ErrNoWebhookDelivery)},
+		},
+		{
+			name: "Failure::no delivery",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetWebhook("w1").Times(1).Return(active, nil)
+				mockDs.EXPECT().GetWebhookDelivery("d1").Times(1).Return(nil, nil)
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusNotFound, Message: codes.GetErr(codes.ErrNoWebhookDelivery)},
+		},
+		{
+			name: "Failure::webhook of another user",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetWebhook("w1").Times(1).Return(&model.Webhook{WebhookId: "w1", UserId: "456", Status: model.WebhookActive}, nil)
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusNotFound, Message: codes.GetErr(codes.ErrNoWebhook)},
+		},
+		{
+			name: "Failure::webhook disabled",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetWebhook("w1").Times(1).Return(&model.Webhook{WebhookId: "w1", UserId: "123", Status: model.WebhookDisabled}, nil)
+				mockDs.EXPECT().GetWebhookDelivery("d1").Times(1).Return(delivery(), nil)
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusConflict, Message: codes.GetErr(codes.ErrWebhookDisabled)},
+		},
+		{
+			name: "Failure::delivery error",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetWebhook("w1").Times(1).Return(active, nil)
+				mockDs.EXPECT().GetWebhookDelivery("d1").Times(1).Return(nil, errors.New("error"))
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusInternalServerError, Message: codes.GetErr(codes.ErrGetWebhook)},
+		},
+		{
+			name: "Failure::update error",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().GetWebhook("w1").Times(1).Return(active, nil)
+				mockDs.EXPECT().GetWebhookDelivery("d1").Times(1).Return(delivery(), nil)
+				mockDs.EXPECT().UpdateWebhookDelivery(redelivered).Times(1).Return(errors.New("error"))
+				return mockDs
+			},
+			want: respModel.Response{Status: http.StatusInternalServerError, Message: codes.GetErr(codes.ErrWebhook)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := NewTransactionManagementServiceLogic(tt.setup(), config.ExternalSvc{})
+			diff := testutil.Diff(rec.RedeliverWebhook("w1", "d1", "123"), &tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
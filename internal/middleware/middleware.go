@@ -1,6 +1,9 @@
 package middleware
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"github.com/PereRohit/util/log"
@@ -10,18 +13,24 @@ import (
 	svcCfg "github.com/vatsal278/TransactionManagementService/internal/config"
 	"github.com/vatsal278/TransactionManagementService/internal/model"
 	"github.com/vatsal278/TransactionManagementService/internal/repo/authentication"
+	"github.com/vatsal278/TransactionManagementService/internal/repo/datasource"
 	"github.com/vatsal278/TransactionManagementService/pkg/session"
 	"github.com/vatsal278/go-redis-cache"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strings"
 )
 
 // TransactionMgmtMiddleware is a middleware struct that includes a configuration object, a JWT service,
-// and a Redis cacher. It is responsible for handling authentication and caching for requests.
+// a Redis cacher and the datasource holding idempotency keys. It is responsible for handling authentication, caching
+// and idempotency for requests.
 type TransactionMgmtMiddleware struct {
 	cfg    *svcCfg.Config
 	jwt    authentication.JWTService
 	cacher redis.Cacher
+	ds     datasource.DataSourceI
 }
 
 // respWriterWithStatus is a wrapper for http.ResponseWriter that includes the status code and response
@@ -45,11 +54,12 @@ func (w *respWriterWithStatus) Write(d []byte) (int, error) {
 }
 
 // NewTransactionMgmtMiddleware is a constructor function that returns a new instance of the TransactionMgmtMiddleware struct.
-func NewTransactionMgmtMiddleware(cfg *svcCfg.SvcConfig) *TransactionMgmtMiddleware {
+func NewTransactionMgmtMiddleware(cfg *svcCfg.SvcConfig, ds datasource.DataSourceI) *TransactionMgmtMiddleware {
 	return &TransactionMgmtMiddleware{
 		cfg:    cfg.Cfg,
 		jwt:    cfg.JwtSvc.JwtSvc,
 		cacher: cfg.Cacher.Cacher,
+		ds:     ds,
 	}
 }
 
@@ -102,16 +112,145 @@ func (u TransactionMgmtMiddleware) ExtractUser(next http.Handler) http.Handler {
 	})
 }
 
+// maxIdempotencyKeyLen is the longest Idempotency-Key accepted
+const maxIdempotencyKeyLen = 255
+
+// requestFingerprint hashes the parts of a request that must match for a retry to reuse its Idempotency-Key. The
+// query is encoded with sorted keys so the order of its parameters does not matter.
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.Path + "?" + r.URL.Query().Encode() + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Idempotency makes retries of a request carrying an Idempotency-Key header replay the response of the first
+// request instead of running the handler again. Keys are scoped to the session user and remembered for the
+// configured window. Reusing a key with a different query or body is rejected with 422, and a retry arriving while the first
+// request is still running is rejected with 409. Responses with a 5xx status are not remembered, so those can be
+// retried with the same key. Keys are reserved with an insert on a unique key, so of concurrent requests with the
+// same key only one runs. A body larger than the configured max_bytes is refused with 413 when a key is sent, as
+// it is held in memory to fingerprint it.
+func (t TransactionMgmtMiddleware) Idempotency(next http.Handler) http.Handler {
+	return t.IdempotencyLimit(t.cfg.Idempotency.MaxBytes)(next)
+}
+
+// IdempotencyLimit works as Idempotency but refuses bodies larger than maxBytes, for routes taking larger bodies
+// such as file imports.
+func (t TransactionMgmtMiddleware) IdempotencyLimit(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return t.idempotency(next, maxBytes)
+	}
+}
+
+func (t TransactionMgmtMiddleware) idempotency(next http.Handler, maxBytes int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idempotencyKey := r.Header.Get("Idempotency-Key")
+		if idempotencyKey == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(idempotencyKey) > maxIdempotencyKeyLen {
+			response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrIdempotencyKey), nil)
+			return
+		}
+		sessionStruct := session.GetSession(r.Context())
+		session, ok := sessionStruct.(model.SessionStruct)
+		if !ok {
+			response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrAssertUserid), nil)
+			return
+		}
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxBytes+1))
+		if err != nil {
+			log.Error(err)
+			response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrReadingReqBody), nil)
+			return
+		}
+		if int64(len(body)) > maxBytes {
+			response.ToJson(w, http.StatusRequestEntityTooLarge, codes.GetErr(codes.ErrRequestTooLarge), nil)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		// Reserve the key so concurrent retries do not run the handler as well
+		record := model.IdempotencyRecord{UserId: session.UserId, IdempotencyKey: idempotencyKey, Fingerprint: requestFingerprint(r, body)}
+		reserved, err := t.ds.ReserveIdempotencyKey(record, t.cfg.Idempotency.Time)
+		if err != nil {
+			log.Error(err)
+			response.ToJson(w, http.StatusInternalServerError, codes.GetErr(codes.ErrIdempotencyStore), nil)
+			return
+		}
+
+		// Replay or reject when the key is already known
+		if !reserved {
+			known, err := t.ds.GetIdempotencyKey(session.UserId, idempotencyKey)
+			if err != nil {
+				log.Error(err)
+				response.ToJson(w, http.StatusInternalServerError, codes.GetErr(codes.ErrIdempotencyStore), nil)
+				return
+			}
+			switch {
+			case known != nil && known.Fingerprint != record.Fingerprint:
+				response.ToJson(w, http.StatusUnprocessableEntity, codes.GetErr(codes.ErrIdempotencyMismatch), nil)
+				return
+			case known == nil || !known.Completed:
+				// A key released after a server error since the reservation failed can be retried as well
+				response.ToJson(w, http.StatusConflict, codes.GetErr(codes.ErrIdempotencyInProgress), nil)
+				return
+			}
+			w.Header().Set("Content-Type", known.ContentType)
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(known.Status)
+			w.Write([]byte(known.Response))
+			return
+		}
+
+		hijackedWriter := &respWriterWithStatus{-1, "", w}
+		next.ServeHTTP(hijackedWriter, r)
+		if hijackedWriter.status == -1 {
+			hijackedWriter.status = http.StatusOK
+		}
+
+		// Release the key after server errors so the request can be retried
+		if hijackedWriter.status >= 500 {
+			err = t.ds.ReleaseIdempotencyKey(session.UserId, idempotencyKey)
+			if err != nil {
+				log.Error(err)
+			}
+			return
+		}
+		record.Completed = true
+		record.CacheResponse = model.CacheResponse{
+			Status:      hijackedWriter.status,
+			Response:    hijackedWriter.response,
+			ContentType: w.Header().Get("Content-Type"),
+		}
+		err = t.ds.CompleteIdempotencyKey(record)
+		if err != nil {
+			log.Error(err)
+		}
+	})
+}
+
+// cacheKey returns the URL with its query parameters sorted by name, so requests differing only in parameter
+// order share a cache entry. The order of repeated values, such as sort keys, is kept as it is significant.
+func cacheKey(u *url.URL) string {
+	normalized := *u
+	normalized.RawQuery = u.Query().Encode()
+	return normalized.String()
+}
+
 // Cacher returns a middleware function that can be used to cache HTTP responses using the provided cache implementation.
 // The middleware checks the cache for an existing response for the current request URL and, if found, writes it to the response writer and returns without invoking the next handler.
 // Otherwise, the middleware calls the next handler to generate a response and caches the response for future requests.
-// The middleware also optionally adds the user ID from the session to the cache key if requireAuth is true.
+// The middleware also optionally adds the user ID from the session to the cache key if requireAuth is true, along with
+// the user's cache generation so responses cached before the user's last write are not served.
 func (t TransactionMgmtMiddleware) Cacher(requireAuth bool) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			var key string
 			var cacheResponse model.CacheResponse
-			key = fmt.Sprint(r.URL.String())
+			key = cacheKey(r.URL)
 
 			// If authentication is required, append the user ID to the cache key
 			if requireAuth != false {
@@ -122,6 +261,10 @@ func (t TransactionMgmtMiddleware) Cacher(requireAuth bool) func(http.Handler) h
 					return
 				}
 				key = fmt.Sprint(key + "/auth/" + session.UserId)
+				generation, err := t.cacher.Get(model.CacheGenerationKey(session.UserId))
+				if err == nil && len(generation) > 0 {
+					key = fmt.Sprint(key + "/" + string(generation))
+				}
 			}
 
 			// Check the cache for an existing response
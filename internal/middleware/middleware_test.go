@@ -18,6 +18,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -391,7 +394,7 @@ func TestTransactionMgmtMiddleware_ExtractUser(t *testing.T) {
 					JwtSvc: jwt,
 				},
 				Cfg: &config.Config{},
-			})
+			}, nil)
 			var hit bool
 			testFunc := test(&hit)
 			x := middleware.ExtractUser(testFunc)
@@ -419,6 +422,7 @@ func TestTransactionMgmtMiddleware_Cacher(t *testing.T) {
 				req := httptest.NewRequest(http.MethodGet, "http://localhost:80", nil)
 				ctx := session.SetSession(req.Context(), model2.SessionStruct{UserId: "123"})
 				mockCacher := redisMock.NewMockCacher(mockCtrl)
+				mockCacher.EXPECT().Get(model2.CacheGenerationKey("123")).Return(nil, errors.New("error"))
 				cacheResponse := model2.CacheResponse{Status: http.StatusOK, Response: "ok", ContentType: "application/json"}
 				b, _ := json.Marshal(cacheResponse)
 				mockCacher.EXPECT().Get("http://localhost:80/auth/123").Return(b, nil)
@@ -477,6 +481,7 @@ func TestTransactionMgmtMiddleware_Cacher(t *testing.T) {
 				req := httptest.NewRequest(http.MethodGet, "http://localhost:80", nil)
 				ctx := session.SetSession(req.Context(), model2.SessionStruct{UserId: "123"})
 				mockCacher := redisMock.NewMockCacher(mockCtrl)
+				mockCacher.EXPECT().Get(model2.CacheGenerationKey("123")).Return(nil, errors.New("error"))
 				mockCacher.EXPECT().Get("http://localhost:80/auth/123").Return([]byte("123"), nil)
 				return req.WithContext(ctx), mockCacher
 			},
@@ -510,6 +515,7 @@ func TestTransactionMgmtMiddleware_Cacher(t *testing.T) {
 				req := httptest.NewRequest(http.MethodGet, "http://localhost:80", nil)
 				ctx := session.SetSession(req.Context(), model2.SessionStruct{UserId: "123"})
 				mockCacher := redisMock.NewMockCacher(mockCtrl)
+				mockCacher.EXPECT().Get(model2.CacheGenerationKey("123")).Return(nil, errors.New("error"))
 				mockCacher.EXPECT().Get("http://localhost:80/auth/123").Return(nil, errors.New("error"))
 				mockCacher.EXPECT().Set("http://localhost:80/auth/123", []byte("{\"Status\":200,\"Response\":\"{\\\"status\\\":200,\\\"message\\\":\\\"passed\\\",\\\"data\\\":\\\"123\\\"}\\n\",\"ContentType\":\"application/json\"}"), time.Minute)
 				return req.WithContext(ctx), mockCacher
@@ -531,6 +537,45 @@ func TestTransactionMgmtMiddleware_Cacher(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:   "SUCCESS::Cacher::Query parameters normalized in key",
+			config: config.Config{Cache: config.CacheCfg{Time: time.Minute}},
+			setupFunc: func() (*http.Request, *redisMock.MockCacher) {
+
+				req := httptest.NewRequest(http.MethodGet, "http://localhost:80/transactions?sort=amount:desc&page=2&sort=created_at", nil)
+				ctx := session.SetSession(req.Context(), model2.SessionStruct{UserId: "123"})
+				mockCacher := redisMock.NewMockCacher(mockCtrl)
+				mockCacher.EXPECT().Get(model2.CacheGenerationKey("123")).Return(nil, errors.New("error"))
+				key := "http://localhost:80/transactions?page=2&sort=amount%3Adesc&sort=created_at/auth/123"
+				mockCacher.EXPECT().Get(key).Return(nil, errors.New("error"))
+				mockCacher.EXPECT().Set(key, gomock.Any(), time.Minute)
+				return req.WithContext(ctx), mockCacher
+			},
+			validator: func(res *httptest.ResponseRecorder, hit *bool) {
+				if *hit != true {
+					t.Errorf("Want: %v, Got: %v", true, *hit)
+				}
+			},
+		},
+		{
+			name:   "SUCCESS::Cacher::Generation in key",
+			config: config.Config{Cache: config.CacheCfg{Time: time.Minute}},
+			setupFunc: func() (*http.Request, *redisMock.MockCacher) {
+
+				req := httptest.NewRequest(http.MethodGet, "http://localhost:80", nil)
+				ctx := session.SetSession(req.Context(), model2.SessionStruct{UserId: "123"})
+				mockCacher := redisMock.NewMockCacher(mockCtrl)
+				mockCacher.EXPECT().Get(model2.CacheGenerationKey("123")).Return([]byte("42"), nil)
+				mockCacher.EXPECT().Get("http://localhost:80/auth/123/42").Return(nil, errors.New("error"))
+				mockCacher.EXPECT().Set("http://localhost:80/auth/123/42", gomock.Any(), time.Minute)
+				return req.WithContext(ctx), mockCacher
+			},
+			validator: func(res *httptest.ResponseRecorder, hit *bool) {
+				if *hit != true {
+					t.Errorf("Want: %v, Got: %v", true, *hit)
+				}
+			},
+		},
 		{
 			name:   "Failure::Cacher::Normal Response::Redis fail",
 			config: config.Config{Cache: config.CacheCfg{Time: time.Minute}},
@@ -539,6 +584,7 @@ func TestTransactionMgmtMiddleware_Cacher(t *testing.T) {
 				req := httptest.NewRequest(http.MethodGet, "http://localhost:80", nil)
 				ctx := session.SetSession(req.Context(), model2.SessionStruct{UserId: "123"})
 				mockCacher := redisMock.NewMockCacher(mockCtrl)
+				mockCacher.EXPECT().Get(model2.CacheGenerationKey("123")).Return(nil, errors.New("error"))
 				mockCacher.EXPECT().Get("http://localhost:80/auth/123").Return(nil, errors.New("error"))
 				mockCacher.EXPECT().Set("http://localhost:80/auth/123", []byte("{\"Status\":200,\"Response\":\"{\\\"status\\\":200,\\\"message\\\":\\\"passed\\\",\\\"data\\\":\\\"123\\\"}\\n\",\"ContentType\":\"application/json\"}"), time.Minute).Return(errors.New("error"))
 				return req.WithContext(ctx), mockCacher
@@ -579,3 +625,290 @@ func TestTransactionMgmtMiddleware_Cacher(t *testing.T) {
 		})
 	}
 }
+
+func TestTransactionMgmtMiddleware_Idempotency(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	body := `{"amount":100}`
+	newRequest := func(idempotencyKey string, body string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "http://localhost:80/transactions", strings.NewReader(body))
+		if idempotencyKey != "" {
+			req.Header.Set("Idempotency-Key", idempotencyKey)
+		}
+		ctx := session.SetSession(req.Context(), model2.SessionStruct{UserId: "123"})
+		return req.WithContext(ctx)
+	}
+	fingerprint := requestFingerprint(newRequest("abc", body), []byte(body))
+	reserved := model2.IdempotencyRecord{UserId: "123", IdempotencyKey: "abc", Fingerprint: fingerprint}
+	passed := "{\"status\":200,\"message\":\"passed\",\"data\":\"123\"}\n"
+	tests := []struct {
+		name       string
+		setupFunc  func() (*http.Request, *mock.MockDataSourceI)
+		handler    func(*bool) http.HandlerFunc
+		wantStatus int
+		wantHit    bool
+		validator  func(*httptest.ResponseRecorder)
+	}{
+		{
+			name: "SUCCESS::no key",
+			setupFunc: func() (*http.Request, *mock.MockDataSourceI) {
+				return newRequest("", body), mock.NewMockDataSourceI(mockCtrl)
+			},
+			wantStatus: http.StatusOK,
+			wantHit:    true,
+		},
+		{
+			name: "SUCCESS::first request stored",
+			setupFunc: func() (*http.Request, *mock.MockDataSourceI) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				completed := reserved
+				completed.Completed = true
+				completed.CacheResponse = model2.CacheResponse{Status: http.StatusOK, Response: passed, ContentType: "application/json"}
+				gomock.InOrder(
+					mockDs.EXPECT().ReserveIdempotencyKey(reserved, time.Hour).Return(true, nil),
+					mockDs.EXPECT().CompleteIdempotencyKey(completed).Return(nil),
+				)
+				return newRequest("abc", body), mockDs
+			},
+			handler: func(hit *bool) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					by, _ := ioutil.ReadAll(r.Body)
+					if string(by) != body {
+						t.Errorf("Want: %v, Got: %v", body, string(by))
+					}
+					test(hit)(w, r)
+				}
+			},
+			wantStatus: http.StatusOK,
+			wantHit:    true,
+		},
+		{
+			name: "SUCCESS::retry replayed",
+			setupFunc: func() (*http.Request, *mock.MockDataSourceI) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().ReserveIdempotencyKey(reserved, time.Hour).Return(false, nil)
+				mockDs.EXPECT().GetIdempotencyKey("123", "abc").Return(&model2.IdempotencyRecord{UserId: "123", IdempotencyKey: "abc", Fingerprint: fingerprint, Completed: true, CacheResponse: model2.CacheResponse{Status: http.StatusCreated, Response: "replayed", ContentType: "application/json"}}, nil)
+				return newRequest("abc", body), mockDs
+			},
+			wantStatus: http.StatusCreated,
+			validator: func(res *httptest.ResponseRecorder) {
+				if res.Body.String() != "replayed" || res.Header().Get("Idempotent-Replayed") != "true" {
+					t.Errorf("Want: %v, Got: %v", "replayed", res.Body.String())
+				}
+			},
+		},
+		{
+			name: "SUCCESS::server error releases key",
+			setupFunc: func() (*http.Request, *mock.MockDataSourceI) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().ReserveIdempotencyKey(reserved, time.Hour).Return(true, nil)
+				mockDs.EXPECT().ReleaseIdempotencyKey("123", "abc").Return(nil)
+				return newRequest("abc", body), mockDs
+			},
+			handler: func(hit *bool) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					*hit = true
+					response.ToJson(w, http.StatusInternalServerError, "failed", nil)
+				}
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantHit:    true,
+		},
+		{
+			name: "Failure::different body",
+			setupFunc: func() (*http.Request, *mock.MockDataSourceI) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().ReserveIdempotencyKey(gomock.Any(), time.Hour).Return(false, nil)
+				mockDs.EXPECT().GetIdempotencyKey("123", "abc").Return(&model2.IdempotencyRecord{UserId: "123", IdempotencyKey: "abc", Fingerprint: fingerprint, Completed: true}, nil)
+				return newRequest("abc", `{"amount":200}`), mockDs
+			},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Failure::different query",
+			setupFunc: func() (*http.Request, *mock.MockDataSourceI) {
+				dryRun := newRequest("abc", body)
+				dryRun.URL.RawQuery = "dry_run=true"
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().ReserveIdempotencyKey(gomock.Any(), time.Hour).Return(false, nil)
+				mockDs.EXPECT().GetIdempotencyKey("123", "abc").Return(&model2.IdempotencyRecord{UserId: "123", IdempotencyKey: "abc", Fingerprint: requestFingerprint(dryRun, []byte(body)), Completed: true}, nil)
+				req := newRequest("abc", body)
+				req.URL.RawQuery = "dry_run=false"
+				return req, mockDs
+			},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "SUCCESS::query order ignored",
+			setupFunc: func() (*http.Request, *mock.MockDataSourceI) {
+				stored := newRequest("abc", body)
+				stored.URL.RawQuery = "a=1&dry_run=true"
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().ReserveIdempotencyKey(gomock.Any(), time.Hour).Return(false, nil)
+				mockDs.EXPECT().GetIdempotencyKey("123", "abc").Return(&model2.IdempotencyRecord{UserId: "123", IdempotencyKey: "abc", Fingerprint: requestFingerprint(stored, []byte(body)), Completed: true, CacheResponse: model2.CacheResponse{Status: http.StatusCreated, Response: "replayed", ContentType: "application/json"}}, nil)
+				req := newRequest("abc", body)
+				req.URL.RawQuery = "dry_run=true&a=1"
+				return req, mockDs
+			},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name: "Failure::in progress",
+			setupFunc: func() (*http.Request, *mock.MockDataSourceI) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().ReserveIdempotencyKey(reserved, time.Hour).Return(false, nil)
+				mockDs.EXPECT().GetIdempotencyKey("123", "abc").Return(&reserved, nil)
+				return newRequest("abc", body), mockDs
+			},
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name: "Failure::released while reserving",
+			setupFunc: func() (*http.Request, *mock.MockDataSourceI) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().ReserveIdempotencyKey(reserved, time.Hour).Return(false, nil)
+				mockDs.EXPECT().GetIdempotencyKey("123", "abc").Return(nil, nil)
+				return newRequest("abc", body), mockDs
+			},
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name: "Failure::key too long",
+			setupFunc: func() (*http.Request, *mock.MockDataSourceI) {
+				return newRequest(strings.Repeat("a", 256), body), mock.NewMockDataSourceI(mockCtrl)
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Failure::body too large",
+			setupFunc: func() (*http.Request, *mock.MockDataSourceI) {
+				return newRequest("abc", strings.Repeat("a", 1<<10+1)), mock.NewMockDataSourceI(mockCtrl)
+			},
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name: "Failure::no session",
+			setupFunc: func() (*http.Request, *mock.MockDataSourceI) {
+				req := httptest.NewRequest(http.MethodPost, "http://localhost:80/transactions", strings.NewReader(body))
+				req.Header.Set("Idempotency-Key", "abc")
+				return req, mock.NewMockDataSourceI(mockCtrl)
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Failure::get key",
+			setupFunc: func() (*http.Request, *mock.MockDataSourceI) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().ReserveIdempotencyKey(reserved, time.Hour).Return(false, nil)
+				mockDs.EXPECT().GetIdempotencyKey("123", "abc").Return(nil, errors.New("error"))
+				return newRequest("abc", body), mockDs
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name: "Failure::reserve key",
+			setupFunc: func() (*http.Request, *mock.MockDataSourceI) {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				mockDs.EXPECT().ReserveIdempotencyKey(reserved, time.Hour).Return(false, errors.New("error"))
+				return newRequest("abc", body), mockDs
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	// to execute the tests in the table
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := httptest.NewRecorder()
+			req, ds := tt.setupFunc()
+			middleware := TransactionMgmtMiddleware{
+				ds:  ds,
+				cfg: &config.Config{Idempotency: config.IdempotencyCfg{Time: time.Hour, MaxBytes: 1 << 10}}}
+			hit := false
+			handler := test(&hit)
+			if tt.handler != nil {
+				handler = tt.handler(&hit)
+			}
+			middleware.Idempotency(handler).ServeHTTP(res, req)
+
+			if res.Code != tt.wantStatus {
+				t.Errorf("Want: %v, Got: %v", tt.wantStatus, res.Code)
+			}
+			if hit != tt.wantHit {
+				t.Errorf("Want: %v, Got: %v", tt.wantHit, hit)
+			}
+			if tt.validator != nil {
+				tt.validator(res)
+			}
+		})
+	}
+}
+
+func TestTransactionMgmtMiddleware_IdempotencyConcurrent(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	const requests = 10
+
+	// The datasource behaves like the unique key of the idempotency table: only the first insert of a key succeeds
+	var mu sync.Mutex
+	keys := map[string]model2.IdempotencyRecord{}
+	mockDs := mock.NewMockDataSourceI(mockCtrl)
+	mockDs.EXPECT().ReserveIdempotencyKey(gomock.Any(), time.Hour).Times(requests).DoAndReturn(func(record model2.IdempotencyRecord, ttl time.Duration) (bool, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if _, ok := keys[record.UserId+"/"+record.IdempotencyKey]; ok {
+			return false, nil
+		}
+		keys[record.UserId+"/"+record.IdempotencyKey] = record
+		return true, nil
+	})
+	mockDs.EXPECT().GetIdempotencyKey("123", "abc").Times(requests - 1).DoAndReturn(func(userId string, idempotencyKey string) (*model2.IdempotencyRecord, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		record := keys[userId+"/"+idempotencyKey]
+		return &record, nil
+	})
+	mockDs.EXPECT().CompleteIdempotencyKey(gomock.Any()).Times(1).Return(nil)
+
+	// The handler holds the first request until every other one has been answered
+	var runs int32
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&runs, 1)
+		<-release
+		response.ToJson(w, http.StatusCreated, "created", nil)
+	})
+	middleware := TransactionMgmtMiddleware{
+		ds:  mockDs,
+		cfg: &config.Config{Idempotency: config.IdempotencyCfg{Time: time.Hour, MaxBytes: 1 << 10}}}
+	h := middleware.Idempotency(handler)
+
+	statuses := make(chan int, requests)
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodPost, "http://localhost:80/transactions", strings.NewReader(`{"amount":100}`))
+			req.Header.Set("Idempotency-Key", "abc")
+			req = req.WithContext(session.SetSession(req.Context(), model2.SessionStruct{UserId: "123"}))
+			res := httptest.NewRecorder()
+			h.ServeHTTP(res, req)
+			statuses <- res.Code
+		}()
+	}
+	for i := 0; i < requests-1; i++ {
+		code := <-statuses
+		if code != http.StatusConflict {
+			t.Errorf("Want: %v, Got: %v", http.StatusConflict, code)
+		}
+	}
+	close(release)
+	wg.Wait()
+	if code := <-statuses; code != http.StatusCreated {
+		t.Errorf("Want: %v, Got: %v", http.StatusCreated, code)
+	}
+	if runs != 1 {
+		t.Errorf("Want: %v, Got: %v", 1, runs)
+	}
+}
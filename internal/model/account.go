@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// Account records the user owning an account number. Accounts are registered by the account service and are the
+// only record of ownership: transactions are accepted from, and account data is shown to, the owner alone.
+type Account struct {
+	AccountNumber int       `json:"account_number"`
+	UserId        string    `json:"user_id"`
+	Currency      string    `json:"currency"` // Currency transfers to the account are credited in
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// AccountSchema represents the database schema for the account table
+const AccountSchema = `
+	(
+		account_number INT NOT NULL PRIMARY KEY,
+		user_id VARCHAR(255) NOT NULL,
+		currency CHAR(3) NOT NULL DEFAULT 'USD',
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_account_user (user_id)
+	);
+`
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// SettledStatuses are the statuses of transactions that moved money and count towards a balance.
+// A reversed transaction still counts, as it is given back by separate compensating transactions.
+var SettledStatuses = []string{StatusApproved, StatusReversed}
+
+// Balance is the balance of an account in one currency
+type Balance struct {
+	Currency string `json:"currency"`
+	Balance  Money  `json:"balance"`
+}
+
+// AccountBalance is the balance of an account at a point in time, with one entry per currency it transacted in
+type AccountBalance struct {
+	AccountNumber int       `json:"account_number"`
+	AsOf          time.Time `json:"as_of"`
+	Balances      []Balance `json:"balances"`
+}
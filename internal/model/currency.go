@@ -0,0 +1,189 @@
+package model
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultCurrency is the currency of transactions created without one, and of those recorded before currencies existed
+const DefaultCurrency = "USD"
+
+// currencyExponents lists the supported ISO 4217 currencies with the number of decimal places of their minor unit.
+// Currencies with more than two decimal places cannot be held by Money and are not supported.
+var currencyExponents = map[string]int{
+	"AED": 2, "AUD": 2, "BRL": 2, "CAD": 2, "CHF": 2, "CNY": 2, "CZK": 2, "DKK": 2, "EUR": 2, "GBP": 2,
+	"HKD": 2, "HUF": 2, "IDR": 2, "ILS": 2, "INR": 2, "JPY": 0, "KRW": 0, "MXN": 2, "MYR": 2, "NOK": 2,
+	"NZD": 2, "PHP": 2, "PLN": 2, "SAR": 2, "SEK": 2, "SGD": 2, "THB": 2, "TRY": 2, "USD": 2, "ZAR": 2,
+}
+
+// CurrencyExponent returns the number of decimal places of the currency's minor unit,
+// and whether the currency is supported at all
+func CurrencyExponent(currency string) (int, bool) {
+	exponent, ok := currencyExponents[currency]
+	return exponent, ok
+}
+
+// FitsCurrency reports whether the amount is a whole number of the currency's minor units
+func (m Money) FitsCurrency(currency string) bool {
+	exponent, ok := CurrencyExponent(currency)
+	if !ok {
+		return false
+	}
+	return m%Money(pow10(2-exponent)) == 0
+}
+
+// pow10 returns 10 to the power of n
+func pow10(n int) int64 {
+	p := int64(1)
+	for i := 0; i < n; i++ {
+		p *= 10
+	}
+	return p
+}
+
+// rateDecimals is the number of decimal places an exchange rate is held to
+const rateDecimals = 8
+
+// Rate is an exact exchange rate held in hundred millionths, matching the DECIMAL(18,8) column it is stored in.
+// Like Money it reads and writes JSON as a plain decimal number and is stored as a decimal string.
+type Rate int64
+
+// ParseRate parses a positive decimal exchange rate such as "1.0825" with at most 8 decimal places
+func ParseRate(s string) (Rate, error) {
+	whole, fraction, hasPoint := strings.Cut(s, ".")
+	if whole == "" || (hasPoint && fraction == "") || !isDigits(whole) || !isDigits(fraction) {
+		return 0, fmt.Errorf("invalid rate %q", s)
+	}
+	if len(fraction) > rateDecimals {
+		return 0, fmt.Errorf("rate %q has more than %d decimal places", s, rateDecimals)
+	}
+	whole = strings.TrimLeft(whole, "0")
+	if len(whole) > 10 {
+		return 0, fmt.Errorf("rate %q is out of range", s)
+	}
+	fraction += strings.Repeat("0", rateDecimals-len(fraction))
+	r, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil || r == 0 {
+		return 0, fmt.Errorf("invalid rate %q", s)
+	}
+	return Rate(r), nil
+}
+
+// String formats the rate without trailing zeros, such as "1.0825"
+func (r Rate) String() string {
+	s := fmt.Sprintf("%d.%08d", int64(r)/pow10(rateDecimals), int64(r)%pow10(rateDecimals))
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+}
+
+// MarshalJSON writes the rate as a JSON number
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalJSON reads the rate from a JSON number, or a string holding one, without going through float64
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	if len(data) >= 2 && data[0] == '"' && data[len(data)-1] == '"' {
+		data = data[1 : len(data)-1]
+	}
+	parsed, err := ParseRate(string(data))
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// Scan reads the rate from a DECIMAL column, where zero stands for no rate
+func (r *Rate) Scan(value interface{}) error {
+	var s string
+	switch v := value.(type) {
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	case int64:
+		s = strconv.FormatInt(v, 10)
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	case nil:
+		*r = 0
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into a rate", value)
+	}
+	if strings.Trim(s, "0.") == "" {
+		*r = 0
+		return nil
+	}
+	parsed, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// Value stores the rate as a decimal string so the database receives it exactly
+func (r Rate) Value() (driver.Value, error) {
+	return r.String(), nil
+}
+
+// Invert returns the rate converting in the opposite direction, rounded to the nearest hundred millionth
+func (r Rate) Invert() Rate {
+	scale := pow10(rateDecimals)
+	inverted := new(big.Int).Mul(big.NewInt(scale), big.NewInt(scale))
+	return Rate(divRound(inverted, big.NewInt(int64(r))).Int64())
+}
+
+// Convert multiplies the amount by the rate, rounding half away from zero to the minor unit of the target currency
+func (m Money) Convert(rate Rate, currency string) (Money, error) {
+	exponent, ok := CurrencyExponent(currency)
+	if !ok {
+		return 0, fmt.Errorf("unsupported currency %q", currency)
+	}
+	// The product is in units of 10^-(2+rateDecimals), scale it down to the currency's minor unit
+	product := new(big.Int).Mul(big.NewInt(int64(m)), big.NewInt(int64(rate)))
+	minor := divRound(product, big.NewInt(pow10(2+rateDecimals-exponent)))
+	converted := new(big.Int).Mul(minor, big.NewInt(pow10(2-exponent)))
+	if !converted.IsInt64() || converted.Int64() > int64(maxMoney) || converted.Int64() < -int64(maxMoney) {
+		return 0, fmt.Errorf("converted amount is out of range")
+	}
+	return Money(converted.Int64()), nil
+}
+
+// divRound divides a by b rounding half away from zero, b must be positive
+func divRound(a *big.Int, b *big.Int) *big.Int {
+	quotient, remainder := new(big.Int).QuoRem(a, b, new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2)).Cmp(b) >= 0 {
+		quotient.Add(quotient, big.NewInt(int64(a.Sign())))
+	}
+	return quotient
+}
+
+// FxRate is the rate converting one unit of the base currency into the quote currency from the time it takes effect
+type FxRate struct {
+	BaseCurrency  string    `json:"base_currency"`
+	QuoteCurrency string    `json:"quote_currency"`
+	Rate          Rate      `json:"rate"`
+	EffectiveAt   time.Time `json:"effective_at"`
+}
+
+// FxRateSchema represents the database schema for the exchange rate table
+const FxRateSchema = `
+	(
+		base_currency CHAR(3) NOT NULL,
+		quote_currency CHAR(3) NOT NULL,
+		rate DECIMAL(18,8) NOT NULL,
+		effective_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (base_currency, quote_currency, effective_at)
+	);
+`
@@ -0,0 +1,146 @@
+package model
+
+import (
+	"encoding/json"
+	"github.com/PereRohit/util/testutil"
+	"testing"
+)
+
+func TestMoney_FitsCurrency(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   Money
+		currency string
+		want     bool
+	}{
+		{name: "cents in USD", amount: 1250, currency: "USD", want: true},
+		{name: "whole yen", amount: 1200, currency: "JPY", want: true},
+		{name: "fraction of a yen", amount: 1250, currency: "JPY", want: false},
+		{name: "unsupported currency", amount: 100, currency: "XYZ", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.amount.FitsCurrency(tt.currency)
+			if got != tt.want {
+				t.Errorf("Want: %v, Got: %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		name    string
+		give    string
+		want    Rate
+		wantErr bool
+	}{
+		{name: "whole", give: "2", want: 200000000},
+		{name: "fraction", give: "0.92", want: 92000000},
+		{name: "eight decimals", give: "1.08250001", want: 108250001},
+		{name: "too precise", give: "0.123456789", wantErr: true},
+		{name: "zero", give: "0", wantErr: true},
+		{name: "negative", give: "-1", wantErr: true},
+		{name: "too large", give: "12345678901", wantErr: true},
+		{name: "not a number", give: "abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRate(tt.give)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			diff := testutil.Diff(got, tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestRate_JSON(t *testing.T) {
+	var got FxRate
+	err := json.Unmarshal([]byte(`{"base_currency":"USD","quote_currency":"EUR","rate":0.9250}`), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := testutil.Diff(got.Rate, Rate(92500000))
+	if diff != "" {
+		t.Error(testutil.Callers(), diff)
+	}
+	data, _ := json.Marshal(got.Rate)
+	diff = testutil.Diff(string(data), "0.925")
+	if diff != "" {
+		t.Error(testutil.Callers(), diff)
+	}
+}
+
+func TestRate_Scan(t *testing.T) {
+	tests := []struct {
+		name    string
+		give    interface{}
+		want    Rate
+		wantErr bool
+	}{
+		{name: "decimal text", give: []byte("1.08250000"), want: 108250000},
+		{name: "zero is no rate", give: []byte("0.00000000"), want: 0},
+		{name: "null", give: nil, want: 0},
+		{name: "unsupported type", give: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Rate
+			err := got.Scan(tt.give)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			diff := testutil.Diff(got, tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestRate_Invert(t *testing.T) {
+	diff := testutil.Diff(Rate(80000000).Invert(), Rate(125000000))
+	if diff != "" {
+		t.Error(testutil.Callers(), diff)
+	}
+	// 1 / 3 is rounded to the nearest hundred millionth
+	diff = testutil.Diff(Rate(300000000).Invert(), Rate(33333333))
+	if diff != "" {
+		t.Error(testutil.Callers(), diff)
+	}
+}
+
+func TestMoney_Convert(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   Money
+		rate     Rate
+		currency string
+		want     Money
+		wantErr  bool
+	}{
+		{name: "to cents", amount: 1000, rate: 92000000, currency: "EUR", want: 920},
+		{name: "half cent rounds up", amount: 333, rate: 150000000, currency: "EUR", want: 500},
+		{name: "below half a cent rounds down", amount: 333, rate: 92000000, currency: "EUR", want: 306},
+		{name: "half yen rounds up", amount: 1000, rate: 14925000000, currency: "JPY", want: 149300},
+		{name: "negative rounds away from zero", amount: -1000, rate: 14925000000, currency: "JPY", want: -149300},
+		{name: "unsupported currency", amount: 1000, rate: 100000000, currency: "XYZ", wantErr: true},
+		{name: "out of range", amount: maxMoney, rate: 200000000, currency: "USD", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.amount.Convert(tt.rate, tt.currency)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			diff := testutil.Diff(got, tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
@@ -9,16 +9,25 @@ type ColumnUpdate struct {
 
 // Transaction represents a single transaction for a user's account
 type Transaction struct {
-	UserId        string    `json:"-"` // User ID associated with the transaction (not included in JSON response)
-	AccountNumber int       `json:"account_number"`
-	TransactionId string    `json:"transaction_id"`
-	Amount        float64   `json:"amount"`
-	TransferTo    int       `json:"transfer_to"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
-	Status        string    `json:"status" validate:"required,oneof=approved rejected"`
-	Type          string    `json:"type" validate:"required,oneof=credit debit"`
-	Comment       string    `json:"comment"`
+	UserId          string    `json:"-"` // User ID associated with the transaction (not included in JSON response)
+	AccountNumber   int       `json:"account_number"`
+	TransactionId   string    `json:"transaction_id"`
+	Amount          Money     `json:"amount"`
+	TransferTo      int       `json:"transfer_to"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	Status          string    `json:"status" validate:"required,oneof=pending approved rejected failed reversed review"`
+	Type            string    `json:"type" validate:"required,oneof=credit debit"`
+	Comment         string    `json:"comment"`
+	TransferId      string    `json:"transfer_id,omitempty"`     // Links the debit and credit legs of a transfer
+	ReferenceId     string    `json:"reference_id,omitempty"`    // Transaction a reversal or refund compensates
+	RefundedAmount  Money     `json:"refunded_amount,omitempty"` // Amount already given back by reversals and refunds
+	Currency        string    `json:"currency"`
+	FxRate          Rate      `json:"fx_rate,omitempty"`          // Rate converting the debit leg of a cross-currency transfer into the credit leg
+	CounterAmount   Money     `json:"counter_amount,omitempty"`   // Amount of the other leg of a cross-currency transfer
+	CounterCurrency string    `json:"counter_currency,omitempty"` // Currency of the other leg of a cross-currency transfer
+	RunningBalance  *Money    `json:"running_balance,omitempty"`  // Balance of the account after the transaction, only set on listings asking for it
+	BatchId         string    `json:"batch_id,omitempty"`         // Links the transactions submitted in one batch
 }
 
 // Schema represents the database schema for the transactions table
@@ -33,6 +42,44 @@ const Schema = `
 		updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
 		status VARCHAR(255) NOT NULL,
 		type VARCHAR(255) NOT NULL,
-		comment VARCHAR(255)
+		comment VARCHAR(255),
+		transfer_id VARCHAR(255) NOT NULL DEFAULT '',
+		reference_id VARCHAR(255) NOT NULL DEFAULT '',
+		refunded_amount DECIMAL(18,2) NOT NULL DEFAULT 0.00,
+		currency CHAR(3) NOT NULL DEFAULT 'USD',
+		fx_rate DECIMAL(18,8) NOT NULL DEFAULT 0,
+		counter_amount DECIMAL(18,2) NOT NULL DEFAULT 0.00,
+		counter_currency CHAR(3) NOT NULL DEFAULT '',
+		batch_id VARCHAR(255) NOT NULL DEFAULT '',
+		INDEX idx_user_created (user_id, created_at, transaction_id),
+		INDEX idx_account_created (account_number, created_at, transaction_id),
+		INDEX idx_transfer (transfer_id),
+		INDEX idx_reference (reference_id),
+		INDEX idx_batch (batch_id)
 	);
 `
+
+// Migration adds a column or an index missing from a table created by an earlier version of its schema
+type Migration struct {
+	Column     string // Name of the column added, empty for an index
+	Index      string // Name of the index added, empty for a column
+	Definition string // Definition of the column or index as written in the schema
+}
+
+// SchemaMigrations brings a transactions table created before the columns and indexes of Schema were added up to
+// date. Existing rows take the defaults of the new columns.
+var SchemaMigrations = []Migration{
+	{Column: "transfer_id", Definition: "transfer_id VARCHAR(255) NOT NULL DEFAULT ''"},
+	{Column: "reference_id", Definition: "reference_id VARCHAR(255) NOT NULL DEFAULT ''"},
+	{Column: "refunded_amount", Definition: "refunded_amount DECIMAL(18,2) NOT NULL DEFAULT 0.00"},
+	{Column: "currency", Definition: "currency CHAR(3) NOT NULL DEFAULT 'USD'"},
+	{Column: "fx_rate", Definition: "fx_rate DECIMAL(18,8) NOT NULL DEFAULT 0"},
+	{Column: "counter_amount", Definition: "counter_amount DECIMAL(18,2) NOT NULL DEFAULT 0.00"},
+	{Column: "counter_currency", Definition: "counter_currency CHAR(3) NOT NULL DEFAULT ''"},
+	{Column: "batch_id", Definition: "batch_id VARCHAR(255) NOT NULL DEFAULT ''"},
+	{Index: "idx_user_created", Definition: "INDEX idx_user_created (user_id, created_at, transaction_id)"},
+	{Index: "idx_account_created", Definition: "INDEX idx_account_created (account_number, created_at, transaction_id)"},
+	{Index: "idx_transfer", Definition: "INDEX idx_transfer (transfer_id)"},
+	{Index: "idx_reference", Definition: "INDEX idx_reference (reference_id)"},
+	{Index: "idx_batch", Definition: "INDEX idx_batch (batch_id)"},
+}
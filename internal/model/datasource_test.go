@@ -0,0 +1,29 @@
+package model
+
+import (
+	"github.com/PereRohit/util/validator"
+	"testing"
+)
+
+func TestTransaction_Validate(t *testing.T) {
+	tests := []struct {
+		status  string
+		wantErr bool
+	}{
+		{status: StatusPending},
+		{status: StatusApproved},
+		{status: StatusRejected},
+		{status: StatusFailed},
+		{status: StatusReversed},
+		{status: StatusReview},
+		{status: "unknown", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			err := validator.Validate(Transaction{Status: tt.status, Type: "debit"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package model
+
+// Spending limit windows, each a calendar period in UTC
+const (
+	WindowDaily   = "daily"   // From midnight
+	WindowWeekly  = "weekly"  // From midnight on Monday
+	WindowMonthly = "monthly" // From midnight on the first of the month
+)
+
+// SpendingStatuses are the statuses of debits counting towards spending limits. Pending debits and those held for
+// review count as they may still be approved, reversed ones as they were spent before being given back.
+var SpendingStatuses = []string{StatusPending, StatusReview, StatusApproved, StatusReversed}
+
+// SpendingLockSchema represents the database schema for the table of rows locked to serialize a user's debits
+const SpendingLockSchema = `
+	(
+		user_id VARCHAR(255) NOT NULL PRIMARY KEY
+	);
+`
@@ -0,0 +1,121 @@
+package model
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Money is an exact amount held in minor units, hundredths of the major unit, matching the DECIMAL(18,2) columns.
+// It reads and writes JSON as a plain decimal number and is stored as a decimal string, so amounts never go
+// through float64 on their way between clients, the database and other services.
+type Money int64
+
+// Scale of Money amounts
+const (
+	MinorUnit Money = 1   // Smallest amount that can be represented
+	MajorUnit Money = 100 // One whole unit of the currency
+)
+
+// maxMoney is the largest amount a DECIMAL(18,2) column can hold
+const maxMoney Money = 999999999999999999
+
+// ParseMoney parses a decimal amount such as "12", "-0.5" or "1234.56".
+// Amounts with more than two decimal places, exponents or more digits than the database can hold are rejected.
+func ParseMoney(s string) (Money, error) {
+	digits := strings.TrimPrefix(s, "-")
+	negative := len(digits) != len(s)
+	whole, fraction, hasPoint := strings.Cut(digits, ".")
+	if whole == "" || (hasPoint && fraction == "") || !isDigits(whole) || !isDigits(fraction) {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	if len(fraction) > 2 {
+		return 0, fmt.Errorf("amount %q has more than 2 decimal places", s)
+	}
+	fraction += strings.Repeat("0", 2-len(fraction))
+	whole = strings.TrimLeft(whole, "0")
+	if len(whole) > 16 {
+		return 0, fmt.Errorf("amount %q is out of range", s)
+	}
+	minor, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	if negative {
+		minor = -minor
+	}
+	return Money(minor), nil
+}
+
+// isDigits reports whether s consists of ASCII digits only
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats the amount with two decimal places, such as "12.50" or "-0.05"
+func (m Money) String() string {
+	sign := ""
+	minor := int64(m)
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, minor/100, minor%100)
+}
+
+// MarshalJSON writes the amount as a JSON number with two decimal places
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON reads the amount from a JSON number, or a string holding one, without going through float64
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	if len(data) >= 2 && data[0] == '"' && data[len(data)-1] == '"' {
+		data = data[1 : len(data)-1]
+	}
+	parsed, err := ParseMoney(string(data))
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Scan reads the amount from a DECIMAL column, which the driver returns as text
+func (m *Money) Scan(value interface{}) error {
+	var err error
+	switch v := value.(type) {
+	case []byte:
+		*m, err = ParseMoney(string(v))
+	case string:
+		*m, err = ParseMoney(v)
+	case int64:
+		if v > int64(maxMoney/MajorUnit) || v < -int64(maxMoney/MajorUnit) {
+			return fmt.Errorf("amount %d is out of range", v)
+		}
+		*m = Money(v) * MajorUnit
+	case float64:
+		*m, err = ParseMoney(strconv.FormatFloat(v, 'f', -1, 64))
+	case nil:
+		*m = 0
+	default:
+		return fmt.Errorf("cannot scan %T into an amount", value)
+	}
+	return err
+}
+
+// Value stores the amount as a decimal string so the database receives it exactly
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
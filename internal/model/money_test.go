@@ -0,0 +1,147 @@
+package model
+
+import (
+	"encoding/json"
+	"github.com/PereRohit/util/testutil"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		name    string
+		give    string
+		want    Money
+		wantErr bool
+	}{
+		{name: "whole", give: "12", want: 1200},
+		{name: "one decimal", give: "0.5", want: 50},
+		{name: "two decimals", give: "1234.56", want: 123456},
+		{name: "negative", give: "-0.05", want: -5},
+		{name: "leading zeros", give: "007.10", want: 710},
+		{name: "largest", give: "9999999999999999.99", want: 999999999999999999},
+		{name: "too large", give: "10000000000000000", wantErr: true},
+		{name: "too precise", give: "0.001", wantErr: true},
+		{name: "exponent", give: "1e3", wantErr: true},
+		{name: "empty", give: "", wantErr: true},
+		{name: "dangling point", give: "1.", wantErr: true},
+		{name: "no whole part", give: ".5", wantErr: true},
+		{name: "signed twice", give: "--1", wantErr: true},
+		{name: "not a number", give: "abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMoney(tt.give)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			diff := testutil.Diff(got, tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestMoney_Sum(t *testing.T) {
+	a, _ := ParseMoney("0.1")
+	b, _ := ParseMoney("0.2")
+	c, _ := ParseMoney("0.3")
+	if a+b != c {
+		t.Errorf("Want: %v, Got: %v", c, a+b)
+	}
+	large, _ := ParseMoney("9999999999999999.98")
+	diff := testutil.Diff((large + MinorUnit).String(), "9999999999999999.99")
+	if diff != "" {
+		t.Error(testutil.Callers(), diff)
+	}
+}
+
+func TestMoney_JSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		give    string
+		want    Money
+		wantOut string
+		wantErr bool
+	}{
+		{name: "number", give: `{"amount":0.3}`, want: 30, wantOut: `{"amount":0.30}`},
+		{name: "string", give: `{"amount":"12.5"}`, want: 1250, wantOut: `{"amount":12.50}`},
+		{name: "large", give: `{"amount":9999999999999999.99}`, want: 999999999999999999, wantOut: `{"amount":9999999999999999.99}`},
+		{name: "negative", give: `{"amount":-1}`, want: -100, wantOut: `{"amount":-1.00}`},
+		{name: "null", give: `{"amount":null}`, want: 0, wantOut: `{"amount":0.00}`},
+		{name: "too precise", give: `{"amount":0.125}`, wantErr: true},
+		{name: "exponent", give: `{"amount":1e2}`, wantErr: true},
+		{name: "boolean", give: `{"amount":true}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got struct {
+				Amount Money `json:"amount"`
+			}
+			err := json.Unmarshal([]byte(tt.give), &got)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			diff := testutil.Diff(got.Amount, tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+			out, err := json.Marshal(got)
+			if err != nil {
+				t.Errorf("Want: %v, Got: %v", nil, err)
+			}
+			diff = testutil.Diff(string(out), tt.wantOut)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestMoney_Scan(t *testing.T) {
+	tests := []struct {
+		name    string
+		give    interface{}
+		want    Money
+		wantErr bool
+	}{
+		{name: "decimal column", give: []byte("1234.56"), want: 123456},
+		{name: "string", give: "0.10", want: 10},
+		{name: "integer", give: int64(1000), want: 100000},
+		{name: "integer out of range", give: int64(1) << 62, wantErr: true},
+		{name: "float", give: 0.1, want: 10},
+		{name: "null", give: nil, want: 0},
+		{name: "too precise", give: []byte("1.234"), wantErr: true},
+		{name: "unsupported", give: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Money
+			err := got.Scan(tt.give)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			diff := testutil.Diff(got, tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestMoney_Value(t *testing.T) {
+	got, err := Money(-5).Value()
+	if err != nil {
+		t.Errorf("Want: %v, Got: %v", nil, err)
+	}
+	diff := testutil.Diff(got, "-0.05")
+	if diff != "" {
+		t.Error(testutil.Callers(), diff)
+	}
+}
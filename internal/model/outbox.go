@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// Outbox message statuses
+const (
+	OutboxPending   = "pending"   // Waiting to be delivered, possibly after failed attempts
+	OutboxDelivered = "delivered" // Accepted by the receiving service
+	OutboxDead      = "dead"      // Gave up after the maximum number of attempts
+)
+
+// EventAccountUpdate is the outbox event applying an approved transaction to the account balance
+const EventAccountUpdate = "account.update"
+
+// OutboxMessage is an event recorded in the same database transaction as the change it describes,
+// and delivered to other services by the outbox dispatcher
+type OutboxMessage struct {
+	Id            int64
+	TransactionId string        // Transaction the event belongs to
+	EventType     string        // Kind of event, decides where the payload is delivered
+	Payload       string        // JSON body sent to the receiving service
+	Status        string        // One of OutboxPending, OutboxDelivered or OutboxDead
+	Attempts      int           // Number of delivery attempts made so far
+	LastError     string        // Error of the latest failed attempt
+	RetryAfter    time.Duration // Delay before the next attempt, used when updating a pending message
+}
+
+// OutboxSchema represents the database schema for the outbox table
+const OutboxSchema = `
+	(
+		id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		transaction_id VARCHAR(255) NOT NULL,
+		event_type VARCHAR(64) NOT NULL,
+		payload TEXT NOT NULL,
+		status VARCHAR(32) NOT NULL DEFAULT 'pending',
+		attempts INT NOT NULL DEFAULT 0,
+		last_error VARCHAR(1024) NOT NULL DEFAULT '',
+		next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+		INDEX idx_outbox_due (status, next_attempt_at)
+	);
+`
@@ -0,0 +1,114 @@
+package model
+
+// Operator is a comparison operator supported by a Filter
+type Operator string
+
+const (
+	OpEq      Operator = "="
+	OpNe      Operator = "!="
+	OpGt      Operator = ">"
+	OpGte     Operator = ">="
+	OpLt      Operator = "<"
+	OpLte     Operator = "<="
+	OpIn      Operator = "IN"
+	OpBetween Operator = "BETWEEN"
+	OpLike    Operator = "LIKE"
+	OpIsNull  Operator = "IS NULL"
+	OpNotNull Operator = "IS NOT NULL"
+)
+
+// Logical operators used to join the children of a Filter group
+const (
+	LogicAnd = "AND"
+	LogicOr  = "OR"
+)
+
+// Filter is a single condition on a column, or a group of filters joined by Logic.
+// A zero Filter matches every row.
+type Filter struct {
+	Column  string      // Column the condition applies to (empty for groups)
+	Op      Operator    // Comparison operator of the condition
+	Value   interface{} // Value bound to the condition, a []interface{} for IN and BETWEEN
+	Logic   string      // LogicAnd or LogicOr, set for groups
+	Filters []Filter    // Children of the group
+}
+
+// OrderBy describes a single sort key
+type OrderBy struct {
+	Column string
+	Desc   bool
+}
+
+// Query describes which transactions to fetch and in what order
+type Query struct {
+	Where     Filter    // Conditions rows must satisfy
+	OrderBy   []OrderBy // Sort keys, applied in order
+	Limit     int       // Maximum rows returned, 0 for no limit
+	Offset    int       // Rows skipped before the first returned row
+	SkipCount bool      // Skip counting the matching rows, the returned count is then 0
+}
+
+// Eq returns a filter matching rows where column equals value
+func Eq(column string, value interface{}) Filter {
+	return Filter{Column: column, Op: OpEq, Value: value}
+}
+
+// Ne returns a filter matching rows where column does not equal value
+func Ne(column string, value interface{}) Filter {
+	return Filter{Column: column, Op: OpNe, Value: value}
+}
+
+// Gt returns a filter matching rows where column is greater than value
+func Gt(column string, value interface{}) Filter {
+	return Filter{Column: column, Op: OpGt, Value: value}
+}
+
+// Gte returns a filter matching rows where column is greater than or equal to value
+func Gte(column string, value interface{}) Filter {
+	return Filter{Column: column, Op: OpGte, Value: value}
+}
+
+// Lt returns a filter matching rows where column is less than value
+func Lt(column string, value interface{}) Filter {
+	return Filter{Column: column, Op: OpLt, Value: value}
+}
+
+// Lte returns a filter matching rows where column is less than or equal to value
+func Lte(column string, value interface{}) Filter {
+	return Filter{Column: column, Op: OpLte, Value: value}
+}
+
+// In returns a filter matching rows where column equals any of values
+func In(column string, values ...interface{}) Filter {
+	return Filter{Column: column, Op: OpIn, Value: values}
+}
+
+// Between returns a filter matching rows where column lies within [from, to]
+func Between(column string, from interface{}, to interface{}) Filter {
+	return Filter{Column: column, Op: OpBetween, Value: []interface{}{from, to}}
+}
+
+// Like returns a filter matching rows where column matches the SQL LIKE pattern
+func Like(column string, pattern string) Filter {
+	return Filter{Column: column, Op: OpLike, Value: pattern}
+}
+
+// IsNull returns a filter matching rows where column is NULL
+func IsNull(column string) Filter {
+	return Filter{Column: column, Op: OpIsNull}
+}
+
+// NotNull returns a filter matching rows where column is not NULL
+func NotNull(column string) Filter {
+	return Filter{Column: column, Op: OpNotNull}
+}
+
+// And returns a group matching rows that satisfy every filter
+func And(filters ...Filter) Filter {
+	return Filter{Logic: LogicAnd, Filters: filters}
+}
+
+// Or returns a group matching rows that satisfy at least one filter
+func Or(filters ...Filter) Filter {
+	return Filter{Logic: LogicOr, Filters: filters}
+}
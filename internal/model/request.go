@@ -1,10 +1,13 @@
 package model
 
+import "time"
+
 // UpdateTransaction is the model for updating transactions
 type UpdateTransaction struct {
-	AccountNumber   int     `json:"account_number" validate:"required"`
-	Amount          float64 `json:"amount" validate:"required"`
-	TransactionType string  `json:"transaction_type" validate:"required,oneof=debit credit"`
+	AccountNumber   int    `json:"account_number" validate:"required"`
+	Amount          Money  `json:"amount" validate:"required"`
+	TransactionType string `json:"transaction_type" validate:"required,oneof=debit credit"`
+	Currency        string `json:"currency,omitempty"`
 }
 
 // SessionStruct is the model for user sessions
@@ -15,11 +18,138 @@ type SessionStruct struct {
 
 // NewTransaction is the model for creating new transactions
 type NewTransaction struct {
-	UserId        string  `json:"-"`
-	AccountNumber int     `json:"account_number"`
-	Amount        float64 `json:"amount"`
-	TransferTo    int     `json:"transfer_to"`
-	Status        string  `json:"status" validate:"required,oneof=approved rejected"`
-	Type          string  `json:"type" validate:"required,oneof=credit debit"`
-	Comment       string  `json:"comment"`
+	UserId        string `json:"-"`
+	AccountNumber int    `json:"account_number"`
+	Amount        Money  `json:"amount"`
+	TransferTo    int    `json:"transfer_to"`
+	Status        string `json:"status" validate:"omitempty,oneof=pending"` // New transactions always start pending
+	Type          string `json:"type" validate:"required,oneof=credit debit"`
+	Comment       string `json:"comment"`
+	Currency      string `json:"currency"` // ISO 4217 code, DefaultCurrency when empty
+	BatchId       string `json:"-"`        // Batch the transaction is submitted in, if any
+}
+
+// Modes of a batch of new transactions
+const (
+	BatchAtomic  = "atomic"  // Every transaction is created or none is
+	BatchPartial = "partial" // Each transaction is created on its own
+)
+
+// NewBatch is the model for creating several transactions with one request
+type NewBatch struct {
+	UserId       string           `json:"-"`
+	Mode         string           `json:"mode" validate:"required,oneof=atomic partial"`
+	Transactions []NewTransaction `json:"transactions" validate:"dive"` // Must not be empty, checked by the handler
+}
+
+// NewSchedule is the model for creating or replacing a schedule
+type NewSchedule struct {
+	UserId            string     `json:"-"`
+	AccountNumber     int        `json:"account_number"`
+	Amount            Money      `json:"amount"`
+	TransferTo        int        `json:"transfer_to"`
+	Type              string     `json:"type" validate:"required,oneof=credit debit"`
+	TransactionStatus string     `json:"transaction_status" validate:"omitempty,oneof=pending"`
+	Comment           string     `json:"comment"`
+	Currency          string     `json:"currency"` // ISO 4217 code, DefaultCurrency when empty
+	Frequency         string     `json:"frequency" validate:"required,oneof=once daily weekly monthly cron"`
+	Cron              string     `json:"cron"` // Required by the cron frequency only
+	StartAt           time.Time  `json:"start_at"`
+	EndAt             *time.Time `json:"end_at"`
+	Paused            bool       `json:"paused"`
+}
+
+// NewWebhook is the model for registering a webhook
+type NewWebhook struct {
+	UserId   string   `json:"-"`
+	ClientId string   `json:"client_id"` // Client app the endpoint belongs to, empty for the user's own endpoint
+	Url      string   `json:"url" validate:"required"`
+	Events   []string `json:"events"` // Must hold known event types, checked by the logic
+}
+
+// UpdateStatus is the model for moving a transaction to another status
+type UpdateStatus struct {
+	Status string `json:"status" validate:"required,oneof=approved rejected failed"`
+	Reason string `json:"reason"`
+}
+
+// Reversal is the model for reversing what is left of a transaction
+type Reversal struct {
+	Reason string `json:"reason"`
+}
+
+// Refund is the model for giving back part of a transaction
+type Refund struct {
+	Amount Money  `json:"amount"` // Must be positive, checked by the handler
+	Reason string `json:"reason"`
+}
+
+// ListTransactions is the model for listing a user's transactions with optional filters
+type ListTransactions struct {
+	UserId         string
+	Limit          int
+	Page           int
+	From           *time.Time // Earliest created_at included
+	To             *time.Time // Latest created_at included
+	Type           string
+	Status         string
+	AccountNumber  int
+	TransferTo     int
+	MinAmount      *Money
+	MaxAmount      *Money
+	Comment        string    // Substring the comment must contain
+	BatchId        string    // Batch the transactions were submitted in
+	Sort           []OrderBy // Requested sort keys, newest first when empty
+	Keyset         bool      // Paginate with cursors instead of page numbers
+	Cursor         string    // Cursor of the page to fetch, empty for the first page
+	RunningBalance bool      // Add the running balance of AccountNumber to each transaction
+}
+
+// Formats of the account files exported for personal finance software and ERPs
+const (
+	ExportOfx     = "ofx"
+	ExportQfx     = "qfx"
+	ExportQif     = "qif"
+	ExportCamt053 = "camt053" // ISO 20022 bank to customer statement
+	ExportMt940   = "mt940"   // SWIFT customer statement message
+)
+
+// AccountExport is the model for exporting the settled transactions of an account in one currency to a file for
+// personal finance software or an ERP
+type AccountExport struct {
+	UserId        string
+	AccountNumber int
+	From          time.Time // Earliest created_at included
+	To            time.Time // Latest created_at included
+	Currency      string    // DefaultCurrency when empty
+	Format        string    // ExportOfx, ExportQfx, ExportQif, ExportCamt053 or ExportMt940
+}
+
+// CsvExport is the model for the layout of a CSV export of transactions
+type CsvExport struct {
+	Columns    []string // Columns to write in order, the default set when empty
+	DateFormat string   // Name of the format timestamps are written in, rfc3339 when empty
+}
+
+// Formats of the files transactions are imported from
+const (
+	ImportCsv = "csv"
+	ImportOfx = "ofx"
+)
+
+// ImportTransactions is the model for importing the history of a user's transactions from a file of another system
+type ImportTransactions struct {
+	UserId        string
+	Format        string            // ImportCsv or ImportOfx
+	AccountNumber int               // Account of the rows not naming their own, required by OFX files
+	Columns       map[string]string // Header of the CSV column holding each field, when not named after the field
+	DateFormat    string            // Name of the format CSV timestamps are read in, rfc3339 when empty
+	DryRun        bool              // Validate the rows without storing them
+}
+
+// NewAccount is the request of the account service registering the owner of an account
+type NewAccount struct {
+	AccountNumber int    `json:"-"`
+	UserId        string `json:"user_id" validate:"required"`
+	Currency      string `json:"currency"` // Defaults to DefaultCurrency
 }
@@ -7,6 +7,37 @@ type CacheResponse struct {
 	ContentType string // Content type of the cached response
 }
 
+// IdempotencyRecord is the structure stored for an Idempotency-Key by the idempotency middleware
+type IdempotencyRecord struct {
+	UserId         string // User the key is scoped to
+	IdempotencyKey string // Key sent by the client
+	Fingerprint    string // Hash of the request the key was first used with
+	Completed      bool   // Whether the original request has finished, the response is empty until then
+	CacheResponse         // Response of the original request
+}
+
+// IdempotencySchema represents the database schema for the idempotency key table. The primary key makes reserving a
+// key atomic, so only one of several concurrent requests with the same key runs.
+const IdempotencySchema = `
+	(
+		user_id VARCHAR(255) NOT NULL,
+		idempotency_key VARCHAR(255) NOT NULL,
+		fingerprint CHAR(64) NOT NULL,
+		completed BOOLEAN NOT NULL DEFAULT FALSE,
+		status INT NOT NULL DEFAULT 0,
+		response MEDIUMTEXT NOT NULL,
+		content_type VARCHAR(255) NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_id, idempotency_key)
+	);
+`
+
+// CacheGenerationKey returns the cache key holding the generation of a user's cached responses. Responses are cached
+// under the current generation, so moving it to a new value invalidates all of them at once.
+func CacheGenerationKey(userId string) string {
+	return "cache-generation/" + userId
+}
+
 // PaginatedResponse is the structure for the paginated response of transactions
 type PaginatedResponse struct {
 	Response   []Transaction // List of transactions for the current page
@@ -15,7 +46,41 @@ type PaginatedResponse struct {
 
 // Paginate is the structure for pagination information
 type Paginate struct {
-	CurrentPage int `json:"current_page"` // The current page number
-	NextPage    int `json:"next_page"`    // The next page number
-	TotalPage   int `json:"total_page"`   // The total number of pages
+	CurrentPage int    `json:"current_page"`          // The current page number
+	NextPage    int    `json:"next_page"`             // The next page number
+	TotalPage   int    `json:"total_page"`            // The total number of pages
+	NextCursor  string `json:"next_cursor,omitempty"` // Cursor of the following page, set in cursor mode
+	PrevCursor  string `json:"prev_cursor,omitempty"` // Cursor of the preceding page, set in cursor mode
+}
+
+// ImportReport is the structure for the outcome of an import of transactions
+type ImportReport struct {
+	DryRun   bool          `json:"dry_run"`
+	Rows     int           `json:"rows"`     // Rows read from the file
+	Accepted int           `json:"accepted"` // Rows passing validation
+	Imported int           `json:"imported"` // Rows stored, none on a dry run
+	Errors   []ImportError `json:"errors"`   // Reasons the other rows were rejected
+}
+
+// ImportError is the reason a row of an import file was rejected
+type ImportError struct {
+	Row   int    `json:"row"` // Line of the row in a CSV file, or position of the transaction in an OFX file
+	Error string `json:"error"`
+}
+
+// BatchResult is the structure for the outcome of a batch of new transactions
+type BatchResult struct {
+	BatchId string      `json:"batch_id"`
+	Mode    string      `json:"mode"`
+	Created int         `json:"created"` // Transactions stored as requested or held for review
+	Failed  int         `json:"failed"`
+	Items   []BatchItem `json:"items"` // Outcome of each transaction in the order submitted, only the failed one when an atomic batch is rolled back
+}
+
+// BatchItem is the outcome of one transaction of a batch
+type BatchItem struct {
+	Index         int    `json:"index"`  // Position of the transaction in the batch, from 0
+	Status        int    `json:"status"` // HTTP status creating the transaction on its own responds with
+	Message       string `json:"message"`
+	TransactionId string `json:"transaction_id,omitempty"`
 }
@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// Risk rule types
+const (
+	RiskAmount          = "amount"           // Amount at or above a threshold
+	RiskNewCounterparty = "new_counterparty" // Transfer to an account the user never paid before
+	RiskUnusualHour     = "unusual_hour"     // Created within a range of hours in UTC
+	RiskRapidRepeat     = "rapid_repeat"     // Same transaction repeated within a short window
+)
+
+// RiskRuleTypes lists the supported risk rule types
+var RiskRuleTypes = []string{RiskAmount, RiskNewCounterparty, RiskUnusualHour, RiskRapidRepeat}
+
+// RiskHit is a risk rule matching a transaction when it was created, kept so risk decisions can be audited
+type RiskHit struct {
+	TransactionId string    `json:"-"`
+	Rule          string    `json:"rule"` // Name of the rule
+	Type          string    `json:"type"`
+	Score         int       `json:"score"`
+	Detail        string    `json:"detail"`
+	Decision      string    `json:"decision"` // Status the transaction was given after all rules were scored
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// ReviewItem is a transaction awaiting the decision of a reviewer along with the risk rules it hit
+type ReviewItem struct {
+	Transaction
+	RiskHits []RiskHit `json:"risk_hits"`
+}
+
+// RiskHitSchema represents the database schema for the risk hit table
+const RiskHitSchema = `
+	(
+		id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		transaction_id VARCHAR(255) NOT NULL,
+		rule VARCHAR(255) NOT NULL,
+		type VARCHAR(255) NOT NULL,
+		score INT NOT NULL,
+		detail VARCHAR(255) NOT NULL DEFAULT '',
+		decision VARCHAR(255) NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_risk_transaction (transaction_id, id)
+	);
+`
@@ -0,0 +1,126 @@
+package model
+
+import "time"
+
+// Schedule frequencies
+const (
+	FrequencyOnce    = "once"    // A single future-dated transaction at the start time
+	FrequencyDaily   = "daily"   // Every day at the time of day of the start time
+	FrequencyWeekly  = "weekly"  // Every week on the weekday and at the time of the start time
+	FrequencyMonthly = "monthly" // Every month on the day of the start time, or the month's last day when shorter
+	FrequencyCron    = "cron"    // At the times matching a cron expression in UTC, from the start time on
+)
+
+// Schedule statuses
+const (
+	ScheduleActive    = "active"    // Runs at its next occurrence
+	SchedulePaused    = "paused"    // Does not run until resumed
+	ScheduleCompleted = "completed" // Has no occurrence left
+	ScheduleCancelled = "cancelled" // Was cancelled by the user
+)
+
+// Schedule execution outcomes
+const (
+	ExecutionSucceeded = "succeeded" // The transaction was created
+	ExecutionRejected  = "rejected"  // The transaction was refused, the occurrence is skipped
+	ExecutionRetrying  = "retrying"  // Failed on a server error, the occurrence is tried again
+	ExecutionSkipped   = "skipped"   // Failed on every attempt, the occurrence is skipped
+)
+
+// Schedule is a future-dated or recurring transaction created by the scheduler at each of its occurrences
+type Schedule struct {
+	ScheduleId        string     `json:"schedule_id"`
+	UserId            string     `json:"-"`
+	AccountNumber     int        `json:"account_number"`
+	Amount            Money      `json:"amount"`
+	TransferTo        int        `json:"transfer_to"`
+	Type              string     `json:"type"`
+	TransactionStatus string     `json:"transaction_status"` // Status the transactions are created with
+	Comment           string     `json:"comment"`
+	Currency          string     `json:"currency"`
+	Frequency         string     `json:"frequency"`
+	Cron              string     `json:"cron,omitempty"`
+	StartAt           time.Time  `json:"start_at"`
+	EndAt             *time.Time `json:"end_at,omitempty"` // No occurrence after this time
+	NextRunAt         time.Time  `json:"next_run_at"`      // Occurrence run next
+	DueAt             time.Time  `json:"-"`                // When the next occurrence is attempted, later than NextRunAt while retrying
+	Status            string     `json:"status"`
+	Attempts          int        `json:"attempts"` // Failed attempts at the next occurrence
+	LastError         string     `json:"last_error,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// NewTransaction returns the transaction created at each occurrence of the schedule
+func (s Schedule) NewTransaction() NewTransaction {
+	return NewTransaction{
+		UserId:        s.UserId,
+		AccountNumber: s.AccountNumber,
+		Amount:        s.Amount,
+		TransferTo:    s.TransferTo,
+		Status:        s.TransactionStatus,
+		Type:          s.Type,
+		Comment:       s.Comment,
+		Currency:      s.Currency,
+	}
+}
+
+// ScheduleExecution records the outcome of an attempt at running an occurrence of a schedule
+type ScheduleExecution struct {
+	ScheduleId    string    `json:"-"`
+	RunAt         time.Time `json:"run_at"` // Occurrence attempted
+	Attempt       int       `json:"attempt"`
+	Outcome       string    `json:"outcome"`
+	TransactionId string    `json:"transaction_id,omitempty"` // Transaction created, if any
+	Message       string    `json:"message"`                  // Response of the transaction logic
+	ExecutedAt    time.Time `json:"executed_at"`
+}
+
+// ScheduleDetail is a schedule along with its latest executions, newest first
+type ScheduleDetail struct {
+	Schedule
+	Executions []ScheduleExecution `json:"executions"`
+}
+
+// ScheduleSchema represents the database schema for the schedule table
+const ScheduleSchema = `
+	(
+		schedule_id VARCHAR(255) NOT NULL PRIMARY KEY,
+		user_id VARCHAR(255) NOT NULL,
+		account_number INT NOT NULL,
+		amount DECIMAL(18,2) NOT NULL,
+		transfer_to INT NOT NULL DEFAULT 0,
+		type VARCHAR(255) NOT NULL,
+		transaction_status VARCHAR(255) NOT NULL,
+		comment VARCHAR(255) NOT NULL DEFAULT '',
+		currency CHAR(3) NOT NULL,
+		frequency VARCHAR(32) NOT NULL,
+		cron VARCHAR(255) NOT NULL DEFAULT '',
+		start_at DATETIME NOT NULL,
+		end_at DATETIME NULL,
+		next_run_at DATETIME NOT NULL,
+		due_at DATETIME NOT NULL,
+		status VARCHAR(32) NOT NULL,
+		attempts INT NOT NULL DEFAULT 0,
+		last_error VARCHAR(1024) NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+		INDEX idx_schedule_due (status, due_at),
+		INDEX idx_schedule_user (user_id, created_at)
+	);
+`
+
+// ScheduleExecutionSchema represents the database schema for the schedule execution table
+const ScheduleExecutionSchema = `
+	(
+		id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		schedule_id VARCHAR(255) NOT NULL,
+		run_at DATETIME NOT NULL,
+		attempt INT NOT NULL,
+		outcome VARCHAR(32) NOT NULL,
+		transaction_id VARCHAR(255) NOT NULL DEFAULT '',
+		message VARCHAR(1024) NOT NULL DEFAULT '',
+		executed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_execution_schedule (schedule_id, id)
+	);
+`
@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// Transaction statuses
+const (
+	StatusPending  = "pending"  // Awaiting a decision, does not affect the balance yet
+	StatusApproved = "approved" // Applied to the account balance
+	StatusRejected = "rejected" // Declined, never affects the balance
+	StatusFailed   = "failed"   // Could not be processed, never affects the balance
+	StatusReversed = "reversed" // Was approved and has since been undone
+	StatusReview   = "review"   // Held by the risk rules until a reviewer approves or rejects it
+)
+
+// StatusChange is a status transition recorded in the status history of a transaction
+type StatusChange struct {
+	TransactionId string    `json:"-"`
+	FromStatus    string    `json:"from_status"` // Empty for the status a transaction was created with
+	ToStatus      string    `json:"to_status"`
+	ChangedBy     string    `json:"changed_by"` // User ID of whoever made the change
+	Reason        string    `json:"reason,omitempty"`
+	ChangedAt     time.Time `json:"changed_at"`
+}
+
+// TransactionDetail is a transaction along with the history of its status
+type TransactionDetail struct {
+	Transaction
+	StatusHistory []StatusChange `json:"status_history"`
+}
+
+// StatusHistorySchema represents the database schema for the status history table
+const StatusHistorySchema = `
+	(
+		id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		transaction_id VARCHAR(255) NOT NULL,
+		from_status VARCHAR(255) NOT NULL,
+		to_status VARCHAR(255) NOT NULL,
+		changed_by VARCHAR(255) NOT NULL,
+		reason VARCHAR(255) NOT NULL DEFAULT '',
+		changed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_history_transaction (transaction_id, id)
+	);
+`
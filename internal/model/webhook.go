@@ -0,0 +1,121 @@
+package model
+
+import "time"
+
+// Webhook event types
+const (
+	EventTransactionCreated       = "transaction.created"        // A transaction was created, in the status it was created with
+	EventTransactionStatusChanged = "transaction.status_changed" // A transaction moved to another status
+	EventTransactionReversed      = "transaction.reversed"       // What was left of a transaction was given back
+)
+
+// WebhookEvents lists the event types a webhook may subscribe to
+var WebhookEvents = []string{EventTransactionCreated, EventTransactionStatusChanged, EventTransactionReversed}
+
+// Webhook statuses
+const (
+	WebhookActive   = "active"   // Receives the events it subscribes to
+	WebhookDisabled = "disabled" // Was removed by the user, its pending deliveries are dropped
+)
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "pending"   // Waiting to be delivered, possibly after failed attempts
+	WebhookDeliveryDelivered = "delivered" // Answered with a 2xx status by the endpoint
+	WebhookDeliveryFailed    = "failed"    // Gave up after the maximum number of attempts, or dropped with its webhook
+)
+
+// Webhook is an endpoint of a user or of one of the user's client apps receiving the events it subscribes to
+type Webhook struct {
+	WebhookId string    `json:"webhook_id"`
+	UserId    string    `json:"-"`
+	ClientId  string    `json:"client_id,omitempty"` // Client app the endpoint belongs to, if any
+	Url       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"` // Key signing the deliveries, only returned when the webhook is registered
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery is an event queued for a webhook along with the outcome of the attempts at delivering it
+type WebhookDelivery struct {
+	DeliveryId     string        `json:"delivery_id"`
+	WebhookId      string        `json:"webhook_id"`
+	EventId        string        `json:"event_id"` // Shared by the deliveries of the same event to several webhooks
+	EventType      string        `json:"event_type"`
+	TransactionId  string        `json:"transaction_id"`
+	Payload        string        `json:"-"` // JSON body of the event
+	Status         string        `json:"status"`
+	Attempts       int           `json:"attempts"`
+	ResponseStatus int           `json:"response_status,omitempty"` // HTTP status of the latest attempt, zero when no response was received
+	LastError      string        `json:"last_error,omitempty"`
+	RetryAfter     time.Duration `json:"-"` // Delay before the next attempt, used when updating a delivery
+	NextAttemptAt  time.Time     `json:"next_attempt_at"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+	Url            string        `json:"-"` // Endpoint of the webhook, read along with due deliveries
+	Secret         string        `json:"-"` // Secret of the webhook, read along with due deliveries
+	WebhookStatus  string        `json:"-"` // Status of the webhook, read along with due deliveries
+}
+
+// WebhookEvent is the body of a webhook delivery
+type WebhookEvent struct {
+	Id        string             `json:"id"`
+	Type      string             `json:"type"`
+	CreatedAt time.Time          `json:"created_at"`
+	Data      WebhookTransaction `json:"data"`
+}
+
+// WebhookTransaction is the transaction a webhook event is about, in the status the event moved it to
+type WebhookTransaction struct {
+	TransactionId  string `json:"transaction_id"`
+	AccountNumber  int    `json:"account_number"`
+	Type           string `json:"type"`
+	Status         string `json:"status"`
+	PreviousStatus string `json:"previous_status,omitempty"` // Empty when the transaction was created
+	Amount         Money  `json:"amount"`
+	Currency       string `json:"currency"`
+	TransferTo     int    `json:"transfer_to,omitempty"`
+	TransferId     string `json:"transfer_id,omitempty"`
+	ReferenceId    string `json:"reference_id,omitempty"`
+	BatchId        string `json:"batch_id,omitempty"`
+	Comment        string `json:"comment,omitempty"`
+}
+
+// WebhookSchema represents the database schema for the webhook table
+const WebhookSchema = `
+	(
+		webhook_id VARCHAR(255) NOT NULL PRIMARY KEY,
+		user_id VARCHAR(255) NOT NULL,
+		client_id VARCHAR(255) NOT NULL DEFAULT '',
+		url VARCHAR(2048) NOT NULL,
+		events VARCHAR(255) NOT NULL,
+		secret VARCHAR(255) NOT NULL,
+		status VARCHAR(32) NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+		INDEX idx_webhook_user (user_id, status)
+	);
+`
+
+// WebhookDeliverySchema represents the database schema for the webhook delivery table
+const WebhookDeliverySchema = `
+	(
+		delivery_id VARCHAR(255) NOT NULL PRIMARY KEY,
+		webhook_id VARCHAR(255) NOT NULL,
+		event_id VARCHAR(255) NOT NULL,
+		event_type VARCHAR(64) NOT NULL,
+		transaction_id VARCHAR(255) NOT NULL,
+		payload TEXT NOT NULL,
+		status VARCHAR(32) NOT NULL DEFAULT 'pending',
+		attempts INT NOT NULL DEFAULT 0,
+		response_status INT NOT NULL DEFAULT 0,
+		last_error VARCHAR(1024) NOT NULL DEFAULT '',
+		next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+		INDEX idx_delivery_due (status, next_attempt_at),
+		INDEX idx_delivery_webhook (webhook_id, created_at)
+	);
+`
@@ -0,0 +1,158 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"expvar"
+	"fmt"
+	"github.com/PereRohit/util/log"
+	"github.com/vatsal278/TransactionManagementService/internal/config"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"github.com/vatsal278/TransactionManagementService/internal/repo/datasource"
+	"net/http"
+	"time"
+)
+
+// metrics counts the outcome of delivery attempts, published under "outbox" by expvar
+var metrics = expvar.NewMap("outbox")
+
+// Dispatcher delivers pending outbox messages to the services they are addressed to.
+// Failed deliveries are retried with exponential backoff and moved to the dead state after the maximum number of attempts.
+type Dispatcher struct {
+	ds        datasource.DataSourceI
+	cfg       config.OutboxCfg
+	accSvcUrl string
+	client    *http.Client
+}
+
+// NewDispatcher returns a Dispatcher reading the outbox through ds and delivering account updates to the account service
+func NewDispatcher(ds datasource.DataSourceI, accSvcUrl string, cfg config.OutboxCfg) *Dispatcher {
+	return &Dispatcher{
+		ds:        ds,
+		cfg:       cfg,
+		accSvcUrl: accSvcUrl,
+		client:    &http.Client{Timeout: 3 * time.Second},
+	}
+}
+
+// Backoff returns the delay before the next attempt after the given number of failed attempts.
+// The delay doubles with each attempt starting from base and never exceeds max.
+func Backoff(attempts int, base time.Duration, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max || delay <= 0 {
+		return max
+	}
+	return delay
+}
+
+// Run dispatches due messages every poll interval until the context is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := d.DispatchOnce()
+			if err != nil {
+				metrics.Add("errors", 1)
+				log.Error(err)
+			}
+		}
+	}
+}
+
+// Lease returns how long a dispatcher keeps a batch of n claimed messages to itself: long enough for every delivery
+// to time out one after the other, with a margin to record the outcomes.
+func Lease(n int, timeout time.Duration) time.Duration {
+	return time.Duration(n)*timeout + time.Minute
+}
+
+// DispatchOnce attempts delivery of one batch of due messages and records the outcome of every attempt.
+// The batch is claimed in a short database transaction and delivered after it commits, so no lock is held while the
+// receiving service is called. A message whose outcome cannot be recorded is attempted again once its lease expires.
+func (d *Dispatcher) DispatchOnce() error {
+	messages, err := d.claim()
+	if err != nil {
+		return err
+	}
+	var updateErr error
+	for _, message := range messages {
+		message.Attempts++
+		err = d.deliver(message)
+		switch {
+		case err == nil:
+			message.Status = model.OutboxDelivered
+			message.LastError = ""
+			metrics.Add("delivered", 1)
+		case message.Attempts >= d.cfg.MaxAttempts:
+			message.Status = model.OutboxDead
+			message.LastError = err.Error()
+			metrics.Add("dead", 1)
+			log.Error(fmt.Sprintf("outbox message %d for transaction %s moved to dead letter after %d attempts: %v", message.Id, message.TransactionId, message.Attempts, err))
+		default:
+			message.LastError = err.Error()
+			message.RetryAfter = Backoff(message.Attempts, d.cfg.BaseBackoff, d.cfg.MaxBackoff)
+			metrics.Add("retried", 1)
+			log.Info(fmt.Sprintf("outbox message %d for transaction %s failed, retrying in %s: %v", message.Id, message.TransactionId, message.RetryAfter, err))
+		}
+		err = d.ds.UpdateOutbox(message)
+		if err != nil {
+			log.Error(fmt.Sprintf("recording the outcome of outbox message %d for transaction %s: %v", message.Id, message.TransactionId, err))
+			updateErr = err
+		}
+	}
+	return updateErr
+}
+
+// claim picks up a batch of due messages and leases them, so concurrent dispatchers never pick up the same message
+func (d *Dispatcher) claim() ([]model.OutboxMessage, error) {
+	var messages []model.OutboxMessage
+	err := d.ds.Transaction(func(ds datasource.DataSourceI) error {
+		var err error
+		messages, err = ds.GetDueOutbox(d.cfg.BatchSize)
+		if err != nil || len(messages) == 0 {
+			return err
+		}
+		ids := make([]int64, 0, len(messages))
+		for _, message := range messages {
+			ids = append(ids, message.Id)
+		}
+		return ds.LeaseOutbox(ids, Lease(len(messages), d.client.Timeout))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// deliver sends a message to the service handling its event type, failing unless the service answers with a 2xx status
+func (d *Dispatcher) deliver(message model.OutboxMessage) error {
+	var method, url string
+	switch message.EventType {
+	case model.EventAccountUpdate:
+		method, url = http.MethodPut, d.accSvcUrl+"/microbank/v1/account/update/transaction"
+	default:
+		return fmt.Errorf("unknown event type %q", message.EventType)
+	}
+	req, err := http.NewRequest(method, url, bytes.NewReader([]byte(message.Payload)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// Deliveries are at least once, the key lets the receiver discard repeats of the same message
+	req.Header.Set("Idempotency-Key", fmt.Sprintf("outbox-%d", message.Id))
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s responded with status %d", method, url, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package outbox
+
+import (
+	"errors"
+	"github.com/PereRohit/util/testutil"
+	"github.com/golang/mock/gomock"
+	"github.com/vatsal278/TransactionManagementService/internal/config"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"github.com/vatsal278/TransactionManagementService/internal/repo/datasource"
+	"github.com/vatsal278/TransactionManagementService/pkg/mock"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int
+		want     time.Duration
+	}{
+		{name: "first retry", attempts: 1, want: time.Second},
+		{name: "doubles", attempts: 3, want: 4 * time.Second},
+		{name: "capped", attempts: 10, want: 30 * time.Second},
+		{name: "no overflow", attempts: 200, want: 30 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := testutil.Diff(Backoff(tt.attempts, time.Second, 30*time.Second), tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestDispatcher_DispatchOnce(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Method != http.MethodPut || r.URL.Path != "/microbank/v1/account/update/transaction" || r.Header.Get("Idempotency-Key") != "outbox-1" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if string(body) == "fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	cfg := config.OutboxCfg{BaseBackoff: time.Second, MaxBackoff: time.Minute, MaxAttempts: 3, BatchSize: 10}
+	// inTransaction makes the mock run the function given to Transaction against itself
+	inTransaction := func(mockDs *mock.MockDataSourceI) {
+		mockDs.EXPECT().Transaction(gomock.Any()).Times(1).DoAndReturn(func(fn func(datasource.DataSourceI) error) error {
+			return fn(mockDs)
+		})
+	}
+	lease := Lease(1, 3*time.Second)
+	message := model.OutboxMessage{Id: 1, TransactionId: "1234", EventType: model.EventAccountUpdate, Payload: "{}", Status: model.OutboxPending}
+	tests := []struct {
+		name    string
+		setup   func() datasource.DataSourceI
+		wantErr bool
+	}{
+		{
+			name: "Success::delivered",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				inTransaction(mockDs)
+				mockDs.EXPECT().GetDueOutbox(10).Times(1).Return([]model.OutboxMessage{message}, nil)
+				mockDs.EXPECT().LeaseOutbox([]int64{1}, lease).Times(1).Return(nil)
+				delivered := message
+				delivered.Status = model.OutboxDelivered
+				delivered.Attempts = 1
+				mockDs.EXPECT().UpdateOutbox(delivered).Times(1).Return(nil)
+				return mockDs
+			},
+		},
+		{
+			name: "Success::failure scheduled for retry",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				inTransaction(mockDs)
+				failing := message
+				failing.Payload = "fail"
+				failing.Attempts = 1
+				mockDs.EXPECT().GetDueOutbox(10).Times(1).Return([]model.OutboxMessage{failing}, nil)
+				mockDs.EXPECT().LeaseOutbox([]int64{1}, lease).Times(1).Return(nil)
+				retry := failing
+				retry.Attempts = 2
+				retry.LastError = "PUT " + srv.URL + "/microbank/v1/account/update/transaction responded with status 500"
+				retry.RetryAfter = 2 * time.Second
+				mockDs.EXPECT().UpdateOutbox(retry).Times(1).Return(nil)
+				return mockDs
+			},
+		},
+		{
+			name: "Success::dead letter after max attempts",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				inTransaction(mockDs)
+				unknown := message
+				unknown.EventType = "unknown"
+				unknown.Attempts = 2
+				mockDs.EXPECT().GetDueOutbox(10).Times(1).Return([]model.OutboxMessage{unknown}, nil)
+				mockDs.EXPECT().LeaseOutbox([]int64{1}, lease).Times(1).Return(nil)
+				dead := unknown
+				dead.Attempts = 3
+				dead.Status = model.OutboxDead
+				dead.LastError = `unknown event type "unknown"`
+				mockDs.EXPECT().UpdateOutbox(dead).Times(1).Return(nil)
+				return mockDs
+			},
+		},
+		{
+			name: "Success::no due messages",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				inTransaction(mockDs)
+				mockDs.EXPECT().GetDueOutbox(10).Times(1).Return(nil, nil)
+				return mockDs
+			},
+		},
+		{
+			name: "Failure::get due messages",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				inTransaction(mockDs)
+				mockDs.EXPECT().GetDueOutbox(10).Times(1).Return(nil, errors.New("error"))
+				return mockDs
+			},
+			wantErr: true,
+		},
+		{
+			name: "Failure::lease messages",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				inTransaction(mockDs)
+				mockDs.EXPECT().GetDueOutbox(10).Times(1).Return([]model.OutboxMessage{message}, nil)
+				mockDs.EXPECT().LeaseOutbox([]int64{1}, lease).Times(1).Return(errors.New("error"))
+				return mockDs
+			},
+			wantErr: true,
+		},
+		{
+			name: "Failure::update message does not stop the batch",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				inTransaction(mockDs)
+				other := message
+				other.Id = 2
+				mockDs.EXPECT().GetDueOutbox(10).Times(1).Return([]model.OutboxMessage{message, other}, nil)
+				mockDs.EXPECT().LeaseOutbox([]int64{1, 2}, Lease(2, 3*time.Second)).Times(1).Return(nil)
+				delivered := message
+				delivered.Status = model.OutboxDelivered
+				delivered.Attempts = 1
+				retry := other
+				retry.Attempts = 1
+				retry.LastError = "PUT " + srv.URL + "/microbank/v1/account/update/transaction responded with status 404"
+				retry.RetryAfter = time.Second
+				gomock.InOrder(
+					mockDs.EXPECT().UpdateOutbox(delivered).Times(1).Return(errors.New("error")),
+					mockDs.EXPECT().UpdateOutbox(retry).Times(1).Return(nil),
+				)
+				return mockDs
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDispatcher(tt.setup(), srv.URL, cfg)
+			err := d.DispatchOnce()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -1,11 +1,56 @@
 package datasource
 
-import "github.com/vatsal278/TransactionManagementService/internal/model"
+import (
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"time"
+)
 
 //go:generate mockgen --build_flags=--mod=mod --destination=./../../../pkg/mock/mock_datasource.go --package=mock github.com/vatsal278/TransactionManagementService/internal/repo/datasource DataSourceI
 
 type DataSourceI interface {
 	HealthCheck() bool
-	Get(map[string]interface{}, int, int) ([]model.Transaction, int, error)
+	Get(query model.Query) ([]model.Transaction, int, error)
+	Stream(query model.Query, fn func(model.Transaction) error) error
 	Insert(user model.Transaction) error
+	InsertBatch(transactions []model.Transaction) error
+	Update(where model.Filter, set map[string]interface{}) (int64, error)
+	InsertStatusHistory(change model.StatusChange) error
+	GetStatusHistory(transactionId string) ([]model.StatusChange, error)
+	GetBalances(accountNumber int, userId string, asOf time.Time) ([]model.Balance, error)
+	GetRunningBalances(accountNumber int, userId string, transactionIds []string) (map[string]model.Money, error)
+	UpsertFxRate(rate model.FxRate) error
+	GetFxRate(base string, quote string, at time.Time) (*model.FxRate, error)
+	LockUser(userId string) error
+	GetSpending(where model.Filter) (model.Money, int, error)
+	InsertRiskHit(hit model.RiskHit) error
+	GetRiskHits(transactionId string) ([]model.RiskHit, error)
+	InsertSchedule(schedule model.Schedule) error
+	UpdateSchedule(schedule model.Schedule) error
+	GetSchedules(userId string) ([]model.Schedule, error)
+	GetSchedule(scheduleId string) (*model.Schedule, error)
+	LockSchedule(scheduleId string) (*model.Schedule, error)
+	GetDueSchedules(limit int) ([]model.Schedule, error)
+	InsertScheduleExecution(execution model.ScheduleExecution) error
+	GetScheduleExecutions(scheduleId string, limit int) ([]model.ScheduleExecution, error)
+	Transaction(fn func(DataSourceI) error) error
+	InsertOutbox(message model.OutboxMessage) error
+	GetDueOutbox(limit int) ([]model.OutboxMessage, error)
+	LeaseOutbox(ids []int64, lease time.Duration) error
+	UpdateOutbox(message model.OutboxMessage) error
+	InsertWebhook(webhook model.Webhook) error
+	GetWebhooks(userId string) ([]model.Webhook, error)
+	GetWebhook(webhookId string) (*model.Webhook, error)
+	UpdateWebhookStatus(webhookId string, status string) error
+	InsertWebhookDeliveries(userId string, delivery model.WebhookDelivery) error
+	GetWebhookDeliveries(webhookId string, limit int) ([]model.WebhookDelivery, error)
+	GetWebhookDelivery(deliveryId string) (*model.WebhookDelivery, error)
+	GetDueWebhookDeliveries(limit int) ([]model.WebhookDelivery, error)
+	LeaseWebhookDeliveries(ids []string, lease time.Duration) error
+	UpdateWebhookDelivery(delivery model.WebhookDelivery) error
+	ReserveIdempotencyKey(record model.IdempotencyRecord, ttl time.Duration) (bool, error)
+	GetIdempotencyKey(userId string, idempotencyKey string) (*model.IdempotencyRecord, error)
+	CompleteIdempotencyKey(record model.IdempotencyRecord) error
+	ReleaseIdempotencyKey(userId string, idempotencyKey string) error
+	InsertAccount(account model.Account) (bool, error)
+	GetAccount(accountNumber int) (*model.Account, error)
 }
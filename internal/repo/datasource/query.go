@@ -0,0 +1,118 @@
+package datasource
+
+import (
+	"fmt"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"strings"
+)
+
+// columns lists the transaction columns that may be referenced by a filter or sort key.
+// Column names are interpolated into SQL, so anything outside this list is rejected.
+var columns = map[string]struct{}{
+	"transaction_id":  {},
+	"account_number":  {},
+	"user_id":         {},
+	"amount":          {},
+	"transfer_to":     {},
+	"created_at":      {},
+	"updated_at":      {},
+	"status":          {},
+	"type":            {},
+	"comment":         {},
+	"transfer_id":     {},
+	"reference_id":    {},
+	"refunded_amount": {},
+	"currency":        {},
+	"batch_id":        {},
+}
+
+// checkColumn returns an error if the column is not a known transaction column.
+func checkColumn(column string) error {
+	if _, ok := columns[column]; !ok {
+		return fmt.Errorf("unknown column %q", column)
+	}
+	return nil
+}
+
+// buildWhere compiles a filter into a SQL condition with ? placeholders and the arguments bound to them.
+// An empty string is returned for a filter that matches every row.
+func buildWhere(f model.Filter) (string, []interface{}, error) {
+	if f.Logic != "" {
+		return buildGroup(f)
+	}
+	if f.Column == "" {
+		return "", nil, nil
+	}
+	err := checkColumn(f.Column)
+	if err != nil {
+		return "", nil, err
+	}
+	switch f.Op {
+	case model.OpEq, model.OpNe, model.OpGt, model.OpGte, model.OpLt, model.OpLte, model.OpLike:
+		return fmt.Sprintf("%s %s ?", f.Column, f.Op), []interface{}{f.Value}, nil
+	case model.OpIn:
+		values, ok := f.Value.([]interface{})
+		if !ok || len(values) == 0 {
+			return "", nil, fmt.Errorf("%s on %s needs at least one value", f.Op, f.Column)
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+		return fmt.Sprintf("%s IN (%s)", f.Column, placeholders), values, nil
+	case model.OpBetween:
+		values, ok := f.Value.([]interface{})
+		if !ok || len(values) != 2 {
+			return "", nil, fmt.Errorf("%s on %s needs exactly two values", f.Op, f.Column)
+		}
+		return fmt.Sprintf("%s BETWEEN ? AND ?", f.Column), values, nil
+	case model.OpIsNull, model.OpNotNull:
+		return fmt.Sprintf("%s %s", f.Column, f.Op), nil, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported operator %q on %s", f.Op, f.Column)
+	}
+}
+
+// buildGroup compiles the children of a filter group and joins them with the group's logical operator.
+// Nested groups with more than one condition are wrapped in parentheses.
+func buildGroup(f model.Filter) (string, []interface{}, error) {
+	if f.Logic != model.LogicAnd && f.Logic != model.LogicOr {
+		return "", nil, fmt.Errorf("unsupported logical operator %q", f.Logic)
+	}
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	for _, child := range f.Filters {
+		q, a, err := buildWhere(child)
+		if err != nil {
+			return "", nil, err
+		}
+		if q == "" {
+			continue
+		}
+		if child.Logic != "" && len(child.Filters) > 1 {
+			q = "(" + q + ")"
+		}
+		conditions = append(conditions, q)
+		args = append(args, a...)
+	}
+	return strings.Join(conditions, " "+f.Logic+" "), args, nil
+}
+
+// buildOrderBy compiles sort keys into an ORDER BY clause, defaulting to created_at when none are given.
+func buildOrderBy(orderBy []model.OrderBy) (string, error) {
+	if len(orderBy) == 0 {
+		return " ORDER BY created_at", nil
+	}
+	keys := make([]string, 0, len(orderBy))
+	for _, o := range orderBy {
+		err := checkColumn(o.Column)
+		if err != nil {
+			return "", err
+		}
+		if o.Desc {
+			keys = append(keys, o.Column+" DESC")
+			continue
+		}
+		keys = append(keys, o.Column)
+	}
+	return " ORDER BY " + strings.Join(keys, ", "), nil
+}
@@ -0,0 +1,142 @@
+package datasource
+
+import (
+	"github.com/PereRohit/util/testutil"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"strings"
+	"testing"
+)
+
+func TestBuildWhere(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   model.Filter
+		wantSql  string
+		wantArgs []interface{}
+		wantErr  string
+	}{
+		{
+			name:    "SUCCESS::empty filter",
+			filter:  model.Filter{},
+			wantSql: "",
+		},
+		{
+			name:    "SUCCESS::empty group",
+			filter:  model.And(model.Or(), model.Filter{}),
+			wantSql: "",
+		},
+		{
+			name:     "SUCCESS::comparison operators",
+			filter:   model.And(model.Eq("status", "approved"), model.Ne("type", "credit"), model.Gt("amount", 1), model.Gte("amount", 2), model.Lt("amount", 3), model.Lte("amount", 4)),
+			wantSql:  "status = ? AND type != ? AND amount > ? AND amount >= ? AND amount < ? AND amount <= ?",
+			wantArgs: []interface{}{"approved", "credit", 1, 2, 3, 4},
+		},
+		{
+			name:     "SUCCESS::in between like",
+			filter:   model.And(model.In("account_number", 1, 2, 3), model.Between("amount", 10, 20), model.Like("comment", "%rent%")),
+			wantSql:  "account_number IN (?, ?, ?) AND amount BETWEEN ? AND ? AND comment LIKE ?",
+			wantArgs: []interface{}{1, 2, 3, 10, 20, "%rent%"},
+		},
+		{
+			name:    "SUCCESS::null checks",
+			filter:  model.Or(model.IsNull("comment"), model.NotNull("transfer_to")),
+			wantSql: "comment IS NULL OR transfer_to IS NOT NULL",
+		},
+		{
+			name:     "SUCCESS::nested groups",
+			filter:   model.Or(model.And(model.Eq("user_id", "1"), model.Eq("status", "approved")), model.And(model.Eq("transfer_to", 2)), model.Eq("user_id", "3")),
+			wantSql:  "(user_id = ? AND status = ?) OR transfer_to = ? OR user_id = ?",
+			wantArgs: []interface{}{"1", "approved", 2, "3"},
+		},
+		{
+			name:    "FAILURE::unknown column",
+			filter:  model.Eq("password", "x"),
+			wantErr: "unknown column",
+		},
+		{
+			name:    "FAILURE::unknown column in group",
+			filter:  model.And(model.Eq("user_id", "1"), model.Or(model.Eq("1=1 --", "x"))),
+			wantErr: "unknown column",
+		},
+		{
+			name:    "FAILURE::empty in",
+			filter:  model.In("status"),
+			wantErr: "at least one value",
+		},
+		{
+			name:    "FAILURE::between needs two values",
+			filter:  model.Filter{Column: "amount", Op: model.OpBetween, Value: []interface{}{1}},
+			wantErr: "exactly two values",
+		},
+		{
+			name:    "FAILURE::unsupported operator",
+			filter:  model.Filter{Column: "amount", Op: "; DROP TABLE"},
+			wantErr: "unsupported operator",
+		},
+		{
+			name:    "FAILURE::unsupported logic",
+			filter:  model.Filter{Logic: "XOR", Filters: []model.Filter{model.Eq("user_id", "1")}},
+			wantErr: "unsupported logical operator",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args, err := buildWhere(tt.filter)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Want: %v, Got: %v", nil, err)
+				return
+			}
+			diff := testutil.Diff(sql, tt.wantSql)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+			diff = testutil.Diff(args, tt.wantArgs)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestBuildOrderBy(t *testing.T) {
+	tests := []struct {
+		name    string
+		orderBy []model.OrderBy
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "SUCCESS::default order",
+			want: " ORDER BY created_at",
+		},
+		{
+			name:    "SUCCESS::multiple keys",
+			orderBy: []model.OrderBy{{Column: "created_at", Desc: true}, {Column: "transaction_id"}},
+			want:    " ORDER BY created_at DESC, transaction_id",
+		},
+		{
+			name:    "FAILURE::unknown column",
+			orderBy: []model.OrderBy{{Column: "created_at; DROP TABLE x"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildOrderBy(tt.orderBy)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+				return
+			}
+			diff := testutil.Diff(got, tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
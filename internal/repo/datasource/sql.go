@@ -2,14 +2,25 @@ package datasource
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/vatsal278/TransactionManagementService/internal/config"
 	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"sort"
 	"strings"
+	"time"
 )
 
+// executor runs statements either directly on the database or inside a database transaction.
+type executor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
 type sqlDs struct {
 	sqlSvc *sql.DB
+	tx     *sql.Tx
 	table  string
 }
 
@@ -21,24 +32,42 @@ func NewSql(dbSvc config.DbSvc, tableName string) DataSourceI {
 	}
 }
 
-// queryFromMap returns a string containing SQL queries generated from a given map of filters and a join separator.
-func queryFromMap(d map[string]interface{}, join string) string {
-	var (
-		q string
-		f []string
-	)
-	for k, v := range d {
-		switch v.(type) {
-		case string:
-			f = append(f, fmt.Sprintf(`%s = '%s'`, k, v))
-		default:
-			f = append(f, fmt.Sprintf(`%s = %v`, k, v))
-		}
+// db returns the transaction the datasource is bound to, or the database when it is not bound to one.
+func (d sqlDs) db() executor {
+	if d.tx != nil {
+		return d.tx
 	}
-	if len(f) > 0 {
-		q = fmt.Sprintf(`%s`, strings.Join(f, ` `+join+` `))
-	}
-	return q
+	return d.sqlSvc
+}
+
+// outboxTable returns the name of the outbox table kept alongside the transactions table.
+func (d sqlDs) outboxTable() string {
+	return d.table + "_outbox"
+}
+
+// statusHistoryTable returns the name of the status history table kept alongside the transactions table.
+func (d sqlDs) statusHistoryTable() string {
+	return d.table + "_status_history"
+}
+
+// fxRateTable returns the name of the exchange rate table kept alongside the transactions table.
+func (d sqlDs) fxRateTable() string {
+	return d.table + "_fx_rates"
+}
+
+// spendingLockTable returns the name of the spending lock table kept alongside the transactions table.
+func (d sqlDs) spendingLockTable() string {
+	return d.table + "_spending_locks"
+}
+
+// accountTable returns the name of the account table kept alongside the transactions table.
+func (d sqlDs) accountTable() string {
+	return d.table + "_accounts"
+}
+
+// idempotencyTable returns the name of the idempotency key table kept alongside the transactions table.
+func (d sqlDs) idempotencyTable() string {
+	return d.table + "_idempotency_keys"
 }
 
 // HealthCheck checks the health of the database service.
@@ -47,54 +76,817 @@ func (d sqlDs) HealthCheck() bool {
 	return err == nil
 }
 
-// Get retrieves transactions from the database service based on a given set of filters, limit, and offset.
-func (d sqlDs) Get(filter map[string]interface{}, limit int, offset int) ([]model.Transaction, int, error) {
+// transactionColumns lists the columns of the transaction table read into a transaction
+const transactionColumns = "transaction_id, account_number, user_id, amount, transfer_to, created_at, updated_at, status, type, comment, transfer_id, reference_id, refunded_amount, currency, fx_rate, counter_amount, counter_currency, batch_id"
+
+// scanTransaction reads a transaction selected with transactionColumns
+func scanTransaction(scan func(dest ...interface{}) error) (model.Transaction, error) {
 	var transaction model.Transaction
-	var transactions []model.Transaction
-	var count int
-	q := fmt.Sprintf("SELECT transaction_id, account_number, user_id, amount, transfer_to, created_at, updated_at, status, type, comment FROM %s", d.table)
-	whereQuery := queryFromMap(filter, " AND ")
+	err := scan(&transaction.TransactionId, &transaction.AccountNumber, &transaction.UserId, &transaction.Amount, &transaction.TransferTo, &transaction.CreatedAt, &transaction.UpdatedAt, &transaction.Status, &transaction.Type, &transaction.Comment, &transaction.TransferId, &transaction.ReferenceId, &transaction.RefundedAmount, &transaction.Currency, &transaction.FxRate, &transaction.CounterAmount, &transaction.CounterCurrency, &transaction.BatchId)
+	return transaction, err
+}
+
+// selectTransactions builds the query selecting the transactions matching the query, without counting them
+func (d sqlDs) selectTransactions(query model.Query) (string, []interface{}, error) {
+	whereQuery, args, err := buildWhere(query.Where)
+	if err != nil {
+		return "", nil, err
+	}
 	if whereQuery != "" {
 		whereQuery = " WHERE " + whereQuery
-		q += whereQuery
 	}
-	queryCount := fmt.Sprintf("SELECT COUNT(`transaction_id`) FROM %s %s", d.table, whereQuery)
-	rowsCount, err := d.sqlSvc.Query(queryCount)
+	orderBy, err := buildOrderBy(query.OrderBy)
+	if err != nil {
+		return "", nil, err
+	}
+	q := fmt.Sprintf("SELECT %s FROM %s%s%s", transactionColumns, d.table, whereQuery, orderBy)
+	if query.Limit > 0 {
+		q += fmt.Sprintf(" LIMIT %d OFFSET %d", query.Limit, query.Offset)
+	}
+	return q, args, nil
+}
+
+// Get retrieves transactions matching the query along with the total number of matching rows.
+// All values are bound through placeholders, the count ignores the query's limit and offset and is skipped
+// when the query asks for it.
+func (d sqlDs) Get(query model.Query) ([]model.Transaction, int, error) {
+	var transactions []model.Transaction
+	var count int
+	q, args, err := d.selectTransactions(query)
 	if err != nil {
 		return nil, 0, err
 	}
-	for rowsCount.Next() {
-		err = rowsCount.Scan(&count)
+	if !query.SkipCount {
+		whereQuery, _, _ := buildWhere(query.Where)
+		if whereQuery != "" {
+			whereQuery = " WHERE " + whereQuery
+		}
+		queryCount := fmt.Sprintf("SELECT COUNT(`transaction_id`) FROM %s%s", d.table, whereQuery)
+		err = d.db().QueryRow(queryCount, args...).Scan(&count)
 		if err != nil {
 			return nil, 0, err
 		}
 	}
-	r := " ORDER BY created_at ;"
-	if limit > 0 {
-		r = fmt.Sprintf(" ORDER BY created_at LIMIT %d OFFSET %d ;", limit, offset)
-	}
-	q += r
-	rows, err := d.sqlSvc.Query(q)
+	err = d.stream(q, args, func(transaction model.Transaction) error {
+		transactions = append(transactions, transaction)
+		return nil
+	})
 	if err != nil {
 		return nil, 0, err
 	}
+	return transactions, count, nil
+}
+
+// Stream calls fn with each transaction matching the query, in order, as the rows are read from the database, so
+// the matching transactions are never all held in memory. The count of the query is not computed, and an error
+// returned by fn stops the stream.
+func (d sqlDs) Stream(query model.Query, fn func(model.Transaction) error) error {
+	q, args, err := d.selectTransactions(query)
+	if err != nil {
+		return err
+	}
+	return d.stream(q, args, fn)
+}
+
+// stream runs a query selecting transactionColumns and calls fn with each transaction read
+func (d sqlDs) stream(q string, args []interface{}, fn func(model.Transaction) error) error {
+	rows, err := d.db().Query(q, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
 	for rows.Next() {
-		err = rows.Scan(&transaction.TransactionId, &transaction.AccountNumber, &transaction.UserId, &transaction.Amount, &transaction.TransferTo, &transaction.CreatedAt, &transaction.UpdatedAt, &transaction.Status, &transaction.Type, &transaction.Comment)
+		transaction, err := scanTransaction(rows.Scan)
 		if err != nil {
-			return nil, 0, err
+			return err
+		}
+		err = fn(transaction)
+		if err != nil {
+			return err
 		}
-		transactions = append(transactions, transaction)
 	}
-	rows.Close()
-	return transactions, count, nil
+	return rows.Err()
 }
 
 // Insert adds a new transaction to the database service.
 func (d sqlDs) Insert(transaction model.Transaction) error {
 	queryString := fmt.Sprintf("INSERT INTO %s", d.table)
-	_, err := d.sqlSvc.Exec(queryString+"(user_id, transaction_id, account_number, amount, transfer_to, status, type, comment) VALUES(?,?,?,?,?,?,?,?)", transaction.UserId, transaction.TransactionId, transaction.AccountNumber, transaction.Amount, transaction.TransferTo, transaction.Status, transaction.Type, transaction.Comment)
+	_, err := d.db().Exec(queryString+"(user_id, transaction_id, account_number, amount, transfer_to, status, type, comment, transfer_id, reference_id, currency, fx_rate, counter_amount, counter_currency, batch_id) VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)", transaction.UserId, transaction.TransactionId, transaction.AccountNumber, transaction.Amount, transaction.TransferTo, transaction.Status, transaction.Type, transaction.Comment, transaction.TransferId, transaction.ReferenceId, transaction.Currency, transaction.FxRate, transaction.CounterAmount, transaction.CounterCurrency, transaction.BatchId)
+	if err != nil {
+		return err
+	}
+	return err
+}
+
+// InsertBatch stores several transactions with a single statement, keeping the time each was created at, for
+// transactions carried over from another system.
+func (d sqlDs) InsertBatch(transactions []model.Transaction) error {
+	if len(transactions) == 0 {
+		return nil
+	}
+	rows := make([]string, 0, len(transactions))
+	args := make([]interface{}, 0, 16*len(transactions))
+	for _, t := range transactions {
+		rows = append(rows, "(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)")
+		args = append(args, t.UserId, t.TransactionId, t.AccountNumber, t.Amount, t.TransferTo, t.Status, t.Type, t.Comment, t.TransferId, t.ReferenceId, t.Currency, t.FxRate, t.CounterAmount, t.CounterCurrency, t.BatchId, t.CreatedAt)
+	}
+	queryString := fmt.Sprintf("INSERT INTO %s(user_id, transaction_id, account_number, amount, transfer_to, status, type, comment, transfer_id, reference_id, currency, fx_rate, counter_amount, counter_currency, batch_id, created_at) VALUES%s", d.table, strings.Join(rows, ","))
+	_, err := d.db().Exec(queryString, args...)
+	return err
+}
+
+// Transaction runs fn with a datasource bound to a new database transaction, committing it when fn succeeds and
+// rolling it back when fn fails or panics. Calls made on an already bound datasource join the outer transaction.
+func (d sqlDs) Transaction(fn func(DataSourceI) error) (err error) {
+	if d.tx != nil {
+		return fn(d)
+	}
+	tx, err := d.sqlSvc.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+	err = fn(&sqlDs{sqlSvc: d.sqlSvc, tx: tx, table: d.table})
 	if err != nil {
+		rbErr := tx.Rollback()
+		if rbErr != nil {
+			return fmt.Errorf("%w, rollback failed: %v", err, rbErr)
+		}
 		return err
 	}
+	return tx.Commit()
+}
+
+// InsertOutbox records a pending outbox message, due immediately.
+func (d sqlDs) InsertOutbox(message model.OutboxMessage) error {
+	queryString := fmt.Sprintf("INSERT INTO %s(transaction_id, event_type, payload) VALUES(?,?,?)", d.outboxTable())
+	_, err := d.db().Exec(queryString, message.TransactionId, message.EventType, message.Payload)
+	return err
+}
+
+// GetDueOutbox returns up to limit pending outbox messages whose next attempt is due, oldest first.
+// The rows are locked until the surrounding transaction ends and rows locked by other dispatchers are skipped,
+// so it must be called inside Transaction.
+func (d sqlDs) GetDueOutbox(limit int) ([]model.OutboxMessage, error) {
+	queryString := fmt.Sprintf("SELECT id, transaction_id, event_type, payload, status, attempts, last_error FROM %s WHERE status = ? AND next_attempt_at <= CURRENT_TIMESTAMP ORDER BY next_attempt_at, id LIMIT %d FOR UPDATE SKIP LOCKED", d.outboxTable(), limit)
+	rows, err := d.db().Query(queryString, model.OutboxPending)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var messages []model.OutboxMessage
+	for rows.Next() {
+		var message model.OutboxMessage
+		err = rows.Scan(&message.Id, &message.TransactionId, &message.EventType, &message.Payload, &message.Status, &message.Attempts, &message.LastError)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// LeaseOutbox pushes the next attempt of outbox messages lease from now. Messages claimed by a dispatcher are leased
+// before its transaction commits, so other dispatchers leave them alone while they are delivered.
+func (d sqlDs) LeaseOutbox(ids []int64, lease time.Duration) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	args := make([]interface{}, 0, len(ids)+1)
+	args = append(args, int64(lease.Seconds()))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	queryString := fmt.Sprintf("UPDATE %s SET next_attempt_at = TIMESTAMPADD(SECOND, ?, CURRENT_TIMESTAMP) WHERE id IN (%s)", d.outboxTable(), strings.TrimSuffix(strings.Repeat("?,", len(ids)), ","))
+	_, err := d.db().Exec(queryString, args...)
+	return err
+}
+
+// UpdateOutbox stores the outcome of a delivery attempt, scheduling the next attempt RetryAfter from now.
+func (d sqlDs) UpdateOutbox(message model.OutboxMessage) error {
+	queryString := fmt.Sprintf("UPDATE %s SET status = ?, attempts = ?, last_error = ?, next_attempt_at = TIMESTAMPADD(SECOND, ?, CURRENT_TIMESTAMP) WHERE id = ?", d.outboxTable())
+	_, err := d.db().Exec(queryString, message.Status, message.Attempts, message.LastError, int64(message.RetryAfter.Seconds()), message.Id)
+	return err
+}
+
+// Update sets the given columns on the transactions matching the filter and returns the number of rows changed.
+// Including the expected current values in the filter makes the update a compare-and-set.
+func (d sqlDs) Update(where model.Filter, set map[string]interface{}) (int64, error) {
+	if len(set) == 0 {
+		return 0, errors.New("no columns to update")
+	}
+	whereQuery, whereArgs, err := buildWhere(where)
+	if err != nil {
+		return 0, err
+	}
+	if whereQuery == "" {
+		return 0, errors.New("refusing to update without a filter")
+	}
+	columns := make([]string, 0, len(set))
+	for column := range set {
+		err = checkColumn(column)
+		if err != nil {
+			return 0, err
+		}
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	assignments := make([]string, 0, len(columns))
+	args := make([]interface{}, 0, len(columns)+len(whereArgs))
+	for _, column := range columns {
+		assignments = append(assignments, column+" = ?")
+		args = append(args, set[column])
+	}
+	args = append(args, whereArgs...)
+	queryString := fmt.Sprintf("UPDATE %s SET %s WHERE %s", d.table, strings.Join(assignments, ", "), whereQuery)
+	result, err := d.db().Exec(queryString, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// InsertStatusHistory records a status change of a transaction.
+func (d sqlDs) InsertStatusHistory(change model.StatusChange) error {
+	queryString := fmt.Sprintf("INSERT INTO %s(transaction_id, from_status, to_status, changed_by, reason) VALUES(?,?,?,?,?)", d.statusHistoryTable())
+	_, err := d.db().Exec(queryString, change.TransactionId, change.FromStatus, change.ToStatus, change.ChangedBy, change.Reason)
+	return err
+}
+
+// GetStatusHistory returns the status changes of a transaction, oldest first.
+func (d sqlDs) GetStatusHistory(transactionId string) ([]model.StatusChange, error) {
+	queryString := fmt.Sprintf("SELECT transaction_id, from_status, to_status, changed_by, reason, changed_at FROM %s WHERE transaction_id = ? ORDER BY id", d.statusHistoryTable())
+	rows, err := d.db().Query(queryString, transactionId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var changes []model.StatusChange
+	for rows.Next() {
+		var change model.StatusChange
+		err = rows.Scan(&change.TransactionId, &change.FromStatus, &change.ToStatus, &change.ChangedBy, &change.Reason, &change.ChangedAt)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// settledPlaceholders returns the placeholders and arguments matching the statuses that count towards a balance
+func settledPlaceholders() (string, []interface{}) {
+	args := make([]interface{}, 0, len(model.SettledStatuses))
+	for _, status := range model.SettledStatuses {
+		args = append(args, status)
+	}
+	return strings.TrimSuffix(strings.Repeat("?,", len(args)), ","), args
+}
+
+// GetBalances returns the balance of an account in each currency it transacted in, counting the settled
+// transactions of the user created up to the given time. Credits add to the balance and debits subtract from it.
+func (d sqlDs) GetBalances(accountNumber int, userId string, asOf time.Time) ([]model.Balance, error) {
+	placeholders, statuses := settledPlaceholders()
+	queryString := fmt.Sprintf("SELECT currency, SUM(CASE WHEN type = 'credit' THEN amount ELSE -amount END) FROM %s WHERE account_number = ? AND user_id = ? AND status IN (%s) AND created_at <= ? GROUP BY currency ORDER BY currency", d.table, placeholders)
+	args := append(append([]interface{}{accountNumber, userId}, statuses...), asOf)
+	rows, err := d.db().Query(queryString, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	balances := []model.Balance{}
+	for rows.Next() {
+		var balance model.Balance
+		err = rows.Scan(&balance.Currency, &balance.Balance)
+		if err != nil {
+			return nil, err
+		}
+		balances = append(balances, balance)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	return balances, nil
+}
+
+// GetRunningBalances returns the balance of an account right after each of the given transactions of the user, in
+// the currency of that transaction. The ledger is ordered by (created_at, transaction_id), the order listings break
+// ties in.
+func (d sqlDs) GetRunningBalances(accountNumber int, userId string, transactionIds []string) (map[string]model.Money, error) {
+	balances := map[string]model.Money{}
+	if len(transactionIds) == 0 {
+		return balances, nil
+	}
+	placeholders, statuses := settledPlaceholders()
+	queryString := fmt.Sprintf("SELECT t.transaction_id, COALESCE((SELECT SUM(CASE WHEN l.type = 'credit' THEN l.amount ELSE -l.amount END) FROM %s l "+
+		"WHERE l.account_number = t.account_number AND l.user_id = t.user_id AND l.currency = t.currency AND l.status IN (%s) "+
+		"AND (l.created_at < t.created_at OR (l.created_at = t.created_at AND l.transaction_id <= t.transaction_id))), 0) "+
+		"FROM %s t WHERE t.account_number = ? AND t.user_id = ? AND t.transaction_id IN (%s)",
+		d.table, placeholders, d.table, strings.TrimSuffix(strings.Repeat("?,", len(transactionIds)), ","))
+	args := append(statuses, accountNumber, userId)
+	for _, id := range transactionIds {
+		args = append(args, id)
+	}
+	rows, err := d.db().Query(queryString, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		var balance model.Money
+		err = rows.Scan(&id, &balance)
+		if err != nil {
+			return nil, err
+		}
+		balances[id] = balance
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	return balances, nil
+}
+
+// UpsertFxRate stores an exchange rate, replacing the rate of the same currency pair taking effect at the same time.
+func (d sqlDs) UpsertFxRate(rate model.FxRate) error {
+	queryString := fmt.Sprintf("INSERT INTO %s(base_currency, quote_currency, rate, effective_at) VALUES(?,?,?,?) ON DUPLICATE KEY UPDATE rate = VALUES(rate)", d.fxRateTable())
+	_, err := d.db().Exec(queryString, rate.BaseCurrency, rate.QuoteCurrency, rate.Rate, rate.EffectiveAt)
+	return err
+}
+
+// GetFxRate returns the exchange rate from base to quote in effect at the given time, or nil when there is none.
+func (d sqlDs) GetFxRate(base string, quote string, at time.Time) (*model.FxRate, error) {
+	queryString := fmt.Sprintf("SELECT base_currency, quote_currency, rate, effective_at FROM %s WHERE base_currency = ? AND quote_currency = ? AND effective_at <= ? ORDER BY effective_at DESC LIMIT 1", d.fxRateTable())
+	var rate model.FxRate
+	err := d.db().QueryRow(queryString, base, quote, at).Scan(&rate.BaseCurrency, &rate.QuoteCurrency, &rate.Rate, &rate.EffectiveAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+// LockUser locks the spending lock row of a user until the database transaction ends, creating the row on first use,
+// so concurrent debits of the same user are checked against their spending limits one at a time.
+func (d sqlDs) LockUser(userId string) error {
+	queryString := fmt.Sprintf("INSERT INTO %s(user_id) VALUES(?) ON DUPLICATE KEY UPDATE user_id = user_id", d.spendingLockTable())
+	_, err := d.db().Exec(queryString, userId)
+	return err
+}
+
+// GetSpending returns the total amount and the number of transactions matching the filter.
+func (d sqlDs) GetSpending(where model.Filter) (model.Money, int, error) {
+	whereQuery, args, err := buildWhere(where)
+	if err != nil {
+		return 0, 0, err
+	}
+	if whereQuery != "" {
+		whereQuery = " WHERE " + whereQuery
+	}
+	var total model.Money
+	var count int
+	queryString := fmt.Sprintf("SELECT COALESCE(SUM(amount), 0), COUNT(`transaction_id`) FROM %s%s", d.table, whereQuery)
+	err = d.db().QueryRow(queryString, args...).Scan(&total, &count)
+	if err != nil {
+		return 0, 0, err
+	}
+	return total, count, nil
+}
+
+// riskHitTable returns the name of the risk hit table kept alongside the transactions table.
+func (d sqlDs) riskHitTable() string {
+	return d.table + "_risk_hits"
+}
+
+// InsertRiskHit records a risk rule matching a transaction.
+func (d sqlDs) InsertRiskHit(hit model.RiskHit) error {
+	queryString := fmt.Sprintf("INSERT INTO %s(transaction_id, rule, type, score, detail, decision) VALUES(?,?,?,?,?,?)", d.riskHitTable())
+	_, err := d.db().Exec(queryString, hit.TransactionId, hit.Rule, hit.Type, hit.Score, hit.Detail, hit.Decision)
+	return err
+}
+
+// GetRiskHits returns the risk rules a transaction matched, in the order they were recorded.
+func (d sqlDs) GetRiskHits(transactionId string) ([]model.RiskHit, error) {
+	queryString := fmt.Sprintf("SELECT transaction_id, rule, type, score, detail, decision, created_at FROM %s WHERE transaction_id = ? ORDER BY id", d.riskHitTable())
+	rows, err := d.db().Query(queryString, transactionId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var hits []model.RiskHit
+	for rows.Next() {
+		var hit model.RiskHit
+		err = rows.Scan(&hit.TransactionId, &hit.Rule, &hit.Type, &hit.Score, &hit.Detail, &hit.Decision, &hit.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, hit)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	return hits, nil
+}
+
+// scheduleTable returns the name of the schedule table kept alongside the transactions table.
+func (d sqlDs) scheduleTable() string {
+	return d.table + "_schedules"
+}
+
+// scheduleExecutionTable returns the name of the schedule execution table kept alongside the transactions table.
+func (d sqlDs) scheduleExecutionTable() string {
+	return d.table + "_schedule_executions"
+}
+
+// scheduleColumns lists the columns of a schedule in the order scanSchedule reads them
+const scheduleColumns = "schedule_id, user_id, account_number, amount, transfer_to, type, transaction_status, comment, currency, frequency, cron, start_at, end_at, next_run_at, due_at, status, attempts, last_error, created_at, updated_at"
+
+// scanSchedule reads a schedule selected with scheduleColumns
+func scanSchedule(scan func(dest ...interface{}) error) (model.Schedule, error) {
+	var s model.Schedule
+	err := scan(&s.ScheduleId, &s.UserId, &s.AccountNumber, &s.Amount, &s.TransferTo, &s.Type, &s.TransactionStatus, &s.Comment, &s.Currency, &s.Frequency, &s.Cron, &s.StartAt, &s.EndAt, &s.NextRunAt, &s.DueAt, &s.Status, &s.Attempts, &s.LastError, &s.CreatedAt, &s.UpdatedAt)
+	return s, err
+}
+
+// InsertSchedule stores a new schedule.
+func (d sqlDs) InsertSchedule(s model.Schedule) error {
+	queryString := fmt.Sprintf("INSERT INTO %s(schedule_id, user_id, account_number, amount, transfer_to, type, transaction_status, comment, currency, frequency, cron, start_at, end_at, next_run_at, due_at, status) VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)", d.scheduleTable())
+	_, err := d.db().Exec(queryString, s.ScheduleId, s.UserId, s.AccountNumber, s.Amount, s.TransferTo, s.Type, s.TransactionStatus, s.Comment, s.Currency, s.Frequency, s.Cron, s.StartAt, s.EndAt, s.NextRunAt, s.DueAt, s.Status)
+	return err
+}
+
+// UpdateSchedule stores every column of a schedule but its owner and creation time.
+func (d sqlDs) UpdateSchedule(s model.Schedule) error {
+	queryString := fmt.Sprintf("UPDATE %s SET account_number = ?, amount = ?, transfer_to = ?, type = ?, transaction_status = ?, comment = ?, currency = ?, frequency = ?, cron = ?, start_at = ?, end_at = ?, next_run_at = ?, due_at = ?, status = ?, attempts = ?, last_error = ? WHERE schedule_id = ?", d.scheduleTable())
+	_, err := d.db().Exec(queryString, s.AccountNumber, s.Amount, s.TransferTo, s.Type, s.TransactionStatus, s.Comment, s.Currency, s.Frequency, s.Cron, s.StartAt, s.EndAt, s.NextRunAt, s.DueAt, s.Status, s.Attempts, s.LastError, s.ScheduleId)
+	return err
+}
+
+// querySchedules returns the schedules selected by the query, which must select scheduleColumns.
+func (d sqlDs) querySchedules(queryString string, args ...interface{}) ([]model.Schedule, error) {
+	rows, err := d.db().Query(queryString, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var schedules []model.Schedule
+	for rows.Next() {
+		s, err := scanSchedule(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, s)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// GetSchedules returns the schedules of a user, oldest first.
+func (d sqlDs) GetSchedules(userId string) ([]model.Schedule, error) {
+	queryString := fmt.Sprintf("SELECT %s FROM %s WHERE user_id = ? ORDER BY created_at, schedule_id", scheduleColumns, d.scheduleTable())
+	return d.querySchedules(queryString, userId)
+}
+
+// GetSchedule returns a schedule, or nil when there is none with the id.
+func (d sqlDs) GetSchedule(scheduleId string) (*model.Schedule, error) {
+	queryString := fmt.Sprintf("SELECT %s FROM %s WHERE schedule_id = ?", scheduleColumns, d.scheduleTable())
+	s, err := scanSchedule(d.db().QueryRow(queryString, scheduleId).Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// LockSchedule returns a schedule locked until the surrounding transaction ends, or nil when there is none with the
+// id or another transaction holds its lock, so it must be called inside Transaction.
+func (d sqlDs) LockSchedule(scheduleId string) (*model.Schedule, error) {
+	queryString := fmt.Sprintf("SELECT %s FROM %s WHERE schedule_id = ? FOR UPDATE SKIP LOCKED", scheduleColumns, d.scheduleTable())
+	s, err := scanSchedule(d.db().QueryRow(queryString, scheduleId).Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetDueSchedules returns up to limit active schedules whose next attempt is due, the most overdue first.
+// The schedules are not locked, each is locked again when it is run.
+func (d sqlDs) GetDueSchedules(limit int) ([]model.Schedule, error) {
+	queryString := fmt.Sprintf("SELECT %s FROM %s WHERE status = ? AND due_at <= UTC_TIMESTAMP() ORDER BY due_at, schedule_id LIMIT %d", scheduleColumns, d.scheduleTable(), limit)
+	return d.querySchedules(queryString, model.ScheduleActive)
+}
+
+// InsertScheduleExecution records the outcome of an attempt at running a schedule.
+func (d sqlDs) InsertScheduleExecution(e model.ScheduleExecution) error {
+	queryString := fmt.Sprintf("INSERT INTO %s(schedule_id, run_at, attempt, outcome, transaction_id, message) VALUES(?,?,?,?,?,?)", d.scheduleExecutionTable())
+	_, err := d.db().Exec(queryString, e.ScheduleId, e.RunAt, e.Attempt, e.Outcome, e.TransactionId, e.Message)
+	return err
+}
+
+// GetScheduleExecutions returns up to limit of the latest executions of a schedule, newest first.
+func (d sqlDs) GetScheduleExecutions(scheduleId string, limit int) ([]model.ScheduleExecution, error) {
+	queryString := fmt.Sprintf("SELECT schedule_id, run_at, attempt, outcome, transaction_id, message, executed_at FROM %s WHERE schedule_id = ? ORDER BY id DESC LIMIT %d", d.scheduleExecutionTable(), limit)
+	rows, err := d.db().Query(queryString, scheduleId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	executions := []model.ScheduleExecution{}
+	for rows.Next() {
+		var e model.ScheduleExecution
+		err = rows.Scan(&e.ScheduleId, &e.RunAt, &e.Attempt, &e.Outcome, &e.TransactionId, &e.Message, &e.ExecutedAt)
+		if err != nil {
+			return nil, err
+		}
+		executions = append(executions, e)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	return executions, nil
+}
+
+// webhookTable returns the name of the webhook table kept alongside the transactions table.
+func (d sqlDs) webhookTable() string {
+	return d.table + "_webhooks"
+}
+
+// webhookDeliveryTable returns the name of the webhook delivery table kept alongside the transactions table.
+func (d sqlDs) webhookDeliveryTable() string {
+	return d.table + "_webhook_deliveries"
+}
+
+// webhookColumns lists the columns of a webhook in the order scanWebhook reads them
+const webhookColumns = "webhook_id, user_id, client_id, url, events, secret, status, created_at, updated_at"
+
+// scanWebhook reads a webhook selected with webhookColumns, its event types being stored comma separated
+func scanWebhook(scan func(dest ...interface{}) error) (model.Webhook, error) {
+	var w model.Webhook
+	var events string
+	err := scan(&w.WebhookId, &w.UserId, &w.ClientId, &w.Url, &events, &w.Secret, &w.Status, &w.CreatedAt, &w.UpdatedAt)
+	if events != "" {
+		w.Events = strings.Split(events, ",")
+	}
+	return w, err
+}
+
+// InsertWebhook stores a new webhook.
+func (d sqlDs) InsertWebhook(w model.Webhook) error {
+	queryString := fmt.Sprintf("INSERT INTO %s(webhook_id, user_id, client_id, url, events, secret, status) VALUES(?,?,?,?,?,?,?)", d.webhookTable())
+	_, err := d.db().Exec(queryString, w.WebhookId, w.UserId, w.ClientId, w.Url, strings.Join(w.Events, ","), w.Secret, w.Status)
+	return err
+}
+
+// GetWebhooks returns the webhooks of a user, oldest first.
+func (d sqlDs) GetWebhooks(userId string) ([]model.Webhook, error) {
+	queryString := fmt.Sprintf("SELECT %s FROM %s WHERE user_id = ? ORDER BY created_at, webhook_id", webhookColumns, d.webhookTable())
+	rows, err := d.db().Query(queryString, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	webhooks := []model.Webhook{}
+	for rows.Next() {
+		w, err := scanWebhook(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, w)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// GetWebhook returns a webhook, or nil when there is none with the id.
+func (d sqlDs) GetWebhook(webhookId string) (*model.Webhook, error) {
+	queryString := fmt.Sprintf("SELECT %s FROM %s WHERE webhook_id = ?", webhookColumns, d.webhookTable())
+	w, err := scanWebhook(d.db().QueryRow(queryString, webhookId).Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// UpdateWebhookStatus moves a webhook to another status.
+func (d sqlDs) UpdateWebhookStatus(webhookId string, status string) error {
+	queryString := fmt.Sprintf("UPDATE %s SET status = ? WHERE webhook_id = ?", d.webhookTable())
+	_, err := d.db().Exec(queryString, status, webhookId)
+	return err
+}
+
+// InsertWebhookDeliveries queues an event for every active webhook of the user subscribing to its type, in a single
+// statement, so nothing is written when the user has no such webhook.
+func (d sqlDs) InsertWebhookDeliveries(userId string, delivery model.WebhookDelivery) error {
+	queryString := fmt.Sprintf("INSERT INTO %s(delivery_id, webhook_id, event_id, event_type, transaction_id, payload) SELECT UUID(), webhook_id, ?, ?, ?, ? FROM %s WHERE user_id = ? AND status = ? AND FIND_IN_SET(?, events) > 0", d.webhookDeliveryTable(), d.webhookTable())
+	_, err := d.db().Exec(queryString, delivery.EventId, delivery.EventType, delivery.TransactionId, delivery.Payload, userId, model.WebhookActive, delivery.EventType)
+	return err
+}
+
+// webhookDeliveryColumns lists the columns of a webhook delivery in the order scanWebhookDelivery reads them
+const webhookDeliveryColumns = "delivery_id, webhook_id, event_id, event_type, transaction_id, payload, status, attempts, response_status, last_error, next_attempt_at, created_at, updated_at"
+
+// scanWebhookDelivery reads a webhook delivery selected with webhookDeliveryColumns, followed by the extra
+// destinations given
+func scanWebhookDelivery(scan func(dest ...interface{}) error, extra ...interface{}) (model.WebhookDelivery, error) {
+	var w model.WebhookDelivery
+	dest := []interface{}{&w.DeliveryId, &w.WebhookId, &w.EventId, &w.EventType, &w.TransactionId, &w.Payload, &w.Status, &w.Attempts, &w.ResponseStatus, &w.LastError, &w.NextAttemptAt, &w.CreatedAt, &w.UpdatedAt}
+	err := scan(append(dest, extra...)...)
+	return w, err
+}
+
+// GetWebhookDeliveries returns up to limit of the latest deliveries of a webhook, newest first.
+func (d sqlDs) GetWebhookDeliveries(webhookId string, limit int) ([]model.WebhookDelivery, error) {
+	queryString := fmt.Sprintf("SELECT %s FROM %s WHERE webhook_id = ? ORDER BY created_at DESC, delivery_id LIMIT %d", webhookDeliveryColumns, d.webhookDeliveryTable(), limit)
+	rows, err := d.db().Query(queryString, webhookId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	deliveries := []model.WebhookDelivery{}
+	for rows.Next() {
+		w, err := scanWebhookDelivery(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, w)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// GetWebhookDelivery returns a webhook delivery, or nil when there is none with the id.
+func (d sqlDs) GetWebhookDelivery(deliveryId string) (*model.WebhookDelivery, error) {
+	queryString := fmt.Sprintf("SELECT %s FROM %s WHERE delivery_id = ?", webhookDeliveryColumns, d.webhookDeliveryTable())
+	w, err := scanWebhookDelivery(d.db().QueryRow(queryString, deliveryId).Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// GetDueWebhookDeliveries returns up to limit pending webhook deliveries whose next attempt is due, oldest first,
+// along with the endpoint, secret and status of their webhook. The rows are locked until the surrounding transaction
+// ends and rows locked by other dispatchers are skipped, so it must be called inside Transaction.
+func (d sqlDs) GetDueWebhookDeliveries(limit int) ([]model.WebhookDelivery, error) {
+	queryString := fmt.Sprintf("SELECT d.%s, w.url, w.secret, w.status FROM %s d JOIN %s w ON w.webhook_id = d.webhook_id WHERE d.status = ? AND d.next_attempt_at <= CURRENT_TIMESTAMP ORDER BY d.next_attempt_at, d.delivery_id LIMIT %d FOR UPDATE SKIP LOCKED", strings.ReplaceAll(webhookDeliveryColumns, ", ", ", d."), d.webhookDeliveryTable(), d.webhookTable(), limit)
+	rows, err := d.db().Query(queryString, model.WebhookDeliveryPending)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var deliveries []model.WebhookDelivery
+	for rows.Next() {
+		var url, secret, status string
+		w, err := scanWebhookDelivery(rows.Scan, &url, &secret, &status)
+		if err != nil {
+			return nil, err
+		}
+		w.Url, w.Secret, w.WebhookStatus = url, secret, status
+		deliveries = append(deliveries, w)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// LeaseWebhookDeliveries pushes the next attempt of webhook deliveries lease from now. Deliveries claimed by a
+// dispatcher are leased before its transaction commits, so other dispatchers leave them alone while they are sent.
+func (d sqlDs) LeaseWebhookDeliveries(ids []string, lease time.Duration) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	args := make([]interface{}, 0, len(ids)+1)
+	args = append(args, int64(lease.Seconds()))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	queryString := fmt.Sprintf("UPDATE %s SET next_attempt_at = TIMESTAMPADD(SECOND, ?, CURRENT_TIMESTAMP) WHERE delivery_id IN (%s)", d.webhookDeliveryTable(), strings.TrimSuffix(strings.Repeat("?,", len(ids)), ","))
+	_, err := d.db().Exec(queryString, args...)
+	return err
+}
+
+// UpdateWebhookDelivery stores the outcome of a delivery attempt, scheduling the next attempt RetryAfter from now.
+func (d sqlDs) UpdateWebhookDelivery(delivery model.WebhookDelivery) error {
+	queryString := fmt.Sprintf("UPDATE %s SET status = ?, attempts = ?, response_status = ?, last_error = ?, next_attempt_at = TIMESTAMPADD(SECOND, ?, CURRENT_TIMESTAMP) WHERE delivery_id = ?", d.webhookDeliveryTable())
+	_, err := d.db().Exec(queryString, delivery.Status, delivery.Attempts, delivery.ResponseStatus, delivery.LastError, int64(delivery.RetryAfter.Seconds()), delivery.DeliveryId)
+	return err
+}
+
+// ReserveIdempotencyKey records an idempotency key as in progress and reports whether it was reserved, which it is
+// not when the user already used the key. The insert is atomic, so of concurrent requests with the same key only one
+// reserves it. Keys of the user older than ttl are forgotten first, so they can be used again.
+func (d sqlDs) ReserveIdempotencyKey(record model.IdempotencyRecord, ttl time.Duration) (bool, error) {
+	queryString := fmt.Sprintf("DELETE FROM %s WHERE user_id = ? AND created_at < TIMESTAMPADD(SECOND, ?, CURRENT_TIMESTAMP)", d.idempotencyTable())
+	_, err := d.db().Exec(queryString, record.UserId, -int64(ttl.Seconds()))
+	if err != nil {
+		return false, err
+	}
+	queryString = fmt.Sprintf("INSERT INTO %s(user_id, idempotency_key, fingerprint, response) VALUES(?,?,?,'') ON DUPLICATE KEY UPDATE user_id = user_id", d.idempotencyTable())
+	result, err := d.db().Exec(queryString, record.UserId, record.IdempotencyKey, record.Fingerprint)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+// GetIdempotencyKey fetches an idempotency key of the user, or nil when the user has not used it.
+func (d sqlDs) GetIdempotencyKey(userId string, idempotencyKey string) (*model.IdempotencyRecord, error) {
+	queryString := fmt.Sprintf("SELECT user_id, idempotency_key, fingerprint, completed, status, response, content_type FROM %s WHERE user_id = ? AND idempotency_key = ?", d.idempotencyTable())
+	var record model.IdempotencyRecord
+	err := d.db().QueryRow(queryString, userId, idempotencyKey).Scan(&record.UserId, &record.IdempotencyKey, &record.Fingerprint, &record.Completed, &record.Status, &record.Response, &record.ContentType)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// CompleteIdempotencyKey stores the response of the request that reserved an idempotency key, to be replayed to
+// its retries.
+func (d sqlDs) CompleteIdempotencyKey(record model.IdempotencyRecord) error {
+	queryString := fmt.Sprintf("UPDATE %s SET completed = TRUE, status = ?, response = ?, content_type = ? WHERE user_id = ? AND idempotency_key = ?", d.idempotencyTable())
+	_, err := d.db().Exec(queryString, record.Status, record.Response, record.ContentType, record.UserId, record.IdempotencyKey)
 	return err
 }
+
+// ReleaseIdempotencyKey forgets an idempotency key, so the request can be retried with it.
+func (d sqlDs) ReleaseIdempotencyKey(userId string, idempotencyKey string) error {
+	queryString := fmt.Sprintf("DELETE FROM %s WHERE user_id = ? AND idempotency_key = ?", d.idempotencyTable())
+	_, err := d.db().Exec(queryString, userId, idempotencyKey)
+	return err
+}
+
+// InsertAccount registers the owner of an account and reports whether it was registered, which it is not when the
+// account already is.
+func (d sqlDs) InsertAccount(account model.Account) (bool, error) {
+	queryString := fmt.Sprintf("INSERT INTO %s(account_number, user_id, currency) VALUES(?,?,?) ON DUPLICATE KEY UPDATE account_number = account_number", d.accountTable())
+	result, err := d.db().Exec(queryString, account.AccountNumber, account.UserId, account.Currency)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+// GetAccount fetches a registered account, or nil when the account is not registered.
+func (d sqlDs) GetAccount(accountNumber int) (*model.Account, error) {
+	queryString := fmt.Sprintf("SELECT account_number, user_id, currency, created_at FROM %s WHERE account_number = ?", d.accountTable())
+	var account model.Account
+	err := d.db().QueryRow(queryString, accountNumber).Scan(&account.AccountNumber, &account.UserId, &account.Currency, &account.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
@@ -1,6 +1,7 @@
 package datasource
 
 import (
+	"database/sql/driver"
 	"errors"
 	"github.com/DATA-DOG/go-sqlmock"
 	_ "github.com/go-sql-driver/mysql"
@@ -48,18 +49,16 @@ func TestSqlDs_HealthCheck(t *testing.T) {
 	}
 }
 func TestSqlDs_Get(t *testing.T) {
+	columns := []string{"transaction_id", "account_number", "user_id", "amount", "transfer_to", "created_at", "updated_at", "status", "type", "comment", "transfer_id", "reference_id", "refunded_amount", "currency", "fx_rate", "counter_amount", "counter_currency", "batch_id"}
 	tests := []struct {
 		name      string
 		setupFunc func() (sqlDs, sqlmock.Sqlmock)
-		filter    map[string]interface{}
+		query     model.Query
 		validator func([]model.Transaction, int, error, sqlmock.Sqlmock)
 	}{
 		{
-			name: "SUCCESS::Get",
-			filter: map[string]interface{}{
-				"user_id":        "1234",
-				"account_number": 1,
-			},
+			name:  "SUCCESS::Get",
+			query: model.Query{Where: model.And(model.Eq("user_id", "1234"), model.Eq("account_number", 1)), Limit: 1, Offset: 2},
 			setupFunc: func() (sqlDs, sqlmock.Sqlmock) {
 				db, mock, err := sqlmock.New()
 				if err != nil {
@@ -69,8 +68,8 @@ func TestSqlDs_Get(t *testing.T) {
 					sqlSvc: db,
 					table:  "newTemp",
 				}
-				mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(`transaction_id`) FROM newTemp WHERE user_id = '1234' AND account_number = 1")).WillReturnError(nil).WillReturnRows(sqlmock.NewRows([]string{"count(transaction_id)"}).AddRow("1"))
-				mock.ExpectQuery("SELECT transaction_id, account_number, user_id, amount, transfer_to, created_at, updated_at, status, type, comment FROM newTemp WHERE user_id = '1234' AND account_number = 1 ORDER BY created_at LIMIT 1 OFFSET 2 ;").WillReturnRows(sqlmock.NewRows([]string{"transaction_id", "account_number", "user_id", "amount", "transfer_to", "created_at", "updated_at", "status", "type", "comment"}).AddRow("0000-1111-2222-3333", 1, "4444-1111-2222-3333", 1000, 1234567890, time.Date(2023, time.December, 1, 1, 1, 1, 0, time.UTC), time.Date(2023, time.December, 1, 1, 1, 1, 0, time.UTC), "approved", "debit", "no comments"))
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(`transaction_id`) FROM newTemp WHERE user_id = ? AND account_number = ?")).WithArgs("1234", 1).WillReturnRows(sqlmock.NewRows([]string{"count(transaction_id)"}).AddRow("1"))
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT transaction_id, account_number, user_id, amount, transfer_to, created_at, updated_at, status, type, comment, transfer_id, reference_id, refunded_amount, currency, fx_rate, counter_amount, counter_currency, batch_id FROM newTemp WHERE user_id = ? AND account_number = ? ORDER BY created_at LIMIT 1 OFFSET 2")).WithArgs("1234", 1).WillReturnRows(sqlmock.NewRows(columns).AddRow("0000-1111-2222-3333", 1, "4444-1111-2222-3333", 1000, 1234567890, time.Date(2023, time.December, 1, 1, 1, 1, 0, time.UTC), time.Date(2023, time.December, 1, 1, 1, 1, 0, time.UTC), "approved", "debit", "no comments", "", "", 0, "USD", 0, 0, "", ""))
 				return dB, mock
 			},
 			validator: func(rows []model.Transaction, count int, err error, mock sqlmock.Sqlmock) {
@@ -78,13 +77,14 @@ func TestSqlDs_Get(t *testing.T) {
 					TransactionId: "0000-1111-2222-3333",
 					AccountNumber: 1,
 					UserId:        "4444-1111-2222-3333",
-					Amount:        1000,
+					Amount:        1000 * model.MajorUnit,
 					TransferTo:    1234567890,
 					CreatedAt:     time.Date(2023, time.December, 1, 1, 1, 1, 0, time.UTC),
 					UpdatedAt:     time.Date(2023, time.December, 1, 1, 1, 1, 0, time.UTC),
 					Status:        "approved",
 					Type:          "debit",
 					Comment:       "no comments",
+					Currency:      "USD",
 				}}
 				if mock.ExpectationsWereMet() != nil {
 					t.Errorf("Want: %v, Got: %v", nil, mock.ExpectationsWereMet())
@@ -95,7 +95,7 @@ func TestSqlDs_Get(t *testing.T) {
 					return
 				}
 				if count != 1 {
-					t.Errorf("Want: %v, Got: %v", 3, count)
+					t.Errorf("Want: %v, Got: %v", 1, count)
 					return
 				}
 				if !reflect.DeepEqual(rows, temp) {
@@ -105,8 +105,210 @@ func TestSqlDs_Get(t *testing.T) {
 			},
 		},
 		{
-			name:   "FAILURE::Get:: get rows query error",
-			filter: map[string]interface{}{"userid": "1234"},
+			name:  "SUCCESS::Get:: skip count",
+			query: model.Query{Where: model.Eq("user_id", "1234"), OrderBy: []model.OrderBy{{Column: "created_at", Desc: true}, {Column: "transaction_id", Desc: true}}, Limit: 3, SkipCount: true},
+			setupFunc: func() (sqlDs, sqlmock.Sqlmock) {
+				db, mock, err := sqlmock.New()
+				if err != nil {
+					t.Fail()
+				}
+				dB := sqlDs{
+					sqlSvc: db,
+					table:  "newTemp",
+				}
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT transaction_id, account_number, user_id, amount, transfer_to, created_at, updated_at, status, type, comment, transfer_id, reference_id, refunded_amount, currency, fx_rate, counter_amount, counter_currency, batch_id FROM newTemp WHERE user_id = ? ORDER BY created_at DESC, transaction_id DESC LIMIT 3 OFFSET 0")).WithArgs("1234").WillReturnRows(sqlmock.NewRows(columns).AddRow("0000-1111-2222-3333", 1, "1234", 1000, 1234567890, time.Date(2023, time.December, 1, 1, 1, 1, 0, time.UTC), time.Date(2023, time.December, 1, 1, 1, 1, 0, time.UTC), "approved", "debit", "no comments", "", "", 0, "USD", 0, 0, "", ""))
+				return dB, mock
+			},
+			validator: func(rows []model.Transaction, count int, err error, mock sqlmock.Sqlmock) {
+				if mock.ExpectationsWereMet() != nil {
+					t.Errorf("Want: %v, Got: %v", nil, mock.ExpectationsWereMet())
+					return
+				}
+				if err != nil {
+					t.Errorf("Want: %v, Got: %v", nil, err)
+					return
+				}
+				if count != 0 || len(rows) != 1 {
+					t.Errorf("Want: %v rows and count %v, Got: %v rows and count %v", 1, 0, len(rows), count)
+				}
+			},
+		},
+		{
+			name:  "SUCCESS::Get:: no filter no limit",
+			query: model.Query{},
+			setupFunc: func() (sqlDs, sqlmock.Sqlmock) {
+				db, mock, err := sqlmock.New()
+				if err != nil {
+					t.Fail()
+				}
+				dB := sqlDs{
+					sqlSvc: db,
+					table:  "newTemp",
+				}
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(`transaction_id`) FROM newTemp")).WithArgs().WillReturnRows(sqlmock.NewRows([]string{"count(transaction_id)"}).AddRow("0"))
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT transaction_id, account_number, user_id, amount, transfer_to, created_at, updated_at, status, type, comment, transfer_id, reference_id, refunded_amount, currency, fx_rate, counter_amount, counter_currency, batch_id FROM newTemp ORDER BY created_at")).WithArgs().WillReturnRows(sqlmock.NewRows(columns))
+				return dB, mock
+			},
+			validator: func(rows []model.Transaction, count int, err error, mock sqlmock.Sqlmock) {
+				if mock.ExpectationsWereMet() != nil {
+					t.Errorf("Want: %v, Got: %v", nil, mock.ExpectationsWereMet())
+					return
+				}
+				if err != nil {
+					t.Errorf("Want: %v, Got: %v", nil, err)
+					return
+				}
+				if count != 0 || len(rows) != 0 {
+					t.Errorf("Want: %v, Got: %v", 0, count)
+				}
+			},
+		},
+		{
+			name: "SUCCESS::Get:: operators groups and order",
+			query: model.Query{
+				Where: model.And(
+					model.Eq("user_id", "1234"),
+					model.In("status", "approved", "rejected"),
+					model.Between("created_at", time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, time.February, 1, 0, 0, 0, 0, time.UTC)),
+					model.Or(model.Like("comment", "%rent%"), model.IsNull("comment")),
+					model.Gte("amount", 10),
+					model.Lt("amount", 100),
+				),
+				OrderBy: []model.OrderBy{{Column: "amount", Desc: true}, {Column: "transaction_id"}},
+				Limit:   5,
+			},
+			setupFunc: func() (sqlDs, sqlmock.Sqlmock) {
+				db, mock, err := sqlmock.New()
+				if err != nil {
+					t.Fail()
+				}
+				dB := sqlDs{
+					sqlSvc: db,
+					table:  "newTemp",
+				}
+				where := "WHERE user_id = ? AND status IN (?, ?) AND created_at BETWEEN ? AND ? AND (comment LIKE ? OR comment IS NULL) AND amount >= ? AND amount < ?"
+				args := []driver.Value{"1234", "approved", "rejected", time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, time.February, 1, 0, 0, 0, 0, time.UTC), "%rent%", 10, 100}
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(`transaction_id`) FROM newTemp " + where)).WithArgs(args...).WillReturnRows(sqlmock.NewRows([]string{"count(transaction_id)"}).AddRow("0"))
+				mock.ExpectQuery(regexp.QuoteMeta("FROM newTemp " + where + " ORDER BY amount DESC, transaction_id LIMIT 5 OFFSET 0")).WithArgs(args...).WillReturnRows(sqlmock.NewRows(columns))
+				return dB, mock
+			},
+			validator: func(rows []model.Transaction, count int, err error, mock sqlmock.Sqlmock) {
+				if mock.ExpectationsWereMet() != nil {
+					t.Errorf("Want: %v, Got: %v", nil, mock.ExpectationsWereMet())
+					return
+				}
+				if err != nil {
+					t.Errorf("Want: %v, Got: %v", nil, err)
+				}
+			},
+		},
+		{
+			name:  "SUCCESS::Get:: values are bound not interpolated",
+			query: model.Query{Where: model.Eq("user_id", "1' OR '1'='1")},
+			setupFunc: func() (sqlDs, sqlmock.Sqlmock) {
+				db, mock, err := sqlmock.New()
+				if err != nil {
+					t.Fail()
+				}
+				dB := sqlDs{
+					sqlSvc: db,
+					table:  "newTemp",
+				}
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(`transaction_id`) FROM newTemp WHERE user_id = ?")).WithArgs("1' OR '1'='1").WillReturnRows(sqlmock.NewRows([]string{"count(transaction_id)"}).AddRow("0"))
+				mock.ExpectQuery(regexp.QuoteMeta("FROM newTemp WHERE user_id = ? ORDER BY created_at")).WithArgs("1' OR '1'='1").WillReturnRows(sqlmock.NewRows(columns))
+				return dB, mock
+			},
+			validator: func(rows []model.Transaction, count int, err error, mock sqlmock.Sqlmock) {
+				if mock.ExpectationsWereMet() != nil {
+					t.Errorf("Want: %v, Got: %v", nil, mock.ExpectationsWereMet())
+					return
+				}
+				if err != nil {
+					t.Errorf("Want: %v, Got: %v", nil, err)
+				}
+			},
+		},
+		{
+			name:  "SUCCESS::Get:: account owned by another user is not matched",
+			query: model.Query{Where: model.And(model.Eq("user_id", "1234"), model.Eq("account_number", 55)), Limit: 1},
+			setupFunc: func() (sqlDs, sqlmock.Sqlmock) {
+				db, mock, err := sqlmock.New()
+				if err != nil {
+					t.Fail()
+				}
+				dB := sqlDs{
+					sqlSvc: db,
+					table:  "newTemp",
+				}
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(`transaction_id`) FROM newTemp WHERE user_id = ? AND account_number = ?")).WithArgs("1234", 55).WillReturnRows(sqlmock.NewRows([]string{"count(transaction_id)"}).AddRow("0"))
+				mock.ExpectQuery(regexp.QuoteMeta("FROM newTemp WHERE user_id = ? AND account_number = ? ORDER BY created_at LIMIT 1 OFFSET 0")).WithArgs("1234", 55).WillReturnRows(sqlmock.NewRows(columns))
+				return dB, mock
+			},
+			validator: func(rows []model.Transaction, count int, err error, mock sqlmock.Sqlmock) {
+				if mock.ExpectationsWereMet() != nil {
+					t.Errorf("Want: %v, Got: %v", nil, mock.ExpectationsWereMet())
+					return
+				}
+				if err != nil {
+					t.Errorf("Want: %v, Got: %v", nil, err)
+					return
+				}
+				if count != 0 || len(rows) != 0 {
+					t.Errorf("Want: %v, Got: %v", 0, rows)
+				}
+			},
+		},
+		{
+			name:  "FAILURE::Get:: unknown filter column",
+			query: model.Query{Where: model.Eq("user_id = '1' OR 1 = 1 --", "1234")},
+			setupFunc: func() (sqlDs, sqlmock.Sqlmock) {
+				db, mock, err := sqlmock.New()
+				if err != nil {
+					t.Fail()
+				}
+				dB := sqlDs{
+					sqlSvc: db,
+					table:  "newTemp",
+				}
+				return dB, mock
+			},
+			validator: func(rows []model.Transaction, count int, err error, mock sqlmock.Sqlmock) {
+				if mock.ExpectationsWereMet() != nil {
+					t.Errorf("Want: %v, Got: %v", nil, mock.ExpectationsWereMet())
+					return
+				}
+				if err == nil || !strings.Contains(err.Error(), "unknown column") {
+					t.Errorf("Want: %v, Got: %v", "unknown column", err)
+				}
+			},
+		},
+		{
+			name:  "FAILURE::Get:: unknown sort column",
+			query: model.Query{Where: model.Eq("user_id", "1234"), OrderBy: []model.OrderBy{{Column: "1; DROP TABLE newTemp"}}},
+			setupFunc: func() (sqlDs, sqlmock.Sqlmock) {
+				db, mock, err := sqlmock.New()
+				if err != nil {
+					t.Fail()
+				}
+				dB := sqlDs{
+					sqlSvc: db,
+					table:  "newTemp",
+				}
+				return dB, mock
+			},
+			validator: func(rows []model.Transaction, count int, err error, mock sqlmock.Sqlmock) {
+				if mock.ExpectationsWereMet() != nil {
+					t.Errorf("Want: %v, Got: %v", nil, mock.ExpectationsWereMet())
+					return
+				}
+				if err == nil || !strings.Contains(err.Error(), "unknown column") {
+					t.Errorf("Want: %v, Got: %v", "unknown column", err)
+				}
+			},
+		},
+		{
+			name:  "FAILURE::Get:: get rows query error",
+			query: model.Query{Where: model.Eq("user_id", "1234"), Limit: 1, Offset: 2},
 			setupFunc: func() (sqlDs, sqlmock.Sqlmock) {
 				db, mock, err := sqlmock.New()
 				if err != nil {
@@ -116,8 +318,8 @@ func TestSqlDs_Get(t *testing.T) {
 					sqlSvc: db,
 					table:  "newTemp",
 				}
-				mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(`transaction_id`) FROM newTemp WHERE userid = '1234'")).WillReturnError(nil).WillReturnRows(sqlmock.NewRows([]string{"transaction_id"}).AddRow("1").AddRow("2").AddRow("3"))
-				mock.ExpectQuery("SELECT transaction_id, account_number, user_id, amount, transfer_to, created_at, updated_at, status, type, comment FROM newTemp WHERE userid = '1234' ORDER BY created_at LIMIT 1 OFFSET 2 ;").WillReturnError(errors.New("Unknown column"))
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(`transaction_id`) FROM newTemp WHERE user_id = ?")).WithArgs("1234").WillReturnRows(sqlmock.NewRows([]string{"count(transaction_id)"}).AddRow("3"))
+				mock.ExpectQuery(regexp.QuoteMeta("FROM newTemp WHERE user_id = ? ORDER BY created_at LIMIT 1 OFFSET 2")).WithArgs("1234").WillReturnError(errors.New("Unknown column"))
 				return dB, mock
 			},
 			validator: func(rows []model.Transaction, count int, err error, mock sqlmock.Sqlmock) {
@@ -131,8 +333,8 @@ func TestSqlDs_Get(t *testing.T) {
 			},
 		},
 		{
-			name:   "FAILURE::Get:: get count query error",
-			filter: map[string]interface{}{"userid": "1234"},
+			name:  "FAILURE::Get:: get count query error",
+			query: model.Query{Where: model.Eq("user_id", "1234"), Limit: 1, Offset: 2},
 			setupFunc: func() (sqlDs, sqlmock.Sqlmock) {
 				db, mock, err := sqlmock.New()
 				if err != nil {
@@ -142,7 +344,7 @@ func TestSqlDs_Get(t *testing.T) {
 					sqlSvc: db,
 					table:  "newTemp",
 				}
-				mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(`transaction_id`) FROM newTemp WHERE userid = '1234'")).WillReturnError(errors.New("query error")).WillReturnRows(sqlmock.NewRows([]string{"transaction_id"}).AddRow("1").AddRow("2").AddRow("3"))
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(`transaction_id`) FROM newTemp WHERE user_id = ?")).WithArgs("1234").WillReturnError(errors.New("query error"))
 				return dB, mock
 			},
 			validator: func(rows []model.Transaction, count int, err error, mock sqlmock.Sqlmock) {
@@ -157,10 +359,8 @@ func TestSqlDs_Get(t *testing.T) {
 			},
 		},
 		{
-			name: "failure::Get::get rows scan error",
-			filter: map[string]interface{}{
-				"user_id": "1234",
-			},
+			name:  "failure::Get::get rows scan error",
+			query: model.Query{Where: model.Eq("user_id", "1234"), Limit: 1, Offset: 2},
 			setupFunc: func() (sqlDs, sqlmock.Sqlmock) {
 				db, mock, err := sqlmock.New()
 				if err != nil {
@@ -170,8 +370,8 @@ func TestSqlDs_Get(t *testing.T) {
 					sqlSvc: db,
 					table:  "newTemp",
 				}
-				mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(`transaction_id`) FROM newTemp WHERE user_id = '1234'")).WillReturnError(nil).WillReturnRows(sqlmock.NewRows([]string{"transaction_id"}).AddRow("1").AddRow("2").AddRow("3"))
-				mock.ExpectQuery("SELECT transaction_id, account_number, user_id, amount, transfer_to, created_at, updated_at, status, type, comment FROM newTemp WHERE user_id = '1234' ORDER BY created_at LIMIT 1 OFFSET 2 ;").WillReturnRows(sqlmock.NewRows([]string{"transaction_id", "account_number", "user_id", "amount", "transfer_to", "created_at", "updated_at", "status", "type", "comment"}).AddRow(true, 1, "123", 1000, 1234567890, time.Now(), "abc", "approved", "debit", "no comments"))
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(`transaction_id`) FROM newTemp WHERE user_id = ?")).WithArgs("1234").WillReturnRows(sqlmock.NewRows([]string{"count(transaction_id)"}).AddRow("3"))
+				mock.ExpectQuery(regexp.QuoteMeta("FROM newTemp WHERE user_id = ? ORDER BY created_at LIMIT 1 OFFSET 2")).WithArgs("1234").WillReturnRows(sqlmock.NewRows(columns).AddRow(true, 1, "123", 1000, 1234567890, time.Now(), "abc", "approved", "debit", "no comments", "", "", 0, "USD", 0, 0, "", ""))
 				return dB, mock
 			},
 			validator: func(rows []model.Transaction, count int, err error, mock sqlmock.Sqlmock) {
@@ -185,8 +385,8 @@ func TestSqlDs_Get(t *testing.T) {
 			},
 		},
 		{
-			name:   "FAILURE::Get:: get count scan error",
-			filter: map[string]interface{}{"userid": "1234"},
+			name:  "FAILURE::Get:: get count scan error",
+			query: model.Query{Where: model.Eq("user_id", "1234"), Limit: 1, Offset: 2},
 			setupFunc: func() (sqlDs, sqlmock.Sqlmock) {
 				db, mock, err := sqlmock.New()
 				if err != nil {
@@ -196,7 +396,7 @@ func TestSqlDs_Get(t *testing.T) {
 					sqlSvc: db,
 					table:  "newTemp",
 				}
-				mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(`transaction_id`) FROM newTemp WHERE userid = '1234'")).WillReturnError(nil).WillReturnRows(sqlmock.NewRows([]string{"count(transaction_id)"}).AddRow(true))
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(`transaction_id`) FROM newTemp WHERE user_id = ?")).WithArgs("1234").WillReturnRows(sqlmock.NewRows([]string{"count(transaction_id)"}).AddRow(true))
 				return dB, mock
 			},
 			validator: func(rows []model.Transaction, count int, err error, mock sqlmock.Sqlmock) {
@@ -217,7 +417,7 @@ func TestSqlDs_Get(t *testing.T) {
 			// STEP 1: seting up all instances for the specific test case
 			db, mock := tt.setupFunc()
 			// STEP 2: call the test function
-			rows, count, err := db.Get(tt.filter, 1, 2)
+			rows, count, err := db.Get(tt.query)
 
 			// STEP 3: validation of output
 			if tt.validator != nil {
@@ -242,11 +442,12 @@ func TestSqlDs_Insert(t *testing.T) {
 				UserId:        "1",
 				AccountNumber: 1,
 				TransactionId: "1234",
-				Amount:        1000,
+				Amount:        1000 * model.MajorUnit,
 				TransferTo:    2,
 				Status:        "approved",
 				Type:          "debit",
 				Comment:       "abcd",
+				Currency:      "USD",
 			},
 			setupFunc: func() (sqlDs, sqlmock.Sqlmock) {
 				db, mock, err := sqlmock.New()
@@ -257,7 +458,7 @@ func TestSqlDs_Insert(t *testing.T) {
 					sqlSvc: db,
 					table:  "newTemp",
 				}
-				m := mock.ExpectExec(regexp.QuoteMeta("INSERT INTO newTemp(user_id, transaction_id, account_number, amount, transfer_to, status, type, comment) VALUES(?,?,?,?,?,?,?,?)")).WithArgs("1", "1234", 1, 1000.00, 2, "approved", "debit", "abcd")
+				m := mock.ExpectExec(regexp.QuoteMeta("INSERT INTO newTemp(user_id, transaction_id, account_number, amount, transfer_to, status, type, comment, transfer_id, reference_id, currency, fx_rate, counter_amount, counter_currency, batch_id) VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)")).WithArgs("1", "1234", 1, "1000.00", 2, "approved", "debit", "abcd", "", "", "USD", "0", "0.00", "", "")
 				m.WillReturnError(nil)
 				m.WillReturnResult(sqlmock.NewResult(1, 1))
 				return dB, mock
@@ -279,11 +480,12 @@ func TestSqlDs_Insert(t *testing.T) {
 				UserId:        "1",
 				AccountNumber: 1,
 				TransactionId: "1234",
-				Amount:        1000,
+				Amount:        1000 * model.MajorUnit,
 				TransferTo:    2,
 				Status:        "approved",
 				Type:          "debit",
 				Comment:       "abcd",
+				Currency:      "USD",
 			},
 			setupFunc: func() (sqlDs, sqlmock.Sqlmock) {
 				db, mock, err := sqlmock.New()
@@ -294,8 +496,8 @@ func TestSqlDs_Insert(t *testing.T) {
 					sqlSvc: db,
 					table:  "newTemp",
 				}
-				m := mock.ExpectExec(regexp.QuoteMeta("INSERT INTO newTemp(user_id, transaction_id, account_number, amount, transfer_to, status, type, comment) VALUES(?,?,?,?,?,?,?,?)")).
-					WithArgs("1", "1234", 1, 1000.00, 2, "approved", "debit", "abcd")
+				m := mock.ExpectExec(regexp.QuoteMeta("INSERT INTO newTemp(user_id, transaction_id, account_number, amount, transfer_to, status, type, comment, transfer_id, reference_id, currency, fx_rate, counter_amount, counter_currency, batch_id) VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)")).
+					WithArgs("1", "1234", 1, "1000.00", 2, "approved", "debit", "abcd", "", "", "USD", "0", "0.00", "", "")
 				m.WillReturnError(errors.New("sql error"))
 				m.WillReturnResult(sqlmock.NewResult(0, 0))
 				return dB, mock
@@ -325,3 +527,1618 @@ func TestSqlDs_Insert(t *testing.T) {
 		})
 	}
 }
+
+func TestSqlDs_InsertBatch(t *testing.T) {
+	createdAt := time.Date(2020, time.January, 2, 3, 4, 5, 0, time.UTC)
+	row := "(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)"
+	insert := "INSERT INTO newTemp(user_id, transaction_id, account_number, amount, transfer_to, status, type, comment, transfer_id, reference_id, currency, fx_rate, counter_amount, counter_currency, batch_id, created_at) VALUES"
+	transactions := []model.Transaction{
+		{UserId: "1", TransactionId: "a", AccountNumber: 1, Amount: 10 * model.MajorUnit, Status: "approved", Type: "credit", Comment: "salary", Currency: "USD", CreatedAt: createdAt},
+		{UserId: "1", TransactionId: "b", AccountNumber: 1, Amount: 250, Status: "approved", Type: "debit", Currency: "EUR", CreatedAt: createdAt.Add(time.Hour)},
+	}
+	tests := []struct {
+		name         string
+		transactions []model.Transaction
+		setupFunc    func(sqlmock.Sqlmock)
+		wantErr      string
+	}{
+		{
+			name:         "SUCCESS::insert rows",
+			transactions: transactions,
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(insert+row+","+row)).
+					WithArgs("1", "a", 1, "10.00", 0, "approved", "credit", "salary", "", "", "USD", "0", "0.00", "", "", createdAt,
+						"1", "b", 1, "2.50", 0, "approved", "debit", "", "", "", "EUR", "0", "0.00", "", "", createdAt.Add(time.Hour)).
+					WillReturnResult(sqlmock.NewResult(2, 2))
+			},
+		},
+		{
+			name:      "SUCCESS::no rows",
+			setupFunc: func(mock sqlmock.Sqlmock) {},
+		},
+		{
+			name:         "FAILURE::sql error",
+			transactions: transactions[:1],
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(insert + row)).WillReturnError(errors.New("sql error"))
+			},
+			wantErr: "sql error",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.setupFunc(mock)
+			err = sqlDs{sqlSvc: db, table: "newTemp"}.InsertBatch(tt.transactions)
+			gotErr := ""
+			if err != nil {
+				gotErr = err.Error()
+			}
+			if gotErr != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, gotErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Want: %v, Got: %v", nil, err)
+			}
+		})
+	}
+}
+
+func TestSqlDs_Transaction(t *testing.T) {
+	insert := regexp.QuoteMeta("INSERT INTO newTemp_outbox(transaction_id, event_type, payload) VALUES(?,?,?)")
+	tests := []struct {
+		name      string
+		setupFunc func(sqlmock.Sqlmock)
+		fn        func(DataSourceI) error
+		wantErr   string
+	}{
+		{
+			name: "SUCCESS::commit",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(insert).WithArgs("1234", model.EventAccountUpdate, "{}").WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+			fn: func(ds DataSourceI) error {
+				// nested calls join the outer transaction
+				return ds.Transaction(func(ds DataSourceI) error {
+					return ds.InsertOutbox(model.OutboxMessage{TransactionId: "1234", EventType: model.EventAccountUpdate, Payload: "{}"})
+				})
+			},
+		},
+		{
+			name: "FAILURE::rollback on error",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(insert).WithArgs("1234", model.EventAccountUpdate, "{}").WillReturnError(errors.New("sql error"))
+				mock.ExpectRollback()
+			},
+			fn: func(ds DataSourceI) error {
+				return ds.InsertOutbox(model.OutboxMessage{TransactionId: "1234", EventType: model.EventAccountUpdate, Payload: "{}"})
+			},
+			wantErr: "sql error",
+		},
+		{
+			name: "FAILURE::rollback error reported",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectRollback().WillReturnError(errors.New("rollback error"))
+			},
+			fn: func(ds DataSourceI) error {
+				return errors.New("fn error")
+			},
+			wantErr: "fn error, rollback failed: rollback error",
+		},
+		{
+			name: "FAILURE::begin",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin().WillReturnError(errors.New("begin error"))
+			},
+			fn: func(ds DataSourceI) error {
+				t.Error("fn called without a transaction")
+				return nil
+			},
+			wantErr: "begin error",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.setupFunc(mock)
+			dB := sqlDs{sqlSvc: db, table: "newTemp"}
+			err = dB.Transaction(tt.fn)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			if mock.ExpectationsWereMet() != nil {
+				t.Errorf("Want: %v, Got: %v", nil, mock.ExpectationsWereMet())
+			}
+		})
+	}
+}
+
+func TestSqlDs_GetDueOutbox(t *testing.T) {
+	query := regexp.QuoteMeta("SELECT id, transaction_id, event_type, payload, status, attempts, last_error FROM newTemp_outbox WHERE status = ? AND next_attempt_at <= CURRENT_TIMESTAMP ORDER BY next_attempt_at, id LIMIT 10 FOR UPDATE SKIP LOCKED")
+	columns := []string{"id", "transaction_id", "event_type", "payload", "status", "attempts", "last_error"}
+	tests := []struct {
+		name      string
+		setupFunc func(sqlmock.Sqlmock)
+		want      []model.OutboxMessage
+		wantErr   bool
+	}{
+		{
+			name: "SUCCESS::due messages",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(model.OutboxPending).WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "1234", model.EventAccountUpdate, "{}", model.OutboxPending, 2, "timeout"))
+			},
+			want: []model.OutboxMessage{{Id: 1, TransactionId: "1234", EventType: model.EventAccountUpdate, Payload: "{}", Status: model.OutboxPending, Attempts: 2, LastError: "timeout"}},
+		},
+		{
+			name: "FAILURE::query error",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(model.OutboxPending).WillReturnError(errors.New("sql error"))
+			},
+			wantErr: true,
+		},
+		{
+			name: "FAILURE::scan error",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(model.OutboxPending).WillReturnRows(sqlmock.NewRows(columns).AddRow("abc", "1234", model.EventAccountUpdate, "{}", model.OutboxPending, 2, ""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.setupFunc(mock)
+			dB := sqlDs{sqlSvc: db, table: "newTemp"}
+			got, err := dB.GetDueOutbox(10)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Want: %v, Got: %v", tt.want, got)
+			}
+			if mock.ExpectationsWereMet() != nil {
+				t.Errorf("Want: %v, Got: %v", nil, mock.ExpectationsWereMet())
+			}
+		})
+	}
+}
+
+func TestSqlDs_LeaseOutbox(t *testing.T) {
+	query := regexp.QuoteMeta("UPDATE newTemp_outbox SET next_attempt_at = TIMESTAMPADD(SECOND, ?, CURRENT_TIMESTAMP) WHERE id IN (?,?)")
+	tests := []struct {
+		name      string
+		ids       []int64
+		setupFunc func(sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name:      "SUCCESS::nothing to lease",
+			setupFunc: func(mock sqlmock.Sqlmock) {},
+		},
+		{
+			name: "SUCCESS::lease",
+			ids:  []int64{1, 2},
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WithArgs(90, 1, 2).WillReturnResult(sqlmock.NewResult(0, 2))
+			},
+		},
+		{
+			name: "FAILURE::sql error",
+			ids:  []int64{1, 2},
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WithArgs(90, 1, 2).WillReturnError(errors.New("sql error"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.setupFunc(mock)
+			dB := sqlDs{sqlSvc: db, table: "newTemp"}
+			err = dB.LeaseOutbox(tt.ids, 90*time.Second)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			if mock.ExpectationsWereMet() != nil {
+				t.Errorf("Want: %v, Got: %v", nil, mock.ExpectationsWereMet())
+			}
+		})
+	}
+}
+
+func TestSqlDs_UpdateOutbox(t *testing.T) {
+	query := regexp.QuoteMeta("UPDATE newTemp_outbox SET status = ?, attempts = ?, last_error = ?, next_attempt_at = TIMESTAMPADD(SECOND, ?, CURRENT_TIMESTAMP) WHERE id = ?")
+	tests := []struct {
+		name      string
+		message   model.OutboxMessage
+		setupFunc func(sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name:    "SUCCESS::schedule retry",
+			message: model.OutboxMessage{Id: 1, Status: model.OutboxPending, Attempts: 3, LastError: "timeout", RetryAfter: 4 * time.Second},
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WithArgs(model.OutboxPending, 3, "timeout", 4, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name:    "FAILURE::sql error",
+			message: model.OutboxMessage{Id: 1, Status: model.OutboxDelivered, Attempts: 1},
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WithArgs(model.OutboxDelivered, 1, "", 0, 1).WillReturnError(errors.New("sql error"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.setupFunc(mock)
+			dB := sqlDs{sqlSvc: db, table: "newTemp"}
+			err = dB.UpdateOutbox(tt.message)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			if mock.ExpectationsWereMet() != nil {
+				t.Errorf("Want: %v, Got: %v", nil, mock.ExpectationsWereMet())
+			}
+		})
+	}
+}
+
+func TestSqlDs_Update(t *testing.T) {
+	tests := []struct {
+		name      string
+		where     model.Filter
+		set       map[string]interface{}
+		setupFunc func(sqlmock.Sqlmock)
+		want      int64
+		wantErr   bool
+	}{
+		{
+			name:  "SUCCESS::compare and set",
+			where: model.And(model.Eq("transaction_id", "abc"), model.Eq("status", "pending")),
+			set:   map[string]interface{}{"status": "approved", "comment": "ok"},
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE newTemp SET comment = ?, status = ? WHERE transaction_id = ? AND status = ?")).WithArgs("ok", "approved", "abc", "pending").WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			want: 1,
+		},
+		{
+			name:      "FAILURE::no filter",
+			set:       map[string]interface{}{"status": "approved"},
+			setupFunc: func(mock sqlmock.Sqlmock) {},
+			wantErr:   true,
+		},
+		{
+			name:      "FAILURE::nothing to set",
+			where:     model.Eq("transaction_id", "abc"),
+			setupFunc: func(mock sqlmock.Sqlmock) {},
+			wantErr:   true,
+		},
+		{
+			name:      "FAILURE::unknown column",
+			where:     model.Eq("transaction_id", "abc"),
+			set:       map[string]interface{}{"status; DROP TABLE newTemp": "approved"},
+			setupFunc: func(mock sqlmock.Sqlmock) {},
+			wantErr:   true,
+		},
+		{
+			name:  "FAILURE::sql error",
+			where: model.Eq("transaction_id", "abc"),
+			set:   map[string]interface{}{"status": "approved"},
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE newTemp SET status = ? WHERE transaction_id = ?")).WithArgs("approved", "abc").WillReturnError(errors.New("sql error"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.setupFunc(mock)
+			dB := sqlDs{sqlSvc: db, table: "newTemp"}
+			got, err := dB.Update(tt.where, tt.set)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("Want: %v, Got: %v", tt.want, got)
+			}
+			if mock.ExpectationsWereMet() != nil {
+				t.Errorf("Want: %v, Got: %v", nil, mock.ExpectationsWereMet())
+			}
+		})
+	}
+}
+
+func TestSqlDs_InsertStatusHistory(t *testing.T) {
+	query := regexp.QuoteMeta("INSERT INTO newTemp_status_history(transaction_id, from_status, to_status, changed_by, reason) VALUES(?,?,?,?,?)")
+	change := model.StatusChange{TransactionId: "abc", FromStatus: "pending", ToStatus: "approved", ChangedBy: "123", Reason: "verified"}
+	tests := []struct {
+		name      string
+		setupFunc func(sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name: "SUCCESS::insert",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WithArgs("abc", "pending", "approved", "123", "verified").WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+		},
+		{
+			name: "FAILURE::sql error",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WithArgs("abc", "pending", "approved", "123", "verified").WillReturnError(errors.New("sql error"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.setupFunc(mock)
+			dB := sqlDs{sqlSvc: db, table: "newTemp"}
+			err = dB.InsertStatusHistory(change)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			if mock.ExpectationsWereMet() != nil {
+				t.Errorf("Want: %v, Got: %v", nil, mock.ExpectationsWereMet())
+			}
+		})
+	}
+}
+
+func TestSqlDs_GetStatusHistory(t *testing.T) {
+	query := regexp.QuoteMeta("SELECT transaction_id, from_status, to_status, changed_by, reason, changed_at FROM newTemp_status_history WHERE transaction_id = ? ORDER BY id")
+	changedAt := time.Date(2023, time.January, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		setupFunc func(sqlmock.Sqlmock)
+		want      []model.StatusChange
+		wantErr   bool
+	}{
+		{
+			name: "SUCCESS::oldest first",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"transaction_id", "from_status", "to_status", "changed_by", "reason", "changed_at"}).
+					AddRow("abc", "", "pending", "123", "", changedAt).
+					AddRow("abc", "pending", "approved", "123", "verified", changedAt)
+				mock.ExpectQuery(query).WithArgs("abc").WillReturnRows(rows)
+			},
+			want: []model.StatusChange{
+				{TransactionId: "abc", ToStatus: "pending", ChangedBy: "123", ChangedAt: changedAt},
+				{TransactionId: "abc", FromStatus: "pending", ToStatus: "approved", ChangedBy: "123", Reason: "verified", ChangedAt: changedAt},
+			},
+		},
+		{
+			name: "FAILURE::scan error",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"transaction_id", "from_status", "to_status", "changed_by", "reason", "changed_at"}).
+					AddRow("abc", "", "pending", "123", "", "not a time")
+				mock.ExpectQuery(query).WithArgs("abc").WillReturnRows(rows)
+			},
+			wantErr: true,
+		},
+		{
+			name: "FAILURE::sql error",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs("abc").WillReturnError(errors.New("sql error"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.setupFunc(mock)
+			dB := sqlDs{sqlSvc: db, table: "newTemp"}
+			got, err := dB.GetStatusHistory("abc")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Want: %v, Got: %v", tt.want, got)
+			}
+			if mock.ExpectationsWereMet() != nil {
+				t.Errorf("Want: %v, Got: %v", nil, mock.ExpectationsWereMet())
+			}
+		})
+	}
+}
+
+func TestSqlDs_UpsertFxRate(t *testing.T) {
+	query := regexp.QuoteMeta("INSERT INTO newTemp_fx_rates(base_currency, quote_currency, rate, effective_at) VALUES(?,?,?,?) ON DUPLICATE KEY UPDATE rate = VALUES(rate)")
+	effectiveAt := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	rate := model.FxRate{BaseCurrency: "USD", QuoteCurrency: "EUR", Rate: 92500000, EffectiveAt: effectiveAt}
+	tests := []struct {
+		name      string
+		setupFunc func(sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name: "SUCCESS::upsert",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WithArgs("USD", "EUR", "0.925", effectiveAt).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "FAILURE::sql error",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WithArgs("USD", "EUR", "0.925", effectiveAt).WillReturnError(errors.New("sql error"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.setupFunc(mock)
+			dB := sqlDs{sqlSvc: db, table: "newTemp"}
+			err = dB.UpsertFxRate(rate)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			if mock.ExpectationsWereMet() != nil {
+				t.Errorf("Want: %v, Got: %v", nil, mock.ExpectationsWereMet())
+			}
+		})
+	}
+}
+
+func TestSqlDs_GetFxRate(t *testing.T) {
+	query := regexp.QuoteMeta("SELECT base_currency, quote_currency, rate, effective_at FROM newTemp_fx_rates WHERE base_currency = ? AND quote_currency = ? AND effective_at <= ? ORDER BY effective_at DESC LIMIT 1")
+	at := time.Date(2023, time.June, 1, 0, 0, 0, 0, time.UTC)
+	effectiveAt := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	columns := []string{"base_currency", "quote_currency", "rate", "effective_at"}
+	tests := []struct {
+		name      string
+		setupFunc func(sqlmock.Sqlmock)
+		want      *model.FxRate
+		wantErr   bool
+	}{
+		{
+			name: "SUCCESS::rate in effect",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs("USD", "EUR", at).WillReturnRows(sqlmock.NewRows(columns).AddRow("USD", "EUR", []byte("0.92500000"), effectiveAt))
+			},
+			want: &model.FxRate{BaseCurrency: "USD", QuoteCurrency: "EUR", Rate: 92500000, EffectiveAt: effectiveAt},
+		},
+		{
+			name: "SUCCESS::no rate",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs("USD", "EUR", at).WillReturnRows(sqlmock.NewRows(columns))
+			},
+		},
+		{
+			name: "FAILURE::sql error",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs("USD", "EUR", at).WillReturnError(errors.New("sql error"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.setupFunc(mock)
+			dB := sqlDs{sqlSvc: db, table: "newTemp"}
+			got, err := dB.GetFxRate("USD", "EUR", at)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Want: %v, Got: %v", tt.want, got)
+			}
+			if mock.ExpectationsWereMet() != nil {
+				t.Errorf("Want: %v, Got: %v", nil, mock.ExpectationsWereMet())
+			}
+		})
+	}
+}
+
+func TestSqlDs_GetBalances(t *testing.T) {
+	query := regexp.QuoteMeta("SELECT currency, SUM(CASE WHEN type = 'credit' THEN amount ELSE -amount END) FROM newTemp WHERE account_number = ? AND user_id = ? AND status IN (?,?) AND created_at <= ? GROUP BY currency ORDER BY currency")
+	asOf := time.Date(2023, time.June, 1, 0, 0, 0, 0, time.UTC)
+	columns := []string{"currency", "balance"}
+	tests := []struct {
+		name      string
+		setupFunc func(sqlmock.Sqlmock)
+		want      []model.Balance
+		wantErr   bool
+	}{
+		{
+			name: "SUCCESS::balance per currency",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(1, "123", "approved", "reversed", asOf).WillReturnRows(sqlmock.NewRows(columns).AddRow("EUR", []byte("-2.50")).AddRow("USD", []byte("100.10")))
+			},
+			want: []model.Balance{{Currency: "EUR", Balance: -250}, {Currency: "USD", Balance: 10010}},
+		},
+		{
+			name: "SUCCESS::no transactions",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(1, "123", "approved", "reversed", asOf).WillReturnRows(sqlmock.NewRows(columns))
+			},
+			want: []model.Balance{},
+		},
+		{
+			name: "FAILURE::sql error",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(1, "123", "approved", "reversed", asOf).WillReturnError(errors.New("sql error"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.setupFunc(mock)
+			dB := sqlDs{sqlSvc: db, table: "newTemp"}
+			got, err := dB.GetBalances(1, "123", asOf)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Want: %v, Got: %v", tt.want, got)
+			}
+			if mock.ExpectationsWereMet() != nil {
+				t.Errorf("Want: %v, Got: %v", nil, mock.ExpectationsWereMet())
+			}
+		})
+	}
+}
+
+func TestSqlDs_GetRunningBalances(t *testing.T) {
+	query := regexp.QuoteMeta("SELECT t.transaction_id, COALESCE((SELECT SUM(CASE WHEN l.type = 'credit' THEN l.amount ELSE -l.amount END) FROM newTemp l " +
+		"WHERE l.account_number = t.account_number AND l.user_id = t.user_id AND l.currency = t.currency AND l.status IN (?,?) " +
+		"AND (l.created_at < t.created_at OR (l.created_at = t.created_at AND l.transaction_id <= t.transaction_id))), 0) " +
+		"FROM newTemp t WHERE t.account_number = ? AND t.user_id = ? AND t.transaction_id IN (?,?)")
+	columns := []string{"transaction_id", "running_balance"}
+	tests := []struct {
+		name      string
+		ids       []string
+		setupFunc func(sqlmock.Sqlmock)
+		want      map[string]model.Money
+		wantErr   bool
+	}{
+		{
+			name: "SUCCESS::balance after each transaction",
+			ids:  []string{"a", "b"},
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs("approved", "reversed", 1, "123", "a", "b").WillReturnRows(sqlmock.NewRows(columns).AddRow("a", []byte("10.00")).AddRow("b", []byte("7.50")))
+			},
+			want: map[string]model.Money{"a": 1000, "b": 750},
+		},
+		{
+			name:      "SUCCESS::no transactions",
+			setupFunc: func(mock sqlmock.Sqlmock) {},
+			want:      map[string]model.Money{},
+		},
+		{
+			name: "FAILURE::sql error",
+			ids:  []string{"a", "b"},
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs("approved", "reversed", 1, "123", "a", "b").WillReturnError(errors.New("sql error"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.setupFunc(mock)
+			dB := sqlDs{sqlSvc: db, table: "newTemp"}
+			got, err := dB.GetRunningBalances(1, "123", tt.ids)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Want: %v, Got: %v", tt.want, got)
+			}
+			if mock.ExpectationsWereMet() != nil {
+				t.Errorf("Want: %v, Got: %v", nil, mock.ExpectationsWereMet())
+			}
+		})
+	}
+}
+
+func TestSqlDs_LockUser(t *testing.T) {
+	query := regexp.QuoteMeta("INSERT INTO newTemp_spending_locks(user_id) VALUES(?) ON DUPLICATE KEY UPDATE user_id = user_id")
+	tests := []struct {
+		name      string
+		setupFunc func(sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name: "SUCCESS::lock",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WithArgs("123").WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "FAILURE::sql error",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WithArgs("123").WillReturnError(errors.New("sql error"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.setupFunc(mock)
+			dB := sqlDs{sqlSvc: db, table: "newTemp"}
+			err = dB.LockUser("123")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			if mock.ExpectationsWereMet() != nil {
+				t.Errorf("Want: %v, Got: %v", nil, mock.ExpectationsWereMet())
+			}
+		})
+	}
+}
+
+func TestSqlDs_GetSpending(t *testing.T) {
+	query := regexp.QuoteMeta("SELECT COALESCE(SUM(amount), 0), COUNT(`transaction_id`) FROM newTemp WHERE user_id = ? AND type = ? AND created_at >= ?")
+	since := time.Date(2023, time.June, 1, 0, 0, 0, 0, time.UTC)
+	filter := model.And(model.Eq("user_id", "123"), model.Eq("type", "debit"), model.Gte("created_at", since))
+	columns := []string{"total", "count"}
+	tests := []struct {
+		name      string
+		filter    model.Filter
+		setupFunc func(sqlmock.Sqlmock)
+		wantTotal model.Money
+		wantCount int
+		wantErr   bool
+	}{
+		{
+			name:   "SUCCESS::spending",
+			filter: filter,
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs("123", "debit", since).WillReturnRows(sqlmock.NewRows(columns).AddRow([]byte("250.75"), 3))
+			},
+			wantTotal: 25075,
+			wantCount: 3,
+		},
+		{
+			name:   "SUCCESS::nothing spent",
+			filter: filter,
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs("123", "debit", since).WillReturnRows(sqlmock.NewRows(columns).AddRow([]byte("0"), 0))
+			},
+		},
+		{
+			name:      "FAILURE::invalid filter",
+			filter:    model.Eq("user_id; DROP TABLE newTemp", "123"),
+			setupFunc: func(mock sqlmock.Sqlmock) {},
+			wantErr:   true,
+		},
+		{
+			name:   "FAILURE::sql error",
+			filter: filter,
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs("123", "debit", since).WillReturnError(errors.New("sql error"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.setupFunc(mock)
+			dB := sqlDs{sqlSvc: db, table: "newTemp"}
+			total, count, err := dB.GetSpending(tt.filter)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			if total != tt.wantTotal || count != tt.wantCount {
+				t.Errorf("Want: %v %v, Got: %v %v", tt.wantTotal, tt.wantCount, total, count)
+			}
+			if mock.ExpectationsWereMet() != nil {
+				t.Errorf("Want: %v, Got: %v", nil, mock.ExpectationsWereMet())
+			}
+		})
+	}
+}
+
+func TestSqlDs_InsertRiskHit(t *testing.T) {
+	query := regexp.QuoteMeta("INSERT INTO newTemp_risk_hits(transaction_id, rule, type, score, detail, decision) VALUES(?,?,?,?,?,?)")
+	hit := model.RiskHit{TransactionId: "abc", Rule: "large amount", Type: "amount", Score: 60, Detail: "amount 150.00 USD", Decision: "review"}
+	tests := []struct {
+		name      string
+		setupFunc func(sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name: "SUCCESS::insert",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WithArgs("abc", "large amount", "amount", 60, "amount 150.00 USD", "review").WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+		},
+		{
+			name: "FAILURE::sql error",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WithArgs("abc", "large amount", "amount", 60, "amount 150.00 USD", "review").WillReturnError(errors.New("sql error"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.setupFunc(mock)
+			dB := sqlDs{sqlSvc: db, table: "newTemp"}
+			err = dB.InsertRiskHit(hit)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			if mock.ExpectationsWereMet() != nil {
+				t.Errorf("Want: %v, Got: %v", nil, mock.ExpectationsWereMet())
+			}
+		})
+	}
+}
+
+func TestSqlDs_GetRiskHits(t *testing.T) {
+	query := regexp.QuoteMeta("SELECT transaction_id, rule, type, score, detail, decision, created_at FROM newTemp_risk_hits WHERE transaction_id = ? ORDER BY id")
+	createdAt := time.Date(2023, time.January, 1, 10, 0, 0, 0, time.UTC)
+	columns := []string{"transaction_id", "rule", "type", "score", "detail", "decision", "created_at"}
+	tests := []struct {
+		name      string
+		setupFunc func(sqlmock.Sqlmock)
+		want      []model.RiskHit
+		wantErr   bool
+	}{
+		{
+			name: "SUCCESS::hits in order",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows(columns).
+					AddRow("abc", "large amount", "amount", 60, "amount 150.00 USD", "review", createdAt).
+					AddRow("abc", "night", "unusual_hour", 20, "created at 03:00 UTC", "review", createdAt)
+				mock.ExpectQuery(query).WithArgs("abc").WillReturnRows(rows)
+			},
+			want: []model.RiskHit{
+				{TransactionId: "abc", Rule: "large amount", Type: "amount", Score: 60, Detail: "amount 150.00 USD", Decision: "review", CreatedAt: createdAt},
+				{TransactionId: "abc", Rule: "night", Type: "unusual_hour", Score: 20, Detail: "created at 03:00 UTC", Decision: "review", CreatedAt: createdAt},
+			},
+		},
+		{
+			name: "FAILURE::scan error",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows(columns).AddRow("abc", "large amount", "amount", 60, "", "review", "not a time")
+				mock.ExpectQuery(query).WithArgs("abc").WillReturnRows(rows)
+			},
+			wantErr: true,
+		},
+		{
+			name: "FAILURE::sql error",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs("abc").WillReturnError(errors.New("sql error"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.setupFunc(mock)
+			dB := sqlDs{sqlSvc: db, table: "newTemp"}
+			got, err := dB.GetRiskHits("abc")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Want: %v, Got: %v", tt.want, got)
+			}
+			if mock.ExpectationsWereMet() != nil {
+				t.Errorf("Want: %v, Got: %v", nil, mock.ExpectationsWereMet())
+			}
+		})
+	}
+}
+
+func TestSqlDs_InsertSchedule(t *testing.T) {
+	query := regexp.QuoteMeta("INSERT INTO newTemp_schedules(schedule_id, user_id, account_number, amount, transfer_to, type, transaction_status, comment, currency, frequency, cron, start_at, end_at, next_run_at, due_at, status) VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)")
+	startAt := time.Date(2023, time.July, 1, 9, 0, 0, 0, time.UTC)
+	schedule := model.Schedule{ScheduleId: "s1", UserId: "123", AccountNumber: 1, Amount: 120000, TransferTo: 2, Type: "debit", TransactionStatus: "approved", Comment: "rent", Currency: "USD", Frequency: "monthly", StartAt: startAt, NextRunAt: startAt, DueAt: startAt, Status: "active"}
+	tests := []struct {
+		name      string
+		setupFunc func(sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name: "SUCCESS::insert",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WithArgs("s1", "123", 1, "1200.00", 2, "debit", "approved", "rent", "USD", "monthly", "", startAt, nil, startAt, startAt, "active").WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+		},
+		{
+			name: "FAILURE::sql error",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WillReturnError(errors.New("sql error"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.setupFunc(mock)
+			dB := sqlDs{sqlSvc: db, table: "newTemp"}
+			err = dB.InsertSchedule(schedule)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			if mock.ExpectationsWereMet() != nil {
+				t.Errorf("Want: %v, Got: %v", nil, mock.ExpectationsWereMet())
+			}
+		})
+	}
+}
+
+func TestSqlDs_UpdateSchedule(t *testing.T) {
+	query := regexp.QuoteMeta("UPDATE newTemp_schedules SET account_number = ?, amount = ?, transfer_to = ?, type = ?, transaction_status = ?, comment = ?, currency = ?, frequency = ?, cron = ?, start_at = ?, end_at = ?, next_run_at = ?, due_at = ?, status = ?, attempts = ?, last_error = ? WHERE schedule_id = ?")
+	startAt := time.Date(2023, time.July, 1, 9, 0, 0, 0, time.UTC)
+	endAt := time.Date(2024, time.July, 1, 9, 0, 0, 0, time.UTC)
+	dueAt := startAt.Add(time.Minute)
+	schedule := model.Schedule{ScheduleId: "s1", UserId: "123", AccountNumber: 1, Amount: 120000, Type: "debit", TransactionStatus: "approved", Currency: "USD", Frequency: "cron", Cron: "0 9 1 * *", StartAt: startAt, EndAt: &endAt, NextRunAt: startAt, DueAt: dueAt, Status: "active", Attempts: 1, LastError: "error"}
+	tests := []struct {
+		name      string
+		setupFunc func(sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name: "SUCCESS::update",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WithArgs(1, "1200.00", 0, "debit", "approved", "", "USD", "cron", "0 9 1 * *", startAt, endAt, startAt, dueAt, "active", 1, "error", "s1").WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "FAILURE::sql error",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WillReturnError(errors.New("sql error"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.setupFunc(mock)
+			dB := sqlDs{sqlSvc: db, table: "newTemp"}
+			err = dB.UpdateSchedule(schedule)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			if mock.ExpectationsWereMet() != nil {
+				t.Errorf("Want: %v, Got: %v", nil, mock.ExpectationsWereMet())
+			}
+		})
+	}
+}
+
+func TestSqlDs_Schedules(t *testing.T) {
+	columns := []string{"schedule_id", "user_id", "account_number", "amount", "transfer_to", "type", "transaction_status", "comment", "currency", "frequency", "cron", "start_at", "end_at", "next_run_at", "due_at", "status", "attempts", "last_error", "created_at", "updated_at"}
+	selectColumns := "SELECT schedule_id, user_id, account_number, amount, transfer_to, type, transaction_status, comment, currency, frequency, cron, start_at, end_at, next_run_at, due_at, status, attempts, last_error, created_at, updated_at FROM newTemp_schedules"
+	at := time.Date(2023, time.July, 1, 9, 0, 0, 0, time.UTC)
+	endAt := at.AddDate(1, 0, 0)
+	row := func() *sqlmock.Rows {
+		return sqlmock.NewRows(columns).AddRow("s1", "123", 1, []byte("1200.00"), 0, "debit", "approved", "rent", "USD", "monthly", "", at, endAt, at, at, "active", 0, "", at, at)
+	}
+	want := model.Schedule{ScheduleId: "s1", UserId: "123", AccountNumber: 1, Amount: 120000, Type: "debit", TransactionStatus: "approved", Comment: "rent", Currency: "USD", Frequency: "monthly", StartAt: at, EndAt: &endAt, NextRunAt: at, DueAt: at, Status: "active", CreatedAt: at, UpdatedAt: at}
+	tests := []struct {
+		name      string
+		setupFunc func(sqlmock.Sqlmock)
+		call      func(sqlDs) (interface{}, error)
+		want      interface{}
+		wantErr   bool
+	}{
+		{
+			name: "SUCCESS::schedules of a user",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(selectColumns + " WHERE user_id = ? ORDER BY created_at, schedule_id")).WithArgs("123").WillReturnRows(row())
+			},
+			call: func(d sqlDs) (interface{}, error) { return d.GetSchedules("123") },
+			want: []model.Schedule{want},
+		},
+		{
+			name: "FAILURE::schedules of a user",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(selectColumns + " WHERE user_id = ? ORDER BY created_at, schedule_id")).WithArgs("123").WillReturnError(errors.New("sql error"))
+			},
+			call:    func(d sqlDs) (interface{}, error) { return d.GetSchedules("123") },
+			want:    []model.Schedule(nil),
+			wantErr: true,
+		},
+		{
+			name: "SUCCESS::schedule",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(selectColumns + " WHERE schedule_id = ?")).WithArgs("s1").WillReturnRows(row())
+			},
+			call: func(d sqlDs) (interface{}, error) { return d.GetSchedule("s1") },
+			want: &want,
+		},
+		{
+			name: "SUCCESS::no schedule",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(selectColumns + " WHERE schedule_id = ?")).WithArgs("s1").WillReturnRows(sqlmock.NewRows(columns))
+			},
+			call: func(d sqlDs) (interface{}, error) { return d.GetSchedule("s1") },
+			want: (*model.Schedule)(nil),
+		},
+		{
+			name: "SUCCESS::locked schedule",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(selectColumns + " WHERE schedule_id = ? FOR UPDATE SKIP LOCKED")).WithArgs("s1").WillReturnRows(row())
+			},
+			call: func(d sqlDs) (interface{}, error) { return d.LockSchedule("s1") },
+			want: &want,
+		},
+		{
+			name: "SUCCESS::schedule locked elsewhere",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(selectColumns + " WHERE schedule_id = ? FOR UPDATE SKIP LOCKED")).WithArgs("s1").WillReturnRows(sqlmock.NewRows(columns))
+			},
+			call: func(d sqlDs) (interface{}, error) { return d.LockSchedule("s1") },
+			want: (*model.Schedule)(nil),
+		},
+		{
+			name: "FAILURE::lock error",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(selectColumns + " WHERE schedule_id = ? FOR UPDATE SKIP LOCKED")).WithArgs("s1").WillReturnError(errors.New("sql error"))
+			},
+			call:    func(d sqlDs) (interface{}, error) { return d.LockSchedule("s1") },
+			want:    (*model.Schedule)(nil),
+			wantErr: true,
+		},
+		{
+			name: "SUCCESS::due schedules",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(selectColumns + " WHERE status = ? AND due_at <= UTC_TIMESTAMP() ORDER BY due_at, schedule_id LIMIT 10")).WithArgs("active").WillReturnRows(row())
+			},
+			call: func(d sqlDs) (interface{}, error) { return d.GetDueSchedules(10) },
+			want: []model.Schedule{want},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.setupFunc(mock)
+			got, err := tt.call(sqlDs{sqlSvc: db, table: "newTemp"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Want: %v, Got: %v", tt.want, got)
+			}
+			if mock.ExpectationsWereMet() != nil {
+				t.Errorf("Want: %v, Got: %v", nil, mock.ExpectationsWereMet())
+			}
+		})
+	}
+}
+
+func TestSqlDs_ScheduleExecutions(t *testing.T) {
+	runAt := time.Date(2023, time.July, 1, 9, 0, 0, 0, time.UTC)
+	insert := regexp.QuoteMeta("INSERT INTO newTemp_schedule_executions(schedule_id, run_at, attempt, outcome, transaction_id, message) VALUES(?,?,?,?,?,?)")
+	query := regexp.QuoteMeta("SELECT schedule_id, run_at, attempt, outcome, transaction_id, message, executed_at FROM newTemp_schedule_executions WHERE schedule_id = ? ORDER BY id DESC LIMIT 20")
+	execution := model.ScheduleExecution{ScheduleId: "s1", RunAt: runAt, Attempt: 1, Outcome: "succeeded", TransactionId: "abc", Message: "SUCCESS", ExecutedAt: runAt}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dB := sqlDs{sqlSvc: db, table: "newTemp"}
+	mock.ExpectExec(insert).WithArgs("s1", runAt, 1, "succeeded", "abc", "SUCCESS").WillReturnResult(sqlmock.NewResult(1, 1))
+	err = dB.InsertScheduleExecution(execution)
+	if err != nil {
+		t.Errorf("Want: %v, Got: %v", nil, err)
+	}
+	mock.ExpectQuery(query).WithArgs("s1").WillReturnRows(sqlmock.NewRows([]string{"schedule_id", "run_at", "attempt", "outcome", "transaction_id", "message", "executed_at"}).AddRow("s1", runAt, 1, "succeeded", "abc", "SUCCESS", runAt))
+	got, err := dB.GetScheduleExecutions("s1", 20)
+	if err != nil {
+		t.Errorf("Want: %v, Got: %v", nil, err)
+	}
+	if !reflect.DeepEqual(got, []model.ScheduleExecution{execution}) {
+		t.Errorf("Want: %v, Got: %v", []model.ScheduleExecution{execution}, got)
+	}
+	mock.ExpectQuery(query).WithArgs("s1").WillReturnRows(sqlmock.NewRows([]string{"schedule_id", "run_at", "attempt", "outcome", "transaction_id", "message", "executed_at"}))
+	got, err = dB.GetScheduleExecutions("s1", 20)
+	if err != nil || got == nil || len(got) != 0 {
+		t.Errorf("Want: %v, Got: %v %v", "no executions", got, err)
+	}
+	mock.ExpectQuery(query).WithArgs("s1").WillReturnError(errors.New("sql error"))
+	_, err = dB.GetScheduleExecutions("s1", 20)
+	if err == nil {
+		t.Errorf("Want: %v, Got: %v", "error", err)
+	}
+	if mock.ExpectationsWereMet() != nil {
+		t.Errorf("Want: %v, Got: %v", nil, mock.ExpectationsWereMet())
+	}
+}
+
+func TestSqlDs_Stream(t *testing.T) {
+	query := regexp.QuoteMeta("SELECT transaction_id, account_number, user_id, amount, transfer_to, created_at, updated_at, status, type, comment, transfer_id, reference_id, refunded_amount, currency, fx_rate, counter_amount, counter_currency, batch_id FROM newTemp WHERE user_id = ? ORDER BY created_at DESC, transaction_id")
+	columns := []string{"transaction_id", "account_number", "user_id", "amount", "transfer_to", "created_at", "updated_at", "status", "type", "comment", "transfer_id", "reference_id", "refunded_amount", "currency", "fx_rate", "counter_amount", "counter_currency", "batch_id"}
+	at := time.Date(2023, time.June, 1, 9, 0, 0, 0, time.UTC)
+	row := func(id string) []driver.Value {
+		return []driver.Value{id, 1, "123", []byte("10.00"), 0, at, at, "approved", "credit", "", "", "", []byte("0.00"), "USD", []byte("0.00000000"), []byte("0.00"), "", ""}
+	}
+	stopped := errors.New("stopped")
+	tests := []struct {
+		name      string
+		setupFunc func(sqlmock.Sqlmock)
+		query     model.Query
+		stopAt    int
+		want      []string
+		wantErr   error
+	}{
+		{
+			name: "SUCCESS::every row streamed in order",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs("123").WillReturnRows(sqlmock.NewRows(columns).AddRow(row("b")...).AddRow(row("a")...))
+			},
+			query: model.Query{Where: model.Eq("user_id", "123"), OrderBy: []model.OrderBy{{Column: "created_at", Desc: true}, {Column: "transaction_id"}}},
+			want:  []string{"b", "a"},
+		},
+		{
+			name: "FAILURE::stopped by the callback",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs("123").WillReturnRows(sqlmock.NewRows(columns).AddRow(row("b")...).AddRow(row("a")...))
+			},
+			query:   model.Query{Where: model.Eq("user_id", "123"), OrderBy: []model.OrderBy{{Column: "created_at", Desc: true}, {Column: "transaction_id"}}},
+			stopAt:  1,
+			want:    []string{"b"},
+			wantErr: stopped,
+		},
+		{
+			name: "FAILURE::query error",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs("123").WillReturnError(stopped)
+			},
+			query:   model.Query{Where: model.Eq("user_id", "123"), OrderBy: []model.OrderBy{{Column: "created_at", Desc: true}, {Column: "transaction_id"}}},
+			wantErr: stopped,
+		},
+		{
+			name:      "FAILURE::unknown sort column",
+			setupFunc: func(mock sqlmock.Sqlmock) {},
+			query:     model.Query{OrderBy: []model.OrderBy{{Column: "password"}}},
+			wantErr:   errors.New(`unknown column "password"`),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.setupFunc(mock)
+			dB := sqlDs{sqlSvc: db, table: "newTemp"}
+			var got []string
+			err = dB.Stream(tt.query, func(transaction model.Transaction) error {
+				got = append(got, transaction.TransactionId)
+				if len(got) == tt.stopAt {
+					return stopped
+				}
+				return nil
+			})
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Want: %v, Got: %v", tt.want, got)
+			}
+			if mock.ExpectationsWereMet() != nil {
+				t.Errorf("Want: %v, Got: %v", nil, mock.ExpectationsWereMet())
+			}
+		})
+	}
+}
+
+func TestSqlDs_Webhooks(t *testing.T) {
+	columns := []string{"webhook_id", "user_id", "client_id", "url", "events", "secret", "status", "created_at", "updated_at"}
+	selectColumns := "SELECT webhook_id, user_id, client_id, url, events, secret, status, created_at, updated_at FROM newTemp_webhooks"
+	at := time.Date(2023, time.July, 1, 9, 0, 0, 0, time.UTC)
+	row := func() *sqlmock.Rows {
+		return sqlmock.NewRows(columns).AddRow("w1", "123", "payroll", "https://example.com/hook", "transaction.created,transaction.reversed", "whsec_abc", "active", at, at)
+	}
+	webhook := model.Webhook{WebhookId: "w1", UserId: "123", ClientId: "payroll", Url: "https://example.com/hook", Events: []string{model.EventTransactionCreated, model.EventTransactionReversed}, Secret: "whsec_abc", Status: model.WebhookActive, CreatedAt: at, UpdatedAt: at}
+	tests := []struct {
+		name      string
+		setupFunc func(sqlmock.Sqlmock)
+		call      func(sqlDs) (interface{}, error)
+		want      interface{}
+		wantErr   bool
+	}{
+		{
+			name: "SUCCESS::insert",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO newTemp_webhooks(webhook_id, user_id, client_id, url, events, secret, status) VALUES(?,?,?,?,?,?,?)")).WithArgs("w1", "123", "payroll", "https://example.com/hook", "transaction.created,transaction.reversed", "whsec_abc", "active").WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+			call: func(d sqlDs) (interface{}, error) { return nil, d.InsertWebhook(webhook) },
+		},
+		{
+			name: "SUCCESS::webhooks of a user",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(selectColumns + " WHERE user_id = ? ORDER BY created_at, webhook_id")).WithArgs("123").WillReturnRows(row())
+			},
+			call: func(d sqlDs) (interface{}, error) { return d.GetWebhooks("123") },
+			want: []model.Webhook{webhook},
+		},
+		{
+			name: "FAILURE::webhooks of a user",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(selectColumns + " WHERE user_id = ? ORDER BY created_at, webhook_id")).WithArgs("123").WillReturnError(errors.New("sql error"))
+			},
+			call:    func(d sqlDs) (interface{}, error) { return d.GetWebhooks("123") },
+			want:    []model.Webhook(nil),
+			wantErr: true,
+		},
+		{
+			name: "SUCCESS::webhook",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(selectColumns + " WHERE webhook_id = ?")).WithArgs("w1").WillReturnRows(row())
+			},
+			call: func(d sqlDs) (interface{}, error) { return d.GetWebhook("w1") },
+			want: &webhook,
+		},
+		{
+			name: "SUCCESS::no webhook",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(selectColumns + " WHERE webhook_id = ?")).WithArgs("w1").WillReturnRows(sqlmock.NewRows(columns))
+			},
+			call: func(d sqlDs) (interface{}, error) { return d.GetWebhook("w1") },
+			want: (*model.Webhook)(nil),
+		},
+		{
+			name: "SUCCESS::update status",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE newTemp_webhooks SET status = ? WHERE webhook_id = ?")).WithArgs("disabled", "w1").WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			call: func(d sqlDs) (interface{}, error) { return nil, d.UpdateWebhookStatus("w1", model.WebhookDisabled) },
+		},
+		{
+			name: "FAILURE::update status",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE newTemp_webhooks SET status = ? WHERE webhook_id = ?")).WillReturnError(errors.New("sql error"))
+			},
+			call:    func(d sqlDs) (interface{}, error) { return nil, d.UpdateWebhookStatus("w1", model.WebhookDisabled) },
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.setupFunc(mock)
+			got, err := tt.call(sqlDs{sqlSvc: db, table: "newTemp"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Want: %v, Got: %v", tt.want, got)
+			}
+			if mock.ExpectationsWereMet() != nil {
+				t.Errorf("Want: %v, Got: %v", nil, mock.ExpectationsWereMet())
+			}
+		})
+	}
+}
+
+func TestSqlDs_WebhookDeliveries(t *testing.T) {
+	columns := []string{"delivery_id", "webhook_id", "event_id", "event_type", "transaction_id", "payload", "status", "attempts", "response_status", "last_error", "next_attempt_at", "created_at", "updated_at"}
+	selectColumns := "SELECT delivery_id, webhook_id, event_id, event_type, transaction_id, payload, status, attempts, response_status, last_error, next_attempt_at, created_at, updated_at FROM newTemp_webhook_deliveries"
+	at := time.Date(2023, time.July, 1, 9, 0, 0, 0, time.UTC)
+	values := []driver.Value{"d1", "w1", "e1", "transaction.created", "abc", "{}", "pending", 1, 500, "timeout", at, at, at}
+	delivery := model.WebhookDelivery{DeliveryId: "d1", WebhookId: "w1", EventId: "e1", EventType: model.EventTransactionCreated, TransactionId: "abc", Payload: "{}", Status: model.WebhookDeliveryPending, Attempts: 1, ResponseStatus: 500, LastError: "timeout", NextAttemptAt: at, CreatedAt: at, UpdatedAt: at}
+	due := delivery
+	due.Url, due.Secret, due.WebhookStatus = "https://example.com/hook", "whsec_abc", model.WebhookActive
+	dueQuery := "SELECT d.delivery_id, d.webhook_id, d.event_id, d.event_type, d.transaction_id, d.payload, d.status, d.attempts, d.response_status, d.last_error, d.next_attempt_at, d.created_at, d.updated_at, w.url, w.secret, w.status FROM newTemp_webhook_deliveries d JOIN newTemp_webhooks w ON w.webhook_id = d.webhook_id WHERE d.status = ? AND d.next_attempt_at <= CURRENT_TIMESTAMP ORDER BY d.next_attempt_at, d.delivery_id LIMIT 10 FOR UPDATE SKIP LOCKED"
+	update := "UPDATE newTemp_webhook_deliveries SET status = ?, attempts = ?, response_status = ?, last_error = ?, next_attempt_at = TIMESTAMPADD(SECOND, ?, CURRENT_TIMESTAMP) WHERE delivery_id = ?"
+	tests := []struct {
+		name      string
+		setupFunc func(sqlmock.Sqlmock)
+		call      func(sqlDs) (interface{}, error)
+		want      interface{}
+		wantErr   bool
+	}{
+		{
+			name: "SUCCESS::queue an event",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO newTemp_webhook_deliveries(delivery_id, webhook_id, event_id, event_type, transaction_id, payload) SELECT UUID(), webhook_id, ?, ?, ?, ? FROM newTemp_webhooks WHERE user_id = ? AND status = ? AND FIND_IN_SET(?, events) > 0")).
+					WithArgs("e1", "transaction.created", "abc", "{}", "123", "active", "transaction.created").WillReturnResult(sqlmock.NewResult(0, 2))
+			},
+			call: func(d sqlDs) (interface{}, error) {
+				return nil, d.InsertWebhookDeliveries("123", model.WebhookDelivery{EventId: "e1", EventType: model.EventTransactionCreated, TransactionId: "abc", Payload: "{}"})
+			},
+		},
+		{
+			name: "SUCCESS::deliveries of a webhook",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(selectColumns + " WHERE webhook_id = ? ORDER BY created_at DESC, delivery_id LIMIT 20")).WithArgs("w1").WillReturnRows(sqlmock.NewRows(columns).AddRow(values...))
+			},
+			call: func(d sqlDs) (interface{}, error) { return d.GetWebhookDeliveries("w1", 20) },
+			want: []model.WebhookDelivery{delivery},
+		},
+		{
+			name: "SUCCESS::no deliveries of a webhook",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(selectColumns + " WHERE webhook_id = ? ORDER BY created_at DESC, delivery_id LIMIT 20")).WithArgs("w1").WillReturnRows(sqlmock.NewRows(columns))
+			},
+			call: func(d sqlDs) (interface{}, error) { return d.GetWebhookDeliveries("w1", 20) },
+			want: []model.WebhookDelivery{},
+		},
+		{
+			name: "SUCCESS::delivery",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(selectColumns + " WHERE delivery_id = ?")).WithArgs("d1").WillReturnRows(sqlmock.NewRows(columns).AddRow(values...))
+			},
+			call: func(d sqlDs) (interface{}, error) { return d.GetWebhookDelivery("d1") },
+			want: &delivery,
+		},
+		{
+			name: "SUCCESS::no delivery",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(selectColumns + " WHERE delivery_id = ?")).WithArgs("d1").WillReturnRows(sqlmock.NewRows(columns))
+			},
+			call: func(d sqlDs) (interface{}, error) { return d.GetWebhookDelivery("d1") },
+			want: (*model.WebhookDelivery)(nil),
+		},
+		{
+			name: "SUCCESS::due deliveries",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(dueQuery)).WithArgs("pending").WillReturnRows(sqlmock.NewRows(append(columns, "url", "secret", "status")).AddRow(append(values, "https://example.com/hook", "whsec_abc", "active")...))
+			},
+			call: func(d sqlDs) (interface{}, error) { return d.GetDueWebhookDeliveries(10) },
+			want: []model.WebhookDelivery{due},
+		},
+		{
+			name: "FAILURE::due deliveries",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(dueQuery)).WithArgs("pending").WillReturnError(errors.New("sql error"))
+			},
+			call:    func(d sqlDs) (interface{}, error) { return d.GetDueWebhookDeliveries(10) },
+			want:    []model.WebhookDelivery(nil),
+			wantErr: true,
+		},
+		{
+			name: "SUCCESS::update",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(update)).WithArgs("pending", 2, 503, "busy", 20, "d1").WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			call: func(d sqlDs) (interface{}, error) {
+				return nil, d.UpdateWebhookDelivery(model.WebhookDelivery{DeliveryId: "d1", Status: model.WebhookDeliveryPending, Attempts: 2, ResponseStatus: 503, LastError: "busy", RetryAfter: 20 * time.Second})
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.setupFunc(mock)
+			got, err := tt.call(sqlDs{sqlSvc: db, table: "newTemp"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Want: %v, Got: %v", tt.want, got)
+			}
+			if mock.ExpectationsWereMet() != nil {
+				t.Errorf("Want: %v, Got: %v", nil, mock.ExpectationsWereMet())
+			}
+		})
+	}
+}
+
+func TestSqlDs_LeaseWebhookDeliveries(t *testing.T) {
+	query := regexp.QuoteMeta("UPDATE newTemp_webhook_deliveries SET next_attempt_at = TIMESTAMPADD(SECOND, ?, CURRENT_TIMESTAMP) WHERE delivery_id IN (?,?)")
+	tests := []struct {
+		name      string
+		ids       []string
+		setupFunc func(sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name:      "SUCCESS::nothing to lease",
+			setupFunc: func(mock sqlmock.Sqlmock) {},
+		},
+		{
+			name: "SUCCESS::lease",
+			ids:  []string{"d1", "d2"},
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WithArgs(90, "d1", "d2").WillReturnResult(sqlmock.NewResult(0, 2))
+			},
+		},
+		{
+			name: "FAILURE::sql error",
+			ids:  []string{"d1", "d2"},
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WithArgs(90, "d1", "d2").WillReturnError(errors.New("sql error"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.setupFunc(mock)
+			dB := sqlDs{sqlSvc: db, table: "newTemp"}
+			err = dB.LeaseWebhookDeliveries(tt.ids, 90*time.Second)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			if mock.ExpectationsWereMet() != nil {
+				t.Errorf("Want: %v, Got: %v", nil, mock.ExpectationsWereMet())
+			}
+		})
+	}
+}
+
+func TestSqlDs_IdempotencyKeys(t *testing.T) {
+	purge := regexp.QuoteMeta("DELETE FROM newTemp_idempotency_keys WHERE user_id = ? AND created_at < TIMESTAMPADD(SECOND, ?, CURRENT_TIMESTAMP)")
+	insert := regexp.QuoteMeta("INSERT INTO newTemp_idempotency_keys(user_id, idempotency_key, fingerprint, response) VALUES(?,?,?,'') ON DUPLICATE KEY UPDATE user_id = user_id")
+	get := regexp.QuoteMeta("SELECT user_id, idempotency_key, fingerprint, completed, status, response, content_type FROM newTemp_idempotency_keys WHERE user_id = ? AND idempotency_key = ?")
+	columns := []string{"user_id", "idempotency_key", "fingerprint", "completed", "status", "response", "content_type"}
+	record := model.IdempotencyRecord{UserId: "123", IdempotencyKey: "abc", Fingerprint: "f1"}
+	completed := model.IdempotencyRecord{UserId: "123", IdempotencyKey: "abc", Fingerprint: "f1", Completed: true, CacheResponse: model.CacheResponse{Status: 201, Response: "{}", ContentType: "application/json"}}
+	tests := []struct {
+		name      string
+		setupFunc func(sqlmock.Sqlmock)
+		call      func(sqlDs) (interface{}, error)
+		want      interface{}
+		wantErr   bool
+	}{
+		{
+			name: "SUCCESS::key reserved",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(purge).WithArgs("123", -3600).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(insert).WithArgs("123", "abc", "f1").WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			call: func(d sqlDs) (interface{}, error) { return d.ReserveIdempotencyKey(record, time.Hour) },
+			want: true,
+		},
+		{
+			name: "SUCCESS::key already used",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(purge).WithArgs("123", -3600).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(insert).WithArgs("123", "abc", "f1").WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			call: func(d sqlDs) (interface{}, error) { return d.ReserveIdempotencyKey(record, time.Hour) },
+			want: false,
+		},
+		{
+			name: "FAILURE::purge expired keys",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(purge).WithArgs("123", -3600).WillReturnError(errors.New("sql error"))
+			},
+			call:    func(d sqlDs) (interface{}, error) { return d.ReserveIdempotencyKey(record, time.Hour) },
+			want:    false,
+			wantErr: true,
+		},
+		{
+			name: "FAILURE::reserve key",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(purge).WithArgs("123", -3600).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(insert).WithArgs("123", "abc", "f1").WillReturnError(errors.New("sql error"))
+			},
+			call:    func(d sqlDs) (interface{}, error) { return d.ReserveIdempotencyKey(record, time.Hour) },
+			want:    false,
+			wantErr: true,
+		},
+		{
+			name: "SUCCESS::get key",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(get).WithArgs("123", "abc").WillReturnRows(sqlmock.NewRows(columns).AddRow("123", "abc", "f1", true, 201, "{}", "application/json"))
+			},
+			call: func(d sqlDs) (interface{}, error) { return d.GetIdempotencyKey("123", "abc") },
+			want: &completed,
+		},
+		{
+			name: "SUCCESS::unknown key",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(get).WithArgs("123", "abc").WillReturnRows(sqlmock.NewRows(columns))
+			},
+			call: func(d sqlDs) (interface{}, error) { return d.GetIdempotencyKey("123", "abc") },
+			want: (*model.IdempotencyRecord)(nil),
+		},
+		{
+			name: "FAILURE::get key",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(get).WithArgs("123", "abc").WillReturnError(errors.New("sql error"))
+			},
+			call:    func(d sqlDs) (interface{}, error) { return d.GetIdempotencyKey("123", "abc") },
+			want:    (*model.IdempotencyRecord)(nil),
+			wantErr: true,
+		},
+		{
+			name: "SUCCESS::complete key",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE newTemp_idempotency_keys SET completed = TRUE, status = ?, response = ?, content_type = ? WHERE user_id = ? AND idempotency_key = ?")).WithArgs(201, "{}", "application/json", "123", "abc").WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			call: func(d sqlDs) (interface{}, error) { return nil, d.CompleteIdempotencyKey(completed) },
+		},
+		{
+			name: "SUCCESS::release key",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta("DELETE FROM newTemp_idempotency_keys WHERE user_id = ? AND idempotency_key = ?")).WithArgs("123", "abc").WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			call: func(d sqlDs) (interface{}, error) { return nil, d.ReleaseIdempotencyKey("123", "abc") },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.setupFunc(mock)
+			got, err := tt.call(sqlDs{sqlSvc: db, table: "newTemp"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Want: %v, Got: %v", tt.want, got)
+			}
+			if mock.ExpectationsWereMet() != nil {
+				t.Errorf("Want: %v, Got: %v", nil, mock.ExpectationsWereMet())
+			}
+		})
+	}
+}
+
+func TestSqlDs_Accounts(t *testing.T) {
+	insert := regexp.QuoteMeta("INSERT INTO newTemp_accounts(account_number, user_id, currency) VALUES(?,?,?) ON DUPLICATE KEY UPDATE account_number = account_number")
+	get := regexp.QuoteMeta("SELECT account_number, user_id, currency, created_at FROM newTemp_accounts WHERE account_number = ?")
+	columns := []string{"account_number", "user_id", "currency", "created_at"}
+	createdAt := time.Date(2023, time.June, 1, 0, 0, 0, 0, time.UTC)
+	account := model.Account{AccountNumber: 1, UserId: "123", Currency: "USD"}
+	tests := []struct {
+		name      string
+		setupFunc func(sqlmock.Sqlmock)
+		call      func(sqlDs) (interface{}, error)
+		want      interface{}
+		wantErr   bool
+	}{
+		{
+			name: "SUCCESS::account registered",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(insert).WithArgs(1, "123", "USD").WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			call: func(d sqlDs) (interface{}, error) { return d.InsertAccount(account) },
+			want: true,
+		},
+		{
+			name: "SUCCESS::account already registered",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(insert).WithArgs(1, "123", "USD").WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			call: func(d sqlDs) (interface{}, error) { return d.InsertAccount(account) },
+			want: false,
+		},
+		{
+			name: "FAILURE::register account",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(insert).WithArgs(1, "123", "USD").WillReturnError(errors.New("sql error"))
+			},
+			call:    func(d sqlDs) (interface{}, error) { return d.InsertAccount(account) },
+			want:    false,
+			wantErr: true,
+		},
+		{
+			name: "SUCCESS::get account",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(get).WithArgs(1).WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "123", "USD", createdAt))
+			},
+			call: func(d sqlDs) (interface{}, error) { return d.GetAccount(1) },
+			want: &model.Account{AccountNumber: 1, UserId: "123", Currency: "USD", CreatedAt: createdAt},
+		},
+		{
+			name: "SUCCESS::unregistered account",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(get).WithArgs(1).WillReturnRows(sqlmock.NewRows(columns))
+			},
+			call: func(d sqlDs) (interface{}, error) { return d.GetAccount(1) },
+			want: (*model.Account)(nil),
+		},
+		{
+			name: "FAILURE::get account",
+			setupFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(get).WithArgs(1).WillReturnError(errors.New("sql error"))
+			},
+			call:    func(d sqlDs) (interface{}, error) { return d.GetAccount(1) },
+			want:    (*model.Account)(nil),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.setupFunc(mock)
+			got, err := tt.call(sqlDs{sqlSvc: db, table: "newTemp"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Want: %v, Got: %v", tt.want, got)
+			}
+			if mock.ExpectationsWereMet() != nil {
+				t.Errorf("Want: %v, Got: %v", nil, mock.ExpectationsWereMet())
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package router
 
 import (
+	"expvar"
 	"net/http"
 
 	"github.com/PereRohit/util/constant"
@@ -40,30 +41,75 @@ func Register(svcCfg *config.SvcConfig) *mux.Router {
 	return m
 }
 
+// RegisterAdmin creates a new mux.Router with the internal routes of the service, its metrics and the registration of
+// accounts. It must only be served on the admin address, which is not exposed to clients.
+func RegisterAdmin(svcCfg *config.SvcConfig) *mux.Router {
+	m := mux.NewRouter()
+
+	// middleware for request hijacking and panic recovery
+	m.Use(middleware.RequestHijacker)
+	m.Use(middleware.RecoverPanic)
+
+	commons := handler.NewCommonSvc()
+	m.Handle("/debug/vars", expvar.Handler()).Methods(http.MethodGet)
+	m.NotFoundHandler = http.HandlerFunc(commons.RouteNotFound)
+	m.MethodNotAllowedHandler = http.HandlerFunc(commons.MethodNotAllowed)
+
+	// the account service registers the owners of accounts here
+	dataSource := datasource.NewSql(svcCfg.DbSvc, svcCfg.Cfg.DataBase.TableName)
+	svc := handler.NewTransactionManagementService(dataSource, svcCfg.ExternalService)
+	m.HandleFunc("/accounts/{account_number}", svc.RegisterAccount).Methods(http.MethodPut)
+
+	return m
+}
+
 // attachTransactionManagementServiceRoutes attaches all the routes for the TransactionManagementService
 func attachTransactionManagementServiceRoutes(m *mux.Router, svcCfg *config.SvcConfig) *mux.Router {
 	// create new datasource for the TransactionManagementService
 	dataSource := datasource.NewSql(svcCfg.DbSvc, svcCfg.Cfg.DataBase.TableName)
 
 	// create new middleware for the TransactionManagementService
-	middleware := middleware2.NewTransactionMgmtMiddleware(svcCfg)
+	middleware := middleware2.NewTransactionMgmtMiddleware(svcCfg, dataSource)
 
 	// create new handler for the TransactionManagementService
 	svc := handler.NewTransactionManagementService(dataSource, svcCfg.ExternalService)
 
 	// create new subrouter for the new transaction route
 	router := m.PathPrefix("").Subrouter()
-	router.HandleFunc("", svc.NewTransaction).Methods(http.MethodPost)
+	router.Handle("", middleware.Idempotency(http.HandlerFunc(svc.NewTransaction))).Methods(http.MethodPost)
+	router.Handle("/batch", middleware.Idempotency(http.HandlerFunc(svc.NewBatch))).Methods(http.MethodPost)
 	router.HandleFunc("/download/{transaction_id}", svc.DownloadTransaction).Methods(http.MethodGet)
+	router.HandleFunc("/statements", svc.GetStatement).Methods(http.MethodGet)
+	router.HandleFunc("/export.csv", svc.ExportTransactions).Methods(http.MethodGet)
+	router.HandleFunc("/export.{format:ofx|qfx|qif|camt053|mt940}", svc.ExportAccount).Methods(http.MethodGet)
+	// an import may be as large as the import file and the form around it
+	importLimit := svcCfg.Cfg.Import.MaxBytes + svcCfg.Cfg.Idempotency.MaxBytes
+	router.Handle("/import", middleware.IdempotencyLimit(importLimit)(http.HandlerFunc(svc.ImportTransactions))).Methods(http.MethodPost)
+	router.HandleFunc("/reviews", svc.GetReviewQueue).Methods(http.MethodGet)
+	router.HandleFunc("/{transaction_id}", svc.UpdateTransactionStatus).Methods(http.MethodPatch)
+	router.Handle("/{transaction_id}/reverse", middleware.Idempotency(http.HandlerFunc(svc.ReverseTransaction))).Methods(http.MethodPost)
+	router.Handle("/{transaction_id}/refund", middleware.Idempotency(http.HandlerFunc(svc.RefundTransaction))).Methods(http.MethodPost)
+	router.Handle("/schedules", middleware.Idempotency(http.HandlerFunc(svc.CreateSchedule))).Methods(http.MethodPost)
+	router.HandleFunc("/schedules", svc.GetSchedules).Methods(http.MethodGet)
+	router.HandleFunc("/schedules/{schedule_id}", svc.GetSchedule).Methods(http.MethodGet)
+	router.HandleFunc("/schedules/{schedule_id}", svc.UpdateSchedule).Methods(http.MethodPut)
+	router.HandleFunc("/schedules/{schedule_id}", svc.CancelSchedule).Methods(http.MethodDelete)
+	router.Handle("/webhooks", middleware.Idempotency(http.HandlerFunc(svc.CreateWebhook))).Methods(http.MethodPost)
+	router.HandleFunc("/webhooks", svc.GetWebhooks).Methods(http.MethodGet)
+	router.HandleFunc("/webhooks/{webhook_id}", svc.DeleteWebhook).Methods(http.MethodDelete)
+	router.HandleFunc("/webhooks/{webhook_id}/deliveries", svc.GetWebhookDeliveries).Methods(http.MethodGet)
+	router.Handle("/webhooks/{webhook_id}/deliveries/{delivery_id}/redeliver", middleware.Idempotency(http.HandlerFunc(svc.RedeliverWebhook))).Methods(http.MethodPost)
 
 	// attach middleware to the new transaction route
 	router.Use(middleware.ExtractUser)
 
-	// create new subrouter for the get transactions route
+	// create new subrouter for the get transactions routes
 	router2 := m.PathPrefix("").Subrouter()
 	router2.HandleFunc("", svc.GetTransactions).Methods(http.MethodGet)
+	router2.HandleFunc("/{transaction_id}", svc.GetTransaction).Methods(http.MethodGet)
+	router2.HandleFunc("/accounts/{account_number}/balance", svc.GetBalance).Methods(http.MethodGet)
 
-	// attach middleware to the get transactions route
+	// attach middleware to the get transactions routes
 	router2.Use(middleware.ExtractUser)
 	router2.Use(middleware.Cacher(true))
 
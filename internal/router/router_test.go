@@ -11,6 +11,7 @@ import (
 	"github.com/vatsal278/TransactionManagementService/internal/repo/authentication"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -177,6 +178,294 @@ func TestRegister(t *testing.T) {
 			},
 			give: httptest.NewRequest(http.MethodPut, "/v1/health", nil),
 		},
+		{
+			name: "Metrics not served publicly",
+			setup: func() *config.SvcConfig {
+				return &config.SvcConfig{
+					JwtSvc: config.JWTSvc{JwtSvc: authentication.JWTAuthService("")},
+					Cfg: &config.Config{
+						ServiceRouteVersion: "v1",
+						DataBase: config.DbCfg{
+							Driver: "mysql",
+						},
+					},
+					ServiceRouteVersion: "v1",
+					DbSvc:               config.DbSvc{}}
+			},
+			validate: func(w http.ResponseWriter) {
+				wIn := w.(*httptest.ResponseRecorder)
+
+				diff := testutil.Diff(wIn.Code, http.StatusNotFound)
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+			},
+			give: httptest.NewRequest(http.MethodGet, "/v1/debug/vars", nil),
+		},
+		{
+			name: "Get transaction requires authentication",
+			setup: func() *config.SvcConfig {
+				return &config.SvcConfig{
+					JwtSvc: config.JWTSvc{JwtSvc: authentication.JWTAuthService("")},
+					Cfg: &config.Config{
+						ServiceRouteVersion: "v1",
+						DataBase: config.DbCfg{
+							Driver: "mysql",
+						},
+					},
+					ServiceRouteVersion: "v1",
+					DbSvc:               config.DbSvc{}}
+			},
+			validate: func(w http.ResponseWriter) {
+				wIn := w.(*httptest.ResponseRecorder)
+
+				diff := testutil.Diff(wIn.Code, http.StatusUnauthorized)
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+			},
+			give: httptest.NewRequest(http.MethodGet, "/v1/1234-abcd", nil),
+		},
+		{
+			name: "Update transaction status requires authentication",
+			setup: func() *config.SvcConfig {
+				return &config.SvcConfig{
+					JwtSvc: config.JWTSvc{JwtSvc: authentication.JWTAuthService("")},
+					Cfg: &config.Config{
+						ServiceRouteVersion: "v1",
+						DataBase: config.DbCfg{
+							Driver: "mysql",
+						},
+					},
+					ServiceRouteVersion: "v1",
+					DbSvc:               config.DbSvc{}}
+			},
+			validate: func(w http.ResponseWriter) {
+				wIn := w.(*httptest.ResponseRecorder)
+
+				diff := testutil.Diff(wIn.Code, http.StatusUnauthorized)
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+			},
+			give: httptest.NewRequest(http.MethodPatch, "/v1/1234-abcd", nil),
+		},
+		{
+			name: "Refund transaction requires authentication",
+			setup: func() *config.SvcConfig {
+				return &config.SvcConfig{
+					JwtSvc: config.JWTSvc{JwtSvc: authentication.JWTAuthService("")},
+					Cfg: &config.Config{
+						ServiceRouteVersion: "v1",
+						DataBase: config.DbCfg{
+							Driver: "mysql",
+						},
+					},
+					ServiceRouteVersion: "v1",
+					DbSvc:               config.DbSvc{}}
+			},
+			validate: func(w http.ResponseWriter) {
+				wIn := w.(*httptest.ResponseRecorder)
+
+				diff := testutil.Diff(wIn.Code, http.StatusUnauthorized)
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+			},
+			give: httptest.NewRequest(http.MethodPost, "/v1/1234-abcd/refund", nil),
+		},
+		{
+			name: "Account balance requires authentication",
+			setup: func() *config.SvcConfig {
+				return &config.SvcConfig{
+					JwtSvc: config.JWTSvc{JwtSvc: authentication.JWTAuthService("")},
+					Cfg: &config.Config{
+						ServiceRouteVersion: "v1",
+						DataBase: config.DbCfg{
+							Driver: "mysql",
+						},
+					},
+					ServiceRouteVersion: "v1",
+					DbSvc:               config.DbSvc{}}
+			},
+			validate: func(w http.ResponseWriter) {
+				wIn := w.(*httptest.ResponseRecorder)
+
+				diff := testutil.Diff(wIn.Code, http.StatusUnauthorized)
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+			},
+			give: httptest.NewRequest(http.MethodGet, "/v1/accounts/1/balance", nil),
+		},
+		{
+			name: "Schedules require authentication",
+			setup: func() *config.SvcConfig {
+				return &config.SvcConfig{
+					JwtSvc: config.JWTSvc{JwtSvc: authentication.JWTAuthService("")},
+					Cfg: &config.Config{
+						ServiceRouteVersion: "v1",
+						DataBase: config.DbCfg{
+							Driver: "mysql",
+						},
+					},
+					ServiceRouteVersion: "v1",
+					DbSvc:               config.DbSvc{}}
+			},
+			validate: func(w http.ResponseWriter) {
+				wIn := w.(*httptest.ResponseRecorder)
+
+				diff := testutil.Diff(wIn.Code, http.StatusUnauthorized)
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+			},
+			give: httptest.NewRequest(http.MethodGet, "/v1/schedules", nil),
+		},
+		{
+			name: "Statements require authentication",
+			setup: func() *config.SvcConfig {
+				return &config.SvcConfig{
+					JwtSvc: config.JWTSvc{JwtSvc: authentication.JWTAuthService("")},
+					Cfg: &config.Config{
+						ServiceRouteVersion: "v1",
+						DataBase: config.DbCfg{
+							Driver: "mysql",
+						},
+					},
+					ServiceRouteVersion: "v1",
+					DbSvc:               config.DbSvc{}}
+			},
+			validate: func(w http.ResponseWriter) {
+				wIn := w.(*httptest.ResponseRecorder)
+
+				diff := testutil.Diff(wIn.Code, http.StatusUnauthorized)
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+			},
+			give: httptest.NewRequest(http.MethodGet, "/v1/statements?account_number=1&month=2023-06", nil),
+		},
+		{
+			name: "Exports require authentication",
+			setup: func() *config.SvcConfig {
+				return &config.SvcConfig{
+					JwtSvc: config.JWTSvc{JwtSvc: authentication.JWTAuthService("")},
+					Cfg: &config.Config{
+						ServiceRouteVersion: "v1",
+						DataBase: config.DbCfg{
+							Driver: "mysql",
+						},
+					},
+					ServiceRouteVersion: "v1",
+					DbSvc:               config.DbSvc{}}
+			},
+			validate: func(w http.ResponseWriter) {
+				wIn := w.(*httptest.ResponseRecorder)
+
+				diff := testutil.Diff(wIn.Code, http.StatusUnauthorized)
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+			},
+			give: httptest.NewRequest(http.MethodGet, "/v1/export.csv?type=debit", nil),
+		},
+		{
+			name: "Account exports require authentication",
+			setup: func() *config.SvcConfig {
+				return &config.SvcConfig{
+					JwtSvc: config.JWTSvc{JwtSvc: authentication.JWTAuthService("")},
+					Cfg: &config.Config{
+						ServiceRouteVersion: "v1",
+						DataBase: config.DbCfg{
+							Driver: "mysql",
+						},
+					},
+					ServiceRouteVersion: "v1",
+					DbSvc:               config.DbSvc{}}
+			},
+			validate: func(w http.ResponseWriter) {
+				wIn := w.(*httptest.ResponseRecorder)
+
+				diff := testutil.Diff(wIn.Code, http.StatusUnauthorized)
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+			},
+			give: httptest.NewRequest(http.MethodGet, "/v1/export.ofx?account_number=1&from=2023-06-01&to=2023-06-30", nil),
+		},
+		{
+			name: "Imports require authentication",
+			setup: func() *config.SvcConfig {
+				return &config.SvcConfig{
+					JwtSvc: config.JWTSvc{JwtSvc: authentication.JWTAuthService("")},
+					Cfg: &config.Config{
+						ServiceRouteVersion: "v1",
+						DataBase: config.DbCfg{
+							Driver: "mysql",
+						},
+					},
+					ServiceRouteVersion: "v1",
+					DbSvc:               config.DbSvc{}}
+			},
+			validate: func(w http.ResponseWriter) {
+				wIn := w.(*httptest.ResponseRecorder)
+
+				diff := testutil.Diff(wIn.Code, http.StatusUnauthorized)
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+			},
+			give: httptest.NewRequest(http.MethodPost, "/v1/import", nil),
+		},
+		{
+			name: "Batches require authentication",
+			setup: func() *config.SvcConfig {
+				return &config.SvcConfig{
+					JwtSvc: config.JWTSvc{JwtSvc: authentication.JWTAuthService("")},
+					Cfg: &config.Config{
+						ServiceRouteVersion: "v1",
+						DataBase: config.DbCfg{
+							Driver: "mysql",
+						},
+					},
+					ServiceRouteVersion: "v1",
+					DbSvc:               config.DbSvc{}}
+			},
+			validate: func(w http.ResponseWriter) {
+				wIn := w.(*httptest.ResponseRecorder)
+
+				diff := testutil.Diff(wIn.Code, http.StatusUnauthorized)
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+			},
+			give: httptest.NewRequest(http.MethodPost, "/v1/batch", nil),
+		},
+		{
+			name: "Webhooks require authentication",
+			setup: func() *config.SvcConfig {
+				return &config.SvcConfig{
+					JwtSvc: config.JWTSvc{JwtSvc: authentication.JWTAuthService("")},
+					Cfg: &config.Config{
+						ServiceRouteVersion: "v1",
+						DataBase: config.DbCfg{
+							Driver: "mysql",
+						},
+					},
+					ServiceRouteVersion: "v1",
+					DbSvc:               config.DbSvc{}}
+			},
+			validate: func(w http.ResponseWriter) {
+				wIn := w.(*httptest.ResponseRecorder)
+
+				diff := testutil.Diff(wIn.Code, http.StatusUnauthorized)
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+			},
+			give: httptest.NewRequest(http.MethodGet, "/v1/webhooks/w1/deliveries", nil),
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -195,3 +484,63 @@ func TestRegister(t *testing.T) {
 		})
 	}
 }
+
+func TestRegisterAdmin(t *testing.T) {
+	tests := []struct {
+		name     string
+		validate func(http.ResponseWriter)
+		give     *http.Request
+	}{
+		{
+			name: "Metrics",
+			validate: func(w http.ResponseWriter) {
+				wIn := w.(*httptest.ResponseRecorder)
+
+				diff := testutil.Diff(wIn.Code, http.StatusOK)
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+
+				vars := map[string]interface{}{}
+				err := json.NewDecoder(wIn.Body).Decode(&vars)
+				diff = testutil.Diff(err, nil)
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+			},
+			give: httptest.NewRequest(http.MethodGet, "/debug/vars", nil),
+		},
+		{
+			name: "Service routes not served",
+			validate: func(w http.ResponseWriter) {
+				wIn := w.(*httptest.ResponseRecorder)
+
+				diff := testutil.Diff(wIn.Code, http.StatusNotFound)
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+			},
+			give: httptest.NewRequest(http.MethodGet, "/v1/health", nil),
+		},
+		{
+			name: "Register account:: invalid account number",
+			validate: func(w http.ResponseWriter) {
+				wIn := w.(*httptest.ResponseRecorder)
+
+				diff := testutil.Diff(wIn.Code, http.StatusBadRequest)
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+			},
+			give: httptest.NewRequest(http.MethodPut, "/accounts/abc", strings.NewReader(`{"user_id":"123"}`)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := RegisterAdmin(&config.SvcConfig{Cfg: &config.Config{}})
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, tt.give)
+			tt.validate(w)
+		})
+	}
+}
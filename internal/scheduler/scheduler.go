@@ -0,0 +1,56 @@
+package scheduler
+
+import (
+	"context"
+	"expvar"
+	"github.com/PereRohit/util/log"
+	"github.com/vatsal278/TransactionManagementService/internal/config"
+	"github.com/vatsal278/TransactionManagementService/internal/logic"
+	"time"
+)
+
+// metrics counts the outcome of scheduled transaction executions, published under "scheduler" by expvar
+var metrics = expvar.NewMap("scheduler")
+
+// Scheduler runs the schedules whose occurrence is due through the transaction logic.
+// Each occurrence is run once, even by schedulers running concurrently, and failures are retried with exponential
+// backoff before the occurrence is skipped.
+type Scheduler struct {
+	svc logic.TransactionManagementServiceLogicIer
+	cfg config.SchedulerCfg
+}
+
+// NewScheduler returns a Scheduler creating the transactions of due schedules through svc
+func NewScheduler(svc logic.TransactionManagementServiceLogicIer, cfg config.SchedulerCfg) *Scheduler {
+	return &Scheduler{
+		svc: svc,
+		cfg: cfg,
+	}
+}
+
+// Run runs due schedules every poll interval until the context is cancelled
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := s.RunOnce()
+			if err != nil {
+				metrics.Add("errors", 1)
+				log.Error(err)
+			}
+		}
+	}
+}
+
+// RunOnce runs one batch of due schedules and counts the outcome of every execution
+func (s *Scheduler) RunOnce() error {
+	executions, err := s.svc.RunDueSchedules()
+	for _, execution := range executions {
+		metrics.Add(execution.Outcome, 1)
+	}
+	return err
+}
@@ -0,0 +1,64 @@
+package scheduler
+
+import (
+	"errors"
+	"expvar"
+	"github.com/golang/mock/gomock"
+	"github.com/vatsal278/TransactionManagementService/internal/config"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"github.com/vatsal278/TransactionManagementService/pkg/mock"
+	"testing"
+)
+
+func TestScheduler_RunOnce(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	// count returns the number of executions counted with an outcome so far
+	count := func(outcome string) int64 {
+		v, ok := metrics.Get(outcome).(*expvar.Int)
+		if !ok {
+			return 0
+		}
+		return v.Value()
+	}
+	tests := []struct {
+		name       string
+		executions []model.ScheduleExecution
+		err        error
+		want       map[string]int64
+		wantErr    bool
+	}{
+		{
+			name:       "Success::executions counted",
+			executions: []model.ScheduleExecution{{Outcome: model.ExecutionSucceeded}, {Outcome: model.ExecutionSucceeded}, {Outcome: model.ExecutionRetrying}},
+			want:       map[string]int64{model.ExecutionSucceeded: 2, model.ExecutionRetrying: 1},
+		},
+		{
+			name:       "Failure::executions counted along with the error",
+			executions: []model.ScheduleExecution{{Outcome: model.ExecutionSkipped}},
+			err:        errors.New("error"),
+			want:       map[string]int64{model.ExecutionSkipped: 1},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLogic := mock.NewMockTransactionManagementServiceLogicIer(mockCtrl)
+			mockLogic.EXPECT().RunDueSchedules().Times(1).Return(tt.executions, tt.err)
+			before := map[string]int64{}
+			for outcome := range tt.want {
+				before[outcome] = count(outcome)
+			}
+			err := NewScheduler(mockLogic, config.SchedulerCfg{}).RunOnce()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			for outcome, want := range tt.want {
+				got := count(outcome) - before[outcome]
+				if got != want {
+					t.Errorf("Want: %v, Got: %v", want, got)
+				}
+			}
+		})
+	}
+}
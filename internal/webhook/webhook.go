@@ -0,0 +1,303 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"expvar"
+	"fmt"
+	"github.com/PereRohit/util/log"
+	"github.com/vatsal278/TransactionManagementService/internal/config"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"github.com/vatsal278/TransactionManagementService/internal/outbox"
+	"github.com/vatsal278/TransactionManagementService/internal/repo/datasource"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Headers sent along with every delivery
+const (
+	HeaderEvent     = "X-Webhook-Event"     // Type of the event delivered
+	HeaderDelivery  = "X-Webhook-Delivery"  // Id of the delivery, the same across its attempts
+	HeaderSignature = "X-Webhook-Signature" // Timestamp of the attempt and signature of the body, see Sign
+)
+
+// maxErrorLength is the longest error stored on a delivery
+const maxErrorLength = 1024
+
+// metrics counts the outcome of delivery attempts, published under "webhooks" by expvar
+var metrics = expvar.NewMap("webhooks")
+
+var (
+	// ErrMalformedSignature is returned by Verify when the signature header cannot be parsed
+	ErrMalformedSignature = errors.New("malformed webhook signature")
+	// ErrSignatureExpired is returned by Verify when the signature was made too long ago
+	ErrSignatureExpired = errors.New("webhook signature expired")
+	// ErrSignatureMismatch is returned by Verify when the body was not signed with the secret
+	ErrSignatureMismatch = errors.New("webhook signature mismatch")
+	// ErrAddressNotAllowed is returned when an endpoint resolves to an address deliveries must not reach
+	ErrAddressNotAllowed = errors.New("endpoint address is not allowed")
+)
+
+// blockedNetworks are refused on top of the special purpose addresses recognised by net.IP: "this network" and the
+// shared address space of carrier-grade NAT, which some cloud networks route internally
+var blockedNetworks = []*net.IPNet{
+	{IP: net.IPv4(0, 0, 0, 0), Mask: net.CIDRMask(8, 32)},
+	{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)},
+}
+
+// AllowedIP reports whether deliveries may reach an address. Loopback, private, link-local (among them the cloud
+// metadata services), unspecified and multicast addresses are refused, so a webhook cannot be used to reach the
+// network the service runs in.
+func AllowedIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// Sign returns the hex encoded HMAC-SHA256, keyed with the webhook secret, of the timestamp in unix seconds, a dot
+// and the body
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignatureHeader returns the value of the signature header of a body sent at the given time
+func SignatureHeader(secret string, at time.Time, body []byte) string {
+	timestamp := at.Unix()
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, Sign(secret, timestamp, body))
+}
+
+// Verify checks, on behalf of a receiver, that a body was signed with the webhook secret no longer than tolerance
+// before now. Receivers should reject deliveries failing it, and can discard repeats by the event id of the body.
+func Verify(secret string, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var timestamp int64
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return ErrMalformedSignature
+		}
+		switch key {
+		case "t":
+			var err error
+			timestamp, err = strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return ErrMalformedSignature
+			}
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == 0 || len(signatures) == 0 {
+		return ErrMalformedSignature
+	}
+	at := time.Unix(timestamp, 0)
+	if now.Sub(at) > tolerance || at.Sub(now) > tolerance {
+		return ErrSignatureExpired
+	}
+	want := Sign(secret, timestamp, body)
+	for _, signature := range signatures {
+		if hmac.Equal([]byte(signature), []byte(want)) {
+			return nil
+		}
+	}
+	return ErrSignatureMismatch
+}
+
+// Dispatcher delivers pending webhook deliveries to the endpoints they are queued for.
+// Failed deliveries are retried with exponential backoff and marked failed after the maximum number of attempts.
+type Dispatcher struct {
+	ds     datasource.DataSourceI
+	cfg    config.WebhookCfg
+	client *http.Client
+}
+
+// NewDispatcher returns a Dispatcher reading the deliveries through ds
+func NewDispatcher(ds datasource.DataSourceI, cfg config.WebhookCfg) *Dispatcher {
+	return &Dispatcher{
+		ds:     ds,
+		cfg:    cfg,
+		client: newClient(cfg.Timeout, AllowedIP),
+	}
+}
+
+// newClient returns the client deliveries are sent with. It only connects to addresses allowed accepts, checked on
+// the address actually dialed so that neither DNS answers changing after the webhook was registered nor redirects
+// get around it. Redirects are not followed and count as failed attempts, and no proxy is used.
+func newClient(timeout time.Duration, allowed func(net.IP) bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network string, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !allowed(ip) {
+				return ErrAddressNotAllowed
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Run dispatches due deliveries every poll interval until the context is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := d.DispatchOnce()
+			if err != nil {
+				metrics.Add("errors", 1)
+				log.Error(err)
+			}
+		}
+	}
+}
+
+// DispatchOnce attempts one batch of due deliveries and records the outcome of every attempt.
+// The batch is claimed in a short database transaction and sent after it commits, so no lock is held while the
+// endpoints are called. A delivery whose outcome cannot be recorded is attempted again once its lease expires.
+func (d *Dispatcher) DispatchOnce() error {
+	deliveries, err := d.claim()
+	if err != nil {
+		return err
+	}
+	var updateErr error
+	for _, delivery := range deliveries {
+		if delivery.WebhookStatus == model.WebhookDisabled {
+			// Deliveries queued before the webhook was removed are dropped without being sent
+			delivery.Status = model.WebhookDeliveryFailed
+			delivery.LastError = "webhook was disabled"
+			metrics.Add("dropped", 1)
+		} else {
+			delivery.Attempts++
+			delivery.ResponseStatus, err = d.deliver(delivery)
+			switch {
+			case err == nil:
+				delivery.Status = model.WebhookDeliveryDelivered
+				delivery.LastError = ""
+				metrics.Add("delivered", 1)
+			case delivery.Attempts >= d.cfg.MaxAttempts:
+				delivery.Status = model.WebhookDeliveryFailed
+				delivery.LastError = truncate(attemptError(err))
+				metrics.Add("failed", 1)
+				log.Error(fmt.Sprintf("webhook delivery %s of event %s failed after %d attempts: %v", delivery.DeliveryId, delivery.EventId, delivery.Attempts, err))
+			default:
+				delivery.LastError = truncate(attemptError(err))
+				delivery.RetryAfter = outbox.Backoff(delivery.Attempts, d.cfg.BaseBackoff, d.cfg.MaxBackoff)
+				metrics.Add("retried", 1)
+				log.Info(fmt.Sprintf("webhook delivery %s of event %s failed, retrying in %s: %v", delivery.DeliveryId, delivery.EventId, delivery.RetryAfter, err))
+			}
+		}
+		err = d.ds.UpdateWebhookDelivery(delivery)
+		if err != nil {
+			log.Error(fmt.Sprintf("recording the outcome of webhook delivery %s of event %s: %v", delivery.DeliveryId, delivery.EventId, err))
+			updateErr = err
+		}
+	}
+	return updateErr
+}
+
+// claim picks up a batch of due deliveries and leases them, so concurrent dispatchers never send the same delivery
+func (d *Dispatcher) claim() ([]model.WebhookDelivery, error) {
+	var deliveries []model.WebhookDelivery
+	err := d.ds.Transaction(func(ds datasource.DataSourceI) error {
+		var err error
+		deliveries, err = ds.GetDueWebhookDeliveries(d.cfg.BatchSize)
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+		ids := make([]string, 0, len(deliveries))
+		for _, delivery := range deliveries {
+			ids = append(ids, delivery.DeliveryId)
+		}
+		return ds.LeaseWebhookDeliveries(ids, outbox.Lease(len(deliveries), d.cfg.Timeout))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// deliver posts a delivery to its endpoint, signed with the webhook secret, and returns the status the endpoint
+// answered with. It fails unless the endpoint answers with a 2xx status.
+func (d *Dispatcher) deliver(delivery model.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequest(http.MethodPost, delivery.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, delivery.DeliveryId)
+	req.Header.Set(HeaderSignature, SignatureHeader(delivery.Secret, time.Now(), body))
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("POST %s responded with status %d", delivery.Url, resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// attemptError describes a failed attempt to the owner of the webhook. Errors of the transport are reduced to their
+// kind, so the delivery log does not disclose how the service sees the network; the full error is only logged.
+func attemptError(err error) string {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return err.Error()
+	}
+	switch {
+	case errors.Is(err, ErrAddressNotAllowed):
+		return ErrAddressNotAllowed.Error()
+	case urlErr.Timeout():
+		return "timed out"
+	default:
+		return "connection failed"
+	}
+}
+
+// truncate shortens an error to what a delivery can store
+func truncate(s string) string {
+	if len(s) > maxErrorLength {
+		return s[:maxErrorLength]
+	}
+	return s
+}
@@ -0,0 +1,324 @@
+package webhook
+
+import (
+	"errors"
+	"github.com/PereRohit/util/testutil"
+	"github.com/golang/mock/gomock"
+	"github.com/vatsal278/TransactionManagementService/internal/config"
+	"github.com/vatsal278/TransactionManagementService/internal/model"
+	"github.com/vatsal278/TransactionManagementService/internal/outbox"
+	"github.com/vatsal278/TransactionManagementService/internal/repo/datasource"
+	"github.com/vatsal278/TransactionManagementService/pkg/mock"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"id":"e1"}`)
+	tests := []struct {
+		name   string
+		header string
+		want   error
+	}{
+		{name: "valid", header: SignatureHeader("whsec_abc", now, body)},
+		{name: "valid among several signatures", header: "t=1700000000,v1=00," + "v1=" + Sign("whsec_abc", 1700000000, body)},
+		{name: "within tolerance", header: SignatureHeader("whsec_abc", now.Add(-4*time.Minute), body)},
+		{name: "expired", header: SignatureHeader("whsec_abc", now.Add(-6*time.Minute), body), want: ErrSignatureExpired},
+		{name: "other secret", header: SignatureHeader("whsec_xyz", now, body), want: ErrSignatureMismatch},
+		{name: "no timestamp", header: "v1=" + Sign("whsec_abc", 1700000000, body), want: ErrMalformedSignature},
+		{name: "no signature", header: "t=1700000000", want: ErrMalformedSignature},
+		{name: "garbage", header: "signature", want: ErrMalformedSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify("whsec_abc", tt.header, body, 5*time.Minute, now)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Want: %v, Got: %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestAllowedIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "93.184.216.34", want: true},
+		{ip: "2606:2800:220:1:248:1893:25c8:1946", want: true},
+		{ip: "127.0.0.1"},
+		{ip: "::1"},
+		{ip: "::ffff:127.0.0.1"},
+		{ip: "10.1.2.3"},
+		{ip: "172.16.0.1"},
+		{ip: "192.168.1.1"},
+		{ip: "fd00::1"},
+		{ip: "169.254.169.254"},
+		{ip: "fe80::1"},
+		{ip: "0.0.0.0"},
+		{ip: "0.1.2.3"},
+		{ip: "::"},
+		{ip: "100.64.0.1"},
+		{ip: "224.0.0.1"},
+		{ip: "ff02::1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			got := AllowedIP(net.ParseIP(tt.ip))
+			if got != tt.want {
+				t.Errorf("Want: %v, Got: %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDispatcher_DispatchOnce(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	// The receiver checks every delivery the way a subscriber would
+	var received []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Method != http.MethodPost || r.Header.Get(HeaderEvent) != model.EventTransactionCreated || r.Header.Get(HeaderDelivery) != "d1" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		err := Verify("whsec_abc", r.Header.Get(HeaderSignature), body, time.Minute, time.Now())
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/hook", http.StatusFound)
+			return
+		}
+		if r.URL.Path == "/busy" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		received = append(received, string(body))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	cfg := config.WebhookCfg{Timeout: time.Second, BaseBackoff: time.Second, MaxBackoff: time.Minute, MaxAttempts: 3, BatchSize: 10}
+	// inTransaction makes the mock run the function given to Transaction against itself
+	inTransaction := func(mockDs *mock.MockDataSourceI) {
+		mockDs.EXPECT().Transaction(gomock.Any()).Times(1).DoAndReturn(func(fn func(datasource.DataSourceI) error) error {
+			return fn(mockDs)
+		})
+	}
+	lease := outbox.Lease(1, time.Second)
+	delivery := model.WebhookDelivery{DeliveryId: "d1", WebhookId: "w1", EventId: "e1", EventType: model.EventTransactionCreated, TransactionId: "abc", Payload: `{"id":"e1"}`, Status: model.WebhookDeliveryPending, Url: srv.URL + "/hook", Secret: "whsec_abc", WebhookStatus: model.WebhookActive}
+	// The test server listens on loopback, which the dispatcher refuses unless restricted is false
+	allowAll := func(net.IP) bool { return true }
+	tests := []struct {
+		name         string
+		restricted   bool
+		setup        func() datasource.DataSourceI
+		wantReceived []string
+		wantErr      bool
+	}{
+		{
+			name: "Success::delivered",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				inTransaction(mockDs)
+				mockDs.EXPECT().GetDueWebhookDeliveries(10).Times(1).Return([]model.WebhookDelivery{delivery}, nil)
+				mockDs.EXPECT().LeaseWebhookDeliveries([]string{"d1"}, lease).Times(1).Return(nil)
+				delivered := delivery
+				delivered.Status = model.WebhookDeliveryDelivered
+				delivered.Attempts = 1
+				delivered.ResponseStatus = http.StatusNoContent
+				mockDs.EXPECT().UpdateWebhookDelivery(delivered).Times(1).Return(nil)
+				return mockDs
+			},
+			wantReceived: []string{`{"id":"e1"}`},
+		},
+		{
+			name: "Success::wrong secret scheduled for retry",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				inTransaction(mockDs)
+				failing := delivery
+				failing.Secret = "whsec_xyz"
+				failing.Attempts = 1
+				mockDs.EXPECT().GetDueWebhookDeliveries(10).Times(1).Return([]model.WebhookDelivery{failing}, nil)
+				mockDs.EXPECT().LeaseWebhookDeliveries([]string{"d1"}, lease).Times(1).Return(nil)
+				retry := failing
+				retry.Attempts = 2
+				retry.ResponseStatus = http.StatusUnauthorized
+				retry.LastError = "POST " + srv.URL + "/hook responded with status 401"
+				retry.RetryAfter = 2 * time.Second
+				mockDs.EXPECT().UpdateWebhookDelivery(retry).Times(1).Return(nil)
+				return mockDs
+			},
+		},
+		{
+			name: "Success::failed after max attempts",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				inTransaction(mockDs)
+				busy := delivery
+				busy.Url = srv.URL + "/busy"
+				busy.Attempts = 2
+				mockDs.EXPECT().GetDueWebhookDeliveries(10).Times(1).Return([]model.WebhookDelivery{busy}, nil)
+				mockDs.EXPECT().LeaseWebhookDeliveries([]string{"d1"}, lease).Times(1).Return(nil)
+				failed := busy
+				failed.Attempts = 3
+				failed.Status = model.WebhookDeliveryFailed
+				failed.ResponseStatus = http.StatusServiceUnavailable
+				failed.LastError = "POST " + srv.URL + "/busy responded with status 503"
+				mockDs.EXPECT().UpdateWebhookDelivery(failed).Times(1).Return(nil)
+				return mockDs
+			},
+		},
+		{
+			name: "Success::disabled webhook dropped",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				inTransaction(mockDs)
+				disabled := delivery
+				disabled.WebhookStatus = model.WebhookDisabled
+				mockDs.EXPECT().GetDueWebhookDeliveries(10).Times(1).Return([]model.WebhookDelivery{disabled}, nil)
+				mockDs.EXPECT().LeaseWebhookDeliveries([]string{"d1"}, lease).Times(1).Return(nil)
+				dropped := disabled
+				dropped.Status = model.WebhookDeliveryFailed
+				dropped.LastError = "webhook was disabled"
+				mockDs.EXPECT().UpdateWebhookDelivery(dropped).Times(1).Return(nil)
+				return mockDs
+			},
+		},
+		{
+			name: "Failure::get due deliveries",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				inTransaction(mockDs)
+				mockDs.EXPECT().GetDueWebhookDeliveries(10).Times(1).Return(nil, errors.New("error"))
+				return mockDs
+			},
+			wantErr: true,
+		},
+		{
+			name: "Success::redirect not followed",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				inTransaction(mockDs)
+				redirect := delivery
+				redirect.Url = srv.URL + "/redirect"
+				mockDs.EXPECT().GetDueWebhookDeliveries(10).Times(1).Return([]model.WebhookDelivery{redirect}, nil)
+				mockDs.EXPECT().LeaseWebhookDeliveries([]string{"d1"}, lease).Times(1).Return(nil)
+				retry := redirect
+				retry.Attempts = 1
+				retry.ResponseStatus = http.StatusFound
+				retry.LastError = "POST " + srv.URL + "/redirect responded with status 302"
+				retry.RetryAfter = time.Second
+				mockDs.EXPECT().UpdateWebhookDelivery(retry).Times(1).Return(nil)
+				return mockDs
+			},
+		},
+		{
+			name:       "Success::loopback endpoint refused",
+			restricted: true,
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				inTransaction(mockDs)
+				mockDs.EXPECT().GetDueWebhookDeliveries(10).Times(1).Return([]model.WebhookDelivery{delivery}, nil)
+				mockDs.EXPECT().LeaseWebhookDeliveries([]string{"d1"}, lease).Times(1).Return(nil)
+				retry := delivery
+				retry.Attempts = 1
+				retry.LastError = "endpoint address is not allowed"
+				retry.RetryAfter = time.Second
+				mockDs.EXPECT().UpdateWebhookDelivery(retry).Times(1).Return(nil)
+				return mockDs
+			},
+		},
+		{
+			name: "Success::connection error not disclosed",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				inTransaction(mockDs)
+				closed := delivery
+				closed.Url = "http://127.0.0.1:1/hook"
+				mockDs.EXPECT().GetDueWebhookDeliveries(10).Times(1).Return([]model.WebhookDelivery{closed}, nil)
+				mockDs.EXPECT().LeaseWebhookDeliveries([]string{"d1"}, lease).Times(1).Return(nil)
+				retry := closed
+				retry.Attempts = 1
+				retry.LastError = "connection failed"
+				retry.RetryAfter = time.Second
+				mockDs.EXPECT().UpdateWebhookDelivery(retry).Times(1).Return(nil)
+				return mockDs
+			},
+		},
+		{
+			name: "Success::no due deliveries",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				inTransaction(mockDs)
+				mockDs.EXPECT().GetDueWebhookDeliveries(10).Times(1).Return(nil, nil)
+				return mockDs
+			},
+		},
+		{
+			name: "Failure::lease deliveries",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				inTransaction(mockDs)
+				mockDs.EXPECT().GetDueWebhookDeliveries(10).Times(1).Return([]model.WebhookDelivery{delivery}, nil)
+				mockDs.EXPECT().LeaseWebhookDeliveries([]string{"d1"}, lease).Times(1).Return(errors.New("error"))
+				return mockDs
+			},
+			wantErr: true,
+		},
+		{
+			name: "Failure::update delivery does not stop the batch",
+			setup: func() datasource.DataSourceI {
+				mockDs := mock.NewMockDataSourceI(mockCtrl)
+				inTransaction(mockDs)
+				other := delivery
+				other.DeliveryId = "d2"
+				mockDs.EXPECT().GetDueWebhookDeliveries(10).Times(1).Return([]model.WebhookDelivery{delivery, other}, nil)
+				mockDs.EXPECT().LeaseWebhookDeliveries([]string{"d1", "d2"}, outbox.Lease(2, time.Second)).Times(1).Return(nil)
+				delivered := delivery
+				delivered.Status = model.WebhookDeliveryDelivered
+				delivered.Attempts = 1
+				delivered.ResponseStatus = http.StatusNoContent
+				retry := other
+				retry.Attempts = 1
+				retry.ResponseStatus = http.StatusNotFound
+				retry.LastError = "POST " + srv.URL + "/hook responded with status 404"
+				retry.RetryAfter = time.Second
+				gomock.InOrder(
+					mockDs.EXPECT().UpdateWebhookDelivery(delivered).Times(1).Return(errors.New("error")),
+					mockDs.EXPECT().UpdateWebhookDelivery(retry).Times(1).Return(nil),
+				)
+				return mockDs
+			},
+			wantReceived: []string{`{"id":"e1"}`},
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received = nil
+			d := NewDispatcher(tt.setup(), cfg)
+			if !tt.restricted {
+				d.client = newClient(cfg.Timeout, allowAll)
+			}
+			err := d.DispatchOnce()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Want: %v, Got: %v", tt.wantErr, err)
+			}
+			diff := testutil.Diff(received, tt.wantReceived)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
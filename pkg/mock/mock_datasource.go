@@ -6,9 +6,11 @@ package mock
 
 import (
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	model "github.com/vatsal278/TransactionManagementService/internal/model"
+	datasource "github.com/vatsal278/TransactionManagementService/internal/repo/datasource"
 )
 
 // MockDataSourceI is a mock of DataSourceI interface.
@@ -34,10 +36,24 @@ func (m *MockDataSourceI) EXPECT() *MockDataSourceIMockRecorder {
 	return m.recorder
 }
 
+// CompleteIdempotencyKey mocks base method.
+func (m *MockDataSourceI) CompleteIdempotencyKey(arg0 model.IdempotencyRecord) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteIdempotencyKey", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CompleteIdempotencyKey indicates an expected call of CompleteIdempotencyKey.
+func (mr *MockDataSourceIMockRecorder) CompleteIdempotencyKey(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteIdempotencyKey", reflect.TypeOf((*MockDataSourceI)(nil).CompleteIdempotencyKey), arg0)
+}
+
 // Get mocks base method.
-func (m *MockDataSourceI) Get(arg0 map[string]interface{}, arg1, arg2 int) ([]model.Transaction, int, error) {
+func (m *MockDataSourceI) Get(arg0 model.Query) ([]model.Transaction, int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Get", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "Get", arg0)
 	ret0, _ := ret[0].([]model.Transaction)
 	ret1, _ := ret[1].(int)
 	ret2, _ := ret[2].(error)
@@ -45,9 +61,280 @@ func (m *MockDataSourceI) Get(arg0 map[string]interface{}, arg1, arg2 int) ([]mo
 }
 
 // Get indicates an expected call of Get.
-func (mr *MockDataSourceIMockRecorder) Get(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockDataSourceIMockRecorder) Get(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockDataSourceI)(nil).Get), arg0)
+}
+
+// GetAccount mocks base method.
+func (m *MockDataSourceI) GetAccount(arg0 int) (*model.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccount", arg0)
+	ret0, _ := ret[0].(*model.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccount indicates an expected call of GetAccount.
+func (mr *MockDataSourceIMockRecorder) GetAccount(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccount", reflect.TypeOf((*MockDataSourceI)(nil).GetAccount), arg0)
+}
+
+// GetBalances mocks base method.
+func (m *MockDataSourceI) GetBalances(arg0 int, arg1 string, arg2 time.Time) ([]model.Balance, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBalances", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]model.Balance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBalances indicates an expected call of GetBalances.
+func (mr *MockDataSourceIMockRecorder) GetBalances(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBalances", reflect.TypeOf((*MockDataSourceI)(nil).GetBalances), arg0, arg1, arg2)
+}
+
+// GetDueOutbox mocks base method.
+func (m *MockDataSourceI) GetDueOutbox(arg0 int) ([]model.OutboxMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDueOutbox", arg0)
+	ret0, _ := ret[0].([]model.OutboxMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDueOutbox indicates an expected call of GetDueOutbox.
+func (mr *MockDataSourceIMockRecorder) GetDueOutbox(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDueOutbox", reflect.TypeOf((*MockDataSourceI)(nil).GetDueOutbox), arg0)
+}
+
+// GetDueSchedules mocks base method.
+func (m *MockDataSourceI) GetDueSchedules(arg0 int) ([]model.Schedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDueSchedules", arg0)
+	ret0, _ := ret[0].([]model.Schedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDueSchedules indicates an expected call of GetDueSchedules.
+func (mr *MockDataSourceIMockRecorder) GetDueSchedules(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDueSchedules", reflect.TypeOf((*MockDataSourceI)(nil).GetDueSchedules), arg0)
+}
+
+// GetDueWebhookDeliveries mocks base method.
+func (m *MockDataSourceI) GetDueWebhookDeliveries(arg0 int) ([]model.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDueWebhookDeliveries", arg0)
+	ret0, _ := ret[0].([]model.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDueWebhookDeliveries indicates an expected call of GetDueWebhookDeliveries.
+func (mr *MockDataSourceIMockRecorder) GetDueWebhookDeliveries(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockDataSourceI)(nil).Get), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDueWebhookDeliveries", reflect.TypeOf((*MockDataSourceI)(nil).GetDueWebhookDeliveries), arg0)
+}
+
+// GetFxRate mocks base method.
+func (m *MockDataSourceI) GetFxRate(arg0, arg1 string, arg2 time.Time) (*model.FxRate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFxRate", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*model.FxRate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFxRate indicates an expected call of GetFxRate.
+func (mr *MockDataSourceIMockRecorder) GetFxRate(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFxRate", reflect.TypeOf((*MockDataSourceI)(nil).GetFxRate), arg0, arg1, arg2)
+}
+
+// GetIdempotencyKey mocks base method.
+func (m *MockDataSourceI) GetIdempotencyKey(arg0, arg1 string) (*model.IdempotencyRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetIdempotencyKey", arg0, arg1)
+	ret0, _ := ret[0].(*model.IdempotencyRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetIdempotencyKey indicates an expected call of GetIdempotencyKey.
+func (mr *MockDataSourceIMockRecorder) GetIdempotencyKey(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIdempotencyKey", reflect.TypeOf((*MockDataSourceI)(nil).GetIdempotencyKey), arg0, arg1)
+}
+
+// GetRiskHits mocks base method.
+func (m *MockDataSourceI) GetRiskHits(arg0 string) ([]model.RiskHit, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRiskHits", arg0)
+	ret0, _ := ret[0].([]model.RiskHit)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRiskHits indicates an expected call of GetRiskHits.
+func (mr *MockDataSourceIMockRecorder) GetRiskHits(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRiskHits", reflect.TypeOf((*MockDataSourceI)(nil).GetRiskHits), arg0)
+}
+
+// GetRunningBalances mocks base method.
+func (m *MockDataSourceI) GetRunningBalances(arg0 int, arg1 string, arg2 []string) (map[string]model.Money, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRunningBalances", arg0, arg1, arg2)
+	ret0, _ := ret[0].(map[string]model.Money)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRunningBalances indicates an expected call of GetRunningBalances.
+func (mr *MockDataSourceIMockRecorder) GetRunningBalances(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRunningBalances", reflect.TypeOf((*MockDataSourceI)(nil).GetRunningBalances), arg0, arg1, arg2)
+}
+
+// GetSchedule mocks base method.
+func (m *MockDataSourceI) GetSchedule(arg0 string) (*model.Schedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSchedule", arg0)
+	ret0, _ := ret[0].(*model.Schedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSchedule indicates an expected call of GetSchedule.
+func (mr *MockDataSourceIMockRecorder) GetSchedule(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSchedule", reflect.TypeOf((*MockDataSourceI)(nil).GetSchedule), arg0)
+}
+
+// GetScheduleExecutions mocks base method.
+func (m *MockDataSourceI) GetScheduleExecutions(arg0 string, arg1 int) ([]model.ScheduleExecution, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetScheduleExecutions", arg0, arg1)
+	ret0, _ := ret[0].([]model.ScheduleExecution)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetScheduleExecutions indicates an expected call of GetScheduleExecutions.
+func (mr *MockDataSourceIMockRecorder) GetScheduleExecutions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetScheduleExecutions", reflect.TypeOf((*MockDataSourceI)(nil).GetScheduleExecutions), arg0, arg1)
+}
+
+// GetSchedules mocks base method.
+func (m *MockDataSourceI) GetSchedules(arg0 string) ([]model.Schedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSchedules", arg0)
+	ret0, _ := ret[0].([]model.Schedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSchedules indicates an expected call of GetSchedules.
+func (mr *MockDataSourceIMockRecorder) GetSchedules(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSchedules", reflect.TypeOf((*MockDataSourceI)(nil).GetSchedules), arg0)
+}
+
+// GetSpending mocks base method.
+func (m *MockDataSourceI) GetSpending(arg0 model.Filter) (model.Money, int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSpending", arg0)
+	ret0, _ := ret[0].(model.Money)
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetSpending indicates an expected call of GetSpending.
+func (mr *MockDataSourceIMockRecorder) GetSpending(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSpending", reflect.TypeOf((*MockDataSourceI)(nil).GetSpending), arg0)
+}
+
+// GetStatusHistory mocks base method.
+func (m *MockDataSourceI) GetStatusHistory(arg0 string) ([]model.StatusChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStatusHistory", arg0)
+	ret0, _ := ret[0].([]model.StatusChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStatusHistory indicates an expected call of GetStatusHistory.
+func (mr *MockDataSourceIMockRecorder) GetStatusHistory(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStatusHistory", reflect.TypeOf((*MockDataSourceI)(nil).GetStatusHistory), arg0)
+}
+
+// GetWebhook mocks base method.
+func (m *MockDataSourceI) GetWebhook(arg0 string) (*model.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWebhook", arg0)
+	ret0, _ := ret[0].(*model.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWebhook indicates an expected call of GetWebhook.
+func (mr *MockDataSourceIMockRecorder) GetWebhook(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWebhook", reflect.TypeOf((*MockDataSourceI)(nil).GetWebhook), arg0)
+}
+
+// GetWebhookDeliveries mocks base method.
+func (m *MockDataSourceI) GetWebhookDeliveries(arg0 string, arg1 int) ([]model.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWebhookDeliveries", arg0, arg1)
+	ret0, _ := ret[0].([]model.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWebhookDeliveries indicates an expected call of GetWebhookDeliveries.
+func (mr *MockDataSourceIMockRecorder) GetWebhookDeliveries(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWebhookDeliveries", reflect.TypeOf((*MockDataSourceI)(nil).GetWebhookDeliveries), arg0, arg1)
+}
+
+// GetWebhookDelivery mocks base method.
+func (m *MockDataSourceI) GetWebhookDelivery(arg0 string) (*model.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWebhookDelivery", arg0)
+	ret0, _ := ret[0].(*model.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWebhookDelivery indicates an expected call of GetWebhookDelivery.
+func (mr *MockDataSourceIMockRecorder) GetWebhookDelivery(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWebhookDelivery", reflect.TypeOf((*MockDataSourceI)(nil).GetWebhookDelivery), arg0)
+}
+
+// GetWebhooks mocks base method.
+func (m *MockDataSourceI) GetWebhooks(arg0 string) ([]model.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWebhooks", arg0)
+	ret0, _ := ret[0].([]model.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWebhooks indicates an expected call of GetWebhooks.
+func (mr *MockDataSourceIMockRecorder) GetWebhooks(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWebhooks", reflect.TypeOf((*MockDataSourceI)(nil).GetWebhooks), arg0)
 }
 
 // HealthCheck mocks base method.
@@ -77,3 +364,329 @@ func (mr *MockDataSourceIMockRecorder) Insert(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockDataSourceI)(nil).Insert), arg0)
 }
+
+// InsertAccount mocks base method.
+func (m *MockDataSourceI) InsertAccount(arg0 model.Account) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertAccount", arg0)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertAccount indicates an expected call of InsertAccount.
+func (mr *MockDataSourceIMockRecorder) InsertAccount(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertAccount", reflect.TypeOf((*MockDataSourceI)(nil).InsertAccount), arg0)
+}
+
+// InsertBatch mocks base method.
+func (m *MockDataSourceI) InsertBatch(arg0 []model.Transaction) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertBatch", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertBatch indicates an expected call of InsertBatch.
+func (mr *MockDataSourceIMockRecorder) InsertBatch(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertBatch", reflect.TypeOf((*MockDataSourceI)(nil).InsertBatch), arg0)
+}
+
+// InsertOutbox mocks base method.
+func (m *MockDataSourceI) InsertOutbox(arg0 model.OutboxMessage) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertOutbox", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertOutbox indicates an expected call of InsertOutbox.
+func (mr *MockDataSourceIMockRecorder) InsertOutbox(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertOutbox", reflect.TypeOf((*MockDataSourceI)(nil).InsertOutbox), arg0)
+}
+
+// InsertRiskHit mocks base method.
+func (m *MockDataSourceI) InsertRiskHit(arg0 model.RiskHit) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertRiskHit", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertRiskHit indicates an expected call of InsertRiskHit.
+func (mr *MockDataSourceIMockRecorder) InsertRiskHit(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertRiskHit", reflect.TypeOf((*MockDataSourceI)(nil).InsertRiskHit), arg0)
+}
+
+// InsertSchedule mocks base method.
+func (m *MockDataSourceI) InsertSchedule(arg0 model.Schedule) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertSchedule", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertSchedule indicates an expected call of InsertSchedule.
+func (mr *MockDataSourceIMockRecorder) InsertSchedule(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertSchedule", reflect.TypeOf((*MockDataSourceI)(nil).InsertSchedule), arg0)
+}
+
+// InsertScheduleExecution mocks base method.
+func (m *MockDataSourceI) InsertScheduleExecution(arg0 model.ScheduleExecution) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertScheduleExecution", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertScheduleExecution indicates an expected call of InsertScheduleExecution.
+func (mr *MockDataSourceIMockRecorder) InsertScheduleExecution(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertScheduleExecution", reflect.TypeOf((*MockDataSourceI)(nil).InsertScheduleExecution), arg0)
+}
+
+// InsertStatusHistory mocks base method.
+func (m *MockDataSourceI) InsertStatusHistory(arg0 model.StatusChange) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertStatusHistory", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertStatusHistory indicates an expected call of InsertStatusHistory.
+func (mr *MockDataSourceIMockRecorder) InsertStatusHistory(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertStatusHistory", reflect.TypeOf((*MockDataSourceI)(nil).InsertStatusHistory), arg0)
+}
+
+// InsertWebhook mocks base method.
+func (m *MockDataSourceI) InsertWebhook(arg0 model.Webhook) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertWebhook", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertWebhook indicates an expected call of InsertWebhook.
+func (mr *MockDataSourceIMockRecorder) InsertWebhook(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertWebhook", reflect.TypeOf((*MockDataSourceI)(nil).InsertWebhook), arg0)
+}
+
+// InsertWebhookDeliveries mocks base method.
+func (m *MockDataSourceI) InsertWebhookDeliveries(arg0 string, arg1 model.WebhookDelivery) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertWebhookDeliveries", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertWebhookDeliveries indicates an expected call of InsertWebhookDeliveries.
+func (mr *MockDataSourceIMockRecorder) InsertWebhookDeliveries(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertWebhookDeliveries", reflect.TypeOf((*MockDataSourceI)(nil).InsertWebhookDeliveries), arg0, arg1)
+}
+
+// LeaseOutbox mocks base method.
+func (m *MockDataSourceI) LeaseOutbox(arg0 []int64, arg1 time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LeaseOutbox", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LeaseOutbox indicates an expected call of LeaseOutbox.
+func (mr *MockDataSourceIMockRecorder) LeaseOutbox(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LeaseOutbox", reflect.TypeOf((*MockDataSourceI)(nil).LeaseOutbox), arg0, arg1)
+}
+
+// LeaseWebhookDeliveries mocks base method.
+func (m *MockDataSourceI) LeaseWebhookDeliveries(arg0 []string, arg1 time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LeaseWebhookDeliveries", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LeaseWebhookDeliveries indicates an expected call of LeaseWebhookDeliveries.
+func (mr *MockDataSourceIMockRecorder) LeaseWebhookDeliveries(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LeaseWebhookDeliveries", reflect.TypeOf((*MockDataSourceI)(nil).LeaseWebhookDeliveries), arg0, arg1)
+}
+
+// LockSchedule mocks base method.
+func (m *MockDataSourceI) LockSchedule(arg0 string) (*model.Schedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockSchedule", arg0)
+	ret0, _ := ret[0].(*model.Schedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LockSchedule indicates an expected call of LockSchedule.
+func (mr *MockDataSourceIMockRecorder) LockSchedule(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockSchedule", reflect.TypeOf((*MockDataSourceI)(nil).LockSchedule), arg0)
+}
+
+// LockUser mocks base method.
+func (m *MockDataSourceI) LockUser(arg0 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockUser", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LockUser indicates an expected call of LockUser.
+func (mr *MockDataSourceIMockRecorder) LockUser(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockUser", reflect.TypeOf((*MockDataSourceI)(nil).LockUser), arg0)
+}
+
+// ReleaseIdempotencyKey mocks base method.
+func (m *MockDataSourceI) ReleaseIdempotencyKey(arg0, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseIdempotencyKey", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseIdempotencyKey indicates an expected call of ReleaseIdempotencyKey.
+func (mr *MockDataSourceIMockRecorder) ReleaseIdempotencyKey(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseIdempotencyKey", reflect.TypeOf((*MockDataSourceI)(nil).ReleaseIdempotencyKey), arg0, arg1)
+}
+
+// ReserveIdempotencyKey mocks base method.
+func (m *MockDataSourceI) ReserveIdempotencyKey(arg0 model.IdempotencyRecord, arg1 time.Duration) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReserveIdempotencyKey", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReserveIdempotencyKey indicates an expected call of ReserveIdempotencyKey.
+func (mr *MockDataSourceIMockRecorder) ReserveIdempotencyKey(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReserveIdempotencyKey", reflect.TypeOf((*MockDataSourceI)(nil).ReserveIdempotencyKey), arg0, arg1)
+}
+
+// Stream mocks base method.
+func (m *MockDataSourceI) Stream(arg0 model.Query, arg1 func(model.Transaction) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stream", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Stream indicates an expected call of Stream.
+func (mr *MockDataSourceIMockRecorder) Stream(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stream", reflect.TypeOf((*MockDataSourceI)(nil).Stream), arg0, arg1)
+}
+
+// Transaction mocks base method.
+func (m *MockDataSourceI) Transaction(arg0 func(datasource.DataSourceI) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Transaction", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Transaction indicates an expected call of Transaction.
+func (mr *MockDataSourceIMockRecorder) Transaction(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transaction", reflect.TypeOf((*MockDataSourceI)(nil).Transaction), arg0)
+}
+
+// Update mocks base method.
+func (m *MockDataSourceI) Update(arg0 model.Filter, arg1 map[string]interface{}) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockDataSourceIMockRecorder) Update(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockDataSourceI)(nil).Update), arg0, arg1)
+}
+
+// UpdateOutbox mocks base method.
+func (m *MockDataSourceI) UpdateOutbox(arg0 model.OutboxMessage) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateOutbox", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateOutbox indicates an expected call of UpdateOutbox.
+func (mr *MockDataSourceIMockRecorder) UpdateOutbox(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOutbox", reflect.TypeOf((*MockDataSourceI)(nil).UpdateOutbox), arg0)
+}
+
+// UpdateSchedule mocks base method.
+func (m *MockDataSourceI) UpdateSchedule(arg0 model.Schedule) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSchedule", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateSchedule indicates an expected call of UpdateSchedule.
+func (mr *MockDataSourceIMockRecorder) UpdateSchedule(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSchedule", reflect.TypeOf((*MockDataSourceI)(nil).UpdateSchedule), arg0)
+}
+
+// UpdateWebhookDelivery mocks base method.
+func (m *MockDataSourceI) UpdateWebhookDelivery(arg0 model.WebhookDelivery) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateWebhookDelivery", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateWebhookDelivery indicates an expected call of UpdateWebhookDelivery.
+func (mr *MockDataSourceIMockRecorder) UpdateWebhookDelivery(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateWebhookDelivery", reflect.TypeOf((*MockDataSourceI)(nil).UpdateWebhookDelivery), arg0)
+}
+
+// UpdateWebhookStatus mocks base method.
+func (m *MockDataSourceI) UpdateWebhookStatus(arg0, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateWebhookStatus", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateWebhookStatus indicates an expected call of UpdateWebhookStatus.
+func (mr *MockDataSourceIMockRecorder) UpdateWebhookStatus(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateWebhookStatus", reflect.TypeOf((*MockDataSourceI)(nil).UpdateWebhookStatus), arg0, arg1)
+}
+
+// UpsertFxRate mocks base method.
+func (m *MockDataSourceI) UpsertFxRate(arg0 model.FxRate) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertFxRate", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpsertFxRate indicates an expected call of UpsertFxRate.
+func (mr *MockDataSourceIMockRecorder) UpsertFxRate(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertFxRate", reflect.TypeOf((*MockDataSourceI)(nil).UpsertFxRate), arg0)
+}
@@ -34,6 +34,54 @@ func (m *MockTransactionManagementServiceHandler) EXPECT() *MockTransactionManag
 	return m.recorder
 }
 
+// CancelSchedule mocks base method.
+func (m *MockTransactionManagementServiceHandler) CancelSchedule(arg0 http.ResponseWriter, arg1 *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "CancelSchedule", arg0, arg1)
+}
+
+// CancelSchedule indicates an expected call of CancelSchedule.
+func (mr *MockTransactionManagementServiceHandlerMockRecorder) CancelSchedule(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelSchedule", reflect.TypeOf((*MockTransactionManagementServiceHandler)(nil).CancelSchedule), arg0, arg1)
+}
+
+// CreateSchedule mocks base method.
+func (m *MockTransactionManagementServiceHandler) CreateSchedule(arg0 http.ResponseWriter, arg1 *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "CreateSchedule", arg0, arg1)
+}
+
+// CreateSchedule indicates an expected call of CreateSchedule.
+func (mr *MockTransactionManagementServiceHandlerMockRecorder) CreateSchedule(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSchedule", reflect.TypeOf((*MockTransactionManagementServiceHandler)(nil).CreateSchedule), arg0, arg1)
+}
+
+// CreateWebhook mocks base method.
+func (m *MockTransactionManagementServiceHandler) CreateWebhook(arg0 http.ResponseWriter, arg1 *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "CreateWebhook", arg0, arg1)
+}
+
+// CreateWebhook indicates an expected call of CreateWebhook.
+func (mr *MockTransactionManagementServiceHandlerMockRecorder) CreateWebhook(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWebhook", reflect.TypeOf((*MockTransactionManagementServiceHandler)(nil).CreateWebhook), arg0, arg1)
+}
+
+// DeleteWebhook mocks base method.
+func (m *MockTransactionManagementServiceHandler) DeleteWebhook(arg0 http.ResponseWriter, arg1 *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "DeleteWebhook", arg0, arg1)
+}
+
+// DeleteWebhook indicates an expected call of DeleteWebhook.
+func (mr *MockTransactionManagementServiceHandlerMockRecorder) DeleteWebhook(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWebhook", reflect.TypeOf((*MockTransactionManagementServiceHandler)(nil).DeleteWebhook), arg0, arg1)
+}
+
 // DownloadTransaction mocks base method.
 func (m *MockTransactionManagementServiceHandler) DownloadTransaction(arg0 http.ResponseWriter, arg1 *http.Request) {
 	m.ctrl.T.Helper()
@@ -46,6 +94,102 @@ func (mr *MockTransactionManagementServiceHandlerMockRecorder) DownloadTransacti
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DownloadTransaction", reflect.TypeOf((*MockTransactionManagementServiceHandler)(nil).DownloadTransaction), arg0, arg1)
 }
 
+// ExportAccount mocks base method.
+func (m *MockTransactionManagementServiceHandler) ExportAccount(arg0 http.ResponseWriter, arg1 *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ExportAccount", arg0, arg1)
+}
+
+// ExportAccount indicates an expected call of ExportAccount.
+func (mr *MockTransactionManagementServiceHandlerMockRecorder) ExportAccount(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportAccount", reflect.TypeOf((*MockTransactionManagementServiceHandler)(nil).ExportAccount), arg0, arg1)
+}
+
+// ExportTransactions mocks base method.
+func (m *MockTransactionManagementServiceHandler) ExportTransactions(arg0 http.ResponseWriter, arg1 *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ExportTransactions", arg0, arg1)
+}
+
+// ExportTransactions indicates an expected call of ExportTransactions.
+func (mr *MockTransactionManagementServiceHandlerMockRecorder) ExportTransactions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportTransactions", reflect.TypeOf((*MockTransactionManagementServiceHandler)(nil).ExportTransactions), arg0, arg1)
+}
+
+// GetBalance mocks base method.
+func (m *MockTransactionManagementServiceHandler) GetBalance(arg0 http.ResponseWriter, arg1 *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "GetBalance", arg0, arg1)
+}
+
+// GetBalance indicates an expected call of GetBalance.
+func (mr *MockTransactionManagementServiceHandlerMockRecorder) GetBalance(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBalance", reflect.TypeOf((*MockTransactionManagementServiceHandler)(nil).GetBalance), arg0, arg1)
+}
+
+// GetReviewQueue mocks base method.
+func (m *MockTransactionManagementServiceHandler) GetReviewQueue(arg0 http.ResponseWriter, arg1 *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "GetReviewQueue", arg0, arg1)
+}
+
+// GetReviewQueue indicates an expected call of GetReviewQueue.
+func (mr *MockTransactionManagementServiceHandlerMockRecorder) GetReviewQueue(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReviewQueue", reflect.TypeOf((*MockTransactionManagementServiceHandler)(nil).GetReviewQueue), arg0, arg1)
+}
+
+// GetSchedule mocks base method.
+func (m *MockTransactionManagementServiceHandler) GetSchedule(arg0 http.ResponseWriter, arg1 *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "GetSchedule", arg0, arg1)
+}
+
+// GetSchedule indicates an expected call of GetSchedule.
+func (mr *MockTransactionManagementServiceHandlerMockRecorder) GetSchedule(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSchedule", reflect.TypeOf((*MockTransactionManagementServiceHandler)(nil).GetSchedule), arg0, arg1)
+}
+
+// GetSchedules mocks base method.
+func (m *MockTransactionManagementServiceHandler) GetSchedules(arg0 http.ResponseWriter, arg1 *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "GetSchedules", arg0, arg1)
+}
+
+// GetSchedules indicates an expected call of GetSchedules.
+func (mr *MockTransactionManagementServiceHandlerMockRecorder) GetSchedules(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSchedules", reflect.TypeOf((*MockTransactionManagementServiceHandler)(nil).GetSchedules), arg0, arg1)
+}
+
+// GetStatement mocks base method.
+func (m *MockTransactionManagementServiceHandler) GetStatement(arg0 http.ResponseWriter, arg1 *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "GetStatement", arg0, arg1)
+}
+
+// GetStatement indicates an expected call of GetStatement.
+func (mr *MockTransactionManagementServiceHandlerMockRecorder) GetStatement(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStatement", reflect.TypeOf((*MockTransactionManagementServiceHandler)(nil).GetStatement), arg0, arg1)
+}
+
+// GetTransaction mocks base method.
+func (m *MockTransactionManagementServiceHandler) GetTransaction(arg0 http.ResponseWriter, arg1 *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "GetTransaction", arg0, arg1)
+}
+
+// GetTransaction indicates an expected call of GetTransaction.
+func (mr *MockTransactionManagementServiceHandlerMockRecorder) GetTransaction(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransaction", reflect.TypeOf((*MockTransactionManagementServiceHandler)(nil).GetTransaction), arg0, arg1)
+}
+
 // GetTransactions mocks base method.
 func (m *MockTransactionManagementServiceHandler) GetTransactions(arg0 http.ResponseWriter, arg1 *http.Request) {
 	m.ctrl.T.Helper()
@@ -58,6 +202,30 @@ func (mr *MockTransactionManagementServiceHandlerMockRecorder) GetTransactions(a
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransactions", reflect.TypeOf((*MockTransactionManagementServiceHandler)(nil).GetTransactions), arg0, arg1)
 }
 
+// GetWebhookDeliveries mocks base method.
+func (m *MockTransactionManagementServiceHandler) GetWebhookDeliveries(arg0 http.ResponseWriter, arg1 *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "GetWebhookDeliveries", arg0, arg1)
+}
+
+// GetWebhookDeliveries indicates an expected call of GetWebhookDeliveries.
+func (mr *MockTransactionManagementServiceHandlerMockRecorder) GetWebhookDeliveries(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWebhookDeliveries", reflect.TypeOf((*MockTransactionManagementServiceHandler)(nil).GetWebhookDeliveries), arg0, arg1)
+}
+
+// GetWebhooks mocks base method.
+func (m *MockTransactionManagementServiceHandler) GetWebhooks(arg0 http.ResponseWriter, arg1 *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "GetWebhooks", arg0, arg1)
+}
+
+// GetWebhooks indicates an expected call of GetWebhooks.
+func (mr *MockTransactionManagementServiceHandlerMockRecorder) GetWebhooks(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWebhooks", reflect.TypeOf((*MockTransactionManagementServiceHandler)(nil).GetWebhooks), arg0, arg1)
+}
+
 // HealthCheck mocks base method.
 func (m *MockTransactionManagementServiceHandler) HealthCheck() (string, string, bool) {
 	m.ctrl.T.Helper()
@@ -74,6 +242,30 @@ func (mr *MockTransactionManagementServiceHandlerMockRecorder) HealthCheck() *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HealthCheck", reflect.TypeOf((*MockTransactionManagementServiceHandler)(nil).HealthCheck))
 }
 
+// ImportTransactions mocks base method.
+func (m *MockTransactionManagementServiceHandler) ImportTransactions(arg0 http.ResponseWriter, arg1 *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ImportTransactions", arg0, arg1)
+}
+
+// ImportTransactions indicates an expected call of ImportTransactions.
+func (mr *MockTransactionManagementServiceHandlerMockRecorder) ImportTransactions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportTransactions", reflect.TypeOf((*MockTransactionManagementServiceHandler)(nil).ImportTransactions), arg0, arg1)
+}
+
+// NewBatch mocks base method.
+func (m *MockTransactionManagementServiceHandler) NewBatch(arg0 http.ResponseWriter, arg1 *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "NewBatch", arg0, arg1)
+}
+
+// NewBatch indicates an expected call of NewBatch.
+func (mr *MockTransactionManagementServiceHandlerMockRecorder) NewBatch(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewBatch", reflect.TypeOf((*MockTransactionManagementServiceHandler)(nil).NewBatch), arg0, arg1)
+}
+
 // NewTransaction mocks base method.
 func (m *MockTransactionManagementServiceHandler) NewTransaction(arg0 http.ResponseWriter, arg1 *http.Request) {
 	m.ctrl.T.Helper()
@@ -85,3 +277,75 @@ func (mr *MockTransactionManagementServiceHandlerMockRecorder) NewTransaction(ar
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewTransaction", reflect.TypeOf((*MockTransactionManagementServiceHandler)(nil).NewTransaction), arg0, arg1)
 }
+
+// RedeliverWebhook mocks base method.
+func (m *MockTransactionManagementServiceHandler) RedeliverWebhook(arg0 http.ResponseWriter, arg1 *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RedeliverWebhook", arg0, arg1)
+}
+
+// RedeliverWebhook indicates an expected call of RedeliverWebhook.
+func (mr *MockTransactionManagementServiceHandlerMockRecorder) RedeliverWebhook(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RedeliverWebhook", reflect.TypeOf((*MockTransactionManagementServiceHandler)(nil).RedeliverWebhook), arg0, arg1)
+}
+
+// RefundTransaction mocks base method.
+func (m *MockTransactionManagementServiceHandler) RefundTransaction(arg0 http.ResponseWriter, arg1 *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RefundTransaction", arg0, arg1)
+}
+
+// RefundTransaction indicates an expected call of RefundTransaction.
+func (mr *MockTransactionManagementServiceHandlerMockRecorder) RefundTransaction(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefundTransaction", reflect.TypeOf((*MockTransactionManagementServiceHandler)(nil).RefundTransaction), arg0, arg1)
+}
+
+// RegisterAccount mocks base method.
+func (m *MockTransactionManagementServiceHandler) RegisterAccount(arg0 http.ResponseWriter, arg1 *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RegisterAccount", arg0, arg1)
+}
+
+// RegisterAccount indicates an expected call of RegisterAccount.
+func (mr *MockTransactionManagementServiceHandlerMockRecorder) RegisterAccount(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterAccount", reflect.TypeOf((*MockTransactionManagementServiceHandler)(nil).RegisterAccount), arg0, arg1)
+}
+
+// ReverseTransaction mocks base method.
+func (m *MockTransactionManagementServiceHandler) ReverseTransaction(arg0 http.ResponseWriter, arg1 *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ReverseTransaction", arg0, arg1)
+}
+
+// ReverseTransaction indicates an expected call of ReverseTransaction.
+func (mr *MockTransactionManagementServiceHandlerMockRecorder) ReverseTransaction(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReverseTransaction", reflect.TypeOf((*MockTransactionManagementServiceHandler)(nil).ReverseTransaction), arg0, arg1)
+}
+
+// UpdateSchedule mocks base method.
+func (m *MockTransactionManagementServiceHandler) UpdateSchedule(arg0 http.ResponseWriter, arg1 *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UpdateSchedule", arg0, arg1)
+}
+
+// UpdateSchedule indicates an expected call of UpdateSchedule.
+func (mr *MockTransactionManagementServiceHandlerMockRecorder) UpdateSchedule(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSchedule", reflect.TypeOf((*MockTransactionManagementServiceHandler)(nil).UpdateSchedule), arg0, arg1)
+}
+
+// UpdateTransactionStatus mocks base method.
+func (m *MockTransactionManagementServiceHandler) UpdateTransactionStatus(arg0 http.ResponseWriter, arg1 *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UpdateTransactionStatus", arg0, arg1)
+}
+
+// UpdateTransactionStatus indicates an expected call of UpdateTransactionStatus.
+func (mr *MockTransactionManagementServiceHandlerMockRecorder) UpdateTransactionStatus(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateTransactionStatus", reflect.TypeOf((*MockTransactionManagementServiceHandler)(nil).UpdateTransactionStatus), arg0, arg1)
+}
@@ -5,7 +5,9 @@
 package mock
 
 import (
+	io "io"
 	reflect "reflect"
+	time "time"
 
 	model "github.com/PereRohit/util/model"
 	gomock "github.com/golang/mock/gomock"
@@ -35,32 +37,228 @@ func (m *MockTransactionManagementServiceLogicIer) EXPECT() *MockTransactionMana
 	return m.recorder
 }
 
+// CancelSchedule mocks base method.
+func (m *MockTransactionManagementServiceLogicIer) CancelSchedule(arg0, arg1 string) *model.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelSchedule", arg0, arg1)
+	ret0, _ := ret[0].(*model.Response)
+	return ret0
+}
+
+// CancelSchedule indicates an expected call of CancelSchedule.
+func (mr *MockTransactionManagementServiceLogicIerMockRecorder) CancelSchedule(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelSchedule", reflect.TypeOf((*MockTransactionManagementServiceLogicIer)(nil).CancelSchedule), arg0, arg1)
+}
+
+// CreateSchedule mocks base method.
+func (m *MockTransactionManagementServiceLogicIer) CreateSchedule(arg0 model0.NewSchedule) *model.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSchedule", arg0)
+	ret0, _ := ret[0].(*model.Response)
+	return ret0
+}
+
+// CreateSchedule indicates an expected call of CreateSchedule.
+func (mr *MockTransactionManagementServiceLogicIerMockRecorder) CreateSchedule(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSchedule", reflect.TypeOf((*MockTransactionManagementServiceLogicIer)(nil).CreateSchedule), arg0)
+}
+
+// CreateWebhook mocks base method.
+func (m *MockTransactionManagementServiceLogicIer) CreateWebhook(arg0 model0.NewWebhook) *model.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWebhook", arg0)
+	ret0, _ := ret[0].(*model.Response)
+	return ret0
+}
+
+// CreateWebhook indicates an expected call of CreateWebhook.
+func (mr *MockTransactionManagementServiceLogicIerMockRecorder) CreateWebhook(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWebhook", reflect.TypeOf((*MockTransactionManagementServiceLogicIer)(nil).CreateWebhook), arg0)
+}
+
+// DeleteWebhook mocks base method.
+func (m *MockTransactionManagementServiceLogicIer) DeleteWebhook(arg0, arg1 string) *model.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteWebhook", arg0, arg1)
+	ret0, _ := ret[0].(*model.Response)
+	return ret0
+}
+
+// DeleteWebhook indicates an expected call of DeleteWebhook.
+func (mr *MockTransactionManagementServiceLogicIerMockRecorder) DeleteWebhook(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWebhook", reflect.TypeOf((*MockTransactionManagementServiceLogicIer)(nil).DeleteWebhook), arg0, arg1)
+}
+
 // DownloadTransaction mocks base method.
-func (m *MockTransactionManagementServiceLogicIer) DownloadTransaction(arg0, arg1 string) *model.Response {
+func (m *MockTransactionManagementServiceLogicIer) DownloadTransaction(arg0, arg1, arg2 string) *model.Response {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DownloadTransaction", arg0, arg1)
+	ret := m.ctrl.Call(m, "DownloadTransaction", arg0, arg1, arg2)
 	ret0, _ := ret[0].(*model.Response)
 	return ret0
 }
 
 // DownloadTransaction indicates an expected call of DownloadTransaction.
-func (mr *MockTransactionManagementServiceLogicIerMockRecorder) DownloadTransaction(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockTransactionManagementServiceLogicIerMockRecorder) DownloadTransaction(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DownloadTransaction", reflect.TypeOf((*MockTransactionManagementServiceLogicIer)(nil).DownloadTransaction), arg0, arg1, arg2)
+}
+
+// ExportAccount mocks base method.
+func (m *MockTransactionManagementServiceLogicIer) ExportAccount(arg0 model0.AccountExport, arg1 io.Writer) *model.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportAccount", arg0, arg1)
+	ret0, _ := ret[0].(*model.Response)
+	return ret0
+}
+
+// ExportAccount indicates an expected call of ExportAccount.
+func (mr *MockTransactionManagementServiceLogicIerMockRecorder) ExportAccount(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportAccount", reflect.TypeOf((*MockTransactionManagementServiceLogicIer)(nil).ExportAccount), arg0, arg1)
+}
+
+// ExportTransactions mocks base method.
+func (m *MockTransactionManagementServiceLogicIer) ExportTransactions(arg0 model0.ListTransactions, arg1 model0.CsvExport, arg2 io.Writer) *model.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportTransactions", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*model.Response)
+	return ret0
+}
+
+// ExportTransactions indicates an expected call of ExportTransactions.
+func (mr *MockTransactionManagementServiceLogicIerMockRecorder) ExportTransactions(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportTransactions", reflect.TypeOf((*MockTransactionManagementServiceLogicIer)(nil).ExportTransactions), arg0, arg1, arg2)
+}
+
+// GetBalance mocks base method.
+func (m *MockTransactionManagementServiceLogicIer) GetBalance(arg0 int, arg1 string, arg2 *time.Time) *model.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBalance", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*model.Response)
+	return ret0
+}
+
+// GetBalance indicates an expected call of GetBalance.
+func (mr *MockTransactionManagementServiceLogicIerMockRecorder) GetBalance(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBalance", reflect.TypeOf((*MockTransactionManagementServiceLogicIer)(nil).GetBalance), arg0, arg1, arg2)
+}
+
+// GetReviewQueue mocks base method.
+func (m *MockTransactionManagementServiceLogicIer) GetReviewQueue(arg0 string) *model.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReviewQueue", arg0)
+	ret0, _ := ret[0].(*model.Response)
+	return ret0
+}
+
+// GetReviewQueue indicates an expected call of GetReviewQueue.
+func (mr *MockTransactionManagementServiceLogicIerMockRecorder) GetReviewQueue(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReviewQueue", reflect.TypeOf((*MockTransactionManagementServiceLogicIer)(nil).GetReviewQueue), arg0)
+}
+
+// GetSchedule mocks base method.
+func (m *MockTransactionManagementServiceLogicIer) GetSchedule(arg0, arg1 string) *model.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSchedule", arg0, arg1)
+	ret0, _ := ret[0].(*model.Response)
+	return ret0
+}
+
+// GetSchedule indicates an expected call of GetSchedule.
+func (mr *MockTransactionManagementServiceLogicIerMockRecorder) GetSchedule(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSchedule", reflect.TypeOf((*MockTransactionManagementServiceLogicIer)(nil).GetSchedule), arg0, arg1)
+}
+
+// GetSchedules mocks base method.
+func (m *MockTransactionManagementServiceLogicIer) GetSchedules(arg0 string) *model.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSchedules", arg0)
+	ret0, _ := ret[0].(*model.Response)
+	return ret0
+}
+
+// GetSchedules indicates an expected call of GetSchedules.
+func (mr *MockTransactionManagementServiceLogicIerMockRecorder) GetSchedules(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSchedules", reflect.TypeOf((*MockTransactionManagementServiceLogicIer)(nil).GetSchedules), arg0)
+}
+
+// GetStatement mocks base method.
+func (m *MockTransactionManagementServiceLogicIer) GetStatement(arg0 int, arg1 string, arg2 time.Time, arg3 string) *model.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStatement", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*model.Response)
+	return ret0
+}
+
+// GetStatement indicates an expected call of GetStatement.
+func (mr *MockTransactionManagementServiceLogicIerMockRecorder) GetStatement(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStatement", reflect.TypeOf((*MockTransactionManagementServiceLogicIer)(nil).GetStatement), arg0, arg1, arg2, arg3)
+}
+
+// GetTransaction mocks base method.
+func (m *MockTransactionManagementServiceLogicIer) GetTransaction(arg0, arg1 string) *model.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTransaction", arg0, arg1)
+	ret0, _ := ret[0].(*model.Response)
+	return ret0
+}
+
+// GetTransaction indicates an expected call of GetTransaction.
+func (mr *MockTransactionManagementServiceLogicIerMockRecorder) GetTransaction(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DownloadTransaction", reflect.TypeOf((*MockTransactionManagementServiceLogicIer)(nil).DownloadTransaction), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransaction", reflect.TypeOf((*MockTransactionManagementServiceLogicIer)(nil).GetTransaction), arg0, arg1)
 }
 
 // GetTransactions mocks base method.
-func (m *MockTransactionManagementServiceLogicIer) GetTransactions(arg0 string, arg1, arg2 int) *model.Response {
+func (m *MockTransactionManagementServiceLogicIer) GetTransactions(arg0 model0.ListTransactions) *model.Response {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetTransactions", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "GetTransactions", arg0)
 	ret0, _ := ret[0].(*model.Response)
 	return ret0
 }
 
 // GetTransactions indicates an expected call of GetTransactions.
-func (mr *MockTransactionManagementServiceLogicIerMockRecorder) GetTransactions(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockTransactionManagementServiceLogicIerMockRecorder) GetTransactions(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransactions", reflect.TypeOf((*MockTransactionManagementServiceLogicIer)(nil).GetTransactions), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransactions", reflect.TypeOf((*MockTransactionManagementServiceLogicIer)(nil).GetTransactions), arg0)
+}
+
+// GetWebhookDeliveries mocks base method.
+func (m *MockTransactionManagementServiceLogicIer) GetWebhookDeliveries(arg0, arg1 string) *model.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWebhookDeliveries", arg0, arg1)
+	ret0, _ := ret[0].(*model.Response)
+	return ret0
+}
+
+// GetWebhookDeliveries indicates an expected call of GetWebhookDeliveries.
+func (mr *MockTransactionManagementServiceLogicIerMockRecorder) GetWebhookDeliveries(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWebhookDeliveries", reflect.TypeOf((*MockTransactionManagementServiceLogicIer)(nil).GetWebhookDeliveries), arg0, arg1)
+}
+
+// GetWebhooks mocks base method.
+func (m *MockTransactionManagementServiceLogicIer) GetWebhooks(arg0 string) *model.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWebhooks", arg0)
+	ret0, _ := ret[0].(*model.Response)
+	return ret0
+}
+
+// GetWebhooks indicates an expected call of GetWebhooks.
+func (mr *MockTransactionManagementServiceLogicIerMockRecorder) GetWebhooks(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWebhooks", reflect.TypeOf((*MockTransactionManagementServiceLogicIer)(nil).GetWebhooks), arg0)
 }
 
 // HealthCheck mocks base method.
@@ -77,6 +275,34 @@ func (mr *MockTransactionManagementServiceLogicIerMockRecorder) HealthCheck() *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HealthCheck", reflect.TypeOf((*MockTransactionManagementServiceLogicIer)(nil).HealthCheck))
 }
 
+// ImportTransactions mocks base method.
+func (m *MockTransactionManagementServiceLogicIer) ImportTransactions(arg0 model0.ImportTransactions, arg1 io.Reader) *model.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportTransactions", arg0, arg1)
+	ret0, _ := ret[0].(*model.Response)
+	return ret0
+}
+
+// ImportTransactions indicates an expected call of ImportTransactions.
+func (mr *MockTransactionManagementServiceLogicIerMockRecorder) ImportTransactions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportTransactions", reflect.TypeOf((*MockTransactionManagementServiceLogicIer)(nil).ImportTransactions), arg0, arg1)
+}
+
+// NewBatch mocks base method.
+func (m *MockTransactionManagementServiceLogicIer) NewBatch(arg0 model0.NewBatch) *model.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewBatch", arg0)
+	ret0, _ := ret[0].(*model.Response)
+	return ret0
+}
+
+// NewBatch indicates an expected call of NewBatch.
+func (mr *MockTransactionManagementServiceLogicIerMockRecorder) NewBatch(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewBatch", reflect.TypeOf((*MockTransactionManagementServiceLogicIer)(nil).NewBatch), arg0)
+}
+
 // NewTransaction mocks base method.
 func (m *MockTransactionManagementServiceLogicIer) NewTransaction(arg0 model0.NewTransaction) *model.Response {
 	m.ctrl.T.Helper()
@@ -90,3 +316,102 @@ func (mr *MockTransactionManagementServiceLogicIerMockRecorder) NewTransaction(a
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewTransaction", reflect.TypeOf((*MockTransactionManagementServiceLogicIer)(nil).NewTransaction), arg0)
 }
+
+// RedeliverWebhook mocks base method.
+func (m *MockTransactionManagementServiceLogicIer) RedeliverWebhook(arg0, arg1, arg2 string) *model.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RedeliverWebhook", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*model.Response)
+	return ret0
+}
+
+// RedeliverWebhook indicates an expected call of RedeliverWebhook.
+func (mr *MockTransactionManagementServiceLogicIerMockRecorder) RedeliverWebhook(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RedeliverWebhook", reflect.TypeOf((*MockTransactionManagementServiceLogicIer)(nil).RedeliverWebhook), arg0, arg1, arg2)
+}
+
+// Refund mocks base method.
+func (m *MockTransactionManagementServiceLogicIer) Refund(arg0, arg1 string, arg2 model0.Refund) *model.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Refund", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*model.Response)
+	return ret0
+}
+
+// Refund indicates an expected call of Refund.
+func (mr *MockTransactionManagementServiceLogicIerMockRecorder) Refund(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Refund", reflect.TypeOf((*MockTransactionManagementServiceLogicIer)(nil).Refund), arg0, arg1, arg2)
+}
+
+// RegisterAccount mocks base method.
+func (m *MockTransactionManagementServiceLogicIer) RegisterAccount(arg0 model0.NewAccount) *model.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RegisterAccount", arg0)
+	ret0, _ := ret[0].(*model.Response)
+	return ret0
+}
+
+// RegisterAccount indicates an expected call of RegisterAccount.
+func (mr *MockTransactionManagementServiceLogicIerMockRecorder) RegisterAccount(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterAccount", reflect.TypeOf((*MockTransactionManagementServiceLogicIer)(nil).RegisterAccount), arg0)
+}
+
+// Reverse mocks base method.
+func (m *MockTransactionManagementServiceLogicIer) Reverse(arg0, arg1 string, arg2 model0.Reversal) *model.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reverse", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*model.Response)
+	return ret0
+}
+
+// Reverse indicates an expected call of Reverse.
+func (mr *MockTransactionManagementServiceLogicIerMockRecorder) Reverse(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reverse", reflect.TypeOf((*MockTransactionManagementServiceLogicIer)(nil).Reverse), arg0, arg1, arg2)
+}
+
+// RunDueSchedules mocks base method.
+func (m *MockTransactionManagementServiceLogicIer) RunDueSchedules() ([]model0.ScheduleExecution, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RunDueSchedules")
+	ret0, _ := ret[0].([]model0.ScheduleExecution)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RunDueSchedules indicates an expected call of RunDueSchedules.
+func (mr *MockTransactionManagementServiceLogicIerMockRecorder) RunDueSchedules() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunDueSchedules", reflect.TypeOf((*MockTransactionManagementServiceLogicIer)(nil).RunDueSchedules))
+}
+
+// UpdateSchedule mocks base method.
+func (m *MockTransactionManagementServiceLogicIer) UpdateSchedule(arg0, arg1 string, arg2 model0.NewSchedule) *model.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSchedule", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*model.Response)
+	return ret0
+}
+
+// UpdateSchedule indicates an expected call of UpdateSchedule.
+func (mr *MockTransactionManagementServiceLogicIerMockRecorder) UpdateSchedule(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSchedule", reflect.TypeOf((*MockTransactionManagementServiceLogicIer)(nil).UpdateSchedule), arg0, arg1, arg2)
+}
+
+// UpdateStatus mocks base method.
+func (m *MockTransactionManagementServiceLogicIer) UpdateStatus(arg0, arg1 string, arg2 model0.UpdateStatus) *model.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateStatus", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*model.Response)
+	return ret0
+}
+
+// UpdateStatus indicates an expected call of UpdateStatus.
+func (mr *MockTransactionManagementServiceLogicIerMockRecorder) UpdateStatus(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStatus", reflect.TypeOf((*MockTransactionManagementServiceLogicIer)(nil).UpdateStatus), arg0, arg1, arg2)
+}